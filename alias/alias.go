@@ -0,0 +1,39 @@
+// Package alias implements a remote which is another name for a
+// path on a different remote
+package alias
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "alias",
+		Description: "Alias for an existing remote",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote or path to alias.\nCan be \"myremote:path/to/dir\", \"myremote:bucket\", \"myremote:\" or \"/local/path\".",
+		}},
+	})
+}
+
+// NewFs contstructs an Fs from the path.
+//
+// The returned Fs is the actual Fs of the remote aliased, not a
+// wrapper of it.
+func NewFs(name, root string) (fs.Fs, error) {
+	remote := fs.ConfigFileGet(name, "remote")
+	if remote == "" {
+		return nil, errors.New("alias can't point to an empty remote - check the value of the remote setting")
+	}
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point alias remote at itself - check the value of the remote setting")
+	}
+	return fs.NewFs(path.Join(remote, root))
+}
@@ -0,0 +1,71 @@
+package alias
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestMain(m *testing.M) {
+	fs.LoadConfig()
+	os.Exit(m.Run())
+}
+
+func TestNewFs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-alias")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "file"), []byte("hello"), 0600))
+
+	fs.ConfigFileSet("TestAlias", "type", "alias")
+	fs.ConfigFileSet("TestAlias", "remote", filepath.Join(dir, "sub"))
+
+	for _, test := range []struct {
+		root    string
+		entries []string
+		isFile  bool
+	}{
+		{"", []string{"deep", "file"}, false},
+		{"deep", nil, false},
+		{"file", []string{"deep", "file"}, true},
+	} {
+		f, err := fs.NewFs("TestAlias:" + test.root)
+		if test.isFile {
+			assert.Equal(t, fs.ErrorIsFile, err, test.root)
+		} else {
+			require.NoError(t, err, test.root)
+		}
+		// The alias is the Fs of the remote it points to
+		assert.Equal(t, "local", f.Name())
+		entries, err := f.List(context.Background(), "")
+		require.NoError(t, err)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Remote())
+		}
+		sort.Strings(names)
+		assert.Equal(t, test.entries, names, test.root)
+	}
+}
+
+func TestNewFsErrors(t *testing.T) {
+	fs.ConfigFileSet("TestAliasEmpty", "type", "alias")
+	_, err := fs.NewFs("TestAliasEmpty:")
+	assert.Error(t, err)
+
+	fs.ConfigFileSet("TestAliasSelf", "type", "alias")
+	fs.ConfigFileSet("TestAliasSelf", "remote", "TestAliasSelf:dir")
+	_, err = fs.NewFs("TestAliasSelf:")
+	assert.Error(t, err)
+}
@@ -0,0 +1,462 @@
+// Package archive provides a read only wrapper for a remote which
+// shows the zip and tar files in it as directories
+package archive
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+var errorReadOnly = errors.New("archive remotes are read only")
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "archive",
+		Description: "Read archives",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote containing the archives.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\".",
+		}},
+	})
+}
+
+// archiveKind is the type of an archive
+type archiveKind int
+
+// Types of archive
+const (
+	kindNone archiveKind = iota
+	kindZip
+	kindTar
+	kindTarGz
+)
+
+// archiveKindOf returns the kind of archive the file name is from its
+// extension or kindNone if it isn't an archive
+func archiveKindOf(name string) archiveKind {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return kindZip
+	case strings.HasSuffix(lower, ".tar"):
+		return kindTar
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return kindTarGz
+	}
+	return kindNone
+}
+
+// splitArchivePath splits p into the path of the archive it is in
+// and the path inside that archive.  ok is false if p isn't in an
+// archive.
+func splitArchivePath(p string) (archivePath, inner string, ok bool) {
+	if p == "" {
+		return "", "", false
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if archiveKindOf(part) != kindNone {
+			return path.Join(parts[:i+1]...), path.Join(parts[i+1:]...), true
+		}
+	}
+	return "", "", false
+}
+
+// NewFs contstructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := fs.ConfigFileGet(name, "remote")
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point archive remote at itself - check the value of the remote setting")
+	}
+	wrappedFs, err := fs.NewFs(remote)
+	if err == fs.ErrorIsFile {
+		return nil, errors.Errorf("remote %q must be a directory not a file", remote)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remote)
+	}
+	f := &Fs{
+		wrapped:  wrappedFs,
+		name:     name,
+		root:     strings.Trim(rpath, "/"),
+		archives: make(map[string]*archive),
+	}
+	f.features = (&fs.Features{}).Fill(f)
+
+	// Point the Fs at the parent directory if the root is a file
+	if f.root != "" {
+		_, err := f.NewObject(context.Background(), "")
+		if err == nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// Fs represents a wrapped fs.Fs with its archives shown as
+// directories
+type Fs struct {
+	wrapped  fs.Fs
+	name     string
+	root     string
+	features *fs.Features // optional features
+
+	mu       sync.Mutex
+	archives map[string]*archive // archives read so far by path in wrapped
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Archives of %s/%s", f.wrapped.String(), f.root)
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Second
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() fs.HashSet {
+	return fs.HashSet(fs.HashNone)
+}
+
+// fullPath returns the path of remote in the wrapped Fs
+func (f *Fs) fullPath(remote string) string {
+	return path.Join(f.root, remote)
+}
+
+// getArchive returns the index of the archive at archivePath in the
+// wrapped Fs reading it if it hasn't been read or has changed.
+//
+// If the archive doesn't exist it returns fs.ErrorDirNotFound.
+func (f *Fs) getArchive(ctx context.Context, archivePath string) (*archive, error) {
+	o, err := f.wrapped.NewObject(ctx, archivePath)
+	if err == fs.ErrorObjectNotFound || errors.Cause(err) == fs.ErrorNotAFile {
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	a := f.archives[archivePath]
+	f.mu.Unlock()
+	if a != nil && a.size == o.Size() && a.modTime.Equal(o.ModTime()) {
+		return a, nil
+	}
+	fs.Debugf(f, "Reading archive %q", archivePath)
+	a, err = readArchive(ctx, o, archiveKindOf(archivePath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read archive %q", archivePath)
+	}
+	f.mu.Lock()
+	f.archives[archivePath] = a
+	f.mu.Unlock()
+	return a, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	dirPath := f.fullPath(dir)
+	if archivePath, inner, ok := splitArchivePath(dirPath); ok {
+		a, err := f.getArchive(ctx, archivePath)
+		if err != nil {
+			return nil, err
+		}
+		return a.list(f, dir, inner)
+	}
+	wrappedEntries, err := f.wrapped.List(ctx, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range wrappedEntries {
+		remote := path.Join(dir, path.Base(entry.Remote()))
+		switch x := entry.(type) {
+		case fs.Object:
+			if archiveKindOf(remote) != kindNone {
+				entries = append(entries, &fs.Dir{
+					Name:  remote,
+					When:  x.ModTime(),
+					Bytes: x.Size(),
+					Count: -1,
+				})
+			} else {
+				entries = append(entries, f.newWrappedObject(x, remote))
+			}
+		case *fs.Dir:
+			entries = append(entries, &fs.Dir{
+				Name:  remote,
+				When:  x.When,
+				Bytes: x.Bytes,
+				Count: x.Count,
+			})
+		default:
+			return nil, errors.Errorf("unknown object type %T", entry)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	fullPath := f.fullPath(remote)
+	if archivePath, inner, ok := splitArchivePath(fullPath); ok {
+		if inner == "" {
+			// The archive itself is a directory
+			return nil, fs.ErrorNotAFile
+		}
+		a, err := f.getArchive(ctx, archivePath)
+		if err == fs.ErrorDirNotFound {
+			return nil, fs.ErrorObjectNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		e, found := a.entries[inner]
+		if !found {
+			return nil, fs.ErrorObjectNotFound
+		}
+		if e.isDir {
+			return nil, fs.ErrorNotAFile
+		}
+		return a.newObject(f, remote, e), nil
+	}
+	o, err := f.wrapped.NewObject(ctx, fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return f.newWrappedObject(o, remote), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// Archive remotes are read only so this returns an error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Archive remotes are read only so this returns an error
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Archive remotes are read only so this returns an error
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// ------------------------------------------------------------
+
+// wrappedObject is an object in the wrapped Fs which isn't an archive
+type wrappedObject struct {
+	fs.Object
+	f      *Fs
+	remote string
+}
+
+// newWrappedObject wraps o to have the remote given
+func (f *Fs) newWrappedObject(o fs.Object, remote string) *wrappedObject {
+	return &wrappedObject{
+		Object: o,
+		f:      f,
+		remote: remote,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *wrappedObject) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *wrappedObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *wrappedObject) Remote() string {
+	return o.remote
+}
+
+// SetModTime sets the modification time of the object
+func (o *wrappedObject) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Update the object with the contents of the io.Reader
+func (o *wrappedObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove an object
+func (o *wrappedObject) Remove(ctx context.Context) error {
+	return errorReadOnly
+}
+
+// ------------------------------------------------------------
+
+// Object is a file inside an archive
+type Object struct {
+	fs      *Fs
+	remote  string
+	archive *archive
+	entry   *entry
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(t fs.HashType) (string, error) {
+	return "", fs.ErrHashUnsupported
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	return o.entry.size
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime() time.Time {
+	return o.entry.modTime
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return o.archive.open(ctx, o.entry, offset, limit)
+}
+
+// Update the file with the contents of the io.Reader
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove the file
+func (o *Object) Remove(ctx context.Context) error {
+	return errorReadOnly
+}
+
+// ------------------------------------------------------------
+
+// readCloser is an io.Reader which closes all its closers in order
+// when closed
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close all the closers returning the first error
+func (rc *readCloser) Close() (err error) {
+	for _, closer := range rc.closers {
+		closeErr := closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// skipAndLimit discards offset bytes from in then returns a reader
+// for at most limit bytes of the rest, or all of it if limit < 0
+func skipAndLimit(in io.ReadCloser, offset, limit int64) (io.ReadCloser, error) {
+	if offset > 0 {
+		_, err := io.CopyN(ioutil.Discard, in, offset)
+		if err != nil && err != io.EOF {
+			_ = in.Close()
+			return nil, err
+		}
+	}
+	return fs.NewLimitedReadCloser(in, limit), nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs     = (*Fs)(nil)
+	_ fs.Object = (*Object)(nil)
+	_ fs.Object = (*wrappedObject)(nil)
+)
@@ -0,0 +1,244 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestMain(m *testing.M) {
+	fs.LoadConfig()
+	os.Exit(m.Run())
+}
+
+var (
+	testModTime = time.Date(2017, 8, 1, 12, 30, 46, 0, time.UTC)
+	bigContents = strings.Repeat("rclone archive ", 20000)
+)
+
+// testFiles are the files put in the test archives
+var testFiles = []struct {
+	name     string
+	contents string
+	method   uint16
+}{
+	{"hello.txt", "hello world", zip.Store},
+	{"dir/big.txt", bigContents, zip.Deflate},
+	{"dir/sub/stored.txt", "0123456789", zip.Store},
+	{"../escape.txt", "no escape", zip.Deflate},
+}
+
+// makeZip makes a zip archive of testFiles
+func makeZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range testFiles {
+		hdr := &zip.FileHeader{
+			Name:   file.name,
+			Method: file.method,
+		}
+		hdr.SetModTime(testModTime)
+		w, err := zw.CreateHeader(hdr)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(file.contents))
+		require.NoError(t, err)
+	}
+	_, err := zw.Create("empty/")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// makeTarGz makes a gzipped tar archive of testFiles
+func makeTarGz(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range testFiles {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     file.name,
+			Mode:     0644,
+			Size:     int64(len(file.contents)),
+			ModTime:  testModTime,
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(file.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     "link",
+		Linkname: "hello.txt",
+		Typeflag: tar.TypeSymlink,
+	}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// prepare makes a directory with archives in and a remote called
+// name for it returning a cleanup function
+func prepare(t *testing.T, name string) func() {
+	dir, err := ioutil.TempDir("", "rclone-archive")
+	require.NoError(t, err)
+	write := func(name string, contents []byte) {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0700))
+		require.NoError(t, ioutil.WriteFile(filePath, contents, 0600))
+	}
+	write("bundles/test.zip", makeZip(t))
+	write("bundles/test.tar.gz", makeTarGz(t))
+	write("bundles/plain.txt", []byte("plain"))
+	fs.ConfigFileSet(name, "type", "archive")
+	fs.ConfigFileSet(name, "remote", dir)
+	return func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func listNames(t *testing.T, f fs.Fs, dir string) (names []string) {
+	entries, err := f.List(context.Background(), dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		name := entry.Remote()
+		if _, isDir := entry.(*fs.Dir); isDir {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readObject(t *testing.T, f fs.Fs, remote string, options ...fs.OpenOption) string {
+	o, err := f.NewObject(context.Background(), remote)
+	require.NoError(t, err)
+	in, err := o.Open(context.Background(), options...)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	for _, test := range []struct {
+		in          string
+		archivePath string
+		inner       string
+		ok          bool
+	}{
+		{"", "", "", false},
+		{"dir/file.txt", "", "", false},
+		{"a.zip", "a.zip", "", true},
+		{"dir/A.ZIP/x/y", "dir/A.ZIP", "x/y", true},
+		{"b.tar.gz/c.zip/d", "b.tar.gz", "c.zip/d", true},
+		{"c.tgz/d", "c.tgz", "d", true},
+		{"e.tar", "e.tar", "", true},
+	} {
+		archivePath, inner, ok := splitArchivePath(test.in)
+		assert.Equal(t, test.archivePath, archivePath, test.in)
+		assert.Equal(t, test.inner, inner, test.in)
+		assert.Equal(t, test.ok, ok, test.in)
+	}
+}
+
+func testArchive(t *testing.T, archiveName string, hasEmpty bool) {
+	defer prepare(t, "TestArchive")()
+	f, err := fs.NewFs("TestArchive:bundles")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"plain.txt", "test.tar.gz/", "test.zip/"}, listNames(t, f, ""))
+	root := []string{archiveName + "/dir/", archiveName + "/escape.txt", archiveName + "/hello.txt"}
+	if hasEmpty {
+		root = append(root[:1], append([]string{archiveName + "/empty/"}, root[1:]...)...)
+	}
+	assert.Equal(t, root, listNames(t, f, archiveName))
+	assert.Equal(t, []string{archiveName + "/dir/big.txt", archiveName + "/dir/sub/"}, listNames(t, f, archiveName+"/dir"))
+
+	o, err := f.NewObject(context.Background(), archiveName+"/hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), o.Size())
+	assert.True(t, testModTime.Equal(o.ModTime()), o.ModTime())
+
+	assert.Equal(t, "hello world", readObject(t, f, archiveName+"/hello.txt"))
+	assert.Equal(t, bigContents, readObject(t, f, archiveName+"/dir/big.txt"))
+	assert.Equal(t, "no escape", readObject(t, f, archiveName+"/escape.txt"))
+	assert.Equal(t, "3456789", readObject(t, f, archiveName+"/dir/sub/stored.txt", &fs.SeekOption{Offset: 3}))
+	assert.Equal(t, "234", readObject(t, f, archiveName+"/dir/sub/stored.txt", &fs.RangeOption{Start: 2, End: 4}))
+	assert.Equal(t, bigContents[1000:1010], readObject(t, f, archiveName+"/dir/big.txt", &fs.RangeOption{Start: 1000, End: 1009}))
+
+	_, err = f.NewObject(context.Background(), archiveName+"/missing")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.NewObject(context.Background(), archiveName+"/dir")
+	assert.Equal(t, fs.ErrorNotAFile, err)
+	_, err = f.List(context.Background(), archiveName+"/missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	// Pointing the root at a file in an archive gives its directory
+	f2, err := fs.NewFs("TestArchive:bundles/" + archiveName + "/dir/sub/stored.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "bundles/"+archiveName+"/dir/sub", f2.Root())
+	assert.Equal(t, "0123456789", readObject(t, f2, "stored.txt"))
+}
+
+func TestZip(t *testing.T) {
+	testArchive(t, "test.zip", true)
+}
+
+func TestTarGz(t *testing.T) {
+	testArchive(t, "test.tar.gz", false)
+}
+
+func TestReadOnly(t *testing.T) {
+	defer prepare(t, "TestArchive")()
+	f, err := fs.NewFs("TestArchive:bundles")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.Equal(t, "plain", readObject(t, f, "plain.txt"))
+	o, err := f.NewObject(ctx, "plain.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "plain.txt", o.Remote())
+	assert.Equal(t, errorReadOnly, o.Remove(ctx))
+
+	src := fs.NewStaticObjectInfo("new.txt", time.Now(), 1, true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewBufferString("x"), src)
+	assert.Equal(t, errorReadOnly, err)
+	assert.Equal(t, errorReadOnly, f.Mkdir(ctx, "dir"))
+}
+
+func TestObjectReaderAt(t *testing.T) {
+	defer prepare(t, "TestArchive")()
+	f, err := fs.NewFs("TestArchive:bundles")
+	require.NoError(t, err)
+	o, err := f.(*Fs).wrapped.NewObject(context.Background(), "bundles/test.zip")
+	require.NoError(t, err)
+	contents, err := ioutil.ReadFile(filepath.Join(fs.ConfigFileGet("TestArchive", "remote"), "bundles", "test.zip"))
+	require.NoError(t, err)
+
+	ra := newObjectReaderAt(context.Background(), o)
+	buf := make([]byte, 100)
+	for _, off := range []int64{0, 100, 50, int64(len(contents)) - 100} {
+		n, err := ra.ReadAt(buf, off)
+		require.NoError(t, err)
+		assert.Equal(t, 100, n)
+		assert.Equal(t, contents[off:off+100], buf)
+	}
+	n, err := ra.ReadAt(buf, int64(len(contents))-10)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, "EOF", err.Error())
+}
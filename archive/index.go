@@ -0,0 +1,147 @@
+// The index of the files in an archive
+
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// entry is a file or directory in an archive
+type entry struct {
+	name    string    // path in the archive
+	isDir   bool      // set if this is a directory
+	size    int64     // uncompressed size of the file
+	modTime time.Time // modification time from the archive
+	zipFile *zip.File // the file if this is in a zip archive
+}
+
+// archive is the index of an archive
+type archive struct {
+	kind    archiveKind
+	o       fs.Object         // the archive file
+	size    int64             // size of the archive when it was read
+	modTime time.Time         // modification time of the archive when it was read
+	entries map[string]*entry // files and directories by path
+
+	mu sync.Mutex      // protects ra
+	ra *objectReaderAt // reads the archive for zip archives
+}
+
+// cleanName makes the name of a file in an archive into a path
+// relative to the root of the archive which can't point outside it
+func cleanName(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+// parentDir returns the parent directory of p with "" for the root
+func parentDir(p string) string {
+	parent := path.Dir(p)
+	if parent == "." {
+		return ""
+	}
+	return parent
+}
+
+// readArchive reads the index of the archive o which is of kind
+func readArchive(ctx context.Context, o fs.Object, kind archiveKind) (a *archive, err error) {
+	a = &archive{
+		kind:    kind,
+		o:       o,
+		size:    o.Size(),
+		modTime: o.ModTime(),
+		entries: make(map[string]*entry),
+	}
+	switch kind {
+	case kindZip:
+		err = a.readZip(ctx)
+	case kindTar, kindTarGz:
+		err = a.readTar(ctx)
+	default:
+		err = errors.Errorf("unknown archive kind %d", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// add adds e to the index along with any missing parent directories
+func (a *archive) add(e *entry) {
+	e.name = cleanName(e.name)
+	if e.name == "" {
+		return
+	}
+	if old, found := a.entries[e.name]; found && old.isDir && !e.isDir {
+		fs.Debugf(a.o, "Ignoring file %q which has the same name as a directory", e.name)
+		return
+	}
+	a.entries[e.name] = e
+	for dir := parentDir(e.name); dir != ""; dir = parentDir(dir) {
+		if _, found := a.entries[dir]; found {
+			break
+		}
+		a.entries[dir] = &entry{
+			name:  dir,
+			isDir: true,
+		}
+	}
+}
+
+// list returns the entries of the directory inner in the archive
+// named as if they were in dir of f
+func (a *archive) list(f *Fs, dir string, inner string) (entries fs.DirEntries, err error) {
+	if inner != "" {
+		e, found := a.entries[inner]
+		if !found || !e.isDir {
+			return nil, fs.ErrorDirNotFound
+		}
+	}
+	for name, e := range a.entries {
+		if parentDir(name) != inner {
+			continue
+		}
+		remote := path.Join(dir, path.Base(name))
+		if e.isDir {
+			entries = append(entries, &fs.Dir{
+				Name:  remote,
+				When:  e.modTime,
+				Bytes: -1,
+				Count: -1,
+			})
+		} else {
+			entries = append(entries, a.newObject(f, remote, e))
+		}
+	}
+	return entries, nil
+}
+
+// newObject returns an Object for the entry e called remote in f
+func (a *archive) newObject(f *Fs, remote string, e *entry) *Object {
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		archive: a,
+		entry:   e,
+	}
+}
+
+// open opens the file e in the archive for reading from offset for
+// limit bytes or to the end if limit < 0
+func (a *archive) open(ctx context.Context, e *entry, offset, limit int64) (io.ReadCloser, error) {
+	switch a.kind {
+	case kindZip:
+		return a.openZip(ctx, e, offset, limit)
+	case kindTar, kindTarGz:
+		return a.openTar(ctx, e, offset, limit)
+	}
+	return nil, errors.Errorf("unknown archive kind %d", a.kind)
+}
@@ -0,0 +1,102 @@
+// Reading tar archives by streaming them
+
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/net/context"
+)
+
+// openTarStream opens the archive for streaming returning a tar
+// reader and a closer to close everything when done.
+func (a *archive) openTarStream(ctx context.Context) (tr *tar.Reader, rc *readCloser, err error) {
+	in, err := a.o.Open(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc = &readCloser{
+		Reader:  in,
+		closers: []io.Closer{in},
+	}
+	if a.kind == kindTarGz {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			_ = in.Close()
+			return nil, nil, err
+		}
+		rc.closers = []io.Closer{gz, in}
+		rc.Reader = gz
+	}
+	tr = tar.NewReader(rc.Reader)
+	rc.Reader = tr
+	return tr, rc, nil
+}
+
+// isTarFile returns whether the tar header is for a regular file
+func isTarFile(hdr *tar.Header) bool {
+	return hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA
+}
+
+// readTar reads the whole of the tar archive to index it
+func (a *archive) readTar(ctx context.Context) (err error) {
+	tr, rc, err := a.openTarStream(ctx)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(rc, &err)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case hdr.Typeflag == tar.TypeDir:
+			a.add(&entry{
+				name:    hdr.Name,
+				isDir:   true,
+				modTime: hdr.ModTime,
+			})
+		case isTarFile(hdr):
+			a.add(&entry{
+				name:    hdr.Name,
+				size:    hdr.Size,
+				modTime: hdr.ModTime,
+			})
+		default:
+			fs.Debugf(a.o, "Ignoring %q which isn't a file or directory", hdr.Name)
+		}
+	}
+	return nil
+}
+
+// openTar opens the file e in the tar archive
+//
+// Tar archives can't be read at random so this reads the archive from
+// the start until it finds e.
+func (a *archive) openTar(ctx context.Context, e *entry, offset, limit int64) (io.ReadCloser, error) {
+	tr, rc, err := a.openTarStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			_ = rc.Close()
+			return nil, fs.ErrorObjectNotFound
+		}
+		if err != nil {
+			_ = rc.Close()
+			return nil, err
+		}
+		if isTarFile(hdr) && cleanName(hdr.Name) == e.name {
+			return skipAndLimit(rc, offset, limit)
+		}
+	}
+}
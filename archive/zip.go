@@ -0,0 +1,177 @@
+// Reading zip archives with ranged reads
+
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Sizes of the blocks read by objectReaderAt.  Sequential reads
+// double the block size up to the maximum.
+const (
+	minReadAtBlock = 64 * 1024
+	maxReadAtBlock = 1024 * 1024
+)
+
+// objectReaderAt is an io.ReaderAt which reads an fs.Object with
+// ranged reads.
+//
+// It reads in blocks and keeps the last block read so the many small
+// sequential reads that archive/zip does when reading the central
+// directory don't each need a request.
+type objectReaderAt struct {
+	mu        sync.Mutex
+	ctx       context.Context // context for the reads
+	o         fs.Object       // object being read
+	size      int64           // size of the object
+	buf       []byte          // the last block read
+	bufOffset int64           // offset of buf in the object
+	blockSize int64           // size of the next block to read
+}
+
+// newObjectReaderAt makes an objectReaderAt for o
+func newObjectReaderAt(ctx context.Context, o fs.Object) *objectReaderAt {
+	return &objectReaderAt{
+		ctx:  ctx,
+		o:    o,
+		size: o.Size(),
+	}
+}
+
+// setContext sets the context used for the reads
+func (r *objectReaderAt) setContext(ctx context.Context) {
+	r.mu.Lock()
+	r.ctx = ctx
+	r.mu.Unlock()
+}
+
+// ReadAt reads len(p) bytes at offset off into p
+func (r *objectReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+		if pos >= r.bufOffset && pos < r.bufOffset+int64(len(r.buf)) {
+			n += copy(p[n:], r.buf[pos-r.bufOffset:])
+			continue
+		}
+		err = r.fill(pos, int64(len(p)-n))
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// fill reads the block at pos into the buffer making it at least
+// want bytes long if possible
+//
+// Call with the lock held
+func (r *objectReaderAt) fill(pos, want int64) error {
+	if r.buf != nil && pos == r.bufOffset+int64(len(r.buf)) {
+		r.blockSize *= 2
+		if r.blockSize > maxReadAtBlock {
+			r.blockSize = maxReadAtBlock
+		}
+	} else {
+		r.blockSize = minReadAtBlock
+	}
+	size := r.blockSize
+	if want > size {
+		size = want
+	}
+	if pos+size > r.size {
+		size = r.size - pos
+	}
+	in, err := r.o.Open(r.ctx, &fs.RangeOption{Start: pos, End: pos + size - 1})
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, size)
+	_, err = io.ReadFull(in, buf)
+	closeErr := in.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to read archive")
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	r.buf, r.bufOffset = buf, pos
+	return nil
+}
+
+// readZip reads the central directory of the zip archive into the
+// index
+func (a *archive) readZip(ctx context.Context) error {
+	a.ra = newObjectReaderAt(ctx, a.o)
+	zr, err := zip.NewReader(a.ra, a.size)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		a.add(&entry{
+			name:    zf.Name,
+			isDir:   strings.HasSuffix(zf.Name, "/"),
+			size:    int64(zf.UncompressedSize64),
+			modTime: zf.ModTime(),
+			zipFile: zf,
+		})
+	}
+	return nil
+}
+
+// openZip opens the file e in the zip archive
+//
+// Stored files are read with a ranged read of just the part required
+// and deflated files with a ranged read of the compressed data.
+func (a *archive) openZip(ctx context.Context, e *entry, offset, limit int64) (io.ReadCloser, error) {
+	zf := e.zipFile
+	// Find the start of the data which needs the local header
+	a.mu.Lock()
+	a.ra.setContext(ctx)
+	dataOffset, err := zf.DataOffset()
+	a.mu.Unlock()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find %q in archive", e.name)
+	}
+	compressedSize := int64(zf.CompressedSize64)
+	if compressedSize == 0 || offset >= e.size {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	switch zf.Method {
+	case zip.Store:
+		end := dataOffset + compressedSize - 1
+		if limit >= 0 && offset+limit < e.size {
+			end = dataOffset + offset + limit - 1
+		}
+		if end < dataOffset+offset {
+			return ioutil.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return a.o.Open(ctx, &fs.RangeOption{Start: dataOffset + offset, End: end})
+	case zip.Deflate:
+		in, err := a.o.Open(ctx, &fs.RangeOption{Start: dataOffset, End: dataOffset + compressedSize - 1})
+		if err != nil {
+			return nil, err
+		}
+		decompressor := flate.NewReader(in)
+		rc := &readCloser{
+			Reader:  decompressor,
+			closers: []io.Closer{decompressor, in},
+		}
+		return skipAndLimit(rc, offset, limit)
+	}
+	return nil, errors.Errorf("unsupported compression method %d for %q", zf.Method, e.name)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/rest"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // largeUpload is used to control the upload of large files which need chunking
@@ -31,7 +32,7 @@ type largeUpload struct {
 }
 
 // newLargeUpload starts an upload of object o from in with metadata in src
-func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *largeUpload, err error) {
+func (f *Fs) newLargeUpload(ctx context.Context, o *Object, in io.Reader, src fs.ObjectInfo) (up *largeUpload, err error) {
 	remote := o.remote
 	size := src.Size()
 	parts := size / int64(chunkSize)
@@ -46,7 +47,7 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 		Method: "POST",
 		Path:   "/b2_start_large_file",
 	}
-	bucketID, err := f.getBucketID()
+	bucketID, err := f.getBucketID(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -63,9 +64,9 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 		request.Info[sha1Key] = calculatedSha1
 	}
 	var response api.StartLargeFileResponse
-	err = f.pacer.Call(func() (bool, error) {
-		resp, err := f.srv.CallJSON(&opts, &request, &response)
-		return f.shouldRetry(resp, err)
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, &request, &response)
+		return f.shouldRetry(ctx, resp, err)
 	})
 	if err != nil {
 		return nil, err
@@ -85,7 +86,7 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 // getUploadURL returns the upload info with the UploadURL and the AuthorizationToken
 //
 // This should be returned with returnUploadURL when finished
-func (up *largeUpload) getUploadURL() (upload *api.GetUploadPartURLResponse, err error) {
+func (up *largeUpload) getUploadURL(ctx context.Context) (upload *api.GetUploadPartURLResponse, err error) {
 	up.uploadMu.Lock()
 	defer up.uploadMu.Unlock()
 	if len(up.uploads) == 0 {
@@ -96,9 +97,9 @@ func (up *largeUpload) getUploadURL() (upload *api.GetUploadPartURLResponse, err
 		var request = api.GetUploadPartURLRequest{
 			ID: up.id,
 		}
-		err := up.f.pacer.Call(func() (bool, error) {
-			resp, err := up.f.srv.CallJSON(&opts, &request, &upload)
-			return up.f.shouldRetry(resp, err)
+		err := up.f.pacer.Call(ctx, func() (bool, error) {
+			resp, err := up.f.srv.CallJSON(ctx, &opts, &request, &upload)
+			return up.f.shouldRetry(ctx, resp, err)
 		})
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to get upload URL")
@@ -127,15 +128,15 @@ func (up *largeUpload) clearUploadURL() {
 }
 
 // Transfer a chunk
-func (up *largeUpload) transferChunk(part int64, body []byte) error {
+func (up *largeUpload) transferChunk(ctx context.Context, part int64, body []byte) error {
 	calculatedSHA1 := fmt.Sprintf("%x", sha1.Sum(body))
 	up.sha1s[part-1] = calculatedSHA1
 	size := int64(len(body))
-	err := up.f.pacer.Call(func() (bool, error) {
+	err := up.f.pacer.Call(ctx, func() (bool, error) {
 		fs.Debugf(up.o, "Sending chunk %d length %d", part, len(body))
 
 		// Get upload URL
-		upload, err := up.getUploadURL()
+		upload, err := up.getUploadURL(ctx)
 		if err != nil {
 			return false, err
 		}
@@ -177,8 +178,8 @@ func (up *largeUpload) transferChunk(part int64, body []byte) error {
 
 		var response api.UploadPartResponse
 
-		resp, err := up.f.srv.CallJSON(&opts, nil, &response)
-		retry, err := up.f.shouldRetry(resp, err)
+		resp, err := up.f.srv.CallJSON(ctx, &opts, nil, &response)
+		retry, err := up.f.shouldRetry(ctx, resp, err)
 		// On retryable error clear PartUploadURL
 		if retry {
 			fs.Debugf(up.o, "Clearing part upload URL because of error: %v", err)
@@ -196,7 +197,7 @@ func (up *largeUpload) transferChunk(part int64, body []byte) error {
 }
 
 // finish closes off the large upload
-func (up *largeUpload) finish() error {
+func (up *largeUpload) finish(ctx context.Context) error {
 	opts := rest.Opts{
 		Method: "POST",
 		Path:   "/b2_finish_large_file",
@@ -206,9 +207,9 @@ func (up *largeUpload) finish() error {
 		SHA1s: up.sha1s,
 	}
 	var response api.FileInfo
-	err := up.f.pacer.Call(func() (bool, error) {
-		resp, err := up.f.srv.CallJSON(&opts, &request, &response)
-		return up.f.shouldRetry(resp, err)
+	err := up.f.pacer.Call(ctx, func() (bool, error) {
+		resp, err := up.f.srv.CallJSON(ctx, &opts, &request, &response)
+		return up.f.shouldRetry(ctx, resp, err)
 	})
 	if err != nil {
 		return err
@@ -217,7 +218,7 @@ func (up *largeUpload) finish() error {
 }
 
 // cancel aborts the large upload
-func (up *largeUpload) cancel() error {
+func (up *largeUpload) cancel(ctx context.Context) error {
 	opts := rest.Opts{
 		Method: "POST",
 		Path:   "/b2_cancel_large_file",
@@ -226,15 +227,15 @@ func (up *largeUpload) cancel() error {
 		ID: up.id,
 	}
 	var response api.CancelLargeFileResponse
-	err := up.f.pacer.Call(func() (bool, error) {
-		resp, err := up.f.srv.CallJSON(&opts, &request, &response)
-		return up.f.shouldRetry(resp, err)
+	err := up.f.pacer.Call(ctx, func() (bool, error) {
+		resp, err := up.f.srv.CallJSON(ctx, &opts, &request, &response)
+		return up.f.shouldRetry(ctx, resp, err)
 	})
 	return err
 }
 
 // Upload uploads the chunks from the input
-func (up *largeUpload) Upload() error {
+func (up *largeUpload) Upload(ctx context.Context) error {
 	fs.Debugf(up.o, "Starting upload of large file in %d chunks (id %q)", up.parts, up.id)
 	remaining := up.size
 	errs := make(chan error, 1)
@@ -270,7 +271,7 @@ outer:
 		go func(part int64, buf []byte) {
 			defer wg.Done()
 			defer up.f.putUploadBlock(buf)
-			err := up.transferChunk(part, buf)
+			err := up.transferChunk(ctx, part, buf)
 			if err != nil {
 				select {
 				case errs <- err:
@@ -290,7 +291,7 @@ outer:
 	}
 	if err != nil {
 		fs.Debugf(up.o, "Cancelling large file upload due to error: %v", err)
-		cancelErr := up.cancel()
+		cancelErr := up.cancel(ctx)
 		if cancelErr != nil {
 			fs.Errorf(up.o, "Failed to cancel large file upload: %v", cancelErr)
 		}
@@ -298,5 +299,5 @@ outer:
 	}
 	// Check any errors
 	fs.Debugf(up.o, "Finishing large file upload")
-	return up.finish()
+	return up.finish(ctx)
 }
@@ -31,7 +31,17 @@ docs = [
     "yandex.md",
     "sftp.md",
     "crypt.md",
+    "cache.md",
+    "union.md",
+    "chunker.md",
+    "compress.md",
+    "hasher.md",
+    "alias.md",
+    "archive.md",
     "ftp.md",
+    "http.md",
+    "webdav.md",
+    "memory.md",
     "local.md",
     "changelog.md",
     "bugs.md",
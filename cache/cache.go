@@ -0,0 +1,493 @@
+// Package cache provides a wrapper for an Fs which caches directory
+// listings, object metadata and file data on local disk
+package cache
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Constants
+const (
+	defaultChunkSize      = 5 * 1024 * 1024
+	defaultChunkTotalSize = 10 * 1024 * 1024 * 1024
+	defaultInfoAge        = 6 * time.Hour
+)
+
+// Globals
+var (
+	// Flags
+	cacheDbPurge = fs.BoolP("cache-db-purge", "", false, "Purge the cache storage for the remote before using it.")
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "cache",
+		Description: "Cache a remote",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote to cache.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\" (not recommended).",
+		}, {
+			Name: "chunk_size",
+			Help: "The size of a chunk of file data. Lower value good for slow connections but can affect seamless reading.\nDefault: 5M",
+			Examples: []fs.OptionExample{
+				{
+					Value: "1m",
+					Help:  "1MB",
+				}, {
+					Value: "5M",
+					Help:  "5 MB",
+				}, {
+					Value: "10M",
+					Help:  "10 MB",
+				},
+			},
+			Optional: true,
+		}, {
+			Name: "info_age",
+			Help: "How long to cache directory listings and file metadata for.\nDefault: 6h",
+			Examples: []fs.OptionExample{
+				{
+					Value: "1h",
+					Help:  "1 hour",
+				}, {
+					Value: "24h",
+					Help:  "24 hours",
+				}, {
+					Value: "48h",
+					Help:  "48 hours",
+				},
+			},
+			Optional: true,
+		}, {
+			Name: "chunk_total_size",
+			Help: "The maximum size of stored chunks. When the storage grows beyond this size, the least recently used chunks will be deleted.\nDefault: 10G",
+			Examples: []fs.OptionExample{
+				{
+					Value: "500M",
+					Help:  "500 MB",
+				}, {
+					Value: "1G",
+					Help:  "1 GB",
+				}, {
+					Value: "10G",
+					Help:  "10 GB",
+				},
+			},
+			Optional: true,
+		}},
+	})
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name      string
+	root      string
+	features  *fs.Features  // optional features
+	cache     *persistent   // listings and chunks stored on disk
+	chunkSize int64         // size of the chunks of file data
+	infoAge   time.Duration // how long listings are valid for
+}
+
+// NewFs contstructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := fs.ConfigFileGet(name, "remote")
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point cache remote at itself - check the value of the remote setting")
+	}
+	chunkSize := fs.SizeSuffix(defaultChunkSize)
+	if value := fs.ConfigFileGet(name, "chunk_size"); value != "" {
+		err := chunkSize.Set(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse chunk_size %q", value)
+		}
+	}
+	if chunkSize <= 0 {
+		return nil, errors.Errorf("chunk_size must be > 0 - was %v", chunkSize)
+	}
+	chunkTotalSize := fs.SizeSuffix(defaultChunkTotalSize)
+	if value := fs.ConfigFileGet(name, "chunk_total_size"); value != "" {
+		err := chunkTotalSize.Set(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse chunk_total_size %q", value)
+		}
+	}
+	infoAge := defaultInfoAge
+	if value := fs.ConfigFileGet(name, "info_age"); value != "" {
+		var err error
+		infoAge, err = fs.ParseDuration(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse info_age %q", value)
+		}
+	}
+	storage, err := getPersistent(name, int64(chunkTotalSize), *cacheDbPurge)
+	if err != nil {
+		return nil, err
+	}
+
+	rpath = strings.Trim(rpath, "/")
+	remotePath := path.Join(remote, rpath)
+	wrappedFs, wrapErr := fs.NewFs(remotePath)
+	if wrapErr != fs.ErrorIsFile && wrapErr != nil {
+		return nil, errors.Wrapf(wrapErr, "failed to make remote %q to wrap", remotePath)
+	}
+	root := rpath
+	if wrapErr == fs.ErrorIsFile {
+		root = parentDir(rpath)
+	}
+	f := &Fs{
+		Fs:        wrappedFs,
+		name:      name,
+		root:      root,
+		cache:     storage,
+		chunkSize: int64(chunkSize),
+		infoAge:   infoAge,
+	}
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from wrappedFs
+	f.features = (&fs.Features{
+		CaseInsensitive: true,
+		DuplicateFiles:  false,
+		ReadMimeType:    false, // MimeTypes are not cached
+		WriteMimeType:   true,
+	}).Fill(f).Mask(wrappedFs)
+	return f, wrapErr
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Cached drive '%s:%s'", f.name, f.root)
+}
+
+// key returns the path of remote relative to the wrapped remote
+// which is used to identify it in the cache
+func (f *Fs) key(remote string) string {
+	return path.Join(f.root, remote)
+}
+
+// expireParents expires the listings of the directories containing
+// remote in the cache
+func (f *Fs) expireParents(remote string) {
+	f.cache.expireParents(f.key(remote))
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	key := f.key(dir)
+	if cd, ok := f.cache.getDir(key, f.infoAge); ok {
+		fs.Debugf(dir, "cache: list from cache")
+		for _, entry := range cd.Entries {
+			remote := path.Join(dir, entry.Name)
+			if entry.IsDir {
+				entries = append(entries, &fs.Dir{
+					Name:  remote,
+					When:  entry.ModTime,
+					Bytes: entry.Size,
+					Count: entry.Count,
+				})
+			} else {
+				entries = append(entries, f.newCachedObject(remote, entry))
+			}
+		}
+		return entries, nil
+	}
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	cachedEntries := make([]cachedEntry, 0, len(entries))
+	for i, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			cachedEntries = append(cachedEntries, cachedEntry{
+				Name:    path.Base(x.Remote()),
+				Size:    x.Size(),
+				ModTime: x.ModTime(),
+			})
+			entries[i] = f.newObject(x)
+		case *fs.Dir:
+			cachedEntries = append(cachedEntries, cachedEntry{
+				Name:    path.Base(x.Name),
+				IsDir:   true,
+				Size:    x.Bytes,
+				ModTime: x.When,
+				Count:   x.Count,
+			})
+		default:
+			return nil, errors.Errorf("Unknown object type %T", entry)
+		}
+	}
+	err = f.cache.putDir(key, cachedEntries)
+	if err != nil {
+		fs.Errorf(dir, "cache: %v", err)
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.
+//
+// If the listing of the directory containing remote is in the cache
+// then it is used to find the object without calling the wrapped
+// remote.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if cd, ok := f.cache.getDir(f.key(parentDir(remote)), f.infoAge); ok {
+		leaf := path.Base(remote)
+		for _, entry := range cd.Entries {
+			if entry.Name == leaf && !entry.IsDir {
+				return f.newCachedObject(remote, entry), nil
+			}
+		}
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	defer f.expireParents(src.Remote())
+	o, err := f.Fs.Put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	defer f.expireParents(dir)
+	return f.Fs.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	err := f.Fs.Rmdir(ctx, dir)
+	f.cache.expireDir(f.key(dir))
+	f.expireParents(dir)
+	return err
+}
+
+// Purge all files in the root and the root directory
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge(ctx context.Context) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	err := do(ctx)
+	f.cache.expireTree(f.root)
+	f.cache.expireParents(f.root)
+	return err
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, err := o.wrapped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer f.expireParents(remote)
+	oResult, err := do(ctx, srcObj, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(oResult), nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, err := o.wrapped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer o.expire()
+	defer f.expireParents(remote)
+	oResult, err := do(ctx, srcObj, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(oResult), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	err := do(ctx, srcFs.Fs, srcRemote, dstRemote)
+	srcKey, dstKey := srcFs.key(srcRemote), f.key(dstRemote)
+	f.cache.expireTree(srcKey)
+	f.cache.expireParents(srcKey)
+	f.cache.expireTree(dstKey)
+	f.cache.expireParents(dstKey)
+	return err
+}
+
+// PutUnchecked uploads the object
+//
+// This will create a duplicate if we upload a new file without
+// checking to see if there is one already - use Put() for that.
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("can't PutUnchecked")
+	}
+	defer f.expireParents(src.Remote())
+	o, err := do(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// CleanUp the trash in the Fs
+//
+// Implement this if you have a way of emptying the trash or
+// otherwise cleaning up old versions of files.
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do(ctx)
+}
+
+// DirChangeNotify calls the passed function with a path of a
+// directory that has had changes in the wrapped remote, expiring
+// that directory in the cache first.
+func (f *Fs) DirChangeNotify(notifyFunc func(string), pollInterval time.Duration) chan bool {
+	do := f.Fs.Features().DirChangeNotify
+	if do == nil {
+		return nil
+	}
+	return do(func(dir string) {
+		f.cache.expireDir(f.key(dir))
+		notifyFunc(dir)
+	}, pollInterval)
+}
+
+// DirCacheFlush expires all the cached listings of this Fs and
+// flushes the directory cache of the wrapped remote if it has one
+func (f *Fs) DirCacheFlush() {
+	f.cache.expireTree(f.root)
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// Stats returns statistics about the cache storage for this remote
+func (f *Fs) Stats() (map[string]interface{}, error) {
+	return f.cache.stats()
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs                = (*Fs)(nil)
+	_ fs.Purger            = (*Fs)(nil)
+	_ fs.Copier            = (*Fs)(nil)
+	_ fs.Mover             = (*Fs)(nil)
+	_ fs.DirMover          = (*Fs)(nil)
+	_ fs.PutUncheckeder    = (*Fs)(nil)
+	_ fs.CleanUpper        = (*Fs)(nil)
+	_ fs.DirChangeNotifier = (*Fs)(nil)
+	_ fs.DirCacheFlusher   = (*Fs)(nil)
+	_ fs.UnWrapper         = (*Fs)(nil)
+)
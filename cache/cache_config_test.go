@@ -0,0 +1,20 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestCache: remote
+func init() {
+	tempdir := filepath.Join(os.TempDir(), "rclone-cache-test")
+	name := "TestCache"
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "cache"},
+		{Name: name, Key: "remote", Value: tempdir},
+		{Name: name, Key: "chunk_size", Value: "1k"},
+		{Name: name, Key: "info_age", Value: "1h"},
+	}
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newTestFs makes a cache remote wrapping a local temporary
+// directory returning the Fs, the directory and a cleanup function
+func newTestFs(t *testing.T, name string, options map[string]string) (*Fs, string, func()) {
+	r := fstest.NewWrapperRemote(t, "cache", name, 1, options)
+	return r.Fs.(*Fs), r.Dirs[0], r.Finalise
+}
+
+func getStats(t *testing.T, f *Fs) map[string]interface{} {
+	stats, err := f.Stats()
+	require.NoError(t, err)
+	return stats
+}
+
+func TestCacheListing(t *testing.T) {
+	ctx := context.Background()
+	f, remoteDir, cleanup := newTestFs(t, "TestCacheListing", map[string]string{"info_age": "1h"})
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "file1"), []byte("hello"), 0600))
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+
+	// A file added behind the cache's back isn't seen
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "file2"), []byte("potato"), 0600))
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	_, err = f.NewObject(ctx, "file2")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// The object metadata comes from the cache
+	o, err := f.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+	assert.Nil(t, o.(*Object).o)
+
+	stats := getStats(t, f)
+	assert.Equal(t, 1, stats["dirs"])
+	assert.Equal(t, int64(1), stats["list_misses"])
+	assert.Equal(t, int64(3), stats["list_hits"])
+
+	// Until the cache is flushed
+	f.DirCacheFlush()
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// Listings in the cache expire
+	f.infoAge = 0
+	require.NoError(t, os.Remove(filepath.Join(remoteDir, "file2")))
+	time.Sleep(10 * time.Millisecond)
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestCacheExpireOnWrite(t *testing.T) {
+	ctx := context.Background()
+	f, _, cleanup := newTestFs(t, "TestCacheExpireOnWrite", nil)
+	defer cleanup()
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+
+	// Writing through the cache expires the parent listings
+	src := fs.NewStaticObjectInfo("dir/file", time.Now(), 3, true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader("abc"), src)
+	require.NoError(t, err)
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	entries, err = f.List(ctx, "dir")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+
+	o, err := f.NewObject(ctx, "dir/file")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	entries, err = f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestCacheChunks(t *testing.T) {
+	ctx := context.Background()
+	f, remoteDir, cleanup := newTestFs(t, "TestCacheChunks", map[string]string{
+		"chunk_size":       "10b",
+		"chunk_total_size": "35b",
+	})
+	defer cleanup()
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "file"), data, 0600))
+
+	read := func(options ...fs.OpenOption) []byte {
+		o, err := f.NewObject(ctx, "file")
+		require.NoError(t, err)
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return got
+	}
+
+	assert.Equal(t, data[15:32], read(&fs.RangeOption{Start: 15, End: 31}))
+	stats := getStats(t, f)
+	assert.Equal(t, int64(0), stats["chunk_hits"])
+	assert.Equal(t, int64(3), stats["chunk_misses"])
+	assert.Equal(t, 3, stats["chunks"])
+	assert.Equal(t, int64(30), stats["chunk_total_size"])
+
+	// Reading again uses the cached chunks
+	assert.Equal(t, data[12:25], read(&fs.SeekOption{Offset: 12}, &fs.RangeOption{Start: 12, End: 24}))
+	stats = getStats(t, f)
+	assert.Equal(t, int64(2), stats["chunk_hits"])
+
+	// Reading the whole file removes the least recently used
+	// chunks to keep within the limit
+	assert.Equal(t, data, read())
+	stats = getStats(t, f)
+	assert.Equal(t, 4, stats["chunks"])
+	assert.Equal(t, int64(32), stats["chunk_total_size"])
+
+	// The index of the chunks is read back from the database
+	p, err := newPersistent(f.cache.db, 35, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(p.chunks))
+	assert.Equal(t, int64(32), p.chunkTotal)
+
+	// Changing the file means the cached chunks aren't used
+	newData := []byte("potato")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "file"), newData, 0600))
+	f.DirCacheFlush()
+	assert.Equal(t, newData, read())
+}
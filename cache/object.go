@@ -0,0 +1,334 @@
+// Objects for the cache backend
+
+package cache
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Object describes a cached object
+//
+// The metadata is read from the cache so the wrapped object is only
+// looked up when it is needed.
+type Object struct {
+	f       *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+
+	mu     sync.Mutex
+	o      fs.Object              // the wrapped object - nil until needed
+	hashes map[fs.HashType]string // hashes read from the wrapped object
+}
+
+// newObject wraps the object o from the wrapped remote
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		f:       f,
+		remote:  o.Remote(),
+		size:    o.Size(),
+		modTime: o.ModTime(),
+		o:       o,
+	}
+}
+
+// newCachedObject makes an Object at remote from a cached entry
+func (f *Fs) newCachedObject(remote string, entry cachedEntry) *Object {
+	return &Object{
+		f:       f,
+		remote:  remote,
+		size:    entry.Size,
+		modTime: entry.ModTime,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.modTime
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.size
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// wrapped returns the wrapped object, looking it up if necessary
+func (o *Object) wrapped(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o != nil {
+		return o.o, nil
+	}
+	wrapped, err := o.f.Fs.NewObject(ctx, o.remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find object in wrapped remote")
+	}
+	o.o = wrapped
+	return wrapped, nil
+}
+
+// UnWrap returns the wrapped Object, or nil if it can't be found
+func (o *Object) UnWrap() fs.Object {
+	wrapped, err := o.wrapped(context.Background())
+	if err != nil {
+		fs.Debugf(o, "cache: %v", err)
+		return nil
+	}
+	return wrapped
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+func (o *Object) Hash(hashType fs.HashType) (string, error) {
+	o.mu.Lock()
+	hash, ok := o.hashes[hashType]
+	o.mu.Unlock()
+	if ok {
+		return hash, nil
+	}
+	wrapped, err := o.wrapped(context.Background())
+	if err != nil {
+		return "", err
+	}
+	hash, err = wrapped.Hash(hashType)
+	if err != nil {
+		return "", err
+	}
+	o.mu.Lock()
+	if o.hashes == nil {
+		o.hashes = make(map[fs.HashType]string)
+	}
+	o.hashes[hashType] = hash
+	o.mu.Unlock()
+	return hash, nil
+}
+
+// fingerprint identifies this version of the object in the chunk
+// storage
+func (o *Object) fingerprint() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return fmt.Sprintf("%s\x00%d\x00%d", o.f.key(o.remote), o.size, o.modTime.UnixNano())
+}
+
+// expire removes the cached data of this version of the object and
+// the listings of the directories containing it
+func (o *Object) expire() {
+	o.f.cache.removeChunks(o.fingerprint())
+	o.f.expireParents(o.remote)
+}
+
+// refresh reads the metadata from the wrapped object after it has
+// been changed
+func (o *Object) refresh(wrapped fs.Object) {
+	o.mu.Lock()
+	o.o = wrapped
+	o.size = wrapped.Size()
+	o.modTime = wrapped.ModTime()
+	o.hashes = nil
+	o.mu.Unlock()
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	wrapped, err := o.wrapped(ctx)
+	if err != nil {
+		return err
+	}
+	o.f.cache.removeChunks(o.fingerprint())
+	defer o.f.expireParents(o.remote)
+	err = wrapped.SetModTime(ctx, t)
+	if err != nil {
+		return err
+	}
+	o.refresh(wrapped)
+	return nil
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+//
+// The data is read in chunks which are stored in the cache so
+// subsequent reads don't need to fetch them from the wrapped remote.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	size := o.Size()
+	if size < 0 {
+		// Can't chunk objects of unknown size so read them directly
+		wrapped, err := o.wrapped(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return wrapped.Open(ctx, options...)
+	}
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	end := size
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return &chunkReader{
+		ctx:         ctx,
+		o:           o,
+		fingerprint: o.fingerprint(),
+		offset:      offset,
+		end:         end,
+	}, nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	wrapped, err := o.wrapped(ctx)
+	if err != nil {
+		return err
+	}
+	o.f.cache.removeChunks(o.fingerprint())
+	defer o.f.expireParents(o.remote)
+	err = wrapped.Update(ctx, in, src, options...)
+	if err != nil {
+		return err
+	}
+	o.refresh(wrapped)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	wrapped, err := o.wrapped(ctx)
+	if err != nil {
+		return err
+	}
+	defer o.expire()
+	return wrapped.Remove(ctx)
+}
+
+// chunkReader reads an object a chunk at a time using the chunks in
+// the cache where possible
+type chunkReader struct {
+	ctx         context.Context
+	o           *Object
+	fingerprint string
+	offset      int64  // offset of the next read
+	end         int64  // offset to stop reading at
+	chunk       []byte // the current chunk
+	chunkStart  int64  // offset of the current chunk
+}
+
+// getChunk returns the chunk starting at offset, reading it from the
+// wrapped object and storing it in the cache if necessary
+func (r *chunkReader) getChunk(offset int64) ([]byte, error) {
+	f := r.o.f
+	if data, ok := f.cache.getChunk(r.fingerprint, offset); ok {
+		return data, nil
+	}
+	wrapped, err := r.o.wrapped(r.ctx)
+	if err != nil {
+		return nil, err
+	}
+	size := f.chunkSize
+	if objectSize := r.o.Size(); offset+size > objectSize {
+		size = objectSize - offset
+	}
+	in, err := wrapped.Open(r.ctx, &fs.RangeOption{Start: offset, End: offset + size - 1})
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	n, err := io.ReadFull(in, data)
+	closeErr := in.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read chunk")
+	}
+	if closeErr != nil {
+		return nil, errors.Wrap(closeErr, "failed to close chunk")
+	}
+	data = data[:n]
+	err = f.cache.putChunk(r.fingerprint, offset, data)
+	if err != nil {
+		fs.Errorf(r.o, "cache: %v", err)
+	}
+	return data, nil
+}
+
+// Read reads up to len(p) bytes into p
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	if r.offset >= r.end {
+		return 0, io.EOF
+	}
+	chunkStart := r.offset - r.offset%r.o.f.chunkSize
+	if r.chunk == nil || chunkStart != r.chunkStart {
+		r.chunk, err = r.getChunk(chunkStart)
+		if err != nil {
+			r.chunk = nil
+			return 0, err
+		}
+		r.chunkStart = chunkStart
+	}
+	i := r.offset - r.chunkStart
+	if i >= int64(len(r.chunk)) {
+		// The object was shorter than expected
+		return 0, io.EOF
+	}
+	data := r.chunk[i:]
+	if remaining := r.end - r.offset; int64(len(data)) > remaining {
+		data = data[:remaining]
+	}
+	n = copy(p, data)
+	r.offset += int64(n)
+	return n, nil
+}
+
+// Close the reader
+func (r *chunkReader) Close() error {
+	r.chunk = nil
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object     = (*Object)(nil)
+	_ io.ReadCloser = (*chunkReader)(nil)
+)
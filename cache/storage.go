@@ -0,0 +1,381 @@
+// Persistent storage for the cache backend
+
+package cache
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/kv"
+	"github.com/pkg/errors"
+)
+
+// Names of the buckets in the database
+const (
+	dirsBucket   = "dirs"   // directory listings by dirKey
+	chunksBucket = "chunks" // chunks of file data by chunkKey
+)
+
+// When the chunks grow beyond the limit the least recently used are
+// removed until they are below this fraction of it so that cleaning
+// isn't needed for every chunk written.
+const lowWaterMark = 0.9
+
+// cachedEntry is an entry in a cached directory listing
+type cachedEntry struct {
+	Name    string    // leaf name of the entry
+	IsDir   bool      // set if the entry is a directory
+	Size    int64     // size of the object or directory
+	ModTime time.Time // modification time of the object or directory
+	Count   int64     // number of objects in a directory
+}
+
+// cachedDir is a directory listing as stored in the database
+type cachedDir struct {
+	Dir      string        // path of the directory relative to the wrapped remote
+	CachedAt time.Time     // when the listing was read
+	Entries  []cachedEntry // the entries in the directory
+}
+
+// storedChunk is the index entry for a chunk in the database
+type storedChunk struct {
+	key   string    // key of the chunk in the database
+	size  int64     // size of the chunk data
+	atime time.Time // when the chunk was last used
+}
+
+// persistent stores directory listings and chunks of file data in a
+// database on disk so they survive between runs of rclone.
+//
+// Directory listings are stored as JSON keyed by the directory path.
+// Chunks are keyed by the MD5 of the fingerprint of the object and
+// the offset, where the fingerprint identifies the remote, size and
+// modification time of the object so chunks of changed objects are
+// never used.  Each chunk is stored with the time it was written.
+//
+// An index of the chunks is kept in memory so the least recently
+// used chunks can be found without reading the database.
+type persistent struct {
+	db *kv.DB
+
+	mu            sync.Mutex              // protects the variables below
+	maxChunkTotal int64                   // remove chunks when there are more than this many bytes
+	chunkTotal    int64                   // bytes of chunks stored
+	chunks        map[string]*storedChunk // index of the chunks by key
+	listHits      int64                   // number of listings read from the cache
+	listMisses    int64                   // number of listings not found or expired
+	chunkHits     int64                   // number of chunks read from the cache
+	chunkMisses   int64                   // number of chunks not in the cache
+}
+
+// storedChunks sorts chunks by access time, oldest first
+type storedChunks []*storedChunk
+
+func (sc storedChunks) Len() int           { return len(sc) }
+func (sc storedChunks) Swap(i, j int)      { sc[i], sc[j] = sc[j], sc[i] }
+func (sc storedChunks) Less(i, j int) bool { return sc[i].atime.Before(sc[j].atime) }
+
+var (
+	storagesMu sync.Mutex
+	storages   = map[*kv.DB]*persistent{}
+)
+
+// getPersistent returns the storage for the remote called name,
+// creating it if necessary.
+//
+// All the Fs made from the same remote share the same storage.
+func getPersistent(name string, maxChunkTotal int64, purge bool) (*persistent, error) {
+	storagesMu.Lock()
+	defer storagesMu.Unlock()
+	db, err := kv.Open("cache-backend", name)
+	if err != nil {
+		return nil, err
+	}
+	if p := storages[db]; p != nil {
+		p.mu.Lock()
+		p.maxChunkTotal = maxChunkTotal
+		p.mu.Unlock()
+		return p, nil
+	}
+	p, err := newPersistent(db, maxChunkTotal, purge)
+	if err != nil {
+		return nil, err
+	}
+	storages[db] = p
+	return p, nil
+}
+
+// newPersistent makes the storage in db, removing its contents first
+// if purge is set
+func newPersistent(db *kv.DB, maxChunkTotal int64, purge bool) (*persistent, error) {
+	p := &persistent{
+		db:            db,
+		maxChunkTotal: maxChunkTotal,
+		chunks:        make(map[string]*storedChunk),
+	}
+	if purge {
+		fs.Infof(nil, "cache: purging storage %q", db.Path())
+		err := db.Purge()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to purge cache storage")
+		}
+	}
+	err := db.ForEach(chunksBucket, func(key string, value []byte) error {
+		if len(value) < 8 {
+			return nil
+		}
+		chunk := &storedChunk{
+			key:   key,
+			size:  int64(len(value) - 8),
+			atime: time.Unix(0, int64(binary.BigEndian.Uint64(value))),
+		}
+		p.chunks[key] = chunk
+		p.chunkTotal += chunk.size
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read chunk index")
+	}
+	fs.Debugf(nil, "cache: storage %q has %v of chunks", db.Path(), fs.SizeSuffix(p.chunkTotal))
+	return p, nil
+}
+
+// md5hex returns the hex MD5 of s
+func md5hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// dirKey returns the key of the listing of dir.  The keys start
+// with "/" as the root can't have an empty key.
+func dirKey(dir string) string {
+	return "/" + dir
+}
+
+// getDir returns the listing of dir if it is in the cache and is
+// younger than maxAge
+func (p *persistent) getDir(dir string, maxAge time.Duration) (*cachedDir, bool) {
+	data, err := p.db.Get(dirsBucket, dirKey(dir))
+	if err == nil && data != nil {
+		cd := new(cachedDir)
+		err = json.Unmarshal(data, cd)
+		if err == nil && time.Since(cd.CachedAt) <= maxAge {
+			p.mu.Lock()
+			p.listHits++
+			p.mu.Unlock()
+			return cd, true
+		}
+		// Expired or bad so remove it
+		if err != nil {
+			fs.Debugf(dir, "cache: ignoring bad listing: %v", err)
+		}
+		p.expireDir(dir)
+	} else if err != nil {
+		fs.Debugf(dir, "cache: failed to read listing: %v", err)
+	}
+	p.mu.Lock()
+	p.listMisses++
+	p.mu.Unlock()
+	return nil, false
+}
+
+// putDir stores the listing of dir
+func (p *persistent) putDir(dir string, entries []cachedEntry) error {
+	data, err := json.Marshal(&cachedDir{
+		Dir:      dir,
+		CachedAt: time.Now(),
+		Entries:  entries,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode listing")
+	}
+	err = p.db.Put(dirsBucket, dirKey(dir), data)
+	if err != nil {
+		return errors.Wrap(err, "failed to store listing")
+	}
+	return nil
+}
+
+// expireDir removes the listing of dir
+func (p *persistent) expireDir(dir string) {
+	err := p.db.Delete(dirsBucket, dirKey(dir))
+	if err != nil {
+		fs.Errorf(dir, "cache: failed to expire listing: %v", err)
+	}
+}
+
+// expireParents removes the listings of all the directories
+// containing remote.  Adding or removing remote may have changed
+// all of them.
+func (p *persistent) expireParents(remote string) {
+	for remote != "" {
+		remote = parentDir(remote)
+		p.expireDir(remote)
+	}
+}
+
+// expireTree removes the listings of dir and all the directories
+// below it
+func (p *persistent) expireTree(dir string) {
+	prefix := dirKey(dir)
+	if dir != "" {
+		p.expireDir(dir)
+		prefix += "/"
+	}
+	_, err := p.db.DeletePrefix(dirsBucket, prefix)
+	if err != nil {
+		fs.Errorf(dir, "cache: failed to expire listings: %v", err)
+		return
+	}
+	fs.Debugf(dir, "cache: expired listings of directory tree")
+}
+
+// chunkPrefix returns the prefix of the keys of the chunks for the
+// object identified by fingerprint
+func chunkPrefix(fingerprint string) string {
+	return md5hex(fingerprint) + "/"
+}
+
+// chunkKey returns the key of the chunk at offset
+func chunkKey(fingerprint string, offset int64) string {
+	return chunkPrefix(fingerprint) + fmt.Sprintf("%016x", offset)
+}
+
+// getChunk returns the chunk at offset if it is in the cache
+func (p *persistent) getChunk(fingerprint string, offset int64) ([]byte, bool) {
+	key := chunkKey(fingerprint, offset)
+	value, err := p.db.Get(chunksBucket, key)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || len(value) < 8 {
+		if err != nil {
+			fs.Debugf(nil, "cache: failed to read chunk: %v", err)
+		}
+		p.chunkMisses++
+		return nil, false
+	}
+	p.chunkHits++
+	// Mark the chunk as recently used
+	if chunk := p.chunks[key]; chunk != nil {
+		chunk.atime = time.Now()
+	}
+	return value[8:], true
+}
+
+// putChunk stores the chunk at offset then removes the least
+// recently used chunks if there are too many
+func (p *persistent) putChunk(fingerprint string, offset int64, data []byte) error {
+	key := chunkKey(fingerprint, offset)
+	now := time.Now()
+	value := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(value, uint64(now.UnixNano()))
+	copy(value[8:], data)
+	err := p.db.Put(chunksBucket, key, value)
+	if err != nil {
+		return errors.Wrap(err, "failed to store chunk")
+	}
+	p.mu.Lock()
+	if old := p.chunks[key]; old != nil {
+		p.chunkTotal -= old.size
+	}
+	p.chunks[key] = &storedChunk{key: key, size: int64(len(data)), atime: now}
+	p.chunkTotal += int64(len(data))
+	var remove []string
+	if p.maxChunkTotal >= 0 && p.chunkTotal > p.maxChunkTotal {
+		remove = p.oldestChunks(int64(float64(p.maxChunkTotal) * lowWaterMark))
+	}
+	p.mu.Unlock()
+	if len(remove) > 0 {
+		err = p.db.Delete(chunksBucket, remove...)
+		if err != nil {
+			fs.Errorf(nil, "cache: failed to remove chunks: %v", err)
+		}
+	}
+	return nil
+}
+
+// oldestChunks removes the least recently used chunks from the index
+// until the total size is no more than target, returning their keys
+// for removal from the database
+//
+// Call with the lock held
+func (p *persistent) oldestChunks(target int64) (keys []string) {
+	chunks := make(storedChunks, 0, len(p.chunks))
+	for _, chunk := range p.chunks {
+		chunks = append(chunks, chunk)
+	}
+	sort.Sort(chunks)
+	for _, chunk := range chunks {
+		if p.chunkTotal <= target {
+			break
+		}
+		delete(p.chunks, chunk.key)
+		p.chunkTotal -= chunk.size
+		keys = append(keys, chunk.key)
+	}
+	fs.Debugf(nil, "cache: removing %d chunks, %v remaining", len(keys), fs.SizeSuffix(p.chunkTotal))
+	return keys
+}
+
+// removeChunks removes all the chunks for the object identified by
+// fingerprint
+func (p *persistent) removeChunks(fingerprint string) {
+	prefix := chunkPrefix(fingerprint)
+	_, err := p.db.DeletePrefix(chunksBucket, prefix)
+	if err != nil {
+		fs.Errorf(nil, "cache: failed to remove chunks: %v", err)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, chunk := range p.chunks {
+		if strings.HasPrefix(key, prefix) {
+			delete(p.chunks, key)
+			p.chunkTotal -= chunk.size
+		}
+	}
+}
+
+// stats returns statistics about the storage
+func (p *persistent) stats() (map[string]interface{}, error) {
+	dirs := 0
+	err := p.db.ForEach(dirsBucket, func(key string, value []byte) error {
+		dirs++
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read listings")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return map[string]interface{}{
+		"path":             p.db.Path(),
+		"dirs":             dirs,
+		"chunks":           len(p.chunks),
+		"chunk_total_size": p.chunkTotal,
+		"chunk_size_limit": p.maxChunkTotal,
+		"list_hits":        p.listHits,
+		"list_misses":      p.listMisses,
+		"chunk_hits":       p.chunkHits,
+		"chunk_misses":     p.chunkMisses,
+	}, nil
+}
+
+// parentDir returns the parent directory of remote, or "" for the
+// root
+func parentDir(remote string) string {
+	parent := path.Dir(remote)
+	if parent == "." || parent == "/" {
+		parent = ""
+	}
+	return parent
+}
@@ -0,0 +1,932 @@
+// Package chunker provides wrappers for Fs and Object which split
+// large files into chunks
+package chunker
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Constants
+const (
+	defaultChunkSize = 2 * 1024 * 1024 * 1024
+	chunkSuffix      = ".rclone_chunk."
+	metadataVersion  = 1
+	maxMetadataSize  = 1024 // metadata objects are never bigger than this
+)
+
+// chunkRe matches the names of chunks, capturing the name of the
+// file, the chunk number and the transaction ID
+var chunkRe = regexp.MustCompile(`^(.+)` + regexp.QuoteMeta(chunkSuffix) + `([0-9]{3,})_([0-9a-z]+)$`)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "chunker",
+		Description: "Transparently chunk/split large files",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote to chunk/unchunk.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\" (not recommended).",
+		}, {
+			Name:     "chunk_size",
+			Help:     "Files larger than chunk size will be split in chunks.\nDefault: 2G",
+			Optional: true,
+		}, {
+			Name: "hash_type",
+			Help: "Choose how chunker handles hash sums of chunked files.",
+			Examples: []fs.OptionExample{
+				{
+					Value: "md5",
+					Help:  "MD5 for the whole file (default)",
+				}, {
+					Value: "sha1",
+					Help:  "SHA1 for the whole file",
+				}, {
+					Value: "none",
+					Help:  "Don't store hashes of chunked files",
+				},
+			},
+			Optional: true,
+		}},
+	})
+}
+
+// NewFs contstructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := fs.ConfigFileGet(name, "remote")
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point chunker remote at itself - check the value of the remote setting")
+	}
+	chunkSize := fs.SizeSuffix(defaultChunkSize)
+	if value := fs.ConfigFileGet(name, "chunk_size"); value != "" {
+		err := chunkSize.Set(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse chunk_size %q", value)
+		}
+	}
+	if chunkSize <= 0 {
+		return nil, errors.Errorf("chunk_size must be > 0 - was %v", chunkSize)
+	}
+	hashType := fs.HashNone
+	switch value := fs.ConfigFileGet(name, "hash_type", "md5"); value {
+	case "md5":
+		hashType = fs.HashMD5
+	case "sha1":
+		hashType = fs.HashSHA1
+	case "none":
+	default:
+		return nil, errors.Errorf("unknown hash_type %q", value)
+	}
+	remotePath := path.Join(remote, rpath)
+	wrappedFs, err := fs.NewFs(remotePath)
+	if err != fs.ErrorIsFile && err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
+	}
+	f := &Fs{
+		Fs:        wrappedFs,
+		name:      name,
+		root:      rpath,
+		chunkSize: int64(chunkSize),
+		hashType:  hashType,
+	}
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from wrappedFs
+	f.features = (&fs.Features{
+		CaseInsensitive: true,
+		DuplicateFiles:  false,
+		ReadMimeType:    false, // MimeTypes not supported with chunking
+		WriteMimeType:   false,
+	}).Fill(f).Mask(wrappedFs)
+	return f, err
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name      string
+	root      string
+	features  *fs.Features // optional features
+	chunkSize int64        // files bigger than this are split into chunks
+	hashType  fs.HashType  // hash of whole chunked files to store
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Chunked drive '%s:%s'", f.name, f.root)
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() fs.HashSet {
+	if f.hashType == fs.HashNone {
+		return fs.HashSet(fs.HashNone)
+	}
+	return fs.NewHashSet(f.hashType)
+}
+
+// chunkName returns the name of chunk number i (from 0) of remote
+// uploaded in transaction txn
+func chunkName(remote string, i int, txn string) string {
+	return fmt.Sprintf("%s%s%03d_%s", remote, chunkSuffix, i+1, txn)
+}
+
+// parseChunkName returns the name of the file, the chunk number
+// (from 0) and the transaction ID if remote is the name of a chunk
+func parseChunkName(remote string) (name string, i int, txn string, ok bool) {
+	match := chunkRe.FindStringSubmatch(remote)
+	if match == nil {
+		return "", 0, "", false
+	}
+	n, err := strconv.Atoi(match[2])
+	if err != nil || n < 1 {
+		return "", 0, "", false
+	}
+	return match[1], n - 1, match[3], true
+}
+
+// newTxn returns a new transaction ID for naming the chunks of an
+// upload.
+//
+// The chunks of each upload have different names so they never
+// overwrite the chunks of the file being replaced.  The upload is
+// only committed when the metadata naming the transaction is written.
+func newTxn() (string, error) {
+	var id [6]byte
+	_, err := rand.Read(id[:])
+	if err != nil {
+		return "", errors.Wrap(err, "failed to make transaction ID")
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// The chunks of chunked files are gathered into a single Object and
+// are not listed individually.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	wrappedEntries, err := f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make(map[string]map[string][]fs.Object) // chunks by file name then transaction
+	var objects []fs.Object
+	for _, entry := range wrappedEntries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if name, i, txn, ok := parseChunkName(x.Remote()); ok {
+				if chunks[name] == nil {
+					chunks[name] = make(map[string][]fs.Object)
+				}
+				chunks[name][txn] = addChunk(chunks[name][txn], x, i)
+			} else {
+				objects = append(objects, x)
+			}
+		case *fs.Dir:
+			entries = append(entries, x)
+		default:
+			return nil, errors.Errorf("Unknown object type %T", entry)
+		}
+	}
+	for _, main := range objects {
+		o, err := f.chooseChunks(ctx, main, chunks[main.Remote()])
+		if err != nil {
+			return nil, err
+		}
+		delete(chunks, main.Remote())
+		entries = append(entries, o)
+	}
+	for name := range chunks {
+		fs.Debugf(name, "chunker: ignoring chunks without metadata")
+	}
+	return entries, nil
+}
+
+// addChunk puts chunk into chunks at position i, growing it if
+// necessary
+func addChunk(chunks []fs.Object, chunk fs.Object, i int) []fs.Object {
+	for len(chunks) <= i {
+		chunks = append(chunks, nil)
+	}
+	chunks[i] = chunk
+	return chunks
+}
+
+// chooseChunks makes the Object for main from the chunks found for
+// it grouped by transaction.
+//
+// The metadata is read to find the transaction which was committed
+// and how many chunks it has, the same as NewObject does.  Any other
+// chunks, eg left over from an interrupted upload or from before main
+// was overwritten with a small file, are ignored.
+func (f *Fs) chooseChunks(ctx context.Context, main fs.Object, txns map[string][]fs.Object) (*Object, error) {
+	o := f.newObject(main, nil, "")
+	if len(txns) == 0 {
+		return o, nil
+	}
+	meta, err := o.readMetadata(ctx)
+	if err == errNotMetadata {
+		return o, nil
+	} else if err != nil {
+		return nil, err
+	}
+	o.txn = meta.Txn
+	o.chunks = make([]fs.Object, meta.Chunks)
+	copy(o.chunks, txns[meta.Txn])
+	return o, nil
+}
+
+// findChunks finds the n chunks of remote uploaded in transaction
+// txn.  Missing chunks are returned as nil.
+func (f *Fs) findChunks(ctx context.Context, remote string, txn string, n int) (chunks []fs.Object, err error) {
+	for i := 0; i < n; i++ {
+		chunk, err := f.Fs.NewObject(ctx, chunkName(remote, i, txn))
+		if err == fs.ErrorObjectNotFound {
+			chunk = nil
+		} else if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// NewObject finds the Object at remote.
+//
+// If the object is small enough to be metadata it is read to find
+// the chunks.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if _, _, _, ok := parseChunkName(remote); ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	main, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(main, nil, "")
+	if main.Size() > maxMetadataSize {
+		return o, nil
+	}
+	meta, err := o.readMetadata(ctx)
+	if err == errNotMetadata {
+		return o, nil
+	} else if err != nil {
+		return nil, err
+	}
+	o.txn = meta.Txn
+	o.chunks, err = f.findChunks(ctx, remote, meta.Txn, meta.Chunks)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// metadata is stored in the main object of a chunked file
+type metadata struct {
+	Version int    `json:"ver"`
+	Size    int64  `json:"size"`
+	Chunks  int    `json:"nchunks"`
+	Txn     string `json:"txn"`
+	MD5     string `json:"md5,omitempty"`
+	SHA1    string `json:"sha1,omitempty"`
+}
+
+// removeChunks removes chunks, logging any errors
+func removeChunks(ctx context.Context, chunks []fs.Object) {
+	for _, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+		err := chunk.Remove(ctx)
+		if err != nil {
+			fs.Errorf(chunk, "chunker: failed to remove chunk: %v", err)
+		}
+	}
+}
+
+// put uploads in to remote, splitting it into chunks if it is
+// bigger than the chunk size.
+//
+// oldChunks are the chunks of any existing object at remote which
+// will be removed once the upload has succeeded.  If main is set
+// then it is updated rather than a new object being put.
+//
+// The chunks are uploaded with the names of a new transaction so the
+// existing object is left intact if the upload fails.  Writing the
+// metadata commits the new chunks.
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, main fs.Object, oldChunks []fs.Object, options ...fs.OpenOption) (fs.Object, error) {
+	remote := src.Remote()
+	size := src.Size()
+	if size >= 0 && size <= f.chunkSize {
+		// Small enough to upload in one piece
+		var err error
+		if main != nil {
+			err = main.Update(ctx, in, src, options...)
+		} else {
+			main, err = f.Fs.Put(ctx, in, src, options...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		removeChunks(ctx, oldChunks)
+		return f.newObject(main, nil, ""), nil
+	}
+	txn, err := newTxn()
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload the chunks, hashing the whole file as we go
+	var hasher *fs.MultiHasher
+	hashSet := f.Hashes()
+	if f.hashType != fs.HashNone {
+		hasher, err = fs.NewMultiHasherTypes(hashSet)
+		if err != nil {
+			return nil, err
+		}
+		in = io.TeeReader(in, hasher)
+	}
+	buffered := bufio.NewReader(in)
+	var chunks []fs.Object
+	var total int64
+	for i := 0; ; i++ {
+		if _, err := buffered.Peek(1); err == io.EOF {
+			break
+		} else if err != nil {
+			removeChunks(ctx, chunks)
+			return nil, err
+		}
+		chunkSize := f.chunkSize
+		if size >= 0 && size-total < chunkSize {
+			chunkSize = size - total
+		} else if size < 0 {
+			chunkSize = -1
+		}
+		chunkIn := &countingReader{in: io.LimitReader(buffered, f.chunkSize)}
+		chunkInfo := fs.NewStaticObjectInfo(chunkName(remote, i, txn), src.ModTime(), chunkSize, true, nil, f)
+		chunk, err := f.Fs.Put(ctx, chunkIn, chunkInfo)
+		if err != nil {
+			removeChunks(ctx, chunks)
+			return nil, errors.Wrapf(err, "failed to upload chunk %d", i+1)
+		}
+		chunks = append(chunks, chunk)
+		total += chunkIn.n
+	}
+	if size >= 0 && total != size {
+		removeChunks(ctx, chunks)
+		return nil, errors.Errorf("chunker: read %d bytes expecting %d", total, size)
+	}
+
+	// Write the metadata to the main object
+	meta := metadata{
+		Version: metadataVersion,
+		Size:    total,
+		Chunks:  len(chunks),
+		Txn:     txn,
+	}
+	if hasher != nil {
+		sums := hasher.Sums()
+		meta.MD5 = sums[fs.HashMD5]
+		meta.SHA1 = sums[fs.HashSHA1]
+	}
+	data, err := json.Marshal(&meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode metadata")
+	}
+	metaInfo := fs.NewStaticObjectInfo(remote, src.ModTime(), int64(len(data)), true, nil, f)
+	if main != nil {
+		err = main.Update(ctx, bytes.NewReader(data), metaInfo)
+	} else {
+		main, err = f.Fs.Put(ctx, bytes.NewReader(data), metaInfo)
+	}
+	if err != nil {
+		removeChunks(ctx, chunks)
+		return nil, errors.Wrap(err, "failed to upload metadata")
+	}
+	removeChunks(ctx, oldChunks)
+	o := f.newObject(main, chunks, txn)
+	o.meta = &meta
+	return o, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	in io.Reader
+	n  int64
+}
+
+// Read bytes from the reader counting them
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.in.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	oldChunks, _, err := f.existingChunks(ctx, src.Remote())
+	if err != nil {
+		return nil, err
+	}
+	return f.put(ctx, in, src, nil, oldChunks, options...)
+}
+
+// existingChunks returns the chunks of any object at remote and the
+// transaction they were uploaded in
+func (f *Fs) existingChunks(ctx context.Context, remote string) (chunks []fs.Object, txn string, err error) {
+	o, err := f.NewObject(ctx, remote)
+	if err == fs.ErrorObjectNotFound {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	return o.(*Object).chunks, o.(*Object).txn, nil
+}
+
+// Purge all files in the root and the root directory
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge(ctx context.Context) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do(ctx)
+}
+
+// copyOrMove copies or moves src and its chunks to remote with do
+//
+// The chunks keep their transaction ID as it is recorded in the
+// metadata.  The chunks of any object being replaced are removed
+// unless they had the same names and so were overwritten.
+func (f *Fs) copyOrMove(ctx context.Context, o *Object, remote string, do func(context.Context, fs.Object, string) (fs.Object, error)) (fs.Object, error) {
+	oldChunks, oldTxn, err := f.existingChunks(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []fs.Object
+	for i, chunk := range o.chunks {
+		if chunk == nil {
+			return nil, errors.Errorf("chunker: chunk %d of %q is missing", i+1, o.Remote())
+		}
+		newChunk, err := do(ctx, chunk, chunkName(remote, i, o.txn))
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, newChunk)
+	}
+	main, err := do(ctx, o.main, remote)
+	if err != nil {
+		return nil, err
+	}
+	if oldTxn != o.txn {
+		removeChunks(ctx, oldChunks)
+	}
+	newObj := f.newObject(main, chunks, o.txn)
+	newObj.meta = o.meta
+	return newObj, nil
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	return f.copyOrMove(ctx, o, remote, do)
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	return f.copyOrMove(ctx, o, remote, do)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// CleanUp the trash in the Fs
+//
+// Implement this if you have a way of emptying the trash or
+// otherwise cleaning up old versions of files.
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do(ctx)
+}
+
+// DirChangeNotify calls the passed function with a path of a
+// directory that has had changes in the wrapped remote
+func (f *Fs) DirChangeNotify(notifyFunc func(string), pollInterval time.Duration) chan bool {
+	do := f.Fs.Features().DirChangeNotify
+	if do == nil {
+		return nil
+	}
+	return do(notifyFunc, pollInterval)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// Object represents a file which may be split into chunks
+//
+// If the file is chunked then main contains the metadata, otherwise
+// it is the file itself.
+type Object struct {
+	f      *Fs
+	main   fs.Object   // the main object
+	chunks []fs.Object // the chunks in order or nil if not chunked
+	txn    string      // the transaction the chunks were uploaded in
+	meta   *metadata   // metadata read from main if chunked
+}
+
+// newObject makes an Object from main and its chunks uploaded in
+// transaction txn
+func (f *Fs) newObject(main fs.Object, chunks []fs.Object, txn string) *Object {
+	return &Object{
+		f:      f,
+		main:   main,
+		chunks: chunks,
+		txn:    txn,
+	}
+}
+
+// isChunked returns whether the object is split into chunks
+func (o *Object) isChunked() bool {
+	return len(o.chunks) > 0
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.main.Remote()
+}
+
+// Size returns the size of the file - the sum of the sizes of the
+// chunks if it is chunked
+func (o *Object) Size() int64 {
+	if !o.isChunked() {
+		return o.main.Size()
+	}
+	var size int64
+	for _, chunk := range o.chunks {
+		if chunk == nil {
+			return -1
+		}
+		size += chunk.Size()
+	}
+	return size
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime() time.Time {
+	return o.main.ModTime()
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	return o.main.SetModTime(ctx, t)
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return o.main.Storable()
+}
+
+// errNotMetadata is returned by readMetadata if the main object
+// isn't metadata
+var errNotMetadata = errors.New("chunker: not metadata")
+
+// readMetadata reads the metadata from the main object
+//
+// It returns errNotMetadata if the main object doesn't contain
+// metadata, in which case it is a file which isn't chunked.
+func (o *Object) readMetadata(ctx context.Context) (*metadata, error) {
+	if o.meta != nil {
+		return o.meta, nil
+	}
+	if o.main.Size() > maxMetadataSize {
+		return nil, errNotMetadata
+	}
+	in, err := o.main.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open metadata")
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(in, maxMetadataSize+1))
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read metadata")
+	}
+	meta := new(metadata)
+	err = json.Unmarshal(data, meta)
+	if err != nil || meta.Version == 0 || meta.Txn == "" {
+		return nil, errNotMetadata
+	}
+	if meta.Version != metadataVersion {
+		return nil, errors.Errorf("chunker: unsupported metadata version %d", meta.Version)
+	}
+	o.meta = meta
+	return meta, nil
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+func (o *Object) Hash(hashType fs.HashType) (string, error) {
+	if hashType == fs.HashNone || hashType != o.f.hashType {
+		return "", fs.ErrHashUnsupported
+	}
+	if !o.isChunked() {
+		hash, err := o.main.Hash(hashType)
+		if err == fs.ErrHashUnsupported {
+			return "", nil
+		}
+		return hash, err
+	}
+	meta, err := o.readMetadata(context.Background())
+	if err != nil {
+		return "", err
+	}
+	switch hashType {
+	case fs.HashMD5:
+		return meta.MD5, nil
+	case fs.HashSHA1:
+		return meta.SHA1, nil
+	}
+	return "", nil
+}
+
+// UnWrap returns the wrapped main Object
+func (o *Object) UnWrap() fs.Object {
+	return o.main
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+//
+// Only the chunks needed for the range requested are read.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	if !o.isChunked() {
+		return o.main.Open(ctx, options...)
+	}
+	for i, chunk := range o.chunks {
+		if chunk == nil {
+			return nil, errors.Errorf("chunker: chunk %d of %q is missing", i+1, o.Remote())
+		}
+	}
+	size := o.Size()
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	end := size
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return &chunkReader{
+		ctx:    ctx,
+		chunks: o.chunks,
+		offset: offset,
+		end:    end,
+	}, nil
+}
+
+// chunkReader reads a range of a chunked file opening the chunks as
+// they are needed
+type chunkReader struct {
+	ctx    context.Context
+	chunks []fs.Object
+	offset int64         // offset of the next read in the file
+	end    int64         // offset in the file to stop reading at
+	in     io.ReadCloser // current chunk being read or nil
+	inEnd  int64         // offset in the file in should reach
+}
+
+// next opens the chunk containing the current offset
+func (r *chunkReader) next() error {
+	var start int64
+	for _, chunk := range r.chunks {
+		chunkEnd := start + chunk.Size()
+		if r.offset < chunkEnd {
+			// Read to the end of the chunk or the end of the range
+			end := chunkEnd
+			if r.end < end {
+				end = r.end
+			}
+			var options []fs.OpenOption
+			if r.offset != start || end != chunkEnd {
+				options = append(options, &fs.RangeOption{Start: r.offset - start, End: end - start - 1})
+			}
+			in, err := chunk.Open(r.ctx, options...)
+			if err != nil {
+				return err
+			}
+			r.in = fs.NewLimitedReadCloser(in, end-r.offset)
+			r.inEnd = end
+			return nil
+		}
+		start = chunkEnd
+	}
+	return io.EOF
+}
+
+// Read reads up to len(p) bytes into p
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	for {
+		if r.offset >= r.end {
+			return 0, io.EOF
+		}
+		if r.in == nil {
+			err = r.next()
+			if err != nil {
+				return 0, err
+			}
+		}
+		n, err = r.in.Read(p)
+		r.offset += int64(n)
+		if err == io.EOF {
+			err = r.in.Close()
+			r.in = nil
+			if err != nil {
+				return n, err
+			}
+			if r.offset < r.inEnd {
+				// The chunk is shorter than listed
+				return n, io.ErrUnexpectedEOF
+			}
+			if n == 0 {
+				continue
+			}
+		}
+		return n, err
+	}
+}
+
+// Close the reader
+func (r *chunkReader) Close() error {
+	if r.in == nil {
+		return nil
+	}
+	err := r.in.Close()
+	r.in = nil
+	return err
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	newObj, err := o.f.put(ctx, in, &renamedInfo{ObjectInfo: src, remote: o.Remote()}, o.main, o.chunks, options...)
+	if err != nil {
+		return err
+	}
+	*o = *newObj.(*Object)
+	return nil
+}
+
+// renamedInfo is an ObjectInfo with a different remote
+type renamedInfo struct {
+	fs.ObjectInfo
+	remote string
+}
+
+// Remote returns the remote path
+func (ri *renamedInfo) Remote() string {
+	return ri.remote
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.main.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	removeChunks(ctx, o.chunks)
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs                = (*Fs)(nil)
+	_ fs.Purger            = (*Fs)(nil)
+	_ fs.Copier            = (*Fs)(nil)
+	_ fs.Mover             = (*Fs)(nil)
+	_ fs.DirMover          = (*Fs)(nil)
+	_ fs.CleanUpper        = (*Fs)(nil)
+	_ fs.DirChangeNotifier = (*Fs)(nil)
+	_ fs.UnWrapper         = (*Fs)(nil)
+	_ fs.Object            = (*Object)(nil)
+)
@@ -0,0 +1,263 @@
+package chunker
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	_ "github.com/ncw/rclone/local"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newTestFs makes a chunker remote with the chunk size given
+// wrapping a local temporary directory returning the Fs, the
+// directory and a cleanup function
+func newTestFs(t *testing.T, name, chunkSize string) (*Fs, string, func()) {
+	r := fstest.NewWrapperRemote(t, "chunker", name, 1, map[string]string{
+		"chunk_size": chunkSize,
+	})
+	return r.Fs.(*Fs), r.Dirs[0], r.Finalise
+}
+
+func listDir(t *testing.T, dir string) (names []string) {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestParseChunkName(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		name string
+		i    int
+		txn  string
+		ok   bool
+	}{
+		{"file", "", 0, "", false},
+		{"file.rclone_chunk.001_0f3a", "file", 0, "0f3a", true},
+		{"dir/file.rclone_chunk.1000_x", "dir/file", 999, "x", true},
+		{"file.rclone_chunk.001", "", 0, "", false},
+		{"file.rclone_chunk.001_", "", 0, "", false},
+		{"file.rclone_chunk.000_0f3a", "", 0, "", false},
+		{"file.rclone_chunk.01_0f3a", "", 0, "", false},
+		{".rclone_chunk.001_0f3a", "", 0, "", false},
+	} {
+		name, i, txn, ok := parseChunkName(test.in)
+		assert.Equal(t, test.ok, ok, test.in)
+		assert.Equal(t, test.name, name, test.in)
+		assert.Equal(t, test.i, i, test.in)
+		assert.Equal(t, test.txn, txn, test.in)
+		if ok {
+			assert.Equal(t, test.in, chunkName(name, i, txn))
+		}
+	}
+}
+
+// chunkNames returns the names of the n chunks of file from the
+// transaction of the chunks found in dir
+func chunkNames(t *testing.T, dir, file string, n int) []string {
+	txn := ""
+	for _, name := range listDir(t, dir) {
+		if chunkFile, _, chunkTxn, ok := parseChunkName(name); ok && chunkFile == file {
+			txn = chunkTxn
+		}
+	}
+	require.NotEqual(t, "", txn, "no chunks found")
+	names := []string{file}
+	for i := 0; i < n; i++ {
+		names = append(names, chunkName(file, i, txn))
+	}
+	return names
+}
+
+func TestChunkerPutAndRead(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestChunkerPutAndRead", "10b")
+	defer cleanup()
+	data := "0123456789abcdefghijklmnopqrstuvwxyz"
+	src := fs.NewStaticObjectInfo("file", time.Now(), int64(len(data)), true, nil, nil)
+	_, err := f.Put(ctx, strings.NewReader(data), src)
+	require.NoError(t, err)
+
+	// The file is stored as metadata and 4 chunks
+	assert.Equal(t, chunkNames(t, dir, "file", 4), listDir(t, dir))
+
+	// But is listed as a single object
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "file", entries[0].Remote())
+	assert.Equal(t, int64(len(data)), entries[0].(fs.Object).Size())
+	_, err = f.NewObject(ctx, chunkNames(t, dir, "file", 1)[1])
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	o, err := f.NewObject(ctx, "file")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), o.Size())
+	sum := md5.Sum([]byte(data))
+	hash, err := o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+
+	read := func(options ...fs.OpenOption) string {
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(got)
+	}
+	assert.Equal(t, data, read())
+	assert.Equal(t, data[15:], read(&fs.SeekOption{Offset: 15}))
+	assert.Equal(t, data[8:23], read(&fs.RangeOption{Start: 8, End: 22}))
+	assert.Equal(t, data[30:], read(&fs.RangeOption{Start: -1, End: 6}))
+
+	// Updating to a smaller file removes the extra chunks
+	data = "potato"
+	src = fs.NewStaticObjectInfo("file", time.Now(), int64(len(data)), true, nil, nil)
+	require.NoError(t, o.Update(ctx, strings.NewReader(data), src))
+	assert.Equal(t, []string{"file"}, listDir(t, dir))
+	assert.Equal(t, data, read())
+
+	// Files of unknown size are chunked too
+	data = "0123456789abcdef"
+	src = fs.NewStaticObjectInfo("file", time.Now(), -1, true, nil, nil)
+	require.NoError(t, o.Update(ctx, strings.NewReader(data), src))
+	assert.Equal(t, 3, len(listDir(t, dir)))
+	assert.Equal(t, data, read())
+
+	require.NoError(t, o.Remove(ctx))
+	assert.Equal(t, 0, len(listDir(t, dir)))
+}
+
+func TestChunkerOrphanChunks(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestChunkerOrphanChunks", "10b")
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "orphan.rclone_chunk.001_0f3a"), []byte("x"), 0600))
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+	_, err = f.NewObject(ctx, "orphan")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestChunkerStaleChunks(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestChunkerStaleChunks", "10b")
+	defer cleanup()
+	data := "potato"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file"), []byte(data), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file.rclone_chunk.001_0f3a"), []byte("0123456789"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file.rclone_chunk.002_0f3a"), []byte("abcdef"), 0600))
+
+	// The stale chunks aren't added to the small file by List or NewObject
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	listed := entries[0].(*Object)
+	o, err := f.NewObject(ctx, "file")
+	require.NoError(t, err)
+	for _, o := range []*Object{listed, o.(*Object)} {
+		assert.False(t, o.isChunked())
+		assert.Equal(t, int64(len(data)), o.Size())
+		in, err := o.Open(ctx)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		assert.Equal(t, data, string(got))
+	}
+}
+
+// errorReader returns err after reading n bytes from in
+type errorReader struct {
+	in  io.Reader
+	n   int
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (n int, err error) {
+	if r.n <= 0 {
+		return 0, r.err
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	n, err = r.in.Read(p)
+	r.n -= n
+	return n, err
+}
+
+func TestChunkerFailedUpdate(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestChunkerFailedUpdate", "10b")
+	defer cleanup()
+	data := "0123456789abcdefghijklmnopqrstuvwxyz"
+	src := fs.NewStaticObjectInfo("file", time.Now(), int64(len(data)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(data), src)
+	require.NoError(t, err)
+	names := listDir(t, dir)
+
+	// An update which fails part way through leaves the old file
+	newData := strings.Repeat("x", 50)
+	src = fs.NewStaticObjectInfo("file", time.Now(), int64(len(newData)), true, nil, nil)
+	in := &errorReader{in: strings.NewReader(newData), n: 25, err: errors.New("read failed")}
+	require.Error(t, o.Update(ctx, in, src))
+	assert.Equal(t, names, listDir(t, dir))
+
+	// As does one which is shorter than expected
+	in = &errorReader{in: strings.NewReader(newData), n: 25, err: io.EOF}
+	require.Error(t, o.Update(ctx, in, src))
+	assert.Equal(t, names, listDir(t, dir))
+
+	o, err = f.NewObject(ctx, "file")
+	require.NoError(t, err)
+	rc, err := o.Open(ctx)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, data, string(got))
+
+	// A successful update replaces the chunks
+	require.NoError(t, o.Update(ctx, strings.NewReader(newData), src))
+	assert.Equal(t, chunkNames(t, dir, "file", 5), listDir(t, dir))
+}
+
+func TestChunkerShortChunk(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestChunkerShortChunk", "10b")
+	defer cleanup()
+	data := "0123456789abcdefghijklmnopqrstuvwxyz"
+	src := fs.NewStaticObjectInfo("file", time.Now(), int64(len(data)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(data), src)
+	require.NoError(t, err)
+
+	// Truncate the second chunk after it has been listed
+	chunk := filepath.Join(dir, chunkNames(t, dir, "file", 2)[2])
+	require.NoError(t, os.Truncate(chunk, 5))
+
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(in)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	require.NoError(t, in.Close())
+}
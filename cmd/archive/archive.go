@@ -0,0 +1,37 @@
+// Package archive implements the archive command and the sub commands
+// used to make archives of remotes.
+package archive
+
+import (
+	"errors"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/archive/create"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Command.AddCommand(create.Command)
+	cmd.Root.AddCommand(Command)
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "archive <action> [opts] <source> <destination>",
+	Short: `Make archives of remotes.`,
+	Long: `rclone archive is used to make archives of remotes.  This command
+requires the use of a subcommand to specify the action, eg
+
+    rclone archive create remote:path remote:path/to/file.tar.gz
+
+Each subcommand has its own options which you can see in their help.
+
+To read the files in existing archives use the archive backend.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("archive requires an action, eg 'rclone archive create remote:path remote:file.zip'")
+		}
+		return errors.New("unknown action")
+	},
+}
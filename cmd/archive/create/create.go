@@ -0,0 +1,330 @@
+// Package create implements the archive create command
+package create
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Globals
+var (
+	formatName = ""
+)
+
+func init() {
+	Command.Flags().StringVarP(&formatName, "format", "", formatName, "Format of the archive: zip, tar or tar.gz. Default is from the destination extension.")
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "create source:path dest:path/to/file.tar.gz",
+	Short: `Make an archive of source:path on dest:path.`,
+	Long: `
+Make a zip or tar archive of the files in source:path and upload it
+as a single file to dest:path.
+
+The archive is streamed from the source to the destination so no
+local disk is needed, however the destination must support uploading
+files of unknown size.  Local disk, S3 and the memory backend do, but
+many other backends need to know the size of a file before uploading
+it.
+
+The format is taken from the extension of the destination file name:
+` + "`.zip`" + `, ` + "`.tar`" + `, ` + "`.tar.gz`" + ` or ` + "`.tgz`" + `.  Use the ` + "`--format`" + ` flag to set it
+explicitly, eg
+
+    rclone archive create --format tar.gz remote:photos remote:backup/photos.bin
+
+The paths of the files in the archive are relative to source:path and
+the modification times of the files and directories are preserved.
+Filters, eg ` + "`--include`" + ` and ` + "`--max-age`" + `, and ` + "`--max-depth`" + ` control what goes
+in the archive.
+
+If anything goes wrong reading the source the upload is aborted.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		dstRemote, dstFileName := fs.RemoteSplit(args[1])
+		if dstRemote == "" {
+			dstRemote = "."
+		}
+		if dstFileName == "" {
+			log.Fatalf("%q is a directory", args[1])
+		}
+		fdst := cmd.NewFsDst([]string{dstRemote})
+		format, err := chooseFormat(formatName, dstFileName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cmd.Run(true, true, command, func() error {
+			_, err := makeArchive(context.Background(), fsrc, fdst, dstFileName, format)
+			return err
+		})
+	},
+}
+
+// format is the type of archive to make
+type format int
+
+// Types of archive
+const (
+	formatZip format = iota + 1
+	formatTar
+	formatTarGz
+)
+
+// chooseFormat returns the format called name or if name is empty
+// the format from the extension of fileName
+func chooseFormat(name, fileName string) (format, error) {
+	switch strings.ToLower(name) {
+	case "zip":
+		return formatZip, nil
+	case "tar":
+		return formatTar, nil
+	case "tar.gz", "tgz":
+		return formatTarGz, nil
+	case "":
+	default:
+		return 0, errors.Errorf("unknown archive format %q - use zip, tar or tar.gz", name)
+	}
+	lower := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return formatZip, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return formatTar, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return formatTarGz, nil
+	}
+	return 0, errors.Errorf("can't work out archive format from %q - use --format", fileName)
+}
+
+// archiveWriter writes the entries of an archive
+type archiveWriter interface {
+	// addDir adds the directory d
+	addDir(d *fs.Dir) error
+	// addFile adds the object o reading its contents from in
+	addFile(o fs.Object, in io.Reader) error
+	// Close finishes the archive
+	Close() error
+}
+
+// zipWriter writes zip archives
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func newZipWriter(out io.Writer) *zipWriter {
+	return &zipWriter{zw: zip.NewWriter(out)}
+}
+
+func (w *zipWriter) addDir(d *fs.Dir) error {
+	hdr := &zip.FileHeader{
+		Name: d.Remote() + "/",
+	}
+	hdr.SetModTime(d.ModTime())
+	hdr.SetMode(os.ModeDir | 0755)
+	_, err := w.zw.CreateHeader(hdr)
+	return err
+}
+
+func (w *zipWriter) addFile(o fs.Object, in io.Reader) error {
+	hdr := &zip.FileHeader{
+		Name:   o.Remote(),
+		Method: zip.Deflate,
+	}
+	hdr.SetModTime(o.ModTime())
+	hdr.SetMode(0644)
+	out, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	return err
+}
+
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
+
+// tarWriter writes tar archives, optionally gzipped
+type tarWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer // set if gzipping
+}
+
+func newTarWriter(out io.Writer, gzipped bool) *tarWriter {
+	w := &tarWriter{}
+	if gzipped {
+		w.gz = gzip.NewWriter(out)
+		out = w.gz
+	}
+	w.tw = tar.NewWriter(out)
+	return w
+}
+
+func (w *tarWriter) addDir(d *fs.Dir) error {
+	return w.tw.WriteHeader(&tar.Header{
+		Name:     d.Remote() + "/",
+		Mode:     0755,
+		ModTime:  d.ModTime(),
+		Typeflag: tar.TypeDir,
+	})
+}
+
+func (w *tarWriter) addFile(o fs.Object, in io.Reader) error {
+	size := o.Size()
+	if size < 0 {
+		return errors.New("can't put files of unknown size in a tar archive")
+	}
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:     o.Remote(),
+		Mode:     0644,
+		Size:     size,
+		ModTime:  o.ModTime(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(w.tw, in)
+	if err == tar.ErrWriteTooLong || (err == nil && n != size) {
+		return errors.Errorf("file changed size while reading: expecting %d bytes", size)
+	}
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	err := w.tw.Close()
+	if w.gz != nil {
+		gzErr := w.gz.Close()
+		if err == nil {
+			err = gzErr
+		}
+	}
+	return err
+}
+
+// addFile reads the object o into the archive w accounting the
+// transfer
+func addFile(ctx context.Context, w archiveWriter, o fs.Object) (err error) {
+	fs.Stats.Transferring(o.Remote())
+	defer func() {
+		fs.Stats.DoneTransferring(o.Remote(), err == nil)
+	}()
+	in, err := o.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to open")
+	}
+	acc := fs.NewAccount(in, o).WithBuffer() // account the transfer
+	err = w.addFile(o, acc)
+	closeErr := acc.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// writeArchive writes the entries to out as an archive of type
+// format
+func writeArchive(ctx context.Context, out io.Writer, entries fs.DirEntries, format format) (err error) {
+	var w archiveWriter
+	switch format {
+	case formatZip:
+		w = newZipWriter(out)
+	case formatTar:
+		w = newTarWriter(out, false)
+	case formatTarGz:
+		w = newTarWriter(out, true)
+	default:
+		return errors.Errorf("unknown archive format %d", format)
+	}
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			err = addFile(ctx, w, x)
+		case *fs.Dir:
+			err = w.addDir(x)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to add %q to archive", entry.Remote())
+		}
+	}
+	return w.Close()
+}
+
+// errUploadFinished is used to stop the archive writer if the upload
+// finishes before it does
+var errUploadFinished = errors.New("upload finished")
+
+// makeArchive makes an archive of type format of the files in fsrc
+// and uploads it to fdst as remote
+//
+// The archive is written into a pipe as it is uploaded so the upload
+// is of unknown size.
+func makeArchive(ctx context.Context, fsrc, fdst fs.Fs, remote string, format format) (dst fs.Object, err error) {
+	objs, dirs, err := fs.WalkGetAll(ctx, fsrc, "", false, fs.Config.MaxDepth)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list source")
+	}
+	entries := make(fs.DirEntries, 0, len(objs)+len(dirs))
+	for _, dir := range dirs {
+		entries = append(entries, dir)
+	}
+	for _, o := range objs {
+		entries = append(entries, o)
+	}
+	// Sorting puts directories before their contents
+	sort.Sort(entries)
+
+	pr, pw := io.Pipe()
+	writeErr := make(chan error, 1)
+	go func() {
+		err := writeArchive(ctx, pw, entries, format)
+		_ = pw.CloseWithError(err)
+		writeErr <- err
+	}()
+	src := fs.NewStaticObjectInfo(remote, time.Now(), -1, true, nil, fdst)
+	dst, err = fdst.Put(ctx, pr, src)
+	// Stop the writer if the upload finished early
+	_ = pr.CloseWithError(errUploadFinished)
+	archiveErr := <-writeErr
+	if errors.Cause(archiveErr) == errUploadFinished {
+		if err == nil {
+			archiveErr = errors.New("upload finished before the archive was complete")
+		} else {
+			archiveErr = nil
+		}
+	}
+	if archiveErr != nil {
+		fs.Stats.Error()
+		if dst != nil {
+			// The upload shouldn't have succeeded without
+			// the whole archive so remove it
+			if removeErr := dst.Remove(ctx); removeErr != nil {
+				fs.Errorf(dst, "Failed to remove incomplete archive: %v", removeErr)
+			}
+		}
+		return nil, archiveErr
+	}
+	if err != nil {
+		fs.Stats.Error()
+		return nil, errors.Wrap(err, "failed to upload archive")
+	}
+	fs.Infof(dst, "Archived %d files and %d directories", len(objs), len(dirs))
+	return dst, nil
+}
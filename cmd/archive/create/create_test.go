@@ -0,0 +1,151 @@
+package create
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06Z")
+	t2 = fstest.Time("2011-12-25T12:59:58Z")
+)
+
+func TestChooseFormat(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		fileName string
+		want     format
+		wantErr  bool
+	}{
+		{"", "file.zip", formatZip, false},
+		{"", "file.TAR", formatTar, false},
+		{"", "file.tar.gz", formatTarGz, false},
+		{"", "file.tgz", formatTarGz, false},
+		{"", "file.bin", 0, true},
+		{"zip", "file.bin", formatZip, false},
+		{"TGZ", "file.zip", formatTarGz, false},
+		{"rar", "file.zip", 0, true},
+	} {
+		got, err := chooseFormat(test.name, test.fileName)
+		assert.Equal(t, test.want, got, test.fileName)
+		assert.Equal(t, test.wantErr, err != nil, test.fileName)
+	}
+}
+
+// archivedFile is a file or directory read back from an archive
+type archivedFile struct {
+	name     string
+	contents string
+	modTime  time.Time
+}
+
+func readZip(t *testing.T, data []byte) (files []archivedFile) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	for _, zf := range zr.File {
+		in, err := zf.Open()
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		files = append(files, archivedFile{zf.Name, string(contents), zf.ModTime()})
+	}
+	return files
+}
+
+func readTar(t *testing.T, data []byte, gzipped bool) (files []archivedFile) {
+	var in io.Reader = bytes.NewReader(data)
+	if gzipped {
+		gz, err := gzip.NewReader(in)
+		require.NoError(t, err)
+		in = gz
+	}
+	tr := tar.NewReader(in)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files = append(files, archivedFile{hdr.Name, string(contents), hdr.ModTime})
+	}
+	return files
+}
+
+func TestMakeArchive(t *testing.T) {
+	fs.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-archive-create")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	srcDir := filepath.Join(dir, "src")
+	write := func(name, contents string, modTime time.Time) {
+		filePath := filepath.Join(srcDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0700))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0600))
+		require.NoError(t, os.Chtimes(filePath, modTime, modTime))
+	}
+	write("one.txt", "one", t1)
+	write("sub dir/two.txt", "two two", t2)
+	write("sub dir/deeper/three.txt", "", t1)
+	require.NoError(t, os.Chtimes(filepath.Join(srcDir, "sub dir", "deeper"), t2, t2))
+	require.NoError(t, os.Chtimes(filepath.Join(srcDir, "sub dir"), t1, t1))
+	require.NoError(t, os.Mkdir(filepath.Join(srcDir, "empty"), 0700))
+	require.NoError(t, os.Chtimes(filepath.Join(srcDir, "empty"), t2, t2))
+
+	fsrc, err := fs.NewFs(srcDir)
+	require.NoError(t, err)
+	fdst, err := fs.NewFs(filepath.Join(dir, "dst"))
+	require.NoError(t, err)
+
+	want := []archivedFile{
+		{"empty/", "", t2},
+		{"one.txt", "one", t1},
+		{"sub dir/", "", t1},
+		{"sub dir/deeper/", "", t2},
+		{"sub dir/deeper/three.txt", "", t1},
+		{"sub dir/two.txt", "two two", t2},
+	}
+	for _, test := range []struct {
+		remote string
+		format format
+		read   func(data []byte) []archivedFile
+	}{
+		{"archive.zip", formatZip, func(data []byte) []archivedFile { return readZip(t, data) }},
+		{"archive.tar", formatTar, func(data []byte) []archivedFile { return readTar(t, data, false) }},
+		{"backups/archive.tgz", formatTarGz, func(data []byte) []archivedFile { return readTar(t, data, true) }},
+	} {
+		dst, err := makeArchive(context.Background(), fsrc, fdst, test.remote, test.format)
+		require.NoError(t, err, test.remote)
+		assert.Equal(t, test.remote, dst.Remote())
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "dst", filepath.FromSlash(test.remote)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), dst.Size())
+		got := test.read(data)
+		require.Equal(t, len(want), len(got), test.remote)
+		for i := range want {
+			assert.Equal(t, want[i].name, got[i].name, test.remote)
+			assert.Equal(t, want[i].contents, got[i].contents, test.remote)
+			assert.True(t, want[i].modTime.Equal(got[i].modTime), "%s: %s: want %v got %v", test.remote, want[i].name, want[i].modTime, got[i].modTime)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+// Package bisync implements the bisync command which keeps two paths
+// in sync with each other by remembering their state between runs.
+package bisync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Options control the bisync
+type Options struct {
+	Resync    bool   // copy both ways and record the result as the baseline
+	MaxDelete int    // abort if more than this percentage of files would be deleted
+	Force     bool   // ignore MaxDelete
+	Workdir   string // where the listings are stored
+}
+
+// Globals
+var (
+	opt = Options{
+		MaxDelete: 50,
+	}
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&opt.Resync, "resync", "", opt.Resync, "Copy path1 to path2 and path2 to path1 and record the result as the baseline.")
+	commandDefintion.Flags().IntVarP(&opt.MaxDelete, "max-delete", "", opt.MaxDelete, "Abort if more than this percentage of files on either side would be deleted.")
+	commandDefintion.Flags().BoolVarP(&opt.Force, "force", "", opt.Force, "Bypass the --max-delete safety check.")
+	commandDefintion.Flags().StringVarP(&opt.Workdir, "workdir", "", opt.Workdir, "Directory to keep the listings in (default the bisync directory next to the config file).")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "bisync path1 path2",
+	Short: `Bidirectional synchronization between two paths.`,
+	Long: `
+Bisync keeps path1 and path2 in sync with each other, propagating
+new, changed and deleted files in both directions.
+
+To do this it keeps a listing of each path from the last successful
+run.  Each run the current state of each path is compared with its
+listing to find out what changed on that side since last time, and
+those changes are copied to the other side.
+
+  * A file new or changed on one side only is copied to the other side.
+  * A file deleted on one side only is deleted on the other side.
+  * A file deleted on one side but changed on the other is copied back.
+  * A file changed on both sides is left alone if both copies are the
+    same, otherwise both are kept by renaming them to ` + "`" + `file..path1` + "`" + `
+    and ` + "`" + `file..path2` + "`" + ` and copying each to the other side.
+
+The first time you run bisync on a pair of paths (or if the listings
+are lost) you must use ` + "`" + `--resync` + "`" + `.  This copies files from path1 to
+path2 then from path2 to path1 (so path1 wins if a file differs) and
+records the result as the baseline for future runs.
+
+As a safety measure bisync will stop without making any changes if
+more than ` + "`" + `--max-delete` + "`" + ` percent of the files on either side would be
+deleted.  This stops an accidentally emptied directory from emptying
+the other side too.  Use ` + "`" + `--force` + "`" + ` to bypass this check.
+
+Use ` + "`" + `--dry-run` + "`" + ` to see what would be done without changing anything.
+The listings aren't updated on a dry run.
+
+The listings are kept in ` + "`" + `--workdir` + "`" + ` which defaults to a ` + "`" + `bisync` + "`" + `
+directory alongside the config file.  Only one bisync of a given pair
+of paths may run at once.
+
+Only files are synchronized - empty directories are not created or
+removed.  Filters apply to both paths and should not be changed
+between runs without using ` + "`" + `--resync` + "`" + `.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fs1 := cmd.NewFsDst(args)
+		fs2 := cmd.NewFsDst(args[1:])
+		fs.CalculateModifyWindow(fs1, fs2)
+		cmd.Run(false, true, command, func() error {
+			return Bisync(context.Background(), fs1, fs2, &opt)
+		})
+	},
+}
+
+// bisync holds the state of a single bisync run
+type bisync struct {
+	ctx      context.Context
+	opt      *Options
+	fs1      fs.Fs
+	fs2      fs.Fs
+	listing1 string // file name of the listing for fs1
+	listing2 string // file name of the listing for fs2
+	lockFile string // file name of the lock file
+}
+
+// Bisync synchronizes fs1 and fs2 in both directions
+func Bisync(ctx context.Context, fs1, fs2 fs.Fs, opt *Options) (err error) {
+	if fs.Overlapping(fs1, fs2) {
+		return fs.FatalError(errors.New("can't bisync overlapping paths"))
+	}
+	workdir := opt.Workdir
+	if workdir == "" {
+		workdir = filepath.Join(filepath.Dir(fs.ConfigPath), "bisync")
+	}
+	err = os.MkdirAll(workdir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to make bisync workdir")
+	}
+	base := filepath.Join(workdir, fileNameFor(fs1)+".."+fileNameFor(fs2))
+	b := &bisync{
+		ctx:      ctx,
+		opt:      opt,
+		fs1:      fs1,
+		fs2:      fs2,
+		listing1: base + ".path1.lst",
+		listing2: base + ".path2.lst",
+		lockFile: base + ".lck",
+	}
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if opt.Resync {
+		return b.resync()
+	}
+	return b.run()
+}
+
+// matchUnsafe matches characters which shouldn't appear in file names
+var matchUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fileNameFor makes a name suitable for a file from an Fs
+func fileNameFor(f fs.Fs) string {
+	name := f.Name() + "_" + strings.Trim(f.Root(), "/")
+	return strings.Trim(matchUnsafe.ReplaceAllString(name, "_"), "_")
+}
+
+// lock makes sure only one bisync of this pair of paths is running
+//
+// It returns a function to release the lock.
+func (b *bisync) lock() (func(), error) {
+	fd, err := os.OpenFile(b.lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fs.FatalError(errors.Errorf("bisync already running or lock file %q left over from a crashed run - remove it if not running", b.lockFile))
+		}
+		return nil, errors.Wrap(err, "failed to make lock file")
+	}
+	_, _ = fmt.Fprintf(fd, "%d\n", os.Getpid())
+	_ = fd.Close()
+	return func() {
+		err := os.Remove(b.lockFile)
+		if err != nil {
+			fs.Errorf(nil, "Failed to remove bisync lock file: %v", err)
+		}
+	}, nil
+}
+
+// resync copies both ways and saves the listings as the new baseline
+func (b *bisync) resync() error {
+	fs.Infof(nil, "Resync: copying %v to %v", b.fs1, b.fs2)
+	err := fs.CopyDir(b.ctx, b.fs2, b.fs1)
+	if err != nil {
+		return err
+	}
+	fs.Infof(nil, "Resync: copying %v to %v", b.fs2, b.fs1)
+	err = fs.CopyDir(b.ctx, b.fs1, b.fs2)
+	if err != nil {
+		return err
+	}
+	return b.saveListings()
+}
+
+// saveListings reads the state of both sides and saves it for the
+// next run unless this is a dry run
+func (b *bisync) saveListings() error {
+	if fs.Config.DryRun {
+		fs.Logf(nil, "Not saving listings as --dry-run")
+		return nil
+	}
+	if fs.Stats.Errored() {
+		return errors.New("not saving listings as there were errors")
+	}
+	ls1, _, err := makeListing(b.ctx, b.fs1)
+	if err != nil {
+		return err
+	}
+	ls2, _, err := makeListing(b.ctx, b.fs2)
+	if err != nil {
+		return err
+	}
+	err = ls1.save(b.listing1)
+	if err != nil {
+		return err
+	}
+	return ls2.save(b.listing2)
+}
+
+// side is the state of one of the paths
+type side struct {
+	name   string // path1 or path2
+	f      fs.Fs
+	old    listing
+	cur    listing
+	objs   map[string]fs.Object
+	deltas deltas
+}
+
+// newSide reads the old and current state of f
+func (b *bisync) newSide(name string, f fs.Fs, listingFile string) (*side, error) {
+	old, err := loadListing(listingFile)
+	if os.IsNotExist(err) {
+		return nil, fs.FatalError(errors.Errorf("no listing found for %s %v - run with --resync first", name, f))
+	} else if err != nil {
+		return nil, fs.FatalError(err)
+	}
+	cur, objs, err := makeListing(b.ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	s := &side{
+		name:   name,
+		f:      f,
+		old:    old,
+		cur:    cur,
+		objs:   objs,
+		deltas: findDeltas(old, cur),
+	}
+	fs.Logf(f, "%s: %d new, %d changed, %d deleted", name, s.deltas.count(deltaNew), s.deltas.count(deltaChanged), s.deltas.count(deltaDeleted))
+	return s, nil
+}
+
+// checkDeletes returns an error if too many files would be deleted on
+// the other side to s
+func (b *bisync) checkDeletes(s *side) error {
+	deletes := s.deltas.count(deltaDeleted)
+	if b.opt.Force || deletes == 0 || len(s.old) == 0 {
+		return nil
+	}
+	if percent := 100 * deletes / len(s.old); percent > b.opt.MaxDelete {
+		return fs.FatalError(errors.Errorf("%d of %d files (%d%%) deleted on %s %v which is more than --max-delete %d%% - aborting", deletes, len(s.old), percent, s.name, s.f, b.opt.MaxDelete))
+	}
+	return nil
+}
+
+// action is a single step needed to bring the sides into sync
+type action struct {
+	remote string
+	what   string
+	do     func() error
+}
+
+// run does a normal bisync
+func (b *bisync) run() error {
+	s1, err := b.newSide("path1", b.fs1, b.listing1)
+	if err != nil {
+		return err
+	}
+	s2, err := b.newSide("path2", b.fs2, b.listing2)
+	if err != nil {
+		return err
+	}
+	for _, s := range []*side{s1, s2} {
+		err = b.checkDeletes(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	actions := b.plan(s1, s2)
+	if len(actions) == 0 {
+		fs.Logf(nil, "No changes found")
+	}
+	var lastErr error
+	for _, a := range actions {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		if fs.Config.DryRun {
+			fs.Logf(a.remote, "Not %s as --dry-run", a.what)
+			continue
+		}
+		fs.Debugf(a.remote, "%s", a.what)
+		err = a.do()
+		if err != nil {
+			fs.Stats.Error()
+			fs.Errorf(a.remote, "Failed %s: %v", a.what, err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return errors.Wrap(lastErr, "not saving listings as there were errors")
+	}
+	return b.saveListings()
+}
+
+// plan works out the actions needed to bring s1 and s2 into sync
+func (b *bisync) plan(s1, s2 *side) (actions []action) {
+	for _, remote := range sortedRemotes(s1.deltas, s2.deltas) {
+		d1, d2 := s1.deltas[remote], s2.deltas[remote]
+		switch {
+		case d1 == deltaNone:
+			actions = b.propagate(actions, remote, s2, s1)
+		case d2 == deltaNone:
+			actions = b.propagate(actions, remote, s1, s2)
+		case d1 == deltaDeleted && d2 == deltaDeleted:
+			fs.Debugf(remote, "Deleted on both sides")
+		case d1 == deltaDeleted:
+			actions = b.copyAction(actions, remote, s2, s1)
+		case d2 == deltaDeleted:
+			actions = b.copyAction(actions, remote, s1, s2)
+		default:
+			// new or changed on both sides
+			if fs.Equal(b.ctx, s1.objs[remote], s2.objs[remote]) {
+				fs.Debugf(remote, "Changed identically on both sides")
+				continue
+			}
+			actions = b.conflictActions(actions, remote, s1, s2)
+		}
+	}
+	return actions
+}
+
+// propagate the change to remote on src to dst which is unchanged
+func (b *bisync) propagate(actions []action, remote string, src, dst *side) []action {
+	if src.deltas[remote] != deltaDeleted {
+		return b.copyAction(actions, remote, src, dst)
+	}
+	dstObj := dst.objs[remote]
+	if dstObj == nil {
+		return actions
+	}
+	return append(actions, action{
+		remote: remote,
+		what:   fmt.Sprintf("deleting from %s (deleted on %s)", dst.name, src.name),
+		do: func() error {
+			return fs.DeleteFile(b.ctx, dstObj)
+		},
+	})
+}
+
+// copyAction adds an action to copy remote from src to dst
+func (b *bisync) copyAction(actions []action, remote string, src, dst *side) []action {
+	srcObj, dstObj := src.objs[remote], dst.objs[remote]
+	return append(actions, action{
+		remote: remote,
+		what:   fmt.Sprintf("copying %s to %s (%s on %s)", src.name, dst.name, src.deltas[remote], src.name),
+		do: func() error {
+			return copyObject(b.ctx, dst.f, dstObj, remote, srcObj)
+		},
+	})
+}
+
+// conflictActions adds actions to keep both versions of remote which
+// has been changed differently on s1 and s2
+//
+// Each version is renamed with a suffix of the side it came from and
+// then copied to the other side.
+func (b *bisync) conflictActions(actions []action, remote string, s1, s2 *side) []action {
+	for _, x := range [][2]*side{{s1, s2}, {s2, s1}} {
+		src, dst := x[0], x[1]
+		srcObj := src.objs[remote]
+		newRemote := conflictName(remote, src.name, s1, s2)
+		actions = append(actions, action{
+			remote: remote,
+			what:   fmt.Sprintf("renaming %s version to %q and copying to %s (changed on both sides)", src.name, newRemote, dst.name),
+			do: func() error {
+				err := fs.Move(b.ctx, src.f, nil, newRemote, srcObj)
+				if err != nil {
+					return err
+				}
+				renamed, err := src.f.NewObject(b.ctx, newRemote)
+				if err != nil {
+					return err
+				}
+				return copyObject(b.ctx, dst.f, nil, newRemote, renamed)
+			},
+		})
+	}
+	return actions
+}
+
+// conflictName makes a name for the conflicting version of remote
+// from side which doesn't exist on either side
+func conflictName(remote, name string, s1, s2 *side) string {
+	newRemote := remote + ".." + name
+	for i := 1; ; i++ {
+		_, found1 := s1.cur[newRemote]
+		_, found2 := s2.cur[newRemote]
+		if !found1 && !found2 {
+			return newRemote
+		}
+		newRemote = fmt.Sprintf("%s..%s.%d", remote, name, i)
+	}
+}
+
+// copyObject copies src to remote on f accounting the transfer
+func copyObject(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object) error {
+	fs.Stats.Transferring(remote)
+	err := fs.Copy(ctx, f, dst, remote, src)
+	fs.Stats.DoneTransferring(remote, err == nil)
+	return err
+}
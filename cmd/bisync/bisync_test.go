@@ -0,0 +1,306 @@
+package bisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+	t2 = fstest.Time("2011-12-25T12:59:59.123456789Z")
+	t3 = fstest.Time("2011-12-30T12:59:59.000000000Z")
+)
+
+// bisyncRun holds the temporary directories for a test
+type bisyncRun struct {
+	t       *testing.T
+	dir     string
+	path1   string
+	path2   string
+	fs1     fs.Fs
+	fs2     fs.Fs
+	options Options
+}
+
+func newBisyncRun(t *testing.T) *bisyncRun {
+	fs.LoadConfig()
+	fs.Stats.ResetCounters()
+	dir, err := ioutil.TempDir("", "rclone-bisync-test")
+	require.NoError(t, err)
+	r := &bisyncRun{
+		t:     t,
+		dir:   dir,
+		path1: filepath.Join(dir, "path1"),
+		path2: filepath.Join(dir, "path2"),
+		options: Options{
+			MaxDelete: 50,
+			Workdir:   filepath.Join(dir, "workdir"),
+		},
+	}
+	require.NoError(t, os.Mkdir(r.path1, 0700))
+	require.NoError(t, os.Mkdir(r.path2, 0700))
+	r.fs1, err = fs.NewFs(r.path1)
+	require.NoError(t, err)
+	r.fs2, err = fs.NewFs(r.path2)
+	require.NoError(t, err)
+	fs.CalculateModifyWindow(r.fs1, r.fs2)
+	return r
+}
+
+func (r *bisyncRun) finalise() {
+	require.NoError(r.t, os.RemoveAll(r.dir))
+}
+
+// writeFile writes a file into the directory root
+func (r *bisyncRun) writeFile(root, remote, content string, modTime time.Time) fstest.Item {
+	name := filepath.Join(root, remote)
+	require.NoError(r.t, os.MkdirAll(filepath.Dir(name), 0700))
+	require.NoError(r.t, ioutil.WriteFile(name, []byte(content), 0600))
+	require.NoError(r.t, os.Chtimes(name, modTime, modTime))
+	return fstest.NewItem(remote, content, modTime)
+}
+
+// removeFile removes a file from the directory root
+func (r *bisyncRun) removeFile(root, remote string) {
+	require.NoError(r.t, os.Remove(filepath.Join(root, remote)))
+}
+
+func (r *bisyncRun) bisync() error {
+	return Bisync(context.Background(), r.fs1, r.fs2, &r.options)
+}
+
+func (r *bisyncRun) resync() {
+	r.options.Resync = true
+	require.NoError(r.t, r.bisync())
+	r.options.Resync = false
+}
+
+func TestBisyncNeedsResync(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	r.writeFile(r.path1, "one", "one", t1)
+
+	err := r.bisync()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--resync")
+	assert.True(t, fs.IsFatalError(err))
+}
+
+func TestBisyncResync(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	file1 := r.writeFile(r.path1, "one", "one", t1)
+	file2 := r.writeFile(r.path2, "sub/two", "two", t2)
+	r.writeFile(r.path2, "both", "path2 version", t1)
+	file3 := r.writeFile(r.path1, "both", "path1 version", t2)
+
+	r.resync()
+
+	fstest.CheckItems(t, r.fs1, file1, file2, file3)
+	fstest.CheckItems(t, r.fs2, file1, file2, file3)
+
+	// A second run should find nothing to do
+	require.NoError(t, r.bisync())
+	fstest.CheckItems(t, r.fs1, file1, file2, file3)
+	fstest.CheckItems(t, r.fs2, file1, file2, file3)
+}
+
+func TestBisyncPropagate(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	r.writeFile(r.path1, "changed1", "changed1", t1)
+	r.writeFile(r.path1, "changed2", "changed2", t1)
+	r.writeFile(r.path1, "deleted1", "deleted1", t1)
+	r.writeFile(r.path1, "deleted2", "deleted2", t1)
+	r.writeFile(r.path1, "deletedboth", "deletedboth", t1)
+	unchanged := r.writeFile(r.path1, "unchanged", "unchanged", t1)
+	r.resync()
+
+	changed1 := r.writeFile(r.path1, "changed1", "changed1 on path1", t2)
+	changed2 := r.writeFile(r.path2, "changed2", "changed2 on path2", t2)
+	new1 := r.writeFile(r.path1, "dir/new1", "new1", t2)
+	new2 := r.writeFile(r.path2, "dir/new2", "new2", t2)
+	r.removeFile(r.path1, "deleted1")
+	r.removeFile(r.path2, "deleted2")
+	r.removeFile(r.path1, "deletedboth")
+	r.removeFile(r.path2, "deletedboth")
+
+	r.options.Force = true
+	require.NoError(t, r.bisync())
+
+	fstest.CheckItems(t, r.fs1, changed1, changed2, new1, new2, unchanged)
+	fstest.CheckItems(t, r.fs2, changed1, changed2, new1, new2, unchanged)
+}
+
+func TestBisyncDeletedAndChanged(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	r.writeFile(r.path1, "file", "file", t1)
+	r.resync()
+
+	r.removeFile(r.path1, "file")
+	file := r.writeFile(r.path2, "file", "changed on path2", t2)
+
+	r.options.Force = true
+	require.NoError(t, r.bisync())
+
+	fstest.CheckItems(t, r.fs1, file)
+	fstest.CheckItems(t, r.fs2, file)
+}
+
+func TestBisyncConflict(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	r.writeFile(r.path1, "conflict", "original", t1)
+	r.writeFile(r.path1, "same", "original", t1)
+	r.resync()
+
+	r.writeFile(r.path1, "conflict", "changed on path1", t2)
+	r.writeFile(r.path2, "conflict", "changed on path2", t3)
+	same := r.writeFile(r.path1, "same", "changed the same", t2)
+	r.writeFile(r.path2, "same", "changed the same", t2)
+
+	require.NoError(t, r.bisync())
+
+	conflict1 := fstest.NewItem("conflict..path1", "changed on path1", t2)
+	conflict2 := fstest.NewItem("conflict..path2", "changed on path2", t3)
+	fstest.CheckItems(t, r.fs1, conflict1, conflict2, same)
+	fstest.CheckItems(t, r.fs2, conflict1, conflict2, same)
+
+	// The result should now be stable
+	require.NoError(t, r.bisync())
+	fstest.CheckItems(t, r.fs1, conflict1, conflict2, same)
+	fstest.CheckItems(t, r.fs2, conflict1, conflict2, same)
+}
+
+func TestBisyncDryRun(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	file1 := r.writeFile(r.path1, "one", "one", t1)
+	r.resync()
+
+	file2 := r.writeFile(r.path1, "two", "two", t2)
+
+	fs.Config.DryRun = true
+	err := r.bisync()
+	fs.Config.DryRun = false
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.fs1, file1, file2)
+	fstest.CheckItems(t, r.fs2, file1)
+
+	// The listings weren't updated so the real run still sees the change
+	require.NoError(t, r.bisync())
+	fstest.CheckItems(t, r.fs2, file1, file2)
+}
+
+func TestBisyncMaxDelete(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	file1 := r.writeFile(r.path1, "one", "one", t1)
+	r.writeFile(r.path1, "two", "two", t1)
+	r.writeFile(r.path1, "three", "three", t1)
+	r.resync()
+
+	r.removeFile(r.path1, "two")
+	r.removeFile(r.path1, "three")
+
+	err := r.bisync()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--max-delete")
+
+	// Nothing should have been deleted from path2
+	assert.Equal(t, 3, len(listNames(t, r.fs2)))
+
+	r.options.Force = true
+	require.NoError(t, r.bisync())
+	fstest.CheckItems(t, r.fs1, file1)
+	fstest.CheckItems(t, r.fs2, file1)
+}
+
+func TestBisyncLocked(t *testing.T) {
+	r := newBisyncRun(t)
+	defer r.finalise()
+	r.resync()
+
+	b := &bisync{lockFile: filepath.Join(r.options.Workdir, fileNameFor(r.fs1)+".."+fileNameFor(r.fs2)+".lck")}
+	unlock, err := b.lock()
+	require.NoError(t, err)
+
+	err = r.bisync()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already running")
+
+	unlock()
+	require.NoError(t, r.bisync())
+}
+
+func TestFindDeltas(t *testing.T) {
+	old := listing{
+		"unchanged": {Size: 1, ModTime: t1},
+		"changed":   {Size: 1, ModTime: t1},
+		"resized":   {Size: 1, ModTime: t1},
+		"rehashed":  {Size: 1, ModTime: t1, Hash: "aaaa"},
+		"deleted":   {Size: 1, ModTime: t1},
+	}
+	cur := listing{
+		"unchanged": {Size: 1, ModTime: t1},
+		"changed":   {Size: 1, ModTime: t2},
+		"resized":   {Size: 2, ModTime: t1},
+		"rehashed":  {Size: 1, ModTime: t1, Hash: "bbbb"},
+		"new":       {Size: 1, ModTime: t1},
+	}
+	assert.Equal(t, deltas{
+		"changed":  deltaChanged,
+		"resized":  deltaChanged,
+		"rehashed": deltaChanged,
+		"deleted":  deltaDeleted,
+		"new":      deltaNew,
+	}, findDeltas(old, cur))
+}
+
+func TestListingSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-bisync-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	name := filepath.Join(dir, "test.lst")
+
+	_, err = loadListing(name)
+	assert.True(t, os.IsNotExist(err))
+
+	ls := listing{
+		"a":     {Size: 1, ModTime: t1},
+		"b/c/d": {Size: 2, ModTime: t2, Hash: "abcd"},
+	}
+	require.NoError(t, ls.save(name))
+	got, err := loadListing(name)
+	require.NoError(t, err)
+	require.Equal(t, len(ls), len(got))
+	for remote, info := range ls {
+		assert.Equal(t, info.Size, got[remote].Size)
+		assert.True(t, info.ModTime.Equal(got[remote].ModTime))
+		assert.Equal(t, info.Hash, got[remote].Hash)
+	}
+}
+
+// listNames returns the remotes of all the objects in f
+func listNames(t *testing.T, f fs.Fs) (names []string) {
+	objs, _, err := fs.WalkGetAll(context.Background(), f, "", true, -1)
+	require.NoError(t, err)
+	for _, o := range objs {
+		names = append(names, o.Remote())
+	}
+	return names
+}
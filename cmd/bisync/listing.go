@@ -0,0 +1,222 @@
+package bisync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// listingVersion is bumped when the format of the listing file changes
+const listingVersion = 1
+
+// fileInfo is what is remembered about each file between runs
+type fileInfo struct {
+	Size    int64
+	ModTime time.Time
+	Hash    string `json:",omitempty"`
+}
+
+// listing is the snapshot of one side of the sync keyed by remote
+type listing map[string]fileInfo
+
+// listingFile is the on disk format of a listing
+type listingFile struct {
+	Version int
+	Files   listing
+}
+
+// makeListing reads the current state of f returning a listing and
+// the objects found keyed by remote.
+//
+// If --checksum is in use then a hash is recorded for each file too.
+func makeListing(ctx context.Context, f fs.Fs) (listing, map[string]fs.Object, error) {
+	hashType := fs.HashNone
+	if fs.Config.CheckSum {
+		hashType = f.Hashes().GetOne()
+	}
+	ls := listing{}
+	objs := map[string]fs.Object{}
+	err := fs.Walk(ctx, f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			info := fileInfo{
+				Size:    o.Size(),
+				ModTime: o.ModTime(),
+			}
+			if hashType != fs.HashNone {
+				info.Hash, err = o.Hash(hashType)
+				if err != nil {
+					return errors.Wrapf(err, "failed to read hash of %q", o.Remote())
+				}
+			}
+			ls[o.Remote()] = info
+			objs[o.Remote()] = o
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to list %v", f)
+	}
+	return ls, objs, nil
+}
+
+// loadListing reads a listing from the file name passed in
+//
+// It returns an error satisfying os.IsNotExist if the listing isn't
+// found.
+func loadListing(name string) (listing, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var lf listingFile
+	err = json.Unmarshal(data, &lf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse listing %q", name)
+	}
+	if lf.Version != listingVersion {
+		return nil, errors.Errorf("listing %q has unknown version %d - run with --resync", name, lf.Version)
+	}
+	if lf.Files == nil {
+		lf.Files = listing{}
+	}
+	return lf.Files, nil
+}
+
+// save writes the listing to the file name passed in
+//
+// The listing is written to a temporary file first and renamed into
+// place so an interrupted save can't corrupt the previous listing.
+func (ls listing) save(name string) error {
+	data, err := json.MarshalIndent(listingFile{
+		Version: listingVersion,
+		Files:   ls,
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create listing")
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrapf(err, "failed to save listing %q", name)
+	}
+	return nil
+}
+
+// delta describes how a file changed since the last run
+type delta byte
+
+// Types of delta
+const (
+	deltaNone delta = iota
+	deltaNew
+	deltaChanged
+	deltaDeleted
+)
+
+// String turns a delta into a human readable form
+func (d delta) String() string {
+	switch d {
+	case deltaNone:
+		return "unchanged"
+	case deltaNew:
+		return "new"
+	case deltaChanged:
+		return "changed"
+	case deltaDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// deltas is the set of changes to one side since the last run
+type deltas map[string]delta
+
+// count returns the number of deltas of type d
+func (ds deltas) count(d delta) (n int) {
+	for _, x := range ds {
+		if x == d {
+			n++
+		}
+	}
+	return n
+}
+
+// changed returns true if the file info has changed between old and
+// cur.
+//
+// The size and hash (if recorded in both) must match and the
+// modification times must agree within the modify window.
+func changed(old, cur fileInfo) bool {
+	if old.Size != cur.Size {
+		return true
+	}
+	if old.Hash != "" && cur.Hash != "" && old.Hash != cur.Hash {
+		return true
+	}
+	if fs.Config.ModifyWindow == fs.ModTimeNotSupported {
+		return false
+	}
+	dt := cur.ModTime.Sub(old.ModTime)
+	return dt >= fs.Config.ModifyWindow || dt <= -fs.Config.ModifyWindow
+}
+
+// findDeltas works out what has changed from old to cur
+func findDeltas(old, cur listing) deltas {
+	ds := deltas{}
+	for remote, curInfo := range cur {
+		oldInfo, found := old[remote]
+		if !found {
+			ds[remote] = deltaNew
+		} else if changed(oldInfo, curInfo) {
+			ds[remote] = deltaChanged
+		}
+	}
+	for remote := range old {
+		if _, found := cur[remote]; !found {
+			ds[remote] = deltaDeleted
+		}
+	}
+	return ds
+}
+
+// sortedRemotes returns the union of the remotes in ds1 and ds2 in
+// sorted order
+func sortedRemotes(ds1, ds2 deltas) []string {
+	seen := make(map[string]struct{}, len(ds1)+len(ds2))
+	var remotes []string
+	for _, ds := range []deltas{ds1, ds2} {
+		for remote := range ds {
+			if _, found := seen[remote]; !found {
+				seen[remote] = struct{}{}
+				remotes = append(remotes, remote)
+			}
+		}
+	}
+	sort.Strings(remotes)
+	return remotes
+}
@@ -0,0 +1,62 @@
+package cachestats
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ncw/rclone/cache"
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "cachestats source:",
+	Short: `Print cache stats for a remote`,
+	Long: `
+Print cache stats for a remote in JSON format.
+
+This shows where the cache for the remote is stored, how many
+directory listings and chunks of file data are in it, and how many
+cache hits and misses there have been while this command ran.
+
+The remote must be a cache remote, or a remote which wraps one, eg a
+crypt remote of a cache remote.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			return cacheStats(fsrc)
+		})
+	},
+}
+
+// cacheStats prints the stats of the cache remote f, or the cache
+// remote f wraps
+func cacheStats(f fs.Fs) error {
+	for {
+		if fcache, ok := f.(*cache.Fs); ok {
+			stats, err := fcache.Stats()
+			if err != nil {
+				return err
+			}
+			raw, err := json.MarshalIndent(stats, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to encode stats")
+			}
+			fmt.Printf("%s\n", raw)
+			return nil
+		}
+		doUnWrap := f.Features().UnWrap
+		if doUnWrap == nil {
+			return errors.Errorf("%s:%s is not a cache remote", f.Name(), f.Root())
+		}
+		f = doUnWrap()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 // Globals
@@ -37,9 +38,9 @@ to check all the data.
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(false, false, command, func() error {
 			if download {
-				return fs.CheckDownload(fdst, fsrc)
+				return fs.CheckDownload(context.Background(), fdst, fsrc)
 			}
-			return fs.Check(fdst, fsrc)
+			return fs.Check(context.Background(), fdst, fsrc)
 		})
 	},
 }
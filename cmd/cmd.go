@@ -20,6 +20,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/rc"
 )
 
 // Globals
@@ -192,6 +193,14 @@ func NewFsSrc(args []string) fs.Fs {
 	return fsrc
 }
 
+// NewFsFile creates a new fs from the arguments which may point to
+// a file
+//
+// It returns the file name if it does
+func NewFsFile(args []string) (fs.Fs, string) {
+	return newFsFile(args[0])
+}
+
 // NewFsDst creates a new dst fs from the arguments
 //
 // Dst fs-es can't point to single files
@@ -303,6 +312,12 @@ func initConfig() {
 	// Load the rest of the config now we have started the logger
 	fs.LoadConfig()
 
+	// Start the remote control server if configured
+	err := rc.Start()
+	if err != nil {
+		log.Fatalf("Failed to start remote control: %v", err)
+	}
+
 	// Write the args for debug purposes
 	fs.Debugf("rclone", "Version %q starting with parameters %q", fs.Version, os.Args)
 
@@ -26,6 +26,7 @@ type FS struct {
 	openDirs    *openFiles
 	openFilesWr *openFiles
 	openFilesRd *openFiles
+	openFilesRW *openFiles
 	ready       chan (struct{})
 }
 
@@ -37,6 +38,7 @@ func NewFS(f fs.Fs) *FS {
 		openDirs:    newOpenFiles(0x01),
 		openFilesWr: newOpenFiles(0x02),
 		openFilesRd: newOpenFiles(0x03),
+		openFilesRW: newOpenFiles(0x04),
 		ready:       make(chan (struct{})),
 	}
 	return fsys
@@ -163,6 +165,8 @@ func (fsys *FS) getOpenFilesFromFh(fh uint64) (of *openFiles, errc int) {
 		return fsys.openFilesRd, 0
 	case fsys.openFilesWr.InRange(fh):
 		return fsys.openFilesWr, 0
+	case fsys.openFilesRW.InRange(fh):
+		return fsys.openFilesRW, 0
 	case fsys.openDirs.InRange(fh):
 		return fsys.openDirs, 0
 	}
@@ -335,6 +339,21 @@ func (fsys *FS) Statfs(path string, stat *fuse.Statfs_t) (errc int) {
 	return 0
 }
 
+// openHandle stores the file handle in the right open files table
+// returning a fh
+func (fsys *FS) openHandle(handle mountlib.Noder) (errc int, fh uint64) {
+	switch handle.(type) {
+	case *mountlib.ReadFileHandle:
+		return 0, fsys.openFilesRd.Open(handle)
+	case *mountlib.WriteFileHandle:
+		return 0, fsys.openFilesWr.Open(handle)
+	case *mountlib.RWFileHandle:
+		return 0, fsys.openFilesRW.Open(handle)
+	}
+	fs.Errorf(handle, "Unknown file handle type %T", handle)
+	return -fuse.EIO, fhUnset
+}
+
 // Open opens a file
 func (fsys *FS) Open(path string, flags int) (errc int, fh uint64) {
 	defer fs.Trace(path, "flags=0x%X", flags)("errc=%d, fh=0x%X", &errc, &fh)
@@ -342,28 +361,11 @@ func (fsys *FS) Open(path string, flags int) (errc int, fh uint64) {
 	if errc != 0 {
 		return errc, fhUnset
 	}
-	rdwrMode := flags & fuse.O_ACCMODE
-	var err error
-	var handle mountlib.Noder
-	switch {
-	case rdwrMode == fuse.O_RDONLY:
-		handle, err = file.OpenRead()
-		if err != nil {
-			return translateError(err), fhUnset
-		}
-		return 0, fsys.openFilesRd.Open(handle)
-	case rdwrMode == fuse.O_WRONLY || (rdwrMode == fuse.O_RDWR && (flags&fuse.O_TRUNC) != 0):
-		handle, err = file.OpenWrite()
-		if err != nil {
-			return translateError(err), fhUnset
-		}
-		return 0, fsys.openFilesWr.Open(handle)
-	case rdwrMode == fuse.O_RDWR:
-		fs.Errorf(path, "Can't open for Read and Write")
-		return -fuse.EPERM, fhUnset
+	handle, err := file.Open(flags)
+	if err != nil {
+		return translateError(err), fhUnset
 	}
-	fs.Errorf(path, "Can't figure out how to open with flags: 0x%X", flags)
-	return -fuse.EPERM, fhUnset
+	return fsys.openHandle(handle)
 }
 
 // Create creates and opens a file.
@@ -373,16 +375,25 @@ func (fsys *FS) Create(filePath string, flags int, mode uint32) (errc int, fh ui
 	if errc != 0 {
 		return errc, fhUnset
 	}
-	_, handle, err := parentDir.Create(leaf)
+	_, handle, err := parentDir.Create(leaf, flags)
 	if err != nil {
 		return translateError(err), fhUnset
 	}
-	return 0, fsys.openFilesWr.Open(handle)
+	return fsys.openHandle(handle)
 }
 
 // Truncate truncates a file to size
 func (fsys *FS) Truncate(path string, size int64, fh uint64) (errc int) {
 	defer fs.Trace(path, "size=%d, fh=0x%X", size, fh)("errc=%d", &errc)
+	if fh != fhUnset {
+		handle, errc := fsys.getHandleFromFh(fh)
+		if errc != 0 {
+			return errc
+		}
+		if rwfh, ok := handle.(*mountlib.RWFileHandle); ok {
+			return translateError(rwfh.Truncate(size))
+		}
+	}
 	node, errc := fsys.getNode(path, fh)
 	if errc != 0 {
 		return errc
@@ -391,32 +402,27 @@ func (fsys *FS) Truncate(path string, size int64, fh uint64) (errc int) {
 	if !ok {
 		return -fuse.EIO
 	}
-	// Read the size so far
-	_, currentSize, _, err := file.Attr(true)
-	if err != nil {
-		return translateError(err)
-	}
-	fs.Debugf(path, "truncate to %d, currentSize %d", size, currentSize)
-	if int64(currentSize) != size {
-		fs.Errorf(path, "Can't truncate files")
-		return -fuse.EPERM
-	}
-	return 0
+	return translateError(file.Truncate(size))
 }
 
 func (fsys *FS) Read(path string, buff []byte, ofst int64, fh uint64) (n int) {
 	defer fs.Trace(path, "ofst=%d, fh=0x%X", ofst, fh)("n=%d", &n)
 	// FIXME detect seek
-	handle, errc := fsys.openFilesRd.Get(fh)
+	handle, errc := fsys.getHandleFromFh(fh)
 	if errc != 0 {
 		return errc
 	}
-	rfh, ok := handle.(*mountlib.ReadFileHandle)
-	if !ok {
+	var data []byte
+	var err error
+	switch x := handle.(type) {
+	case *mountlib.ReadFileHandle:
+		data, err = x.Read(int64(len(buff)), ofst)
+	case *mountlib.RWFileHandle:
+		data, err = x.Read(int64(len(buff)), ofst)
+	default:
 		// Can only read from read file handle
 		return -fuse.EIO
 	}
-	data, err := rfh.Read(int64(len(buff)), ofst)
 	if err != nil {
 		return translateError(err)
 	}
@@ -427,17 +433,22 @@ func (fsys *FS) Read(path string, buff []byte, ofst int64, fh uint64) (n int) {
 func (fsys *FS) Write(path string, buff []byte, ofst int64, fh uint64) (n int) {
 	defer fs.Trace(path, "ofst=%d, fh=0x%X", ofst, fh)("n=%d", &n)
 	// FIXME detect seek
-	handle, errc := fsys.openFilesWr.Get(fh)
+	handle, errc := fsys.getHandleFromFh(fh)
 	if errc != 0 {
 		return errc
 	}
-	wfh, ok := handle.(*mountlib.WriteFileHandle)
-	if !ok {
+	var n64 int64
+	var err error
+	// FIXME made Write return int and Read take int since must fit in RAM
+	switch x := handle.(type) {
+	case *mountlib.WriteFileHandle:
+		n64, err = x.Write(buff, ofst)
+	case *mountlib.RWFileHandle:
+		n64, err = x.Write(buff, ofst)
+	default:
 		// Can only write to write file handle
 		return -fuse.EIO
 	}
-	// FIXME made Write return int and Read take int since must fit in RAM
-	n64, err := wfh.Write(buff, ofst)
 	if err != nil {
 		return translateError(err)
 	}
@@ -457,6 +468,8 @@ func (fsys *FS) Flush(path string, fh uint64) (errc int) {
 		err = x.Flush()
 	case *mountlib.WriteFileHandle:
 		err = x.Flush()
+	case *mountlib.RWFileHandle:
+		err = x.Flush()
 	default:
 		return -fuse.EIO
 	}
@@ -481,6 +494,8 @@ func (fsys *FS) Release(path string, fh uint64) (errc int) {
 		err = x.Release()
 	case *mountlib.WriteFileHandle:
 		err = x.Release()
+	case *mountlib.RWFileHandle:
+		err = x.Release()
 	default:
 		return -fuse.EIO
 	}
@@ -656,6 +671,8 @@ func translateError(err error) (errc int) {
 			return -fuse.EBADF
 		case mountlib.EROFS:
 			return -fuse.EROFS
+		case mountlib.EPERM:
+			return -fuse.EPERM
 		}
 	}
 	fs.Errorf(nil, "IO error: %v", err)
@@ -4,6 +4,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
@@ -57,7 +58,7 @@ the destination directory or not.
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(true, true, command, func() error {
-			return fs.CopyDir(fdst, fsrc)
+			return fs.CopyDir(context.Background(), fdst, fsrc)
 		})
 	},
 }
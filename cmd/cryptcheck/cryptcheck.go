@@ -6,6 +6,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
@@ -79,7 +80,7 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 		if underlyingHash == "" {
 			return false, true
 		}
-		cryptHash, err := fcrypt.ComputeHash(cryptDst, src, hashType)
+		cryptHash, err := fcrypt.ComputeHash(context.Background(), cryptDst, src, hashType)
 		if err != nil {
 			fs.Stats.Error()
 			fs.Errorf(dst, "Error computing hash: %v", err)
@@ -97,5 +98,5 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 		return false, false
 	}
 
-	return fs.CheckFn(fcrypt, fsrc, checkIdentical)
+	return fs.CheckFn(context.Background(), fcrypt, fsrc, checkIdentical)
 }
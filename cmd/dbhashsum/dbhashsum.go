@@ -6,6 +6,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
@@ -25,7 +26,7 @@ The output is in the same format as md5sum and sha1sum.
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			return fs.DropboxHashSum(fsrc, os.Stdout)
+			return fs.DropboxHashSum(context.Background(), fsrc, os.Stdout)
 		})
 	},
 }
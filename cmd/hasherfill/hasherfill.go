@@ -0,0 +1,81 @@
+package hasherfill
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/hasher"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "hasherfill remote:path",
+	Short: `Calculate and store the hashes of a hasher remote.`,
+	Long: `
+Reads all the files in the path of a hasher remote whose hashes
+aren't already stored and stores their hashes.
+
+This means that later commands, eg ` + "`rclone check`" + ` or ` + "`rclone sync --checksum`" + `,
+can use the hashes without reading the files.
+
+Files are read ` + "`--checkers`" + ` at a time.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			return hasherFill(context.Background(), fsrc)
+		})
+	},
+}
+
+// hasherFill fills in the missing hashes of all the objects in f
+func hasherFill(ctx context.Context, f fs.Fs) error {
+	if _, ok := f.(*hasher.Fs); !ok {
+		return errors.Errorf("%s:%s is not a hasher remote", f.Name(), f.Root())
+	}
+	var (
+		wg     sync.WaitGroup
+		errs   int32
+		objs   = make(chan *hasher.Object, fs.Config.Checkers)
+		reader = func() {
+			defer wg.Done()
+			for o := range objs {
+				fs.Stats.Checking(o.Remote())
+				err := o.Fill(ctx)
+				fs.Stats.DoneChecking(o.Remote())
+				if err != nil {
+					fs.Stats.Error()
+					fs.Errorf(o, "Failed to read hashes: %v", err)
+					atomic.AddInt32(&errs, 1)
+				}
+			}
+		}
+	)
+	wg.Add(fs.Config.Checkers)
+	for i := 0; i < fs.Config.Checkers; i++ {
+		go reader()
+	}
+	err := fs.ListFn(ctx, f, func(o fs.Object) {
+		if o, ok := o.(*hasher.Object); ok {
+			objs <- o
+		}
+	})
+	close(objs)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if errs > 0 {
+		return errors.Errorf("failed to read hashes of %d files", errs)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package link
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Globals
+var (
+	expire = time.Duration(0)
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().DurationVarP(&expire, "expire", "", expire, "The link expires after this time, eg 24h.")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "link remote:path",
+	Short: `Generate a public link to a file.`,
+	Long: `
+rclone link makes a link which anyone can use to download the file
+at remote:path without needing any credentials, and prints it.  Use
+this to share a file with someone who doesn't use rclone.
+
+    rclone link remote:path/to/file
+    rclone link --expire 24h s3:bucket/path/to/file
+
+Use the --expire flag to make a link which stops working after that
+time.  How the link is made depends on the remote:
+
+  * S3 - a pre-signed URL which lasts for --expire or 1 week if not set
+  * B2 - a download authorization which lasts for --expire or 1 week if not set
+  * Swift - a temporary URL which lasts for --expire or 1 week if not set
+  * Google Drive, Dropbox and OneDrive - the file is shared with anyone who has the link
+
+Google Drive and Dropbox can't make links which expire so an error is
+returned if --expire is set.  OneDrive can only make links which
+expire on OneDrive Personal.
+
+B2 authorizes downloads by file name prefix, so a B2 link also works
+for any other files in the bucket whose names start with the name of
+the file, eg a link to "file" can be used to read "file.bak" too.
+
+Note that anyone who has the link can read the file until it expires
+or the sharing is removed, so take care where you send it.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc, remote := cmd.NewFsFile(args)
+		cmd.Run(false, false, command, func() error {
+			link, err := fs.PublicLink(context.Background(), fsrc, remote, expire)
+			if err != nil {
+				return err
+			}
+			fmt.Println(link)
+			return nil
+		})
+	},
+}
@@ -138,11 +138,15 @@ var _ fusefs.NodeCreater = (*Dir)(nil)
 // Create makes a new file
 func (d *Dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (node fusefs.Node, handle fusefs.Handle, err error) {
 	defer fs.Trace(d, "name=%q", req.Name)("node=%v, handle=%v, err=%v", &node, &handle, &err)
-	file, fh, err := d.Dir.Create(req.Name)
+	file, fh, err := d.Dir.Create(req.Name, int(req.Flags))
 	if err != nil {
 		return nil, nil, translateError(err)
 	}
-	return &File{file}, &WriteFileHandle{fh}, err
+	handle, err = wrapHandle(fh, &resp.OpenResponse)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &File{file}, handle, nil
 }
 
 var _ fusefs.NodeMkdirer = (*Dir)(nil)
@@ -49,16 +49,18 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 // Check interface satisfied
 var _ fusefs.NodeSetattrer = (*File)(nil)
 
-// Setattr handles attribute changes from FUSE. Currently supports ModTime only.
+// Setattr handles attribute changes from FUSE. Currently supports ModTime and Size only
 func (f *File) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) (err error) {
 	defer fs.Trace(f, "a=%+v", req)("err=%v", &err)
-	if mountlib.NoModTime {
-		return nil
+	if !mountlib.NoModTime {
+		if req.Valid.MtimeNow() {
+			err = f.File.SetModTime(time.Now())
+		} else if req.Valid.Mtime() {
+			err = f.File.SetModTime(req.Mtime)
+		}
 	}
-	if req.Valid.MtimeNow() {
-		err = f.File.SetModTime(time.Now())
-	} else if req.Valid.Mtime() {
-		err = f.File.SetModTime(req.Mtime)
+	if err == nil && req.Valid.Size() {
+		err = f.File.Truncate(int64(req.Size))
 	}
 	return translateError(err)
 }
@@ -69,41 +71,29 @@ var _ fusefs.NodeOpener = (*File)(nil)
 // Open the file for read or write
 func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fh fusefs.Handle, err error) {
 	defer fs.Trace(f, "flags=%v", req.Flags)("fh=%v, err=%v", &fh, &err)
-	switch {
-	case req.Flags.IsReadOnly():
+	handle, err := f.File.Open(int(req.Flags))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return wrapHandle(handle, resp)
+}
+
+// wrapHandle wraps the file handle returned by mountlib so it can be
+// used by bazil, setting the open flags in resp
+func wrapHandle(handle mountlib.Noder, resp *fuse.OpenResponse) (fh fusefs.Handle, err error) {
+	switch x := handle.(type) {
+	case *mountlib.ReadFileHandle:
 		if mountlib.NoSeek {
 			resp.Flags |= fuse.OpenNonSeekable
 		}
-		var rfh *mountlib.ReadFileHandle
-		rfh, err = f.File.OpenRead()
-		fh = &ReadFileHandle{rfh}
-	case req.Flags.IsWriteOnly() || (req.Flags.IsReadWrite() && (req.Flags&fuse.OpenTruncate) != 0):
+		return &ReadFileHandle{x}, nil
+	case *mountlib.WriteFileHandle:
 		resp.Flags |= fuse.OpenNonSeekable
-		var wfh *mountlib.WriteFileHandle
-		wfh, err = f.File.OpenWrite()
-		fh = &WriteFileHandle{wfh}
-	case req.Flags.IsReadWrite():
-		err = errors.New("can't open for read and write simultaneously")
-	default:
-		err = errors.Errorf("can't figure out how to open with flags %v", req.Flags)
-	}
-
-	/*
-	   // File was opened in append-only mode, all writes will go to end
-	   // of file. OS X does not provide this information.
-	   OpenAppend    OpenFlags = syscall.O_APPEND
-	   OpenCreate    OpenFlags = syscall.O_CREAT
-	   OpenDirectory OpenFlags = syscall.O_DIRECTORY
-	   OpenExclusive OpenFlags = syscall.O_EXCL
-	   OpenNonblock  OpenFlags = syscall.O_NONBLOCK
-	   OpenSync      OpenFlags = syscall.O_SYNC
-	   OpenTruncate  OpenFlags = syscall.O_TRUNC
-	*/
-
-	if err != nil {
-		return nil, translateError(err)
+		return &WriteFileHandle{x}, nil
+	case *mountlib.RWFileHandle:
+		return &RWFileHandle{x}, nil
 	}
-	return fh, nil
+	return nil, errors.Errorf("unknown file handle type %T", handle)
 }
 
 // Check interface satisfied
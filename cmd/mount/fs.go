@@ -85,6 +85,8 @@ func translateError(err error) error {
 			return fuse.Errno(syscall.EBADF)
 		case mountlib.EROFS:
 			return fuse.Errno(syscall.EROFS)
+		case mountlib.EPERM:
+			return fuse.EPERM
 		}
 	}
 	return err
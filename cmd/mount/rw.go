@@ -0,0 +1,72 @@
+// +build linux darwin freebsd
+
+package mount
+
+import (
+	"bazil.org/fuse"
+	fusefs "bazil.org/fuse/fs"
+	"github.com/ncw/rclone/cmd/mountlib"
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/net/context"
+)
+
+// RWFileHandle is an open for read and write file handle on a File
+// which is buffered in the file cache
+type RWFileHandle struct {
+	*mountlib.RWFileHandle
+}
+
+// Check interface satisfied
+var _ fusefs.Handle = (*RWFileHandle)(nil)
+
+// Check interface satisfied
+var _ fusefs.HandleReader = (*RWFileHandle)(nil)
+
+// Read from the file handle
+func (fh *RWFileHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) (err error) {
+	dataRead := -1
+	defer fs.Trace(fh, "len=%d, offset=%d", req.Size, req.Offset)("read=%d, err=%v", &dataRead, &err)
+	data, err := fh.RWFileHandle.Read(int64(req.Size), req.Offset)
+	if err != nil {
+		return translateError(err)
+	}
+	resp.Data = data
+	dataRead = len(data)
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.HandleWriter = (*RWFileHandle)(nil)
+
+// Write data to the file handle
+func (fh *RWFileHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) (err error) {
+	defer fs.Trace(fh, "len=%d, offset=%d", len(req.Data), req.Offset)("written=%d, err=%v", &resp.Size, &err)
+	n, err := fh.RWFileHandle.Write(req.Data, req.Offset)
+	if err != nil {
+		return translateError(err)
+	}
+	resp.Size = int(n)
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.HandleFlusher = (*RWFileHandle)(nil)
+
+// Flush is called each time the file or directory is closed.
+// Because there can be multiple file descriptors referring to a
+// single opened file, Flush can be called multiple times.
+func (fh *RWFileHandle) Flush(ctx context.Context, req *fuse.FlushRequest) (err error) {
+	defer fs.Trace(fh, "")("err=%v", &err)
+	return translateError(fh.RWFileHandle.Flush())
+}
+
+var _ fusefs.HandleReleaser = (*RWFileHandle)(nil)
+
+// Release is called when we are finished with the file handle
+//
+// It isn't called directly from userspace so the error is ignored by
+// the kernel
+func (fh *RWFileHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) (err error) {
+	defer fs.Trace(fh, "")("err=%v", &err)
+	return translateError(fh.RWFileHandle.Release())
+}
@@ -0,0 +1,414 @@
+// This deals with caching of files locally
+
+package mountlib
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"golang.org/x/net/context"
+)
+
+// CacheMode controls the functionality of the cache
+type CacheMode byte
+
+// CacheMode options
+const (
+	CacheModeOff     CacheMode = iota // cache nothing - return errors for writes which can't be satisfied
+	CacheModeMinimal                  // cache only the minimum, eg read/write opens
+	CacheModeWrites                   // cache all files opened with write intent
+	CacheModeFull                     // cache all files opened in any mode
+)
+
+var cacheModeToString = []string{
+	CacheModeOff:     "off",
+	CacheModeMinimal: "minimal",
+	CacheModeWrites:  "writes",
+	CacheModeFull:    "full",
+}
+
+// String turns a CacheMode into a string
+func (l CacheMode) String() string {
+	if l >= CacheMode(len(cacheModeToString)) {
+		return fmt.Sprintf("CacheMode(%d)", l)
+	}
+	return cacheModeToString[l]
+}
+
+// Set a CacheMode
+func (l *CacheMode) Set(s string) error {
+	for n, name := range cacheModeToString {
+		if s != "" && name == s {
+			*l = CacheMode(n)
+			return nil
+		}
+	}
+	return errors.Errorf("Unknown cache mode level %q", s)
+}
+
+// Type of the value
+func (l *CacheMode) Type() string {
+	return "string"
+}
+
+// Check it satisfies the interface
+var _ pflag.Value = (*CacheMode)(nil)
+
+// cache opened files
+type cache struct {
+	f            fs.Fs                 // fs for the remote we are caching
+	fcache       fs.Fs                 // fs for the cache directory
+	root         string                // root of the cache directory
+	maxAge       time.Duration         // remove files not accessed for this long
+	maxSize      fs.SizeSuffix         // remove files while the cache is bigger than this, if >= 0
+	pollInterval time.Duration         // interval to check the cache at
+	itemMu       sync.Mutex            // protects the following variables
+	item         map[string]*cacheItem // files in the cache
+}
+
+// cacheItem is stored in the item map
+type cacheItem struct {
+	mu     sync.Mutex // protects the following variables and the cached file while it is opened
+	opens  int        // number of times file is open
+	atime  time.Time  // last time file was accessed
+	loaded bool       // set if the cached file is valid for the current opens
+}
+
+// newCacheItem returns an item for the cache
+func newCacheItem() *cacheItem {
+	return &cacheItem{atime: time.Now()}
+}
+
+// newCache creates a new cache heirachy for f
+//
+// This starts a background goroutine to clean the cache.
+func newCache(f fs.Fs) (*cache, error) {
+	fRoot := filepath.FromSlash(f.Root())
+	if runtime.GOOS == "windows" {
+		if strings.HasPrefix(fRoot, `\\?`) {
+			fRoot = fRoot[3:]
+		}
+		fRoot = strings.Replace(fRoot, ":", "", -1)
+	}
+	root := filepath.Join(fs.CacheDir, "vfs", fs.CacheName(f.Name()), fRoot)
+	fs.Debugf(nil, "vfs cache root is %q", root)
+
+	fcache, err := fs.NewFs(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cache remote")
+	}
+
+	c := &cache{
+		f:            f,
+		fcache:       fcache,
+		root:         root,
+		maxAge:       CacheMaxAge,
+		maxSize:      CacheMaxSize,
+		pollInterval: CachePollInterval,
+		item:         make(map[string]*cacheItem),
+	}
+
+	go c.cleaner()
+
+	return c, nil
+}
+
+// findParent returns the parent directory of name, or "" for the
+// root
+func findParent(name string) string {
+	parent := path.Dir(name)
+	if parent == "." || parent == "/" {
+		parent = ""
+	}
+	return parent
+}
+
+// toOSPath turns a remote relative name into an OS path in the cache
+func (c *cache) toOSPath(name string) string {
+	return filepath.Join(c.root, filepath.FromSlash(name))
+}
+
+// mkdir makes the directory for name in the cache and returns an os
+// path for the file
+func (c *cache) mkdir(name string) (string, error) {
+	parent := findParent(name)
+	leaf := path.Base(name)
+	parentPath := c.toOSPath(parent)
+	err := os.MkdirAll(parentPath, 0700)
+	if err != nil {
+		return "", errors.Wrap(err, "make cache directory failed")
+	}
+	return filepath.Join(parentPath, leaf), nil
+}
+
+// _get gets name from the cache or creates a new one
+//
+// must be called with itemMu held
+func (c *cache) _get(name string) *cacheItem {
+	item := c.item[name]
+	if item == nil {
+		item = newCacheItem()
+		c.item[name] = item
+	}
+	return item
+}
+
+// get gets name from the cache or creates a new one
+func (c *cache) get(name string) *cacheItem {
+	c.itemMu.Lock()
+	item := c._get(name)
+	c.itemMu.Unlock()
+	return item
+}
+
+// open marks name as open
+func (c *cache) open(name string) {
+	c.itemMu.Lock()
+	item := c._get(name)
+	item.opens++
+	item.atime = time.Now()
+	c.itemMu.Unlock()
+}
+
+// close marks name as closed
+func (c *cache) close(name string) {
+	c.itemMu.Lock()
+	item := c._get(name)
+	item.opens--
+	item.atime = time.Now()
+	if item.opens < 0 {
+		fs.Errorf(name, "cache: double close")
+	}
+	if item.opens <= 0 {
+		// The next open should check the cached file is still
+		// valid
+		item.loaded = false
+	}
+	c.itemMu.Unlock()
+}
+
+// isOpen returns whether name is currently open
+//
+// must be called with itemMu held
+func (c *cache) _isOpen(name string) bool {
+	item := c.item[name]
+	return item != nil && item.opens > 0
+}
+
+// remove should be called if name is deleted
+func (c *cache) remove(name string) {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	if c._isOpen(name) {
+		fs.Debugf(name, "Not removing open file from cache")
+		return
+	}
+	osPath := c.toOSPath(name)
+	err := os.Remove(osPath)
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(name, "Failed to remove from cache: %v", err)
+	} else {
+		fs.Debugf(name, "Removed from cache")
+	}
+	delete(c.item, name)
+}
+
+// rename should be called if name is renamed to newName
+//
+// The cached file is moved if possible otherwise it is discarded
+func (c *cache) rename(name, newName string) {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	if c._isOpen(name) || c._isOpen(newName) {
+		fs.Debugf(name, "Not renaming open file in cache")
+		return
+	}
+	osPath := c.toOSPath(name)
+	if _, err := os.Stat(osPath); err != nil {
+		return
+	}
+	newOSPath, err := c.mkdir(newName)
+	if err == nil {
+		err = os.Rename(osPath, newOSPath)
+	}
+	if err != nil {
+		fs.Errorf(name, "Failed to rename in cache to %q: %v", newName, err)
+		_ = os.Remove(osPath)
+		delete(c.item, name)
+		return
+	}
+	if item, ok := c.item[name]; ok {
+		delete(c.item, name)
+		c.item[newName] = item
+	}
+	fs.Debugf(name, "Renamed in cache to %q", newName)
+}
+
+// removeDir removes the directory name from the cache if it is empty
+func (c *cache) removeDir(name string) {
+	osPath := c.toOSPath(name)
+	err := os.Remove(osPath)
+	if err != nil && !os.IsNotExist(err) {
+		fs.Debugf(name, "Not removing directory from cache: %v", err)
+	}
+}
+
+// isFresh returns true if the cached file at osPath is a valid copy
+// of o
+func (c *cache) isFresh(osPath string, o fs.Object) bool {
+	fi, err := os.Stat(osPath)
+	if err != nil {
+		return false
+	}
+	if fi.Size() != o.Size() {
+		return false
+	}
+	dt := fi.ModTime().Sub(o.ModTime())
+	window := fs.Config.ModifyWindow
+	if precision := c.f.Precision(); precision > window {
+		window = precision
+	}
+	return dt >= -window && dt <= window
+}
+
+// fetch downloads o into the cache at name
+func (c *cache) fetch(name string, o fs.Object) error {
+	ctx := context.Background()
+	dst, err := c.fcache.NewObject(ctx, name)
+	if err == fs.ErrorObjectNotFound {
+		dst = nil
+	} else if err != nil {
+		return err
+	}
+	return fs.Copy(ctx, c.fcache, dst, name, o)
+}
+
+// store uploads the cached file at name to the remote returning the
+// new object
+func (c *cache) store(name string, dst fs.Object) (fs.Object, error) {
+	ctx := context.Background()
+	src, err := c.fcache.NewObject(ctx, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find cached file")
+	}
+	err = fs.Copy(ctx, c.f, dst, name, src)
+	if err != nil {
+		return nil, err
+	}
+	return c.f.NewObject(ctx, name)
+}
+
+// cachedFile is a file in the cache
+type cachedFile struct {
+	name  string
+	size  int64
+	atime time.Time
+}
+
+// cachedFiles sorts cached files by access time, oldest first
+type cachedFiles []cachedFile
+
+func (cf cachedFiles) Len() int           { return len(cf) }
+func (cf cachedFiles) Swap(i, j int)      { cf[i], cf[j] = cf[j], cf[i] }
+func (cf cachedFiles) Less(i, j int) bool { return cf[i].atime.Before(cf[j].atime) }
+
+// clean empties the cache of stuff if it can
+func (c *cache) clean() {
+	// Find all the files in the cache
+	var files cachedFiles
+	err := filepath.Walk(c.root, func(osPath string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(c.root, osPath)
+		if err != nil {
+			return nil
+		}
+		files = append(files, cachedFile{
+			name:  filepath.ToSlash(rel),
+			size:  fi.Size(),
+			atime: fi.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		fs.Errorf(nil, "Failed to walk cache %q: %v", c.root, err)
+		return
+	}
+
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+
+	// Use the access times we know about in preference to the
+	// modification times of the files
+	var totalSize int64
+	for i := range files {
+		file := &files[i]
+		if item, ok := c.item[file.name]; ok && item.atime.After(file.atime) {
+			file.atime = item.atime
+		}
+		totalSize += file.size
+	}
+	sort.Sort(files)
+
+	// Remove files which are too old or while the cache is too big
+	cutoff := time.Now().Add(-c.maxAge)
+	removed := 0
+	for _, file := range files {
+		tooOld := file.atime.Before(cutoff)
+		tooBig := c.maxSize >= 0 && totalSize > int64(c.maxSize)
+		if !tooOld && !tooBig {
+			break
+		}
+		if c._isOpen(file.name) {
+			continue
+		}
+		err := os.Remove(c.toOSPath(file.name))
+		if err != nil {
+			fs.Errorf(file.name, "Failed to remove from cache: %v", err)
+			continue
+		}
+		delete(c.item, file.name)
+		totalSize -= file.size
+		removed++
+		fs.Debugf(file.name, "Removed from cache")
+	}
+	if removed > 0 {
+		fs.Debugf(nil, "Cleaned the cache: removed %d files, %v remaining", removed, fs.SizeSuffix(totalSize))
+	}
+
+	// Remove items which are no longer in the cache and not open
+	for name, item := range c.item {
+		if item.opens <= 0 {
+			if _, err := os.Stat(c.toOSPath(name)); os.IsNotExist(err) {
+				delete(c.item, name)
+			}
+		}
+	}
+}
+
+// cleaner calls clean at regular intervals
+//
+// doesn't return
+func (c *cache) cleaner() {
+	if c.pollInterval <= 0 {
+		fs.Debugf(nil, "cache cleaner thread disabled because poll interval <= 0")
+		return
+	}
+	// Start cleaning the cache immediately
+	c.clean()
+	// Then every interval specified
+	for range time.Tick(c.pollInterval) {
+		c.clean()
+	}
+}
@@ -1,6 +1,7 @@
 package mountlib
 
 import (
+	"os"
 	"path"
 	"strings"
 	"sync"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // DirEntry describes the contents of a directory entry
@@ -129,7 +131,11 @@ func (d *Dir) addObject(o fs.BasicInfo, node Node) *DirEntry {
 		Node: node,
 	}
 	d.mu.Lock()
-	d.items[path.Base(o.Remote())] = item
+	// If the directory hasn't been read yet then the object will
+	// be found when it is
+	if d.items != nil {
+		d.items[path.Base(o.Remote())] = item
+	}
 	d.mu.Unlock()
 	return item
 }
@@ -155,7 +161,7 @@ func (d *Dir) readDir() error {
 		}
 		fs.Debugf(d.path, "Re-reading directory (%v old)", age)
 	}
-	entries, err := fs.ListDirSorted(d.f, false, d.path)
+	entries, err := fs.ListDirSorted(context.Background(), d.f, false, d.path)
 	if err == fs.ErrorDirNotFound {
 		// We treat directory not found as empty because we
 		// create directories on the fly
@@ -311,17 +317,27 @@ func (d *Dir) ReadDirAll() (items []*DirEntry, err error) {
 	return items, nil
 }
 
-// Create makes a new file
-func (d *Dir) Create(name string) (*File, *WriteFileHandle, error) {
+// Create makes a new file using the open flags passed in
+//
+// It returns a *WriteFileHandle or a *RWFileHandle depending on the
+// flags and the --cache-mode in use.
+func (d *Dir) Create(name string, flags int) (*File, Noder, error) {
 	if d.fsys.readOnly {
 		return nil, nil, EROFS
 	}
 	path := path.Join(d.path, name)
 	// fs.Debugf(path, "Dir.Create")
-	src := newCreateInfo(d.f, path)
 	// This gets added to the directory when the file is written
 	file := newFile(d, nil, name)
-	fh, err := newWriteFileHandle(d, file, src)
+	var fh Noder
+	var err error
+	cacheMode := d.fsys.cacheMode
+	if cacheMode >= CacheModeWrites || (flags&accessModeMask == os.O_RDWR && cacheMode >= CacheModeMinimal) {
+		fh, err = newRWFileHandle(d, file, path, flags|os.O_CREATE|os.O_TRUNC)
+	} else {
+		src := newCreateInfo(d.f, path)
+		fh, err = newWriteFileHandle(d, file, src)
+	}
 	if err != nil {
 		fs.Errorf(path, "Dir.Create error: %v", err)
 		return nil, nil, err
@@ -337,7 +353,7 @@ func (d *Dir) Mkdir(name string) (*Dir, error) {
 	}
 	path := path.Join(d.path, name)
 	// fs.Debugf(path, "Dir.Mkdir")
-	err := d.f.Mkdir(path)
+	err := d.f.Mkdir(context.Background(), path)
 	if err != nil {
 		fs.Errorf(path, "Dir.Mkdir failed to create directory: %v", err)
 		return nil, err
@@ -368,11 +384,14 @@ func (d *Dir) Remove(name string) error {
 	}
 	switch x := item.Obj.(type) {
 	case fs.Object:
-		err = x.Remove()
+		err = x.Remove(context.Background())
 		if err != nil {
 			fs.Errorf(path, "Dir.Remove file error: %v", err)
 			return err
 		}
+		if d.fsys.cache != nil {
+			d.fsys.cache.remove(path)
+		}
 	case *fs.Dir:
 		// Check directory is empty first
 		dir := item.Node.(*Dir)
@@ -386,11 +405,14 @@ func (d *Dir) Remove(name string) error {
 			return ENOTEMPTY
 		}
 		// remove directory
-		err = d.f.Rmdir(path)
+		err = d.f.Rmdir(context.Background(), path)
 		if err != nil {
 			fs.Errorf(path, "Dir.Remove failed to remove directory: %v", err)
 			return err
 		}
+		if d.fsys.cache != nil {
+			d.fsys.cache.removeDir(path)
+		}
 	default:
 		fs.Errorf(path, "Dir.Remove unknown type %T", item)
 		return errors.Errorf("unknown type %T", item)
@@ -427,12 +449,15 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
 		}
-		newObject, err := doMove(oldObject, newPath)
+		newObject, err := doMove(context.Background(), oldObject, newPath)
 		if err != nil {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
 		}
 		newObj = newObject
+		if d.fsys.cache != nil {
+			d.fsys.cache.rename(oldPath, newPath)
+		}
 		// Update the node with the new details
 		if oldNode != nil {
 			if oldFile, ok := oldNode.(*File); ok {
@@ -449,7 +474,7 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 		}
 		srcRemote := x.Name
 		dstRemote := newPath
-		err = doDirMove(d.f, srcRemote, dstRemote)
+		err = doDirMove(context.Background(), d.f, srcRemote, dstRemote)
 		if err != nil {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
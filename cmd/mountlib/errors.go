@@ -18,6 +18,7 @@ const (
 	ESPIPE
 	EBADF
 	EROFS
+	EPERM
 )
 
 var errorNames = []string{
@@ -28,6 +29,7 @@ var errorNames = []string{
 	ESPIPE:    "Illegal seek",
 	EBADF:     "Bad file descriptor",
 	EROFS:     "Read only file system",
+	EPERM:     "Operation not permitted",
 }
 
 // Error renders the error as a string
@@ -1,6 +1,7 @@
 package mountlib
 
 import (
+	"os"
 	"path"
 	"sync"
 	"sync/atomic"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // File represents a file
@@ -55,11 +57,19 @@ func (f *File) Node() Node {
 	return f
 }
 
+// remote returns the path of the file relative to the remote
+func (f *File) remote() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return path.Join(f.d.path, f.leaf)
+}
+
 // rename should be called to update f.o and f.d after a rename
 func (f *File) rename(d *Dir, o fs.Object) {
 	f.mu.Lock()
 	f.o = o
 	f.d = d
+	f.leaf = path.Base(o.Remote())
 	f.mu.Unlock()
 }
 
@@ -121,7 +131,7 @@ func (f *File) applyPendingModTime() error {
 		return errors.New("Cannot apply ModTime, file object is not available")
 	}
 
-	err := f.o.SetModTime(f.pendingModTime)
+	err := f.o.SetModTime(context.Background(), f.pendingModTime)
 	switch err {
 	case nil:
 		fs.Debugf(f.o, "File.applyPendingModTime OK")
@@ -212,6 +222,86 @@ func (f *File) OpenWrite() (fh *WriteFileHandle, err error) {
 	return fh, nil
 }
 
+// OpenRW open the file for read and write using a temporary file
+//
+// It uses the open flags passed in.
+func (f *File) OpenRW(flags int) (fh *RWFileHandle, err error) {
+	if flags&accessModeMask != os.O_RDONLY && f.d.fsys.readOnly {
+		return nil, EROFS
+	}
+	// fs.Debugf(f, "File.OpenRW")
+
+	fh, err = newRWFileHandle(f.d, f, f.remote(), flags)
+	err = errors.Wrap(err, "open for read write")
+
+	if err != nil {
+		fs.Errorf(f, "File.OpenRW failed: %v", err)
+		return nil, err
+	}
+	return fh, nil
+}
+
+// Open a file according to the flags provided
+//
+// It returns a *ReadFileHandle, *WriteFileHandle or *RWFileHandle
+// depending on the open flags and the --cache-mode in use.
+func (f *File) Open(flags int) (fh Noder, err error) {
+	rdwrMode := flags & accessModeMask
+	cacheMode := f.d.fsys.cacheMode
+	switch {
+	case rdwrMode == os.O_RDONLY && cacheMode >= CacheModeFull:
+		return f.OpenRW(flags)
+	case rdwrMode == os.O_RDONLY:
+		return f.OpenRead()
+	case cacheMode >= CacheModeWrites:
+		return f.OpenRW(flags)
+	case rdwrMode == os.O_RDWR && cacheMode >= CacheModeMinimal:
+		return f.OpenRW(flags)
+	case rdwrMode == os.O_WRONLY || flags&os.O_TRUNC != 0:
+		// Open for write only or read/write with truncate
+		// can be done without a cache, but can only write
+		// sequentially
+		return f.OpenWrite()
+	}
+	fs.Errorf(f, "Can't open for read and write without cache")
+	return nil, EPERM
+}
+
+// Truncate changes the size of the file
+//
+// This needs --cache-mode writes or above unless the size isn't
+// changing or the file is being truncated to 0 before being opened
+// for write
+func (f *File) Truncate(size int64) (err error) {
+	if f.d.fsys.readOnly {
+		return EROFS
+	}
+	_, currentSize, _, _ := f.Attr(true)
+	if uint64(size) == currentSize {
+		return nil
+	}
+	if f.d.fsys.cacheMode < CacheModeWrites {
+		if size == 0 {
+			// The kernel truncates files before opening
+			// them for write with O_TRUNC
+			fs.Debugf(f, "Ignoring truncate to 0 - needs --cache-mode writes")
+			return nil
+		}
+		fs.Errorf(f, "Can't truncate file without --cache-mode writes or above")
+		return EPERM
+	}
+	fh, err := f.OpenRW(os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+	err = fh.Truncate(size)
+	releaseErr := fh.Release()
+	if err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
 // Fsync the file
 //
 // Note that we don't do anything except return OK
@@ -31,6 +31,7 @@ var (
 	_ Noder = (*Dir)(nil)
 	_ Noder = (*ReadFileHandle)(nil)
 	_ Noder = (*WriteFileHandle)(nil)
+	_ Noder = (*RWFileHandle)(nil)
 )
 
 // FS represents the top level filing system
@@ -41,6 +42,8 @@ type FS struct {
 	noChecksum   bool          // don't check checksums if set
 	readOnly     bool          // if set FS is read only
 	dirCacheTime time.Duration // how long to consider directory listing cache valid
+	cacheMode    CacheMode     // which files to cache locally
+	cache        *cache        // the local file cache, nil if not in use
 }
 
 // NewFS creates a new filing system and root directory
@@ -64,11 +67,22 @@ func NewFS(f fs.Fs) *FS {
 	}
 	fsys.dirCacheTime = DirCacheTime
 
+	if FileCacheMode > CacheModeOff {
+		var err error
+		fsys.cache, err = newCache(f)
+		if err != nil {
+			fs.Errorf(f, "Failed to create vfs cache - disabling: %v", err)
+		} else {
+			fsys.cacheMode = FileCacheMode
+		}
+	}
+
 	fsys.root = newDir(fsys, f, fsDir)
 
 	if PollInterval > 0 {
 		fsys.PollChanges(PollInterval)
 	}
+	fsys.addRc()
 	return fsys
 }
 
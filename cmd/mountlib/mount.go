@@ -19,6 +19,11 @@ var (
 	NoSeek       = false
 	DirCacheTime = 5 * 60 * time.Second
 	PollInterval = time.Minute
+	// cache options
+	FileCacheMode                   = CacheModeOff
+	CacheMaxAge                     = 3600 * time.Second
+	CacheMaxSize      fs.SizeSuffix = -1
+	CachePollInterval               = 60 * time.Second
 	// mount options
 	ReadOnly                         = false
 	AllowNonEmpty                    = false
@@ -73,9 +78,11 @@ When that happens, it is the user's responsibility to stop the mount manually wi
 
 ### Limitations ###
 
-This can only write files seqentially, it can only seek when reading.
-This means that many applications won't work with their files on an
-rclone mount.
+Without the use of "--cache-mode" this can only write files
+sequentially, it can only seek when reading.  This means that many
+applications won't work with their files on an rclone mount without
+"--cache-mode writes" or "--cache-mode full".  See the [File
+Caching](#file-caching) section for more info.
 
 The bucket based remotes (eg Swift, S3, Google Compute Storage, B2,
 Hubic) won't work from the root - you will need to specify a bucket,
@@ -93,8 +100,8 @@ File systems expect things to be 100% reliable, whereas cloud storage
 systems are a long way from 100% reliable. The rclone sync/copy
 commands cope with this with lots of retries.  However rclone ` + commandName + `
 can't use retries in the same way without making local copies of the
-uploads.  This might happen in the future, but for the moment rclone
-` + commandName + ` won't do that, so will be less reliable than the rclone command.
+uploads. Look at the **EXPERIMENTAL** [file caching](#file-caching)
+for solutions to make ` + commandName + ` more reliable.
 
 ### Filters ###
 
@@ -116,12 +123,103 @@ like this:
 
     kill -SIGHUP $(pidof rclone)
 
+If rclone was started with ` + "`--rc`" + ` then the cache can be flushed
+with
+
+    rclone rc mount/forget
+
+### File Caching ###
+
+**NB** File caching is **EXPERIMENTAL** - use with care!
+
+These flags control the file caching options.
+
+    --cache-dir string                   Directory rclone will use for caching.
+    --cache-max-age duration             Max age of objects in the cache. (default 1h0m0s)
+    --cache-max-size int                 Max total size of objects in the cache. (default off)
+    --cache-mode string                  Cache mode off|minimal|writes|full (default "off")
+    --cache-poll-interval duration       Interval to poll the cache for stale objects. (default 1m0s)
+
+If run with ` + "`-vv`" + ` rclone will print the location of the file cache.  The
+files are stored in the user cache file area which is OS dependent but
+can be controlled with ` + "`--cache-dir`" + ` or setting the appropriate
+environment variable.
+
+The cache has 4 different modes selected by ` + "`--cache-mode`" + `.
+The higher the cache mode the more compatible rclone becomes at the
+cost of using disk space.
+
+Note that files are written back to the remote only when they are
+closed so if rclone is quit or dies with open files then these won't
+get written back to the remote.  However they will still be in the on
+disk cache.
+
+Files in the cache are removed once they have not been accessed for
+` + "`--cache-max-age`" + `, or if the total size of the cache is bigger
+than ` + "`--cache-max-size`" + `, oldest first.  Files which are open
+are never removed.  The cache is checked every
+` + "`--cache-poll-interval`" + `.
+
+#### --cache-mode off ####
+
+In this mode the cache will read directly from the remote and write
+directly to the remote without caching anything on disk.
+
+This will mean some operations are not possible
+
+  * Files can't be opened for both read AND write unless O_TRUNC is
+    set, in which case they are opened write only
+  * Files opened for write can't be seeked
+  * Files opened for write will behave as if O_TRUNC was supplied and
+    O_APPEND is ignored
+  * Files can't be truncated except to 0
+  * If an upload fails it can't be retried
+
+#### --cache-mode minimal ####
+
+This is very similar to "off" except that files opened for read AND
+write will be buffered to disk.  This means that files opened for
+write will be a lot more compatible, but uses the minimal disk space.
+
+These operations are not possible
+
+  * Files opened for write only can't be seeked
+  * Files opened for write only will behave as if O_TRUNC was supplied
+    and O_APPEND is ignored
+  * Files can't be truncated except to 0 unless they are open for
+    read AND write
+  * If an upload of a file opened for write only fails it can't be
+    retried
+
+#### --cache-mode writes ####
+
+In this mode files opened for read only are still read directly from
+the remote, write only and read/write files are buffered to disk
+first.
+
+This mode should support all normal file system operations.
+
+If an upload fails it will be retried up to ` + "`--low-level-retries`" + ` times.
+
+#### --cache-mode full ####
+
+In this mode all reads and writes are buffered to and from disk.  When
+a file is opened for read it will be downloaded in its entirety first.
+
+This may be appropriate for your needs if you want to read parts of
+files which don't support seeking on the remote.
+
+This mode should support all normal file system operations.
+
+If an upload or download fails it will be retried up to
+` + "`--low-level-retries`" + ` times.
+
 ### Bugs ###
 
   * All the remotes should work for read, but some may not for write
     * those which need to know the size in advance won't - eg B2
     * maybe should pass in size as -1 to mean work it out
-    * Or put in an an upload cache to cache the files on disk first
+    * Or use --cache-mode writes to cache the files on disk first
 `,
 		Run: func(command *cobra.Command, args []string) {
 			cmd.CheckArgs(2, 2, command, args)
@@ -163,6 +261,10 @@ like this:
 	flags.BoolVarP(&DefaultPermissions, "default-permissions", "", DefaultPermissions, "Makes kernel enforce access control based on the file mode.")
 	flags.BoolVarP(&WritebackCache, "write-back-cache", "", WritebackCache, "Makes kernel buffer writes before sending them to rclone. Without this, writethrough caching is used.")
 	flags.VarP(&MaxReadAhead, "max-read-ahead", "", "The number of bytes that can be prefetched for sequential reads.")
+	flags.VarP(&FileCacheMode, "cache-mode", "", "Cache mode off|minimal|writes|full")
+	flags.DurationVarP(&CacheMaxAge, "cache-max-age", "", CacheMaxAge, "Max age of objects in the cache.")
+	flags.VarP(&CacheMaxSize, "cache-max-size", "", "Max total size of objects in the cache.")
+	flags.DurationVarP(&CachePollInterval, "cache-poll-interval", "", CachePollInterval, "Interval to poll the cache for stale objects.")
 	ExtraOptions = flags.StringArrayP("option", "o", []string{}, "Option for libfuse/WinFsp. Repeat if required.")
 	ExtraFlags = flags.StringArrayP("fuse-flag", "", []string{}, "Flags or arguments to be passed direct to libfuse/WinFsp. Repeat if required.")
 	//flags.BoolVarP(&foreground, "foreground", "", foreground, "Do not detach.")
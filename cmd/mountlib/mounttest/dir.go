@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 // TestDirLs checks out listing
@@ -159,6 +160,7 @@ func TestDirModTime(t *testing.T) {
 
 // TestDirCacheFlush tests fluching the dir cache
 func TestDirCacheFlush(t *testing.T) {
+	ctx := context.Background()
 	run.skipIfNoFUSE(t)
 
 	run.checkDir(t, "")
@@ -173,7 +175,7 @@ func TestDirCacheFlush(t *testing.T) {
 	run.readLocal(t, localDm, "")
 	assert.Equal(t, dm, localDm, "expected vs fuse mount")
 
-	err := run.fremote.Mkdir("dir/subdir")
+	err := run.fremote.Mkdir(ctx, "dir/subdir")
 	require.NoError(t, err)
 
 	root, err := run.filesys.Root()
@@ -199,6 +201,7 @@ func TestDirCacheFlush(t *testing.T) {
 
 // TestDirCacheFlushOnDirRename tests flushing the dir cache on rename
 func TestDirCacheFlushOnDirRename(t *testing.T) {
+	ctx := context.Background()
 	run.skipIfNoFUSE(t)
 	run.mkdir(t, "dir")
 	run.createFile(t, "dir/file", "1")
@@ -209,7 +212,7 @@ func TestDirCacheFlushOnDirRename(t *testing.T) {
 	assert.Equal(t, dm, localDm, "expected vs fuse mount")
 
 	// expect remotely created directory to not show up
-	err := run.fremote.Mkdir("dir/subdir")
+	err := run.fremote.Mkdir(ctx, "dir/subdir")
 	require.NoError(t, err)
 	run.readLocal(t, localDm, "")
 	assert.Equal(t, dm, localDm, "expected vs fuse mount")
@@ -21,6 +21,7 @@ import (
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 // Globals
@@ -77,6 +78,7 @@ var run *Run
 //
 // Finalise() will tidy them away when done.
 func newRun() *Run {
+	ctx := context.Background()
 	r := &Run{
 		umountResult: make(chan error, 1),
 	}
@@ -98,7 +100,7 @@ func newRun() *Run {
 		log.Fatalf("Failed to open remote %q: %v", *RemoteName, err)
 	}
 
-	err = r.fremote.Mkdir("")
+	err = r.fremote.Mkdir(ctx, "")
 	if err != nil {
 		log.Fatalf("Failed to open mkdir %q: %v", *RemoteName, err)
 	}
@@ -237,7 +239,8 @@ func (r *Run) readLocal(t *testing.T, dir dirMap, filepath string) {
 
 // reads the remote tree into dir
 func (r *Run) readRemote(t *testing.T, dir dirMap, filepath string) {
-	objs, dirs, err := fs.WalkGetAll(r.fremote, filepath, true, 1)
+	ctx := context.Background()
+	objs, dirs, err := fs.WalkGetAll(ctx, r.fremote, filepath, true, 1)
 	if err == fs.ErrorDirNotFound {
 		return
 	}
@@ -0,0 +1,44 @@
+// Remote control calls for the mount
+
+package mountlib
+
+import (
+	"strings"
+
+	"github.com/ncw/rclone/rc"
+	"golang.org/x/net/context"
+)
+
+// addRc registers the remote control calls for fsys
+//
+// Only one mount can be controlled at once - the last one to be made.
+func (fsys *FS) addRc() {
+	rc.Add(rc.Call{
+		Path:  "mount/forget",
+		Fn:    fsys.rcForget,
+		Title: "Forget the directory cache of the mount",
+		Help: `
+This flushes the directory cache of the mount in the same way as
+sending rclone a SIGHUP does.
+
+Pass a dir parameter to only flush that directory and its children,
+eg
+
+    rclone rc mount/forget dir=path/to/dir
+
+Otherwise the whole cache is flushed.`,
+	})
+}
+
+// rcForget flushes the directory cache
+func (fsys *FS) rcForget(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	dir, err := in.GetString("dir")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	dir = strings.Trim(dir, "/")
+	fsys.root.ForgetPath(dir)
+	out = make(rc.Params)
+	out["forgotten"] = dir
+	return out, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // ReadFileHandle is an open for read file handle on a File
@@ -48,15 +49,43 @@ func (fh *ReadFileHandle) openPending() (err error) {
 	if fh.opened {
 		return nil
 	}
-	r, err := fh.o.Open()
+	r, err := fh.openAt(0)
 	if err != nil {
 		return err
 	}
-	fh.r = fs.NewAccount(r, fh.o).WithBuffer() // account the transfer
+	fh.r = fs.NewAccount(r, fh.o) // account the transfer
+	if _, chunked := r.(*fs.ChunkedReader); !chunked {
+		// The chunked reader does its own read ahead
+		fh.r = fh.r.WithBuffer()
+	}
 	fh.opened = true
 	return nil
 }
 
+// openAt opens the object for reading from offset
+//
+// If enabled the object will be read in chunks using a
+// fs.ChunkedReader, otherwise it will be opened as a single stream.
+func (fh *ReadFileHandle) openAt(offset int64) (io.ReadCloser, error) {
+	// Reading in chunks needs range requests so don't use it with
+	// --no-seek
+	if !fh.noSeek {
+		if cr := fs.NewChunkedReaderFromConfig(context.Background(), fh.o); cr != nil {
+			_, err := cr.Seek(offset, 0)
+			if err != nil {
+				_ = cr.Close()
+				return nil, err
+			}
+			return cr, nil
+		}
+	}
+	var options []fs.OpenOption
+	if offset > 0 {
+		options = append(options, &fs.SeekOption{Offset: offset})
+	}
+	return fh.o.Open(context.Background(), options...)
+}
+
 // String converts it to printable
 func (fh *ReadFileHandle) String() string {
 	if fh == nil {
@@ -102,7 +131,7 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 			fs.Debugf(fh.o, "ReadFileHandle.Read seek close old failed: %v", err)
 		}
 		// re-open with a seek
-		r, err = fh.o.Open(&fs.SeekOption{Offset: offset})
+		r, err = fh.openAt(offset)
 		if err != nil {
 			fs.Debugf(fh.o, "ReadFileHandle.Read seek failed: %v", err)
 			return err
@@ -0,0 +1,341 @@
+package mountlib
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// RWFileHandle is a handle that can be open for read and write.
+//
+// It will be open to a temporary file which, when closed, will be
+// transferred to the remote.
+type RWFileHandle struct {
+	mu      sync.Mutex
+	closed  bool // set if handle has been closed
+	remote  string
+	file    *File
+	d       *Dir
+	opened  bool
+	flags   int        // open flags
+	osPath  string     // path to the file in the cache
+	item    *cacheItem // item in the cache
+	fd      *os.File
+	changed bool // set if the file has been changed and needs uploading
+}
+
+// accessModeMask masks off the read/write flags of the open flags
+const accessModeMask = os.O_RDONLY | os.O_WRONLY | os.O_RDWR
+
+func newRWFileHandle(d *Dir, f *File, remote string, flags int) (fh *RWFileHandle, err error) {
+	// Make a place for the file
+	osPath, err := d.fsys.cache.mkdir(remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "open RW handle failed to make cache directory")
+	}
+
+	fh = &RWFileHandle{
+		file:   f,
+		d:      d,
+		remote: remote,
+		flags:  flags,
+		osPath: osPath,
+		item:   d.fsys.cache.get(remote),
+	}
+	d.fsys.cache.open(remote)
+	if fh.writable() {
+		fh.file.addWriters(1)
+	}
+	return fh, nil
+}
+
+// writable returns true if the handle was opened for write
+func (fh *RWFileHandle) writable() bool {
+	return fh.flags&accessModeMask != os.O_RDONLY
+}
+
+// openPending opens the file if there is a pending open
+//
+// call with the lock held
+func (fh *RWFileHandle) openPending() (err error) {
+	if fh.opened {
+		return nil
+	}
+
+	truncate := fh.flags&os.O_TRUNC != 0
+	fh.file.mu.Lock()
+	o := fh.file.o
+	fh.file.mu.Unlock()
+
+	// Make sure the cached file is ready for use - only the first
+	// open handle needs to do this
+	fh.item.mu.Lock()
+	if !fh.item.loaded {
+		if o != nil && !truncate {
+			if fh.d.fsys.cache.isFresh(fh.osPath, o) {
+				fs.Debugf(fh.remote, "Using cached copy of file")
+			} else {
+				err = fh.d.fsys.cache.fetch(fh.remote, o)
+				if err != nil {
+					fh.item.mu.Unlock()
+					return errors.Wrap(err, "open RW handle failed to cache file")
+				}
+			}
+		} else {
+			// Set the size to 0 since we are truncating or
+			// creating a new file
+			truncate = true
+		}
+		fh.item.loaded = true
+	}
+	fh.item.mu.Unlock()
+
+	if o == nil || truncate {
+		// The file needs uploading even if it isn't written to
+		fh.changed = fh.writable()
+	}
+
+	flags := fh.flags &^ (os.O_TRUNC | os.O_EXCL)
+	flags |= os.O_CREATE
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	fd, err := os.OpenFile(fh.osPath, flags, 0600)
+	if err != nil {
+		return errors.Wrap(err, "open RW handle failed to open cache file")
+	}
+	fh.fd = fd
+	fh.opened = true
+	if fh.writable() {
+		fi, err := fd.Stat()
+		if err != nil {
+			return errors.Wrap(err, "open RW handle failed to stat cache file")
+		}
+		fh.file.setSize(fi.Size())
+	}
+	return nil
+}
+
+// String converts it to printable
+func (fh *RWFileHandle) String() string {
+	if fh == nil {
+		return "<nil *RWFileHandle>"
+	}
+	if fh.file == nil {
+		return "<nil *RWFileHandle.file>"
+	}
+	return fh.file.String() + " (rw)"
+}
+
+// Node returns the Node assocuated with this - satisfies Noder interface
+func (fh *RWFileHandle) Node() Node {
+	return fh.file
+}
+
+// Read bytes from the file at offset
+//
+// A short read is returned at the end of the file
+func (fh *RWFileHandle) Read(reqSize, reqOffset int64) (respData []byte, err error) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.closed {
+		fs.Errorf(fh.remote, "RWFileHandle.Read error: %v", EBADF)
+		return nil, EBADF
+	}
+	if fh.flags&accessModeMask == os.O_WRONLY {
+		return nil, EBADF
+	}
+	err = fh.openPending()
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Read error: %v", err)
+		return nil, err
+	}
+	buf := make([]byte, reqSize)
+	n, err := fh.fd.ReadAt(buf, reqOffset)
+	if err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Read error: %v", err)
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// Write data to the file handle at offset
+func (fh *RWFileHandle) Write(data []byte, offset int64) (written int64, err error) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.closed {
+		fs.Errorf(fh.remote, "RWFileHandle.Write error: %v", EBADF)
+		return 0, EBADF
+	}
+	if !fh.writable() {
+		return 0, EBADF
+	}
+	err = fh.openPending()
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Write error: %v", err)
+		return 0, err
+	}
+	fh.changed = true
+	var n int
+	if fh.flags&os.O_APPEND != 0 {
+		// WriteAt isn't allowed on files opened O_APPEND and
+		// the write goes to the end of the file regardless
+		n, err = fh.fd.Write(data)
+	} else {
+		n, err = fh.fd.WriteAt(data, offset)
+	}
+	written = int64(n)
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Write error: %v", err)
+		return written, err
+	}
+	fi, err := fh.fd.Stat()
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Write failed to stat cache file: %v", err)
+		return written, err
+	}
+	fh.file.setSize(fi.Size())
+	return written, nil
+}
+
+// Truncate file to given size
+func (fh *RWFileHandle) Truncate(size int64) (err error) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.closed {
+		return EBADF
+	}
+	if !fh.writable() {
+		return EBADF
+	}
+	err = fh.openPending()
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Truncate error: %v", err)
+		return err
+	}
+	fh.changed = true
+	err = fh.fd.Truncate(size)
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Truncate error: %v", err)
+		return err
+	}
+	fh.file.setSize(size)
+	return nil
+}
+
+// flush uploads the file to the remote if it has been changed
+//
+// call with the lock held
+func (fh *RWFileHandle) flush() error {
+	if !fh.opened || !fh.changed {
+		return nil
+	}
+	err := fh.fd.Sync()
+	if err != nil {
+		return errors.Wrap(err, "failed to sync cache file")
+	}
+
+	// Set the modification time of the cached file so it is
+	// transferred to the remote
+	fh.file.mu.Lock()
+	modTime := fh.file.pendingModTime
+	o := fh.file.o
+	fh.file.mu.Unlock()
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	err = os.Chtimes(fh.osPath, modTime, modTime)
+	if err != nil {
+		fs.Errorf(fh.remote, "Failed to set modification time of cached file: %v", err)
+	}
+
+	// Transfer the file to the remote
+	fh.item.mu.Lock()
+	newObj, err := fh.d.fsys.cache.store(fh.remote, o)
+	fh.item.mu.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "failed to transfer file from cache to remote")
+	}
+	fh.changed = false
+	fh.file.setObject(newObj)
+	fs.Debugf(fh.remote, "transferred to remote")
+	return nil
+}
+
+// Flush is called each time the file or directory is closed.
+// Because there can be multiple file descriptors referring to a
+// single opened file, Flush can be called multiple times.
+func (fh *RWFileHandle) Flush() error {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.closed {
+		return nil
+	}
+	err := fh.flush()
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Flush error: %v", err)
+	}
+	return err
+}
+
+// close the file handle returning EBADF if it has been
+// closed already.
+//
+// call with the lock held
+func (fh *RWFileHandle) close() (err error) {
+	if fh.closed {
+		return EBADF
+	}
+	fh.closed = true
+	if fh.writable() {
+		defer fh.file.addWriters(-1)
+	}
+	defer fh.d.fsys.cache.close(fh.remote)
+	if !fh.opened && fh.writable() {
+		// A new or truncated file needs creating even if it
+		// was never written to
+		fh.file.mu.Lock()
+		o := fh.file.o
+		fh.file.mu.Unlock()
+		if o == nil || fh.flags&os.O_TRUNC != 0 {
+			err = fh.openPending()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = fh.flush()
+	if fh.fd != nil {
+		closeErr := fh.fd.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Release is called when we are finished with the file handle
+//
+// It isn't called directly from userspace so the error is ignored by
+// the kernel
+func (fh *RWFileHandle) Release() error {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.closed {
+		fs.Debugf(fh.remote, "RWFileHandle.Release nothing to do")
+		return nil
+	}
+	fs.Debugf(fh.remote, "RWFileHandle.Release closing")
+	err := fh.close()
+	if err != nil {
+		fs.Errorf(fh.remote, "RWFileHandle.Release error: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,191 @@
+package mountlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	fs.LoadConfig()
+	os.Exit(m.Run())
+}
+
+// newTestFS makes an FS on a local temporary directory with the file
+// cache enabled returning the FS, the directory and a cleanup
+// function
+func newTestFS(t *testing.T, cacheMode CacheMode) (*FS, string, func()) {
+	remoteDir, err := ioutil.TempDir("", "rclone-mountlib-remote")
+	require.NoError(t, err)
+	cacheDir, err := ioutil.TempDir("", "rclone-mountlib-cache")
+	require.NoError(t, err)
+
+	oldCacheDir, oldCacheMode, oldCachePollInterval := fs.CacheDir, FileCacheMode, CachePollInterval
+	fs.CacheDir, FileCacheMode, CachePollInterval = cacheDir, cacheMode, 0
+
+	f, err := fs.NewFs(remoteDir)
+	require.NoError(t, err)
+	fsys := NewFS(f)
+	require.Equal(t, cacheMode, fsys.cacheMode)
+
+	return fsys, remoteDir, func() {
+		fs.CacheDir, FileCacheMode, CachePollInterval = oldCacheDir, oldCacheMode, oldCachePollInterval
+		_ = os.RemoveAll(remoteDir)
+		_ = os.RemoveAll(cacheDir)
+	}
+}
+
+// lookupFile finds the file called name in the root of fsys
+func lookupFile(t *testing.T, fsys *FS, name string) *File {
+	node, err := fsys.Lookup(name)
+	require.NoError(t, err)
+	file, ok := node.(*File)
+	require.True(t, ok)
+	return file
+}
+
+func TestCacheModeString(t *testing.T) {
+	assert.Equal(t, "off", CacheModeOff.String())
+	assert.Equal(t, "full", CacheModeFull.String())
+	assert.Equal(t, "CacheMode(17)", CacheMode(17).String())
+
+	var mode CacheMode
+	assert.NoError(t, mode.Set("writes"))
+	assert.Equal(t, CacheModeWrites, mode)
+	assert.NoError(t, mode.Set("minimal"))
+	assert.Equal(t, CacheModeMinimal, mode)
+	assert.Error(t, mode.Set("potato"))
+	assert.Error(t, mode.Set(""))
+	assert.Equal(t, CacheModeMinimal, mode)
+}
+
+func TestRWFileHandleCreate(t *testing.T) {
+	fsys, remoteDir, cleanup := newTestFS(t, CacheModeWrites)
+	defer cleanup()
+	root, err := fsys.Root()
+	require.NoError(t, err)
+
+	file, handle, err := root.Create("file1", os.O_RDWR|os.O_CREATE)
+	require.NoError(t, err)
+	fh, ok := handle.(*RWFileHandle)
+	require.True(t, ok)
+
+	// Write out of order leaving a hole and read it back
+	n, err := fh.Write([]byte("HELLO"), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	n, err = fh.Write([]byte("hello"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	data, err := fh.Read(100, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\x00\x00\x00\x00\x00HELLO", string(data))
+	data, err = fh.Read(3, 11)
+	require.NoError(t, err)
+	assert.Equal(t, "ELL", string(data))
+
+	// The size is visible while the file is open
+	_, size, _, err := file.Attr(true)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(15), size)
+
+	require.NoError(t, fh.Release())
+	assert.NoError(t, fh.Release()) // releasing twice is OK
+
+	contents, err := ioutil.ReadFile(filepath.Join(remoteDir, "file1"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\x00\x00\x00\x00\x00HELLO", string(contents))
+
+	// The new file should be in the directory
+	file = lookupFile(t, fsys, "file1")
+	_, size, _, err = file.Attr(true)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(15), size)
+}
+
+func TestRWFileHandleCreateEmpty(t *testing.T) {
+	fsys, remoteDir, cleanup := newTestFS(t, CacheModeWrites)
+	defer cleanup()
+	root, err := fsys.Root()
+	require.NoError(t, err)
+
+	_, handle, err := root.Create("empty", os.O_WRONLY|os.O_CREATE)
+	require.NoError(t, err)
+	fh, ok := handle.(*RWFileHandle)
+	require.True(t, ok)
+	require.NoError(t, fh.Release())
+
+	fi, err := os.Stat(filepath.Join(remoteDir, "empty"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), fi.Size())
+}
+
+func TestRWFileHandleModify(t *testing.T) {
+	fsys, remoteDir, cleanup := newTestFS(t, CacheModeMinimal)
+	defer cleanup()
+	remotePath := filepath.Join(remoteDir, "file2")
+	require.NoError(t, ioutil.WriteFile(remotePath, []byte("0123456789"), 0600))
+
+	file := lookupFile(t, fsys, "file2")
+
+	// Write only opens don't use the cache in minimal mode
+	handle, err := file.Open(os.O_WRONLY)
+	require.NoError(t, err)
+	wfh, ok := handle.(*WriteFileHandle)
+	require.True(t, ok)
+	_, err = wfh.Write([]byte("x"), 1)
+	assert.Equal(t, ESPIPE, err)
+	_, err = wfh.Write([]byte("0123456789"), 0)
+	require.NoError(t, err)
+	require.NoError(t, wfh.Release())
+
+	handle, err = file.Open(os.O_RDWR)
+	require.NoError(t, err)
+	fh, ok := handle.(*RWFileHandle)
+	require.True(t, ok)
+
+	data, err := fh.Read(4, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "0123", string(data))
+	_, err = fh.Write([]byte("XY"), 2)
+	require.NoError(t, err)
+	require.NoError(t, fh.Truncate(6))
+	_, err = fh.Write([]byte("!"), 6)
+	require.NoError(t, err)
+	require.NoError(t, fh.Flush())
+	require.NoError(t, fh.Release())
+
+	contents, err := ioutil.ReadFile(remotePath)
+	require.NoError(t, err)
+	assert.Equal(t, "01XY45!", string(contents))
+
+	// Truncating needs --cache-mode writes unless it is to 0
+	assert.Equal(t, EPERM, file.Truncate(3))
+	fsys.cacheMode = CacheModeWrites
+	require.NoError(t, file.Truncate(3))
+	contents, err = ioutil.ReadFile(remotePath)
+	require.NoError(t, err)
+	assert.Equal(t, "01X", string(contents))
+}
+
+func TestFileOpenNoCache(t *testing.T) {
+	fsys, remoteDir, cleanup := newTestFS(t, CacheModeOff)
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "file3"), []byte("hello"), 0600))
+
+	file := lookupFile(t, fsys, "file3")
+	_, err := file.Open(os.O_RDWR)
+	assert.Equal(t, EPERM, err)
+
+	handle, err := file.Open(os.O_RDONLY)
+	require.NoError(t, err)
+	rfh, ok := handle.(*ReadFileHandle)
+	require.True(t, ok)
+	require.NoError(t, rfh.Release())
+}
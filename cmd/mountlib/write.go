@@ -6,6 +6,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // WriteFileHandle is an open for write handle on a File
@@ -42,7 +43,7 @@ func newWriteFileHandle(d *Dir, f *File, src fs.ObjectInfo) (*WriteFileHandle, e
 	fh.pipeReader, fh.pipeWriter = io.Pipe()
 	r := fs.NewAccountSizeName(fh.pipeReader, 0, src.Remote()).WithBuffer() // account the transfer
 	go func() {
-		o, err := d.f.Put(r, src)
+		o, err := d.f.Put(context.Background(), r, src)
 		fh.o = o
 		fh.result <- err
 	}()
@@ -4,6 +4,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
@@ -49,9 +50,9 @@ transfer.
 
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
-				return fs.MoveDir(fdst, fsrc)
+				return fs.MoveDir(context.Background(), fdst, fsrc)
 			}
-			return fs.MoveFile(fdst, fsrc, dstFileName, srcFileName)
+			return fs.MoveFile(context.Background(), fdst, fsrc, dstFileName, srcFileName)
 		})
 	},
 }
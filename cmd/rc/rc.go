@@ -0,0 +1,148 @@
+package rc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/rc"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	url      = "http://localhost:5572/"
+	authUser = ""
+	authPass = ""
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().StringVarP(&url, "url", "", url, "URL to connect to rclone remote control.")
+	commandDefintion.Flags().StringVarP(&authUser, "user", "", authUser, "User name to authenticate with the remote control.")
+	commandDefintion.Flags().StringVarP(&authPass, "pass", "", authPass, "Password to authenticate with the remote control.")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "rc commands parameter",
+	Short: `Run a command against a running rclone.`,
+	Long: `
+This runs a command against a running rclone.  By default it will
+connect to http://localhost:5572/ - use --url to change this.
+
+Arguments should be passed in as parameter=value.
+
+The result will be returned as a JSON object by default.
+
+Use "rclone rc" to see a list of all possible commands.
+
+Some useful commands are
+
+    rclone rc core/stats
+    rclone rc core/bwlimit rate=1M
+    rclone rc mount/forget dir=path/to/dir
+    rclone rc sync/copy srcFs=/tmp/src dstFs=remote:dst _async=true
+    rclone rc job/status jobid=1
+
+Start the rclone you want to control with ` + "`--rc`" + ` to enable the
+remote control server.`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1e9, command, args)
+		cmd.Run(false, false, command, func() error {
+			if len(args) == 0 {
+				return list()
+			}
+			return run(args)
+		})
+	},
+}
+
+// do a single call to the remote control
+func doCall(path string, in rc.Params) (out rc.Params, err error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode JSON")
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(url, "/")+"/"+path, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authUser != "" {
+		req.SetBasicAuth(authUser, authPass)
+	}
+	resp, err := fs.Config.Client().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "connection failed")
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+	err = json.Unmarshal(body, &out)
+	if resp.StatusCode != http.StatusOK {
+		if err == nil {
+			if msg, ok := out["error"].(string); ok {
+				return nil, errors.Errorf("%s (%s)", msg, resp.Status)
+			}
+		}
+		return nil, errors.Errorf("failed with %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode JSON")
+	}
+	return out, nil
+}
+
+// run the command in args
+func run(args []string) error {
+	path := strings.Trim(args[0], "/")
+	in := make(rc.Params)
+	for _, param := range args[1:] {
+		equals := strings.IndexRune(param, '=')
+		if equals < 0 {
+			return errors.Errorf("no '=' found in parameter %q", param)
+		}
+		in[param[:equals]] = param[equals+1:]
+	}
+	out, err := doCall(path, in)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(out, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode JSON")
+	}
+	_, err = os.Stdout.Write(append(data, '\n'))
+	return err
+}
+
+// list the available commands
+func list() error {
+	out, err := doCall("rc/list", nil)
+	if err != nil {
+		return err
+	}
+	commands, ok := out["commands"].([]interface{})
+	if !ok {
+		return errors.New("bad JSON from rc/list")
+	}
+	for _, command := range commands {
+		info, ok := command.(map[string]interface{})
+		if !ok {
+			return errors.New("bad JSON from rc/list")
+		}
+		fmt.Printf("### %s: %s\n\n", info["Path"], info["Title"])
+		if help, _ := info["Help"].(string); help != "" {
+			fmt.Printf("%s\n\n", help)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,880 @@
+// A single client connection to the ftp server
+
+package ftp
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// dataTimeout is how long to wait for a data connection to be made
+const dataTimeout = 30 * time.Second
+
+// commandInfo describes an FTP command
+type commandInfo struct {
+	fn        func(c *conn, arg string)
+	needsAuth bool // must be logged in to use
+	modifies  bool // changes the remote so not allowed if read only
+}
+
+// commands is the table of supported FTP commands
+var commands = map[string]commandInfo{
+	"USER": {fn: (*conn).cmdUser},
+	"PASS": {fn: (*conn).cmdPass},
+	"QUIT": {fn: (*conn).cmdQuit},
+	"SYST": {fn: (*conn).cmdSyst},
+	"FEAT": {fn: (*conn).cmdFeat},
+	"OPTS": {fn: (*conn).cmdOpts},
+	"NOOP": {fn: (*conn).cmdNoop},
+	"TYPE": {fn: (*conn).cmdType, needsAuth: true},
+	"MODE": {fn: (*conn).cmdMode, needsAuth: true},
+	"STRU": {fn: (*conn).cmdStru, needsAuth: true},
+	"ALLO": {fn: (*conn).cmdAllo, needsAuth: true},
+	"ABOR": {fn: (*conn).cmdAbor, needsAuth: true},
+	"PWD":  {fn: (*conn).cmdPwd, needsAuth: true},
+	"XPWD": {fn: (*conn).cmdPwd, needsAuth: true},
+	"CWD":  {fn: (*conn).cmdCwd, needsAuth: true},
+	"XCWD": {fn: (*conn).cmdCwd, needsAuth: true},
+	"CDUP": {fn: (*conn).cmdCdup, needsAuth: true},
+	"XCUP": {fn: (*conn).cmdCdup, needsAuth: true},
+	"PASV": {fn: (*conn).cmdPasv, needsAuth: true},
+	"EPSV": {fn: (*conn).cmdEpsv, needsAuth: true},
+	"PORT": {fn: (*conn).cmdPort, needsAuth: true},
+	"EPRT": {fn: (*conn).cmdEprt, needsAuth: true},
+	"LIST": {fn: (*conn).cmdList, needsAuth: true},
+	"NLST": {fn: (*conn).cmdNlst, needsAuth: true},
+	"SIZE": {fn: (*conn).cmdSize, needsAuth: true},
+	"MDTM": {fn: (*conn).cmdMdtm, needsAuth: true},
+	"REST": {fn: (*conn).cmdRest, needsAuth: true},
+	"RETR": {fn: (*conn).cmdRetr, needsAuth: true},
+	"STOR": {fn: (*conn).cmdStor, needsAuth: true, modifies: true},
+	"DELE": {fn: (*conn).cmdDele, needsAuth: true, modifies: true},
+	"MKD":  {fn: (*conn).cmdMkd, needsAuth: true, modifies: true},
+	"XMKD": {fn: (*conn).cmdMkd, needsAuth: true, modifies: true},
+	"RMD":  {fn: (*conn).cmdRmd, needsAuth: true, modifies: true},
+	"XRMD": {fn: (*conn).cmdRmd, needsAuth: true, modifies: true},
+	"RNFR": {fn: (*conn).cmdRnfr, needsAuth: true, modifies: true},
+	"RNTO": {fn: (*conn).cmdRnto, needsAuth: true, modifies: true},
+}
+
+// features are the extensions returned by FEAT
+var features = []string{"EPSV", "MDTM", "PASV", "REST STREAM", "SIZE", "UTF8"}
+
+// conn is a single control connection from an FTP client
+type conn struct {
+	s          *server
+	f          fs.Fs
+	ctx        context.Context
+	conn       net.Conn
+	r          *bufio.Reader
+	w          *bufio.Writer
+	what       string       // description of the connection for logging
+	user       string       // user name supplied with USER
+	loggedIn   bool         // set if the user has logged in
+	cwd        string       // current directory - always absolute
+	renameFrom string       // remote supplied with RNFR
+	restOffset int64        // offset supplied with REST
+	pasv       net.Listener // listener for a passive data connection
+	activeAddr string       // address for an active data connection
+	quit       bool         // set when the connection should be closed
+}
+
+// newConn makes a new conn for the server s
+func newConn(s *server, nConn net.Conn) *conn {
+	return &conn{
+		s:    s,
+		f:    s.f,
+		ctx:  context.Background(),
+		conn: nConn,
+		r:    bufio.NewReader(nConn),
+		w:    bufio.NewWriter(nConn),
+		what: fmt.Sprintf("ftp connection from %s", nConn.RemoteAddr()),
+		cwd:  "/",
+	}
+}
+
+// serve reads commands from the client and runs them until the
+// client quits or the connection is closed
+func (c *conn) serve() {
+	defer c.close()
+	fs.Debugf(c.what, "Connection opened")
+	c.reply(220, "Welcome to the rclone FTP server")
+	for !c.quit {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			if err != io.EOF {
+				fs.Debugf(c.what, "Failed to read command: %v", err)
+			}
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			command, arg = line[:i], line[i+1:]
+		}
+		command = strings.ToUpper(command)
+		if command == "PASS" {
+			fs.Debugf(c.what, "< PASS ****")
+		} else {
+			fs.Debugf(c.what, "< %s", line)
+		}
+		c.handle(command, arg)
+	}
+}
+
+// handle runs a single command
+func (c *conn) handle(command, arg string) {
+	info, ok := commands[command]
+	switch {
+	case !ok:
+		c.reply(502, "Command not implemented")
+	case info.needsAuth && !c.loggedIn:
+		c.reply(530, "Please login with USER and PASS")
+	case info.modifies && c.s.opt.ReadOnly:
+		c.reply(550, "Permission denied - server is read only")
+	default:
+		info.fn(c, arg)
+	}
+	// The REST offset only applies to the command following it
+	if command != "REST" {
+		c.restOffset = 0
+	}
+	// A rename is only pending for the command after RNFR
+	if command != "RNFR" {
+		c.renameFrom = ""
+	}
+}
+
+// close the connection and any pending data connection
+func (c *conn) close() {
+	c.closePassive()
+	err := c.conn.Close()
+	if err != nil {
+		fs.Debugf(c.what, "Failed to close connection: %v", err)
+	}
+	fs.Debugf(c.what, "Connection closed")
+}
+
+// reply sends a single line response to the client
+func (c *conn) reply(code int, message string) {
+	fs.Debugf(c.what, "> %d %s", code, message)
+	_, _ = fmt.Fprintf(c.w, "%d %s\r\n", code, message)
+	c.flush()
+}
+
+// replyLines sends a multi-line response to the client
+func (c *conn) replyLines(code int, first string, lines []string, last string) {
+	fs.Debugf(c.what, "> %d-%s", code, first)
+	_, _ = fmt.Fprintf(c.w, "%d-%s\r\n", code, first)
+	for _, line := range lines {
+		_, _ = fmt.Fprintf(c.w, " %s\r\n", line)
+	}
+	c.reply(code, last)
+}
+
+// flush the output to the client, closing the connection on error
+func (c *conn) flush() {
+	err := c.w.Flush()
+	if err != nil {
+		fs.Debugf(c.what, "Failed to write reply: %v", err)
+		c.quit = true
+	}
+}
+
+// replyError logs err and sends it to the client with code
+func (c *conn) replyError(code int, what interface{}, message string, err error) {
+	fs.Errorf(what, "%s: %v", message, err)
+	c.reply(code, message)
+}
+
+// absPath returns the absolute, cleaned version of the client
+// supplied path p
+func (c *conn) absPath(p string) string {
+	if !path.IsAbs(p) {
+		p = path.Join(c.cwd, p)
+	}
+	return path.Clean("/" + p)
+}
+
+// toRemote converts an absolute path into a remote
+func toRemote(absPath string) string {
+	return strings.TrimPrefix(absPath, "/")
+}
+
+// quotePath quotes p for use in a 257 reply
+func quotePath(p string) string {
+	return `"` + strings.Replace(p, `"`, `""`, -1) + `"`
+}
+
+// isDir returns true if remote is a directory which is being served
+func (c *conn) isDir(remote string) bool {
+	if remote == "" {
+		return true
+	}
+	if !fs.Config.Filter.IncludeDirectory(remote) {
+		return false
+	}
+	_, err := c.f.List(c.ctx, remote)
+	return err == nil
+}
+
+// findObject finds the object at remote obeying the filters
+func (c *conn) findObject(remote string) (fs.Object, error) {
+	o, err := c.f.NewObject(c.ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	if !fs.Config.Filter.IncludeObject(o) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return o, nil
+}
+
+// cmdUser sets the user name for login
+func (c *conn) cmdUser(arg string) {
+	c.user = arg
+	c.loggedIn = false
+	c.reply(331, "User name ok, password required")
+}
+
+// cmdPass checks the password and logs the user in
+func (c *conn) cmdPass(arg string) {
+	if c.user == "" {
+		c.reply(503, "Login with USER first")
+		return
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(c.user), []byte(c.s.opt.User)) == 1
+	passOK := c.s.opt.Pass == "" || subtle.ConstantTimeCompare([]byte(arg), []byte(c.s.opt.Pass)) == 1
+	if !userOK || !passOK {
+		fs.Infof(c.what, "Login failed for user %q", c.user)
+		c.reply(530, "Login incorrect")
+		return
+	}
+	c.loggedIn = true
+	fs.Infof(c.what, "User %q logged in", c.user)
+	c.reply(230, "Logged in")
+}
+
+// cmdQuit ends the session
+func (c *conn) cmdQuit(arg string) {
+	c.reply(221, "Goodbye")
+	c.quit = true
+}
+
+// cmdSyst returns the system type
+func (c *conn) cmdSyst(arg string) {
+	c.reply(215, "UNIX Type: L8")
+}
+
+// cmdFeat lists the supported extensions
+func (c *conn) cmdFeat(arg string) {
+	c.replyLines(211, "Extensions supported:", features, "End")
+}
+
+// cmdOpts sets options - only UTF8 is supported
+func (c *conn) cmdOpts(arg string) {
+	if strings.ToUpper(arg) == "UTF8 ON" {
+		c.reply(200, "UTF8 mode enabled")
+		return
+	}
+	c.reply(501, "Option not understood")
+}
+
+// cmdNoop does nothing
+func (c *conn) cmdNoop(arg string) {
+	c.reply(200, "OK")
+}
+
+// cmdType sets the transfer type
+func (c *conn) cmdType(arg string) {
+	switch strings.ToUpper(arg) {
+	case "I", "L 8":
+		c.reply(200, "Type set to binary")
+	case "A", "A N":
+		// All transfers are binary but accept ASCII for clients which insist
+		c.reply(200, "Type set to ASCII")
+	default:
+		c.reply(504, "Type not supported")
+	}
+}
+
+// cmdMode sets the transfer mode
+func (c *conn) cmdMode(arg string) {
+	if strings.ToUpper(arg) != "S" {
+		c.reply(504, "Only stream mode is supported")
+		return
+	}
+	c.reply(200, "Mode set to stream")
+}
+
+// cmdStru sets the file structure
+func (c *conn) cmdStru(arg string) {
+	if strings.ToUpper(arg) != "F" {
+		c.reply(504, "Only file structure is supported")
+		return
+	}
+	c.reply(200, "Structure set to file")
+}
+
+// cmdAllo allocates storage which is never needed
+func (c *conn) cmdAllo(arg string) {
+	c.reply(202, "No storage allocation necessary")
+}
+
+// cmdAbor aborts a transfer - transfers are synchronous so there is never one to abort
+func (c *conn) cmdAbor(arg string) {
+	c.reply(225, "No transfer to abort")
+}
+
+// cmdPwd returns the current directory
+func (c *conn) cmdPwd(arg string) {
+	c.reply(257, quotePath(c.cwd)+" is the current directory")
+}
+
+// cmdCwd changes the current directory
+func (c *conn) cmdCwd(arg string) {
+	p := c.absPath(arg)
+	if !c.isDir(toRemote(p)) {
+		c.reply(550, "Directory not found")
+		return
+	}
+	c.cwd = p
+	c.reply(250, "Directory changed to "+p)
+}
+
+// cmdCdup changes to the parent directory
+func (c *conn) cmdCdup(arg string) {
+	c.cmdCwd("..")
+}
+
+// closePassive closes any pending passive listener
+func (c *conn) closePassive() {
+	if c.pasv != nil {
+		_ = c.pasv.Close()
+		c.pasv = nil
+	}
+}
+
+// listenPassive sets up a listener for a passive data connection
+// returning its port
+func (c *conn) listenPassive() (port int, err error) {
+	c.closePassive()
+	c.activeAddr = ""
+	host, _, err := net.SplitHostPort(c.conn.LocalAddr().String())
+	if err != nil {
+		return 0, err
+	}
+	c.pasv, err = c.s.listenPassive(host)
+	if err != nil {
+		return 0, err
+	}
+	return c.pasv.Addr().(*net.TCPAddr).Port, nil
+}
+
+// cmdPasv sets up a passive data connection
+func (c *conn) cmdPasv(arg string) {
+	ip := net.ParseIP(c.s.opt.PublicIP)
+	if ip == nil {
+		ip = c.conn.LocalAddr().(*net.TCPAddr).IP
+	}
+	ip = ip.To4()
+	if ip == nil {
+		c.reply(425, "PASV needs IPv4 - use EPSV")
+		return
+	}
+	port, err := c.listenPassive()
+	if err != nil {
+		c.replyError(425, c.what, "Can't open passive connection", err)
+		return
+	}
+	c.reply(227, fmt.Sprintf("Entering Passive Mode (%d,%d,%d,%d,%d,%d)", ip[0], ip[1], ip[2], ip[3], port>>8, port&0xFF))
+}
+
+// cmdEpsv sets up an extended passive data connection
+func (c *conn) cmdEpsv(arg string) {
+	if strings.ToUpper(arg) == "ALL" {
+		c.reply(200, "EPSV ALL ok")
+		return
+	}
+	port, err := c.listenPassive()
+	if err != nil {
+		c.replyError(425, c.what, "Can't open passive connection", err)
+		return
+	}
+	c.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|)", port))
+}
+
+// setActive sets the address for an active data connection
+// checking it is the same host as the client to prevent FTP bounce
+// attacks
+func (c *conn) setActive(ip net.IP, port int) {
+	clientIP := c.conn.RemoteAddr().(*net.TCPAddr).IP
+	if ip == nil || !ip.Equal(clientIP) || port <= 0 || port > 65535 {
+		c.reply(500, "Illegal PORT command")
+		return
+	}
+	c.closePassive()
+	c.activeAddr = net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	c.reply(200, "PORT command successful")
+}
+
+// cmdPort sets up an active data connection
+func (c *conn) cmdPort(arg string) {
+	parts := strings.Split(arg, ",")
+	if len(parts) != 6 {
+		c.reply(501, "Bad PORT command")
+		return
+	}
+	var nums [6]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 || n > 255 {
+			c.reply(501, "Bad PORT command")
+			return
+		}
+		nums[i] = n
+	}
+	ip := net.IPv4(byte(nums[0]), byte(nums[1]), byte(nums[2]), byte(nums[3]))
+	c.setActive(ip, nums[4]<<8|nums[5])
+}
+
+// cmdEprt sets up an extended active data connection
+func (c *conn) cmdEprt(arg string) {
+	// format is <d><proto><d><addr><d><port><d>
+	if len(arg) < 1 {
+		c.reply(501, "Bad EPRT command")
+		return
+	}
+	parts := strings.Split(arg[1:], arg[:1])
+	if len(parts) != 4 {
+		c.reply(501, "Bad EPRT command")
+		return
+	}
+	port, err := strconv.Atoi(parts[2])
+	if err != nil {
+		c.reply(501, "Bad EPRT command")
+		return
+	}
+	c.setActive(net.ParseIP(parts[1]), port)
+}
+
+// openDataConn opens the data connection set up by PASV, EPSV, PORT
+// or EPRT
+func (c *conn) openDataConn() (net.Conn, error) {
+	if c.pasv != nil {
+		listener := c.pasv
+		c.pasv = nil
+		defer func() {
+			_ = listener.Close()
+		}()
+		if tcpListener, ok := listener.(*net.TCPListener); ok {
+			_ = tcpListener.SetDeadline(time.Now().Add(dataTimeout))
+		}
+		dataConn, err := listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		// Only accept data connections from the client
+		clientIP := c.conn.RemoteAddr().(*net.TCPAddr).IP
+		if !dataConn.RemoteAddr().(*net.TCPAddr).IP.Equal(clientIP) {
+			_ = dataConn.Close()
+			return nil, errors.Errorf("data connection from wrong address %v", dataConn.RemoteAddr())
+		}
+		return dataConn, nil
+	}
+	if c.activeAddr != "" {
+		addr := c.activeAddr
+		c.activeAddr = ""
+		return net.DialTimeout("tcp", addr, dataTimeout)
+	}
+	return nil, errors.New("use PASV, EPSV, PORT or EPRT first")
+}
+
+// withDataConn opens the data connection, replies with message and
+// calls fn with the connection.  The result of fn is sent to the
+// client when the connection is closed.
+func (c *conn) withDataConn(message string, fn func(dataConn net.Conn) error) {
+	dataConn, err := c.openDataConn()
+	if err != nil {
+		c.replyError(425, c.what, "Can't open data connection", err)
+		return
+	}
+	c.reply(150, message)
+	err = fn(dataConn)
+	closeErr := dataConn.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		c.replyError(451, c.what, "Transfer failed", err)
+		return
+	}
+	c.reply(226, "Transfer complete")
+}
+
+// listArg removes any ls style flags (eg -la) from the argument to
+// LIST or NLST returning the path
+func listArg(arg string) string {
+	for strings.HasPrefix(arg, "-") {
+		i := strings.IndexByte(arg, ' ')
+		if i < 0 {
+			return ""
+		}
+		arg = strings.TrimLeft(arg[i:], " ")
+	}
+	return arg
+}
+
+// listEntries returns the entries at p which may be a directory or a
+// file
+func (c *conn) listEntries(p string) (fs.DirEntries, error) {
+	remote := toRemote(p)
+	if remote != "" {
+		o, err := c.findObject(remote)
+		if err == nil {
+			return fs.DirEntries{o}, nil
+		}
+		if !fs.Config.Filter.IncludeDirectory(remote) {
+			return nil, fs.ErrorDirNotFound
+		}
+	}
+	return fs.ListDirSorted(c.ctx, c.f, false, remote)
+}
+
+// formatListLine formats entry as a line of ls -l output
+func formatListLine(entry fs.BasicInfo) string {
+	mode, size := "-rw-r--r--", entry.Size()
+	if _, isDir := entry.(*fs.Dir); isDir {
+		mode, size = "drwxr-xr-x", 0
+	}
+	if size < 0 {
+		size = 0
+	}
+	modTime := entry.ModTime()
+	timeFormat := "Jan _2  2006"
+	if modTime.Year() == time.Now().Year() {
+		timeFormat = "Jan _2 15:04"
+	}
+	return fmt.Sprintf("%s 1 ftp ftp %12d %s %s\r\n", mode, size, modTime.Format(timeFormat), path.Base(entry.Remote()))
+}
+
+// list sends a listing of the argument to the client, in long
+// format if long is set
+func (c *conn) list(arg string, long bool) {
+	p := c.absPath(listArg(arg))
+	entries, err := c.listEntries(p)
+	if err == fs.ErrorDirNotFound {
+		c.reply(550, "Directory not found")
+		return
+	} else if err != nil {
+		c.replyError(550, p, "Failed to list directory", err)
+		return
+	}
+	c.withDataConn("Opening data connection for directory listing", func(dataConn net.Conn) error {
+		w := bufio.NewWriter(dataConn)
+		for _, entry := range entries {
+			var err error
+			if long {
+				_, err = io.WriteString(w, formatListLine(entry))
+			} else {
+				_, err = io.WriteString(w, path.Base(entry.Remote())+"\r\n")
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+}
+
+// cmdList sends a long directory listing
+func (c *conn) cmdList(arg string) {
+	c.list(arg, true)
+}
+
+// cmdNlst sends a listing of names only
+func (c *conn) cmdNlst(arg string) {
+	c.list(arg, false)
+}
+
+// cmdSize returns the size of a file
+func (c *conn) cmdSize(arg string) {
+	remote := toRemote(c.absPath(arg))
+	o, err := c.findObject(remote)
+	if err != nil {
+		c.reply(550, "File not found")
+		return
+	}
+	c.reply(213, strconv.FormatInt(o.Size(), 10))
+}
+
+// mdtmFormat is the time format used by MDTM
+const mdtmFormat = "20060102150405"
+
+// cmdMdtm returns, or sets, the modification time of a file
+func (c *conn) cmdMdtm(arg string) {
+	// Some clients set the modification time with
+	// "MDTM YYYYMMDDHHMMSS path"
+	var setTime time.Time
+	if i := strings.IndexByte(arg, ' '); i == len(mdtmFormat) {
+		t, err := time.Parse(mdtmFormat, arg[:i])
+		if err == nil {
+			setTime, arg = t, arg[i+1:]
+		}
+	}
+	remote := toRemote(c.absPath(arg))
+	o, err := c.findObject(remote)
+	if err != nil {
+		c.reply(550, "File not found")
+		return
+	}
+	if !setTime.IsZero() {
+		if c.s.opt.ReadOnly {
+			c.reply(550, "Permission denied - server is read only")
+			return
+		}
+		err = o.SetModTime(c.ctx, setTime)
+		if err != nil {
+			c.replyError(550, o, "Failed to set modification time", err)
+			return
+		}
+	}
+	c.reply(213, o.ModTime().UTC().Format(mdtmFormat))
+}
+
+// cmdRest sets the offset for the next RETR
+func (c *conn) cmdRest(arg string) {
+	offset, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || offset < 0 {
+		c.reply(501, "Bad REST offset")
+		return
+	}
+	c.restOffset = offset
+	c.reply(350, "Restarting at "+arg)
+}
+
+// cmdRetr sends a file to the client
+func (c *conn) cmdRetr(arg string) {
+	remote := toRemote(c.absPath(arg))
+	o, err := c.findObject(remote)
+	if err != nil {
+		c.reply(550, "File not found")
+		return
+	}
+	offset := c.restOffset
+	if o.Size() >= 0 && offset > o.Size() {
+		c.reply(554, "Restart offset is beyond the end of the file")
+		return
+	}
+	size := o.Size() - offset
+	var options []fs.OpenOption
+	if offset > 0 {
+		options = append(options, &fs.SeekOption{Offset: offset})
+	}
+	c.withDataConn(fmt.Sprintf("Opening BINARY mode data connection for %s (%d bytes)", path.Base(remote), size), func(dataConn net.Conn) (err error) {
+		in, err := o.Open(c.ctx, options...)
+		if err != nil {
+			return errors.Wrap(err, "failed to open file")
+		}
+		fs.Stats.Transferring(remote)
+		in = fs.NewAccountSizeName(in, size, remote).WithBuffer() // account the transfer
+		defer func() {
+			closeErr := in.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				fs.Stats.Error()
+			}
+			fs.Stats.DoneTransferring(remote, err == nil)
+		}()
+		_, err = io.Copy(dataConn, in)
+		return err
+	})
+}
+
+// cmdStor receives a file from the client
+func (c *conn) cmdStor(arg string) {
+	if c.restOffset != 0 {
+		c.reply(504, "Resuming uploads is not supported")
+		return
+	}
+	p := c.absPath(arg)
+	remote := toRemote(p)
+	if remote == "" || c.isDir(remote) {
+		c.reply(553, "Can't upload to a directory")
+		return
+	}
+	parent := path.Dir(p)
+	if !c.isDir(toRemote(parent)) {
+		c.reply(553, "Directory not found")
+		return
+	}
+	c.withDataConn("Ok to send data", func(dataConn net.Conn) error {
+		return c.upload(remote, dataConn)
+	})
+}
+
+// upload reads the data from in into a temporary file and then
+// uploads it to remote
+func (c *conn) upload(remote string, in io.Reader) (err error) {
+	tmp, err := ioutil.TempFile("", "rclone-ftp-")
+	if err != nil {
+		return errors.Wrap(err, "failed to make temporary file")
+	}
+	defer func() {
+		_ = tmp.Close()
+		removeErr := os.Remove(tmp.Name())
+		if removeErr != nil {
+			fs.Errorf(remote, "Failed to remove temporary file: %v", removeErr)
+		}
+	}()
+	size, err := io.Copy(tmp, in)
+	if err != nil {
+		return errors.Wrap(err, "failed to receive file")
+	}
+	_, err = tmp.Seek(0, os.SEEK_SET)
+	if err != nil {
+		return err
+	}
+
+	fs.Stats.Transferring(remote)
+	defer func() {
+		if err != nil {
+			fs.Stats.Error()
+		}
+		fs.Stats.DoneTransferring(remote, err == nil)
+	}()
+	account := fs.NewAccountSizeName(tmp, size, remote).WithBuffer() // account the transfer
+	defer fs.CheckClose(account, &err)
+	src := fs.NewStaticObjectInfo(remote, time.Now(), size, true, nil, nil)
+	o, err := c.f.NewObject(c.ctx, remote)
+	if err == nil {
+		return o.Update(c.ctx, account, src)
+	} else if err != fs.ErrorObjectNotFound {
+		return err
+	}
+	_, err = c.f.Put(c.ctx, account, src)
+	return err
+}
+
+// cmdDele deletes a file
+func (c *conn) cmdDele(arg string) {
+	remote := toRemote(c.absPath(arg))
+	o, err := c.findObject(remote)
+	if err != nil {
+		c.reply(550, "File not found")
+		return
+	}
+	err = fs.DeleteFile(c.ctx, o)
+	if err != nil {
+		c.replyError(550, o, "Failed to delete file", err)
+		return
+	}
+	c.reply(250, "File deleted")
+}
+
+// cmdMkd makes a directory
+func (c *conn) cmdMkd(arg string) {
+	p := c.absPath(arg)
+	remote := toRemote(p)
+	if remote == "" || c.isDir(remote) {
+		c.reply(550, "Directory already exists")
+		return
+	}
+	if !c.isDir(toRemote(path.Dir(p))) {
+		c.reply(550, "Parent directory not found")
+		return
+	}
+	err := c.f.Mkdir(c.ctx, remote)
+	if err != nil {
+		c.replyError(550, remote, "Failed to make directory", err)
+		return
+	}
+	c.reply(257, quotePath(p)+" created")
+}
+
+// cmdRmd removes an empty directory
+func (c *conn) cmdRmd(arg string) {
+	remote := toRemote(c.absPath(arg))
+	if remote == "" || !c.isDir(remote) {
+		c.reply(550, "Directory not found")
+		return
+	}
+	err := c.f.Rmdir(c.ctx, remote)
+	if err != nil {
+		c.replyError(550, remote, "Failed to remove directory", err)
+		return
+	}
+	c.reply(250, "Directory removed")
+}
+
+// cmdRnfr starts a rename by setting the source
+func (c *conn) cmdRnfr(arg string) {
+	remote := toRemote(c.absPath(arg))
+	if remote == "" {
+		c.reply(550, "Can't rename the root")
+		return
+	}
+	if _, err := c.findObject(remote); err != nil && !c.isDir(remote) {
+		c.reply(550, "File not found")
+		return
+	}
+	c.renameFrom = remote
+	c.reply(350, "Ready for RNTO")
+}
+
+// cmdRnto completes a rename started with RNFR
+func (c *conn) cmdRnto(arg string) {
+	if c.renameFrom == "" {
+		c.reply(503, "Use RNFR first")
+		return
+	}
+	p := c.absPath(arg)
+	remote := toRemote(p)
+	if remote == "" || !c.isDir(toRemote(path.Dir(p))) {
+		c.reply(553, "Directory not found")
+		return
+	}
+	err := c.rename(c.renameFrom, remote)
+	if err != nil {
+		c.replyError(550, c.renameFrom, "Rename failed", err)
+		return
+	}
+	c.reply(250, "Rename successful")
+}
+
+// rename the file or directory oldRemote to newRemote
+func (c *conn) rename(oldRemote, newRemote string) error {
+	o, err := c.findObject(oldRemote)
+	if err == nil {
+		dst, err := c.f.NewObject(c.ctx, newRemote)
+		if err == fs.ErrorObjectNotFound {
+			dst = nil
+		} else if err != nil {
+			return err
+		}
+		return fs.Move(c.ctx, c.f, dst, newRemote, o)
+	}
+	doDirMove := c.f.Features().DirMove
+	if doDirMove == nil {
+		return fs.ErrorCantDirMove
+	}
+	if c.isDir(newRemote) {
+		return errors.New("destination directory exists")
+	}
+	return doDirMove(c.ctx, c.f, oldRemote, newRemote)
+}
@@ -0,0 +1,226 @@
+// Package ftp implements an FTP server to serve an rclone remote
+package ftp
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Options contains options for the ftp server
+type Options struct {
+	ListenAddr   string // Port to listen on
+	PublicIP     string // Public IP address to advertise for passive connections
+	PassivePorts string // Passive ports range
+	User         string // single username
+	Pass         string // password for user - empty means accept any
+	ReadOnly     bool   // disallow any changes to the remote
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:   "localhost:2121",
+	PassivePorts: "30000-32000",
+	User:         "anonymous",
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+func init() {
+	flags := Command.Flags()
+	flags.StringVarP(&Opt.ListenAddr, "addr", "", Opt.ListenAddr, "IPaddress:Port or :Port to bind server to.")
+	flags.StringVarP(&Opt.PublicIP, "public-ip", "", Opt.PublicIP, "Public IP address to advertise for passive connections.")
+	flags.StringVarP(&Opt.PassivePorts, "passive-port", "", Opt.PassivePorts, "Passive port range to use.")
+	flags.StringVarP(&Opt.User, "user", "", Opt.User, "User name for authentication.")
+	flags.StringVarP(&Opt.Pass, "pass", "", Opt.Pass, "Password for authentication. (empty value allow every password)")
+	flags.BoolVarP(&Opt.ReadOnly, "read-only", "", Opt.ReadOnly, "Only allow read access.")
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "ftp remote:path",
+	Short: `Serve the remote over FTP.`,
+	Long: `rclone serve ftp implements a basic FTP server to serve the
+remote over the FTP protocol.  This can be viewed with an FTP client
+or you can make a remote of type ftp to read and write it.
+
+You can use the filter flags (eg --include, --exclude) to control what
+is served.
+
+Uploaded files are buffered in a temporary file and sent to the remote
+when the transfer is complete.  Resuming uploads (REST before STOR)
+and appending (APPE) aren't supported, but downloads may be resumed.
+
+### Server options
+
+Use --addr to specify which IP address and port the server should
+listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
+IPs.  By default it only listens on localhost.  You can use port
+:0 to let the OS choose an available port.
+
+If you set --addr to listen on a public or LAN accessible IP address
+then using Authentication is advised - see the next section for info.
+
+Passive mode data connections are made to a port chosen from the
+range given by --passive-port (default "30000-32000").  Use a single
+port number, eg "--passive-port 30000", or "0" to let the OS choose.
+If the server is behind NAT then use --public-ip to set the IP
+address advertised to clients in reply to PASV.  Active mode (PORT
+and EPRT) is supported too.
+
+Use --read-only to stop clients making any changes to the remote.
+
+### Authentication
+
+By default this will serve files without needing a login.
+
+You can set a single username and password with the --user and --pass
+flags.  If --pass is empty then any password is accepted for --user
+which defaults to "anonymous".
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			s, err := newServer(f, &Opt)
+			if err != nil {
+				return err
+			}
+			err = s.serve()
+			if err != nil {
+				return err
+			}
+			s.Wait()
+			return nil
+		})
+	},
+}
+
+// server contains everything to run the ftp server
+type server struct {
+	f        fs.Fs
+	opt      Options
+	portMin  int // lowest passive port - 0 to let the OS choose
+	portMax  int // highest passive port
+	listener net.Listener
+	waitChan chan struct{} // for waiting on the listener to close
+	closing  int32         // set to 1 when Close has been called
+}
+
+// newServer makes a new ftp server serving f
+func newServer(f fs.Fs, opt *Options) (*server, error) {
+	s := &server{
+		f:   f,
+		opt: *opt,
+	}
+	var err error
+	s.portMin, s.portMax, err = parsePortRange(s.opt.PassivePorts)
+	if err != nil {
+		return nil, err
+	}
+	if s.opt.PublicIP != "" {
+		ip := net.ParseIP(s.opt.PublicIP)
+		if ip == nil || ip.To4() == nil {
+			return nil, errors.Errorf("invalid IPv4 address %q for --public-ip", s.opt.PublicIP)
+		}
+	}
+	return s, nil
+}
+
+// parsePortRange parses a port range like "30000-32000" or a single
+// port "30000" returning the minimum and maximum ports.
+func parsePortRange(portRange string) (portMin, portMax int, err error) {
+	portRange = strings.TrimSpace(portRange)
+	if portRange == "" {
+		return 0, 0, nil
+	}
+	parts := strings.SplitN(portRange, "-", 2)
+	portMin, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, errors.Errorf("invalid passive port range %q", portRange)
+	}
+	portMax = portMin
+	if len(parts) == 2 {
+		portMax, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, 0, errors.Errorf("invalid passive port range %q", portRange)
+		}
+	}
+	if portMin < 0 || portMax > 65535 || portMin > portMax {
+		return 0, 0, errors.Errorf("invalid passive port range %q", portRange)
+	}
+	return portMin, portMax, nil
+}
+
+// serve starts the server listening in the background
+func (s *server) serve() error {
+	listener, err := net.Listen("tcp", s.opt.ListenAddr)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for connection")
+	}
+	s.listener = listener
+	s.waitChan = make(chan struct{})
+	fs.Logf(s.f, "FTP server listening on %v", listener.Addr())
+	go s.acceptConnections()
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Wait blocks until the listener is closed
+func (s *server) Wait() {
+	<-s.waitChan
+}
+
+// Close shuts the listener down
+func (s *server) Close() {
+	atomic.StoreInt32(&s.closing, 1)
+	err := s.listener.Close()
+	if err != nil {
+		fs.Errorf(nil, "Error on closing FTP server: %v", err)
+		return
+	}
+	s.Wait()
+}
+
+// acceptConnections accepts connections until the listener is closed
+func (s *server) acceptConnections() {
+	defer close(s.waitChan)
+	for {
+		nConn, err := s.listener.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&s.closing) == 0 {
+				fs.Errorf(nil, "Failed to accept incoming connection: %v", err)
+			}
+			return
+		}
+		go newConn(s, nConn).serve()
+	}
+}
+
+// listenPassive opens a listener for a passive data connection on
+// the IP address ip using a port from the passive port range
+func (s *server) listenPassive(ip string) (net.Listener, error) {
+	if s.portMin == 0 {
+		return net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	}
+	var err error
+	for port := s.portMin; port <= s.portMax; port++ {
+		var listener net.Listener
+		listener, err = net.Listen("tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err == nil {
+			return listener, nil
+		}
+	}
+	return nil, errors.Errorf("no free passive ports in range %d-%d: %v", s.portMin, s.portMax, err)
+}
@@ -0,0 +1,216 @@
+package ftp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ftpRun holds a running ftp server and client for a test
+type ftpRun struct {
+	t      *testing.T
+	dir    string
+	s      *server
+	client *ftp.ServerConn
+}
+
+func newFtpRun(t *testing.T, readOnly bool) *ftpRun {
+	fs.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-serve-ftp-test")
+	require.NoError(t, err)
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	opt := DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	opt.PassivePorts = "0"
+	opt.User = "user"
+	opt.Pass = "pass"
+	opt.ReadOnly = readOnly
+	s, err := newServer(f, &opt)
+	require.NoError(t, err)
+	require.NoError(t, s.serve())
+
+	client, err := ftp.Dial(s.Addr())
+	require.NoError(t, err)
+	require.NoError(t, client.Login("user", "pass"))
+	return &ftpRun{
+		t:      t,
+		dir:    dir,
+		s:      s,
+		client: client,
+	}
+}
+
+func (r *ftpRun) finalise() {
+	_ = r.client.Quit()
+	r.s.Close()
+	_ = os.RemoveAll(r.dir)
+}
+
+// readFile reads a file over ftp
+func (r *ftpRun) readFile(name string, offset uint64) string {
+	resp, err := r.client.RetrFrom(name, offset)
+	require.NoError(r.t, err)
+	data, err := ioutil.ReadAll(resp)
+	require.NoError(r.t, err)
+	require.NoError(r.t, resp.Close())
+	return string(data)
+}
+
+// readLocal reads the file in the served directory
+func (r *ftpRun) readLocal(name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, filepath.FromSlash(name)))
+	require.NoError(r.t, err)
+	return string(data)
+}
+
+// existsLocal returns whether the path exists in the served directory
+func (r *ftpRun) existsLocal(name string) bool {
+	_, err := os.Stat(filepath.Join(r.dir, filepath.FromSlash(name)))
+	return err == nil
+}
+
+func TestFtpReadWrite(t *testing.T) {
+	r := newFtpRun(t, false)
+	defer r.finalise()
+
+	require.NoError(t, r.client.Stor("/file.txt", bytes.NewBufferString("hello world")))
+	assert.Equal(t, "hello world", r.readLocal("file.txt"))
+	assert.Equal(t, "hello world", r.readFile("/file.txt", 0))
+	assert.Equal(t, "world", r.readFile("/file.txt", 6))
+	assert.Equal(t, "", r.readFile("/file.txt", 11))
+	_, err := r.client.RetrFrom("/file.txt", 12)
+	assert.Error(t, err)
+
+	size, err := r.client.FileSize("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), size)
+
+	// Overwrite
+	require.NoError(t, r.client.Stor("file.txt", bytes.NewBufferString("potato")))
+	assert.Equal(t, "potato", r.readLocal("file.txt"))
+
+	_, err = r.client.Retr("/notfound.txt")
+	assert.Error(t, err)
+
+	assert.Error(t, r.client.Stor("/notfound/file.txt", bytes.NewBufferString("hello")))
+
+	require.NoError(t, r.client.Delete("/file.txt"))
+	assert.False(t, r.existsLocal("file.txt"))
+	assert.Error(t, r.client.Delete("/file.txt"))
+}
+
+func TestFtpDirectories(t *testing.T) {
+	r := newFtpRun(t, false)
+	defer r.finalise()
+
+	require.NoError(t, r.client.MakeDir("/dir"))
+	assert.True(t, r.existsLocal("dir"))
+	assert.Error(t, r.client.MakeDir("/dir"))
+	require.NoError(t, r.client.ChangeDir("dir"))
+	cwd, err := r.client.CurrentDir()
+	require.NoError(t, err)
+	assert.Equal(t, "/dir", cwd)
+	assert.Error(t, r.client.ChangeDir("/notfound"))
+
+	require.NoError(t, r.client.Stor("a.txt", bytes.NewBufferString("a")))
+	require.NoError(t, r.client.Stor("b.txt", bytes.NewBufferString("bb")))
+	require.NoError(t, r.client.MakeDir("sub"))
+	assert.Equal(t, "a", r.readLocal("dir/a.txt"))
+
+	entries, err := r.client.List("")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+		switch entry.Name {
+		case "b.txt":
+			assert.Equal(t, ftp.EntryTypeFile, entry.Type)
+			assert.Equal(t, uint64(2), entry.Size)
+		case "sub":
+			assert.Equal(t, ftp.EntryTypeFolder, entry.Type)
+		}
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"a.txt", "b.txt", "sub"}, names)
+
+	names, err = r.client.NameList("/dir")
+	require.NoError(t, err)
+	sort.Strings(names)
+	assert.Equal(t, []string{"a.txt", "b.txt", "sub"}, names)
+
+	// Rename a file
+	require.NoError(t, r.client.Rename("a.txt", "sub/c.txt"))
+	assert.False(t, r.existsLocal("dir/a.txt"))
+	assert.Equal(t, "a", r.readLocal("dir/sub/c.txt"))
+
+	// Rename a directory
+	require.NoError(t, r.client.ChangeDirToParent())
+	require.NoError(t, r.client.Rename("/dir/sub", "/sub2"))
+	assert.Equal(t, "a", r.readLocal("sub2/c.txt"))
+
+	assert.Error(t, r.client.RemoveDir("/sub2"))
+	require.NoError(t, r.client.Delete("/sub2/c.txt"))
+	require.NoError(t, r.client.RemoveDir("/sub2"))
+	assert.False(t, r.existsLocal("sub2"))
+}
+
+func TestFtpReadOnly(t *testing.T) {
+	r := newFtpRun(t, true)
+	defer r.finalise()
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(r.dir, "file.txt"), []byte("hello"), 0666))
+	assert.Equal(t, "hello", r.readFile("file.txt", 0))
+
+	assert.Error(t, r.client.Stor("new.txt", bytes.NewBufferString("hello")))
+	assert.False(t, r.existsLocal("new.txt"))
+	assert.Error(t, r.client.Delete("file.txt"))
+	assert.Error(t, r.client.MakeDir("dir"))
+	assert.Error(t, r.client.Rename("file.txt", "file2.txt"))
+	assert.True(t, r.existsLocal("file.txt"))
+}
+
+func TestFtpAuth(t *testing.T) {
+	r := newFtpRun(t, false)
+	defer r.finalise()
+
+	client, err := ftp.Dial(r.s.Addr())
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Quit()
+	}()
+	assert.Error(t, client.Login("user", "wrong"))
+	_, err = client.List("/")
+	assert.Error(t, err)
+}
+
+func TestParsePortRange(t *testing.T) {
+	for _, test := range []struct {
+		in       string
+		min, max int
+		err      bool
+	}{
+		{"", 0, 0, false},
+		{"0", 0, 0, false},
+		{"30000", 30000, 30000, false},
+		{"30000-32000", 30000, 32000, false},
+		{"32000-30000", 0, 0, true},
+		{"potato", 0, 0, true},
+		{"1-70000", 0, 0, true},
+	} {
+		min, max, err := parsePortRange(test.in)
+		assert.Equal(t, test.err, err != nil, test.in)
+		assert.Equal(t, test.min, min, test.in)
+		assert.Equal(t, test.max, max, test.in)
+	}
+}
@@ -0,0 +1,227 @@
+// Package http provides a server to serve a remote over HTTP
+package http
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Globals
+var (
+	httpOptions = httplib.DefaultOpt
+)
+
+func init() {
+	httplib.AddFlags(Command.Flags(), &httpOptions)
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "http remote:path",
+	Short: `Serve the remote over HTTP.`,
+	Long: `rclone serve http implements a basic web server to serve the remote
+over HTTP.  This can be viewed in a web browser or you can make a
+remote of type http read from it.
+
+You can use the filter flags (eg --include, --exclude) to control what
+is served.
+
+Directories are shown as an index page and files can be downloaded,
+with Range requests supported so downloads can be resumed or
+streamed.  The Content-Type of each file is read from the remote if
+it supports it, or guessed from the file extension otherwise.
+` + httplib.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, true, command, func() error {
+			s := newServer(f, &httpOptions)
+			err := s.serve()
+			if err != nil {
+				return err
+			}
+			s.srv.Wait()
+			return nil
+		})
+	},
+}
+
+// server contains everything to run the server
+type server struct {
+	f   fs.Fs
+	srv *httplib.Server
+}
+
+// newServer makes a new http server serving f
+func newServer(f fs.Fs, opt *httplib.Options) *server {
+	mux := http.NewServeMux()
+	s := &server{
+		f:   f,
+		srv: httplib.NewServer(mux, opt),
+	}
+	mux.HandleFunc("/", s.handler)
+	return s
+}
+
+// serve starts the server running in the background
+func (s *server) serve() error {
+	err := s.srv.Serve()
+	if err != nil {
+		return err
+	}
+	fs.Logf(s.f, "Serving on %s", s.srv.URL())
+	return nil
+}
+
+// handler reads incoming requests and dispatches them
+func (s *server) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Server", "rclone/"+fs.Version)
+
+	urlPath := r.URL.Path
+	isDir := strings.HasSuffix(urlPath, "/")
+	remote := strings.Trim(urlPath, "/")
+	if isDir {
+		s.serveDir(w, r, remote)
+	} else {
+		s.serveFile(w, r, remote)
+	}
+}
+
+// entry is a single item in a directory listing
+type entry struct {
+	URL  string
+	Leaf string
+	Size string
+	Date string
+}
+
+// indexData is the data passed to the index page template
+type indexData struct {
+	Title   string
+	Parent  bool
+	Entries []entry
+}
+
+// indexPage is the template for a directory listing
+var indexPage = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{ if .Parent }}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{ end }}{{ range .Entries }}<tr><td><a href="{{ .URL }}">{{ .Leaf }}</a></td><td>{{ .Size }}</td><td>{{ .Date }}</td></tr>
+{{ end }}</table>
+</body>
+</html>
+`))
+
+// serveDir serves a directory index at dirRemote
+func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote string) {
+	ctx := r.Context()
+	if dirRemote != "" && !fs.Config.Filter.IncludeDirectory(dirRemote) {
+		http.Error(w, "Directory not found", http.StatusNotFound)
+		return
+	}
+	dirEntries, err := fs.ListDirSorted(ctx, s.f, false, dirRemote)
+	if err == fs.ErrorDirNotFound {
+		http.Error(w, "Directory not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		httplib.InternalError(dirRemote, w, "Failed to list directory", err)
+		return
+	}
+
+	data := indexData{
+		Title:   "Directory listing of /" + dirRemote,
+		Parent:  dirRemote != "",
+		Entries: make([]entry, 0, len(dirEntries)),
+	}
+	for _, o := range dirEntries {
+		remote := strings.Trim(o.Remote(), "/")
+		leaf := path.Base(remote)
+		e := entry{
+			Leaf: leaf,
+			Date: o.ModTime().UTC().Format("2006-01-02 15:04:05"),
+		}
+		urlLeaf := (&url.URL{Path: leaf}).String()
+		if _, isDir := o.(*fs.Dir); isDir {
+			e.Leaf += "/"
+			e.URL = urlLeaf + "/"
+			e.Size = "-"
+		} else {
+			e.URL = urlLeaf
+			e.Size = fs.SizeSuffix(o.Size()).String()
+		}
+		data.Entries = append(data.Entries, e)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == "HEAD" {
+		return
+	}
+	err = indexPage.Execute(w, data)
+	if err != nil {
+		httplib.InternalError(dirRemote, w, "Failed to render template", err)
+		return
+	}
+}
+
+// serveFile serves a file object at remote
+func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string) {
+	ctx := r.Context()
+	o, err := s.f.NewObject(ctx, remote)
+	if errors.Cause(err) == fs.ErrorNotAFile || (err == fs.ErrorObjectNotFound && s.isDir(ctx, remote)) {
+		// Redirect directories to the canonical form with a /
+		http.Redirect(w, r, (&url.URL{Path: r.URL.Path + "/"}).String(), http.StatusMovedPermanently)
+		return
+	} else if err == fs.ErrorObjectNotFound {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		httplib.InternalError(remote, w, "Failed to find file", err)
+		return
+	}
+	if !fs.Config.Filter.IncludeObject(o) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", fs.MimeType(o))
+	w.Header().Set("Last-Modified", o.ModTime().UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+	err = httplib.ServeObject(w, r, o)
+	if err == httplib.ErrRangeNotSatisfiable {
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+	} else if err != nil {
+		httplib.InternalError(remote, w, "Failed to open file", err)
+	}
+}
+
+// isDir returns true if remote is a directory which is being served
+func (s *server) isDir(ctx context.Context, remote string) bool {
+	if !fs.Config.Filter.IncludeDirectory(remote) {
+		return false
+	}
+	_, err := s.f.List(ctx, remote)
+	return err == nil
+}
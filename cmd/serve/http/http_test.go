@@ -0,0 +1,175 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFileContents = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// startServer starts a server serving a temporary directory with some
+// files in, returning the server and a function to clean up
+func startServer(t *testing.T, opt httplib.Options) (*server, func()) {
+	fs.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-serve-http-test")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub dir"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte(testFileContents), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub dir", "hello.html"), []byte("<h1>hello</h1>"), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "excluded.bak"), []byte("excluded"), 0666))
+
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	opt.ListenAddr = "localhost:0"
+	s := newServer(f, &opt)
+	require.NoError(t, s.serve())
+	return s, func() {
+		s.srv.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// get does a request returning the response and body
+func get(t *testing.T, method, url string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	// Use the transport directly so redirects aren't followed
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	return resp, string(body)
+}
+
+func TestServeHTTP(t *testing.T) {
+	s, cleanup := startServer(t, httplib.DefaultOpt)
+	defer cleanup()
+
+	filterOld := fs.Config.Filter
+	defer func() { fs.Config.Filter = filterOld }()
+	filter, err := fs.NewFilter()
+	require.NoError(t, err)
+	require.NoError(t, filter.AddRule("- *.bak"))
+	fs.Config.Filter = filter
+	url := s.srv.URL()
+
+	// Directory listings
+	resp, body := get(t, "GET", url, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, `<a href="file.txt">file.txt</a>`)
+	assert.Contains(t, body, `<a href="sub%20dir/">sub dir/</a>`)
+	assert.NotContains(t, body, "excluded.bak")
+	assert.NotContains(t, body, `href="../"`)
+
+	resp, body = get(t, "GET", url+"sub%20dir/", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `<a href="hello.html">hello.html</a>`)
+	assert.Contains(t, body, `href="../"`)
+
+	resp, _ = get(t, "GET", url+"sub%20dir", nil)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/sub%20dir/", resp.Header.Get("Location"))
+
+	resp, _ = get(t, "GET", url+"notfound/", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Files
+	resp, body = get(t, "GET", url+"file.txt", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+	assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+	assert.Equal(t, testFileContents, body)
+
+	resp, body = get(t, "GET", url+"sub%20dir/hello.html", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html"))
+	assert.Equal(t, "<h1>hello</h1>", body)
+
+	resp, body = get(t, "HEAD", url+"file.txt", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "36", resp.Header.Get("Content-Length"))
+	assert.Equal(t, "", body)
+
+	resp, _ = get(t, "GET", url+"notfound.txt", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = get(t, "GET", url+"excluded.bak", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = get(t, "POST", url+"file.txt", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServeHTTPRange(t *testing.T) {
+	s, cleanup := startServer(t, httplib.DefaultOpt)
+	defer cleanup()
+	url := s.srv.URL() + "file.txt"
+
+	for _, test := range []struct {
+		rangeHeader  string
+		wantStatus   int
+		wantBody     string
+		contentRange string
+	}{
+		{"bytes=0-9", http.StatusPartialContent, "0123456789", "bytes 0-9/36"},
+		{"bytes=30-", http.StatusPartialContent, "uvwxyz", "bytes 30-35/36"},
+		{"bytes=-3", http.StatusPartialContent, "xyz", "bytes 33-35/36"},
+		{"bytes=10-1000", http.StatusPartialContent, testFileContents[10:], "bytes 10-35/36"},
+		{"bytes=36-", http.StatusRequestedRangeNotSatisfiable, "", "bytes */36"},
+		{"bytes=0-1,3-4", http.StatusOK, testFileContents, ""},
+		{"potato", http.StatusOK, testFileContents, ""},
+	} {
+		resp, body := get(t, "GET", url, map[string]string{"Range": test.rangeHeader})
+		assert.Equal(t, test.wantStatus, resp.StatusCode, test.rangeHeader)
+		assert.Equal(t, test.contentRange, resp.Header.Get("Content-Range"), test.rangeHeader)
+		if test.wantStatus != http.StatusRequestedRangeNotSatisfiable {
+			assert.Equal(t, test.wantBody, body, test.rangeHeader)
+		}
+	}
+}
+
+func TestServeHTTPAuth(t *testing.T) {
+	opt := httplib.DefaultOpt
+	opt.BasicUser = "user"
+	opt.BasicPass = "pass"
+	s, cleanup := startServer(t, opt)
+	defer cleanup()
+	url := s.srv.URL() + "file.txt"
+
+	resp, _ := get(t, "GET", url, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Basic realm="rclone"`, resp.Header.Get("WWW-Authenticate"))
+
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("user", "wrong")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req.SetBasicAuth("user", "pass")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, testFileContents, string(body))
+}
@@ -0,0 +1,247 @@
+// Package httplib provides common functionality for http servers
+package httplib
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Help contains text describing the http server to add to the command
+// help.
+var Help = `
+### Server options
+
+Use --addr to specify which IP address and port the server should
+listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
+IPs.  By default it only listens on localhost.
+
+If you set --addr to listen on a public or LAN accessible IP address
+then using Authentication is advised - see the next section for info.
+
+--server-read-timeout and --server-write-timeout can be used to
+control the timeouts on the server.  Note that this is the total time
+for a transfer.
+
+--max-header-bytes controls the maximum number of bytes the server will
+accept in the HTTP header.
+
+#### Authentication
+
+By default this will serve files without needing a login.
+
+You can set a single username and password with the --user and --pass
+flags.
+
+Use --realm to set the authentication realm.
+
+#### SSL/TLS
+
+By default this will serve over http.  If you want you can serve over
+https.  You will need to supply the --cert and --key flags.  If you
+wish to do client side certificate validation then you will need to
+supply --client-ca also.
+
+--cert should be a either a PEM encoded certificate or a concatenation
+of that with the CA certificate.  --key should be the PEM encoded
+private key and --client-ca should be the PEM encoded client
+certificate authority certificate.
+`
+
+// Options contains options for the http Server
+type Options struct {
+	ListenAddr         string        // Port to listen on
+	ServerReadTimeout  time.Duration // Timeout for server reading data
+	ServerWriteTimeout time.Duration // Timeout for server writing data
+	MaxHeaderBytes     int           // Maximum size of request header
+	SslCert            string        // SSL PEM key (concatenation of certificate and CA certificate)
+	SslKey             string        // SSL PEM Private key
+	ClientCA           string        // Client certificate authority to verify clients with
+	Realm              string        // realm for authentication
+	BasicUser          string        // single username for basic auth if not using Htpasswd
+	BasicPass          string        // password for BasicUser
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:         "localhost:8080",
+	Realm:              "rclone",
+	ServerReadTimeout:  1 * time.Hour,
+	ServerWriteTimeout: 1 * time.Hour,
+	MaxHeaderBytes:     4096,
+}
+
+// AddFlags adds flags for the http server to the flag set, setting
+// the defaults from what is in opt
+func AddFlags(flagSet *pflag.FlagSet, opt *Options) {
+	flagSet.StringVarP(&opt.ListenAddr, "addr", "", opt.ListenAddr, "IPaddress:Port or :Port to bind server to.")
+	flagSet.DurationVarP(&opt.ServerReadTimeout, "server-read-timeout", "", opt.ServerReadTimeout, "Timeout for server reading data")
+	flagSet.DurationVarP(&opt.ServerWriteTimeout, "server-write-timeout", "", opt.ServerWriteTimeout, "Timeout for server writing data")
+	flagSet.IntVarP(&opt.MaxHeaderBytes, "max-header-bytes", "", opt.MaxHeaderBytes, "Maximum size of request header")
+	flagSet.StringVarP(&opt.SslCert, "cert", "", opt.SslCert, "SSL PEM key (concatenation of certificate and CA certificate)")
+	flagSet.StringVarP(&opt.SslKey, "key", "", opt.SslKey, "SSL PEM Private key")
+	flagSet.StringVarP(&opt.ClientCA, "client-ca", "", opt.ClientCA, "Client certificate authority to verify clients with")
+	flagSet.StringVarP(&opt.Realm, "realm", "", opt.Realm, "realm for authentication")
+	flagSet.StringVarP(&opt.BasicUser, "user", "", opt.BasicUser, "User name for authentication.")
+	flagSet.StringVarP(&opt.BasicPass, "pass", "", opt.BasicPass, "Password for authentication.")
+}
+
+// Server contains info about the running http server
+type Server struct {
+	Opt        Options
+	handler    http.Handler // original handler
+	listener   net.Listener
+	waitChan   chan struct{} // for waiting on the listener to close
+	httpServer *http.Server
+	useSSL     bool  // if server is configured for SSL/TLS
+	closing    int32 // set to 1 when Close has been called
+}
+
+// NewServer creates an http server.  The opt can be nil in which case
+// the default options will be used.
+func NewServer(handler http.Handler, opt *Options) *Server {
+	s := &Server{
+		handler: handler,
+	}
+
+	// Make a copy of the options
+	if opt != nil {
+		s.Opt = *opt
+	} else {
+		s.Opt = DefaultOpt
+	}
+
+	// Use basic auth if configured
+	if s.Opt.BasicUser != "" {
+		fs.Infof(nil, "Using --user %s --pass XXXX as authenticated user", s.Opt.BasicUser)
+		handler = s.basicAuth(handler)
+	}
+
+	s.useSSL = s.Opt.SslKey != ""
+	if (s.Opt.SslCert != "") != s.useSSL {
+		log.Fatalf("Need both -cert and -key to use SSL")
+	}
+
+	s.httpServer = &http.Server{
+		Addr:           s.Opt.ListenAddr,
+		Handler:        handler,
+		ReadTimeout:    s.Opt.ServerReadTimeout,
+		WriteTimeout:   s.Opt.ServerWriteTimeout,
+		MaxHeaderBytes: s.Opt.MaxHeaderBytes,
+	}
+
+	if s.Opt.ClientCA != "" {
+		if !s.useSSL {
+			log.Fatalf("Can't use --client-ca without --cert and --key")
+		}
+		certpool := x509.NewCertPool()
+		pem, err := ioutil.ReadFile(s.Opt.ClientCA)
+		if err != nil {
+			log.Fatalf("Failed to read client certificate authority: %v", err)
+		}
+		if !certpool.AppendCertsFromPEM(pem) {
+			log.Fatalf("Can't parse client certificate authority")
+		}
+		s.httpServer.TLSConfig = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  certpool,
+		}
+	}
+
+	return s
+}
+
+// basicAuth wraps handler requiring the configured user and password
+// to be supplied with HTTP basic authentication
+func (s *Server) basicAuth(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if ok {
+			userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.Opt.BasicUser)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.Opt.BasicPass)) == 1
+			if userOK && passOK {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", s.Opt.Realm))
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// Serve runs the server - returns an error only if the listener was
+// not started; does not block, so use s.Wait() to block on the
+// server
+func (s *Server) Serve() error {
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "start server failed")
+	}
+	s.listener = ln
+	s.waitChan = make(chan struct{})
+	go func() {
+		var err error
+		if s.useSSL {
+			// Load the certificates by hand as old Go versions
+			// don't have ServeTLS on http.Server
+			tlsConfig := s.httpServer.TLSConfig
+			if tlsConfig == nil {
+				tlsConfig = &tls.Config{}
+			}
+			tlsConfig.Certificates = make([]tls.Certificate, 1)
+			tlsConfig.Certificates[0], err = tls.LoadX509KeyPair(s.Opt.SslCert, s.Opt.SslKey)
+			if err == nil {
+				tlsConfig.NextProtos = []string{"http/1.1"}
+				err = s.httpServer.Serve(tls.NewListener(s.listener, tlsConfig))
+			}
+		} else {
+			err = s.httpServer.Serve(s.listener)
+		}
+		if err != nil && atomic.LoadInt32(&s.closing) == 0 {
+			fs.Errorf(nil, "Error on serving HTTP server: %v", err)
+		}
+		close(s.waitChan)
+	}()
+	return nil
+}
+
+// Wait blocks while the listener is open.
+func (s *Server) Wait() {
+	<-s.waitChan
+}
+
+// Close shuts the running server down
+func (s *Server) Close() {
+	atomic.StoreInt32(&s.closing, 1)
+	err := s.listener.Close()
+	if err != nil {
+		fs.Errorf(nil, "Error on closing HTTP server: %v", err)
+		return
+	}
+	<-s.waitChan
+}
+
+// URL returns the serving address of this server
+func (s *Server) URL() string {
+	proto := "http"
+	if s.useSSL {
+		proto = "https"
+	}
+	addr := s.Opt.ListenAddr
+	if s.listener != nil {
+		// prefer actual listener address if using ":port" or "addr:0"
+		addr = s.listener.Addr().String()
+	}
+	return fmt.Sprintf("%s://%s/", proto, addr)
+}
@@ -0,0 +1,68 @@
+// Parsing of HTTP Range headers
+
+package httplib
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRangeNotSatisfiable is returned by ParseRange when the range
+// doesn't overlap the object
+var ErrRangeNotSatisfiable = errors.New("requested range not satisfiable")
+
+// ParseRange parses a single HTTP Range header for an object of size
+// bytes, returning the absolute start and end (inclusive) offsets.
+//
+// It returns ErrRangeNotSatisfiable if the range is syntactically
+// valid but lies outside the object, or another error if the range
+// couldn't be parsed.  Multiple ranges aren't supported.
+func ParseRange(s string, size int64) (start, end int64, err error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, errors.Errorf("unknown range unit in %q", s)
+	}
+	s = strings.TrimSpace(s[len(prefix):])
+	if strings.Contains(s, ",") {
+		return 0, 0, errors.New("multiple ranges not supported")
+	}
+	i := strings.Index(s, "-")
+	if i < 0 {
+		return 0, 0, errors.Errorf("invalid range %q", s)
+	}
+	startStr, endStr := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	if startStr == "" {
+		// suffix range - the last N bytes
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, errors.Errorf("invalid range %q", s)
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, ErrRangeNotSatisfiable
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+	}
+	start, err = strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errors.Errorf("invalid range %q", s)
+	}
+	if start >= size {
+		return 0, 0, ErrRangeNotSatisfiable
+	}
+	end = size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errors.Errorf("invalid range %q", s)
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, nil
+}
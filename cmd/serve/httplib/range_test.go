@@ -0,0 +1,50 @@
+package httplib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	for _, test := range []struct {
+		in        string
+		size      int64
+		wantStart int64
+		wantEnd   int64
+		wantErr   string
+	}{
+		{in: "bytes=0-9", size: 100, wantStart: 0, wantEnd: 9},
+		{in: "bytes=10-", size: 100, wantStart: 10, wantEnd: 99},
+		{in: "bytes= 10 - 20 ", size: 100, wantStart: 10, wantEnd: 20},
+		{in: "bytes=90-200", size: 100, wantStart: 90, wantEnd: 99},
+		{in: "bytes=-10", size: 100, wantStart: 90, wantEnd: 99},
+		{in: "bytes=-200", size: 100, wantStart: 0, wantEnd: 99},
+		{in: "bytes=99-99", size: 100, wantStart: 99, wantEnd: 99},
+		{in: "bytes=100-", size: 100, wantErr: ErrRangeNotSatisfiable.Error()},
+		{in: "bytes=-0", size: 100, wantErr: ErrRangeNotSatisfiable.Error()},
+		{in: "bytes=0-", size: 0, wantErr: ErrRangeNotSatisfiable.Error()},
+		{in: "bytes=-5", size: 0, wantErr: ErrRangeNotSatisfiable.Error()},
+		{in: "items=0-9", size: 100, wantErr: "unknown range unit"},
+		{in: "bytes=0-9,20-29", size: 100, wantErr: "multiple ranges not supported"},
+		{in: "bytes=10", size: 100, wantErr: "invalid range"},
+		{in: "bytes=a-9", size: 100, wantErr: "invalid range"},
+		{in: "bytes=-a", size: 100, wantErr: "invalid range"},
+		{in: "bytes=20-10", size: 100, wantErr: "invalid range"},
+		{in: "bytes=-1-5", size: 100, wantErr: "invalid range"},
+	} {
+		start, end, err := ParseRange(test.in, test.size)
+		if test.wantErr != "" {
+			assert.Error(t, err, test.in)
+			if err != nil {
+				assert.Contains(t, err.Error(), test.wantErr, test.in)
+			}
+			continue
+		}
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.wantStart, start, test.in)
+		assert.Equal(t, test.wantEnd, end, test.in)
+	}
+	_, _, err := ParseRange("bytes=200-", 100)
+	assert.Equal(t, ErrRangeNotSatisfiable, err)
+}
@@ -0,0 +1,90 @@
+// Serving of objects over HTTP
+
+package httplib
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// ServeObject sends the object o in reply to a GET or HEAD request,
+// obeying any Range header.
+//
+// Any headers other than the length and range headers should be set
+// before calling this.  If the Range can't be satisfied it sets the
+// Content-Range header and returns ErrRangeNotSatisfiable.  Errors
+// opening the object are returned.  In both cases nothing will have
+// been written so the caller should reply with the error.  Errors
+// after the reply has been started can only be logged.
+func ServeObject(w http.ResponseWriter, r *http.Request, o fs.Object) error {
+	size := o.Size()
+
+	// Work out the Range if any
+	status := http.StatusOK
+	length := size
+	var options []fs.OpenOption
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && size >= 0 {
+		start, end, err := ParseRange(rangeHeader, size)
+		if err == ErrRangeNotSatisfiable {
+			w.Header().Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+			return err
+		} else if err != nil {
+			// Ignore Range headers we don't understand as
+			// allowed by RFC 7233 and send the whole object
+			fs.Debugf(o, "Ignoring Range %q: %v", rangeHeader, err)
+		} else {
+			options = append(options, &fs.RangeOption{Start: start, End: end})
+			length = end - start + 1
+			status = http.StatusPartialContent
+			w.Header().Set("Content-Range", "bytes "+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10)+"/"+strconv.FormatInt(size, 10))
+		}
+	}
+	if length >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+
+	// If HEAD no need to read the object since we have set the headers
+	if r.Method == "HEAD" {
+		w.WriteHeader(status)
+		return nil
+	}
+
+	in, err := o.Open(r.Context(), options...)
+	if err != nil {
+		return err
+	}
+	remote := o.Remote()
+	fs.Stats.Transferring(remote)
+	in = fs.NewAccountSizeName(in, length, remote).WithBuffer() // account the transfer
+
+	// Copy the contents of the object to the output
+	w.WriteHeader(status)
+	var reader io.Reader = in
+	if length >= 0 {
+		reader = io.LimitReader(in, length)
+	}
+	_, err = io.Copy(w, reader)
+	if err != nil {
+		fs.Stats.Error()
+		fs.Errorf(remote, "Failed to write file: %v", err)
+	}
+	closeErr := in.Close()
+	if closeErr != nil {
+		fs.Errorf(remote, "Failed to close file: %v", closeErr)
+		if err == nil {
+			err = closeErr
+		}
+	}
+	fs.Stats.DoneTransferring(remote, err == nil)
+	return nil
+}
+
+// InternalError logs the error and returns a 500 to the client
+func InternalError(what interface{}, w http.ResponseWriter, text string, err error) {
+	fs.Stats.Error()
+	fs.Errorf(what, "%s: %v", text, err)
+	http.Error(w, text+".", http.StatusInternalServerError)
+}
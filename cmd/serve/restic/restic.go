@@ -0,0 +1,386 @@
+// Package restic serves a remote suitable for use with restic
+package restic
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	httpOptions = httplib.DefaultOpt
+	appendOnly  = false
+)
+
+func init() {
+	httplib.AddFlags(Command.Flags(), &httpOptions)
+	Command.Flags().BoolVarP(&appendOnly, "append-only", "", appendOnly, "Disallow deletion of repository data")
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "restic remote:path",
+	Short: `Serve the remote for restic's REST API.`,
+	Long: `rclone serve restic implements restic's REST backend API
+over HTTP.  This allows restic to use rclone as a data storage
+mechanism for cloud providers that restic does not support directly.
+
+[Restic](https://restic.net/) is a command line program for doing
+backups.
+
+The server will log errors.  Use -v to see access logs.
+
+--bwlimit will be respected for file transfers.  Use --stats to
+control the stats printing.
+
+### Setting up rclone for use by restic ###
+
+First [set up a remote for your chosen cloud provider](/docs/#configure).
+
+Once you have set up the remote, check it is working with, for example
+"rclone lsd remote:".  You may have called the remote something other
+than "remote:" - just substitute whatever you called it in the
+following instructions.
+
+Now start the rclone restic server
+
+    rclone serve restic -v remote:backup
+
+Where you can replace "backup" in the above by whatever path in the
+remote you wish to use.
+
+By default this will serve on "localhost:8080" you can change this
+with use of the "--addr" flag.
+
+You might wish to start this server on boot.
+
+### Setting up restic to use rclone ###
+
+Now you can [follow the restic
+instructions](http://restic.readthedocs.io/en/latest/030_preparing_a_new_repo.html#rest-server)
+on setting up restic.
+
+Note that you will need restic 0.8.2 or later to interoperate with
+rclone.
+
+For the example above you will want to use "http://localhost:8080/"
+as the URL for the REST server.
+
+For example:
+
+    $ export RESTIC_REPOSITORY=rest:http://localhost:8080/
+    $ export RESTIC_PASSWORD=yourpassword
+    $ restic init
+    created restic backend 8b1a4b56ae at rest:http://localhost:8080/
+
+    Please note that knowledge of your password is required to access
+    the repository. Losing your password means that your data is
+    irrecoverably lost.
+    $ restic backup /path/to/files/to/backup
+    scan [/path/to/files/to/backup]
+    scanned 189 directories, 312 files in 0:00
+    [0:00] 100.00%  38.128 MiB / 38.128 MiB  501 / 501 items  0 errors  ETA 0:00
+    duration: 0:00
+    snapshot 45c8fdd8 saved
+
+#### Multiple repositories ####
+
+Note that you can use the endpoint to host multiple repositories.  Do
+this by adding a directory name or path after the URL.  Note that
+these **must** end with /.  Eg
+
+    $ export RESTIC_REPOSITORY=rest:http://localhost:8080/user1repo/
+    # backup user1 stuff
+    $ export RESTIC_REPOSITORY=rest:http://localhost:8080/user2repo/
+    # backup user2 stuff
+
+#### Append only mode ####
+
+If --append-only is set then restic will not be able to delete or
+overwrite any of the repository data apart from its lock files.  This
+protects the backups against a compromised client deleting the
+snapshots, for example with ransomware.  Note that "restic forget"
+and "restic prune" won't work against a server in append only mode -
+run them against the remote directly, or against a server without
+the flag.
+
+Because restic stores its data encrypted, it is fine to serve a crypt
+remote, but not necessary.
+` + httplib.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, true, command, func() error {
+			s := newServer(f, &httpOptions)
+			err := s.serve()
+			if err != nil {
+				return err
+			}
+			s.srv.Wait()
+			return nil
+		})
+	},
+}
+
+const (
+	resticAPIV2 = "application/vnd.x.restic.rest.v2"
+	resticAPIV1 = "application/vnd.x.restic.rest.v1"
+)
+
+// resticTypes are the directories restic stores its files in
+var resticTypes = []string{"data", "index", "keys", "locks", "snapshots"}
+
+var (
+	// matchConfig matches the config file of a repository,
+	// capturing the repository prefix
+	matchConfig = regexp.MustCompile(`^(.*/)?config$`)
+
+	// matchData matches a file or directory of a repository,
+	// capturing the repository prefix, the type and the name
+	// which is empty for a directory listing
+	matchData = regexp.MustCompile(`^(.*/)?(data|index|keys|locks|snapshots)/([^/]*)$`)
+)
+
+// server contains everything to run the server
+type server struct {
+	f          fs.Fs
+	srv        *httplib.Server
+	appendOnly bool
+}
+
+// newServer makes a new restic server serving f
+func newServer(f fs.Fs, opt *httplib.Options) *server {
+	mux := http.NewServeMux()
+	s := &server{
+		f:          f,
+		srv:        httplib.NewServer(mux, opt),
+		appendOnly: appendOnly,
+	}
+	mux.HandleFunc("/", s.handler)
+	return s
+}
+
+// serve starts the server running in the background
+func (s *server) serve() error {
+	err := s.srv.Serve()
+	if err != nil {
+		return err
+	}
+	fs.Logf(s.f, "Serving restic REST API on %s", s.srv.URL())
+	return nil
+}
+
+// handler reads incoming requests and dispatches them
+func (s *server) handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Server", "rclone/"+fs.Version)
+	urlPath := strings.TrimLeft(r.URL.Path, "/")
+	fs.Infof(s.f, "%s %s", r.Method, r.URL.Path)
+
+	// Create a repository
+	if urlPath == "" || strings.HasSuffix(urlPath, "/") {
+		if r.Method == "POST" && r.URL.Query().Get("create") == "true" {
+			s.createRepo(w, r, urlPath)
+			return
+		}
+	}
+
+	if match := matchConfig.FindStringSubmatch(urlPath); match != nil {
+		s.serveObject(w, r, "config", match[1]+"config")
+		return
+	}
+
+	match := matchData.FindStringSubmatch(urlPath)
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+	prefix, resticType, name := match[1], match[2], match[3]
+	if name == "" {
+		s.listObjects(w, r, prefix+resticType)
+		return
+	}
+	if name == "." || name == ".." {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveObject(w, r, resticType, objectRemote(prefix, resticType, name))
+}
+
+// objectRemote returns the path of the object called name of the
+// given type in the repository at prefix.
+//
+// Data files are stored in sub directories named after the first
+// two characters of their name as the restic local backend does.
+func objectRemote(prefix, resticType, name string) string {
+	if resticType == "data" && len(name) > 2 {
+		return prefix + "data/" + name[:2] + "/" + name
+	}
+	return prefix + resticType + "/" + name
+}
+
+// createRepo makes the directories for a new repository at prefix
+func (s *server) createRepo(w http.ResponseWriter, r *http.Request, prefix string) {
+	ctx := r.Context()
+	for _, resticType := range resticTypes {
+		dir := prefix + resticType
+		err := s.f.Mkdir(ctx, dir)
+		if err != nil {
+			httplib.InternalError(dir, w, "Failed to create repository", err)
+			return
+		}
+	}
+	fs.Logf(s.f, "Created repository at %q", "/"+prefix)
+}
+
+// serveObject serves the object at remote with the method requested
+func (s *server) serveObject(w http.ResponseWriter, r *http.Request, resticType, remote string) {
+	switch r.Method {
+	case "HEAD", "GET":
+		s.getObject(w, r, remote)
+	case "POST":
+		s.postObject(w, r, remote)
+	case "DELETE":
+		s.deleteObject(w, r, resticType, remote)
+	default:
+		w.Header().Set("Allow", "HEAD, GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getObject returns the object at remote with HEAD or GET, obeying
+// any Range header
+func (s *server) getObject(w http.ResponseWriter, r *http.Request, remote string) {
+	ctx := r.Context()
+	o, err := s.f.NewObject(ctx, remote)
+	if err == fs.ErrorObjectNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		httplib.InternalError(remote, w, "Failed to find object", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	err = httplib.ServeObject(w, r, o)
+	if err == httplib.ErrRangeNotSatisfiable {
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+	} else if err != nil {
+		httplib.InternalError(remote, w, "Failed to open object", err)
+	}
+}
+
+// postObject uploads the body of the request as the object at
+// remote.  Existing objects are never overwritten.
+func (s *server) postObject(w http.ResponseWriter, r *http.Request, remote string) {
+	ctx := r.Context()
+	if r.ContentLength < 0 {
+		http.Error(w, "Content-Length required", http.StatusLengthRequired)
+		return
+	}
+	_, err := s.f.NewObject(ctx, remote)
+	if err == nil {
+		fs.Errorf(remote, "Refusing to overwrite existing object")
+		http.Error(w, "Object already exists", http.StatusForbidden)
+		return
+	} else if err != fs.ErrorObjectNotFound {
+		httplib.InternalError(remote, w, "Failed to find object", err)
+		return
+	}
+
+	fs.Stats.Transferring(remote)
+	in := fs.NewAccountSizeName(r.Body, r.ContentLength, remote).WithBuffer() // account the transfer
+	src := fs.NewStaticObjectInfo(remote, time.Now(), r.ContentLength, true, nil, s.f)
+	_, err = s.f.Put(ctx, in, src)
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	fs.Stats.DoneTransferring(remote, err == nil)
+	if err != nil {
+		httplib.InternalError(remote, w, "Failed to write object", err)
+		return
+	}
+}
+
+// deleteObject removes the object at remote.  In append only mode
+// only locks may be deleted.
+func (s *server) deleteObject(w http.ResponseWriter, r *http.Request, resticType, remote string) {
+	ctx := r.Context()
+	if s.appendOnly && resticType != "locks" {
+		fs.Errorf(remote, "Refusing to delete object in append only mode")
+		http.Error(w, "Can't delete in append only mode", http.StatusForbidden)
+		return
+	}
+	o, err := s.f.NewObject(ctx, remote)
+	if err == fs.ErrorObjectNotFound {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		httplib.InternalError(remote, w, "Failed to find object", err)
+		return
+	}
+	err = o.Remove(ctx)
+	if err != nil {
+		httplib.InternalError(remote, w, "Failed to delete object", err)
+		return
+	}
+}
+
+// listItem is an element returned for the restic v2 list response
+type listItem struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// listObjects lists all the objects in dir, returning the format
+// requested by the Accept header.  A directory which doesn't exist
+// is returned as empty.
+func (s *server) listObjects(w http.ResponseWriter, r *http.Request, dir string) {
+	ctx := r.Context()
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// data is stored in sub directories, everything else is flat
+	maxLevel := 1
+	if path.Base(dir) == "data" {
+		maxLevel = 2
+	}
+	objs, _, err := fs.WalkGetAll(ctx, s.f, dir, true, maxLevel)
+	if err != nil && err != fs.ErrorDirNotFound {
+		httplib.InternalError(dir, w, "Failed to list directory", err)
+		return
+	}
+
+	var out interface{}
+	if r.Header.Get("Accept") == resticAPIV2 {
+		items := []listItem{}
+		for _, o := range objs {
+			items = append(items, listItem{Name: path.Base(o.Remote()), Size: o.Size()})
+		}
+		out = items
+		w.Header().Set("Content-Type", resticAPIV2)
+	} else {
+		names := []string{}
+		for _, o := range objs {
+			names = append(names, path.Base(o.Remote()))
+		}
+		out = names
+		w.Header().Set("Content-Type", resticAPIV1)
+	}
+	err = json.NewEncoder(w).Encode(out)
+	if err != nil {
+		fs.Errorf(dir, "Failed to write listing: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package restic
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resticRun holds a running restic server for a test
+type resticRun struct {
+	t   *testing.T
+	dir string
+	s   *server
+	url string
+}
+
+func newResticRun(t *testing.T, appendOnly bool) *resticRun {
+	fs.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-serve-restic-test")
+	require.NoError(t, err)
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	opt := httplib.DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	s := newServer(f, &opt)
+	s.appendOnly = appendOnly
+	require.NoError(t, s.serve())
+	return &resticRun{
+		t:   t,
+		dir: dir,
+		s:   s,
+		url: s.srv.URL(),
+	}
+}
+
+func (r *resticRun) finalise() {
+	r.s.srv.Close()
+	_ = os.RemoveAll(r.dir)
+}
+
+// do makes a request returning the status code and body
+func (r *resticRun) do(method, path string, body io.Reader, headers map[string]string) (int, string) {
+	req, err := http.NewRequest(method, r.url+path, body)
+	require.NoError(r.t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(r.t, err)
+	out, err := ioutil.ReadAll(resp.Body)
+	require.NoError(r.t, err)
+	require.NoError(r.t, resp.Body.Close())
+	return resp.StatusCode, string(out)
+}
+
+// readLocal reads the file in the served directory
+func (r *resticRun) readLocal(name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, filepath.FromSlash(name)))
+	require.NoError(r.t, err)
+	return string(data)
+}
+
+// existsLocal returns whether the path exists in the served directory
+func (r *resticRun) existsLocal(name string) bool {
+	_, err := os.Stat(filepath.Join(r.dir, filepath.FromSlash(name)))
+	return err == nil
+}
+
+const dataName = "3b1a4b56ae0123456789abcdef0123456789abcdef0123456789abcdef012345"
+
+func TestResticCreateAndConfig(t *testing.T) {
+	r := newResticRun(t, false)
+	defer r.finalise()
+
+	code, _ := r.do("POST", "?create=true", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	for _, resticType := range resticTypes {
+		assert.True(t, r.existsLocal(resticType), resticType)
+	}
+
+	code, _ = r.do("HEAD", "config", nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = r.do("POST", "config", strings.NewReader("config data"), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "config data", r.readLocal("config"))
+
+	code, _ = r.do("HEAD", "config", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := r.do("GET", "config", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "config data", body)
+
+	// Can't overwrite
+	code, _ = r.do("POST", "config", strings.NewReader("potato"), nil)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "config data", r.readLocal("config"))
+
+	code, _ = r.do("PUT", "config", strings.NewReader("potato"), nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	// A repository in a sub directory
+	code, _ = r.do("POST", "repo/sub/?create=true", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, r.existsLocal("repo/sub/keys"))
+	code, _ = r.do("POST", "repo/sub/config", strings.NewReader("sub config"), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "sub config", r.readLocal("repo/sub/config"))
+
+	code, _ = r.do("GET", "potato/", nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestResticObjects(t *testing.T) {
+	r := newResticRun(t, false)
+	defer r.finalise()
+
+	code, _ := r.do("POST", "?create=true", nil, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = r.do("POST", "data/"+dataName, strings.NewReader("hello world"), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "hello world", r.readLocal("data/3b/"+dataName))
+
+	code, _ = r.do("POST", "keys/abcd", strings.NewReader("key"), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "key", r.readLocal("keys/abcd"))
+
+	code, body := r.do("GET", "data/"+dataName, nil, map[string]string{"Range": "bytes=6-"})
+	assert.Equal(t, http.StatusPartialContent, code)
+	assert.Equal(t, "world", body)
+
+	code, body = r.do("GET", "data/"+dataName, nil, map[string]string{"Range": "bytes=0-4"})
+	assert.Equal(t, http.StatusPartialContent, code)
+	assert.Equal(t, "hello", body)
+
+	code, _ = r.do("GET", "data/notfound", nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Listings
+	code, body = r.do("GET", "data/", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `["`+dataName+`"]`+"\n", body)
+
+	code, body = r.do("GET", "data/", nil, map[string]string{"Accept": resticAPIV2})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `[{"name":"`+dataName+`","size":11}]`+"\n", body)
+
+	code, body = r.do("GET", "keys/", nil, map[string]string{"Accept": resticAPIV2})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `[{"name":"abcd","size":3}]`+"\n", body)
+
+	code, body = r.do("GET", "locks/", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[]\n", body)
+
+	code, body = r.do("GET", "missing/locks/", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[]\n", body)
+
+	// Deletion
+	code, _ = r.do("DELETE", "data/"+dataName, nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, r.existsLocal("data/3b/"+dataName))
+
+	code, _ = r.do("DELETE", "data/"+dataName, nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestResticAppendOnly(t *testing.T) {
+	r := newResticRun(t, true)
+	defer r.finalise()
+
+	code, _ := r.do("POST", "?create=true", nil, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = r.do("POST", "snapshots/abcd", strings.NewReader("snapshot"), nil)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = r.do("POST", "locks/ef01", strings.NewReader("lock"), nil)
+	require.Equal(t, http.StatusOK, code)
+
+	// Can't delete or overwrite snapshots
+	code, _ = r.do("DELETE", "snapshots/abcd", nil, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.True(t, r.existsLocal("snapshots/abcd"))
+	code, _ = r.do("POST", "snapshots/abcd", strings.NewReader("potato"), nil)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, "snapshot", r.readLocal("snapshots/abcd"))
+
+	// But can delete locks
+	code, _ = r.do("DELETE", "locks/ef01", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, r.existsLocal("locks/ef01"))
+}
@@ -0,0 +1,439 @@
+// Authentication of requests using AWS Signature Version 4 and
+// verification of the request bodies
+
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signV4Algorithm  = "AWS4-HMAC-SHA256"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	streamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	emptySHA256      = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	amzDateFormat    = "20060102T150405Z"
+	maxClockSkew     = 15 * time.Minute
+)
+
+// authorization is a parsed AWS4-HMAC-SHA256 Authorization header
+type authorization struct {
+	accessKey     string
+	date          string // YYYYMMDD
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+}
+
+// parseAuthorization parses an Authorization header which looks like
+//
+//	AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=fe5f80f77d5fa3be
+func parseAuthorization(header string) (*authorization, error) {
+	if !strings.HasPrefix(header, signV4Algorithm+" ") {
+		return nil, errAuthorizationHeaderMalformed.withMessage("Only AWS Signature Version 4 is supported.")
+	}
+	auth := &authorization{}
+	for _, field := range strings.Split(header[len(signV4Algorithm)+1:], ",") {
+		field = strings.TrimSpace(field)
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			return nil, errAuthorizationHeaderMalformed
+		}
+		key, value := field[:i], field[i+1:]
+		switch key {
+		case "Credential":
+			parts := strings.Split(value, "/")
+			if len(parts) != 5 || parts[4] != "aws4_request" {
+				return nil, errAuthorizationHeaderMalformed
+			}
+			auth.accessKey, auth.date, auth.region, auth.service = parts[0], parts[1], parts[2], parts[3]
+		case "SignedHeaders":
+			auth.signedHeaders = strings.Split(value, ";")
+		case "Signature":
+			auth.signature = value
+		}
+	}
+	if auth.accessKey == "" || len(auth.signedHeaders) == 0 || auth.signature == "" {
+		return nil, errAuthorizationHeaderMalformed
+	}
+	return auth, nil
+}
+
+// containsString returns true if s is in ss
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sha256Hex returns the hex encoded SHA256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signingKey derives the key used to sign requests from the secret
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// canonicalHeaderValue returns the value of the header as used in the
+// canonical request - multiple values are joined with commas and
+// runs of spaces are collapsed
+func canonicalHeaderValue(r *http.Request, name string) string {
+	if name == "host" {
+		return r.Host
+	}
+	values := r.Header[http.CanonicalHeaderKey(name)]
+	if len(values) == 0 && name == "content-length" && r.ContentLength >= 0 {
+		// The server removes Content-Length from the headers
+		values = []string{strconv.FormatInt(r.ContentLength, 10)}
+	}
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.Join(strings.Fields(value), " ")
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// canonicalRequest builds the canonical form of r for signing
+func canonicalRequest(r *http.Request, signedHeaders []string, payloadHash string) string {
+	// Use the path exactly as the client sent it
+	uri := r.RequestURI
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i]
+	}
+	if uri == "" {
+		uri = r.URL.EscapedPath()
+	}
+	query := strings.Replace(r.URL.Query().Encode(), "+", "%20", -1)
+	var headers bytes.Buffer
+	for _, name := range signedHeaders {
+		headers.WriteString(name)
+		headers.WriteByte(':')
+		headers.WriteString(canonicalHeaderValue(r, name))
+		headers.WriteByte('\n')
+	}
+	return strings.Join([]string{
+		r.Method,
+		uri,
+		query,
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// requestSigner holds what is needed to check the signatures of a
+// request and of the chunks of a streaming upload
+type requestSigner struct {
+	key       []byte // derived signing key
+	amzDate   string // time of the request in amzDateFormat
+	scope     string // credential scope
+	signature string // signature of the request
+}
+
+// sign returns the hex encoded signature of stringToSign
+func (rs *requestSigner) sign(stringToSign string) string {
+	return hex.EncodeToString(hmacSHA256(rs.key, stringToSign))
+}
+
+// chunkSignature returns the signature of a chunk of a streaming
+// upload given the signature of the previous chunk and the SHA256 of
+// the chunk data
+func (rs *requestSigner) chunkSignature(prevSignature string, chunkSHA256 []byte) string {
+	return rs.sign(strings.Join([]string{
+		"AWS4-HMAC-SHA256-PAYLOAD",
+		rs.amzDate,
+		rs.scope,
+		prevSignature,
+		emptySHA256,
+		hex.EncodeToString(chunkSHA256),
+	}, "\n"))
+}
+
+// checkAuth checks the signature of r against the configured keys.
+//
+// It returns nil, nil if no keys are configured, in which case all
+// requests are allowed.
+func (s *server) checkAuth(r *http.Request) (*requestSigner, error) {
+	if len(s.keys) == 0 {
+		return nil, nil
+	}
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, errAccessDenied
+	}
+	auth, err := parseAuthorization(header)
+	if err != nil {
+		return nil, err
+	}
+	secret, ok := s.keys[auth.accessKey]
+	if !ok {
+		return nil, errInvalidAccessKeyID
+	}
+	if !containsString(auth.signedHeaders, "host") {
+		return nil, errAuthorizationHeaderMalformed.withMessage("The host header must be signed.")
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	requestTime, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		return nil, errAccessDenied.withMessage("AWS authentication requires a valid X-Amz-Date header.")
+	}
+	if skew := time.Since(requestTime); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, errRequestTimeTooSkewed
+	}
+	if !strings.HasPrefix(amzDate, auth.date) {
+		return nil, errSignatureDoesNotMatch
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return nil, errInvalidRequest.withMessage("Missing required header for this request: x-amz-content-sha256.")
+	}
+	rs := &requestSigner{
+		key:     signingKey(secret, auth.date, auth.region, auth.service),
+		amzDate: amzDate,
+		scope:   strings.Join([]string{auth.date, auth.region, auth.service, "aws4_request"}, "/"),
+	}
+	rs.signature = rs.sign(strings.Join([]string{
+		signV4Algorithm,
+		amzDate,
+		rs.scope,
+		sha256Hex([]byte(canonicalRequest(r, auth.signedHeaders, payloadHash))),
+	}, "\n"))
+	if !hmac.Equal([]byte(rs.signature), []byte(auth.signature)) {
+		return nil, errSignatureDoesNotMatch
+	}
+	return rs, nil
+}
+
+// verifyReader reads the body of an upload checking its length and
+// any hashes supplied by the client when the end is reached.  It
+// records the first error so it can be returned to the client.
+type verifyReader struct {
+	in         io.Reader
+	size       int64     // expected size
+	read       int64     // bytes read so far
+	md5        hash.Hash // always calculated for the ETag
+	sha256     hash.Hash // nil if not being checked
+	wantMD5    []byte    // expected MD5 or nil
+	wantSHA256 string    // expected hex encoded SHA256 or ""
+	err        error     // first error returned
+}
+
+// requestBody returns a verifyReader for the body of r decoding
+// aws-chunked uploads if necessary.  signer may be nil if the
+// request isn't authenticated.
+func requestBody(r *http.Request, signer *requestSigner) (*verifyReader, error) {
+	v := &verifyReader{
+		in:   r.Body,
+		size: r.ContentLength,
+		md5:  md5.New(),
+	}
+	contentSHA256 := r.Header.Get("X-Amz-Content-Sha256")
+	switch {
+	case contentSHA256 == streamingPayload:
+		size, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil || size < 0 {
+			return nil, errMissingContentLength
+		}
+		v.size = size
+		v.in = newChunkedReader(r.Body, signer)
+	case contentSHA256 == "" || contentSHA256 == unsignedPayload:
+	default:
+		v.sha256 = sha256.New()
+		v.wantSHA256 = contentSHA256
+	}
+	if v.size < 0 {
+		return nil, errMissingContentLength
+	}
+	if contentMD5 := r.Header.Get("Content-Md5"); contentMD5 != "" {
+		wantMD5, err := base64.StdEncoding.DecodeString(contentMD5)
+		if err != nil || len(wantMD5) != md5.Size {
+			return nil, errInvalidDigest
+		}
+		v.wantMD5 = wantMD5
+	}
+	return v, nil
+}
+
+// Read the body checking it when the end is reached
+func (v *verifyReader) Read(p []byte) (n int, err error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err = v.in.Read(p)
+	v.read += int64(n)
+	_, _ = v.md5.Write(p[:n])
+	if v.sha256 != nil {
+		_, _ = v.sha256.Write(p[:n])
+	}
+	if v.read > v.size {
+		err = errIncompleteBody
+	} else if err == io.EOF {
+		err = v.check()
+		if err == nil {
+			err = io.EOF
+		}
+	}
+	if err != nil && err != io.EOF {
+		v.err = err
+	}
+	return n, err
+}
+
+// check the body once it has all been read
+func (v *verifyReader) check() error {
+	if v.read != v.size {
+		return errIncompleteBody
+	}
+	if v.wantMD5 != nil && !bytes.Equal(v.md5.Sum(nil), v.wantMD5) {
+		return errBadDigest
+	}
+	if v.sha256 != nil && hex.EncodeToString(v.sha256.Sum(nil)) != strings.ToLower(v.wantSHA256) {
+		return errContentSHA256Mismatch
+	}
+	return nil
+}
+
+// MD5 returns the hex encoded MD5 of the data read
+func (v *verifyReader) MD5() string {
+	return hex.EncodeToString(v.md5.Sum(nil))
+}
+
+// chunkedReader decodes a body sent with aws-chunked encoding,
+// checking the signature of each chunk if signer is set.
+//
+// Each chunk looks like
+//
+//	hex-size;chunk-signature=signature\r\n
+//	data\r\n
+//
+// and the body is terminated by a chunk of size 0.
+type chunkedReader struct {
+	in            *bufio.Reader
+	signer        *requestSigner
+	prevSignature string    // signature of the previous chunk
+	signature     string    // signature of the current chunk
+	hash          hash.Hash // SHA256 of the current chunk
+	remaining     int64     // bytes remaining in the current chunk
+	inChunk       bool      // set if a chunk header has been read
+	final         bool      // set if the current chunk is the last
+	err           error     // sticky error
+}
+
+// newChunkedReader makes a chunkedReader reading from in
+func newChunkedReader(in io.Reader, signer *requestSigner) *chunkedReader {
+	c := &chunkedReader{
+		in:     bufio.NewReader(in),
+		signer: signer,
+	}
+	if signer != nil {
+		c.prevSignature = signer.signature
+	}
+	return c
+}
+
+// Read decoded data from the chunks
+func (c *chunkedReader) Read(p []byte) (n int, err error) {
+	for c.err == nil && c.remaining == 0 {
+		c.err = c.nextChunk()
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err = c.in.Read(p)
+	c.remaining -= int64(n)
+	_, _ = c.hash.Write(p[:n])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// nextChunk finishes the current chunk, if any, and reads the
+// header of the next one.  It returns io.EOF after the final chunk.
+func (c *chunkedReader) nextChunk() error {
+	if c.inChunk {
+		// The chunk data is terminated by CRLF
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if line != "" {
+			return errIncompleteBody
+		}
+		if c.signer != nil {
+			signature := c.signer.chunkSignature(c.prevSignature, c.hash.Sum(nil))
+			if !hmac.Equal([]byte(signature), []byte(c.signature)) {
+				return errSignatureDoesNotMatch
+			}
+			c.prevSignature = signature
+		}
+		c.inChunk = false
+		if c.final {
+			return io.EOF
+		}
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	i := strings.Index(line, ";chunk-signature=")
+	if i < 0 {
+		return errIncompleteBody
+	}
+	size, err := strconv.ParseInt(line[:i], 16, 64)
+	if err != nil || size < 0 {
+		return errIncompleteBody
+	}
+	c.signature = line[i+len(";chunk-signature="):]
+	c.remaining = size
+	c.final = size == 0
+	c.hash = sha256.New()
+	c.inChunk = true
+	return nil
+}
+
+// readLine reads a CRLF terminated line
+func (c *chunkedReader) readLine() (string, error) {
+	line, err := c.in.ReadSlice('\n')
+	if err == io.EOF {
+		return "", io.ErrUnexpectedEOF
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuthorization(t *testing.T) {
+	auth, err := parseAuthorization("AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=fe5f80f77d5fa3be")
+	require.NoError(t, err)
+	assert.Equal(t, &authorization{
+		accessKey:     "AKID",
+		date:          "20130524",
+		region:        "us-east-1",
+		service:       "s3",
+		signedHeaders: []string{"host", "x-amz-date"},
+		signature:     "fe5f80f77d5fa3be",
+	}, auth)
+
+	for _, header := range []string{
+		"AWS AKID:signature",
+		"AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3, SignedHeaders=host, Signature=fe",
+		"AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, Signature=fe",
+		"AWS4-HMAC-SHA256 Credential",
+	} {
+		_, err := parseAuthorization(header)
+		assert.Error(t, err, header)
+	}
+}
+
+// makeChunked encodes chunks in aws-chunked encoding signing them
+// with rs
+func makeChunked(rs *requestSigner, chunks ...string) []byte {
+	var buf bytes.Buffer
+	prev := rs.signature
+	for _, chunk := range append(chunks, "") {
+		sum := sha256.Sum256([]byte(chunk))
+		signature := rs.chunkSignature(prev, sum[:])
+		_, _ = fmt.Fprintf(&buf, "%x;chunk-signature=%s\r\n%s\r\n", len(chunk), signature, chunk)
+		prev = signature
+	}
+	return buf.Bytes()
+}
+
+func TestChunkedReader(t *testing.T) {
+	rs := &requestSigner{
+		key:       signingKey("SECRET", "20130524", "us-east-1", "s3"),
+		amzDate:   "20130524T000000Z",
+		scope:     "20130524/us-east-1/s3/aws4_request",
+		signature: "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9",
+	}
+	body := makeChunked(rs, "hello ", "chunked ", "world")
+
+	// Correctly signed
+	data, err := ioutil.ReadAll(newChunkedReader(bytes.NewReader(body), rs))
+	require.NoError(t, err)
+	assert.Equal(t, "hello chunked world", string(data))
+
+	// Unsigned request
+	data, err = ioutil.ReadAll(newChunkedReader(bytes.NewReader(body), nil))
+	require.NoError(t, err)
+	assert.Equal(t, "hello chunked world", string(data))
+
+	// Tampered data
+	tampered := bytes.Replace(body, []byte("chunked"), []byte("CHUNKED"), 1)
+	_, err = ioutil.ReadAll(newChunkedReader(bytes.NewReader(tampered), rs))
+	assert.Equal(t, errSignatureDoesNotMatch, err)
+
+	// Truncated body
+	_, err = ioutil.ReadAll(newChunkedReader(bytes.NewReader(body[:len(body)-10]), rs))
+	assert.Error(t, err)
+}
@@ -0,0 +1,314 @@
+// Bucket operations and listings
+
+package s3
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/net/context"
+)
+
+// maxListKeys is the maximum number of keys returned in a listing
+const maxListKeys = 1000
+
+// listBuckets lists the top level directories as buckets
+func (s *server) listBuckets(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	entries, err := fs.ListDirSorted(ctx, s.f, false, "")
+	if err != nil && err != fs.ErrorDirNotFound {
+		return err
+	}
+	result := &listBucketsResult{
+		Xmlns:   s3Namespace,
+		Owner:   defaultOwner,
+		Buckets: []bucketInfo{},
+	}
+	for _, entry := range entries {
+		if dir, ok := entry.(*fs.Dir); ok {
+			result.Buckets = append(result.Buckets, bucketInfo{
+				Name:         dir.Remote(),
+				CreationDate: formatTime(dir.ModTime()),
+			})
+		}
+	}
+	writeXML(w, http.StatusOK, result)
+	return nil
+}
+
+// getBucketLocation returns an empty location which means the
+// default region
+func (s *server) getBucketLocation(w http.ResponseWriter, r *http.Request, bucket string) error {
+	err := s.checkBucket(r.Context(), bucket)
+	if err != nil {
+		return err
+	}
+	writeXML(w, http.StatusOK, &locationResult{Xmlns: s3Namespace})
+	return nil
+}
+
+// createBucket makes the directory for a bucket
+func (s *server) createBucket(w http.ResponseWriter, r *http.Request, bucket string) error {
+	ctx := r.Context()
+	exists, err := s.bucketExists(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errBucketAlreadyOwnedByYou
+	}
+	err = s.f.Mkdir(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	s.setBucket(bucket, true)
+	w.Header().Set("Location", "/"+bucket)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// deleteBucket removes the directory for a bucket if it is empty
+func (s *server) deleteBucket(w http.ResponseWriter, r *http.Request, bucket string) error {
+	ctx := r.Context()
+	err := s.checkBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	entries, err := s.f.List(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return errBucketNotEmpty
+	}
+	err = s.f.Rmdir(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	s.setBucket(bucket, false)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// listEntry is an object or a common prefix in a listing
+type listEntry struct {
+	key string
+	o   fs.Object // nil for a common prefix
+}
+
+// listEntries is a slice of listEntry sorted by key
+type listEntries []listEntry
+
+// Len is part of sort.Interface
+func (ls listEntries) Len() int { return len(ls) }
+
+// Swap is part of sort.Interface
+func (ls listEntries) Swap(i, j int) { ls[i], ls[j] = ls[j], ls[i] }
+
+// Less is part of sort.Interface
+func (ls listEntries) Less(i, j int) bool { return ls[i].key < ls[j].key }
+
+// listPrefix returns the objects in bucket whose keys start with
+// prefix, sorted by key.  If delimited is set then only the
+// directory containing prefix is listed and its sub directories are
+// returned as common prefixes.
+func (s *server) listPrefix(ctx context.Context, bucket, prefix string, delimited bool) (listEntries, error) {
+	dir := bucket
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = path.Join(bucket, prefix[:i])
+	}
+	var result listEntries
+	addEntries := func(entries fs.DirEntries) {
+		for _, entry := range entries {
+			key := strings.TrimPrefix(entry.Remote(), bucket+"/")
+			switch x := entry.(type) {
+			case fs.Object:
+				if strings.HasPrefix(key, prefix) {
+					result = append(result, listEntry{key: key, o: x})
+				}
+			case *fs.Dir:
+				key += "/"
+				if delimited && strings.HasPrefix(key, prefix) {
+					result = append(result, listEntry{key: key})
+				}
+			}
+		}
+	}
+	var err error
+	if delimited {
+		var entries fs.DirEntries
+		entries, err = fs.ListDirSorted(ctx, s.f, false, dir)
+		addEntries(entries)
+	} else {
+		err = fs.Walk(ctx, s.f, dir, false, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+			if err == fs.ErrorDirNotFound {
+				return fs.ErrorSkipDir
+			} else if err != nil {
+				return err
+			}
+			addEntries(entries)
+			return nil
+		})
+	}
+	if err == fs.ErrorDirNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	sort.Sort(result)
+	return result, nil
+}
+
+// encodeKey encodes key for a listing if encoding is "url"
+func encodeKey(key, encoding string) string {
+	if encoding != "url" {
+		return key
+	}
+	return strings.Replace(url.QueryEscape(key), "+", "%20", -1)
+}
+
+// listObjects implements ListObjects and ListObjectsV2
+func (s *server) listObjects(w http.ResponseWriter, r *http.Request, bucket string) error {
+	ctx := r.Context()
+	query := r.URL.Query()
+	v2 := query.Get("list-type") == "2"
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	if delimiter != "" && delimiter != "/" {
+		return errNotImplemented.withMessage("Only \"/\" is supported as a delimiter.")
+	}
+	encoding := query.Get("encoding-type")
+	if encoding != "" && encoding != "url" {
+		return errInvalidArgument.withMessage("Invalid Encoding Method specified in Request.")
+	}
+	maxKeys := maxListKeys
+	if maxKeysString := query.Get("max-keys"); maxKeysString != "" {
+		var err error
+		maxKeys, err = strconv.Atoi(maxKeysString)
+		if err != nil || maxKeys < 0 {
+			return errInvalidArgument.withMessage("Provided max-keys not an integer or within integer range.")
+		}
+		if maxKeys > maxListKeys {
+			maxKeys = maxListKeys
+		}
+	}
+
+	result := &listBucketResult{
+		Xmlns:        s3Namespace,
+		Name:         bucket,
+		Prefix:       encodeKey(prefix, encoding),
+		MaxKeys:      maxKeys,
+		Delimiter:    delimiter,
+		EncodingType: encoding,
+		Contents:     []objectInfo{},
+	}
+
+	// Work out where to start the listing from
+	var marker string
+	if v2 {
+		result.StartAfter = query.Get("start-after")
+		marker = result.StartAfter
+		if token := query.Get("continuation-token"); token != "" {
+			result.ContinuationToken = token
+			decoded, err := base64.StdEncoding.DecodeString(token)
+			if err != nil {
+				return errInvalidArgument.withMessage("The continuation token provided is incorrect.")
+			}
+			marker = string(decoded)
+		}
+	} else {
+		marker = query.Get("marker")
+		result.Marker = &marker
+	}
+
+	err := s.checkBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	entries, err := s.listPrefix(ctx, bucket, prefix, delimiter != "")
+	if err != nil {
+		return err
+	}
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].key > marker })
+	entries = entries[i:]
+	if len(entries) > maxKeys {
+		entries = entries[:maxKeys]
+		result.IsTruncated = true
+		lastKey := entries[len(entries)-1].key
+		if v2 {
+			result.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(lastKey))
+		} else {
+			result.NextMarker = encodeKey(lastKey, encoding)
+		}
+	}
+	for _, entry := range entries {
+		key := encodeKey(entry.key, encoding)
+		if entry.o == nil {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: key})
+			continue
+		}
+		info := objectInfo{
+			Key:          key,
+			LastModified: formatTime(entry.o.ModTime()),
+			ETag:         s.objectETag(entry.o),
+			Size:         entry.o.Size(),
+			StorageClass: "STANDARD",
+		}
+		if !v2 {
+			info.Owner = &defaultOwner
+		}
+		result.Contents = append(result.Contents, info)
+	}
+	if v2 {
+		keyCount := len(entries)
+		result.KeyCount = &keyCount
+	}
+	writeXML(w, http.StatusOK, result)
+	return nil
+}
+
+// deleteObjects implements DeleteObjects
+func (s *server) deleteObjects(w http.ResponseWriter, r *http.Request, bucket string) error {
+	ctx := r.Context()
+	var request deleteRequest
+	err := readXML(r, &request)
+	if err != nil {
+		return err
+	}
+	if len(request.Objects) > maxListKeys {
+		return errMalformedXML
+	}
+	err = s.checkBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	result := &deleteResult{Xmlns: s3Namespace}
+	for _, object := range request.Objects {
+		err := checkKey(object.Key)
+		if err == nil {
+			err = s.removeObject(ctx, bucket, object.Key)
+		}
+		if err != nil {
+			apiErr, ok := err.(*apiError)
+			if !ok {
+				fs.Errorf(bucket+"/"+object.Key, "Failed to delete: %v", err)
+				apiErr = errInternalError
+			}
+			result.Errors = append(result.Errors, deleteError{
+				Key:     object.Key,
+				Code:    apiErr.Code,
+				Message: apiErr.Message,
+			})
+		} else if !request.Quiet {
+			result.Deleted = append(result.Deleted, deletedObject{Key: object.Key})
+		}
+	}
+	writeXML(w, http.StatusOK, result)
+	return nil
+}
@@ -0,0 +1,426 @@
+// Multipart uploads
+//
+// The parts of an upload are stored in a local temporary directory
+// until the upload is completed, when they are uploaded to the remote
+// as a single object.
+
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// maxPartNumber is the largest part number allowed
+const maxPartNumber = 10000
+
+// multipartUpload is a multipart upload in progress
+type multipartUpload struct {
+	id        string
+	bucket    string
+	key       string
+	dir       string    // temporary directory holding the parts
+	modTime   time.Time // modification time for the object
+	initiated time.Time
+
+	mu    sync.Mutex
+	parts map[int]*uploadPart // parts uploaded so far by number
+}
+
+// uploadPart is a part of a multipart upload
+type uploadPart struct {
+	size     int64
+	md5      []byte
+	modified time.Time
+}
+
+// etag returns the quoted ETag of the part
+func (p *uploadPart) etag() string {
+	return `"` + hex.EncodeToString(p.md5) + `"`
+}
+
+// partPath returns the path of the file holding part n
+func (u *multipartUpload) partPath(n int) string {
+	return filepath.Join(u.dir, fmt.Sprintf("%05d", n))
+}
+
+// remove deletes the temporary files of the upload
+func (u *multipartUpload) remove() {
+	err := os.RemoveAll(u.dir)
+	if err != nil {
+		fs.Errorf(nil, "Failed to remove multipart upload directory: %v", err)
+	}
+}
+
+// writePart writes part n of the upload from in
+func (u *multipartUpload) writePart(n int, in io.Reader) (part *uploadPart, err error) {
+	// Write to a temporary file so a failed upload doesn't
+	// overwrite a previous copy of the part
+	tmp, err := ioutil.TempFile(u.dir, "part-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make part file")
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	h := md5.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), in)
+	if err != nil {
+		return nil, err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write part file")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	err = os.Rename(tmp.Name(), u.partPath(n))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to rename part file")
+	}
+	part = &uploadPart{
+		size:     size,
+		md5:      h.Sum(nil),
+		modified: time.Now(),
+	}
+	u.parts[n] = part
+	return part, nil
+}
+
+// getUpload returns the upload with uploadID for bucket and key
+func (s *server) getUpload(bucket, key, uploadID string) (*multipartUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok || upload.bucket != bucket || upload.key != key {
+		return nil, errNoSuchUpload
+	}
+	return upload, nil
+}
+
+// deleteUpload forgets the upload and removes its temporary files
+func (s *server) deleteUpload(upload *multipartUpload) {
+	s.mu.Lock()
+	delete(s.uploads, upload.id)
+	s.mu.Unlock()
+	upload.remove()
+}
+
+// parsePartNumber reads the part number from the request
+func parsePartNumber(r *http.Request) (int, error) {
+	n, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || n < 1 || n > maxPartNumber {
+		return 0, errInvalidArgument.withMessage(fmt.Sprintf("Part number must be an integer between 1 and %d, inclusive.", maxPartNumber))
+	}
+	return n, nil
+}
+
+// createMultipartUpload implements CreateMultipartUpload
+func (s *server) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	err := s.checkBucket(r.Context(), bucket)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(key, "/") {
+		return errInvalidArgument.withMessage("Directory markers can't be uploaded in parts.")
+	}
+	dir, err := ioutil.TempDir("", "rclone-s3-")
+	if err != nil {
+		return errors.Wrap(err, "failed to make multipart upload directory")
+	}
+	upload := &multipartUpload{
+		id:        newID(),
+		bucket:    bucket,
+		key:       key,
+		dir:       dir,
+		modTime:   requestModTime(r),
+		initiated: time.Now(),
+		parts:     make(map[int]*uploadPart),
+	}
+	s.mu.Lock()
+	s.uploads[upload.id] = upload
+	s.mu.Unlock()
+	writeXML(w, http.StatusOK, &initiateMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: upload.id,
+	})
+	return nil
+}
+
+// uploadPart implements UploadPart
+func (s *server) uploadPart(w http.ResponseWriter, r *http.Request, signer *requestSigner, bucket, key, uploadID string) error {
+	n, err := parsePartNumber(r)
+	if err != nil {
+		return err
+	}
+	upload, err := s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	body, err := requestBody(r, signer)
+	if err != nil {
+		return err
+	}
+	part, err := upload.writePart(n, body)
+	if body.err != nil {
+		return body.err
+	} else if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", part.etag())
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// parseCopySourceRange parses the X-Amz-Copy-Source-Range header
+// which looks like "bytes=first-last"
+func parseCopySourceRange(header string, size int64) (start, end int64, err error) {
+	invalid := errInvalidArgument.withMessage("The x-amz-copy-source-range value must be of the form bytes=first-last where first and last are the zero-based offsets of the first and last bytes to copy.")
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, invalid
+	}
+	parts := strings.SplitN(header[len("bytes="):], "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, invalid
+	}
+	start, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, invalid
+	}
+	end, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || end < start {
+		return 0, 0, invalid
+	}
+	if end >= size {
+		return 0, 0, errInvalidRange
+	}
+	return start, end, nil
+}
+
+// uploadPartCopy implements UploadPartCopy
+func (s *server) uploadPartCopy(w http.ResponseWriter, r *http.Request, bucket, key, uploadID, copySource string) (err error) {
+	ctx := r.Context()
+	n, err := parsePartNumber(r)
+	if err != nil {
+		return err
+	}
+	upload, err := s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	srcBucket, srcKey, err := parseCopySource(copySource)
+	if err != nil {
+		return err
+	}
+	src, err := s.findObject(ctx, srcBucket, srcKey)
+	if err != nil {
+		return err
+	}
+	var options []fs.OpenOption
+	length := src.Size()
+	if header := r.Header.Get("X-Amz-Copy-Source-Range"); header != "" {
+		start, end, err := parseCopySourceRange(header, src.Size())
+		if err != nil {
+			return err
+		}
+		options = append(options, &fs.RangeOption{Start: start, End: end})
+		length = end - start + 1
+	}
+	in, err := src.Open(ctx, options...)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	part, err := upload.writePart(n, io.LimitReader(in, length))
+	if err != nil {
+		return err
+	}
+	if part.size != length {
+		return errors.Errorf("copied %d bytes but expected %d", part.size, length)
+	}
+	writeXML(w, http.StatusOK, &copyPartResult{
+		Xmlns:        s3Namespace,
+		LastModified: formatTime(part.modified),
+		ETag:         part.etag(),
+	})
+	return nil
+}
+
+// completeMultipartUpload implements CompleteMultipartUpload
+func (s *server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) error {
+	ctx := r.Context()
+	upload, err := s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	var request completeMultipartUploadRequest
+	err = readXML(r, &request)
+	if err != nil {
+		return err
+	}
+	if len(request.Parts) == 0 {
+		return errMalformedXML
+	}
+
+	// Stop parts being uploaded while completing
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	// Check the parts and open them
+	var (
+		readers  []io.Reader
+		size     int64
+		md5s     []byte
+		lastPart int
+	)
+	defer func() {
+		for _, in := range readers {
+			_ = in.(*os.File).Close()
+		}
+	}()
+	for _, requestPart := range request.Parts {
+		if requestPart.PartNumber <= lastPart {
+			return errInvalidPartOrder
+		}
+		lastPart = requestPart.PartNumber
+		part, ok := upload.parts[requestPart.PartNumber]
+		if !ok || strings.Trim(requestPart.ETag, `"`) != hex.EncodeToString(part.md5) {
+			return errInvalidPart
+		}
+		in, err := os.Open(upload.partPath(requestPart.PartNumber))
+		if err != nil {
+			return errors.Wrap(err, "failed to open part file")
+		}
+		readers = append(readers, in)
+		size += part.size
+		md5s = append(md5s, part.md5...)
+	}
+
+	_, err = s.upload(ctx, path.Join(bucket, key), io.MultiReader(readers...), size, upload.modTime)
+	if err != nil {
+		return err
+	}
+	s.deleteUpload(upload)
+
+	// The ETag of a multipart upload is the MD5 of the MD5s of
+	// the parts followed by the number of parts
+	etag := md5.Sum(md5s)
+	writeXML(w, http.StatusOK, &completeMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: "/" + bucket + "/" + key,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(etag[:]), len(request.Parts)),
+	})
+	return nil
+}
+
+// abortMultipartUpload implements AbortMultipartUpload
+func (s *server) abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) error {
+	upload, err := s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	s.deleteUpload(upload)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// listParts implements ListParts
+func (s *server) listParts(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) error {
+	upload, err := s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	result := &listPartsResult{
+		Xmlns:        s3Namespace,
+		Bucket:       bucket,
+		Key:          key,
+		UploadID:     uploadID,
+		StorageClass: "STANDARD",
+	}
+	upload.mu.Lock()
+	numbers := make([]int, 0, len(upload.parts))
+	for n := range upload.parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		part := upload.parts[n]
+		result.Parts = append(result.Parts, partInfo{
+			PartNumber:   n,
+			LastModified: formatTime(part.modified),
+			ETag:         part.etag(),
+			Size:         part.size,
+		})
+	}
+	upload.mu.Unlock()
+	writeXML(w, http.StatusOK, result)
+	return nil
+}
+
+// uploadInfos is a slice of uploadInfo sorted by key then time
+type uploadInfos []uploadInfo
+
+// Len is part of sort.Interface
+func (us uploadInfos) Len() int { return len(us) }
+
+// Swap is part of sort.Interface
+func (us uploadInfos) Swap(i, j int) { us[i], us[j] = us[j], us[i] }
+
+// Less is part of sort.Interface
+func (us uploadInfos) Less(i, j int) bool {
+	if us[i].Key != us[j].Key {
+		return us[i].Key < us[j].Key
+	}
+	return us[i].Initiated < us[j].Initiated
+}
+
+// listMultipartUploads implements ListMultipartUploads
+func (s *server) listMultipartUploads(w http.ResponseWriter, r *http.Request, bucket string) error {
+	err := s.checkBucket(r.Context(), bucket)
+	if err != nil {
+		return err
+	}
+	prefix := r.URL.Query().Get("prefix")
+	var uploads uploadInfos
+	s.mu.Lock()
+	for _, upload := range s.uploads {
+		if upload.bucket == bucket && strings.HasPrefix(upload.key, prefix) {
+			uploads = append(uploads, uploadInfo{
+				Key:          upload.key,
+				UploadID:     upload.id,
+				Initiated:    formatTime(upload.initiated),
+				StorageClass: "STANDARD",
+			})
+		}
+	}
+	s.mu.Unlock()
+	sort.Sort(uploads)
+	writeXML(w, http.StatusOK, &listMultipartUploadsResult{
+		Xmlns:   s3Namespace,
+		Bucket:  bucket,
+		Uploads: uploads,
+	})
+	return nil
+}
@@ -0,0 +1,289 @@
+// Object operations
+
+package s3
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/swift"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// metaMtime is the header the modification time is stored in as
+// used by rclone's s3 backend
+const metaMtime = "X-Amz-Meta-Mtime"
+
+// findObject returns the object for key in bucket
+func (s *server) findObject(ctx context.Context, bucket, key string) (fs.Object, error) {
+	o, err := s.f.NewObject(ctx, path.Join(bucket, key))
+	if err == nil && fs.Config.Filter.IncludeObject(o) {
+		return o, nil
+	} else if err != nil && err != fs.ErrorObjectNotFound && errors.Cause(err) != fs.ErrorNotAFile {
+		return nil, err
+	}
+	err = s.checkBucket(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return nil, errNoSuchKey
+}
+
+// requestModTime reads the modification time from the metadata of
+// r, returning the current time if it isn't set
+func requestModTime(r *http.Request) time.Time {
+	if mtime := r.Header.Get(metaMtime); mtime != "" {
+		modTime, err := swift.FloatStringToTime(mtime)
+		if err == nil {
+			return modTime
+		}
+		fs.Debugf(r.URL.Path, "Failed to read %s %q: %v", metaMtime, mtime, err)
+	}
+	return time.Now()
+}
+
+// setObjectHeaders sets the headers describing o
+func (s *server) setObjectHeaders(w http.ResponseWriter, o fs.Object) {
+	header := w.Header()
+	header.Set("ETag", s.objectETag(o))
+	header.Set("Last-Modified", o.ModTime().UTC().Format(http.TimeFormat))
+	header.Set("Content-Type", fs.MimeType(o))
+	header.Set("Accept-Ranges", "bytes")
+	header.Set(metaMtime, swift.TimeToFloatString(o.ModTime()))
+}
+
+// getObject implements GetObject and HeadObject
+func (s *server) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	ctx := r.Context()
+	o, err := s.findObject(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+	s.setObjectHeaders(w, o)
+	err = httplib.ServeObject(w, r, o)
+	if err == httplib.ErrRangeNotSatisfiable {
+		return errInvalidRange
+	}
+	return err
+}
+
+// upload uploads in to remote with the size and modification time
+// given, replacing any existing object
+func (s *server) upload(ctx context.Context, remote string, in io.Reader, size int64, modTime time.Time) (o fs.Object, err error) {
+	fs.Stats.Transferring(remote)
+	defer func() {
+		if err != nil {
+			fs.Stats.Error()
+		}
+		fs.Stats.DoneTransferring(remote, err == nil)
+	}()
+	account := fs.NewAccountSizeName(ioutil.NopCloser(in), size, remote).WithBuffer() // account the transfer
+	defer fs.CheckClose(account, &err)
+	src := fs.NewStaticObjectInfo(remote, modTime, size, true, nil, nil)
+	o, err = s.f.NewObject(ctx, remote)
+	if err == nil {
+		return o, o.Update(ctx, account, src)
+	} else if err != fs.ErrorObjectNotFound {
+		return nil, err
+	}
+	return s.f.Put(ctx, account, src)
+}
+
+// uploadBody uploads the body of r to remote checking it against
+// the hashes the client sent.  It returns the object and the ETag.
+//
+// The body is read into a temporary file and checked before it is
+// uploaded so a bad upload never replaces an existing object.
+func (s *server) uploadBody(ctx context.Context, r *http.Request, signer *requestSigner, remote string) (o fs.Object, etag string, err error) {
+	body, err := requestBody(r, signer)
+	if err != nil {
+		return nil, "", err
+	}
+	tmp, err := ioutil.TempFile("", "rclone-s3-")
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to make temporary file")
+	}
+	defer func() {
+		_ = tmp.Close()
+		removeErr := os.Remove(tmp.Name())
+		if removeErr != nil {
+			fs.Errorf(remote, "Failed to remove temporary file: %v", removeErr)
+		}
+	}()
+	_, err = io.Copy(tmp, body)
+	if body.err != nil {
+		return nil, "", body.err
+	} else if err != nil {
+		return nil, "", errors.Wrap(err, "failed to receive file")
+	}
+	_, err = tmp.Seek(0, os.SEEK_SET)
+	if err != nil {
+		return nil, "", err
+	}
+	o, err = s.upload(ctx, remote, tmp, body.size, requestModTime(r))
+	if err != nil {
+		return nil, "", err
+	}
+	md5sum := s.objectMD5(o)
+	if md5sum == "" {
+		// Use the MD5 we calculated rather than a synthetic ETag
+		md5sum = body.MD5()
+	}
+	return o, `"` + md5sum + `"`, nil
+}
+
+// putObject implements PutObject
+func (s *server) putObject(w http.ResponseWriter, r *http.Request, signer *requestSigner, bucket, key string) error {
+	ctx := r.Context()
+	err := s.checkBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(key, "/") {
+		// A directory marker
+		if r.ContentLength > 0 {
+			return errInvalidArgument.withMessage("Directory markers must be empty.")
+		}
+		err = s.f.Mkdir(ctx, path.Join(bucket, key))
+		if err != nil {
+			return err
+		}
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	_, etag, err := s.uploadBody(ctx, r, signer, path.Join(bucket, key))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// parseCopySource parses the X-Amz-Copy-Source header into a bucket
+// and key
+func parseCopySource(copySource string) (bucket, key string, err error) {
+	if i := strings.Index(copySource, "?"); i >= 0 {
+		if strings.HasPrefix(copySource[i+1:], "versionId=") {
+			return "", "", errNotImplemented.withMessage("Copying versions is not implemented.")
+		}
+		copySource = copySource[:i]
+	}
+	copySource, err = url.QueryUnescape(copySource)
+	if err != nil {
+		return "", "", errInvalidArgument.withMessage("Copy Source must mention the source bucket and key: sourcebucket/sourcekey.")
+	}
+	bucket, key = splitPath(copySource)
+	if bucket == "" || key == "" || checkBucketName(bucket) != nil || checkKey(key) != nil || strings.HasSuffix(key, "/") {
+		return "", "", errInvalidArgument.withMessage("Copy Source must mention the source bucket and key: sourcebucket/sourcekey.")
+	}
+	return bucket, key, nil
+}
+
+// copyObject implements CopyObject
+func (s *server) copyObject(w http.ResponseWriter, r *http.Request, bucket, key, copySource string) error {
+	ctx := r.Context()
+	srcBucket, srcKey, err := parseCopySource(copySource)
+	if err != nil {
+		return err
+	}
+	src, err := s.findObject(ctx, srcBucket, srcKey)
+	if err != nil {
+		return err
+	}
+	err = s.checkBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	remote := path.Join(bucket, key)
+	replace := r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE"
+	modTime := src.ModTime()
+	if replace {
+		modTime = requestModTime(r)
+	}
+
+	var o fs.Object
+	if src.Remote() == remote {
+		// Copying an object to itself is used to change its metadata
+		if !replace {
+			return errInvalidRequest.withMessage("This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata.")
+		}
+		o = src
+	} else {
+		dst, err := s.f.NewObject(ctx, remote)
+		if err == fs.ErrorObjectNotFound {
+			dst = nil
+		} else if err != nil {
+			return err
+		}
+		err = fs.Copy(ctx, s.f, dst, remote, src)
+		if err != nil {
+			return err
+		}
+		o, err = s.f.NewObject(ctx, remote)
+		if err != nil {
+			return err
+		}
+	}
+	if !o.ModTime().Equal(modTime) {
+		err = o.SetModTime(ctx, modTime)
+		if err != nil && err != fs.ErrorCantSetModTime {
+			return err
+		}
+	}
+	writeXML(w, http.StatusOK, &copyObjectResult{
+		Xmlns:        s3Namespace,
+		LastModified: formatTime(o.ModTime()),
+		ETag:         s.objectETag(o),
+	})
+	return nil
+}
+
+// removeObject removes key from bucket.  Keys ending in "/" remove
+// the directory if empty.  It isn't an error if the key doesn't
+// exist.
+func (s *server) removeObject(ctx context.Context, bucket, key string) error {
+	remote := path.Join(bucket, key)
+	if strings.HasSuffix(key, "/") {
+		err := s.f.Rmdir(ctx, remote)
+		if err != nil {
+			fs.Debugf(remote, "Not removing directory: %v", err)
+		}
+		return nil
+	}
+	o, err := s.f.NewObject(ctx, remote)
+	if err == fs.ErrorObjectNotFound || errors.Cause(err) == fs.ErrorNotAFile {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !fs.Config.Filter.IncludeObject(o) {
+		return nil
+	}
+	return fs.DeleteFile(ctx, o)
+}
+
+// deleteObject implements DeleteObject
+func (s *server) deleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	ctx := r.Context()
+	err := s.checkBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	err = s.removeObject(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
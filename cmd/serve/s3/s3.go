@@ -0,0 +1,502 @@
+// Package s3 implements an S3 compatible server to serve an rclone
+// remote
+package s3
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Globals
+var (
+	httpOptions = httplib.DefaultOpt
+	authKeys    []string
+	etagMD5     = true
+)
+
+func init() {
+	flags := Command.Flags()
+	httplib.AddFlags(flags, &httpOptions)
+	flags.StringArrayVarP(&authKeys, "auth-key", "", authKeys, "Set key pair for v4 authorization, split by comma (Can be multi-valued)")
+	flags.BoolVarP(&etagMD5, "etag-md5", "", etagMD5, "Use the MD5 hash of objects as their ETag if the remote supports it")
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "s3 remote:path",
+	Short: `Serve the remote as an S3 compatible server.`,
+	Long: `rclone serve s3 implements a basic S3 compatible server to serve
+the remote over the S3 protocol.  This means S3 tools and SDKs can
+read and write any remote rclone supports, including crypt remotes.
+
+The top level directories of the remote are served as buckets and
+the files within them as objects.  Files in the root of the remote
+aren't visible.  Making and deleting buckets makes and removes the
+top level directories.
+
+The following S3 operations are supported
+
+  - ListBuckets, CreateBucket, DeleteBucket, HeadBucket, GetBucketLocation
+  - ListObjects and ListObjectsV2 (with "/" as the only delimiter)
+  - GetObject (including Range requests), HeadObject, PutObject
+  - CopyObject (server side if the remote supports it)
+  - DeleteObject and DeleteObjects
+  - CreateMultipartUpload, UploadPart, UploadPartCopy,
+    CompleteMultipartUpload, AbortMultipartUpload, ListParts and
+    ListMultipartUploads
+
+Clients must use path style addressing (eg
+http://localhost:8080/bucket/key) - virtual host style addressing isn't
+supported.
+
+The modification time of objects is read from and written to the
+"X-Amz-Meta-Mtime" metadata as used by rclone's s3 backend.  Other
+metadata is discarded.  Keys ending in "/" with no data are treated
+as directory markers and make a directory.
+
+You can use the filter flags (eg --include, --exclude) to control what
+is served.
+
+### ETags
+
+If the remote supports MD5 hashes then these are returned as the ETag
+of objects, so clients can check their transfers.  Calculating them
+can be expensive for some remotes, eg local disk, in which case you
+can use --etag-md5=false to stop this.  Without MD5 hashes a synthetic
+ETag in the style of a multipart upload is returned which clients
+won't attempt to check.
+
+### Authentication
+
+By default any client can use the server without credentials.
+
+Use --auth-key "accessKey,secretKey" to require clients to sign their
+requests with AWS Signature Version 4 using these credentials.  This
+may be repeated to allow several key pairs.  Streaming (aws-chunked)
+uploads are supported and the signature of every chunk is checked.
+Note that the HTTP basic authentication flags (--user, --pass)
+shouldn't be used as S3 clients don't support them.
+
+### Testing the s3 backend
+
+You can run the integration tests of rclone's s3 backend against this
+server by configuring a remote of type s3 with "endpoint" set to the
+server's URL, eg
+
+    rclone serve s3 --auth-key ACCESS,SECRET /tmp/s3test
+    go test -v ./s3 -remote TestS3:
+
+with the TestS3 remote configured with access_key_id ACCESS,
+secret_access_key SECRET, region "other-v4-signature" and endpoint
+"http://localhost:8080/".
+` + httplib.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, true, command, func() error {
+			s, err := newServer(f, &httpOptions, authKeys)
+			if err != nil {
+				return err
+			}
+			err = s.serve()
+			if err != nil {
+				return err
+			}
+			s.srv.Wait()
+			s.cleanup()
+			return nil
+		})
+	},
+}
+
+// server contains everything to run the server
+type server struct {
+	f       fs.Fs
+	srv     *httplib.Server
+	keys    map[string]string // secret keys indexed by access key
+	etagMD5 bool              // use the MD5 as the ETag if available
+
+	mu       sync.Mutex
+	buckets  map[string]struct{}         // buckets known to exist
+	uploads  map[string]*multipartUpload // uploads in progress by ID
+	requests uint64                      // number of requests received
+}
+
+// newServer makes a new S3 server serving f
+//
+// authKeys is a list of "accessKey,secretKey" pairs
+func newServer(f fs.Fs, opt *httplib.Options, authKeys []string) (*server, error) {
+	mux := http.NewServeMux()
+	s := &server{
+		f:       f,
+		srv:     httplib.NewServer(mux, opt),
+		keys:    make(map[string]string, len(authKeys)),
+		etagMD5: etagMD5,
+		buckets: make(map[string]struct{}),
+		uploads: make(map[string]*multipartUpload),
+	}
+	for _, authKey := range authKeys {
+		parts := strings.SplitN(authKey, ",", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid --auth-key %q - expecting \"accessKey,secretKey\"", authKey)
+		}
+		s.keys[parts[0]] = parts[1]
+	}
+	mux.HandleFunc("/", s.handler)
+	return s, nil
+}
+
+// serve starts the server running in the background
+func (s *server) serve() error {
+	err := s.srv.Serve()
+	if err != nil {
+		return err
+	}
+	fs.Logf(s.f, "Serving S3 on %s", s.srv.URL())
+	return nil
+}
+
+// cleanup removes the temporary files of any unfinished uploads
+func (s *server) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, upload := range s.uploads {
+		upload.remove()
+		delete(s.uploads, id)
+	}
+}
+
+// newRequestID returns an ID to identify a request in the logs
+func (s *server) newRequestID() string {
+	s.mu.Lock()
+	s.requests++
+	n := s.requests
+	s.mu.Unlock()
+	return fmt.Sprintf("%016X", n)
+}
+
+// handler reads incoming requests and dispatches them
+func (s *server) handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Server", "rclone/"+fs.Version)
+	w.Header().Set("X-Amz-Request-Id", s.newRequestID())
+	fs.Infof(s.f, "%s %s", r.Method, r.URL)
+
+	signer, err := s.checkAuth(r)
+	if err == nil {
+		bucket, key := splitPath(r.URL.Path)
+		switch {
+		case bucket == "":
+			err = s.serviceHandler(w, r)
+		case key == "":
+			err = s.bucketHandler(w, r, bucket)
+		default:
+			err = s.objectHandler(w, r, signer, bucket, key)
+		}
+	}
+	if err != nil {
+		s.writeError(w, r, err)
+	}
+}
+
+// splitPath splits the URL path into a bucket and a key
+func splitPath(urlPath string) (bucket, key string) {
+	urlPath = strings.TrimPrefix(urlPath, "/")
+	i := strings.IndexByte(urlPath, '/')
+	if i < 0 {
+		return urlPath, ""
+	}
+	return urlPath[:i], urlPath[i+1:]
+}
+
+// unsupportedSubresources are the query parameters of S3 requests
+// for features which aren't implemented
+var unsupportedSubresources = []string{
+	"accelerate", "acl", "analytics", "cors", "encryption",
+	"inventory", "legal-hold", "lifecycle", "logging", "metrics",
+	"notification", "object-lock", "policy", "replication",
+	"requestPayment", "restore", "retention", "select", "tagging",
+	"torrent", "versioning", "versions", "website",
+}
+
+// checkSubresources returns an error if r uses an unsupported
+// subresource
+func checkSubresources(r *http.Request) error {
+	query := r.URL.Query()
+	for _, subresource := range unsupportedSubresources {
+		if _, found := query[subresource]; found {
+			return errNotImplemented.withMessage(fmt.Sprintf("The %q subresource is not implemented.", subresource))
+		}
+	}
+	return nil
+}
+
+// checkBucketName returns an error if bucket can't be a directory
+func checkBucketName(bucket string) error {
+	if bucket == "." || bucket == ".." {
+		return errInvalidBucketName
+	}
+	return nil
+}
+
+// checkKey returns an error if key can't be mapped onto a remote
+// path.  Keys may end in "/" for directory markers.
+func checkKey(key string) error {
+	for _, segment := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return errInvalidArgument.withMessage(fmt.Sprintf("Object key %q can't be stored.", key))
+		}
+	}
+	return nil
+}
+
+// bucketExists returns true if the bucket is a directory in the
+// root of the remote, remembering buckets which are found
+func (s *server) bucketExists(ctx context.Context, bucket string) (bool, error) {
+	s.mu.Lock()
+	_, found := s.buckets[bucket]
+	s.mu.Unlock()
+	if found {
+		return true, nil
+	}
+	if !fs.Config.Filter.IncludeDirectory(bucket) {
+		return false, nil
+	}
+	_, err := s.f.List(ctx, bucket)
+	if err == fs.ErrorDirNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	s.setBucket(bucket, true)
+	return true, nil
+}
+
+// setBucket records whether bucket exists
+func (s *server) setBucket(bucket string, exists bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if exists {
+		s.buckets[bucket] = struct{}{}
+	} else {
+		delete(s.buckets, bucket)
+	}
+}
+
+// checkBucket returns errNoSuchBucket if the bucket doesn't exist
+func (s *server) checkBucket(ctx context.Context, bucket string) error {
+	exists, err := s.bucketExists(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errNoSuchBucket
+	}
+	return nil
+}
+
+// objectETag returns the quoted ETag for o.
+//
+// This is the MD5 hash of the object if available, otherwise a
+// value which looks like the ETag of a multipart upload so clients
+// don't try to check it against the data.
+func (s *server) objectETag(o fs.Object) string {
+	if md5sum := s.objectMD5(o); md5sum != "" {
+		return `"` + md5sum + `"`
+	}
+	h := md5.New()
+	_, _ = fmt.Fprintf(h, "%s\x00%d\x00%d", o.Remote(), o.Size(), o.ModTime().UnixNano())
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `-1"`
+}
+
+// objectMD5 returns the MD5 hash of o or "" if it isn't available
+// or not wanted
+func (s *server) objectMD5(o fs.Object) string {
+	if !s.etagMD5 {
+		return ""
+	}
+	md5sum, err := o.Hash(fs.HashMD5)
+	if err != nil {
+		fs.Debugf(o, "Failed to read MD5: %v", err)
+		return ""
+	}
+	return md5sum
+}
+
+// formatTime formats t in the ISO 8601 style used in S3 XML documents
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// newID returns a new random ID
+func newID() string {
+	var id [16]byte
+	_, err := rand.Read(id[:])
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// writeXML writes v as the XML response to the client
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, err := w.Write([]byte(xml.Header))
+	if err == nil {
+		err = xml.NewEncoder(w).Encode(v)
+	}
+	if err != nil {
+		fs.Errorf(nil, "Failed to write XML response: %v", err)
+	}
+}
+
+// readXML reads the XML body of r into v
+func readXML(r *http.Request, v interface{}) error {
+	const maxXMLSize = 4 * 1024 * 1024
+	err := xml.NewDecoder(&limitedBody{r: r, n: maxXMLSize}).Decode(v)
+	if err != nil {
+		return errMalformedXML
+	}
+	return nil
+}
+
+// limitedBody reads at most n bytes of the body of r
+type limitedBody struct {
+	r *http.Request
+	n int64
+}
+
+// Read at most n bytes from the body
+func (l *limitedBody) Read(p []byte) (n int, err error) {
+	if l.n <= 0 {
+		return 0, errMalformedXML
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err = l.r.Body.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// writeError writes err to the client in the S3 error format.
+// Errors which aren't apiErrors are logged and returned as internal
+// errors.
+func (s *server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr, ok := errors.Cause(err).(*apiError)
+	if !ok {
+		fs.Stats.Error()
+		fs.Errorf(r.URL.Path, "%s failed: %v", r.Method, err)
+		apiErr = errInternalError
+	} else {
+		fs.Debugf(r.URL.Path, "%s failed: %v", r.Method, err)
+	}
+	if r.Method == "HEAD" {
+		w.WriteHeader(apiErr.StatusCode)
+		return
+	}
+	writeXML(w, apiErr.StatusCode, &errorResponse{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Resource:  r.URL.Path,
+		RequestID: w.Header().Get("X-Amz-Request-Id"),
+	})
+}
+
+// serviceHandler handles requests to the root which lists the buckets
+func (s *server) serviceHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return errMethodNotAllowed
+	}
+	return s.listBuckets(w, r)
+}
+
+// bucketHandler handles requests for a bucket
+func (s *server) bucketHandler(w http.ResponseWriter, r *http.Request, bucket string) error {
+	err := checkBucketName(bucket)
+	if err != nil {
+		return err
+	}
+	err = checkSubresources(r)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	_, location := query["location"]
+	_, uploads := query["uploads"]
+	_, del := query["delete"]
+	switch {
+	case r.Method == "GET" && location:
+		return s.getBucketLocation(w, r, bucket)
+	case r.Method == "GET" && uploads:
+		return s.listMultipartUploads(w, r, bucket)
+	case r.Method == "GET":
+		return s.listObjects(w, r, bucket)
+	case r.Method == "HEAD":
+		return s.checkBucket(r.Context(), bucket)
+	case r.Method == "PUT":
+		return s.createBucket(w, r, bucket)
+	case r.Method == "DELETE":
+		return s.deleteBucket(w, r, bucket)
+	case r.Method == "POST" && del:
+		return s.deleteObjects(w, r, bucket)
+	}
+	return errMethodNotAllowed
+}
+
+// objectHandler handles requests for an object
+func (s *server) objectHandler(w http.ResponseWriter, r *http.Request, signer *requestSigner, bucket, key string) error {
+	err := checkBucketName(bucket)
+	if err != nil {
+		return err
+	}
+	err = checkKey(key)
+	if err != nil {
+		return err
+	}
+	err = checkSubresources(r)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	_, uploads := query["uploads"]
+	copySource := r.Header.Get("X-Amz-Copy-Source")
+	switch {
+	case r.Method == "GET" && uploadID != "":
+		return s.listParts(w, r, bucket, key, uploadID)
+	case r.Method == "GET" || r.Method == "HEAD":
+		return s.getObject(w, r, bucket, key)
+	case r.Method == "PUT" && uploadID != "" && copySource != "":
+		return s.uploadPartCopy(w, r, bucket, key, uploadID, copySource)
+	case r.Method == "PUT" && uploadID != "":
+		return s.uploadPart(w, r, signer, bucket, key, uploadID)
+	case r.Method == "PUT" && copySource != "":
+		return s.copyObject(w, r, bucket, key, copySource)
+	case r.Method == "PUT":
+		return s.putObject(w, r, signer, bucket, key)
+	case r.Method == "POST" && uploads:
+		return s.createMultipartUpload(w, r, bucket, key)
+	case r.Method == "POST" && uploadID != "":
+		return s.completeMultipartUpload(w, r, bucket, key, uploadID)
+	case r.Method == "DELETE" && uploadID != "":
+		return s.abortMultipartUpload(w, r, bucket, key, uploadID)
+	case r.Method == "DELETE":
+		return s.deleteObject(w, r, bucket, key)
+	}
+	return errMethodNotAllowed
+}
@@ -0,0 +1,636 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	_ "github.com/ncw/rclone/s3"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+const (
+	testAccessKey = "ACCESS"
+	testSecretKey = "SECRET"
+)
+
+// s3Run holds a running S3 server for a test
+type s3Run struct {
+	t   *testing.T
+	dir string
+	s   *server
+	c   *s3.S3
+}
+
+func newS3Run(t *testing.T) *s3Run {
+	fs.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-serve-s3-test")
+	require.NoError(t, err)
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	opt := httplib.DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	s, err := newServer(f, &opt, []string{testAccessKey + "," + testSecretKey})
+	require.NoError(t, err)
+	require.NoError(t, s.serve())
+	return &s3Run{
+		t:   t,
+		dir: dir,
+		s:   s,
+		c:   newClient(s, testSecretKey),
+	}
+}
+
+// newClient makes an S3 client for s using secret as the secret key
+func newClient(s *server, secret string) *s3.S3 {
+	config := aws.NewConfig().
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials(testAccessKey, secret, "")).
+		WithEndpoint(s.srv.URL()).
+		WithS3ForcePathStyle(true).
+		WithMaxRetries(0)
+	return s3.New(session.New(), config)
+}
+
+func (r *s3Run) finalise() {
+	r.s.srv.Close()
+	r.s.cleanup()
+	_ = os.RemoveAll(r.dir)
+}
+
+// writeLocal writes a file in the served directory
+func (r *s3Run) writeLocal(name, contents string) {
+	p := filepath.Join(r.dir, filepath.FromSlash(name))
+	require.NoError(r.t, os.MkdirAll(filepath.Dir(p), 0777))
+	require.NoError(r.t, ioutil.WriteFile(p, []byte(contents), 0666))
+}
+
+// readLocal reads a file in the served directory
+func (r *s3Run) readLocal(name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, filepath.FromSlash(name)))
+	require.NoError(r.t, err)
+	return string(data)
+}
+
+// existsLocal returns whether the path exists in the served directory
+func (r *s3Run) existsLocal(name string) bool {
+	_, err := os.Stat(filepath.Join(r.dir, filepath.FromSlash(name)))
+	return err == nil
+}
+
+// errorCode returns the S3 error code of err
+func errorCode(t *testing.T, err error) string {
+	require.Error(t, err)
+	awsErr, ok := err.(awserr.Error)
+	require.True(t, ok, "not an awserr: %v", err)
+	return awsErr.Code()
+}
+
+// quotedMD5 returns the MD5 of s as an ETag
+func quotedMD5(s string) string {
+	sum := md5.Sum([]byte(s))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func TestS3Buckets(t *testing.T) {
+	r := newS3Run(t)
+	defer r.finalise()
+
+	_, err := r.c.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("bucket1")})
+	require.NoError(t, err)
+	assert.True(t, r.existsLocal("bucket1"))
+	_, err = r.c.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("bucket1")})
+	assert.Equal(t, "BucketAlreadyOwnedByYou", errorCode(t, err))
+	r.writeLocal("bucket2/file.txt", "hello")
+	r.writeLocal("notabucket.txt", "hello")
+
+	out, err := r.c.ListBuckets(&s3.ListBucketsInput{})
+	require.NoError(t, err)
+	var names []string
+	for _, bucket := range out.Buckets {
+		names = append(names, aws.StringValue(bucket.Name))
+	}
+	assert.Equal(t, []string{"bucket1", "bucket2"}, names)
+
+	_, err = r.c.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String("bucket2")})
+	require.NoError(t, err)
+	_, err = r.c.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String("missing")})
+	assert.Equal(t, "NotFound", errorCode(t, err))
+
+	location, err := r.c.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: aws.String("bucket1")})
+	require.NoError(t, err)
+	assert.Equal(t, "", aws.StringValue(location.LocationConstraint))
+
+	_, err = r.c.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("bucket2")})
+	assert.Equal(t, "BucketNotEmpty", errorCode(t, err))
+	_, err = r.c.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("bucket1")})
+	require.NoError(t, err)
+	assert.False(t, r.existsLocal("bucket1"))
+	_, err = r.c.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("bucket1")})
+	assert.Equal(t, "NoSuchBucket", errorCode(t, err))
+
+	_, err = r.c.GetBucketAcl(&s3.GetBucketAclInput{Bucket: aws.String("bucket2")})
+	assert.Equal(t, "NotImplemented", errorCode(t, err))
+}
+
+func TestS3Objects(t *testing.T) {
+	r := newS3Run(t)
+	defer r.finalise()
+	r.writeLocal("bucket/existing.txt", "existing")
+
+	// Put with metadata and an MD5
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	req, put := r.c.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("dir/file.txt"),
+		Body:     strings.NewReader("hello world"),
+		Metadata: map[string]*string{"Mtime": aws.String("981173106.0")},
+	})
+	req.HTTPRequest.Header.Set("Content-Md5", "XrY7u+Ae7tCTyyK7j1rNww==")
+	require.NoError(t, req.Send())
+	assert.Equal(t, quotedMD5("hello world"), aws.StringValue(put.ETag))
+	assert.Equal(t, "hello world", r.readLocal("bucket/dir/file.txt"))
+
+	// A bad MD5 is rejected and doesn't leave a file
+	req, _ = r.c.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("bad.txt"),
+		Body:   strings.NewReader("hello world"),
+	})
+	req.HTTPRequest.Header.Set("Content-Md5", "AAAAAAAAAAAAAAAAAAAAAA==")
+	err := req.Send()
+	assert.Equal(t, "BadDigest", errorCode(t, err))
+	assert.False(t, r.existsLocal("bucket/bad.txt"))
+
+	// Or replace an existing file
+	req, _ = r.c.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("existing.txt"),
+		Body:   strings.NewReader("hello world"),
+	})
+	req.HTTPRequest.Header.Set("Content-Md5", "AAAAAAAAAAAAAAAAAAAAAA==")
+	err = req.Send()
+	assert.Equal(t, "BadDigest", errorCode(t, err))
+	assert.Equal(t, "existing", r.readLocal("bucket/existing.txt"))
+
+	// Put to a missing bucket
+	_, err = r.c.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("missing"),
+		Key:    aws.String("file.txt"),
+		Body:   strings.NewReader("hello"),
+	})
+	assert.Equal(t, "NoSuchBucket", errorCode(t, err))
+
+	// Get and Head
+	get, err := r.c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/file.txt"),
+	})
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(get.Body)
+	require.NoError(t, err)
+	require.NoError(t, get.Body.Close())
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, int64(11), aws.Int64Value(get.ContentLength))
+	assert.Equal(t, quotedMD5("hello world"), aws.StringValue(get.ETag))
+	assert.Equal(t, "981173106", aws.StringValue(get.Metadata["Mtime"]))
+	assert.Equal(t, modTime, aws.TimeValue(get.LastModified).UTC())
+
+	head, err := r.c.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/file.txt"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), aws.Int64Value(head.ContentLength))
+	_, err = r.c.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/missing.txt"),
+	})
+	assert.Equal(t, "NotFound", errorCode(t, err))
+	_, err = r.c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir"),
+	})
+	assert.Equal(t, "NoSuchKey", errorCode(t, err))
+
+	// Range
+	get, err = r.c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/file.txt"),
+		Range:  aws.String("bytes=6-"),
+	})
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(get.Body)
+	require.NoError(t, err)
+	require.NoError(t, get.Body.Close())
+	assert.Equal(t, "world", string(data))
+	assert.Equal(t, "bytes 6-10/11", aws.StringValue(get.ContentRange))
+	_, err = r.c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/file.txt"),
+		Range:  aws.String("bytes=20-"),
+	})
+	assert.Equal(t, "InvalidRange", errorCode(t, err))
+
+	// Copy
+	copied, err := r.c.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("copy.txt"),
+		CopySource: aws.String("bucket/dir/file.txt"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, quotedMD5("hello world"), aws.StringValue(copied.CopyObjectResult.ETag))
+	assert.Equal(t, "hello world", r.readLocal("bucket/copy.txt"))
+	_, err = r.c.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("copy.txt"),
+		CopySource: aws.String("bucket/copy.txt"),
+	})
+	assert.Equal(t, "InvalidRequest", errorCode(t, err))
+
+	// Copy to itself to change the modification time
+	_, err = r.c.CopyObject(&s3.CopyObjectInput{
+		Bucket:            aws.String("bucket"),
+		Key:               aws.String("copy.txt"),
+		CopySource:        aws.String("bucket/copy.txt"),
+		MetadataDirective: aws.String("REPLACE"),
+		Metadata:          map[string]*string{"Mtime": aws.String("1000000000.5")},
+	})
+	require.NoError(t, err)
+	fi, err := os.Stat(filepath.Join(r.dir, "bucket", "copy.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1000000000, 5e8).UTC(), fi.ModTime().UTC())
+
+	// Delete
+	_, err = r.c.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("copy.txt"),
+	})
+	require.NoError(t, err)
+	assert.False(t, r.existsLocal("bucket/copy.txt"))
+	_, err = r.c.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("copy.txt"),
+	})
+	require.NoError(t, err)
+
+	deleted, err := r.c.DeleteObjects(&s3.DeleteObjectsInput{
+		Bucket: aws.String("bucket"),
+		Delete: &s3.Delete{
+			Objects: []*s3.ObjectIdentifier{
+				{Key: aws.String("existing.txt")},
+				{Key: aws.String("dir/file.txt")},
+				{Key: aws.String("../escape")},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Len(t, deleted.Deleted, 2)
+	require.Len(t, deleted.Errors, 1)
+	assert.Equal(t, "../escape", aws.StringValue(deleted.Errors[0].Key))
+	assert.False(t, r.existsLocal("bucket/existing.txt"))
+	assert.False(t, r.existsLocal("bucket/dir/file.txt"))
+
+	// Directory markers
+	_, err = r.c.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("newdir/"),
+		Body:   bytes.NewReader(nil),
+	})
+	require.NoError(t, err)
+	assert.True(t, r.existsLocal("bucket/newdir"))
+}
+
+func TestS3List(t *testing.T) {
+	r := newS3Run(t)
+	defer r.finalise()
+	for _, name := range []string{"a.txt", "b/c.txt", "b/d/e.txt", "b/f.txt", "g.txt"} {
+		r.writeLocal("bucket/"+name, name)
+	}
+
+	keys := func(contents []*s3.Object) (out []string) {
+		for _, o := range contents {
+			out = append(out, aws.StringValue(o.Key))
+		}
+		return out
+	}
+	prefixes := func(commonPrefixes []*s3.CommonPrefix) (out []string) {
+		for _, p := range commonPrefixes {
+			out = append(out, aws.StringValue(p.Prefix))
+		}
+		return out
+	}
+
+	// Recursive listing
+	out, err := r.c.ListObjects(&s3.ListObjectsInput{Bucket: aws.String("bucket")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b/c.txt", "b/d/e.txt", "b/f.txt", "g.txt"}, keys(out.Contents))
+	assert.Equal(t, int64(5), aws.Int64Value(out.Contents[0].Size))
+	assert.Equal(t, quotedMD5("a.txt"), aws.StringValue(out.Contents[0].ETag))
+	assert.False(t, aws.BoolValue(out.IsTruncated))
+
+	// Delimited listing
+	out, err = r.c.ListObjects(&s3.ListObjectsInput{
+		Bucket:    aws.String("bucket"),
+		Delimiter: aws.String("/"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "g.txt"}, keys(out.Contents))
+	assert.Equal(t, []string{"b/"}, prefixes(out.CommonPrefixes))
+
+	out, err = r.c.ListObjects(&s3.ListObjectsInput{
+		Bucket:    aws.String("bucket"),
+		Prefix:    aws.String("b/"),
+		Delimiter: aws.String("/"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b/c.txt", "b/f.txt"}, keys(out.Contents))
+	assert.Equal(t, []string{"b/d/"}, prefixes(out.CommonPrefixes))
+
+	// Prefix which isn't a directory
+	out, err = r.c.ListObjects(&s3.ListObjectsInput{
+		Bucket: aws.String("bucket"),
+		Prefix: aws.String("b/d"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b/d/e.txt"}, keys(out.Contents))
+
+	// Missing prefix
+	out, err = r.c.ListObjects(&s3.ListObjectsInput{
+		Bucket: aws.String("bucket"),
+		Prefix: aws.String("z/"),
+	})
+	require.NoError(t, err)
+	assert.Len(t, out.Contents, 0)
+
+	// Paging with markers
+	var got []string
+	marker := ""
+	for {
+		out, err = r.c.ListObjects(&s3.ListObjectsInput{
+			Bucket:  aws.String("bucket"),
+			MaxKeys: aws.Int64(2),
+			Marker:  aws.String(marker),
+		})
+		require.NoError(t, err)
+		got = append(got, keys(out.Contents)...)
+		if !aws.BoolValue(out.IsTruncated) {
+			break
+		}
+		marker = aws.StringValue(out.NextMarker)
+	}
+	assert.Equal(t, []string{"a.txt", "b/c.txt", "b/d/e.txt", "b/f.txt", "g.txt"}, got)
+
+	// Paging with V2
+	got = nil
+	var token *string
+	for {
+		out, err := r.c.ListObjectsV2(&s3.ListObjectsV2Input{
+			Bucket:            aws.String("bucket"),
+			MaxKeys:           aws.Int64(2),
+			ContinuationToken: token,
+			StartAfter:        aws.String("a.txt"),
+		})
+		require.NoError(t, err)
+		got = append(got, keys(out.Contents)...)
+		if !aws.BoolValue(out.IsTruncated) {
+			break
+		}
+		token = out.NextContinuationToken
+	}
+	assert.Equal(t, []string{"b/c.txt", "b/d/e.txt", "b/f.txt", "g.txt"}, got)
+
+	// Missing bucket
+	_, err = r.c.ListObjects(&s3.ListObjectsInput{Bucket: aws.String("missing")})
+	assert.Equal(t, "NoSuchBucket", errorCode(t, err))
+}
+
+func TestS3Multipart(t *testing.T) {
+	r := newS3Run(t)
+	defer r.finalise()
+	r.writeLocal("bucket/source.txt", "0123456789")
+
+	create, err := r.c.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/multi.txt"),
+	})
+	require.NoError(t, err)
+	uploadID := create.UploadId
+
+	part1, err := r.c.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("dir/multi.txt"),
+		UploadId:   uploadID,
+		PartNumber: aws.Int64(1),
+		Body:       strings.NewReader("hello "),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, quotedMD5("hello "), aws.StringValue(part1.ETag))
+	part2, err := r.c.UploadPartCopy(&s3.UploadPartCopyInput{
+		Bucket:          aws.String("bucket"),
+		Key:             aws.String("dir/multi.txt"),
+		UploadId:        uploadID,
+		PartNumber:      aws.Int64(2),
+		CopySource:      aws.String("bucket/source.txt"),
+		CopySourceRange: aws.String("bytes=2-5"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, quotedMD5("2345"), aws.StringValue(part2.CopyPartResult.ETag))
+
+	uploads, err := r.c.ListMultipartUploads(&s3.ListMultipartUploadsInput{Bucket: aws.String("bucket")})
+	require.NoError(t, err)
+	require.Len(t, uploads.Uploads, 1)
+	assert.Equal(t, "dir/multi.txt", aws.StringValue(uploads.Uploads[0].Key))
+
+	parts, err := r.c.ListParts(&s3.ListPartsInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("dir/multi.txt"),
+		UploadId: uploadID,
+	})
+	require.NoError(t, err)
+	require.Len(t, parts.Parts, 2)
+	assert.Equal(t, int64(6), aws.Int64Value(parts.Parts[0].Size))
+	assert.Equal(t, int64(4), aws.Int64Value(parts.Parts[1].Size))
+
+	// Parts out of order and with wrong ETags are rejected
+	_, err = r.c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("dir/multi.txt"),
+		UploadId: uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: []*s3.CompletedPart{
+			{PartNumber: aws.Int64(2), ETag: part2.CopyPartResult.ETag},
+			{PartNumber: aws.Int64(1), ETag: part1.ETag},
+		}},
+	})
+	assert.Equal(t, "InvalidPartOrder", errorCode(t, err))
+	_, err = r.c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("dir/multi.txt"),
+		UploadId: uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: []*s3.CompletedPart{
+			{PartNumber: aws.Int64(1), ETag: part2.CopyPartResult.ETag},
+		}},
+	})
+	assert.Equal(t, "InvalidPart", errorCode(t, err))
+
+	complete, err := r.c.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("dir/multi.txt"),
+		UploadId: uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: []*s3.CompletedPart{
+			{PartNumber: aws.Int64(1), ETag: part1.ETag},
+			{PartNumber: aws.Int64(2), ETag: part2.CopyPartResult.ETag},
+		}},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(aws.StringValue(complete.ETag), `-2"`))
+	assert.Equal(t, "hello 2345", r.readLocal("bucket/dir/multi.txt"))
+	assert.Len(t, r.s.uploads, 0)
+
+	// Abort
+	create, err = r.c.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("aborted.txt"),
+	})
+	require.NoError(t, err)
+	_, err = r.c.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("aborted.txt"),
+		UploadId: create.UploadId,
+	})
+	require.NoError(t, err)
+	_, err = r.c.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("aborted.txt"),
+		UploadId: create.UploadId,
+	})
+	assert.Equal(t, "NoSuchUpload", errorCode(t, err))
+	assert.False(t, r.existsLocal("bucket/aborted.txt"))
+}
+
+func TestS3Auth(t *testing.T) {
+	r := newS3Run(t)
+	defer r.finalise()
+
+	_, err := newClient(r.s, "WRONG").ListBuckets(&s3.ListBucketsInput{})
+	assert.Equal(t, "SignatureDoesNotMatch", errorCode(t, err))
+
+	c := s3.New(session.New(), aws.NewConfig().
+		WithRegion("us-east-1").
+		WithCredentials(credentials.AnonymousCredentials).
+		WithEndpoint(r.s.srv.URL()).
+		WithS3ForcePathStyle(true).
+		WithMaxRetries(0))
+	_, err = c.ListBuckets(&s3.ListBucketsInput{})
+	assert.Equal(t, "AccessDenied", errorCode(t, err))
+
+	_, err = newServer(r.s.f, &httplib.DefaultOpt, []string{"nosecret"})
+	assert.Error(t, err)
+}
+
+// newBackend makes an Fs using rclone's s3 backend pointing at
+// bucket/dir on the server
+func (r *s3Run) newBackend(ctx context.Context) fs.Fs {
+	fs.ConfigFileSet("TestS3Serve", "type", "s3")
+	fs.ConfigFileSet("TestS3Serve", "access_key_id", testAccessKey)
+	fs.ConfigFileSet("TestS3Serve", "secret_access_key", testSecretKey)
+	fs.ConfigFileSet("TestS3Serve", "region", "other-v4-signature")
+	fs.ConfigFileSet("TestS3Serve", "endpoint", r.s.srv.URL())
+	f, err := fs.NewFs("TestS3Serve:bucket/dir")
+	require.NoError(r.t, err)
+	require.NoError(r.t, f.Mkdir(ctx, ""))
+	return f
+}
+
+// TestS3Backend checks rclone's s3 backend works with the server
+func TestS3Backend(t *testing.T) {
+	r := newS3Run(t)
+	defer r.finalise()
+	ctx := context.Background()
+	f := r.newBackend(ctx)
+
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	contents := strings.Repeat("rclone", 1000)
+	src := fs.NewStaticObjectInfo("file.txt", modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, contents, r.readLocal("bucket/dir/file.txt"))
+
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, modTime.Unix(), o.ModTime().Unix())
+	newModTime := modTime.Add(time.Hour)
+	require.NoError(t, o.SetModTime(ctx, newModTime))
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, newModTime.Unix(), o.ModTime().Unix())
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "file.txt", entries[0].Remote())
+
+	require.NoError(t, o.Remove(ctx))
+	assert.False(t, r.existsLocal("bucket/dir/file.txt"))
+}
+
+func TestS3BackendMultipartCopy(t *testing.T) {
+	r := newS3Run(t)
+	defer r.finalise()
+
+	// Copy in 5MB parts
+	oldCopyCutoff := pflag.Lookup("s3-copy-cutoff").Value.String()
+	require.NoError(t, pflag.Set("s3-copy-cutoff", "5M"))
+	defer func() {
+		require.NoError(t, pflag.Set("s3-copy-cutoff", oldCopyCutoff))
+	}()
+
+	ctx := context.Background()
+	f := r.newBackend(ctx)
+
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	contents := strings.Repeat("0123456789abcdef", 12*1024*1024/16)
+	src := fs.NewStaticObjectInfo("big.txt", modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+
+	// Server side copy of a 3 part object
+	dst, err := f.Features().Copy(ctx, o, "copy/big.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), dst.Size())
+	assert.Equal(t, modTime.Unix(), dst.ModTime().Unix())
+	assert.True(t, contents == r.readLocal("bucket/dir/copy/big.txt"), "contents differ")
+
+	// Setting the mod time uses a multipart copy too
+	newModTime := modTime.Add(time.Hour)
+	require.NoError(t, dst.SetModTime(ctx, newModTime))
+	dst, err = f.NewObject(ctx, "copy/big.txt")
+	require.NoError(t, err)
+	assert.Equal(t, newModTime.Unix(), dst.ModTime().Unix())
+	assert.Equal(t, int64(len(contents)), dst.Size())
+	assert.True(t, contents == r.readLocal("bucket/dir/copy/big.txt"), "contents differ")
+
+	// No multipart uploads should be left behind
+	resp, err := r.c.ListMultipartUploads(&s3.ListMultipartUploadsInput{
+		Bucket: aws.String("bucket"),
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.Uploads, 0)
+}
@@ -0,0 +1,195 @@
+// XML documents sent to and received from S3 clients
+
+package s3
+
+import "encoding/xml"
+
+// s3Namespace is the XML namespace of S3 documents
+const s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// errorResponse is the body of an error response
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// owner is the owner of buckets and objects
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+// defaultOwner owns everything served
+var defaultOwner = owner{ID: "rclone", DisplayName: "rclone"}
+
+// bucketInfo is a bucket in a listBucketsResult
+type bucketInfo struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+// listBucketsResult is the response to ListBuckets
+type listBucketsResult struct {
+	XMLName xml.Name     `xml:"ListAllMyBucketsResult"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Owner   owner        `xml:"Owner"`
+	Buckets []bucketInfo `xml:"Buckets>Bucket"`
+}
+
+// locationResult is the response to GetBucketLocation
+type locationResult struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:",chardata"`
+}
+
+// objectInfo is an object in a listBucketResult
+type objectInfo struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+	Owner        *owner `xml:"Owner,omitempty"`
+}
+
+// commonPrefix is a directory in a listBucketResult
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// listBucketResult is the response to ListObjects and ListObjectsV2
+//
+// Marker and NextMarker are only used by ListObjects and the other
+// optional fields only by ListObjectsV2.
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Marker                *string        `xml:"Marker,omitempty"`
+	NextMarker            string         `xml:"NextMarker,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	KeyCount              *int           `xml:"KeyCount,omitempty"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []objectInfo   `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+// deleteRequest is the body of a DeleteObjects request
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+// deletedObject is an object successfully deleted in a deleteResult
+type deletedObject struct {
+	Key string `xml:"Key"`
+}
+
+// deleteError is an object which couldn't be deleted in a deleteResult
+type deleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// deleteResult is the response to DeleteObjects
+type deleteResult struct {
+	XMLName xml.Name        `xml:"DeleteResult"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Deleted []deletedObject `xml:"Deleted"`
+	Errors  []deleteError   `xml:"Error"`
+}
+
+// copyObjectResult is the response to CopyObject
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+// copyPartResult is the response to UploadPartCopy
+type copyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+// initiateMultipartUploadResult is the response to CreateMultipartUpload
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+// completeMultipartUploadRequest is the body of a CompleteMultipartUpload request
+type completeMultipartUploadRequest struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+// completeMultipartUploadResult is the response to CompleteMultipartUpload
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+// partInfo is a part in a listPartsResult
+type partInfo struct {
+	PartNumber   int    `xml:"PartNumber"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+}
+
+// listPartsResult is the response to ListParts
+type listPartsResult struct {
+	XMLName      xml.Name   `xml:"ListPartsResult"`
+	Xmlns        string     `xml:"xmlns,attr"`
+	Bucket       string     `xml:"Bucket"`
+	Key          string     `xml:"Key"`
+	UploadID     string     `xml:"UploadId"`
+	StorageClass string     `xml:"StorageClass"`
+	IsTruncated  bool       `xml:"IsTruncated"`
+	Parts        []partInfo `xml:"Part"`
+}
+
+// uploadInfo is an upload in a listMultipartUploadsResult
+type uploadInfo struct {
+	Key          string `xml:"Key"`
+	UploadID     string `xml:"UploadId"`
+	Initiated    string `xml:"Initiated"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// listMultipartUploadsResult is the response to ListMultipartUploads
+type listMultipartUploadsResult struct {
+	XMLName     xml.Name     `xml:"ListMultipartUploadsResult"`
+	Xmlns       string       `xml:"xmlns,attr"`
+	Bucket      string       `xml:"Bucket"`
+	IsTruncated bool         `xml:"IsTruncated"`
+	Uploads     []uploadInfo `xml:"Upload"`
+}
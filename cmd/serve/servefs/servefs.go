@@ -0,0 +1,210 @@
+// Package servefs contains the file and directory handling shared by
+// the serve commands which present a remote as a filing system.
+package servefs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// ToRemote converts a path from a client into an rclone remote path
+func ToRemote(p string) string {
+	return strings.Trim(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
+
+// ParentDir returns the parent directory of remote
+func ParentDir(remote string) string {
+	parent := path.Dir(remote)
+	if parent == "." || parent == "/" {
+		parent = ""
+	}
+	return parent
+}
+
+// ListDirFn lists the directory dir returning the entries which pass
+// the filters
+type ListDirFn func(ctx context.Context, dir string) (fs.DirEntries, error)
+
+// ListDir lists the directory dir of f with fs.ListDirSorted
+func ListDir(f fs.Fs) ListDirFn {
+	return func(ctx context.Context, dir string) (fs.DirEntries, error) {
+		return fs.ListDirSorted(ctx, f, false, dir)
+	}
+}
+
+// Lookup finds the entry for remote which will be an fs.Object or
+// an *fs.Dir.  Directories are found in the listing of the parent
+// made with listDir.  It returns os.ErrNotExist if it wasn't found.
+func Lookup(ctx context.Context, f fs.Fs, listDir ListDirFn, remote string) (fs.BasicInfo, error) {
+	if remote == "" {
+		return &fs.Dir{Name: "", When: time.Now()}, nil
+	}
+	// Try for a file first as this is quick on most remotes
+	o, err := f.NewObject(ctx, remote)
+	if err == nil {
+		if !fs.Config.Filter.IncludeObject(o) {
+			return nil, os.ErrNotExist
+		}
+		return o, nil
+	}
+	if err != fs.ErrorObjectNotFound && errors.Cause(err) != fs.ErrorNotAFile {
+		return nil, err
+	}
+	// Then look for a directory in the parent's listing
+	entries, err := listDir(ctx, ParentDir(remote))
+	if err == fs.ErrorDirNotFound {
+		return nil, os.ErrNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if dir, ok := entry.(*fs.Dir); ok && dir.Remote() == remote {
+			return dir, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// MoveDir moves the directory oldDir to newDir
+//
+// This uses the DirMove feature if available, otherwise it moves the
+// directory file by file.
+func MoveDir(ctx context.Context, f fs.Fs, oldDir, newDir string) error {
+	if oldDir == "" {
+		return errors.New("can't move root directory")
+	}
+	if doDirMove := f.Features().DirMove; doDirMove != nil {
+		err := doDirMove(ctx, f, oldDir, newDir)
+		if err != fs.ErrorCantDirMove {
+			return err
+		}
+	}
+	return moveDirFiles(ctx, f, oldDir, newDir)
+}
+
+// moveDirFiles moves the directory oldDir to newDir file by file for
+// remotes which can't move directories directly
+func moveDirFiles(ctx context.Context, f fs.Fs, oldDir, newDir string) error {
+	newRemote := func(remote string) string {
+		return path.Join(newDir, strings.TrimPrefix(remote, oldDir+"/"))
+	}
+	err := f.Mkdir(ctx, newDir)
+	if err != nil {
+		return err
+	}
+	err = fs.Walk(ctx, f, oldDir, true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			switch x := entry.(type) {
+			case fs.Object:
+				err = fs.Move(ctx, f, nil, newRemote(x.Remote()), x)
+			case *fs.Dir:
+				err = f.Mkdir(ctx, newRemote(x.Remote()))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return RemoveDir(ctx, f, oldDir)
+}
+
+// RemoveDir deletes all the files in dir then removes all the
+// directories, deepest first
+func RemoveDir(ctx context.Context, f fs.Fs, dir string) error {
+	dirs := []string{dir}
+	err := fs.Walk(ctx, f, dir, true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			switch x := entry.(type) {
+			case fs.Object:
+				err = fs.DeleteFile(ctx, x)
+				if err != nil {
+					return err
+				}
+			case *fs.Dir:
+				dirs = append(dirs, x.Remote())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		err = f.Rmdir(ctx, dirs[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileInfo implements os.FileInfo for an fs.Object or *fs.Dir
+type FileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// NewFileInfo makes an os.FileInfo from the entry
+func NewFileInfo(entry fs.BasicInfo) *FileInfo {
+	_, isDir := entry.(*fs.Dir)
+	fi := &FileInfo{
+		name:    path.Base(entry.Remote()),
+		size:    entry.Size(),
+		modTime: entry.ModTime(),
+		isDir:   isDir,
+	}
+	if fi.name == "." || fi.name == "" {
+		fi.name = "/"
+	}
+	if isDir {
+		fi.size = 0
+	}
+	return fi
+}
+
+// Name returns the base name of the file
+func (fi *FileInfo) Name() string { return fi.name }
+
+// Size returns the length in bytes
+func (fi *FileInfo) Size() int64 { return fi.size }
+
+// Mode returns the file mode bits
+func (fi *FileInfo) Mode() os.FileMode {
+	if fi.isDir {
+		return os.ModeDir | 0777
+	}
+	return 0666
+}
+
+// ModTime returns the modification time
+func (fi *FileInfo) ModTime() time.Time { return fi.modTime }
+
+// IsDir returns whether this is a directory
+func (fi *FileInfo) IsDir() bool { return fi.isDir }
+
+// Sys returns the underlying data source - always nil
+func (fi *FileInfo) Sys() interface{} { return nil }
+
+// check interfaces
+var (
+	_ os.FileInfo = (*FileInfo)(nil)
+)
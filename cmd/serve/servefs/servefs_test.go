@@ -0,0 +1,95 @@
+package servefs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestToRemote(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"/dir/file.txt", "dir/file.txt"},
+		{"dir/", "dir"},
+		{"/dir/../file.txt", "file.txt"},
+		{"/../../file.txt", "file.txt"},
+	} {
+		assert.Equal(t, test.want, ToRemote(test.in), test.in)
+	}
+}
+
+func TestParentDir(t *testing.T) {
+	assert.Equal(t, "", ParentDir(""))
+	assert.Equal(t, "", ParentDir("file.txt"))
+	assert.Equal(t, "dir/sub", ParentDir("dir/sub/file.txt"))
+}
+
+func TestNewFileInfo(t *testing.T) {
+	when := time.Date(2017, 6, 1, 2, 3, 4, 0, time.UTC)
+	fi := NewFileInfo(fs.NewStaticObjectInfo("dir/file.txt", when, 5, true, nil, nil))
+	assert.Equal(t, "file.txt", fi.Name())
+	assert.Equal(t, int64(5), fi.Size())
+	assert.Equal(t, when, fi.ModTime())
+	assert.False(t, fi.IsDir())
+	assert.Equal(t, os.FileMode(0666), fi.Mode())
+
+	fi = NewFileInfo(&fs.Dir{Name: "", When: when, Bytes: 100})
+	assert.Equal(t, "/", fi.Name())
+	assert.Equal(t, int64(0), fi.Size())
+	assert.True(t, fi.IsDir())
+	assert.True(t, fi.Mode().IsDir())
+}
+
+func TestLookupAndMoveDir(t *testing.T) {
+	fs.LoadConfig()
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-servefs-test")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "dir", "sub"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "dir", "sub", "file.txt"), []byte("hello"), 0666))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	listDir := ListDir(f)
+
+	entry, err := Lookup(ctx, f, listDir, "dir/sub/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), entry.Size())
+
+	entry, err = Lookup(ctx, f, listDir, "dir/sub")
+	require.NoError(t, err)
+	_, isDir := entry.(*fs.Dir)
+	assert.True(t, isDir)
+
+	_, err = Lookup(ctx, f, listDir, "dir/potato")
+	assert.Equal(t, os.ErrNotExist, err)
+	_, err = Lookup(ctx, f, listDir, "potato/file.txt")
+	assert.Equal(t, os.ErrNotExist, err)
+
+	// Move file by file as if the remote didn't support DirMove
+	require.NoError(t, moveDirFiles(ctx, f, "dir", "moved"))
+	_, err = os.Stat(filepath.Join(dir, "dir"))
+	assert.True(t, os.IsNotExist(err))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "moved", "sub", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	assert.Error(t, MoveDir(ctx, f, "", "root"))
+	require.NoError(t, MoveDir(ctx, f, "moved", "dir"))
+	_, err = os.Stat(filepath.Join(dir, "dir", "sub", "file.txt"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,386 @@
+// Translate SFTP requests into rclone operations
+
+package sftp
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ncw/rclone/cmd/serve/servefs"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/net/context"
+)
+
+// Attribute flags from the SFTP protocol used in Setstat
+const (
+	attrSize        = 0x00000001
+	attrUIDGID      = 0x00000002
+	attrPermissions = 0x00000004
+	attrACModTime   = 0x00000008
+)
+
+// errNotFound is returned for missing files and directories - the
+// sftp server translates it to SSH_FX_NO_SUCH_FILE
+var errNotFound = syscall.ENOENT
+
+// handler implements the sftp.Handlers for an fs.Fs
+type handler struct {
+	f fs.Fs
+}
+
+// newHandler makes a new handler for f
+func newHandler(f fs.Fs) *handler {
+	return &handler{f: f}
+}
+
+// handlers returns the sftp.Handlers for use with an
+// sftp.RequestServer
+func (h *handler) handlers() sftp.Handlers {
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileInfo: h,
+	}
+}
+
+// lookup finds the entry for remote which will be an fs.Object or
+// an *fs.Dir.  It returns errNotFound if it wasn't found.
+func (h *handler) lookup(ctx context.Context, remote string) (fs.BasicInfo, error) {
+	entry, err := servefs.Lookup(ctx, h.f, servefs.ListDir(h.f), remote)
+	if err == os.ErrNotExist {
+		return nil, errNotFound
+	}
+	return entry, err
+}
+
+// lookupObject finds the object at remote
+func (h *handler) lookupObject(ctx context.Context, remote string) (fs.Object, error) {
+	entry, err := h.lookup(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := entry.(fs.Object)
+	if !ok {
+		return nil, errors.Errorf("%q is a directory", remote)
+	}
+	return o, nil
+}
+
+// lookupDir finds the directory at remote
+func (h *handler) lookupDir(ctx context.Context, remote string) (*fs.Dir, error) {
+	entry, err := h.lookup(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := entry.(*fs.Dir)
+	if !ok {
+		return nil, errors.Errorf("%q is not a directory", remote)
+	}
+	return dir, nil
+}
+
+// Fileread returns a reader for the file in the request
+func (h *handler) Fileread(r sftp.Request) (io.ReaderAt, error) {
+	ctx := context.Background()
+	o, err := h.lookupObject(ctx, servefs.ToRemote(r.Filepath))
+	if err != nil {
+		return nil, err
+	}
+	fs.Debugf(o, "sftp: opened for read")
+	return newObjectReader(ctx, o), nil
+}
+
+// Filewrite returns a writer for the file in the request
+func (h *handler) Filewrite(r sftp.Request) (io.WriterAt, error) {
+	ctx := context.Background()
+	remote := servefs.ToRemote(r.Filepath)
+	o, err := h.lookupObject(ctx, remote)
+	if err == errNotFound {
+		o = nil
+	} else if err != nil {
+		return nil, err
+	}
+	_, err = h.lookupDir(ctx, servefs.ParentDir(remote))
+	if err != nil {
+		return nil, err
+	}
+	fs.Debugf(remote, "sftp: opened for write")
+	return newObjectWriter(ctx, h.f, remote, o)
+}
+
+// Filecmd runs the command in the request
+func (h *handler) Filecmd(r sftp.Request) (err error) {
+	ctx := context.Background()
+	remote := servefs.ToRemote(r.Filepath)
+	defer fs.Trace(remote, "sftp: %s target=%q", r.Method, r.Target)("err=%v", &err)
+	switch r.Method {
+	case "Setstat":
+		return h.setstat(ctx, remote, r.Flags, r.Attrs)
+	case "Rename":
+		return h.rename(ctx, remote, servefs.ToRemote(r.Target))
+	case "Rmdir":
+		_, err = h.lookupDir(ctx, remote)
+		if err != nil {
+			return err
+		}
+		return h.f.Rmdir(ctx, remote)
+	case "Mkdir":
+		_, err = h.lookupDir(ctx, servefs.ParentDir(remote))
+		if err != nil {
+			return err
+		}
+		return h.f.Mkdir(ctx, remote)
+	case "Remove":
+		o, err := h.lookupObject(ctx, remote)
+		if err != nil {
+			return err
+		}
+		return fs.DeleteFile(ctx, o)
+	case "Symlink":
+		return errors.New("symlinks not supported")
+	}
+	return errors.Errorf("unknown command %q", r.Method)
+}
+
+// setstat sets the modification time if it was supplied in the
+// attributes.  Other attributes are ignored.
+func (h *handler) setstat(ctx context.Context, remote string, flags uint32, attrs []byte) error {
+	entry, err := h.lookup(ctx, remote)
+	if err != nil {
+		return err
+	}
+	o, ok := entry.(fs.Object)
+	if !ok || flags&attrACModTime == 0 {
+		return nil
+	}
+	// Skip the attributes before the times
+	offset := 0
+	if flags&attrSize != 0 {
+		offset += 8
+	}
+	if flags&attrUIDGID != 0 {
+		offset += 8
+	}
+	if flags&attrPermissions != 0 {
+		offset += 4
+	}
+	if len(attrs) < offset+8 {
+		return errors.New("setstat attributes too short")
+	}
+	mtime := binary.BigEndian.Uint32(attrs[offset+4:])
+	return o.SetModTime(ctx, time.Unix(int64(mtime), 0))
+}
+
+// rename renames the file or directory at oldRemote to newRemote
+//
+// Directories are moved with the DirMove feature if available,
+// otherwise file by file.
+func (h *handler) rename(ctx context.Context, oldRemote, newRemote string) error {
+	entry, err := h.lookup(ctx, oldRemote)
+	if err != nil {
+		return err
+	}
+	_, err = h.lookup(ctx, newRemote)
+	if err == nil {
+		return os.ErrExist
+	} else if err != errNotFound {
+		return err
+	}
+	_, err = h.lookupDir(ctx, servefs.ParentDir(newRemote))
+	if err != nil {
+		return err
+	}
+	switch x := entry.(type) {
+	case fs.Object:
+		return fs.Move(ctx, h.f, nil, newRemote, x)
+	case *fs.Dir:
+		return servefs.MoveDir(ctx, h.f, oldRemote, newRemote)
+	}
+	return errors.Errorf("unknown entry type %T", entry)
+}
+
+// Fileinfo returns information about the file or directory in the
+// request
+func (h *handler) Fileinfo(r sftp.Request) (fis []os.FileInfo, err error) {
+	ctx := context.Background()
+	remote := servefs.ToRemote(r.Filepath)
+	switch r.Method {
+	case "List":
+		_, err = h.lookupDir(ctx, remote)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := fs.ListDirSorted(ctx, h.f, false, remote)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			fis = append(fis, servefs.NewFileInfo(entry))
+		}
+		return fis, nil
+	case "Stat":
+		entry, err := h.lookup(ctx, remote)
+		if err != nil {
+			return nil, err
+		}
+		return []os.FileInfo{servefs.NewFileInfo(entry)}, nil
+	case "Readlink":
+		return nil, errors.New("symlinks not supported")
+	}
+	return nil, errors.Errorf("unknown command %q", r.Method)
+}
+
+// objectReader reads an object with ReadAt
+//
+// SFTP clients normally read files sequentially so the object is
+// kept open between calls and only reopened if the client seeks.
+type objectReader struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	o      fs.Object
+	in     io.ReadCloser
+	offset int64
+}
+
+// newObjectReader makes a new objectReader for o
+func newObjectReader(ctx context.Context, o fs.Object) *objectReader {
+	return &objectReader{ctx: ctx, o: o}
+}
+
+// ReadAt reads len(p) bytes from the object at off
+func (or *objectReader) ReadAt(p []byte, off int64) (n int, err error) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	if off >= or.o.Size() {
+		return 0, io.EOF
+	}
+	if or.in == nil || off != or.offset {
+		err = or.closeIn()
+		if err != nil {
+			return 0, err
+		}
+		var options []fs.OpenOption
+		if off > 0 {
+			options = append(options, &fs.SeekOption{Offset: off})
+		}
+		or.in, err = or.o.Open(or.ctx, options...)
+		if err != nil {
+			return 0, err
+		}
+		or.offset = off
+	}
+	n, err = io.ReadFull(or.in, p)
+	or.offset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// closeIn closes the object if open
+func (or *objectReader) closeIn() error {
+	if or.in == nil {
+		return nil
+	}
+	err := or.in.Close()
+	or.in = nil
+	return err
+}
+
+// Close the object - called by the sftp server when the client closes
+// the file
+func (or *objectReader) Close() error {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	return or.closeIn()
+}
+
+// objectWriter writes an object with WriteAt
+//
+// As SFTP clients may write blocks out of order the data is buffered
+// in a temporary file and uploaded to the remote on Close.
+type objectWriter struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	f      fs.Fs
+	remote string
+	o      fs.Object // existing object or nil
+	tmp    *os.File
+	size   int64 // size of the file so far
+}
+
+// newObjectWriter makes a new objectWriter for remote.  o is the
+// existing object if any.
+func newObjectWriter(ctx context.Context, f fs.Fs, remote string, o fs.Object) (*objectWriter, error) {
+	tmp, err := ioutil.TempFile("", "rclone-sftp-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make temporary file")
+	}
+	return &objectWriter{
+		ctx:    ctx,
+		f:      f,
+		remote: remote,
+		o:      o,
+		tmp:    tmp,
+	}, nil
+}
+
+// WriteAt writes p to the temporary file at off
+func (ow *objectWriter) WriteAt(p []byte, off int64) (n int, err error) {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	n, err = ow.tmp.WriteAt(p, off)
+	if end := off + int64(n); end > ow.size {
+		ow.size = end
+	}
+	return n, err
+}
+
+// Close uploads the temporary file to the remote
+//
+// The sftp server ignores errors returned from here so they are
+// logged too.
+func (ow *objectWriter) Close() (err error) {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	defer func() {
+		removeErr := os.Remove(ow.tmp.Name())
+		if removeErr != nil {
+			fs.Errorf(ow.remote, "Failed to remove temporary file: %v", removeErr)
+		}
+		if err != nil {
+			fs.Stats.Error()
+			fs.Errorf(ow.remote, "Failed to upload: %v", err)
+		}
+	}()
+	_, err = ow.tmp.Seek(0, os.SEEK_SET)
+	if err != nil {
+		_ = ow.tmp.Close()
+		return err
+	}
+	defer fs.CheckClose(ow.tmp, &err)
+	src := fs.NewStaticObjectInfo(ow.remote, time.Now(), ow.size, true, nil, nil)
+	if ow.o != nil {
+		return ow.o.Update(ow.ctx, ow.tmp, src)
+	}
+	_, err = ow.f.Put(ow.ctx, ow.tmp, src)
+	return err
+}
+
+// check interfaces
+var (
+	_ sftp.FileReader = (*handler)(nil)
+	_ sftp.FileWriter = (*handler)(nil)
+	_ sftp.FileCmder  = (*handler)(nil)
+	_ sftp.FileInfoer = (*handler)(nil)
+	_ io.ReaderAt     = (*objectReader)(nil)
+	_ io.WriterAt     = (*objectWriter)(nil)
+)
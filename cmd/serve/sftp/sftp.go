@@ -0,0 +1,404 @@
+// Package sftp implements an SFTP server to serve an rclone remote
+package sftp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+// Options contains options for the sftp server
+type Options struct {
+	ListenAddr     string   // Port to listen on
+	Keys           []string // Paths to private host keys
+	AuthorizedKeys string   // Path to authorized keys file
+	User           string   // single username
+	Pass           string   // password for user
+	NoAuth         bool     // allow no authentication on connections
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:     "localhost:2022",
+	AuthorizedKeys: "~/.ssh/authorized_keys",
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+func init() {
+	flags := Command.Flags()
+	flags.StringVarP(&Opt.ListenAddr, "addr", "", Opt.ListenAddr, "IPaddress:Port or :Port to bind server to.")
+	flags.StringArrayVarP(&Opt.Keys, "key", "", Opt.Keys, "SSH private host key file (Can be multi-valued, leave blank to auto generate)")
+	flags.StringVarP(&Opt.AuthorizedKeys, "authorized-keys", "", Opt.AuthorizedKeys, "Authorized keys file")
+	flags.StringVarP(&Opt.User, "user", "", Opt.User, "User name for authentication.")
+	flags.StringVarP(&Opt.Pass, "pass", "", Opt.Pass, "Password for authentication.")
+	flags.BoolVarP(&Opt.NoAuth, "no-auth", "", Opt.NoAuth, "Allow connections with no authentication if set.")
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "sftp remote:path",
+	Short: `Serve the remote over SFTP.`,
+	Long: `rclone serve sftp implements an SFTP server to serve the remote
+over SFTP.  This can be used with an SFTP client or you can make a
+remote of type sftp to use with it.
+
+You can use the filter flags (eg --include, --exclude) to control what
+is served.
+
+Uploaded files are buffered in a temporary file and sent to the remote
+when the client closes them, so no staging disk is needed for the
+final destination.  Note that the SFTP protocol gives no way to report
+an error at that point, so failed uploads are only reported in the
+rclone log.
+
+### Server options
+
+Use --addr to specify which IP address and port the server should
+listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
+IPs.  By default it only listens on localhost.  You can use port
+:0 to let the OS choose an available port.
+
+If you set --addr to listen on a public or LAN accessible IP address
+then using Authentication is advised - see the next section for info.
+
+### Authentication
+
+You can set a single username and password with the --user and --pass
+flags.  Both must be set - empty passwords are not accepted.
+
+The server will also accept public keys listed in the file given by
+--authorized-keys (default "~/.ssh/authorized_keys") for any user.
+
+If you don't supply a --user/--pass and there is no authorized keys
+file then you must pass --no-auth to allow unauthenticated access.
+
+Use --key to supply the SSH private host key file (this may be
+repeated).  If you don't supply one then an RSA key will be generated
+and stored in the "serve-sftp" directory next to the config file so
+the host key stays the same between runs.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			s, err := newServer(f, &Opt)
+			if err != nil {
+				return err
+			}
+			err = s.serve()
+			if err != nil {
+				return err
+			}
+			s.Wait()
+			return nil
+		})
+	},
+}
+
+// server contains everything to run the sftp server
+type server struct {
+	f        fs.Fs
+	opt      Options
+	config   *ssh.ServerConfig
+	listener net.Listener
+	waitChan chan struct{} // for waiting on the listener to close
+	closing  int32         // set to 1 when Close has been called
+}
+
+// newServer makes a new sftp server serving f
+func newServer(f fs.Fs, opt *Options) (*server, error) {
+	s := &server{
+		f:   f,
+		opt: *opt,
+	}
+	err := s.configure()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// configure sets up the ssh server config with the authentication
+// methods and host keys
+func (s *server) configure() error {
+	authorizedKeysMap, err := loadAuthorizedKeys(s.opt.AuthorizedKeys)
+	if err != nil {
+		return err
+	}
+	s.config = &ssh.ServerConfig{
+		NoClientAuth: s.opt.NoAuth,
+	}
+	if s.opt.User != "" || s.opt.Pass != "" {
+		// Don't allow logins with an empty user or password
+		if s.opt.User == "" || s.opt.Pass == "" {
+			return errors.New("both --user and --pass must be set for password authentication")
+		}
+		s.config.PasswordCallback = func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			fs.Debugf(nil, "sftp: password login attempt for %q from %s", c.User(), c.RemoteAddr())
+			userOK := subtle.ConstantTimeCompare([]byte(c.User()), []byte(s.opt.User)) == 1
+			passOK := subtle.ConstantTimeCompare(pass, []byte(s.opt.Pass)) == 1
+			if userOK && passOK {
+				return nil, nil
+			}
+			return nil, errors.Errorf("password rejected for %q", c.User())
+		}
+	}
+	if len(authorizedKeysMap) > 0 {
+		s.config.PublicKeyCallback = func(c ssh.ConnMetadata, pubKey ssh.PublicKey) (*ssh.Permissions, error) {
+			fs.Debugf(nil, "sftp: public key login attempt for %q from %s", c.User(), c.RemoteAddr())
+			if _, ok := authorizedKeysMap[string(pubKey.Marshal())]; ok {
+				return &ssh.Permissions{
+					// Record the public key used for authentication.
+					Extensions: map[string]string{
+						"pubkey-fp": ssh.FingerprintSHA256(pubKey),
+					},
+				}, nil
+			}
+			return nil, errors.Errorf("unknown public key for %q", c.User())
+		}
+	}
+	if s.config.PasswordCallback == nil && s.config.PublicKeyCallback == nil && !s.opt.NoAuth {
+		return errors.New("no authorization found, use --user/--pass or --authorized-keys or --no-auth")
+	}
+
+	// Load the host keys, making one if necessary
+	keyPaths := s.opt.Keys
+	if len(keyPaths) == 0 {
+		keyPath := filepath.Join(filepath.Dir(fs.ConfigPath), "serve-sftp", "id_rsa")
+		err = makeRSAKey(keyPath)
+		if err != nil {
+			return err
+		}
+		keyPaths = []string{keyPath}
+	}
+	for _, keyPath := range keyPaths {
+		private, err := loadPrivateKey(keyPath)
+		if err != nil {
+			return err
+		}
+		fs.Debugf(nil, "sftp: loaded host key %q with fingerprint %s", keyPath, ssh.FingerprintSHA256(private.PublicKey()))
+		s.config.AddHostKey(private)
+	}
+	return nil
+}
+
+// loadAuthorizedKeys reads the authorized keys file returning a map
+// of keys in wire format.  A missing file isn't an error.
+func loadAuthorizedKeys(authorizedKeysPath string) (map[string]struct{}, error) {
+	authorizedKeysMap := map[string]struct{}{}
+	if authorizedKeysPath == "" {
+		return authorizedKeysMap, nil
+	}
+	authorizedKeysPath = expandHome(authorizedKeysPath)
+	authorizedKeysBytes, err := ioutil.ReadFile(authorizedKeysPath)
+	if os.IsNotExist(err) {
+		fs.Debugf(nil, "sftp: authorized keys file %q not found", authorizedKeysPath)
+		return authorizedKeysMap, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to load authorized keys")
+	}
+	for _, line := range bytes.Split(authorizedKeysBytes, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		pubKey, _, _, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse authorized keys")
+		}
+		authorizedKeysMap[string(pubKey.Marshal())] = struct{}{}
+	}
+	return authorizedKeysMap, nil
+}
+
+// expandHome expands a leading ~/ into the user's home directory
+func expandHome(path string) string {
+	if len(path) >= 2 && path[:2] == "~/" {
+		return filepath.Join(os.Getenv("HOME"), path[2:])
+	}
+	return path
+}
+
+// loadPrivateKey reads a PEM encoded private key from path
+func loadPrivateKey(keyPath string) (ssh.Signer, error) {
+	privateBytes, err := ioutil.ReadFile(expandHome(keyPath))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load private key")
+	}
+	private, err := ssh.ParsePrivateKey(privateBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse private key")
+	}
+	return private, nil
+}
+
+// makeRSAKey makes a new RSA private key and writes it to keyPath if
+// there isn't one there already
+func makeRSAKey(keyPath string) error {
+	if _, err := os.Stat(keyPath); err == nil {
+		return nil
+	}
+	fs.Logf(nil, "Generating new SSH host key in %q", keyPath)
+	err := os.MkdirAll(filepath.Dir(keyPath), 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory for host key")
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate host key")
+	}
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+	err = ioutil.WriteFile(keyPath, privateKeyPEM, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write host key")
+	}
+	return nil
+}
+
+// serve starts the server listening in the background
+func (s *server) serve() error {
+	listener, err := net.Listen("tcp", s.opt.ListenAddr)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for connection")
+	}
+	s.listener = listener
+	s.waitChan = make(chan struct{})
+	fs.Logf(s.f, "SFTP server listening on %v", listener.Addr())
+	go s.acceptConnections()
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Wait blocks until the listener is closed
+func (s *server) Wait() {
+	<-s.waitChan
+}
+
+// Close shuts the listener down
+func (s *server) Close() {
+	atomic.StoreInt32(&s.closing, 1)
+	err := s.listener.Close()
+	if err != nil {
+		fs.Errorf(nil, "Error on closing SFTP server: %v", err)
+		return
+	}
+	s.Wait()
+}
+
+// acceptConnections accepts connections until the listener is closed
+func (s *server) acceptConnections() {
+	defer close(s.waitChan)
+	for {
+		nConn, err := s.listener.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&s.closing) == 0 {
+				fs.Errorf(nil, "Failed to accept incoming connection: %v", err)
+			}
+			return
+		}
+		go s.acceptConnection(nConn)
+	}
+}
+
+// acceptConnection does the SSH handshake and serves the channels
+// opened on the connection
+func (s *server) acceptConnection(nConn net.Conn) {
+	what := describeConn(nConn)
+
+	// Before use, a handshake must be performed on the incoming net.Conn.
+	sshConn, chans, reqs, err := ssh.NewServerConn(nConn, s.config)
+	if err != nil {
+		fs.Errorf(what, "SSH login failed: %v", err)
+		return
+	}
+	fs.Infof(what, "SSH login from %s using %s", sshConn.User(), sshConn.ClientVersion())
+
+	// Discard all global out-of-band Requests
+	go ssh.DiscardRequests(reqs)
+
+	// Accept all channels
+	go s.acceptChannels(chans, what)
+}
+
+// describeConn describes a net.Conn for logging
+func describeConn(c net.Conn) string {
+	return fmt.Sprintf("serve sftp %s->%s", c.RemoteAddr(), c.LocalAddr())
+}
+
+// acceptChannels serves the sftp subsystem on session channels
+func (s *server) acceptChannels(chans <-chan ssh.NewChannel, what string) {
+	for newChannel := range chans {
+		// Channels have a type, depending on the application level
+		// protocol intended. In the case of an SFTP session, this is "session".
+		if newChannel.ChannelType() != "session" {
+			err := newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			if err != nil {
+				fs.Errorf(what, "Failed to reject unknown channel: %v", err)
+			}
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			fs.Errorf(what, "could not accept channel: %v", err)
+			continue
+		}
+
+		// Sessions have out-of-band requests such as "shell",
+		// "pty-req" and "env".  Here we handle only the
+		// "subsystem" request for "sftp".
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				ok := false
+				if req.Type == "subsystem" && len(req.Payload) >= 4 && string(req.Payload[4:]) == "sftp" {
+					ok = true
+					go s.serveSFTP(channel, what)
+				}
+				fs.Debugf(what, "request %q accepted %v", req.Type, ok)
+				err := req.Reply(ok, nil)
+				if err != nil {
+					fs.Errorf(what, "Failed to reply to request: %v", err)
+				}
+			}
+		}(requests)
+	}
+}
+
+// serveSFTP runs the sftp server on the channel until it is closed
+func (s *server) serveSFTP(channel ssh.Channel, what string) {
+	fs.Debugf(what, "Starting SFTP server")
+	server := sftp.NewRequestServer(channel, newHandler(s.f).handlers())
+	defer func() {
+		_ = server.Close()
+	}()
+	err := server.Serve()
+	if err != nil && err != io.EOF {
+		fs.Errorf(what, "SFTP server failed: %v", err)
+	}
+	fs.Debugf(what, "SFTP server finished")
+}
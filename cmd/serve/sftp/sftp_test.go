@@ -0,0 +1,211 @@
+package sftp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpRun holds a running sftp server and client for a test
+type sftpRun struct {
+	t      *testing.T
+	dir    string
+	s      *server
+	client *sftp.Client
+}
+
+func newSftpRun(t *testing.T) *sftpRun {
+	fs.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-serve-sftp-test")
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "served"), 0777))
+	f, err := fs.NewFs(filepath.Join(dir, "served"))
+	require.NoError(t, err)
+
+	keyPath := filepath.Join(dir, "id_rsa")
+	require.NoError(t, makeRSAKey(keyPath))
+	opt := DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	opt.User = "user"
+	opt.Pass = "pass"
+	opt.AuthorizedKeys = ""
+	opt.Keys = []string{keyPath}
+	s, err := newServer(f, &opt)
+	require.NoError(t, err)
+	require.NoError(t, s.serve())
+
+	sshClient, err := ssh.Dial("tcp", s.Addr(), &ssh.ClientConfig{
+		User:            "user",
+		Auth:            []ssh.AuthMethod{ssh.Password("pass")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	require.NoError(t, err)
+	client, err := sftp.NewClient(sshClient)
+	require.NoError(t, err)
+	return &sftpRun{
+		t:      t,
+		dir:    filepath.Join(dir, "served"),
+		s:      s,
+		client: client,
+	}
+}
+
+func (r *sftpRun) finalise() {
+	_ = r.client.Close()
+	r.s.Close()
+	_ = os.RemoveAll(filepath.Dir(r.dir))
+}
+
+// writeFile writes a file over sftp
+func (r *sftpRun) writeFile(name, contents string) {
+	f, err := r.client.Create(name)
+	require.NoError(r.t, err)
+	_, err = f.Write([]byte(contents))
+	require.NoError(r.t, err)
+	require.NoError(r.t, f.Close())
+}
+
+// readFile reads a file over sftp
+func (r *sftpRun) readFile(name string) string {
+	f, err := r.client.Open(name)
+	require.NoError(r.t, err)
+	data, err := ioutil.ReadAll(f)
+	require.NoError(r.t, err)
+	require.NoError(r.t, f.Close())
+	return string(data)
+}
+
+// readLocal reads the file in the served directory
+func (r *sftpRun) readLocal(name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, filepath.FromSlash(name)))
+	require.NoError(r.t, err)
+	return string(data)
+}
+
+// existsLocal returns whether the path exists in the served directory
+func (r *sftpRun) existsLocal(name string) bool {
+	_, err := os.Stat(filepath.Join(r.dir, filepath.FromSlash(name)))
+	return err == nil
+}
+
+func TestSftpReadWrite(t *testing.T) {
+	r := newSftpRun(t)
+	defer r.finalise()
+
+	r.writeFile("/file.txt", "hello world")
+	assert.Equal(t, "hello world", r.readLocal("file.txt"))
+	assert.Equal(t, "hello world", r.readFile("/file.txt"))
+
+	// Read at an offset
+	f, err := r.client.Open("/file.txt")
+	require.NoError(t, err)
+	_, err = f.Seek(6, os.SEEK_SET)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "world", string(data))
+
+	// Overwrite
+	r.writeFile("/file.txt", "potato")
+	assert.Equal(t, "potato", r.readLocal("file.txt"))
+
+	fi, err := r.client.Stat("/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), fi.Size())
+	assert.False(t, fi.IsDir())
+
+	// Set the modification time
+	t1 := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	require.NoError(t, r.client.Chtimes("/file.txt", t1, t1))
+	fi, err = os.Stat(filepath.Join(r.dir, "file.txt"))
+	require.NoError(t, err)
+	assert.True(t, t1.Equal(fi.ModTime()), fi.ModTime().String())
+
+	_, err = r.client.Stat("/notfound.txt")
+	assert.True(t, os.IsNotExist(err), err)
+
+	// The error is returned on the first write
+	f, err = r.client.Create("/notfound/file.txt")
+	if err == nil {
+		_, err = f.Write([]byte("hello"))
+		_ = f.Close()
+	}
+	assert.Error(t, err)
+
+	require.NoError(t, r.client.Remove("/file.txt"))
+	assert.False(t, r.existsLocal("file.txt"))
+}
+
+func TestSftpDirectories(t *testing.T) {
+	r := newSftpRun(t)
+	defer r.finalise()
+
+	require.NoError(t, r.client.Mkdir("/dir"))
+	assert.True(t, r.existsLocal("dir"))
+	r.writeFile("/dir/a.txt", "a")
+	r.writeFile("/dir/b.txt", "bb")
+	require.NoError(t, r.client.Mkdir("/dir/sub"))
+
+	fis, err := r.client.ReadDir("/dir")
+	require.NoError(t, err)
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"a.txt", "b.txt", "sub"}, names)
+
+	fi, err := r.client.Stat("/dir/sub")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	// Rename a file
+	require.NoError(t, r.client.Rename("/dir/a.txt", "/dir/sub/c.txt"))
+	assert.False(t, r.existsLocal("dir/a.txt"))
+	assert.Equal(t, "a", r.readLocal("dir/sub/c.txt"))
+
+	// Can't rename over an existing file
+	assert.Error(t, r.client.Rename("/dir/b.txt", "/dir/sub/c.txt"))
+
+	// Rename a directory
+	require.NoError(t, r.client.Rename("/dir/sub", "/sub2"))
+	assert.Equal(t, "a", r.readLocal("sub2/c.txt"))
+
+	require.NoError(t, r.client.Remove("/sub2/c.txt"))
+	require.NoError(t, r.client.RemoveDirectory("/sub2"))
+	assert.False(t, r.existsLocal("sub2"))
+}
+
+func TestSftpAuth(t *testing.T) {
+	r := newSftpRun(t)
+	defer r.finalise()
+
+	_, err := ssh.Dial("tcp", r.s.Addr(), &ssh.ClientConfig{
+		User:            "user",
+		Auth:            []ssh.AuthMethod{ssh.Password("wrong")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	assert.Error(t, err)
+
+	// Must have some sort of authentication
+	opt := DefaultOpt
+	opt.AuthorizedKeys = ""
+	_, err = newServer(r.s.f, &opt)
+	assert.Error(t, err)
+
+	// Must have a password if a user is set
+	opt.User = "user"
+	_, err = newServer(r.s.f, &opt)
+	assert.Error(t, err)
+}
@@ -0,0 +1,303 @@
+// Files and directories for the webdav server
+
+package webdav
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ncw/rclone/cmd/serve/servefs"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/net/webdav"
+)
+
+// Errors returned by the file handles
+var (
+	errIsDir       = errors.New("is a directory")
+	errNotWritable = errors.New("file not open for writing")
+	errNotReadable = errors.New("file not open for reading")
+	errBadSeek     = errors.New("invalid seek")
+)
+
+// readFile is a webdav.File open for reading
+//
+// The object is only opened when it is first read so that Seek and
+// Stat are cheap.  Seeking to a new position closes the object and
+// it is reopened at the new offset on the next Read.
+type readFile struct {
+	ctx    context.Context
+	o      fs.Object
+	offset int64
+	in     io.ReadCloser
+}
+
+// newReadFile makes a new readFile for o
+func newReadFile(ctx context.Context, o fs.Object) *readFile {
+	return &readFile{ctx: ctx, o: o}
+}
+
+// Read bytes from the object opening it if necessary
+func (f *readFile) Read(p []byte) (n int, err error) {
+	if f.in == nil {
+		if f.offset >= f.o.Size() {
+			return 0, io.EOF
+		}
+		var options []fs.OpenOption
+		if f.offset > 0 {
+			options = append(options, &fs.SeekOption{Offset: f.offset})
+		}
+		f.in, err = f.o.Open(f.ctx, options...)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err = f.in.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// Seek to a new position - the object will be reopened on the next
+// Read if necessary
+func (f *readFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case os.SEEK_SET:
+	case os.SEEK_CUR:
+		offset += f.offset
+	case os.SEEK_END:
+		offset += f.o.Size()
+	default:
+		return f.offset, errBadSeek
+	}
+	if offset < 0 {
+		return f.offset, errBadSeek
+	}
+	if offset != f.offset {
+		err := f.closeIn()
+		if err != nil {
+			return f.offset, err
+		}
+		f.offset = offset
+	}
+	return f.offset, nil
+}
+
+// closeIn closes the underlying reader if open
+func (f *readFile) closeIn() error {
+	if f.in == nil {
+		return nil
+	}
+	err := f.in.Close()
+	f.in = nil
+	return err
+}
+
+// Close the file
+func (f *readFile) Close() error {
+	return f.closeIn()
+}
+
+// Write is not supported on a readFile
+func (f *readFile) Write(p []byte) (int, error) {
+	return 0, errNotWritable
+}
+
+// Readdir is not supported on a readFile
+func (f *readFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, errors.New("not a directory")
+}
+
+// Stat returns info about the object
+func (f *readFile) Stat() (os.FileInfo, error) {
+	return servefs.NewFileInfo(f.o), nil
+}
+
+// writeFile is a webdav.File open for writing
+//
+// The data is buffered into a temporary file and uploaded to the
+// remote when the file is closed.
+type writeFile struct {
+	ctx     context.Context
+	w       *WebDAV
+	remote  string
+	o       fs.Object // existing object or nil
+	tmp     *os.File  // temporary file holding the data
+	size    int64     // bytes written so far
+	modTime time.Time
+	copied  bool // set if the data was copied server side
+}
+
+// newWriteFile makes a new writeFile for remote.  o is the existing
+// object if any.
+func newWriteFile(ctx context.Context, w *WebDAV, remote string, o fs.Object) (*writeFile, error) {
+	tmp, err := ioutil.TempFile("", "rclone-webdav-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make temporary file")
+	}
+	return &writeFile{
+		ctx:     ctx,
+		w:       w,
+		remote:  remote,
+		o:       o,
+		tmp:     tmp,
+		modTime: time.Now(),
+	}, nil
+}
+
+// Write data to the temporary file
+func (f *writeFile) Write(p []byte) (n int, err error) {
+	n, err = f.tmp.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// ReadFrom reads all of r into the file.
+//
+// This is called by io.Copy.  If r is another file being served then
+// the object is copied with fs.Copy which will use a server side copy
+// if the remote supports it.  This makes webdav COPY requests
+// efficient.
+func (f *writeFile) ReadFrom(r io.Reader) (n int64, err error) {
+	if src, ok := r.(*readFile); ok && f.size == 0 && src.offset == 0 {
+		err = fs.Copy(f.ctx, f.w.f, f.o, f.remote, src.o)
+		if err != nil {
+			return 0, err
+		}
+		f.copied = true
+		f.size = src.o.Size()
+		return f.size, nil
+	}
+	n, err = io.Copy(f.tmp, r)
+	f.size += n
+	return n, err
+}
+
+// Close the file uploading it to the remote
+func (f *writeFile) Close() (err error) {
+	defer f.w.flushDirCache()
+	defer func() {
+		removeErr := os.Remove(f.tmp.Name())
+		if removeErr != nil {
+			fs.Errorf(f.remote, "Failed to remove temporary file: %v", removeErr)
+		}
+	}()
+	err = f.tmp.Close()
+	if err != nil {
+		return err
+	}
+	if f.copied {
+		return nil
+	}
+	in, err := os.Open(f.tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	src := fs.NewStaticObjectInfo(f.remote, f.modTime, f.size, true, nil, nil)
+	if f.o != nil {
+		return f.o.Update(f.ctx, in, src)
+	}
+	_, err = f.w.f.Put(f.ctx, in, src)
+	return err
+}
+
+// Read is not supported on a writeFile
+func (f *writeFile) Read(p []byte) (int, error) {
+	return 0, errNotReadable
+}
+
+// Seek is not supported on a writeFile except to find the current
+// position
+func (f *writeFile) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == os.SEEK_CUR {
+		return f.size, nil
+	}
+	return f.size, errBadSeek
+}
+
+// Readdir is not supported on a writeFile
+func (f *writeFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, errors.New("not a directory")
+}
+
+// Stat returns info about the data written so far
+func (f *writeFile) Stat() (os.FileInfo, error) {
+	return servefs.NewFileInfo(fs.NewStaticObjectInfo(f.remote, f.modTime, f.size, true, nil, nil)), nil
+}
+
+// dirFile is a webdav.File for a directory
+type dirFile struct {
+	ctx     context.Context
+	w       *WebDAV
+	dir     *fs.Dir
+	entries []os.FileInfo // read on first call of Readdir
+	read    bool          // set if entries has been read
+}
+
+// newDirFile makes a new dirFile for dir
+func newDirFile(ctx context.Context, w *WebDAV, dir *fs.Dir) *dirFile {
+	return &dirFile{ctx: ctx, w: w, dir: dir}
+}
+
+// Readdir reads the contents of the directory returning up to count
+// entries, or all of them if count <= 0, as os.Readdir does
+func (f *dirFile) Readdir(count int) (fis []os.FileInfo, err error) {
+	if !f.read {
+		entries, err := f.w.listDir(f.ctx, f.dir.Remote())
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			f.entries = append(f.entries, servefs.NewFileInfo(entry))
+		}
+		f.read = true
+	}
+	if count <= 0 {
+		fis, f.entries = f.entries, nil
+		return fis, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+	fis, f.entries = f.entries[:count], f.entries[count:]
+	return fis, nil
+}
+
+// Stat returns info about the directory
+func (f *dirFile) Stat() (os.FileInfo, error) {
+	return servefs.NewFileInfo(f.dir), nil
+}
+
+// Read is not supported on a directory
+func (f *dirFile) Read(p []byte) (int, error) {
+	return 0, errIsDir
+}
+
+// Write is not supported on a directory
+func (f *dirFile) Write(p []byte) (int, error) {
+	return 0, errIsDir
+}
+
+// Seek is not supported on a directory
+func (f *dirFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, errIsDir
+}
+
+// Close the directory
+func (f *dirFile) Close() error {
+	return nil
+}
+
+// check interfaces
+var (
+	_ webdav.File   = (*readFile)(nil)
+	_ webdav.File   = (*writeFile)(nil)
+	_ io.ReaderFrom = (*writeFile)(nil)
+	_ webdav.File   = (*dirFile)(nil)
+)
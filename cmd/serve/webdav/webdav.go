@@ -0,0 +1,307 @@
+// Package webdav implements a WebDAV server backed by an rclone remote
+package webdav
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/cmd/serve/servefs"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+	"golang.org/x/net/webdav"
+)
+
+// Globals
+var (
+	httpOptions  = httplib.DefaultOpt
+	dirCacheTime = 5 * time.Second
+)
+
+func init() {
+	httpOptions.ListenAddr = "localhost:8081"
+	httplib.AddFlags(Command.Flags(), &httpOptions)
+	Command.Flags().DurationVarP(&dirCacheTime, "dir-cache-time", "", dirCacheTime, "Time to cache directory entries for.")
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "webdav remote:path",
+	Short: `Serve remote:path over webdav.`,
+	Long: `
+rclone serve webdav implements a basic webdav server to serve the
+remote over HTTP via the webdav protocol. This can be viewed with a
+webdav client or you can make a remote of type webdav to read and
+write it.
+
+This means that you can map the remote as a network drive on Windows
+or macOS without needing FUSE.
+
+Files are uploaded to the remote when the client has finished sending
+them, so they are buffered in a temporary file while being uploaded.
+
+Where the remote supports it, COPY and MOVE requests are done with
+server side copies and moves so the data doesn't need to pass through
+rclone.
+
+### Directory Cache
+
+Directory listings are cached for --dir-cache-time to avoid listing
+the remote for every request.  Changes made through the server flush
+the cache, but changes made to the remote by other means won't be
+seen until the cache expires.
+` + httplib.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			s := newWebDAV(f, &httpOptions)
+			err := s.serve()
+			if err != nil {
+				return err
+			}
+			s.srv.Wait()
+			return nil
+		})
+	},
+}
+
+// WebDAV is a webdav.FileSystem interface
+//
+// A FileSystem implements access to a collection of named files. The
+// elements in a file path are separated by slash ('/', U+002F)
+// characters, regardless of host operating system convention.
+//
+// Each method has the same semantics as the os package's function of
+// the same name.
+//
+// Note that the os.Rename documentation says that "OS-specific
+// restrictions might apply". In particular, whether or not renaming a
+// file or directory overwriting another existing file or directory is
+// an error is OS-dependent.
+type WebDAV struct {
+	f   fs.Fs
+	srv *httplib.Server
+
+	mu       sync.Mutex           // protects the following
+	dirCache map[string]dirCached // cache of directory listings
+}
+
+// dirCached is a cached directory listing
+type dirCached struct {
+	entries fs.DirEntries
+	when    time.Time
+}
+
+// check interface
+var _ webdav.FileSystem = (*WebDAV)(nil)
+
+// newWebDAV makes a webdav server for f
+func newWebDAV(f fs.Fs, opt *httplib.Options) *WebDAV {
+	w := &WebDAV{
+		f:        f,
+		dirCache: make(map[string]dirCached),
+	}
+	webdavHandler := &webdav.Handler{
+		FileSystem: w,
+		LockSystem: webdav.NewMemLS(),
+		Logger:     w.logRequest,
+	}
+	w.srv = httplib.NewServer(webdavHandler, opt)
+	return w
+}
+
+// serve starts the server running in the background
+func (w *WebDAV) serve() error {
+	err := w.srv.Serve()
+	if err != nil {
+		return err
+	}
+	fs.Logf(w.f, "WebDav Server started on %s", w.srv.URL())
+	return nil
+}
+
+// logRequest is called by the webdav module on every request
+func (w *WebDAV) logRequest(r *http.Request, err error) {
+	if err != nil {
+		fs.Errorf(r.URL.Path, "%s failed: %v", r.Method, err)
+	} else {
+		fs.Infof(r.URL.Path, "%s", r.Method)
+	}
+}
+
+// listDir lists the directory dir returning the entries which pass
+// the filters.  The results are cached for dirCacheTime.
+func (w *WebDAV) listDir(ctx context.Context, dir string) (fs.DirEntries, error) {
+	w.mu.Lock()
+	cached, ok := w.dirCache[dir]
+	w.mu.Unlock()
+	if ok && time.Since(cached.when) < dirCacheTime {
+		return cached.entries, nil
+	}
+	entries, err := fs.ListDirSorted(ctx, w.f, false, dir)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.dirCache[dir] = dirCached{entries: entries, when: time.Now()}
+	w.mu.Unlock()
+	return entries, nil
+}
+
+// flushDirCache empties the directory cache - call after any
+// modification to the remote
+func (w *WebDAV) flushDirCache() {
+	w.mu.Lock()
+	w.dirCache = make(map[string]dirCached)
+	w.mu.Unlock()
+}
+
+// lookup finds the entry for remote which will be an fs.Object or
+// an *fs.Dir.  It returns os.ErrNotExist if it wasn't found.
+func (w *WebDAV) lookup(ctx context.Context, remote string) (fs.BasicInfo, error) {
+	return servefs.Lookup(ctx, w.f, w.listDir, remote)
+}
+
+// lookupDir finds the directory remote returning os.ErrNotExist if it
+// doesn't exist or isn't a directory
+func (w *WebDAV) lookupDir(ctx context.Context, remote string) (*fs.Dir, error) {
+	entry, err := w.lookup(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := entry.(*fs.Dir)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return dir, nil
+}
+
+// Mkdir creates a directory
+func (w *WebDAV) Mkdir(ctx context.Context, name string, perm os.FileMode) (err error) {
+	defer fs.Trace(name, "perm=%v", perm)("err = %v", &err)
+	remote := servefs.ToRemote(name)
+	if remote == "" {
+		return os.ErrExist
+	}
+	_, err = w.lookupDir(ctx, servefs.ParentDir(remote))
+	if err != nil {
+		return err
+	}
+	_, err = w.lookup(ctx, remote)
+	if err == nil {
+		return os.ErrExist
+	} else if err != os.ErrNotExist {
+		return err
+	}
+	defer w.flushDirCache()
+	return w.f.Mkdir(ctx, remote)
+}
+
+// OpenFile opens a file or a directory
+func (w *WebDAV) OpenFile(ctx context.Context, name string, flags int, perm os.FileMode) (file webdav.File, err error) {
+	defer fs.Trace(name, "flags=%v, perm=%v", flags, perm)("err = %v", &err)
+	remote := servefs.ToRemote(name)
+	entry, err := w.lookup(ctx, remote)
+	if err != nil && err != os.ErrNotExist {
+		return nil, err
+	}
+	exists := err == nil
+
+	// Open for write
+	if flags&(os.O_WRONLY|os.O_RDWR) != 0 {
+		if exists && flags&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, os.ErrExist
+		}
+		if !exists && flags&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+		var o fs.Object
+		if exists {
+			var ok bool
+			o, ok = entry.(fs.Object)
+			if !ok {
+				return nil, errors.Errorf("%q is a directory", name)
+			}
+		}
+		_, err = w.lookupDir(ctx, servefs.ParentDir(remote))
+		if err != nil {
+			return nil, err
+		}
+		return newWriteFile(ctx, w, remote, o)
+	}
+
+	// Open for read
+	if !exists {
+		return nil, os.ErrNotExist
+	}
+	switch x := entry.(type) {
+	case fs.Object:
+		return newReadFile(ctx, x), nil
+	case *fs.Dir:
+		return newDirFile(ctx, w, x), nil
+	}
+	return nil, errors.Errorf("unknown entry type %T", entry)
+}
+
+// RemoveAll removes a file or a directory and its contents
+func (w *WebDAV) RemoveAll(ctx context.Context, name string) (err error) {
+	defer fs.Trace(name, "")("err = %v", &err)
+	remote := servefs.ToRemote(name)
+	entry, err := w.lookup(ctx, remote)
+	if err != nil {
+		return err
+	}
+	defer w.flushDirCache()
+	switch x := entry.(type) {
+	case fs.Object:
+		return fs.DeleteFile(ctx, x)
+	case *fs.Dir:
+		if remote == "" {
+			return errors.New("can't remove root directory")
+		}
+		return servefs.RemoveDir(ctx, w.f, remote)
+	}
+	return errors.Errorf("unknown entry type %T", entry)
+}
+
+// Rename a file or a directory
+//
+// Files are moved with fs.Move which will use server side moves or
+// copies where possible and directories with the DirMove feature if
+// available.
+func (w *WebDAV) Rename(ctx context.Context, oldName, newName string) (err error) {
+	defer fs.Trace(oldName, "newName=%q", newName)("err = %v", &err)
+	oldRemote, newRemote := servefs.ToRemote(oldName), servefs.ToRemote(newName)
+	entry, err := w.lookup(ctx, oldRemote)
+	if err != nil {
+		return err
+	}
+	_, err = w.lookupDir(ctx, servefs.ParentDir(newRemote))
+	if err != nil {
+		return err
+	}
+	defer w.flushDirCache()
+	switch x := entry.(type) {
+	case fs.Object:
+		return fs.Move(ctx, w.f, nil, newRemote, x)
+	case *fs.Dir:
+		return servefs.MoveDir(ctx, w.f, oldRemote, newRemote)
+	}
+	return errors.Errorf("unknown entry type %T", entry)
+}
+
+// Stat returns info about the file or directory
+func (w *WebDAV) Stat(ctx context.Context, name string) (fi os.FileInfo, err error) {
+	defer fs.Trace(name, "")("fi=%+v, err = %v", &fi, &err)
+	entry, err := w.lookup(ctx, servefs.ToRemote(name))
+	if err != nil {
+		return nil, err
+	}
+	return servefs.NewFileInfo(entry), nil
+}
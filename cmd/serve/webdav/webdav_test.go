@@ -0,0 +1,187 @@
+package webdav
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webdavRun holds a running webdav server for a test
+type webdavRun struct {
+	t   *testing.T
+	dir string
+	w   *WebDAV
+	url string
+}
+
+func newWebdavRun(t *testing.T) *webdavRun {
+	fs.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-serve-webdav-test")
+	require.NoError(t, err)
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	opt := httplib.DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	w := newWebDAV(f, &opt)
+	require.NoError(t, w.serve())
+	return &webdavRun{
+		t:   t,
+		dir: dir,
+		w:   w,
+		url: w.srv.URL(),
+	}
+}
+
+func (r *webdavRun) finalise() {
+	r.w.srv.Close()
+	_ = os.RemoveAll(r.dir)
+}
+
+// do makes a request returning the status code and body
+func (r *webdavRun) do(method, path string, body io.Reader, headers map[string]string) (int, string) {
+	req, err := http.NewRequest(method, r.url+path, body)
+	require.NoError(r.t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(r.t, err)
+	out, err := ioutil.ReadAll(resp.Body)
+	require.NoError(r.t, err)
+	require.NoError(r.t, resp.Body.Close())
+	return resp.StatusCode, string(out)
+}
+
+// readLocal reads the file in the served directory
+func (r *webdavRun) readLocal(name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, filepath.FromSlash(name)))
+	require.NoError(r.t, err)
+	return string(data)
+}
+
+// existsLocal returns whether the path exists in the served directory
+func (r *webdavRun) existsLocal(name string) bool {
+	_, err := os.Stat(filepath.Join(r.dir, filepath.FromSlash(name)))
+	return err == nil
+}
+
+func TestWebDAVReadWrite(t *testing.T) {
+	r := newWebdavRun(t)
+	defer r.finalise()
+
+	code, _ := r.do("PUT", "file.txt", strings.NewReader("hello world"), nil)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "hello world", r.readLocal("file.txt"))
+
+	code, body := r.do("GET", "file.txt", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "hello world", body)
+
+	code, body = r.do("GET", "file.txt", nil, map[string]string{"Range": "bytes=6-"})
+	assert.Equal(t, http.StatusPartialContent, code)
+	assert.Equal(t, "world", body)
+
+	// Overwrite
+	code, _ = r.do("PUT", "file.txt", strings.NewReader("potato"), nil)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "potato", r.readLocal("file.txt"))
+
+	// Can't PUT into a directory which doesn't exist
+	code, _ = r.do("PUT", "notfound/file.txt", strings.NewReader("hello"), nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = r.do("GET", "notfound.txt", nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = r.do("DELETE", "file.txt", nil, nil)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.False(t, r.existsLocal("file.txt"))
+}
+
+func TestWebDAVDirectories(t *testing.T) {
+	r := newWebdavRun(t)
+	defer r.finalise()
+
+	code, _ := r.do("MKCOL", "dir", nil, nil)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, r.existsLocal("dir"))
+
+	code, _ = r.do("MKCOL", "dir", nil, nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	code, _ = r.do("MKCOL", "missing/dir", nil, nil)
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, _ = r.do("PUT", "dir/file.txt", strings.NewReader("hello"), nil)
+	assert.Equal(t, http.StatusCreated, code)
+
+	code, body := r.do("PROPFIND", "", nil, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, code)
+	assert.Contains(t, body, "<D:href>/dir</D:href>")
+	assert.NotContains(t, body, "file.txt")
+
+	code, body = r.do("PROPFIND", "dir/", nil, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, code)
+	assert.Contains(t, body, "<D:href>/dir/file.txt</D:href>")
+	assert.Contains(t, body, "<D:getcontentlength>5</D:getcontentlength>")
+
+	code, _ = r.do("PROPFIND", "missing/", nil, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = r.do("DELETE", "dir", nil, nil)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.False(t, r.existsLocal("dir"))
+}
+
+func TestWebDAVCopyMove(t *testing.T) {
+	r := newWebdavRun(t)
+	defer r.finalise()
+
+	code, _ := r.do("MKCOL", "dir", nil, nil)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = r.do("MKCOL", "dir/sub", nil, nil)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = r.do("PUT", "dir/file.txt", strings.NewReader("hello"), nil)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = r.do("PUT", "dir/sub/file2.txt", strings.NewReader("hello2"), nil)
+	require.Equal(t, http.StatusCreated, code)
+
+	// Copy a file
+	code, _ = r.do("COPY", "dir/file.txt", nil, map[string]string{"Destination": r.url + "copy.txt"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "hello", r.readLocal("copy.txt"))
+	assert.Equal(t, "hello", r.readLocal("dir/file.txt"))
+
+	// Copy without overwrite
+	code, _ = r.do("COPY", "dir/file.txt", nil, map[string]string{"Destination": r.url + "copy.txt", "Overwrite": "F"})
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+
+	// Move a file
+	code, _ = r.do("MOVE", "copy.txt", nil, map[string]string{"Destination": r.url + "moved.txt"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.False(t, r.existsLocal("copy.txt"))
+	assert.Equal(t, "hello", r.readLocal("moved.txt"))
+
+	// Copy a directory
+	code, _ = r.do("COPY", "dir", nil, map[string]string{"Destination": r.url + "dir2"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "hello", r.readLocal("dir2/file.txt"))
+	assert.Equal(t, "hello2", r.readLocal("dir2/sub/file2.txt"))
+
+	// Move a directory
+	code, _ = r.do("MOVE", "dir2", nil, map[string]string{"Destination": r.url + "dir3"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.False(t, r.existsLocal("dir2"))
+	assert.Equal(t, "hello", r.readLocal("dir3/file.txt"))
+	assert.Equal(t, "hello2", r.readLocal("dir3/sub/file2.txt"))
+}
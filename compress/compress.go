@@ -0,0 +1,763 @@
+// Package compress provides wrappers for Fs and Object which
+// compress and decompress files transparently
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// gzipSuffix is the suffix of compressed files
+const gzipSuffix = ".gz"
+
+// indexSuffix is the suffix of the index objects stored next to
+// compressed files.  These record the uncompressed size, which is in
+// the name of the compressed file, so it can be found without
+// listing its directory.
+const indexSuffix = ".rclone_gz"
+
+// dataNameRe matches the names of compressed files capturing the
+// original name and the encoded size
+var dataNameRe = regexp.MustCompile(`^(.+)\.([A-Za-z0-9_-]{11})` + regexp.QuoteMeta(gzipSuffix) + `$`)
+
+// Mime types which are already compressed so aren't compressed
+// again.  Those ending in / are prefixes.
+var compressedMimeTypes = []string{
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/x-compress",
+	"application/zstd",
+	"audio/",
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"video/",
+}
+
+// Extensions of files which are already compressed.  These are
+// checked as well as the mime types as the mime types known depend
+// on the system.
+var compressedExtensions = map[string]bool{
+	".7z":   true,
+	".bz2":  true,
+	".flac": true,
+	".gif":  true,
+	".gz":   true,
+	".jpeg": true,
+	".jpg":  true,
+	".mkv":  true,
+	".mov":  true,
+	".mp3":  true,
+	".mp4":  true,
+	".ogg":  true,
+	".png":  true,
+	".rar":  true,
+	".tgz":  true,
+	".webp": true,
+	".xz":   true,
+	".zip":  true,
+	".zst":  true,
+}
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "compress",
+		Description: "Compress a remote",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote to compress.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\" (not recommended).",
+		}, {
+			Name: "level",
+			Help: "GZIP compression level (1 fastest to 9 best).\nOnly gzip is supported - zstd isn't available yet.\nDefault: 6",
+			Examples: []fs.OptionExample{
+				{
+					Value: "1",
+					Help:  "Fastest compression",
+				}, {
+					Value: "6",
+					Help:  "Default compression",
+				}, {
+					Value: "9",
+					Help:  "Best compression",
+				},
+			},
+			Optional: true,
+		}},
+	})
+}
+
+// NewFs contstructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := fs.ConfigFileGet(name, "remote")
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point compress remote at itself - check the value of the remote setting")
+	}
+	level := gzip.DefaultCompression
+	if value := fs.ConfigFileGet(name, "level"); value != "" {
+		var err error
+		level, err = strconv.Atoi(value)
+		if err != nil || level < gzip.BestSpeed || level > gzip.BestCompression {
+			return nil, errors.Errorf("bad compression level %q - must be 1-9", value)
+		}
+	}
+	f, err := newFs(name, remote, rpath, level)
+	if err != nil || rpath == "" {
+		return f, err
+	}
+	// The wrapped remote doesn't know rpath is a file if it is
+	// compressed, so look for it in the parent directory
+	root := path.Dir(rpath)
+	if root == "." {
+		root = ""
+	}
+	parent, err := newFs(name, remote, root, level)
+	if err != nil {
+		return f, nil
+	}
+	_, err = parent.NewObject(context.Background(), path.Base(rpath))
+	if err == nil {
+		return parent, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// newFs makes an Fs wrapping remote at rpath
+//
+// This returns fs.ErrorIsFile with the Fs if rpath points to an
+// uncompressed file.
+func newFs(name, remote, rpath string, level int) (*Fs, error) {
+	remotePath := path.Join(remote, rpath)
+	wrappedFs, err := fs.NewFs(remotePath)
+	if err != fs.ErrorIsFile && err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
+	}
+	f := &Fs{
+		Fs:    wrappedFs,
+		name:  name,
+		root:  rpath,
+		level: level,
+	}
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from wrappedFs
+	f.features = (&fs.Features{
+		CaseInsensitive: true,
+		DuplicateFiles:  false,
+		ReadMimeType:    false, // MimeTypes not supported with compress
+		WriteMimeType:   false,
+	}).Fill(f).Mask(wrappedFs)
+	return f, err
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	features *fs.Features // optional features
+	level    int          // gzip compression level
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Compressed drive '%s:%s'", f.name, f.root)
+}
+
+// Hashes returns the supported hash sets.
+//
+// The hashes of compressed files are stored with them and the
+// hashes of uncompressed files are read from the wrapped remote.
+func (f *Fs) Hashes() fs.HashSet {
+	return fs.NewHashSet(fs.HashMD5, fs.HashSHA1)
+}
+
+// dataName returns the name of the compressed data for remote which
+// is size bytes long uncompressed
+func dataName(remote string, size int64) string {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(size))
+	return remote + "." + base64.RawURLEncoding.EncodeToString(buf[:]) + gzipSuffix
+}
+
+// parseDataName returns the original name and the uncompressed size
+// if name is the name of compressed data
+func parseDataName(name string) (remote string, size int64, ok bool) {
+	match := dataNameRe.FindStringSubmatch(name)
+	if match == nil {
+		return "", 0, false
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(match[2])
+	if err != nil || len(buf) != 8 {
+		return "", 0, false
+	}
+	size = int64(binary.BigEndian.Uint64(buf))
+	if size < 0 {
+		return "", 0, false
+	}
+	return match[1], size, true
+}
+
+// indexName returns the name of the index object for remote
+func indexName(remote string) string {
+	return remote + indexSuffix
+}
+
+// isIndexName returns whether name is the name of an index object
+func isIndexName(name string) bool {
+	return len(name) > len(indexSuffix) && strings.HasSuffix(name, indexSuffix)
+}
+
+// alwaysCompressed returns whether files called remote are always
+// compressed as they would look like compressed files or index
+// objects otherwise
+func alwaysCompressed(remote string) bool {
+	if _, _, ok := parseDataName(remote); ok {
+		return true
+	}
+	return isIndexName(remote)
+}
+
+// isCompressible returns whether src should be compressed
+func isCompressible(src fs.ObjectInfo) bool {
+	if alwaysCompressed(src.Remote()) {
+		return true
+	}
+	if compressedExtensions[strings.ToLower(path.Ext(src.Remote()))] {
+		return false
+	}
+	mimeType := fs.MimeType(src)
+	for _, compressed := range compressedMimeTypes {
+		if strings.HasSuffix(compressed, "/") {
+			if strings.HasPrefix(mimeType, compressed) {
+				return false
+			}
+		} else if mimeType == compressed {
+			return false
+		}
+	}
+	return true
+}
+
+// newObject makes an Object from the wrapped object
+func (f *Fs) newObject(data fs.Object) *Object {
+	o := &Object{
+		f:      f,
+		data:   data,
+		remote: data.Remote(),
+		size:   -1,
+	}
+	if remote, size, ok := parseDataName(data.Remote()); ok {
+		o.remote = remote
+		o.size = size
+		o.compressed = true
+	}
+	return o
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// The index objects aren't listed.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	wrappedEntries, err := f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(wrappedEntries))
+	indexes := make(map[string]fs.Object)
+	var objects []*Object
+	for _, entry := range wrappedEntries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if isIndexName(x.Remote()) {
+				indexes[strings.TrimSuffix(x.Remote(), indexSuffix)] = x
+				continue
+			}
+			o := f.newObject(x)
+			if _, found := seen[o.remote]; found {
+				fs.Logf(x, "Ignoring duplicate of %q", o.remote)
+				continue
+			}
+			seen[o.remote] = struct{}{}
+			objects = append(objects, o)
+		case *fs.Dir:
+			entries = append(entries, x)
+		default:
+			return nil, errors.Errorf("Unknown object type %T", entry)
+		}
+	}
+	for _, o := range objects {
+		if o.compressed {
+			o.index = indexes[o.remote]
+		}
+		entries = append(entries, o)
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.
+//
+// Files which aren't compressed are stored under their own names.
+// The size in the name of compressed files is read from their index
+// objects.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if !alwaysCompressed(remote) {
+		data, err := f.Fs.NewObject(ctx, remote)
+		if err == nil {
+			return f.newObject(data), nil
+		} else if err != fs.ErrorObjectNotFound {
+			return nil, err
+		}
+	}
+	index, err := f.Fs.NewObject(ctx, indexName(remote))
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readIndex(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	data, err := f.Fs.NewObject(ctx, dataName(remote, meta.Size))
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(data)
+	o.index = index
+	o.meta = meta
+	return o, nil
+}
+
+// readIndex reads the metadata from an index object
+func readIndex(ctx context.Context, index fs.Object) (meta *metadata, err error) {
+	if index.Size() > maxHeaderSize {
+		return nil, errors.Errorf("compress: index too big (%d bytes)", index.Size())
+	}
+	in, err := index.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open index")
+	}
+	defer fs.CheckClose(in, &err)
+	data, err := ioutil.ReadAll(io.LimitReader(in, maxHeaderSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read index")
+	}
+	meta = new(metadata)
+	err = json.Unmarshal(data, meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode index")
+	}
+	return meta, nil
+}
+
+// putIndex writes the index object for the compressed file at remote
+// described by meta, updating old if set
+func (f *Fs) putIndex(ctx context.Context, remote string, meta *metadata, modTime time.Time, old fs.Object) (fs.Object, error) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode index")
+	}
+	info := fs.NewStaticObjectInfo(indexName(remote), modTime, int64(len(data)), true, nil, f)
+	if old != nil {
+		err = old.Update(ctx, bytes.NewReader(data), info)
+		if err != nil {
+			return nil, err
+		}
+		return old, nil
+	}
+	return f.Fs.Put(ctx, bytes.NewReader(data), info)
+}
+
+// metadata is stored in the header and the index of compressed files
+type metadata struct {
+	Size int64  `json:"size"`
+	MD5  string `json:"md5,omitempty"`
+	SHA1 string `json:"sha1,omitempty"`
+}
+
+// compress reads in and writes it compressed to a temporary file,
+// returning the file and the metadata of the uncompressed data.
+//
+// The caller should close and remove the file.
+func (f *Fs) compress(in io.Reader) (out *os.File, meta *metadata, err error) {
+	spool, err := ioutil.TempFile("", "rclone-compress")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to make temporary file")
+	}
+	defer func() {
+		if err != nil {
+			_ = spool.Close()
+			_ = os.Remove(spool.Name())
+		}
+	}()
+	hasher, err := fs.NewMultiHasherTypes(f.Hashes())
+	if err != nil {
+		return nil, nil, err
+	}
+	zw, err := gzip.NewWriterLevel(spool, f.level)
+	if err != nil {
+		return nil, nil, err
+	}
+	size, err := io.Copy(zw, io.TeeReader(in, hasher))
+	if err != nil {
+		return nil, nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = spool.Seek(0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	sums := hasher.Sums()
+	meta = &metadata{
+		Size: size,
+		MD5:  sums[fs.HashMD5],
+		SHA1: sums[fs.HashSHA1],
+	}
+	return spool, meta, nil
+}
+
+// header returns an empty gzip member with meta in its comment.
+//
+// This is put in front of the compressed data - gzip readers treat
+// the concatenation as a single stream.
+func header(meta *metadata) ([]byte, error) {
+	comment, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Comment = string(comment)
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// put uploads in to src.Remote() replacing old if set
+//
+// The index is written after the data of compressed files, then any
+// old version stored under a different name is removed.
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, old *Object, options ...fs.OpenOption) (*Object, error) {
+	remote := src.Remote()
+	var (
+		data     fs.Object
+		dataInfo fs.ObjectInfo = src
+		meta     *metadata
+		err      error
+	)
+	if isCompressible(src) {
+		var spool *os.File
+		spool, meta, err = f.compress(in)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = spool.Close()
+			_ = os.Remove(spool.Name())
+		}()
+		if src.Size() >= 0 && meta.Size != src.Size() {
+			return nil, errors.Errorf("compress: read %d bytes expecting %d", meta.Size, src.Size())
+		}
+		head, err := header(meta)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to make header")
+		}
+		info, err := spool.Stat()
+		if err != nil {
+			return nil, err
+		}
+		in = io.MultiReader(bytes.NewReader(head), spool)
+		dataInfo = fs.NewStaticObjectInfo(dataName(remote, meta.Size), src.ModTime(), int64(len(head))+info.Size(), true, nil, f)
+	}
+	renamed := old == nil || old.data.Remote() != dataInfo.Remote()
+	if !renamed {
+		err = old.data.Update(ctx, in, dataInfo, options...)
+		data = old.data
+	} else {
+		data, err = f.Fs.Put(ctx, in, dataInfo, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(data)
+	o.meta = meta
+	if o.compressed {
+		var oldIndex fs.Object
+		if old != nil {
+			oldIndex = old.index
+		}
+		o.index, err = f.putIndex(ctx, remote, meta, src.ModTime(), oldIndex)
+		if err != nil {
+			if renamed {
+				// Don't leave data which can't be found
+				removeErr := data.Remove(ctx)
+				if removeErr != nil {
+					fs.Errorf(data, "Failed to remove data: %v", removeErr)
+				}
+			}
+			return nil, errors.Wrap(err, "failed to upload index")
+		}
+	}
+	if old != nil && renamed {
+		// The stored name has changed so remove the old one
+		err = old.data.Remove(ctx)
+		if err != nil {
+			fs.Errorf(old, "Failed to remove old version: %v", err)
+		}
+		if old.index != nil && !o.compressed {
+			err = old.index.Remove(ctx)
+			if err != nil {
+				fs.Errorf(old, "Failed to remove old index: %v", err)
+			}
+		}
+	}
+	return o, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	existing, err := f.NewObject(ctx, src.Remote())
+	switch err {
+	case nil:
+		return existing, existing.Update(ctx, in, src, options...)
+	case fs.ErrorObjectNotFound:
+		return f.put(ctx, in, src, nil, options...)
+	default:
+		return nil, err
+	}
+}
+
+// Purge all files in the root and the root directory
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge(ctx context.Context) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do(ctx)
+}
+
+// newName returns the name o should be stored under at remote
+func (o *Object) newName(remote string) string {
+	if o.compressed {
+		return dataName(remote, o.size)
+	}
+	return remote
+}
+
+// copyOrMove copies or moves o and its index to remote with do
+//
+// Any existing object at remote is removed if it was stored under a
+// different name, as is its index if o doesn't have one to replace it.
+func (f *Fs) copyOrMove(ctx context.Context, o *Object, remote string, do func(context.Context, fs.Object, string) (fs.Object, error)) (fs.Object, error) {
+	var old *Object
+	existing, err := f.NewObject(ctx, remote)
+	switch err {
+	case nil:
+		old = existing.(*Object)
+	case fs.ErrorObjectNotFound:
+	default:
+		return nil, err
+	}
+	data, err := do(ctx, o.data, o.newName(remote))
+	if err != nil {
+		return nil, err
+	}
+	newObj := f.newObject(data)
+	newObj.meta = o.meta
+	if o.compressed {
+		if o.index != nil {
+			newObj.index, err = do(ctx, o.index, indexName(remote))
+		} else {
+			// Make the missing index from the header
+			var (
+				meta     *metadata
+				oldIndex fs.Object
+			)
+			if old != nil {
+				oldIndex = old.index
+			}
+			meta, err = o.readMetadata(ctx)
+			if err == nil {
+				newObj.index, err = f.putIndex(ctx, remote, meta, o.ModTime(), oldIndex)
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to copy index")
+		}
+	}
+	if old != nil {
+		if old.data.Remote() != data.Remote() {
+			err = old.data.Remove(ctx)
+			if err != nil {
+				fs.Errorf(old, "Failed to remove old version: %v", err)
+			}
+		}
+		if old.index != nil && !o.compressed {
+			err = old.index.Remove(ctx)
+			if err != nil {
+				fs.Errorf(old, "Failed to remove old index: %v", err)
+			}
+		}
+	}
+	return newObj, nil
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	return f.copyOrMove(ctx, o, remote, do)
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	return f.copyOrMove(ctx, o, remote, do)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// CleanUp the trash in the Fs
+//
+// Implement this if you have a way of emptying the trash or
+// otherwise cleaning up old versions of files.
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do(ctx)
+}
+
+// DirChangeNotify calls the passed function with a path of a
+// directory that has had changes in the wrapped remote
+func (f *Fs) DirChangeNotify(notifyFunc func(string), pollInterval time.Duration) chan bool {
+	do := f.Fs.Features().DirChangeNotify
+	if do == nil {
+		return nil
+	}
+	return do(notifyFunc, pollInterval)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs                = (*Fs)(nil)
+	_ fs.Purger            = (*Fs)(nil)
+	_ fs.Copier            = (*Fs)(nil)
+	_ fs.Mover             = (*Fs)(nil)
+	_ fs.DirMover          = (*Fs)(nil)
+	_ fs.CleanUpper        = (*Fs)(nil)
+	_ fs.DirChangeNotifier = (*Fs)(nil)
+	_ fs.UnWrapper         = (*Fs)(nil)
+)
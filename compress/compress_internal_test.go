@@ -0,0 +1,195 @@
+package compress
+
+import (
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newTestFs makes a compress remote wrapping a local temporary
+// directory returning the Fs, the directory and a cleanup function
+func newTestFs(t *testing.T, name string) (*Fs, string, func()) {
+	r := fstest.NewWrapperRemote(t, "compress", name, 1, nil)
+	return r.Fs.(*Fs), r.Dirs[0], r.Finalise
+}
+
+// listDir returns the sorted names of the files in dir
+func listDir(t *testing.T, dir string) (names []string) {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func put(t *testing.T, f *Fs, remote, contents string) fs.Object {
+	src := fs.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(context.Background(), strings.NewReader(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+func TestDataName(t *testing.T) {
+	for _, size := range []int64{0, 1, 1 << 40} {
+		name := dataName("dir/file.txt", size)
+		remote, gotSize, ok := parseDataName(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, "dir/file.txt", remote)
+		assert.Equal(t, size, gotSize)
+	}
+	assert.Equal(t, "file.AAAAAAAAAAE.gz", dataName("file", 1))
+	for _, name := range []string{"file", "file.gz", "file.AAAAAAAAAA.gz", ".AAAAAAAAAAE.gz", "file.AAAAAAAAAAE.gzip"} {
+		_, _, ok := parseDataName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestCompressPutAndRead(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestCompressPutAndRead")
+	defer cleanup()
+	data := strings.Repeat("hello world ", 100)
+	o := put(t, f, "file.txt", data)
+	assert.Equal(t, int64(len(data)), o.Size())
+
+	// The data is stored compressed with the size in the name
+	dataPath := filepath.Join(dir, dataName("file.txt", int64(len(data))))
+	info, err := os.Stat(dataPath)
+	require.NoError(t, err)
+	assert.True(t, info.Size() < int64(len(data)))
+
+	// and can be read with gzip
+	fd, err := os.Open(dataPath)
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(fd)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	assert.Equal(t, data, string(got))
+
+	// An index records its size so it can be found directly
+	assert.Equal(t, []string{dataName("file.txt", int64(len(data))), "file.txt" + indexSuffix}, listDir(t, dir))
+
+	// It is listed with its original name and size
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "file.txt", entries[0].Remote())
+	assert.Equal(t, int64(len(data)), entries[0].(fs.Object).Size())
+
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	sum := md5.Sum([]byte(data))
+	hash, err := o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+
+	read := func(options ...fs.OpenOption) string {
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(got)
+	}
+	assert.Equal(t, data, read())
+	assert.Equal(t, data[500:], read(&fs.SeekOption{Offset: 500}))
+	assert.Equal(t, data[10:20], read(&fs.RangeOption{Start: 10, End: 19}))
+
+	// Updating the file replaces the old data
+	o = put(t, f, "file.txt", "potato")
+	assert.Equal(t, []string{dataName("file.txt", 6), "file.txt" + indexSuffix}, listDir(t, dir))
+	assert.Equal(t, "potato", read())
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), o.Size())
+
+	// Removing it removes the index too
+	require.NoError(t, o.Remove(ctx))
+	assert.Equal(t, 0, len(listDir(t, dir)))
+	_, err = f.NewObject(ctx, "file.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestCompressMove(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestCompressMove")
+	defer cleanup()
+	data := "hello world"
+	o := put(t, f, "file.txt", data)
+
+	// The index is moved with the data
+	moved, err := f.Move(ctx, o, "moved.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "moved.txt", moved.Remote())
+	assert.Equal(t, []string{dataName("moved.txt", 11), "moved.txt" + indexSuffix}, listDir(t, dir))
+	o, err = f.NewObject(ctx, "moved.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), o.Size())
+}
+
+func TestCompressMoveOverExisting(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, "TestCompressMoveOverExisting")
+	defer cleanup()
+	put(t, f, "file.txt", "hello world")
+
+	// The old data stored under a different name is removed
+	data := "potato"
+	moved, err := f.Move(ctx, put(t, f, "new.txt", data), "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), moved.Size())
+	assert.Equal(t, []string{dataName("file.txt", 6), "file.txt" + indexSuffix}, listDir(t, dir))
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), o.Size())
+
+	// As is the old index when the new file isn't compressed
+	data = "not really a jpeg"
+	_, err = f.Move(ctx, put(t, f, "photo.jpg", data), "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file.txt"}, listDir(t, dir))
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), o.Size())
+}
+
+func TestCompressSkipsCompressed(t *testing.T) {
+	f, dir, cleanup := newTestFs(t, "TestCompressSkipsCompressed")
+	defer cleanup()
+	put(t, f, "archive.zip", "not really a zip")
+	put(t, f, "photo.jpg", "not really a jpeg")
+	for _, name := range []string{"archive.zip", "photo.jpg"} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Contains(t, string(got), "not really")
+	}
+
+	// Files which would look compressed are compressed anyway
+	for _, name := range []string{dataName("file", 3), "file" + indexSuffix} {
+		o := put(t, f, name, "abc")
+		assert.Equal(t, name, o.Remote())
+		_, err := os.Stat(filepath.Join(dir, dataName(name, 3)))
+		assert.NoError(t, err)
+		o, err = f.NewObject(context.Background(), name)
+		require.NoError(t, err)
+		assert.Equal(t, name, o.Remote())
+	}
+}
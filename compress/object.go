@@ -0,0 +1,233 @@
+// Objects for the compress backend
+
+package compress
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// maxHeaderSize is the most that is read to find the metadata
+const maxHeaderSize = 4096
+
+// Object describes a possibly compressed object
+//
+// Compressed objects are stored with their uncompressed size in
+// their names and their hashes in a header.  Uncompressed objects
+// are stored as they are.
+type Object struct {
+	f          *Fs
+	remote     string
+	data       fs.Object // the wrapped object
+	index      fs.Object // the index object if compressed or nil
+	compressed bool      // set if data is compressed
+	size       int64     // uncompressed size if compressed
+	meta       *metadata // metadata read from the header or index or nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Size returns the uncompressed size of the file
+func (o *Object) Size() int64 {
+	if !o.compressed {
+		return o.data.Size()
+	}
+	return o.size
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime() time.Time {
+	return o.data.ModTime()
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	return o.data.SetModTime(ctx, t)
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return o.data.Storable()
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.data
+}
+
+// readMetadata reads the metadata from the header of the object
+func (o *Object) readMetadata(ctx context.Context) (meta *metadata, err error) {
+	if o.meta != nil {
+		return o.meta, nil
+	}
+	in, err := o.data.Open(ctx, &fs.RangeOption{Start: 0, End: maxHeaderSize - 1})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open header")
+	}
+	defer fs.CheckClose(in, &err)
+	zr, err := gzip.NewReader(io.LimitReader(in, maxHeaderSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read header")
+	}
+	meta = new(metadata)
+	err = json.Unmarshal([]byte(zr.Comment), meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode header")
+	}
+	o.meta = meta
+	return meta, nil
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+func (o *Object) Hash(hashType fs.HashType) (string, error) {
+	if !o.f.Hashes().Contains(hashType) {
+		return "", fs.ErrHashUnsupported
+	}
+	if !o.compressed {
+		hash, err := o.data.Hash(hashType)
+		if err == fs.ErrHashUnsupported {
+			return "", nil
+		}
+		return hash, err
+	}
+	meta, err := o.readMetadata(context.Background())
+	if err != nil {
+		return "", err
+	}
+	switch hashType {
+	case fs.HashMD5:
+		return meta.MD5, nil
+	case fs.HashSHA1:
+		return meta.SHA1, nil
+	}
+	return "", nil
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+//
+// Compressed files are always read from the start and the data
+// before any offset requested is discarded.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.ReadCloser, err error) {
+	if !o.compressed {
+		return o.data.Open(ctx, options...)
+	}
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	in, err := o.data.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			fs.CheckClose(in, &err)
+		}
+	}()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read compressed data")
+	}
+	if offset > 0 {
+		_, err = io.CopyN(ioutil.Discard, zr, offset)
+		if err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to seek compressed data")
+		}
+	}
+	rc = &decompressor{Reader: zr, in: in}
+	if limit >= 0 {
+		rc = fs.NewLimitedReadCloser(rc, limit)
+	}
+	return rc, nil
+}
+
+// decompressor reads decompressed data closing the underlying reader
+type decompressor struct {
+	*gzip.Reader
+	in io.ReadCloser
+}
+
+// Close the decompressor and the underlying reader
+func (d *decompressor) Close() error {
+	err := d.Reader.Close()
+	closeErr := d.in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	newObj, err := o.f.put(ctx, in, &renamedInfo{ObjectInfo: src, remote: o.remote}, o, options...)
+	if err != nil {
+		return err
+	}
+	*o = *newObj
+	return nil
+}
+
+// renamedInfo is an ObjectInfo with a different remote
+type renamedInfo struct {
+	fs.ObjectInfo
+	remote string
+}
+
+// Remote returns the remote path
+func (ri *renamedInfo) Remote() string {
+	return ri.remote
+}
+
+// Remove an object and its index
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.data.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	if o.index != nil {
+		return o.index.Remove(ctx)
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object     = (*Object)(nil)
+	_ io.ReadCloser = (*decompressor)(nil)
+)
@@ -66,6 +66,7 @@ func TestObjectUpdate2(t *testing.T)        { fstests.TestObjectUpdate(t) }
 func TestObjectStorable2(t *testing.T)      { fstests.TestObjectStorable(t) }
 func TestFsIsFile2(t *testing.T)            { fstests.TestFsIsFile(t) }
 func TestFsIsFileNotFound2(t *testing.T)    { fstests.TestFsIsFileNotFound(t) }
+func TestFsPublicLink2(t *testing.T)        { fstests.TestFsPublicLink(t) }
 func TestObjectRemove2(t *testing.T)        { fstests.TestObjectRemove(t) }
 func TestObjectPurge2(t *testing.T)         { fstests.TestObjectPurge(t) }
 func TestFinalise2(t *testing.T)            { fstests.TestFinalise(t) }
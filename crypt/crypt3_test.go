@@ -66,6 +66,7 @@ func TestObjectUpdate3(t *testing.T)        { fstests.TestObjectUpdate(t) }
 func TestObjectStorable3(t *testing.T)      { fstests.TestObjectStorable(t) }
 func TestFsIsFile3(t *testing.T)            { fstests.TestFsIsFile(t) }
 func TestFsIsFileNotFound3(t *testing.T)    { fstests.TestFsIsFileNotFound(t) }
+func TestFsPublicLink3(t *testing.T)        { fstests.TestFsPublicLink(t) }
 func TestObjectRemove3(t *testing.T)        { fstests.TestObjectRemove(t) }
 func TestObjectPurge3(t *testing.T)         { fstests.TestObjectPurge(t) }
 func TestFinalise3(t *testing.T)            { fstests.TestFinalise(t) }
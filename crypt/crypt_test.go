@@ -66,6 +66,7 @@ func TestObjectUpdate(t *testing.T)        { fstests.TestObjectUpdate(t) }
 func TestObjectStorable(t *testing.T)      { fstests.TestObjectStorable(t) }
 func TestFsIsFile(t *testing.T)            { fstests.TestFsIsFile(t) }
 func TestFsIsFileNotFound(t *testing.T)    { fstests.TestFsIsFileNotFound(t) }
+func TestFsPublicLink(t *testing.T)        { fstests.TestFsPublicLink(t) }
 func TestObjectRemove(t *testing.T)        { fstests.TestObjectRemove(t) }
 func TestObjectPurge(t *testing.T)         { fstests.TestObjectPurge(t) }
 func TestFinalise(t *testing.T)            { fstests.TestFinalise(t) }
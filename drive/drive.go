@@ -23,6 +23,8 @@ import (
 	"github.com/ncw/rclone/pacer"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v2"
@@ -63,26 +65,26 @@ var (
 		RedirectURL:  oauthutil.TitleBarRedirectURL,
 	}
 	mimeTypeToExtension = map[string]string{
-		"application/epub+zip":                                                      "epub",
-		"application/msword":                                                        "doc",
-		"application/pdf":                                                           "pdf",
-		"application/rtf":                                                           "rtf",
-		"application/vnd.ms-excel":                                                  "xls",
-		"application/vnd.oasis.opendocument.presentation":                           "odp",
-		"application/vnd.oasis.opendocument.spreadsheet":                            "ods",
-		"application/vnd.oasis.opendocument.text":                                   "odt",
+		"application/epub+zip":                            "epub",
+		"application/msword":                              "doc",
+		"application/pdf":                                 "pdf",
+		"application/rtf":                                 "rtf",
+		"application/vnd.ms-excel":                        "xls",
+		"application/vnd.oasis.opendocument.presentation": "odp",
+		"application/vnd.oasis.opendocument.spreadsheet":  "ods",
+		"application/vnd.oasis.opendocument.text":         "odt",
 		"application/vnd.openxmlformats-officedocument.presentationml.presentation": "pptx",
 		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         "xlsx",
 		"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   "docx",
 		"application/x-vnd.oasis.opendocument.spreadsheet":                          "ods",
-		"application/zip":                                                           "zip",
-		"image/jpeg":                                                                "jpg",
-		"image/png":                                                                 "png",
-		"image/svg+xml":                                                             "svg",
-		"text/csv":                                                                  "csv",
-		"text/html":                                                                 "html",
-		"text/plain":                                                                "txt",
-		"text/tab-separated-values":                                                 "tsv",
+		"application/zip":           "zip",
+		"image/jpeg":                "jpg",
+		"image/png":                 "png",
+		"image/svg+xml":             "svg",
+		"text/csv":                  "csv",
+		"text/html":                 "html",
+		"text/plain":                "txt",
+		"text/tab-separated-values": "tsv",
 	}
 	extensionToMimeType map[string]string
 	partialFields       = "id,downloadUrl,exportLinks,fileExtension,fullFileExtension,fileSize,labels,md5Checksum,modifiedDate,mimeType,title"
@@ -90,6 +92,7 @@ var (
 
 // Register with Fs
 func init() {
+	ctx := context.Background()
 	fs.Register(&fs.RegInfo{
 		Name:        "drive",
 		Description: "Google Drive",
@@ -99,7 +102,7 @@ func init() {
 			if err != nil {
 				log.Fatalf("Failed to configure token: %v", err)
 			}
-			err = configTeamDrive(name)
+			err = configTeamDrive(ctx, name)
 			if err != nil {
 				log.Fatalf("Failed to configure team drive: %v", err)
 			}
@@ -212,7 +215,7 @@ type listFn func(*drive.File) bool
 // If the user fn ever returns true then it early exits with found = true
 //
 // Search params: https://developers.google.com/drive/search-parameters
-func (f *Fs) list(dirID string, title string, directoriesOnly bool, filesOnly bool, includeTrashed bool, fn listFn) (found bool, err error) {
+func (f *Fs) list(ctx context.Context, dirID string, title string, directoriesOnly bool, filesOnly bool, includeTrashed bool, fn listFn) (found bool, err error) {
 	var query []string
 
 	if !includeTrashed {
@@ -265,8 +268,8 @@ func (f *Fs) list(dirID string, title string, directoriesOnly bool, filesOnly bo
 OUTER:
 	for {
 		var files *drive.FileList
-		err = f.pacer.Call(func() (bool, error) {
-			files, err = list.Fields(googleapi.Field(fields)).Do()
+		err = f.pacer.Call(ctx, func() (bool, error) {
+			files, err = list.Fields(googleapi.Field(fields)).Context(ctx).Do()
 			return shouldRetry(err)
 		})
 		if err != nil {
@@ -320,7 +323,7 @@ func (f *Fs) parseExtensions(extensions string) error {
 }
 
 // Figure out if the user wants to use a team drive
-func configTeamDrive(name string) error {
+func configTeamDrive(ctx context.Context, name string) error {
 	teamDrive := fs.ConfigFileGet(name, "team_drive")
 	if teamDrive == "" {
 		fmt.Printf("Configure this as a team drive?\n")
@@ -343,8 +346,8 @@ func configTeamDrive(name string) error {
 	listTeamDrives := svc.Teamdrives.List().MaxResults(100)
 	for {
 		var teamDrives *drive.TeamDriveList
-		err = newPacer().Call(func() (bool, error) {
-			teamDrives, err = listTeamDrives.Do()
+		err = newPacer().Call(ctx, func() (bool, error) {
+			teamDrives, err = listTeamDrives.Context(ctx).Do()
 			return shouldRetry(err)
 		})
 		if err != nil {
@@ -376,6 +379,7 @@ func newPacer() *pacer.Pacer {
 
 // NewFs contstructs an Fs from the path, container:path
 func NewFs(name, path string) (fs.Fs, error) {
+	ctx := context.Background()
 	if !isPowerOfTwo(int64(chunkSize)) {
 		return nil, errors.Errorf("drive: chunk size %v isn't a power of two", chunkSize)
 	}
@@ -410,8 +414,8 @@ func NewFs(name, path string) (fs.Fs, error) {
 	}
 
 	// Read About so we know the root path
-	err = f.pacer.Call(func() (bool, error) {
-		f.about, err = f.svc.About.Get().Do()
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		f.about, err = f.svc.About.Get().Context(ctx).Do()
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -435,7 +439,7 @@ func NewFs(name, path string) (fs.Fs, error) {
 	}
 
 	// Find the current root
-	err = f.dirCache.FindRoot(false)
+	err = f.dirCache.FindRoot(ctx, false)
 	if err != nil {
 		// Assume it is a file
 		newRoot, remote := dircache.SplitPath(root)
@@ -443,12 +447,12 @@ func NewFs(name, path string) (fs.Fs, error) {
 		newF.dirCache = dircache.New(newRoot, f.about.RootFolderId, &newF)
 		newF.root = newRoot
 		// Make new Fs which is the parent
-		err = newF.dirCache.FindRoot(false)
+		err = newF.dirCache.FindRoot(ctx, false)
 		if err != nil {
 			// No root so return old f
 			return f, nil
 		}
-		_, err := newF.newObjectWithInfo(remote, nil)
+		_, err := newF.newObjectWithInfo(ctx, remote, nil)
 		if err != nil {
 			// File doesn't exist so return old f
 			return f, nil
@@ -463,7 +467,7 @@ func NewFs(name, path string) (fs.Fs, error) {
 // Return an Object from a path
 //
 // If it can't be found it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) newObjectWithInfo(remote string, info *drive.File) (fs.Object, error) {
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *drive.File) (fs.Object, error) {
 	o := &Object{
 		fs:     f,
 		remote: remote,
@@ -471,7 +475,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *drive.File) (fs.Object, erro
 	if info != nil {
 		o.setMetaData(info)
 	} else {
-		err := o.readMetaData() // reads info and meta, returning an error
+		err := o.readMetaData(ctx) // reads info and meta, returning an error
 		if err != nil {
 			return nil, err
 		}
@@ -481,14 +485,14 @@ func (f *Fs) newObjectWithInfo(remote string, info *drive.File) (fs.Object, erro
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
-	return f.newObjectWithInfo(remote, nil)
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(ctx, remote, nil)
 }
 
 // FindLeaf finds a directory of name leaf in the folder with ID pathID
-func (f *Fs) FindLeaf(pathID, leaf string) (pathIDOut string, found bool, err error) {
+func (f *Fs) FindLeaf(ctx context.Context, pathID, leaf string) (pathIDOut string, found bool, err error) {
 	// Find the leaf in pathID
-	found, err = f.list(pathID, leaf, true, false, false, func(item *drive.File) bool {
+	found, err = f.list(ctx, pathID, leaf, true, false, false, func(item *drive.File) bool {
 		if item.Title == leaf {
 			pathIDOut = item.Id
 			return true
@@ -499,7 +503,7 @@ func (f *Fs) FindLeaf(pathID, leaf string) (pathIDOut string, found bool, err er
 }
 
 // CreateDir makes a directory with pathID as parent and name leaf
-func (f *Fs) CreateDir(pathID, leaf string) (newID string, err error) {
+func (f *Fs) CreateDir(ctx context.Context, pathID, leaf string) (newID string, err error) {
 	// fmt.Println("Making", path)
 	// Define the metadata for the directory we are going to create.
 	createInfo := &drive.File{
@@ -509,8 +513,8 @@ func (f *Fs) CreateDir(pathID, leaf string) (newID string, err error) {
 		Parents:     []*drive.ParentReference{{Id: pathID}},
 	}
 	var info *drive.File
-	err = f.pacer.Call(func() (bool, error) {
-		info, err = f.svc.Files.Insert(createInfo).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		info, err = f.svc.Files.Insert(createInfo).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -563,18 +567,18 @@ func (f *Fs) findExportFormat(filepath string, item *drive.File) (extension, lin
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
-	err = f.dirCache.FindRoot(false)
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.dirCache.FindRoot(ctx, false)
 	if err != nil {
 		return nil, err
 	}
-	directoryID, err := f.dirCache.FindDir(dir, false)
+	directoryID, err := f.dirCache.FindDir(ctx, dir, false)
 	if err != nil {
 		return nil, err
 	}
 
 	var iErr error
-	_, err = f.list(directoryID, "", false, false, false, func(item *drive.File) bool {
+	_, err = f.list(ctx, directoryID, "", false, false, false, func(item *drive.File) bool {
 		remote := path.Join(dir, item.Title)
 		switch {
 		case *driveAuthOwnerOnly && !isAuthOwned(item):
@@ -591,7 +595,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 			entries = append(entries, d)
 		case item.Md5Checksum != "" || item.FileSize > 0:
 			// If item has MD5 sum or a length it is a file stored on drive
-			o, err := f.newObjectWithInfo(remote, item)
+			o, err := f.newObjectWithInfo(ctx, remote, item)
 			if err != nil {
 				iErr = err
 				return true
@@ -603,7 +607,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 			if extension == "" {
 				fs.Debugf(remote, "No export formats found")
 			} else {
-				o, err := f.newObjectWithInfo(remote+"."+extension, item)
+				o, err := f.newObjectWithInfo(ctx, remote+"."+extension, item)
 				if err != nil {
 					iErr = err
 					return true
@@ -636,7 +640,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 // finished Object which must have setMetaData called on it
 //
 // Used to create new objects
-func (f *Fs) createFileInfo(remote string, modTime time.Time, size int64) (*Object, *drive.File, error) {
+func (f *Fs) createFileInfo(ctx context.Context, remote string, modTime time.Time, size int64) (*Object, *drive.File, error) {
 	// Temporary Object under construction
 	o := &Object{
 		fs:     f,
@@ -644,7 +648,7 @@ func (f *Fs) createFileInfo(remote string, modTime time.Time, size int64) (*Obje
 		bytes:  size,
 	}
 
-	leaf, directoryID, err := f.dirCache.FindRootAndPath(remote, true)
+	leaf, directoryID, err := f.dirCache.FindRootAndPath(ctx, remote, true)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -665,14 +669,14 @@ func (f *Fs) createFileInfo(remote string, modTime time.Time, size int64) (*Obje
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	exisitingObj, err := f.newObjectWithInfo(src.Remote(), nil)
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	exisitingObj, err := f.newObjectWithInfo(ctx, src.Remote(), nil)
 	switch err {
 	case nil:
-		return exisitingObj, exisitingObj.Update(in, src)
+		return exisitingObj, exisitingObj.Update(ctx, in, src)
 	case fs.ErrorObjectNotFound:
 		// Not found so create it
-		return f.PutUnchecked(in, src)
+		return f.PutUnchecked(ctx, in, src)
 	default:
 		return nil, err
 	}
@@ -682,12 +686,12 @@ func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.
 //
 // This will create a duplicate if we upload a new file without
 // checking to see if there is one already - use Put() for that.
-func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	remote := src.Remote()
 	size := src.Size()
 	modTime := src.ModTime()
 
-	o, createInfo, err := f.createFileInfo(remote, modTime, size)
+	o, createInfo, err := f.createFileInfo(ctx, remote, modTime, size)
 	if err != nil {
 		return nil, err
 	}
@@ -696,8 +700,8 @@ func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOpt
 	if size == 0 || size < int64(driveUploadCutoff) {
 		// Make the API request to upload metadata and file data.
 		// Don't retry, return a retry error instead
-		err = f.pacer.CallNoRetry(func() (bool, error) {
-			info, err = f.svc.Files.Insert(createInfo).Media(in, googleapi.ContentType("")).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+		err = f.pacer.CallNoRetry(ctx, func() (bool, error) {
+			info, err = f.svc.Files.Insert(createInfo).Media(in, googleapi.ContentType("")).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 			return shouldRetry(err)
 		})
 		if err != nil {
@@ -705,7 +709,7 @@ func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOpt
 		}
 	} else {
 		// Upload the file in chunks
-		info, err = f.Upload(in, size, createInfo.MimeType, createInfo, remote)
+		info, err = f.Upload(ctx, in, size, createInfo.MimeType, createInfo, remote)
 		if err != nil {
 			return o, err
 		}
@@ -715,13 +719,13 @@ func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOpt
 }
 
 // Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
-	err := f.dirCache.FindRoot(true)
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	err := f.dirCache.FindRoot(ctx, true)
 	if err != nil {
 		return err
 	}
 	if dir != "" {
-		_, err = f.dirCache.FindDir(dir, true)
+		_, err = f.dirCache.FindDir(ctx, dir, true)
 	}
 	return err
 }
@@ -729,15 +733,15 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes a directory
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	root := path.Join(f.root, dir)
 	dc := f.dirCache
-	directoryID, err := dc.FindDir(dir, false)
+	directoryID, err := dc.FindDir(ctx, dir, false)
 	if err != nil {
 		return err
 	}
 	var trashedFiles = false
-	found, err := f.list(directoryID, "", false, false, true, func(item *drive.File) bool {
+	found, err := f.list(ctx, directoryID, "", false, false, true, func(item *drive.File) bool {
 		if item.Labels == nil || !item.Labels.Trashed {
 			fs.Debugf(dir, "Rmdir: contains file: %q", item.Title)
 			return true
@@ -754,14 +758,14 @@ func (f *Fs) Rmdir(dir string) error {
 	}
 	// Delete the directory if it isn't the root
 	if root != "" {
-		err = f.pacer.Call(func() (bool, error) {
+		err = f.pacer.Call(ctx, func() (bool, error) {
 			// trash the directory if it had trashed files
 			// in or the user wants to trash, otherwise
 			// delete it.
 			if trashedFiles || *driveUseTrash {
-				_, err = f.svc.Files.Trash(directoryID).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+				_, err = f.svc.Files.Trash(directoryID).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 			} else {
-				err = f.svc.Files.Delete(directoryID).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+				err = f.svc.Files.Delete(directoryID).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 			}
 			return shouldRetry(err)
 		})
@@ -790,7 +794,7 @@ func (f *Fs) Precision() time.Duration {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't copy - not same remote type")
@@ -800,14 +804,14 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 		return nil, errors.New("can't copy a Google document")
 	}
 
-	o, createInfo, err := f.createFileInfo(remote, srcObj.ModTime(), srcObj.bytes)
+	o, createInfo, err := f.createFileInfo(ctx, remote, srcObj.ModTime(), srcObj.bytes)
 	if err != nil {
 		return nil, err
 	}
 
 	var info *drive.File
-	err = o.fs.pacer.Call(func() (bool, error) {
-		info, err = o.fs.svc.Files.Copy(srcObj.id, createInfo).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+	err = o.fs.pacer.Call(ctx, func() (bool, error) {
+		info, err = o.fs.svc.Files.Copy(srcObj.id, createInfo).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -823,19 +827,19 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 // Optional interface: Only implement this if you have a way of
 // deleting all the files quicker than just running Remove() on the
 // result of List()
-func (f *Fs) Purge() error {
+func (f *Fs) Purge(ctx context.Context) error {
 	if f.root == "" {
 		return errors.New("can't purge root directory")
 	}
-	err := f.dirCache.FindRoot(false)
+	err := f.dirCache.FindRoot(ctx, false)
 	if err != nil {
 		return err
 	}
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		if *driveUseTrash {
-			_, err = f.svc.Files.Trash(f.dirCache.RootID()).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+			_, err = f.svc.Files.Trash(f.dirCache.RootID()).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 		} else {
-			err = f.svc.Files.Delete(f.dirCache.RootID()).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+			err = f.svc.Files.Delete(f.dirCache.RootID()).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 		}
 		return shouldRetry(err)
 	})
@@ -855,7 +859,7 @@ func (f *Fs) Purge() error {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantMove
-func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't move - not same remote type")
@@ -866,15 +870,15 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 	}
 
 	// Temporary Object under construction
-	dstObj, dstInfo, err := f.createFileInfo(remote, srcObj.ModTime(), srcObj.bytes)
+	dstObj, dstInfo, err := f.createFileInfo(ctx, remote, srcObj.ModTime(), srcObj.bytes)
 	if err != nil {
 		return nil, err
 	}
 
 	// Do the move
 	var info *drive.File
-	err = f.pacer.Call(func() (bool, error) {
-		info, err = f.svc.Files.Patch(srcObj.id, dstInfo).SetModifiedDate(true).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		info, err = f.svc.Files.Patch(srcObj.id, dstInfo).SetModifiedDate(true).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -893,7 +897,7 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 // If it isn't possible then return fs.ErrorCantDirMove
 //
 // If destination exists then return fs.ErrorDirExists
-func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
 	srcFs, ok := src.(*Fs)
 	if !ok {
 		fs.Debugf(srcFs, "Can't move directory - not same remote type")
@@ -909,14 +913,14 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	}
 
 	// find the root src directory
-	err := srcFs.dirCache.FindRoot(false)
+	err := srcFs.dirCache.FindRoot(ctx, false)
 	if err != nil {
 		return err
 	}
 
 	// find the root dst directory
 	if dstRemote != "" {
-		err = f.dirCache.FindRoot(true)
+		err = f.dirCache.FindRoot(ctx, true)
 		if err != nil {
 			return err
 		}
@@ -932,14 +936,14 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	if dstRemote == "" {
 		findPath = f.root
 	}
-	leaf, directoryID, err = f.dirCache.FindPath(findPath, true)
+	leaf, directoryID, err = f.dirCache.FindPath(ctx, findPath, true)
 	if err != nil {
 		return err
 	}
 
 	// Check destination does not exist
 	if dstRemote != "" {
-		_, err = f.dirCache.FindDir(dstRemote, false)
+		_, err = f.dirCache.FindDir(ctx, dstRemote, false)
 		if err == fs.ErrorDirNotFound {
 			// OK
 		} else if err != nil {
@@ -950,7 +954,7 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	}
 
 	// Find ID of src
-	srcID, err := srcFs.dirCache.FindDir(srcRemote, false)
+	srcID, err := srcFs.dirCache.FindDir(ctx, srcRemote, false)
 	if err != nil {
 		return err
 	}
@@ -960,8 +964,8 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 		Title:   leaf,
 		Parents: []*drive.ParentReference{{Id: directoryID}},
 	}
-	err = f.pacer.Call(func() (bool, error) {
-		_, err = f.svc.Files.Patch(srcID, &patch).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		_, err = f.svc.Files.Patch(srcID, &patch).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -979,6 +983,7 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 //
 // Close the returned channel to stop being notified.
 func (f *Fs) DirChangeNotify(notifyFunc func(string), pollInterval time.Duration) chan bool {
+	ctx := context.Background()
 	quit := make(chan bool)
 	go func() {
 		select {
@@ -986,7 +991,7 @@ func (f *Fs) DirChangeNotify(notifyFunc func(string), pollInterval time.Duration
 			return
 		default:
 			for {
-				f.dirchangeNotifyRunner(notifyFunc, pollInterval)
+				f.dirchangeNotifyRunner(ctx, notifyFunc, pollInterval)
 				fs.Debugf(f, "Notify listener service ran into issues, restarting shortly.")
 				time.Sleep(pollInterval)
 			}
@@ -995,15 +1000,15 @@ func (f *Fs) DirChangeNotify(notifyFunc func(string), pollInterval time.Duration
 	return quit
 }
 
-func (f *Fs) dirchangeNotifyRunner(notifyFunc func(string), pollInterval time.Duration) {
+func (f *Fs) dirchangeNotifyRunner(ctx context.Context, notifyFunc func(string), pollInterval time.Duration) {
 	var err error
 	var changeList *drive.ChangeList
 	var pageToken string
 	var largestChangeID int64
 
 	var startPageToken *drive.StartPageToken
-	err = f.pacer.Call(func() (bool, error) {
-		startPageToken, err = f.svc.Changes.GetStartPageToken().SupportsTeamDrives(f.isTeamDrive).Do()
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		startPageToken, err = f.svc.Changes.GetStartPageToken().SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -1014,7 +1019,7 @@ func (f *Fs) dirchangeNotifyRunner(notifyFunc func(string), pollInterval time.Du
 
 	for {
 		fs.Debugf(f, "Checking for changes on remote")
-		err = f.pacer.Call(func() (bool, error) {
+		err = f.pacer.Call(ctx, func() (bool, error) {
 			changesCall := f.svc.Changes.List().PageToken(pageToken).Fields(googleapi.Field("nextPageToken,largestChangeId,newStartPageToken,items(fileId,file/parents(id))"))
 			if largestChangeID != 0 {
 				changesCall = changesCall.StartChangeId(largestChangeID)
@@ -1022,7 +1027,7 @@ func (f *Fs) dirchangeNotifyRunner(notifyFunc func(string), pollInterval time.Du
 			if *driveListChunk > 0 {
 				changesCall = changesCall.MaxResults(*driveListChunk)
 			}
-			changeList, err = changesCall.SupportsTeamDrives(f.isTeamDrive).Do()
+			changeList, err = changesCall.SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
 			return shouldRetry(err)
 		})
 		if err != nil {
@@ -1071,6 +1076,48 @@ func (f *Fs) dirchangeNotifyRunner(notifyFunc func(string), pollInterval time.Du
 	}
 }
 
+// PublicLink shares the file or directory at remote with anyone who
+// has the link and returns the link
+//
+// Drive can't make links which expire.
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire time.Duration) (string, error) {
+	if expire != 0 {
+		return "", errors.New("can't make links which expire")
+	}
+	id, err := f.dirCache.FindDir(ctx, remote, false)
+	if err == fs.ErrorDirNotFound {
+		var o fs.Object
+		o, err = f.NewObject(ctx, remote)
+		if err != nil {
+			return "", err
+		}
+		id = o.(*Object).id
+	} else if err != nil {
+		return "", err
+	}
+	permission := &drive.Permission{
+		Role:     "reader",
+		Type:     "anyone",
+		WithLink: true,
+	}
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		_, err = f.svc.Permissions.Insert(id, permission).SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't share")
+	}
+	var info *drive.File
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		info, err = f.svc.Files.Get(id).Fields("alternateLink").SupportsTeamDrives(f.isTeamDrive).Context(ctx).Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't read link")
+	}
+	return info.AlternateLink, nil
+}
+
 // DirCacheFlush resets the directory cache - used in testing as an
 // optional interface
 func (f *Fs) DirCacheFlush() {
@@ -1115,7 +1162,7 @@ func (o *Object) Size() int64 {
 	if o.isDocument && o.bytes < 0 {
 		// If it is a google doc then we must HEAD it to see
 		// how big it is
-		_, res, err := o.httpResponse("HEAD", nil)
+		_, res, err := o.httpResponse(context.TODO(), "HEAD", nil)
 		if err != nil {
 			fs.Errorf(o, "Error reading size: %v", err)
 			return 0
@@ -1138,12 +1185,12 @@ func (o *Object) setMetaData(info *drive.File) {
 }
 
 // readMetaData gets the info if it hasn't already been fetched
-func (o *Object) readMetaData() (err error) {
+func (o *Object) readMetaData(ctx context.Context) (err error) {
 	if o.id != "" {
 		return nil
 	}
 
-	leaf, directoryID, err := o.fs.dirCache.FindRootAndPath(o.remote, false)
+	leaf, directoryID, err := o.fs.dirCache.FindRootAndPath(ctx, o.remote, false)
 	if err != nil {
 		if err == fs.ErrorDirNotFound {
 			return fs.ErrorObjectNotFound
@@ -1151,7 +1198,7 @@ func (o *Object) readMetaData() (err error) {
 		return err
 	}
 
-	found, err := o.fs.list(directoryID, leaf, false, true, false, func(item *drive.File) bool {
+	found, err := o.fs.list(ctx, directoryID, leaf, false, true, false, func(item *drive.File) bool {
 		if item.Title == leaf {
 			o.setMetaData(item)
 			return true
@@ -1173,7 +1220,7 @@ func (o *Object) readMetaData() (err error) {
 // It attempts to read the objects mtime and if that isn't present the
 // LastModified returned in the http headers
 func (o *Object) ModTime() time.Time {
-	err := o.readMetaData()
+	err := o.readMetaData(context.TODO())
 	if err != nil {
 		fs.Debugf(o, "Failed to read metadata: %v", err)
 		return time.Now()
@@ -1187,8 +1234,8 @@ func (o *Object) ModTime() time.Time {
 }
 
 // SetModTime sets the modification time of the drive fs object
-func (o *Object) SetModTime(modTime time.Time) error {
-	err := o.readMetaData()
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	err := o.readMetaData(ctx)
 	if err != nil {
 		return err
 	}
@@ -1198,8 +1245,8 @@ func (o *Object) SetModTime(modTime time.Time) error {
 	}
 	// Set modified date
 	var info *drive.File
-	err = o.fs.pacer.Call(func() (bool, error) {
-		info, err = o.fs.svc.Files.Update(o.id, updateInfo).SetModifiedDate(true).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Do()
+	err = o.fs.pacer.Call(ctx, func() (bool, error) {
+		info, err = o.fs.svc.Files.Update(o.id, updateInfo).SetModifiedDate(true).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Context(ctx).Do()
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -1217,7 +1264,7 @@ func (o *Object) Storable() bool {
 
 // httpResponse gets an http.Response object for the object o.url
 // using the method passed in
-func (o *Object) httpResponse(method string, options []fs.OpenOption) (req *http.Request, res *http.Response, err error) {
+func (o *Object) httpResponse(ctx context.Context, method string, options []fs.OpenOption) (req *http.Request, res *http.Response, err error) {
 	if o.url == "" {
 		return nil, nil, errors.New("forbidden to download - check sharing permission")
 	}
@@ -1226,8 +1273,8 @@ func (o *Object) httpResponse(method string, options []fs.OpenOption) (req *http
 		return req, nil, err
 	}
 	fs.OpenOptionAddHTTPHeaders(req.Header, options)
-	err = o.fs.pacer.Call(func() (bool, error) {
-		res, err = o.fs.client.Do(req)
+	err = o.fs.pacer.Call(ctx, func() (bool, error) {
+		res, err = ctxhttp.Do(ctx, o.fs.client, req)
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -1268,8 +1315,8 @@ func (file *openFile) Close() (err error) {
 var _ io.ReadCloser = &openFile{}
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
-	req, res, err := o.httpResponse("GET", options)
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	req, res, err := o.httpResponse(ctx, "GET", options)
 	if err != nil {
 		return nil, err
 	}
@@ -1293,7 +1340,7 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 // Copy the reader into the object updating modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
 	size := src.Size()
 	modTime := src.ModTime()
 	if o.isDocument {
@@ -1310,8 +1357,8 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	var info *drive.File
 	if size == 0 || size < int64(driveUploadCutoff) {
 		// Don't retry, return a retry error instead
-		err = o.fs.pacer.CallNoRetry(func() (bool, error) {
-			info, err = o.fs.svc.Files.Update(updateInfo.Id, updateInfo).SetModifiedDate(true).Media(in, googleapi.ContentType("")).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Do()
+		err = o.fs.pacer.CallNoRetry(ctx, func() (bool, error) {
+			info, err = o.fs.svc.Files.Update(updateInfo.Id, updateInfo).SetModifiedDate(true).Media(in, googleapi.ContentType("")).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Context(ctx).Do()
 			return shouldRetry(err)
 		})
 		if err != nil {
@@ -1319,7 +1366,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		}
 	} else {
 		// Upload the file in chunks
-		info, err = o.fs.Upload(in, size, updateInfo.MimeType, updateInfo, o.remote)
+		info, err = o.fs.Upload(ctx, in, size, updateInfo.MimeType, updateInfo, o.remote)
 		if err != nil {
 			return err
 		}
@@ -1329,16 +1376,16 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() error {
+func (o *Object) Remove(ctx context.Context) error {
 	if o.isDocument {
 		return errors.New("can't delete a google document")
 	}
 	var err error
-	err = o.fs.pacer.Call(func() (bool, error) {
+	err = o.fs.pacer.Call(ctx, func() (bool, error) {
 		if *driveUseTrash {
-			_, err = o.fs.svc.Files.Trash(o.id).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Do()
+			_, err = o.fs.svc.Files.Trash(o.id).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Context(ctx).Do()
 		} else {
-			err = o.fs.svc.Files.Delete(o.id).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Do()
+			err = o.fs.svc.Files.Delete(o.id).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(o.fs.isTeamDrive).Context(ctx).Do()
 		}
 		return shouldRetry(err)
 	})
@@ -1347,7 +1394,7 @@ func (o *Object) Remove() error {
 
 // MimeType of an Object if known, "" otherwise
 func (o *Object) MimeType() string {
-	err := o.readMetaData()
+	err := o.readMetaData(context.TODO())
 	if err != nil {
 		fs.Debugf(o, "Failed to read metadata: %v", err)
 		return ""
@@ -1365,6 +1412,7 @@ var (
 	_ fs.DirCacheFlusher   = (*Fs)(nil)
 	_ fs.DirChangeNotifier = (*Fs)(nil)
 	_ fs.PutUncheckeder    = (*Fs)(nil)
+	_ fs.PublicLinker      = (*Fs)(nil)
 	_ fs.Object            = (*Object)(nil)
 	_ fs.MimeTyper         = &Object{}
 )
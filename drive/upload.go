@@ -22,6 +22,8 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
 	"google.golang.org/api/drive/v2"
 	"google.golang.org/api/googleapi"
 )
@@ -52,7 +54,7 @@ type resumableUpload struct {
 }
 
 // Upload the io.Reader in of size bytes with contentType and info
-func (f *Fs) Upload(in io.Reader, size int64, contentType string, info *drive.File, remote string) (*drive.File, error) {
+func (f *Fs) Upload(ctx context.Context, in io.Reader, size int64, contentType string, info *drive.File, remote string) (*drive.File, error) {
 	fileID := info.Id
 	params := make(url.Values)
 	params.Set("alt", "json")
@@ -70,7 +72,7 @@ func (f *Fs) Upload(in io.Reader, size int64, contentType string, info *drive.Fi
 	urls += "?" + params.Encode()
 	var res *http.Response
 	var err error
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		var body io.Reader
 		body, err = googleapi.WithoutDataWrapper.JSONReader(info)
 		if err != nil {
@@ -87,7 +89,7 @@ func (f *Fs) Upload(in io.Reader, size int64, contentType string, info *drive.Fi
 		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
 		req.Header.Set("X-Upload-Content-Type", contentType)
 		req.Header.Set("X-Upload-Content-Length", fmt.Sprintf("%v", size))
-		res, err = f.client.Do(req)
+		res, err = ctxhttp.Do(ctx, f.client, req)
 		if err == nil {
 			defer googleapi.CloseBody(res)
 			err = googleapi.CheckResponse(res)
@@ -106,7 +108,7 @@ func (f *Fs) Upload(in io.Reader, size int64, contentType string, info *drive.Fi
 		MediaType:     contentType,
 		ContentLength: size,
 	}
-	return rx.Upload()
+	return rx.Upload(ctx)
 }
 
 // Make an http.Request for the range passed in
@@ -130,9 +132,9 @@ var rangeRE = regexp.MustCompile(`^0\-(\d+)$`)
 // Query drive for the amount transferred so far
 //
 // If error is nil, then start should be valid
-func (rx *resumableUpload) transferStatus() (start int64, err error) {
+func (rx *resumableUpload) transferStatus(ctx context.Context) (start int64, err error) {
 	req := rx.makeRequest(0, nil)
-	res, err := rx.f.client.Do(req)
+	res, err := ctxhttp.Do(ctx, rx.f.client, req)
 	if err != nil {
 		return 0, err
 	}
@@ -158,9 +160,9 @@ func (rx *resumableUpload) transferStatus() (start int64, err error) {
 }
 
 // Transfer a chunk - caller must call googleapi.CloseBody(res) if err == nil || res != nil
-func (rx *resumableUpload) transferChunk(start int64, body []byte) (int, error) {
+func (rx *resumableUpload) transferChunk(ctx context.Context, start int64, body []byte) (int, error) {
 	req := rx.makeRequest(start, body)
-	res, err := rx.f.client.Do(req)
+	res, err := ctxhttp.Do(ctx, rx.f.client, req)
 	if err != nil {
 		return 599, err
 	}
@@ -192,7 +194,7 @@ func (rx *resumableUpload) transferChunk(start int64, body []byte) (int, error)
 
 // Upload uploads the chunks from the input
 // It retries each chunk maxTries times (with a pause of uploadPause between attempts).
-func (rx *resumableUpload) Upload() (*drive.File, error) {
+func (rx *resumableUpload) Upload(ctx context.Context) (*drive.File, error) {
 	start := int64(0)
 	buf := make([]byte, chunkSize)
 	var StatusCode int
@@ -211,9 +213,9 @@ func (rx *resumableUpload) Upload() (*drive.File, error) {
 		}
 
 		// Transfer the chunk
-		err = rx.f.pacer.Call(func() (bool, error) {
+		err = rx.f.pacer.Call(ctx, func() (bool, error) {
 			fs.Debugf(rx.remote, "Sending chunk %d length %d", start, reqSize)
-			StatusCode, err = rx.transferChunk(start, buf)
+			StatusCode, err = rx.transferChunk(ctx, start, buf)
 			again, err := shouldRetry(err)
 			if StatusCode == statusResumeIncomplete || StatusCode == http.StatusCreated || StatusCode == http.StatusOK {
 				again = false
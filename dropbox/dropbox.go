@@ -32,10 +32,12 @@ import (
 
 	"github.com/ncw/dropbox-sdk-go-unofficial/dropbox"
 	"github.com/ncw/dropbox-sdk-go-unofficial/dropbox/files"
+	"github.com/ncw/dropbox-sdk-go-unofficial/dropbox/sharing"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/oauthutil"
 	"github.com/ncw/rclone/pacer"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 )
 
@@ -95,13 +97,14 @@ func init() {
 
 // Fs represents a remote dropbox server
 type Fs struct {
-	name           string       // name of this remote
-	root           string       // the path we are working on
-	features       *fs.Features // optional features
-	srv            files.Client // the connection to the dropbox server
-	slashRoot      string       // root with "/" prefix, lowercase
-	slashRootSlash string       // root with "/" prefix and postfix, lowercase
-	pacer          *pacer.Pacer // To pace the API calls
+	name           string         // name of this remote
+	root           string         // the path we are working on
+	features       *fs.Features   // optional features
+	srv            files.Client   // the connection to the dropbox server
+	sharing        sharing.Client // as above, but for generating sharing links
+	slashRoot      string         // root with "/" prefix, lowercase
+	slashRootSlash string         // root with "/" prefix and postfix, lowercase
+	pacer          *pacer.Pacer   // To pace the API calls
 }
 
 // Object describes a dropbox object
@@ -153,6 +156,7 @@ func shouldRetry(err error) (bool, error) {
 
 // NewFs contstructs an Fs from the path, container:path
 func NewFs(name, root string) (fs.Fs, error) {
+	ctx := context.Background()
 	if uploadChunkSize > maxUploadChunkSize {
 		return nil, errors.Errorf("chunk size too big, must be < %v", maxUploadChunkSize)
 	}
@@ -181,15 +185,16 @@ func NewFs(name, root string) (fs.Fs, error) {
 	srv := files.New(config)
 
 	f := &Fs{
-		name:  name,
-		srv:   srv,
-		pacer: pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+		name:    name,
+		srv:     srv,
+		sharing: sharing.New(config),
+		pacer:   pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
 	}
 	f.features = (&fs.Features{CaseInsensitive: true, ReadMimeType: true}).Fill(f)
 	f.setRoot(root)
 
 	// See if the root is actually an object
-	_, err = f.getFileMetadata(f.slashRoot)
+	_, err = f.getFileMetadata(ctx, f.slashRoot)
 	if err == nil {
 		newRoot := path.Dir(f.root)
 		if newRoot == "." {
@@ -215,8 +220,8 @@ func (f *Fs) setRoot(root string) {
 }
 
 // getMetadata gets the metadata for a file or directory
-func (f *Fs) getMetadata(objPath string) (entry files.IsMetadata, notFound bool, err error) {
-	err = f.pacer.Call(func() (bool, error) {
+func (f *Fs) getMetadata(ctx context.Context, objPath string) (entry files.IsMetadata, notFound bool, err error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		entry, err = f.srv.GetMetadata(&files.GetMetadataArg{Path: objPath})
 		return shouldRetry(err)
 	})
@@ -234,8 +239,8 @@ func (f *Fs) getMetadata(objPath string) (entry files.IsMetadata, notFound bool,
 }
 
 // getFileMetadata gets the metadata for a file
-func (f *Fs) getFileMetadata(filePath string) (fileInfo *files.FileMetadata, err error) {
-	entry, notFound, err := f.getMetadata(filePath)
+func (f *Fs) getFileMetadata(ctx context.Context, filePath string) (fileInfo *files.FileMetadata, err error) {
+	entry, notFound, err := f.getMetadata(ctx, filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -250,8 +255,8 @@ func (f *Fs) getFileMetadata(filePath string) (fileInfo *files.FileMetadata, err
 }
 
 // getDirMetadata gets the metadata for a directory
-func (f *Fs) getDirMetadata(dirPath string) (dirInfo *files.FolderMetadata, err error) {
-	entry, notFound, err := f.getMetadata(dirPath)
+func (f *Fs) getDirMetadata(ctx context.Context, dirPath string) (dirInfo *files.FolderMetadata, err error) {
+	entry, notFound, err := f.getMetadata(ctx, dirPath)
 	if err != nil {
 		return nil, err
 	}
@@ -268,7 +273,7 @@ func (f *Fs) getDirMetadata(dirPath string) (dirInfo *files.FolderMetadata, err
 // Return an Object from a path
 //
 // If it can't be found it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) newObjectWithInfo(remote string, info *files.FileMetadata) (fs.Object, error) {
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *files.FileMetadata) (fs.Object, error) {
 	o := &Object{
 		fs:     f,
 		remote: remote,
@@ -277,7 +282,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *files.FileMetadata) (fs.Obje
 	if info != nil {
 		err = o.setMetadataFromEntry(info)
 	} else {
-		err = o.readEntryAndSetMetadata()
+		err = o.readEntryAndSetMetadata(ctx)
 	}
 	if err != nil {
 		return nil, err
@@ -287,8 +292,8 @@ func (f *Fs) newObjectWithInfo(remote string, info *files.FileMetadata) (fs.Obje
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
-	return f.newObjectWithInfo(remote, nil)
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(ctx, remote, nil)
 }
 
 // Strips the root off path and returns it
@@ -323,7 +328,7 @@ func (f *Fs) stripRoot(path string) (string, error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	root := f.slashRoot
 	if dir != "" {
 		root += "/" + dir
@@ -340,7 +345,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 			if root == "/" {
 				arg.Path = "" // Specify root folder as empty string
 			}
-			err = f.pacer.Call(func() (bool, error) {
+			err = f.pacer.Call(ctx, func() (bool, error) {
 				res, err = f.srv.ListFolder(&arg)
 				return shouldRetry(err)
 			})
@@ -359,7 +364,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 			arg := files.ListFolderContinueArg{
 				Cursor: res.Cursor,
 			}
-			err = f.pacer.Call(func() (bool, error) {
+			err = f.pacer.Call(ctx, func() (bool, error) {
 				res, err = f.srv.ListFolderContinue(&arg)
 				return shouldRetry(err)
 			})
@@ -406,7 +411,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 				if err != nil {
 					return nil, err
 				}
-				o, err := f.newObjectWithInfo(path, fileInfo)
+				o, err := f.newObjectWithInfo(ctx, path, fileInfo)
 				if err != nil {
 					return nil, err
 				}
@@ -440,17 +445,17 @@ func (rc *readCloser) Close() error {
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	o := &Object{
 		fs:     f,
 		remote: src.Remote(),
 	}
-	return o, o.Update(in, src, options...)
+	return o, o.Update(ctx, in, src, options...)
 }
 
 // Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	root := path.Join(f.slashRoot, dir)
 
 	// can't create or run metadata on root
@@ -459,7 +464,7 @@ func (f *Fs) Mkdir(dir string) error {
 	}
 
 	// check directory doesn't exist
-	_, err := f.getDirMetadata(root)
+	_, err := f.getDirMetadata(ctx, root)
 	if err == nil {
 		return nil // directory exists already
 	} else if err != fs.ErrorDirNotFound {
@@ -470,7 +475,7 @@ func (f *Fs) Mkdir(dir string) error {
 	arg2 := files.CreateFolderArg{
 		Path: root,
 	}
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		_, err = f.srv.CreateFolder(&arg2)
 		return shouldRetry(err)
 	})
@@ -480,7 +485,7 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes the container
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	root := path.Join(f.slashRoot, dir)
 
 	// can't remove root
@@ -489,7 +494,7 @@ func (f *Fs) Rmdir(dir string) error {
 	}
 
 	// check directory exists
-	_, err := f.getDirMetadata(root)
+	_, err := f.getDirMetadata(ctx, root)
 	if err != nil {
 		return errors.Wrap(err, "Rmdir")
 	}
@@ -503,7 +508,7 @@ func (f *Fs) Rmdir(dir string) error {
 		arg.Path = "" // Specify root folder as empty string
 	}
 	var res *files.ListFolderResult
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		res, err = f.srv.ListFolder(&arg)
 		return shouldRetry(err)
 	})
@@ -515,7 +520,7 @@ func (f *Fs) Rmdir(dir string) error {
 	}
 
 	// remove it
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		_, err = f.srv.Delete(&files.DeleteArg{Path: root})
 		return shouldRetry(err)
 	})
@@ -536,7 +541,7 @@ func (f *Fs) Precision() time.Duration {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't copy - not same remote type")
@@ -555,7 +560,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	arg.ToPath = dstObj.remotePath()
 	var err error
 	var entry files.IsMetadata
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		entry, err = f.srv.Copy(&arg)
 		return shouldRetry(err)
 	})
@@ -576,14 +581,55 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	return dstObj, nil
 }
 
+// PublicLink makes a shared link to the file or directory at remote
+// which anyone with the link can use to read it
+//
+// Only paid Dropbox accounts can make links which expire so they
+// aren't supported.
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire time.Duration) (link string, err error) {
+	if expire != 0 {
+		return "", errors.New("can't make links which expire")
+	}
+	absPath := path.Join(f.slashRoot, remote)
+	var linkRes sharing.IsSharedLinkMetadata
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		linkRes, err = f.sharing.CreateSharedLinkWithSettings(sharing.NewCreateSharedLinkWithSettingsArg(absPath))
+		return shouldRetry(err)
+	})
+	if e, ok := err.(sharing.CreateSharedLinkWithSettingsAPIError); ok && e.EndpointError != nil && e.EndpointError.Tag == sharing.CreateSharedLinkWithSettingsErrorSharedLinkAlreadyExists {
+		// Read the link which already exists - create_shared_link
+		// returns it rather than making a new one
+		var pathLink *sharing.PathLinkMetadata
+		err = f.pacer.Call(ctx, func() (bool, error) {
+			pathLink, err = f.sharing.CreateSharedLink(sharing.NewCreateSharedLinkArg(absPath))
+			return shouldRetry(err)
+		})
+		if err == nil {
+			return pathLink.Url, nil
+		}
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't make shared link")
+	}
+	switch x := linkRes.(type) {
+	case *sharing.FileLinkMetadata:
+		link = x.Url
+	case *sharing.FolderLinkMetadata:
+		link = x.Url
+	default:
+		return "", errors.Errorf("unknown shared link type %T", linkRes)
+	}
+	return link, nil
+}
+
 // Purge deletes all the files and the container
 //
 // Optional interface: Only implement this if you have a way of
 // deleting all the files quicker than just running Remove() on the
 // result of List()
-func (f *Fs) Purge() (err error) {
+func (f *Fs) Purge(ctx context.Context) (err error) {
 	// Let dropbox delete the filesystem tree
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		_, err = f.srv.Delete(&files.DeleteArg{Path: f.slashRoot})
 		return shouldRetry(err)
 	})
@@ -599,7 +645,7 @@ func (f *Fs) Purge() (err error) {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantMove
-func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't move - not same remote type")
@@ -618,7 +664,7 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 	arg.ToPath = dstObj.remotePath()
 	var err error
 	var entry files.IsMetadata
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		entry, err = f.srv.Move(&arg)
 		return shouldRetry(err)
 	})
@@ -646,7 +692,7 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 // If it isn't possible then return fs.ErrorCantDirMove
 //
 // If destination exists then return fs.ErrorDirExists
-func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
 	srcFs, ok := src.(*Fs)
 	if !ok {
 		fs.Debugf(srcFs, "Can't move directory - not same remote type")
@@ -656,7 +702,7 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	dstPath := path.Join(f.slashRoot, dstRemote)
 
 	// Check if destination exists
-	_, err := f.getDirMetadata(f.slashRoot)
+	_, err := f.getDirMetadata(ctx, f.slashRoot)
 	if err == nil {
 		return fs.ErrorDirExists
 	} else if err != fs.ErrorDirNotFound {
@@ -670,7 +716,7 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	arg := files.RelocationArg{}
 	arg.FromPath = srcPath
 	arg.ToPath = dstPath
-	err = f.pacer.Call(func() (bool, error) {
+	err = f.pacer.Call(ctx, func() (bool, error) {
 		_, err = f.srv.Move(&arg)
 		return shouldRetry(err)
 	})
@@ -711,7 +757,7 @@ func (o *Object) Hash(t fs.HashType) (string, error) {
 	if t != fs.HashDropbox {
 		return "", fs.ErrHashUnsupported
 	}
-	err := o.readMetaData()
+	err := o.readMetaData(context.TODO())
 	if err != nil {
 		return "", errors.Wrap(err, "failed to read hash from metadata")
 	}
@@ -734,17 +780,17 @@ func (o *Object) setMetadataFromEntry(info *files.FileMetadata) error {
 }
 
 // Reads the entry for a file from dropbox
-func (o *Object) readEntry() (*files.FileMetadata, error) {
-	return o.fs.getFileMetadata(o.remotePath())
+func (o *Object) readEntry(ctx context.Context) (*files.FileMetadata, error) {
+	return o.fs.getFileMetadata(ctx, o.remotePath())
 }
 
 // Read entry if not set and set metadata from it
-func (o *Object) readEntryAndSetMetadata() error {
+func (o *Object) readEntryAndSetMetadata(ctx context.Context) error {
 	// Last resort set time from client
 	if !o.modTime.IsZero() {
 		return nil
 	}
-	entry, err := o.readEntry()
+	entry, err := o.readEntry(ctx)
 	if err != nil {
 		return err
 	}
@@ -771,12 +817,12 @@ func (o *Object) metadataKey() string {
 }
 
 // readMetaData gets the info if it hasn't already been fetched
-func (o *Object) readMetaData() (err error) {
+func (o *Object) readMetaData(ctx context.Context) (err error) {
 	if !o.modTime.IsZero() {
 		return nil
 	}
 	// Last resort
-	return o.readEntryAndSetMetadata()
+	return o.readEntryAndSetMetadata(ctx)
 }
 
 // ModTime returns the modification time of the object
@@ -784,7 +830,7 @@ func (o *Object) readMetaData() (err error) {
 // It attempts to read the objects mtime and if that isn't present the
 // LastModified returned in the http headers
 func (o *Object) ModTime() time.Time {
-	err := o.readMetaData()
+	err := o.readMetaData(context.TODO())
 	if err != nil {
 		fs.Debugf(o, "Failed to read metadata: %v", err)
 		return time.Now()
@@ -795,7 +841,7 @@ func (o *Object) ModTime() time.Time {
 // SetModTime sets the modification time of the local fs object
 //
 // Commits the datastore
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	// Dropbox doesn't have a way of doing this so returning this
 	// error will cause the file to be deleted first then
 	// re-uploaded to set the time.
@@ -808,10 +854,10 @@ func (o *Object) Storable() bool {
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	headers := fs.OpenOptionHeaders(options)
 	arg := files.DownloadArg{Path: o.remotePath(), ExtraHeaders: headers}
-	err = o.fs.pacer.Call(func() (bool, error) {
+	err = o.fs.pacer.Call(ctx, func() (bool, error) {
 		_, in, err = o.fs.srv.Download(&arg)
 		return shouldRetry(err)
 	})
@@ -832,14 +878,14 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 // Call only if size is >= uploadChunkSize
 //
 // FIXME buffer chunks to improve upload retries
-func (o *Object) uploadChunked(in io.Reader, commitInfo *files.CommitInfo, size int64) (entry *files.FileMetadata, err error) {
+func (o *Object) uploadChunked(ctx context.Context, in io.Reader, commitInfo *files.CommitInfo, size int64) (entry *files.FileMetadata, err error) {
 	chunkSize := int64(uploadChunkSize)
 	chunks := int(size/chunkSize) + 1
 
 	// write the first whole chunk
 	fs.Debugf(o, "Uploading chunk 1/%d", chunks)
 	var res *files.UploadSessionStartResult
-	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+	err = o.fs.pacer.CallNoRetry(ctx, func() (bool, error) {
 		res, err = o.fs.srv.UploadSessionStart(&files.UploadSessionStartArg{}, &io.LimitedReader{R: in, N: chunkSize})
 		return shouldRetry(err)
 	})
@@ -859,7 +905,7 @@ func (o *Object) uploadChunked(in io.Reader, commitInfo *files.CommitInfo, size
 	// write more whole chunks (if any)
 	for i := 2; i < chunks; i++ {
 		fs.Debugf(o, "Uploading chunk %d/%d", i, chunks)
-		err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		err = o.fs.pacer.CallNoRetry(ctx, func() (bool, error) {
 			err = o.fs.srv.UploadSessionAppendV2(&appendArg, &io.LimitedReader{R: in, N: chunkSize})
 			return shouldRetry(err)
 		})
@@ -875,7 +921,7 @@ func (o *Object) uploadChunked(in io.Reader, commitInfo *files.CommitInfo, size
 		Commit: commitInfo,
 	}
 	fs.Debugf(o, "Uploading chunk %d/%d", chunks, chunks)
-	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+	err = o.fs.pacer.CallNoRetry(ctx, func() (bool, error) {
 		entry, err = o.fs.srv.UploadSessionFinish(args, in)
 		return shouldRetry(err)
 	})
@@ -890,7 +936,7 @@ func (o *Object) uploadChunked(in io.Reader, commitInfo *files.CommitInfo, size
 // Copy the reader into the object updating modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
 	remote := o.remotePath()
 	if ignoredFiles.MatchString(remote) {
 		fs.Logf(o, "File name disallowed - not uploading")
@@ -905,9 +951,9 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	var err error
 	var entry *files.FileMetadata
 	if size > int64(uploadChunkSize) {
-		entry, err = o.uploadChunked(in, commitInfo, size)
+		entry, err = o.uploadChunked(ctx, in, commitInfo, size)
 	} else {
-		err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		err = o.fs.pacer.CallNoRetry(ctx, func() (bool, error) {
 			entry, err = o.fs.srv.Upload(commitInfo, in)
 			return shouldRetry(err)
 		})
@@ -919,8 +965,8 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() (err error) {
-	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+func (o *Object) Remove(ctx context.Context) (err error) {
+	err = o.fs.pacer.CallNoRetry(ctx, func() (bool, error) {
 		_, err = o.fs.srv.Delete(&files.DeleteArg{Path: o.remotePath()})
 		return shouldRetry(err)
 	})
@@ -929,10 +975,11 @@ func (o *Object) Remove() (err error) {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs       = (*Fs)(nil)
-	_ fs.Copier   = (*Fs)(nil)
-	_ fs.Purger   = (*Fs)(nil)
-	_ fs.Mover    = (*Fs)(nil)
-	_ fs.DirMover = (*Fs)(nil)
-	_ fs.Object   = (*Object)(nil)
+	_ fs.Fs           = (*Fs)(nil)
+	_ fs.Copier       = (*Fs)(nil)
+	_ fs.Purger       = (*Fs)(nil)
+	_ fs.Mover        = (*Fs)(nil)
+	_ fs.DirMover     = (*Fs)(nil)
+	_ fs.PublicLinker = (*Fs)(nil)
+	_ fs.Object       = (*Object)(nil)
 )
@@ -28,7 +28,7 @@ var (
 // Start the token bucket if necessary
 func startTokenBucket() {
 	currLimitMu.Lock()
-	currLimit := bwLimit.LimitAt(time.Now())
+	currLimit = bwLimit.LimitAt(time.Now())
 	currLimitMu.Unlock()
 
 	if currLimit.bandwidth > 0 {
@@ -55,7 +55,9 @@ func startTokenTicker() {
 			limitNow := bwLimit.LimitAt(time.Now())
 			currLimitMu.Lock()
 
-			if currLimit.bandwidth != limitNow.bandwidth {
+			// Only change the limit when moving into a new time
+			// slot so changes made with SetBwLimit persist until then
+			if currLimit.hhmm != limitNow.hhmm && currLimit.bandwidth != limitNow.bandwidth {
 				tokenBucketMu.Lock()
 				if tokenBucket != nil {
 					err := tokenBucket.Close()
@@ -76,11 +78,48 @@ func startTokenTicker() {
 				currLimit = limitNow
 				tokenBucketMu.Unlock()
 			}
+			currLimit.hhmm = limitNow.hhmm
 			currLimitMu.Unlock()
 		}
 	}()
 }
 
+// SetBwLimit sets the current bandwidth limit to bandwidth bytes/s
+// replacing any limit set by --bwlimit.  Pass 0 or a negative number
+// to remove the limit.
+//
+// The limit will be overridden by the next scheduled change if a
+// --bwlimit timetable is in use.
+func SetBwLimit(bandwidth SizeSuffix) {
+	currLimitMu.Lock()
+	defer currLimitMu.Unlock()
+	tokenBucketMu.Lock()
+	defer tokenBucketMu.Unlock()
+	if tokenBucket != nil {
+		err := tokenBucket.Close()
+		if err != nil {
+			Debugf(nil, "Error closing token bucket: %v", err)
+		}
+	}
+	if bandwidth > 0 {
+		tokenBucket = tb.NewBucket(int64(bandwidth), 100*time.Millisecond)
+		Logf(nil, "Bandwidth limit set to %vBytes/s", &bandwidth)
+	} else {
+		bandwidth = 0
+		tokenBucket = nil
+		Logf(nil, "Bandwidth limit disabled")
+	}
+	currLimit.bandwidth = bandwidth
+}
+
+// BwLimit returns the current bandwidth limit in bytes/s or 0 if
+// there isn't a limit
+func BwLimit() SizeSuffix {
+	currLimitMu.Lock()
+	defer currLimitMu.Unlock()
+	return currLimit.bandwidth
+}
+
 // stringSet holds a set of strings
 type stringSet map[string]struct{}
 
@@ -135,6 +174,16 @@ func (ss stringSet) Strings() []string {
 	return sorted
 }
 
+// names returns the names in the stringSet in sorted order
+func (ss stringSet) names() []string {
+	names := make([]string, 0, len(ss))
+	for name := range ss {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // String returns all the file names in the stringSet joined by newline
 func (ss stringSet) String() string {
 	return strings.Join(ss.Strings(), "\n")
@@ -201,6 +250,60 @@ Elapsed time:  %10v
 	return buf.String()
 }
 
+// RemoteStats returns the StatsInfo in a form suitable for
+// marshalling to JSON
+func (s *StatsInfo) RemoteStats() map[string]interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	dt := time.Now().Sub(s.start)
+	speed := 0.0
+	if dt > 0 {
+		speed = float64(s.bytes) / dt.Seconds()
+	}
+	out := map[string]interface{}{
+		"bytes":       s.bytes,
+		"errors":      s.errors,
+		"checks":      s.checks,
+		"transfers":   s.transfers,
+		"speed":       speed,
+		"elapsedTime": dt.Seconds(),
+	}
+	if len(s.checking) > 0 {
+		out["checking"] = s.checking.names()
+	}
+	if len(s.transferring) > 0 {
+		transferring := make([]map[string]interface{}, 0, len(s.transferring))
+		for _, name := range s.transferring.names() {
+			transferring = append(transferring, remoteStatsFor(name))
+		}
+		out["transferring"] = transferring
+	}
+	return out
+}
+
+// remoteStatsFor returns the stats for the in progress transfer name
+func remoteStatsFor(name string) map[string]interface{} {
+	out := map[string]interface{}{
+		"name": name,
+	}
+	acc := Stats.inProgress.get(name)
+	if acc == nil {
+		return out
+	}
+	bytes, size := acc.Progress()
+	_, speed := acc.Speed()
+	out["bytes"] = bytes
+	out["size"] = size
+	out["speed"] = speed
+	if size > 0 {
+		out["percentage"] = int(100 * float64(bytes) / float64(size))
+	}
+	if eta, ok := acc.ETA(); ok {
+		out["eta"] = eta.Seconds()
+	}
+	return out
+}
+
 // Log outputs the StatsInfo to the log
 func (s *StatsInfo) Log() {
 	Infof(nil, "%v\n", s)
@@ -381,13 +484,16 @@ func (acc *Account) StopBuffering() {
 	}
 }
 
-// UpdateReader updates the underlying io.ReadCloser
+// UpdateReader updates the underlying io.ReadCloser, buffering it if
+// the Account was buffered
 func (acc *Account) UpdateReader(in io.ReadCloser) {
 	acc.mu.Lock()
 	acc.StopBuffering()
 	acc.in = in
 	acc.origIn = in
-	acc.WithBuffer()
+	if acc.withBuf {
+		acc.WithBuffer()
+	}
 	acc.mu.Unlock()
 }
 
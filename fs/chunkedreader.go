@@ -0,0 +1,395 @@
+// Chunked reading of objects
+
+package fs
+
+import (
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// ErrorChunkedReaderClosed is returned when the ChunkedReader is used
+// after Close
+var ErrorChunkedReaderClosed = errors.New("chunked reader already closed")
+
+// readChunk is a range of an object which has been or is being read
+// into memory
+type readChunk struct {
+	ctx    context.Context // context for reading this chunk
+	cancel func()          // cancel the read when the chunk is discarded
+	start  int64           // offset in the object of the first byte
+	size   int64           // number of bytes requested
+	done   chan struct{}   // closed when the read has finished
+	data   []byte          // the data read - may be short at the end of the object
+	err    error           // any error reading the chunk
+}
+
+// end returns the offset one past the last byte requested
+func (c *readChunk) end() int64 {
+	return c.start + c.size
+}
+
+// contains returns true if offset is within the requested range of c
+func (c *readChunk) contains(offset int64) bool {
+	return offset >= c.start && offset < c.end()
+}
+
+// ChunkedReader reads an object in chunks using RangeOption requests
+// rather than opening it once and reading it from beginning to end.
+//
+// The chunks start at initialChunkSize and double in size each time
+// the reads carry on sequentially, up to maxChunkSize.  Up to streams
+// chunks are read in parallel ahead of the current position.  A seek
+// to a position outside the chunks already read resets the chunk
+// size, but seeking backwards into the previous chunk reuses the data
+// already read.
+//
+// A ChunkedReader shouldn't be used for Read or Seek from more than
+// one goroutine at once.
+type ChunkedReader struct {
+	ctx              context.Context
+	cancel           func()
+	o                Object
+	initialChunkSize int64
+	maxChunkSize     int64
+	streams          int
+	noRange          int32 // set atomically if the object ignores RangeOption
+
+	mu        sync.Mutex
+	closed    bool
+	offset    int64        // offset the next Read will read from
+	limit     int64        // offset to stop reading at or -1 for the end of the object
+	chunkSize int64        // size of the next chunk to be read
+	next      int64        // offset of the next chunk to be read
+	chunks    []*readChunk // chunks read or being read in order - the first may be behind offset
+}
+
+// NewChunkedReader returns a ChunkedReader for the object o.
+//
+// The first chunk read will be initialChunkSize bytes, doubling on
+// each sequential read up to maxChunkSize.  streams is the number of
+// chunks which will be read in parallel.
+func NewChunkedReader(ctx context.Context, o Object, initialChunkSize, maxChunkSize int64, streams int) *ChunkedReader {
+	if initialChunkSize <= 0 {
+		initialChunkSize = 1
+	}
+	if maxChunkSize < initialChunkSize {
+		maxChunkSize = initialChunkSize
+	}
+	if streams < 1 {
+		streams = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &ChunkedReader{
+		ctx:              ctx,
+		cancel:           cancel,
+		o:                o,
+		initialChunkSize: initialChunkSize,
+		maxChunkSize:     maxChunkSize,
+		streams:          streams,
+		limit:            -1,
+		chunkSize:        initialChunkSize,
+	}
+}
+
+// NewChunkedReaderFromConfig returns a ChunkedReader for the object o
+// using the chunk sizes and number of streams from the global config.
+//
+// It returns nil if chunked reading is disabled.
+func NewChunkedReaderFromConfig(ctx context.Context, o Object) *ChunkedReader {
+	if Config.ReadChunkSize <= 0 {
+		return nil
+	}
+	return NewChunkedReader(ctx, o, int64(Config.ReadChunkSize), int64(Config.ReadChunkSizeLimit), Config.ReadChunkStreams)
+}
+
+// end returns the offset at which reading should stop, or -1 if
+// unknown
+//
+// Call with the lock held
+func (cr *ChunkedReader) end() int64 {
+	end := cr.o.Size()
+	if cr.limit >= 0 && (end < 0 || cr.limit < end) {
+		end = cr.limit
+	}
+	return end
+}
+
+// fetch reads c from the object retrying on errors
+//
+// If the object returns more data than the range asked for then the
+// backend or server ignored the range.  In that case the data read
+// is thrown away and the object is read from the start from then on,
+// discarding the data before each chunk.
+func (cr *ChunkedReader) fetch(c *readChunk) {
+	defer close(c.done)
+	c.data = make([]byte, 0, c.size)
+	for tries := 0; ; tries++ {
+		start := c.start + int64(len(c.data))
+		noRange := atomic.LoadInt32(&cr.noRange) != 0
+		var options []OpenOption
+		if !noRange {
+			options = append(options, &RangeOption{Start: start, End: c.end() - 1})
+		}
+		rangeIgnored := false
+		var in io.ReadCloser
+		in, c.err = cr.o.Open(c.ctx, options...)
+		if c.err == nil {
+			if noRange {
+				_, c.err = io.CopyN(ioutil.Discard, in, start)
+			}
+			if c.err == nil {
+				var n int
+				n, c.err = io.ReadFull(in, c.data[len(c.data):c.size])
+				if c.err == nil && !noRange && start > 0 {
+					// Check there is no more data than
+					// was asked for
+					var probe [1]byte
+					if extra, _ := io.ReadFull(in, probe[:]); extra > 0 {
+						rangeIgnored = true
+						n = 0
+					}
+				}
+				c.data = c.data[:len(c.data)+n]
+			}
+			if c.err == io.ErrUnexpectedEOF || c.err == io.EOF {
+				// A short read means we have reached the
+				// end of the object
+				c.err = nil
+			}
+			closeErr := in.Close()
+			if closeErr != nil {
+				Debugf(cr.o, "ChunkedReader: ignoring error on close: %v", closeErr)
+			}
+		}
+		if rangeIgnored {
+			Debugf(cr.o, "ChunkedReader: range request ignored - reading from the start of the object")
+			atomic.StoreInt32(&cr.noRange, 1)
+			tries--
+			continue
+		}
+		if c.err == nil || tries >= Config.LowLevelRetries || c.ctx.Err() != nil {
+			return
+		}
+		Debugf(cr.o, "ChunkedReader: low level retry %d/%d reading from %d: %v", tries+1, Config.LowLevelRetries, start, c.err)
+	}
+}
+
+// fill starts reads of chunks so that there are streams chunks at
+// or ahead of the current offset
+//
+// Call with the lock held
+func (cr *ChunkedReader) fill() {
+	end := cr.end()
+	ahead := 0
+	for _, c := range cr.chunks {
+		if c.end() > cr.offset {
+			ahead++
+		}
+	}
+	for ; ahead < cr.streams; ahead++ {
+		if end >= 0 && cr.next >= end {
+			return
+		}
+		size := cr.chunkSize
+		if end >= 0 && cr.next+size > end {
+			size = end - cr.next
+		}
+		ctx, cancel := context.WithCancel(cr.ctx)
+		c := &readChunk{
+			ctx:    ctx,
+			cancel: cancel,
+			start:  cr.next,
+			size:   size,
+			done:   make(chan struct{}),
+		}
+		cr.chunks = append(cr.chunks, c)
+		cr.next += size
+		cr.chunkSize *= 2
+		if cr.chunkSize > cr.maxChunkSize {
+			cr.chunkSize = cr.maxChunkSize
+		}
+		go cr.fetch(c)
+	}
+}
+
+// find returns the chunk containing offset discarding any chunks
+// which can't be used and cancelling their reads.  It returns nil if
+// there isn't one.
+//
+// Call with the lock held
+func (cr *ChunkedReader) find() *readChunk {
+	for i, c := range cr.chunks {
+		if c.contains(cr.offset) {
+			// Keep the chunk before this one for small
+			// backwards seeks
+			if i > 1 {
+				cancelChunks(cr.chunks[:i-1])
+				cr.chunks = cr.chunks[i-1:]
+			}
+			return c
+		}
+	}
+	if cr.offset == cr.next {
+		// Reading sequentially so carry on with the next chunk
+		return nil
+	}
+	// Not found so start reading again from the current offset
+	// with a small chunk
+	if len(cr.chunks) > 0 {
+		Debugf(cr.o, "ChunkedReader: seek to %d outside chunks read - restarting", cr.offset)
+	}
+	cr.reset()
+	return nil
+}
+
+// cancelChunks cancels any reads of chunks still in progress
+func cancelChunks(chunks []*readChunk) {
+	for _, c := range chunks {
+		c.cancel()
+	}
+}
+
+// reset forgets the chunks read, cancelling any reads in progress, so
+// the next read starts from the current offset with the initial chunk
+// size
+//
+// Call with the lock held
+func (cr *ChunkedReader) reset() {
+	cancelChunks(cr.chunks)
+	cr.chunks = nil
+	cr.next = cr.offset
+	cr.chunkSize = cr.initialChunkSize
+}
+
+// Read reads up to len(p) bytes into p
+func (cr *ChunkedReader) Read(p []byte) (n int, err error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for {
+		if cr.closed {
+			return 0, ErrorChunkedReaderClosed
+		}
+		if end := cr.end(); end >= 0 && cr.offset >= end {
+			return 0, io.EOF
+		}
+		if len(p) == 0 {
+			return 0, nil
+		}
+		c := cr.find()
+		cr.fill()
+		if c == nil {
+			c = cr.find()
+			if c == nil {
+				return 0, io.EOF
+			}
+		}
+
+		// Wait for the chunk without the lock held so Close
+		// can interrupt it
+		cr.mu.Unlock()
+		<-c.done
+		cr.mu.Lock()
+
+		if cr.closed {
+			return 0, ErrorChunkedReaderClosed
+		}
+		if c.err != nil && c.ctx.Err() != nil && cr.ctx.Err() == nil {
+			// The chunk was discarded while waiting so try
+			// again
+			continue
+		}
+		if c.err != nil {
+			// Forget the chunks so the read is retried next time
+			cr.reset()
+			return 0, c.err
+		}
+		if !c.contains(cr.offset) {
+			// Seeked while waiting so try again
+			continue
+		}
+		i := cr.offset - c.start
+		if i >= int64(len(c.data)) {
+			// The object was shorter than expected
+			return 0, io.EOF
+		}
+		n = copy(p, c.data[i:])
+		cr.offset += int64(n)
+		return n, nil
+	}
+}
+
+// Seek sets the offset for the next Read.  Only io.SeekStart and
+// io.SeekCurrent are supported, as well as io.SeekEnd if the size
+// of the object is known.
+func (cr *ChunkedReader) Seek(offset int64, whence int) (int64, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.closed {
+		return 0, ErrorChunkedReaderClosed
+	}
+	switch whence {
+	case 0: // io.SeekStart
+	case 1: // io.SeekCurrent
+		offset += cr.offset
+	case 2: // io.SeekEnd
+		size := cr.o.Size()
+		if size < 0 {
+			return 0, errors.New("ChunkedReader: can't seek from end of object of unknown size")
+		}
+		offset += size
+	default:
+		return 0, errors.Errorf("ChunkedReader: unknown whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("ChunkedReader: negative position")
+	}
+	cr.offset = offset
+	return offset, nil
+}
+
+// RangeSeek seeks to offset and limits the reads to length bytes from
+// there.  If length is < 0 then reads continue to the end of the
+// object.  No data beyond the limit will be requested from the
+// object.
+func (cr *ChunkedReader) RangeSeek(offset, length int64) (int64, error) {
+	offset, err := cr.Seek(offset, 0)
+	if err != nil {
+		return offset, err
+	}
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if length >= 0 {
+		cr.limit = offset + length
+	} else {
+		cr.limit = -1
+	}
+	// Discard any reads which go beyond the limit
+	if cr.limit >= 0 && cr.next > cr.limit {
+		cr.reset()
+	}
+	return offset, nil
+}
+
+// Close the reader cancelling any reads in progress
+func (cr *ChunkedReader) Close() error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.closed {
+		return ErrorChunkedReaderClosed
+	}
+	cr.closed = true
+	cr.chunks = nil
+	cr.cancel()
+	return nil
+}
+
+// Check interfaces
+var (
+	_ io.ReadCloser = (*ChunkedReader)(nil)
+	_ io.Seeker     = (*ChunkedReader)(nil)
+)
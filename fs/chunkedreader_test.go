@@ -0,0 +1,265 @@
+package fs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// rangeObject is an Object which serves ranges of data and records
+// the ranges asked for
+type rangeObject struct {
+	Object
+	data []byte
+
+	mu          sync.Mutex
+	ranges      []RangeOption
+	contexts    map[int64]context.Context // context of the last Open by range start
+	errors      int                       // number of errors to return from Open
+	ignoreRange bool                      // if set return all the data like a server ignoring Range
+}
+
+func (o *rangeObject) Size() int64 {
+	return int64(len(o.data))
+}
+
+func (o *rangeObject) Remote() string {
+	return "rangeObject"
+}
+
+func (o *rangeObject) String() string {
+	return o.Remote()
+}
+
+func (o *rangeObject) Open(ctx context.Context, options ...OpenOption) (io.ReadCloser, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.errors > 0 {
+		o.errors--
+		return nil, errors.New("test error")
+	}
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		if x, ok := option.(*RangeOption); ok {
+			o.ranges = append(o.ranges, *x)
+			o.contexts[x.Start] = ctx
+			if !o.ignoreRange {
+				offset, limit = x.Decode(o.Size())
+			}
+		}
+	}
+	data := o.data[offset:]
+	if limit >= 0 && limit < int64(len(data)) {
+		data = data[:limit]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// getRanges returns the ranges requested so far and resets them
+func (o *rangeObject) getRanges() []RangeOption {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ranges := o.ranges
+	o.ranges = nil
+	return ranges
+}
+
+func newRangeObject(size int) *rangeObject {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return &rangeObject{data: data, contexts: make(map[int64]context.Context)}
+}
+
+func TestChunkedReaderSequential(t *testing.T) {
+	o := newRangeObject(1000)
+	cr := NewChunkedReader(context.Background(), o, 10, 100, 1)
+	data, err := ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, o.data, data)
+	require.NoError(t, cr.Close())
+	assert.Equal(t, ErrorChunkedReaderClosed, cr.Close())
+
+	// Check the chunks grew and were limited
+	ranges := o.getRanges()
+	require.True(t, len(ranges) > 4)
+	assert.Equal(t, RangeOption{Start: 0, End: 9}, ranges[0])
+	assert.Equal(t, RangeOption{Start: 10, End: 29}, ranges[1])
+	assert.Equal(t, RangeOption{Start: 30, End: 69}, ranges[2])
+	assert.Equal(t, RangeOption{Start: 70, End: 149}, ranges[3])
+	assert.Equal(t, RangeOption{Start: 150, End: 249}, ranges[4])
+	assert.Equal(t, RangeOption{Start: 950, End: 999}, ranges[len(ranges)-1])
+}
+
+func TestChunkedReaderParallel(t *testing.T) {
+	o := newRangeObject(100000)
+	for _, streams := range []int{2, 4, 8} {
+		cr := NewChunkedReader(context.Background(), o, 100, 1000, streams)
+		data, err := ioutil.ReadAll(cr)
+		require.NoError(t, err)
+		assert.Equal(t, o.data, data)
+		require.NoError(t, cr.Close())
+	}
+}
+
+func TestChunkedReaderSeek(t *testing.T) {
+	o := newRangeObject(1000)
+	cr := NewChunkedReader(context.Background(), o, 100, 100, 1)
+	buf := make([]byte, 50)
+
+	read := func(offset int64) {
+		pos, err := cr.Seek(offset, 0)
+		require.NoError(t, err)
+		assert.Equal(t, offset, pos)
+		n, err := io.ReadFull(cr, buf)
+		require.NoError(t, err)
+		assert.Equal(t, o.data[offset:offset+int64(n)], buf[:n])
+	}
+
+	read(0)
+	assert.Equal(t, []RangeOption{{Start: 0, End: 99}}, o.getRanges())
+	read(50)
+	assert.Equal(t, []RangeOption(nil), o.getRanges())
+
+	// Read into the next chunk then seek back into the previous
+	// chunk which should be reused
+	read(100)
+	assert.Equal(t, []RangeOption{{Start: 100, End: 199}}, o.getRanges())
+	read(20)
+	assert.Equal(t, []RangeOption(nil), o.getRanges())
+
+	// A seek a long way should read new chunks
+	read(700)
+	assert.Equal(t, []RangeOption{{Start: 700, End: 799}}, o.getRanges())
+
+	// Seek relative to the current position and the end
+	pos, err := cr.Seek(10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(760), pos)
+	pos, err = cr.Seek(-10, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(990), pos)
+	data, err := ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, o.data[990:], data)
+
+	// Seeking past the end gives EOF
+	_, err = cr.Seek(2000, 0)
+	require.NoError(t, err)
+	n, err := cr.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = cr.Seek(-1, 0)
+	assert.Error(t, err)
+
+	require.NoError(t, cr.Close())
+	_, err = cr.Read(buf)
+	assert.Equal(t, ErrorChunkedReaderClosed, err)
+}
+
+func TestChunkedReaderRangeSeek(t *testing.T) {
+	o := newRangeObject(1000)
+	cr := NewChunkedReader(context.Background(), o, 100, 1000, 4)
+	_, err := cr.RangeSeek(250, 120)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, o.data[250:370], data)
+	// Nothing should be read beyond the range
+	for _, r := range o.getRanges() {
+		assert.True(t, r.End < 370, r.String())
+	}
+	require.NoError(t, cr.Close())
+}
+
+func TestChunkedReaderCancelsDiscardedChunks(t *testing.T) {
+	o := newRangeObject(1000)
+	cr := NewChunkedReader(context.Background(), o, 100, 100, 4)
+	buf := make([]byte, 10)
+	read := func(offset int64) {
+		_, err := cr.Seek(offset, 0)
+		require.NoError(t, err)
+		_, err = io.ReadFull(cr, buf)
+		require.NoError(t, err)
+	}
+	cancelled := func(start int64) bool {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		ctx := o.contexts[start]
+		require.NotNil(t, ctx, "no read from %d", start)
+		return ctx.Err() != nil
+	}
+
+	// Reading on discards the chunks more than one behind
+	read(0)
+	read(250)
+	assert.True(t, cancelled(0))
+	assert.False(t, cancelled(100))
+	assert.False(t, cancelled(200))
+
+	// Seeking outside the chunks discards all of them
+	read(900)
+	assert.True(t, cancelled(100))
+	assert.True(t, cancelled(200))
+	assert.False(t, cancelled(900))
+
+	require.NoError(t, cr.Close())
+	assert.True(t, cancelled(900))
+}
+
+func TestChunkedReaderRetries(t *testing.T) {
+	oldLowLevelRetries := Config.LowLevelRetries
+	Config.LowLevelRetries = 2
+	defer func() {
+		Config.LowLevelRetries = oldLowLevelRetries
+	}()
+
+	// Recovers from errors within the low level retries
+	o := newRangeObject(100)
+	o.errors = 2
+	cr := NewChunkedReader(context.Background(), o, 1000, 1000, 1)
+	data, err := ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, o.data, data)
+	require.NoError(t, cr.Close())
+
+	// Returns the error if too many, but can be read again
+	o.errors = 3
+	cr = NewChunkedReader(context.Background(), o, 1000, 1000, 1)
+	buf := make([]byte, 10)
+	_, err = cr.Read(buf)
+	assert.EqualError(t, err, "test error")
+	data, err = ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, o.data, data)
+	require.NoError(t, cr.Close())
+}
+
+func TestChunkedReaderIgnoredRange(t *testing.T) {
+	o := newRangeObject(1000)
+	o.ignoreRange = true
+	cr := NewChunkedReader(context.Background(), o, 100, 100, 2)
+	data, err := ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, o.data, data)
+
+	// Once detected no more ranges are asked for
+	o.getRanges()
+	_, err = cr.Seek(500, 0)
+	require.NoError(t, err)
+	buf := make([]byte, 10)
+	_, err = io.ReadFull(cr, buf)
+	require.NoError(t, err)
+	assert.Equal(t, o.data[500:510], buf)
+	assert.Equal(t, 0, len(o.getRanges()))
+	require.NoError(t, cr.Close())
+}
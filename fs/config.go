@@ -7,9 +7,11 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/md5"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -60,6 +62,8 @@ var (
 	configData *goconfig.ConfigFile
 	// ConfigPath points to the config file
 	ConfigPath = makeConfigPath()
+	// CacheDir points to the directory used for caches
+	CacheDir = makeCacheDir()
 	// Config is the global config
 	Config = &ConfigInfo{}
 	// Flags
@@ -70,6 +74,7 @@ var (
 	checkers        = IntP("checkers", "", 8, "Number of checkers to run in parallel.")
 	transfers       = IntP("transfers", "", 4, "Number of file transfers to run in parallel.")
 	configFile      = StringP("config", "", ConfigPath, "Config file.")
+	cacheDir        = StringP("cache-dir", "", CacheDir, "Directory rclone will use for caching.")
 	checkSum        = BoolP("checksum", "c", false, "Skip based on checksum & size, not mod-time & size")
 	sizeOnly        = BoolP("size-only", "", false, "Skip based on size only, not mod-time or checksum")
 	ignoreTimes     = BoolP("ignore-times", "I", false, "Don't skip files that match size and time - transfer all files")
@@ -105,9 +110,18 @@ var (
 	configKey []byte
 )
 
+// Flags for reading files in chunks
+var (
+	readChunkSize      SizeSuffix = 1 << 20
+	readChunkSizeLimit SizeSuffix = 16 << 20
+	readChunkStreams              = IntP("read-chunk-streams", "", 2, "Number of chunks to read in parallel when reading files in chunks.")
+)
+
 func init() {
 	VarP(&bwLimit, "bwlimit", "", "Bandwidth limit in kBytes/s, or use suffix b|k|M|G or a full timetable.")
 	VarP(&bufferSize, "buffer-size", "", "Buffer size when copying files.")
+	VarP(&readChunkSize, "read-chunk-size", "", "Initial chunk size when mount and cat read files in chunks. 0 to disable.")
+	VarP(&readChunkSizeLimit, "read-chunk-size-limit", "", "Max chunk size when mount and cat read files in chunks.")
 }
 
 // crypt internals
@@ -224,6 +238,9 @@ type ConfigInfo struct {
 	Suffix             string
 	UseListR           bool
 	BufferSize         SizeSuffix
+	ReadChunkSize      SizeSuffix // initial size of chunks read or 0 to disable
+	ReadChunkSizeLimit SizeSuffix // chunks double in size up to this limit
+	ReadChunkStreams   int        // number of chunks to read in parallel
 }
 
 // Return the path to the configuration file
@@ -294,6 +311,43 @@ func makeConfigPath() string {
 	return hiddenConfigFileName
 }
 
+// Return the path to the directory used for caches
+func makeCacheDir() string {
+	// Use the XDG cache directory if set, see the XDG Base
+	// Directory specification
+	// https://specifications.freedesktop.org/basedir-spec/latest/
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		usr, err := user.Current()
+		if err == nil && usr.HomeDir != "" {
+			cacheHome = filepath.Join(usr.HomeDir, ".cache")
+		} else if homedir := os.Getenv("HOME"); homedir != "" {
+			cacheHome = filepath.Join(homedir, ".cache")
+		} else {
+			cacheHome = os.TempDir()
+		}
+	}
+	return filepath.Join(cacheHome, "rclone")
+}
+
+// unsafeChars are the characters which can't be used in file names
+// on some operating systems
+const unsafeChars = `<>:"/\|?*`
+
+// CacheName returns a name for the remote called name which can be
+// used as a file or directory name in CacheDir.
+//
+// Names which can't be used as file names, such as those of inline
+// remotes like ":s3,region=eu-west-1" which may contain credentials,
+// are replaced by their MD5.
+func CacheName(name string) string {
+	if name == "" || strings.ContainsAny(name, unsafeChars) || strings.HasPrefix(name, ".") {
+		sum := md5.Sum([]byte(name))
+		name = hex.EncodeToString(sum[:])
+	}
+	return name
+}
+
 // DeleteMode describes the possible delete modes in the config
 type DeleteMode byte
 
@@ -371,8 +425,12 @@ func LoadConfig() {
 	Config.Suffix = *suffix
 	Config.UseListR = *useListR
 	Config.BufferSize = bufferSize
+	Config.ReadChunkSize = readChunkSize
+	Config.ReadChunkSizeLimit = readChunkSizeLimit
+	Config.ReadChunkStreams = *readChunkStreams
 
 	ConfigPath = *configFile
+	CacheDir = *cacheDir
 
 	Config.TrackRenames = *trackRenames
 
@@ -671,7 +729,14 @@ func SaveConfig() {
 // ConfigSetValueAndSave sets the key to the value and saves just that
 // value in the config file.  It loads the old config file in from
 // disk first and overwrites the given value only.
+//
+// Inline remotes aren't in the config file so the value is just set
+// in memory.
 func ConfigSetValueAndSave(name, key, value string) (err error) {
+	if isInlineRemote(name) {
+		inlineConfigSet(name, key, value)
+		return nil
+	}
 	// Set the value in config in case we fail to reload it
 	configData.SetValue(name, key, value)
 	// Reload the config file
@@ -1168,6 +1233,15 @@ func configToEnv(section, name string) string {
 //
 // It looks up defaults in the environment if they are present
 func ConfigFileGet(section, key string, defaultVal ...string) string {
+	if isInlineRemote(section) {
+		if value, found := inlineConfigGet(section, key); found {
+			return value
+		}
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		}
+		return ""
+	}
 	envKey := configToEnv(section, key)
 	newValue, found := os.LookupEnv(envKey)
 	if found {
@@ -1183,6 +1257,9 @@ func ConfigFileGet(section, key string, defaultVal ...string) string {
 func ConfigFileGetBool(section, key string, defaultVal ...bool) bool {
 	envKey := configToEnv(section, key)
 	newValue, found := os.LookupEnv(envKey)
+	if isInlineRemote(section) {
+		newValue, found = inlineConfigGet(section, key)
+	}
 	if found {
 		newBool, err := strconv.ParseBool(newValue)
 		if err != nil {
@@ -1201,6 +1278,9 @@ func ConfigFileGetBool(section, key string, defaultVal ...bool) bool {
 func ConfigFileGetInt(section, key string, defaultVal ...int) int {
 	envKey := configToEnv(section, key)
 	newValue, found := os.LookupEnv(envKey)
+	if isInlineRemote(section) {
+		newValue, found = inlineConfigGet(section, key)
+	}
 	if found {
 		newInt, err := strconv.Atoi(newValue)
 		if err != nil {
@@ -1215,6 +1295,10 @@ func ConfigFileGetInt(section, key string, defaultVal ...int) int {
 // ConfigFileSet sets the key in section to value.  It doesn't save
 // the config file.
 func ConfigFileSet(section, key, value string) {
+	if isInlineRemote(section) {
+		inlineConfigSet(section, key, value)
+		return
+	}
 	configData.SetValue(section, key, value)
 }
 
@@ -1222,6 +1306,9 @@ func ConfigFileSet(section, key, value string) {
 // It returns true if the key was deleted,
 // or returns false if the section or key didn't exist.
 func ConfigFileDeleteKey(section, key string) bool {
+	if isInlineRemote(section) {
+		return inlineConfigDeleteKey(section, key)
+	}
 	return configData.DeleteKey(section, key)
 }
 
@@ -166,3 +166,14 @@ func hashedKeyCompare(t *testing.T, a, b string, shouldMatch bool) {
 		assert.NotEqual(t, k1, k2)
 	}
 }
+
+func TestCacheName(t *testing.T) {
+	assert.Equal(t, "remote", CacheName("remote"))
+	assert.Equal(t, "my remote-2", CacheName("my remote-2"))
+	for _, name := range []string{":s3,region=eu-west-1,secret_access_key=X", `a\b`, "a/b", "", ".."} {
+		got := CacheName(name)
+		assert.Len(t, got, 32, name)
+		assert.NotContains(t, got, "secret", name)
+	}
+	assert.NotEqual(t, CacheName(":s3,region=a"), CacheName(":s3,region=b"))
+}
@@ -8,9 +8,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Constants
@@ -112,14 +114,17 @@ type ListFser interface {
 	//
 	// This should return ErrDirNotFound if the directory isn't
 	// found.
-	List(dir string) (entries DirEntries, err error)
+	List(ctx context.Context, dir string) (entries DirEntries, err error)
 
 	// NewObject finds the Object at remote.  If it can't be found
 	// it returns the error ErrorObjectNotFound.
-	NewObject(remote string) (Object, error)
+	NewObject(ctx context.Context, remote string) (Object, error)
 }
 
 // Fs is the interface a cloud storage system must provide
+//
+// Methods which take a context.Context should give up as soon as
+// possible once the context is cancelled, returning an error.
 type Fs interface {
 	Info
 	ListFser
@@ -129,17 +134,17 @@ type Fs interface {
 	// May create the object even if it returns an error - if so
 	// will return the object and the error, otherwise will return
 	// nil and the error
-	Put(in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error)
+	Put(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error)
 
 	// Mkdir makes the directory (container, bucket)
 	//
 	// Shouldn't return an error if it already exists
-	Mkdir(dir string) error
+	Mkdir(ctx context.Context, dir string) error
 
 	// Rmdir removes the directory (container, bucket) if empty
 	//
 	// Return an error if it doesn't exist or isn't empty
-	Rmdir(dir string) error
+	Rmdir(ctx context.Context, dir string) error
 }
 
 // Info provides an interface to reading information about a filesystem.
@@ -168,16 +173,16 @@ type Object interface {
 	ObjectInfo
 
 	// SetModTime sets the metadata on the object to set the modification date
-	SetModTime(time.Time) error
+	SetModTime(ctx context.Context, t time.Time) error
 
 	// Open opens the file for read.  Call Close() on the returned io.ReadCloser
-	Open(options ...OpenOption) (io.ReadCloser, error)
+	Open(ctx context.Context, options ...OpenOption) (io.ReadCloser, error)
 
 	// Update in to the object with the modTime given of the given size
-	Update(in io.Reader, src ObjectInfo, options ...OpenOption) error
+	Update(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) error
 
 	// Removes this object
-	Remove() error
+	Remove(ctx context.Context) error
 }
 
 // ObjectInfo contains information about an object.
@@ -226,7 +231,7 @@ type MimeTyper interface {
 type ListRCallback func(entries DirEntries) error
 
 // ListRFn is defines the call used to recursively list a directory
-type ListRFn func(dir string, callback ListRCallback) error
+type ListRFn func(ctx context.Context, dir string, callback ListRCallback) error
 
 // Features describe the optional features of the Fs
 type Features struct {
@@ -242,7 +247,7 @@ type Features struct {
 	// quicker than just running Remove() on the result of List()
 	//
 	// Return an error if it doesn't exist
-	Purge func() error
+	Purge func(ctx context.Context) error
 
 	// Copy src to this remote using server side copy operations.
 	//
@@ -253,7 +258,7 @@ type Features struct {
 	// Will only be called if src.Fs().Name() == f.Name()
 	//
 	// If it isn't possible then return fs.ErrorCantCopy
-	Copy func(src Object, remote string) (Object, error)
+	Copy func(ctx context.Context, src Object, remote string) (Object, error)
 
 	// Move src to this remote using server side move operations.
 	//
@@ -264,7 +269,7 @@ type Features struct {
 	// Will only be called if src.Fs().Name() == f.Name()
 	//
 	// If it isn't possible then return fs.ErrorCantMove
-	Move func(src Object, remote string) (Object, error)
+	Move func(ctx context.Context, src Object, remote string) (Object, error)
 
 	// DirMove moves src, srcRemote to this remote at dstRemote
 	// using server side move operations.
@@ -274,7 +279,7 @@ type Features struct {
 	// If it isn't possible then return fs.ErrorCantDirMove
 	//
 	// If destination exists then return fs.ErrorDirExists
-	DirMove func(src Fs, srcRemote, dstRemote string) error
+	DirMove func(ctx context.Context, src Fs, srcRemote, dstRemote string) error
 
 	// DirChangeNotify calls the passed function with a path
 	// of a directory that has had changes. If the implementation
@@ -296,13 +301,13 @@ type Features struct {
 	//
 	// May create duplicates or return errors if src already
 	// exists.
-	PutUnchecked func(in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error)
+	PutUnchecked func(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error)
 
 	// CleanUp the trash in the Fs
 	//
 	// Implement this if you have a way of emptying the trash or
 	// otherwise cleaning up old versions of files.
-	CleanUp func() error
+	CleanUp func(ctx context.Context) error
 
 	// ListR lists the objects and directories of the Fs starting
 	// from dir recursively into out.
@@ -321,6 +326,17 @@ type Features struct {
 	// Don't implement this unless you have a more efficient way
 	// of listing recursively that doing a directory traversal.
 	ListR ListRFn
+
+	// About gets quota information from the Fs
+	About func(ctx context.Context) (*Usage, error)
+
+	// PublicLink generates a public link to the remote path
+	// which anyone can use to read it.
+	//
+	// If expire is non zero the link should stop working after
+	// that time.  If the remote can't make links which expire
+	// then it should return an error if expire is set.
+	PublicLink func(ctx context.Context, remote string, expire time.Duration) (string, error)
 }
 
 // Fill fills in the function pointers in the Features struct from the
@@ -357,6 +373,12 @@ func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(ListRer); ok {
 		ft.ListR = do.ListR
 	}
+	if do, ok := f.(Abouter); ok {
+		ft.About = do.About
+	}
+	if do, ok := f.(PublicLinker); ok {
+		ft.PublicLink = do.PublicLink
+	}
 	return ft
 }
 
@@ -402,6 +424,12 @@ func (ft *Features) Mask(f Fs) *Features {
 	if mask.ListR == nil {
 		ft.ListR = nil
 	}
+	if mask.About == nil {
+		ft.About = nil
+	}
+	if mask.PublicLink == nil {
+		ft.PublicLink = nil
+	}
 	return ft
 }
 
@@ -424,7 +452,7 @@ type Purger interface {
 	// quicker than just running Remove() on the result of List()
 	//
 	// Return an error if it doesn't exist
-	Purge() error
+	Purge(ctx context.Context) error
 }
 
 // Copier is an optional interface for Fs
@@ -438,7 +466,7 @@ type Copier interface {
 	// Will only be called if src.Fs().Name() == f.Name()
 	//
 	// If it isn't possible then return fs.ErrorCantCopy
-	Copy(src Object, remote string) (Object, error)
+	Copy(ctx context.Context, src Object, remote string) (Object, error)
 }
 
 // Mover is an optional interface for Fs
@@ -452,7 +480,7 @@ type Mover interface {
 	// Will only be called if src.Fs().Name() == f.Name()
 	//
 	// If it isn't possible then return fs.ErrorCantMove
-	Move(src Object, remote string) (Object, error)
+	Move(ctx context.Context, src Object, remote string) (Object, error)
 }
 
 // DirMover is an optional interface for Fs
@@ -465,7 +493,7 @@ type DirMover interface {
 	// If it isn't possible then return fs.ErrorCantDirMove
 	//
 	// If destination exists then return fs.ErrorDirExists
-	DirMove(src Fs, srcRemote, dstRemote string) error
+	DirMove(ctx context.Context, src Fs, srcRemote, dstRemote string) error
 }
 
 // DirChangeNotifier is an optional interface for Fs
@@ -499,7 +527,7 @@ type PutUncheckeder interface {
 	//
 	// May create duplicates or return errors if src already
 	// exists.
-	PutUnchecked(in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error)
+	PutUnchecked(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error)
 }
 
 // CleanUpper is an optional interfaces for Fs
@@ -508,7 +536,7 @@ type CleanUpper interface {
 	//
 	// Implement this if you have a way of emptying the trash or
 	// otherwise cleaning up old versions of files.
-	CleanUp() error
+	CleanUp(ctx context.Context) error
 }
 
 // ListRer is an optional interfaces for Fs
@@ -529,7 +557,33 @@ type ListRer interface {
 	//
 	// Don't implement this unless you have a more efficient way
 	// of listing recursively that doing a directory traversal.
-	ListR(dir string, callback ListRCallback) error
+	ListR(ctx context.Context, dir string, callback ListRCallback) error
+}
+
+// Usage is returned by the About call
+//
+// If a value is -1 then it isn't supported
+type Usage struct {
+	Total int64 // quota of bytes that can be used
+	Used  int64 // bytes in use
+	Free  int64 // bytes which can be uploaded before reaching the quota
+}
+
+// Abouter is an optional interface for Fs
+type Abouter interface {
+	// About gets quota information from the Fs
+	About(ctx context.Context) (*Usage, error)
+}
+
+// PublicLinker is an optional interface for Fs
+type PublicLinker interface {
+	// PublicLink generates a public link to the remote path
+	// which anyone can use to read it.
+	//
+	// If expire is non zero the link should stop working after
+	// that time.  If the remote can't make links which expire
+	// then it should return an error if expire is set.
+	PublicLink(ctx context.Context, remote string, expire time.Duration) (string, error)
 }
 
 // ObjectsChan is a channel of Objects
@@ -615,11 +669,22 @@ var matcher = regexp.MustCompile(`^([\w_ -]+):(.*)$`)
 
 // ParseRemote deconstructs a path into configName, fsPath, looking up
 // the fsName in the config file (returning NotFoundInConfigFile if not found)
+//
+// Paths of the form ":type,key=value:path" define a remote inline
+// without using the config file.
 func ParseRemote(path string) (fsInfo *RegInfo, configName, fsPath string, err error) {
 	parts := matcher.FindStringSubmatch(path)
 	var fsName string
 	fsName, configName, fsPath = "local", "local", path
-	if parts != nil && !isDriveLetter(parts[1]) {
+	if strings.HasPrefix(path, ":") {
+		var config map[string]string
+		configName, config, fsPath, err = parseInlineRemote(path)
+		if err != nil {
+			return nil, "", "", err
+		}
+		setInlineConfig(configName, config)
+		fsName = config["type"]
+	} else if parts != nil && !isDriveLetter(parts[1]) {
 		configName, fsPath = parts[1], parts[2]
 		fsName = ConfigFileGet(configName, "type")
 		if fsName == "" {
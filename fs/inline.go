@@ -0,0 +1,146 @@
+// Remotes defined on the command line
+
+package fs
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Inline remotes are defined in the path rather than the config file
+// in the form
+//
+//     :type,key=value,key2="value, with: punctuation":path
+//
+// The config for them is kept in memory under the name
+// ":type,key=value..." so that the remote gets the same config if it
+// is made again from its name and root.  It is never saved to the
+// config file.
+var (
+	inlineConfigMu sync.Mutex
+	inlineConfig   = map[string]map[string]string{}
+)
+
+// Pattern to match the type and keys of inline remotes
+var inlineNameMatcher = regexp.MustCompile(`^[\w_-]+$`)
+
+// isInlineRemote returns whether the config section is for an inline
+// remote
+func isInlineRemote(section string) bool {
+	return strings.HasPrefix(section, ":")
+}
+
+// splitUnquoted splits s at each sep which isn't inside double
+// quotes.  A doubled double quote inside quotes stands for a double
+// quote.
+//
+// If n >= 0 then at most n parts are returned, the last containing
+// the rest of s unparsed.
+func splitUnquoted(s string, sep byte, n int) (parts []string, err error) {
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s) && (n < 0 || len(parts) < n-1); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if inQuotes {
+		return nil, errors.Errorf("unterminated quote in %q", s)
+	}
+	return append(parts, s[start:]), nil
+}
+
+// unquote removes the double quotes from s if it is quoted
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strings.Replace(s[1:len(s)-1], `""`, `"`, -1)
+	}
+	return s
+}
+
+// parseInlineRemote parses an inline remote of the form
+// ":type,key=value:path" returning the name of its config section,
+// its config and the path.
+func parseInlineRemote(remote string) (configName string, config map[string]string, fsPath string, err error) {
+	parts, err := splitUnquoted(remote[1:], ':', 2)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(parts) != 2 {
+		return "", nil, "", errors.Errorf("inline remote %q must end with a ':'", remote)
+	}
+	spec, fsPath := parts[0], parts[1]
+	options, err := splitUnquoted(spec, ',', -1)
+	if err != nil {
+		return "", nil, "", err
+	}
+	fsName := options[0]
+	if !inlineNameMatcher.MatchString(fsName) {
+		return "", nil, "", errors.Errorf("bad remote type %q in inline remote %q", fsName, remote)
+	}
+	config = map[string]string{"type": fsName}
+	for _, option := range options[1:] {
+		keyValue := strings.SplitN(option, "=", 2)
+		key := keyValue[0]
+		if !inlineNameMatcher.MatchString(key) {
+			return "", nil, "", errors.Errorf("bad option %q in inline remote %q", option, remote)
+		}
+		value := "true"
+		if len(keyValue) == 2 {
+			value = unquote(keyValue[1])
+		}
+		config[key] = value
+	}
+	return ":" + spec, config, fsPath, nil
+}
+
+// setInlineConfig sets the config for the inline remote configName
+// if it hasn't been set already.
+//
+// Once set the config isn't replaced as values such as refreshed
+// tokens may have been changed since.
+func setInlineConfig(configName string, config map[string]string) {
+	inlineConfigMu.Lock()
+	defer inlineConfigMu.Unlock()
+	if _, found := inlineConfig[configName]; !found {
+		inlineConfig[configName] = config
+	}
+}
+
+// inlineConfigGet returns the value of key for the inline remote
+// section and whether it was found
+func inlineConfigGet(section, key string) (value string, found bool) {
+	inlineConfigMu.Lock()
+	defer inlineConfigMu.Unlock()
+	value, found = inlineConfig[section][key]
+	return value, found
+}
+
+// inlineConfigSet sets key to value for the inline remote section
+func inlineConfigSet(section, key, value string) {
+	inlineConfigMu.Lock()
+	defer inlineConfigMu.Unlock()
+	config := inlineConfig[section]
+	if config == nil {
+		config = map[string]string{}
+		inlineConfig[section] = config
+	}
+	config[key] = value
+}
+
+// inlineConfigDeleteKey deletes key for the inline remote section
+// returning whether it existed
+func inlineConfigDeleteKey(section, key string) bool {
+	inlineConfigMu.Lock()
+	defer inlineConfigMu.Unlock()
+	_, found := inlineConfig[section][key]
+	delete(inlineConfig[section], key)
+	return found
+}
@@ -0,0 +1,83 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInlineRemote(t *testing.T) {
+	for _, test := range []struct {
+		in         string
+		configName string
+		config     map[string]string
+		fsPath     string
+		err        bool
+	}{
+		{":local:", ":local", map[string]string{"type": "local"}, "", false},
+		{":local:/tmp/dir", ":local", map[string]string{"type": "local"}, "/tmp/dir", false},
+		{
+			":s3,env_auth=true,region=eu-west-1:bucket/path",
+			":s3,env_auth=true,region=eu-west-1",
+			map[string]string{"type": "s3", "env_auth": "true", "region": "eu-west-1"},
+			"bucket/path",
+			false,
+		},
+		{
+			`:sftp,host=example.com,key_file="/path/with,comma:colon",user="a""b":dir:with:colons`,
+			`:sftp,host=example.com,key_file="/path/with,comma:colon",user="a""b"`,
+			map[string]string{"type": "sftp", "host": "example.com", "key_file": "/path/with,comma:colon", "user": `a"b`},
+			"dir:with:colons",
+			false,
+		},
+		{":drive,shared_with_me:", ":drive,shared_with_me", map[string]string{"type": "drive", "shared_with_me": "true"}, "", false},
+		{`:local:"quoted`, ":local", map[string]string{"type": "local"}, `"quoted`, false},
+		{":local", "", nil, "", true},
+		{":,a=b:", "", nil, "", true},
+		{":s3,bad key=b:", "", nil, "", true},
+		{`:s3,a="unterminated:`, "", nil, "", true},
+	} {
+		configName, config, fsPath, err := parseInlineRemote(test.in)
+		if test.err {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.configName, configName, test.in)
+		assert.Equal(t, test.config, config, test.in)
+		assert.Equal(t, test.fsPath, fsPath, test.in)
+	}
+}
+
+func TestInlineConfig(t *testing.T) {
+	fsInfo, configName, fsPath, err := ParseRemote(":local,potato=42,carrot=true:/tmp")
+	require.NoError(t, err)
+	assert.Equal(t, "local", fsInfo.Name)
+	assert.Equal(t, ":local,potato=42,carrot=true", configName)
+	assert.Equal(t, "/tmp", fsPath)
+
+	assert.Equal(t, "local", ConfigFileGet(configName, "type"))
+	assert.Equal(t, "42", ConfigFileGet(configName, "potato"))
+	assert.Equal(t, "default", ConfigFileGet(configName, "missing", "default"))
+	assert.Equal(t, 42, ConfigFileGetInt(configName, "potato"))
+	assert.Equal(t, true, ConfigFileGetBool(configName, "carrot"))
+
+	// Values set for inline remotes stay in memory
+	ConfigFileSet(configName, "token", "secret")
+	assert.Equal(t, "secret", ConfigFileGet(configName, "token"))
+	require.NoError(t, ConfigSetValueAndSave(configName, "token", "new"))
+	assert.Equal(t, "new", ConfigFileGet(configName, "token"))
+	assert.True(t, ConfigFileDeleteKey(configName, "token"))
+	assert.Equal(t, "", ConfigFileGet(configName, "token"))
+	assert.NotContains(t, configData.GetSectionList(), configName)
+
+	// Parsing the remote again doesn't reset changed values
+	ConfigFileSet(configName, "potato", "43")
+	_, _, _, err = ParseRemote(":local,potato=42,carrot=true:/tmp/other")
+	require.NoError(t, err)
+	assert.Equal(t, "43", ConfigFileGet(configName, "potato"))
+
+	_, _, _, err = ParseRemote(":potato:")
+	assert.Error(t, err)
+}
@@ -13,10 +13,12 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 
+	"golang.org/x/net/context"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -113,11 +115,11 @@ func CheckHashes(src, dst Object) (equal bool, hash HashType, err error) {
 //
 // Otherwise the file is considered to be not equal including if there
 // were errors reading info.
-func Equal(src, dst Object) bool {
-	return equal(src, dst, Config.SizeOnly, Config.CheckSum)
+func Equal(ctx context.Context, src, dst Object) bool {
+	return equal(ctx, src, dst, Config.SizeOnly, Config.CheckSum)
 }
 
-func equal(src, dst Object, sizeOnly, checkSum bool) bool {
+func equal(ctx context.Context, src, dst Object, sizeOnly, checkSum bool) bool {
 	if !Config.IgnoreSize {
 		if src.Size() != dst.Size() {
 			Debugf(src, "Sizes differ")
@@ -181,13 +183,13 @@ func equal(src, dst Object, sizeOnly, checkSum bool) bool {
 		} else {
 			// Size and hash the same but mtime different so update the
 			// mtime of the dst object here
-			err := dst.SetModTime(srcModTime)
+			err := dst.SetModTime(ctx, srcModTime)
 			if err == ErrorCantSetModTime {
 				Debugf(dst, "src and dst identical but can't set mod time without re-uploading")
 				return false
 			} else if err == ErrorCantSetModTimeWithoutDelete {
 				Debugf(dst, "src and dst identical but can't set mod time without deleting and re-uploading")
-				err = dst.Remove()
+				err = dst.Remove(ctx)
 				if err != nil {
 					Errorf(dst, "failed to delete before re-upload: %v", err)
 				}
@@ -229,12 +231,12 @@ func MimeType(o ObjectInfo) (mimeType string) {
 // Used to remove a failed copy
 //
 // Returns whether the file was succesfully removed or not
-func removeFailedCopy(dst Object) bool {
+func removeFailedCopy(ctx context.Context, dst Object) bool {
 	if dst == nil {
 		return false
 	}
 	Infof(dst, "Removing failed copy")
-	removeErr := dst.Remove()
+	removeErr := dst.Remove(ctx)
 	if removeErr != nil {
 		Infof(dst, "Failed to remove failed copy: %s", removeErr)
 		return false
@@ -267,7 +269,7 @@ var _ MimeTyper = (*overrideRemoteObject)(nil)
 
 // Copy src object to dst or f if nil.  If dst is nil then it uses
 // remote as the name of the new object.
-func Copy(f Fs, dst Object, remote string, src Object) (err error) {
+func Copy(ctx context.Context, f Fs, dst Object, remote string, src Object) (err error) {
 	if Config.DryRun {
 		Logf(src, "Not copying as --dry-run")
 		return nil
@@ -293,7 +295,7 @@ func Copy(f Fs, dst Object, remote string, src Object) (err error) {
 		actionTaken = "Copied (server side copy)"
 		if doCopy := f.Features().Copy; doCopy != nil && SameConfig(src.Fs(), f) {
 			var newDst Object
-			newDst, err = doCopy(src, remote)
+			newDst, err = doCopy(ctx, src, remote)
 			if err == nil {
 				dst = newDst
 			}
@@ -303,10 +305,11 @@ func Copy(f Fs, dst Object, remote string, src Object) (err error) {
 		// If can't server side copy, do it manually
 		if err == ErrorCantCopy {
 			var in0 io.ReadCloser
-			in0, err = src.Open(hashOption)
+			in0, err = src.Open(ctx, hashOption)
 			if err != nil {
 				err = errors.Wrap(err, "failed to open source object")
 			} else {
+				in0 = NewContextReadCloser(ctx, in0)
 				in := NewAccount(in0, src).WithBuffer() // account and buffer the transfer
 				var wrappedSrc ObjectInfo = src
 				// We try to pass the original object if possible
@@ -315,10 +318,10 @@ func Copy(f Fs, dst Object, remote string, src Object) (err error) {
 				}
 				if doUpdate {
 					actionTaken = "Copied (replaced existing)"
-					err = dst.Update(in, wrappedSrc, hashOption)
+					err = dst.Update(ctx, in, wrappedSrc, hashOption)
 				} else {
 					actionTaken = "Copied (new)"
-					dst, err = f.Put(in, wrappedSrc, hashOption)
+					dst, err = f.Put(ctx, in, wrappedSrc, hashOption)
 				}
 				closeErr := in.Close()
 				if err == nil {
@@ -327,7 +330,7 @@ func Copy(f Fs, dst Object, remote string, src Object) (err error) {
 			}
 		}
 		tries++
-		if tries >= maxTries {
+		if tries >= maxTries || ctx.Err() != nil {
 			break
 		}
 		// Retry if err returned a retry error
@@ -349,7 +352,7 @@ func Copy(f Fs, dst Object, remote string, src Object) (err error) {
 		Stats.Error()
 		err = errors.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), dst.Size())
 		Errorf(dst, "%v", err)
-		removeFailedCopy(dst)
+		removeFailedCopy(ctx, dst)
 		return err
 	}
 
@@ -372,7 +375,7 @@ func Copy(f Fs, dst Object, remote string, src Object) (err error) {
 				Stats.Error()
 				err = errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", hashType, srcSum, dstSum)
 				Errorf(dst, "%v", err)
-				removeFailedCopy(dst)
+				removeFailedCopy(ctx, dst)
 				return err
 			}
 		}
@@ -384,7 +387,7 @@ func Copy(f Fs, dst Object, remote string, src Object) (err error) {
 
 // Move src object to dst or fdst if nil.  If dst is nil then it uses
 // remote as the name of the new object.
-func Move(fdst Fs, dst Object, remote string, src Object) (err error) {
+func Move(ctx context.Context, fdst Fs, dst Object, remote string, src Object) (err error) {
 	if Config.DryRun {
 		Logf(src, "Not moving as --dry-run")
 		return nil
@@ -393,13 +396,13 @@ func Move(fdst Fs, dst Object, remote string, src Object) (err error) {
 	if doMove := fdst.Features().Move; doMove != nil && SameConfig(src.Fs(), fdst) {
 		// Delete destination if it exists
 		if dst != nil {
-			err = DeleteFile(dst)
+			err = DeleteFile(ctx, dst)
 			if err != nil {
 				return err
 			}
 		}
 		// Move dst <- src
-		_, err := doMove(src, remote)
+		_, err := doMove(ctx, src, remote)
 		switch err {
 		case nil:
 			Infof(src, "Moved (server side)")
@@ -413,13 +416,13 @@ func Move(fdst Fs, dst Object, remote string, src Object) (err error) {
 		}
 	}
 	// Move not found or didn't work so copy dst <- src
-	err = Copy(fdst, dst, remote, src)
+	err = Copy(ctx, fdst, dst, remote, src)
 	if err != nil {
 		Errorf(src, "Not deleting source as copy failed: %v", err)
 		return err
 	}
 	// Delete src if no error on copy
-	return DeleteFile(src)
+	return DeleteFile(ctx, src)
 }
 
 // CanServerSideMove returns true if fdst support server side moves or
@@ -438,7 +441,7 @@ func CanServerSideMove(fdst Fs) bool {
 //
 // If backupDir is set then it moves the file to there instead of
 // deleting
-func deleteFileWithBackupDir(dst Object, backupDir Fs) (err error) {
+func deleteFileWithBackupDir(ctx context.Context, dst Object, backupDir Fs) (err error) {
 	Stats.Checking(dst.Remote())
 	action, actioned, actioning := "delete", "Deleted", "deleting"
 	if backupDir != nil {
@@ -451,11 +454,11 @@ func deleteFileWithBackupDir(dst Object, backupDir Fs) (err error) {
 			err = errors.New("parameter to --backup-dir has to be on the same remote as destination")
 		} else {
 			remoteWithSuffix := dst.Remote() + Config.Suffix
-			overwritten, _ := backupDir.NewObject(remoteWithSuffix)
-			err = Move(backupDir, overwritten, remoteWithSuffix, dst)
+			overwritten, _ := backupDir.NewObject(ctx, remoteWithSuffix)
+			err = Move(ctx, backupDir, overwritten, remoteWithSuffix, dst)
 		}
 	} else {
-		err = dst.Remove()
+		err = dst.Remove(ctx)
 	}
 	if err != nil {
 		Stats.Error()
@@ -471,8 +474,8 @@ func deleteFileWithBackupDir(dst Object, backupDir Fs) (err error) {
 //
 // If useBackupDir is set and --backup-dir is in effect then it moves
 // the file to there instead of deleting
-func DeleteFile(dst Object) (err error) {
-	return deleteFileWithBackupDir(dst, nil)
+func DeleteFile(ctx context.Context, dst Object) (err error) {
+	return deleteFileWithBackupDir(ctx, dst, nil)
 }
 
 // deleteFilesWithBackupDir removes all the files passed in the
@@ -480,7 +483,7 @@ func DeleteFile(dst Object) (err error) {
 //
 // If backupDir is set the files will be placed into that directory
 // instead of being deleted.
-func deleteFilesWithBackupDir(toBeDeleted ObjectsChan, backupDir Fs) error {
+func deleteFilesWithBackupDir(ctx context.Context, toBeDeleted ObjectsChan, backupDir Fs) error {
 	var wg sync.WaitGroup
 	wg.Add(Config.Transfers)
 	var errorCount int32
@@ -488,7 +491,7 @@ func deleteFilesWithBackupDir(toBeDeleted ObjectsChan, backupDir Fs) error {
 		go func() {
 			defer wg.Done()
 			for dst := range toBeDeleted {
-				err := deleteFileWithBackupDir(dst, backupDir)
+				err := deleteFileWithBackupDir(ctx, dst, backupDir)
 				if err != nil {
 					atomic.AddInt32(&errorCount, 1)
 				}
@@ -504,8 +507,8 @@ func deleteFilesWithBackupDir(toBeDeleted ObjectsChan, backupDir Fs) error {
 }
 
 // DeleteFiles removes all the files passed in the channel
-func DeleteFiles(toBeDeleted ObjectsChan) error {
-	return deleteFilesWithBackupDir(toBeDeleted, nil)
+func DeleteFiles(ctx context.Context, toBeDeleted ObjectsChan) error {
+	return deleteFilesWithBackupDir(ctx, toBeDeleted, nil)
 }
 
 // Read a Objects into add() for the given Fs.
@@ -515,8 +518,8 @@ func DeleteFiles(toBeDeleted ObjectsChan) error {
 //
 // Each object is passed ito the function provided.  If that returns
 // an error then the listing will be aborted and that error returned.
-func readFilesFn(fs Fs, includeAll bool, dir string, add func(Object) error) (err error) {
-	return Walk(fs, "", includeAll, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
+func readFilesFn(ctx context.Context, fs Fs, includeAll bool, dir string, add func(Object) error) (err error) {
+	return Walk(ctx, fs, "", includeAll, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -598,9 +601,9 @@ func (ds DirEntries) ForDirError(fn func(dir *Dir) error) error {
 // files and directories passing the filter will be added.
 //
 // Files will be returned in sorted order
-func ListDirSorted(fs Fs, includeAll bool, dir string) (entries DirEntries, err error) {
+func ListDirSorted(ctx context.Context, fs Fs, includeAll bool, dir string) (entries DirEntries, err error) {
 	// Get unfiltered entries from the fs
-	entries, err = fs.List(dir)
+	entries, err = fs.List(ctx, dir)
 	if err != nil {
 		return nil, err
 	}
@@ -641,10 +644,10 @@ func ListDirSorted(fs Fs, includeAll bool, dir string) (entries DirEntries, err
 // otherwise only files passing the filter will be added.
 //
 // This also detects duplicates and normalised duplicates
-func readFilesMap(fs Fs, includeAll bool, dir string) (files map[string]Object, err error) {
+func readFilesMap(ctx context.Context, fs Fs, includeAll bool, dir string) (files map[string]Object, err error) {
 	files = make(map[string]Object)
 	normalised := make(map[string]struct{})
-	err = readFilesFn(fs, includeAll, dir, func(o Object) error {
+	err = readFilesFn(ctx, fs, includeAll, dir, func(o Object) error {
 		remote := o.Remote()
 		normalisedRemote := strings.ToLower(norm.NFC.String(remote))
 		if _, ok := files[remote]; !ok {
@@ -666,14 +669,14 @@ func readFilesMap(fs Fs, includeAll bool, dir string) (files map[string]Object,
 
 // readFilesMaps runs readFilesMap on fdst and fsrc at the same time
 // dir is the start directory, "" for root
-func readFilesMaps(fdst Fs, fdstIncludeAll bool, fsrc Fs, fsrcIncludeAll bool, dir string) (dstFiles, srcFiles map[string]Object, err error) {
+func readFilesMaps(ctx context.Context, fdst Fs, fdstIncludeAll bool, fsrc Fs, fsrcIncludeAll bool, dir string) (dstFiles, srcFiles map[string]Object, err error) {
 	var wg sync.WaitGroup
 	var srcErr, dstErr error
 
 	list := func(fs Fs, includeAll bool, pMap *map[string]Object, pErr *error) {
 		defer wg.Done()
 		Infof(fs, "Building file list")
-		files, listErr := readFilesMap(fs, includeAll, dir)
+		files, listErr := readFilesMap(ctx, fs, includeAll, dir)
 		if listErr != nil {
 			Errorf(fs, "Error building file list: %v", listErr)
 			*pErr = listErr
@@ -755,8 +758,8 @@ func checkIdentical(dst, src Object) (differ bool, noHash bool) {
 //
 // it returns true if differences were found
 // it also returns whether it couldn't be hashed
-func CheckFn(fdst, fsrc Fs, checkFunction func(a, b Object) (differ bool, noHash bool)) error {
-	dstFiles, srcFiles, err := readFilesMaps(fdst, false, fsrc, false, "")
+func CheckFn(ctx context.Context, fdst, fsrc Fs, checkFunction func(a, b Object) (differ bool, noHash bool)) error {
+	dstFiles, srcFiles, err := readFilesMaps(ctx, fdst, false, fsrc, false, "")
 	if err != nil {
 		return err
 	}
@@ -845,8 +848,8 @@ func CheckFn(fdst, fsrc Fs, checkFunction func(a, b Object) (differ bool, noHash
 }
 
 // Check the files in fsrc and fdst according to Size and hash
-func Check(fdst, fsrc Fs) error {
-	return CheckFn(fdst, fsrc, checkIdentical)
+func Check(ctx context.Context, fdst, fsrc Fs) error {
+	return CheckFn(ctx, fdst, fsrc, checkIdentical)
 }
 
 // ReadFill reads as much data from r into buf as it can
@@ -898,15 +901,15 @@ func CheckEqualReaders(in1, in2 io.Reader) (differ bool, err error) {
 // reading all their bytes if necessary.
 //
 // it returns true if differences were found
-func CheckIdentical(dst, src Object) (differ bool, err error) {
-	in1, err := dst.Open()
+func CheckIdentical(ctx context.Context, dst, src Object) (differ bool, err error) {
+	in1, err := dst.Open(ctx)
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", dst)
 	}
 	in1 = NewAccount(in1, dst).WithBuffer() // account and buffer the transfer
 	defer CheckClose(in1, &err)
 
-	in2, err := src.Open()
+	in2, err := src.Open(ctx)
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", src)
 	}
@@ -918,9 +921,9 @@ func CheckIdentical(dst, src Object) (differ bool, err error) {
 
 // CheckDownload checks the files in fsrc and fdst according to Size
 // and the actual contents of the files.
-func CheckDownload(fdst, fsrc Fs) error {
+func CheckDownload(ctx context.Context, fdst, fsrc Fs) error {
 	check := func(a, b Object) (differ bool, noHash bool) {
-		differ, err := CheckIdentical(a, b)
+		differ, err := CheckIdentical(ctx, a, b)
 		if err != nil {
 			Stats.Error()
 			Errorf(a, "Failed to download: %v", err)
@@ -928,14 +931,14 @@ func CheckDownload(fdst, fsrc Fs) error {
 		}
 		return differ, false
 	}
-	return CheckFn(fdst, fsrc, check)
+	return CheckFn(ctx, fdst, fsrc, check)
 }
 
 // ListFn lists the Fs to the supplied function
 //
 // Lists in parallel which may get them out of order
-func ListFn(f Fs, fn func(Object)) error {
-	return Walk(f, "", false, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
+func ListFn(ctx context.Context, f Fs, fn func(Object)) error {
+	return Walk(ctx, f, "", false, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
 		if err != nil {
 			// FIXME count errors and carry on for listing
 			return err
@@ -962,8 +965,8 @@ func syncFprintf(w io.Writer, format string, a ...interface{}) {
 // Shows size and path - obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func List(f Fs, w io.Writer) error {
-	return ListFn(f, func(o Object) {
+func List(ctx context.Context, f Fs, w io.Writer) error {
+	return ListFn(ctx, f, func(o Object) {
 		syncFprintf(w, "%9d %s\n", o.Size(), o.Remote())
 	})
 }
@@ -973,8 +976,8 @@ func List(f Fs, w io.Writer) error {
 // Shows size, mod time and path - obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func ListLong(f Fs, w io.Writer) error {
-	return ListFn(f, func(o Object) {
+func ListLong(ctx context.Context, f Fs, w io.Writer) error {
+	return ListFn(ctx, f, func(o Object) {
 		Stats.Checking(o.Remote())
 		modTime := o.ModTime()
 		Stats.DoneChecking(o.Remote())
@@ -988,8 +991,8 @@ func ListLong(f Fs, w io.Writer) error {
 // excludes
 //
 // Lists in parallel which may get them out of order
-func Md5sum(f Fs, w io.Writer) error {
-	return hashLister(HashMD5, f, w)
+func Md5sum(ctx context.Context, f Fs, w io.Writer) error {
+	return hashLister(ctx, HashMD5, f, w)
 }
 
 // Sha1sum list the Fs to the supplied writer
@@ -997,8 +1000,8 @@ func Md5sum(f Fs, w io.Writer) error {
 // Obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func Sha1sum(f Fs, w io.Writer) error {
-	return hashLister(HashSHA1, f, w)
+func Sha1sum(ctx context.Context, f Fs, w io.Writer) error {
+	return hashLister(ctx, HashSHA1, f, w)
 }
 
 // DropboxHashSum list the Fs to the supplied writer
@@ -1006,12 +1009,12 @@ func Sha1sum(f Fs, w io.Writer) error {
 // Obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func DropboxHashSum(f Fs, w io.Writer) error {
-	return hashLister(HashDropbox, f, w)
+func DropboxHashSum(ctx context.Context, f Fs, w io.Writer) error {
+	return hashLister(ctx, HashDropbox, f, w)
 }
 
-func hashLister(ht HashType, f Fs, w io.Writer) error {
-	return ListFn(f, func(o Object) {
+func hashLister(ctx context.Context, ht HashType, f Fs, w io.Writer) error {
+	return ListFn(ctx, f, func(o Object) {
 		Stats.Checking(o.Remote())
 		sum, err := o.Hash(ht)
 		Stats.DoneChecking(o.Remote())
@@ -1028,8 +1031,8 @@ func hashLister(ht HashType, f Fs, w io.Writer) error {
 // Count counts the objects and their sizes in the Fs
 //
 // Obeys includes and excludes
-func Count(f Fs) (objects int64, size int64, err error) {
-	err = ListFn(f, func(o Object) {
+func Count(ctx context.Context, f Fs) (objects int64, size int64, err error) {
+	err = ListFn(ctx, f, func(o Object) {
 		atomic.AddInt64(&objects, 1)
 		atomic.AddInt64(&size, o.Size())
 	})
@@ -1046,8 +1049,8 @@ func ConfigMaxDepth(recursive bool) int {
 }
 
 // ListDir lists the directories/buckets/containers in the Fs to the supplied writer
-func ListDir(f Fs, w io.Writer) error {
-	return Walk(f, "", false, ConfigMaxDepth(false), func(dirPath string, entries DirEntries, err error) error {
+func ListDir(ctx context.Context, f Fs, w io.Writer) error {
+	return Walk(ctx, f, "", false, ConfigMaxDepth(false), func(dirPath string, entries DirEntries, err error) error {
 		if err != nil {
 			// FIXME count errors and carry on for listing
 			return err
@@ -1062,12 +1065,12 @@ func ListDir(f Fs, w io.Writer) error {
 }
 
 // Mkdir makes a destination directory or container
-func Mkdir(f Fs, dir string) error {
+func Mkdir(ctx context.Context, f Fs, dir string) error {
 	if Config.DryRun {
 		Logf(f, "Not making directory as dry run is set")
 		return nil
 	}
-	err := f.Mkdir(dir)
+	err := f.Mkdir(ctx, dir)
 	if err != nil {
 		Stats.Error()
 		return err
@@ -1077,7 +1080,7 @@ func Mkdir(f Fs, dir string) error {
 
 // TryRmdir removes a container but not if not empty.  It doesn't
 // count errors but may return one.
-func TryRmdir(f Fs, dir string) error {
+func TryRmdir(ctx context.Context, f Fs, dir string) error {
 	if Config.DryRun {
 		if dir != "" {
 			Logf(dir, "Not deleting as dry run is set")
@@ -1086,12 +1089,12 @@ func TryRmdir(f Fs, dir string) error {
 		}
 		return nil
 	}
-	return f.Rmdir(dir)
+	return f.Rmdir(ctx, dir)
 }
 
 // Rmdir removes a container but not if not empty
-func Rmdir(f Fs, dir string) error {
-	err := TryRmdir(f, dir)
+func Rmdir(ctx context.Context, f Fs, dir string) error {
+	err := TryRmdir(ctx, f, dir)
 	if err != nil {
 		Stats.Error()
 		return err
@@ -1100,7 +1103,7 @@ func Rmdir(f Fs, dir string) error {
 }
 
 // Purge removes a container and all of its contents
-func Purge(f Fs) error {
+func Purge(ctx context.Context, f Fs) error {
 	doFallbackPurge := true
 	var err error
 	if doPurge := f.Features().Purge; doPurge != nil {
@@ -1108,7 +1111,7 @@ func Purge(f Fs) error {
 		if Config.DryRun {
 			Logf(f, "Not purging as --dry-run set")
 		} else {
-			err = doPurge()
+			err = doPurge(ctx)
 			if err == ErrorCantPurge {
 				doFallbackPurge = true
 			}
@@ -1116,11 +1119,11 @@ func Purge(f Fs) error {
 	}
 	if doFallbackPurge {
 		// DeleteFiles and Rmdir observe --dry-run
-		err = DeleteFiles(listToChan(f))
+		err = DeleteFiles(ctx, listToChan(ctx, f))
 		if err != nil {
 			return err
 		}
-		err = Rmdirs(f, "")
+		err = Rmdirs(ctx, f, "")
 	}
 	if err != nil {
 		Stats.Error()
@@ -1131,13 +1134,13 @@ func Purge(f Fs) error {
 
 // Delete removes all the contents of a container.  Unlike Purge, it
 // obeys includes and excludes.
-func Delete(f Fs) error {
+func Delete(ctx context.Context, f Fs) error {
 	delete := make(ObjectsChan, Config.Transfers)
 	delErr := make(chan error, 1)
 	go func() {
-		delErr <- DeleteFiles(delete)
+		delErr <- DeleteFiles(ctx, delete)
 	}()
-	err := ListFn(f, func(o Object) {
+	err := ListFn(ctx, f, func(o Object) {
 		delete <- o
 	})
 	close(delete)
@@ -1149,7 +1152,7 @@ func Delete(f Fs) error {
 }
 
 // dedupeRename renames the objs slice to different names
-func dedupeRename(remote string, objs []Object) {
+func dedupeRename(ctx context.Context, remote string, objs []Object) {
 	f := objs[0].Fs()
 	doMove := f.Features().Move
 	if doMove == nil {
@@ -1160,7 +1163,7 @@ func dedupeRename(remote string, objs []Object) {
 	for i, o := range objs {
 		newName := fmt.Sprintf("%s-%d%s", base, i+1, ext)
 		if !Config.DryRun {
-			newObj, err := doMove(o, newName)
+			newObj, err := doMove(ctx, o, newName)
 			if err != nil {
 				Stats.Error()
 				Errorf(o, "Failed to rename: %v", err)
@@ -1174,18 +1177,18 @@ func dedupeRename(remote string, objs []Object) {
 }
 
 // dedupeDeleteAllButOne deletes all but the one in keep
-func dedupeDeleteAllButOne(keep int, remote string, objs []Object) {
+func dedupeDeleteAllButOne(ctx context.Context, keep int, remote string, objs []Object) {
 	for i, o := range objs {
 		if i == keep {
 			continue
 		}
-		_ = DeleteFile(o)
+		_ = DeleteFile(ctx, o)
 	}
 	Logf(remote, "Deleted %d extra copies", len(objs)-1)
 }
 
 // dedupeDeleteIdentical deletes all but one of identical (by hash) copies
-func dedupeDeleteIdentical(remote string, objs []Object) []Object {
+func dedupeDeleteIdentical(ctx context.Context, remote string, objs []Object) []Object {
 	// See how many of these duplicates are identical
 	byHash := make(map[string][]Object, len(objs))
 	for _, o := range objs {
@@ -1201,7 +1204,7 @@ func dedupeDeleteIdentical(remote string, objs []Object) []Object {
 		if len(hashObjs) > 1 {
 			Logf(remote, "Deleting %d/%d identical duplicates (md5sum %q)", len(hashObjs)-1, len(hashObjs), md5sum)
 			for _, o := range hashObjs[1:] {
-				_ = DeleteFile(o)
+				_ = DeleteFile(ctx, o)
 			}
 		}
 		objs = append(objs, hashObjs[0])
@@ -1211,7 +1214,7 @@ func dedupeDeleteIdentical(remote string, objs []Object) []Object {
 }
 
 // dedupeInteractive interactively dedupes the slice of objects
-func dedupeInteractive(remote string, objs []Object) {
+func dedupeInteractive(ctx context.Context, remote string, objs []Object) {
 	fmt.Printf("%s: %d duplicates remain\n", remote, len(objs))
 	for i, o := range objs {
 		md5sum, err := o.Hash(HashMD5)
@@ -1224,9 +1227,9 @@ func dedupeInteractive(remote string, objs []Object) {
 	case 's':
 	case 'k':
 		keep := ChooseNumber("Enter the number of the file to keep", 1, len(objs))
-		dedupeDeleteAllButOne(keep-1, remote, objs)
+		dedupeDeleteAllButOne(ctx, keep-1, remote, objs)
 	case 'r':
-		dedupeRename(remote, objs)
+		dedupeRename(ctx, remote, objs)
 	}
 }
 
@@ -1301,10 +1304,10 @@ var _ pflag.Value = (*DeduplicateMode)(nil)
 // Deduplicate interactively finds duplicate files and offers to
 // delete all but one or rename them to be different. Only useful with
 // Google Drive which can have duplicate file names.
-func Deduplicate(f Fs, mode DeduplicateMode) error {
+func Deduplicate(ctx context.Context, f Fs, mode DeduplicateMode) error {
 	Infof(f, "Looking for duplicates using %v mode.", mode)
 	files := map[string][]Object{}
-	err := Walk(f, "", true, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
+	err := Walk(ctx, f, "", true, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -1320,24 +1323,24 @@ func Deduplicate(f Fs, mode DeduplicateMode) error {
 	for remote, objs := range files {
 		if len(objs) > 1 {
 			Logf(remote, "Found %d duplicates - deleting identical copies", len(objs))
-			objs = dedupeDeleteIdentical(remote, objs)
+			objs = dedupeDeleteIdentical(ctx, remote, objs)
 			if len(objs) <= 1 {
 				Logf(remote, "All duplicates removed")
 				continue
 			}
 			switch mode {
 			case DeduplicateInteractive:
-				dedupeInteractive(remote, objs)
+				dedupeInteractive(ctx, remote, objs)
 			case DeduplicateFirst:
-				dedupeDeleteAllButOne(0, remote, objs)
+				dedupeDeleteAllButOne(ctx, 0, remote, objs)
 			case DeduplicateNewest:
 				sort.Sort(objectsSortedByModTime(objs)) // sort oldest first
-				dedupeDeleteAllButOne(len(objs)-1, remote, objs)
+				dedupeDeleteAllButOne(ctx, len(objs)-1, remote, objs)
 			case DeduplicateOldest:
 				sort.Sort(objectsSortedByModTime(objs)) // sort oldest first
-				dedupeDeleteAllButOne(0, remote, objs)
+				dedupeDeleteAllButOne(ctx, 0, remote, objs)
 			case DeduplicateRename:
-				dedupeRename(remote, objs)
+				dedupeRename(ctx, remote, objs)
 			case DeduplicateSkip:
 				// skip
 			default:
@@ -1354,11 +1357,11 @@ func Deduplicate(f Fs, mode DeduplicateMode) error {
 // channel.
 //
 // If the error was ErrorDirNotFound then it will be ignored
-func listToChan(f Fs) ObjectsChan {
+func listToChan(ctx context.Context, f Fs) ObjectsChan {
 	o := make(ObjectsChan, Config.Checkers)
 	go func() {
 		defer close(o)
-		_ = Walk(f, "", true, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
+		_ = Walk(ctx, f, "", true, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
 			if err != nil {
 				if err == ErrorDirNotFound {
 					return nil
@@ -1377,7 +1380,7 @@ func listToChan(f Fs) ObjectsChan {
 }
 
 // CleanUp removes the trash for the Fs
-func CleanUp(f Fs) error {
+func CleanUp(ctx context.Context, f Fs) error {
 	doCleanUp := f.Features().CleanUp
 	if doCleanUp == nil {
 		return errors.Errorf("%v doesn't support cleanup", f)
@@ -1386,7 +1389,17 @@ func CleanUp(f Fs) error {
 		Logf(f, "Not running cleanup as --dry-run set")
 		return nil
 	}
-	return doCleanUp()
+	return doCleanUp(ctx)
+}
+
+// PublicLink makes a public link to remote in f which expires after
+// expire if it is non zero
+func PublicLink(ctx context.Context, f Fs, remote string, expire time.Duration) (string, error) {
+	doPublicLink := f.Features().PublicLink
+	if doPublicLink == nil {
+		return "", errors.Errorf("%v doesn't support public links", f)
+	}
+	return doPublicLink(ctx, remote, expire)
 }
 
 // wrap a Reader and a Closer together into a ReadCloser
@@ -1408,9 +1421,9 @@ func (r *readCloser) Close() error {
 //
 // if count < 0 then it will be ignored
 // if count >= 0 then only that many characters will be output
-func Cat(f Fs, w io.Writer, offset, count int64) error {
+func Cat(ctx context.Context, f Fs, w io.Writer, offset, count int64) error {
 	var mu sync.Mutex
-	return ListFn(f, func(o Object) {
+	return ListFn(ctx, f, func(o Object) {
 		var err error
 		Stats.Transferring(o.Remote())
 		defer func() {
@@ -1423,24 +1436,38 @@ func Cat(f Fs, w io.Writer, offset, count int64) error {
 		}
 		// size remaining is now reduced by thisOffset
 		size -= thisOffset
-		var options []OpenOption
-		if thisOffset > 0 {
-			options = append(options, &SeekOption{Offset: thisOffset})
+		var in io.ReadCloser
+		cr := NewChunkedReaderFromConfig(ctx, o)
+		if cr != nil {
+			// Read only the range required in chunks
+			_, err = cr.RangeSeek(thisOffset, count)
+			in = cr
+		} else {
+			var options []OpenOption
+			if thisOffset > 0 {
+				options = append(options, &SeekOption{Offset: thisOffset})
+			}
+			in, err = o.Open(ctx, options...)
 		}
-		in, err := o.Open(options...)
 		if err != nil {
 			Stats.Error()
 			Errorf(o, "Failed to open: %v", err)
 			return
 		}
 		if count >= 0 {
-			in = &readCloser{Reader: &io.LimitedReader{R: in, N: count}, Closer: in}
+			if cr == nil {
+				in = &readCloser{Reader: &io.LimitedReader{R: in, N: count}, Closer: in}
+			}
 			// reduce remaining size to count
 			if size > count {
 				size = count
 			}
 		}
-		in = NewAccountSizeName(in, size, o.Remote()).WithBuffer() // account and buffer the transfer
+		acc := NewAccountSizeName(in, size, o.Remote()) // account the transfer
+		if cr == nil {
+			acc = acc.WithBuffer() // the chunked reader does its own buffering
+		}
+		in = acc
 		defer func() {
 			err = in.Close()
 			if err != nil {
@@ -1461,10 +1488,10 @@ func Cat(f Fs, w io.Writer, offset, count int64) error {
 
 // Rmdirs removes any empty directories (or directories only
 // containing empty directories) under f, including f.
-func Rmdirs(f Fs, dir string) error {
+func Rmdirs(ctx context.Context, f Fs, dir string) error {
 	dirEmpty := make(map[string]bool)
 	dirEmpty[""] = true
-	err := Walk(f, dir, true, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
+	err := Walk(ctx, f, dir, true, Config.MaxDepth, func(dirPath string, entries DirEntries, err error) error {
 		if err != nil {
 			Stats.Error()
 			Errorf(f, "Failed to list %q: %v", dirPath, err)
@@ -1511,7 +1538,7 @@ func Rmdirs(f Fs, dir string) error {
 	sort.Strings(toDelete)
 	for i := len(toDelete) - 1; i >= 0; i-- {
 		dir := toDelete[i]
-		err := TryRmdir(f, dir)
+		err := TryRmdir(ctx, f, dir)
 		if err != nil {
 			Stats.Error()
 			Errorf(dir, "Failed to rmdir: %v", err)
@@ -1522,7 +1549,7 @@ func Rmdirs(f Fs, dir string) error {
 }
 
 // moveOrCopyFile moves or copies a single file possibly to a new name
-func moveOrCopyFile(fdst Fs, fsrc Fs, dstFileName string, srcFileName string, cp bool) (err error) {
+func moveOrCopyFile(ctx context.Context, fdst Fs, fsrc Fs, dstFileName string, srcFileName string, cp bool) (err error) {
 	if fdst.Name() == fsrc.Name() && dstFileName == srcFileName {
 		Debugf(fdst, "don't need to copy/move %s, it is already at target location", dstFileName)
 		return nil
@@ -1535,27 +1562,27 @@ func moveOrCopyFile(fdst Fs, fsrc Fs, dstFileName string, srcFileName string, cp
 	}
 
 	// Find src object
-	srcObj, err := fsrc.NewObject(srcFileName)
+	srcObj, err := fsrc.NewObject(ctx, srcFileName)
 	if err != nil {
 		return err
 	}
 
 	// Find dst object if it exists
-	dstObj, err := fdst.NewObject(dstFileName)
+	dstObj, err := fdst.NewObject(ctx, dstFileName)
 	if err == ErrorObjectNotFound {
 		dstObj = nil
 	} else if err != nil {
 		return err
 	}
 
-	if NeedTransfer(dstObj, srcObj) {
+	if NeedTransfer(ctx, dstObj, srcObj) {
 		Stats.Transferring(srcFileName)
-		err = Op(fdst, dstObj, dstFileName, srcObj)
+		err = Op(ctx, fdst, dstObj, dstFileName, srcObj)
 		Stats.DoneTransferring(srcFileName, err == nil)
 	} else {
 		Stats.Checking(srcFileName)
 		if !cp {
-			err = DeleteFile(srcObj)
+			err = DeleteFile(ctx, srcObj)
 		}
 		defer Stats.DoneChecking(srcFileName)
 	}
@@ -1563,11 +1590,11 @@ func moveOrCopyFile(fdst Fs, fsrc Fs, dstFileName string, srcFileName string, cp
 }
 
 // MoveFile moves a single file possibly to a new name
-func MoveFile(fdst Fs, fsrc Fs, dstFileName string, srcFileName string) (err error) {
-	return moveOrCopyFile(fdst, fsrc, dstFileName, srcFileName, false)
+func MoveFile(ctx context.Context, fdst Fs, fsrc Fs, dstFileName string, srcFileName string) (err error) {
+	return moveOrCopyFile(ctx, fdst, fsrc, dstFileName, srcFileName, false)
 }
 
 // CopyFile moves a single file possibly to a new name
-func CopyFile(fdst Fs, fsrc Fs, dstFileName string, srcFileName string) (err error) {
-	return moveOrCopyFile(fdst, fsrc, dstFileName, srcFileName, true)
+func CopyFile(ctx context.Context, fdst Fs, fsrc Fs, dstFileName string, srcFileName string) (err error) {
+	return moveOrCopyFile(ctx, fdst, fsrc, dstFileName, srcFileName, true)
 }
@@ -41,6 +41,7 @@ import (
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 // Globals
@@ -149,6 +150,7 @@ func (d dirsToRemove) Less(i, j int) bool { return len(d[i]) > len(d[j]) }
 //
 // Finalise() will tidy them away when done.
 func NewRun(t *testing.T) *Run {
+	ctx := context.Background()
 	var r *Run
 	if *Individual {
 		r = newRun()
@@ -158,7 +160,7 @@ func NewRun(t *testing.T) *Run {
 		*r = *oneRun
 		r.cleanRemote = func() {
 			var toDelete dirsToRemove
-			require.NoError(t, fs.Walk(r.fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+			require.NoError(t, fs.Walk(ctx, r.fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
 				if err != nil {
 					if err == fs.ErrorDirNotFound {
 						return nil
@@ -168,7 +170,7 @@ func NewRun(t *testing.T) *Run {
 				for _, entry := range entries {
 					switch x := entry.(type) {
 					case fs.Object:
-						err = x.Remove()
+						err = x.Remove(ctx)
 						if err != nil {
 							t.Errorf("Error removing file %q: %v", x.Remote(), err)
 						}
@@ -180,7 +182,7 @@ func NewRun(t *testing.T) *Run {
 			}))
 			sort.Sort(toDelete)
 			for _, dir := range toDelete {
-				err := r.fremote.Rmdir(dir)
+				err := r.fremote.Rmdir(ctx, dir)
 				if err != nil {
 					t.Errorf("Error removing dir %q: %v", dir, err)
 				}
@@ -231,7 +233,8 @@ func (r *Run) WriteFile(filePath, content string, t time.Time) fstest.Item {
 
 // ForceMkdir creates the remote
 func (r *Run) ForceMkdir(f fs.Fs) {
-	err := f.Mkdir("")
+	ctx := context.Background()
+	err := f.Mkdir(ctx, "")
 	if err != nil {
 		r.Fatalf("Failed to mkdir %q: %v", f, err)
 	}
@@ -247,6 +250,7 @@ func (r *Run) Mkdir(f fs.Fs) {
 
 // WriteObjectTo writes an object to the fs, remote passed in
 func (r *Run) WriteObjectTo(f fs.Fs, remote, content string, modTime time.Time, useUnchecked bool) fstest.Item {
+	ctx := context.Background()
 	put := f.Put
 	if useUnchecked {
 		put = f.Features().PutUnchecked
@@ -259,7 +263,7 @@ func (r *Run) WriteObjectTo(f fs.Fs, remote, content string, modTime time.Time,
 	for tries := 1; ; tries++ {
 		in := bytes.NewBufferString(content)
 		objinfo := fs.NewStaticObjectInfo(remote, modTime, int64(len(content)), true, nil, nil)
-		_, err := put(in, objinfo)
+		_, err := put(ctx, in, objinfo)
 		if err == nil {
 			break
 		}
@@ -315,6 +319,7 @@ func TestMkdir(t *testing.T) {
 }
 
 func TestLsd(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("sub dir/hello world", "hello world", t1)
@@ -322,13 +327,14 @@ func TestLsd(t *testing.T) {
 	fstest.CheckItems(t, r.fremote, file1)
 
 	var buf bytes.Buffer
-	err := fs.ListDir(r.fremote, &buf)
+	err := fs.ListDir(ctx, r.fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	assert.Contains(t, res, "sub dir\n")
 }
 
 func TestLs(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -337,7 +343,7 @@ func TestLs(t *testing.T) {
 	fstest.CheckItems(t, r.fremote, file1, file2)
 
 	var buf bytes.Buffer
-	err := fs.List(r.fremote, &buf)
+	err := fs.List(ctx, r.fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	assert.Contains(t, res, "        0 empty space\n")
@@ -345,6 +351,7 @@ func TestLs(t *testing.T) {
 }
 
 func TestLsLong(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -353,7 +360,7 @@ func TestLsLong(t *testing.T) {
 	fstest.CheckItems(t, r.fremote, file1, file2)
 
 	var buf bytes.Buffer
-	err := fs.ListLong(r.fremote, &buf)
+	err := fs.ListLong(ctx, r.fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	lines := strings.Split(strings.Trim(res, "\n"), "\n")
@@ -390,6 +397,7 @@ func TestLsLong(t *testing.T) {
 }
 
 func TestHashSums(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -400,7 +408,7 @@ func TestHashSums(t *testing.T) {
 	// MD5 Sum
 
 	var buf bytes.Buffer
-	err := fs.Md5sum(r.fremote, &buf)
+	err := fs.Md5sum(ctx, r.fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	if !strings.Contains(res, "d41d8cd98f00b204e9800998ecf8427e  empty space\n") &&
@@ -417,7 +425,7 @@ func TestHashSums(t *testing.T) {
 	// SHA1 Sum
 
 	buf.Reset()
-	err = fs.Sha1sum(r.fremote, &buf)
+	err = fs.Sha1sum(ctx, r.fremote, &buf)
 	require.NoError(t, err)
 	res = buf.String()
 	if !strings.Contains(res, "da39a3ee5e6b4b0d3255bfef95601890afd80709  empty space\n") &&
@@ -434,7 +442,7 @@ func TestHashSums(t *testing.T) {
 	// Dropbox Hash Sum
 
 	buf.Reset()
-	err = fs.DropboxHashSum(r.fremote, &buf)
+	err = fs.DropboxHashSum(ctx, r.fremote, &buf)
 	require.NoError(t, err)
 	res = buf.String()
 	if !strings.Contains(res, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  empty space\n") &&
@@ -450,6 +458,7 @@ func TestHashSums(t *testing.T) {
 }
 
 func TestCount(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -462,13 +471,14 @@ func TestCount(t *testing.T) {
 	fs.Config.MaxDepth = 1
 	defer func() { fs.Config.MaxDepth = -1 }()
 
-	objects, size, err := fs.Count(r.fremote)
+	objects, size, err := fs.Count(ctx, r.fremote)
 	require.NoError(t, err)
 	assert.Equal(t, int64(2), objects)
 	assert.Equal(t, int64(60), size)
 }
 
 func TestDelete(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("small", "1234567890", t2)                                                                                           // 10 bytes
@@ -481,19 +491,19 @@ func TestDelete(t *testing.T) {
 		fs.Config.Filter.MaxSize = -1
 	}()
 
-	err := fs.Delete(r.fremote)
+	err := fs.Delete(ctx, r.fremote)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.fremote, file3)
 }
 
-func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs) error) {
+func testCheck(t *testing.T, checkFunction func(ctx context.Context, fdst, fsrc fs.Fs) error) {
 	r := NewRun(t)
 	defer r.Finalise()
 
 	check := func(i int, wantErrors int64) {
 		fs.Debugf(r.fremote, "%d: Starting check test", i)
 		oldErrors := fs.Stats.GetErrors()
-		err := checkFunction(r.flocal, r.fremote)
+		err := checkFunction(context.Background(), r.flocal, r.fremote)
 		gotErrors := fs.Stats.GetErrors() - oldErrors
 		if wantErrors == 0 && err != nil {
 			t.Errorf("%d: Got error when not expecting one: %v", i, err)
@@ -549,7 +559,8 @@ func TestCheckSizeOnly(t *testing.T) {
 }
 
 func (r *Run) checkWithDuplicates(t *testing.T, items ...fstest.Item) {
-	objects, size, err := fs.Count(r.fremote)
+	ctx := context.Background()
+	objects, size, err := fs.Count(ctx, r.fremote)
 	require.NoError(t, err)
 	assert.Equal(t, int64(len(items)), objects)
 	wantSize := int64(0)
@@ -572,6 +583,7 @@ func skipIfCantDedupe(t *testing.T, f fs.Fs) {
 }
 
 func TestDeduplicateInteractive(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.fremote)
@@ -581,13 +593,14 @@ func TestDeduplicateInteractive(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is one", t1)
 	r.checkWithDuplicates(t, file1, file2, file3)
 
-	err := fs.Deduplicate(r.fremote, fs.DeduplicateInteractive)
+	err := fs.Deduplicate(ctx, r.fremote, fs.DeduplicateInteractive)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.fremote, file1)
 }
 
 func TestDeduplicateSkip(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.fremote)
@@ -597,13 +610,14 @@ func TestDeduplicateSkip(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is another one", t1)
 	r.checkWithDuplicates(t, file1, file2, file3)
 
-	err := fs.Deduplicate(r.fremote, fs.DeduplicateSkip)
+	err := fs.Deduplicate(ctx, r.fremote, fs.DeduplicateSkip)
 	require.NoError(t, err)
 
 	r.checkWithDuplicates(t, file1, file3)
 }
 
 func TestDeduplicateFirst(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.fremote)
@@ -613,10 +627,10 @@ func TestDeduplicateFirst(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is one BB", t1)
 	r.checkWithDuplicates(t, file1, file2, file3)
 
-	err := fs.Deduplicate(r.fremote, fs.DeduplicateFirst)
+	err := fs.Deduplicate(ctx, r.fremote, fs.DeduplicateFirst)
 	require.NoError(t, err)
 
-	objects, size, err := fs.Count(r.fremote)
+	objects, size, err := fs.Count(ctx, r.fremote)
 	require.NoError(t, err)
 	assert.Equal(t, int64(1), objects)
 	if size != file1.Size && size != file2.Size && size != file3.Size {
@@ -625,6 +639,7 @@ func TestDeduplicateFirst(t *testing.T) {
 }
 
 func TestDeduplicateNewest(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.fremote)
@@ -634,13 +649,14 @@ func TestDeduplicateNewest(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is another one", t3)
 	r.checkWithDuplicates(t, file1, file2, file3)
 
-	err := fs.Deduplicate(r.fremote, fs.DeduplicateNewest)
+	err := fs.Deduplicate(ctx, r.fremote, fs.DeduplicateNewest)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.fremote, file3)
 }
 
 func TestDeduplicateOldest(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.fremote)
@@ -650,13 +666,14 @@ func TestDeduplicateOldest(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is another one", t3)
 	r.checkWithDuplicates(t, file1, file2, file3)
 
-	err := fs.Deduplicate(r.fremote, fs.DeduplicateOldest)
+	err := fs.Deduplicate(ctx, r.fremote, fs.DeduplicateOldest)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.fremote, file1)
 }
 
 func TestDeduplicateRename(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.fremote)
@@ -666,10 +683,10 @@ func TestDeduplicateRename(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one.txt", "This is another one", t3)
 	r.checkWithDuplicates(t, file1, file2, file3)
 
-	err := fs.Deduplicate(r.fremote, fs.DeduplicateRename)
+	err := fs.Deduplicate(ctx, r.fremote, fs.DeduplicateRename)
 	require.NoError(t, err)
 
-	require.NoError(t, fs.Walk(r.fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+	require.NoError(t, fs.Walk(ctx, r.fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -690,6 +707,7 @@ func TestDeduplicateRename(t *testing.T) {
 }
 
 func TestCat(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("file1", "ABCDEFGHIJ", t1)
@@ -709,7 +727,7 @@ func TestCat(t *testing.T) {
 		{1, 3, "BCD", "123"},
 	} {
 		var buf bytes.Buffer
-		err := fs.Cat(r.fremote, &buf, test.offset, test.count)
+		err := fs.Cat(ctx, r.fremote, &buf, test.offset, test.count)
 		require.NoError(t, err)
 		res := buf.String()
 
@@ -720,25 +738,26 @@ func TestCat(t *testing.T) {
 }
 
 func TestRmdirs(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	r.Mkdir(r.fremote)
 
 	// Clean any directories that have crept in so far
 	// FIXME make the Finalise method do this?
-	require.NoError(t, fs.Rmdirs(r.fremote, ""))
+	require.NoError(t, fs.Rmdirs(ctx, r.fremote, ""))
 
 	// Make some files and dirs we expect to keep
 	r.ForceMkdir(r.fremote)
 	file1 := r.WriteObject("A1/B1/C1/one", "aaa", t1)
 	//..and dirs we expect to delete
-	require.NoError(t, fs.Mkdir(r.fremote, "A2"))
-	require.NoError(t, fs.Mkdir(r.fremote, "A1/B2"))
-	require.NoError(t, fs.Mkdir(r.fremote, "A1/B2/C2"))
-	require.NoError(t, fs.Mkdir(r.fremote, "A1/B1/C3"))
-	require.NoError(t, fs.Mkdir(r.fremote, "A3"))
-	require.NoError(t, fs.Mkdir(r.fremote, "A3/B3"))
-	require.NoError(t, fs.Mkdir(r.fremote, "A3/B3/C4"))
+	require.NoError(t, fs.Mkdir(ctx, r.fremote, "A2"))
+	require.NoError(t, fs.Mkdir(ctx, r.fremote, "A1/B2"))
+	require.NoError(t, fs.Mkdir(ctx, r.fremote, "A1/B2/C2"))
+	require.NoError(t, fs.Mkdir(ctx, r.fremote, "A1/B1/C3"))
+	require.NoError(t, fs.Mkdir(ctx, r.fremote, "A3"))
+	require.NoError(t, fs.Mkdir(ctx, r.fremote, "A3/B3"))
+	require.NoError(t, fs.Mkdir(ctx, r.fremote, "A3/B3/C4"))
 	//..and one more file at the end
 	file2 := r.WriteObject("A1/two", "bbb", t2)
 
@@ -765,7 +784,7 @@ func TestRmdirs(t *testing.T) {
 		fs.Config.ModifyWindow,
 	)
 
-	require.NoError(t, fs.Rmdirs(r.fremote, ""))
+	require.NoError(t, fs.Rmdirs(ctx, r.fremote, ""))
 
 	fstest.CheckListingWithPrecision(
 		t,
@@ -784,6 +803,7 @@ func TestRmdirs(t *testing.T) {
 }
 
 func TestMoveFile(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -793,7 +813,7 @@ func TestMoveFile(t *testing.T) {
 	file2 := file1
 	file2.Path = "sub/file2"
 
-	err := fs.MoveFile(r.fremote, r.flocal, file2.Path, file1.Path)
+	err := fs.MoveFile(ctx, r.fremote, r.flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal)
 	fstest.CheckItems(t, r.fremote, file2)
@@ -801,18 +821,19 @@ func TestMoveFile(t *testing.T) {
 	r.WriteFile("file1", "file1 contents", t1)
 	fstest.CheckItems(t, r.flocal, file1)
 
-	err = fs.MoveFile(r.fremote, r.flocal, file2.Path, file1.Path)
+	err = fs.MoveFile(ctx, r.fremote, r.flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal)
 	fstest.CheckItems(t, r.fremote, file2)
 
-	err = fs.MoveFile(r.fremote, r.fremote, file2.Path, file2.Path)
+	err = fs.MoveFile(ctx, r.fremote, r.fremote, file2.Path, file2.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal)
 	fstest.CheckItems(t, r.fremote, file2)
 }
 
 func TestCopyFile(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -822,17 +843,17 @@ func TestCopyFile(t *testing.T) {
 	file2 := file1
 	file2.Path = "sub/file2"
 
-	err := fs.CopyFile(r.fremote, r.flocal, file2.Path, file1.Path)
+	err := fs.CopyFile(ctx, r.fremote, r.flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file1)
 	fstest.CheckItems(t, r.fremote, file2)
 
-	err = fs.CopyFile(r.fremote, r.flocal, file2.Path, file1.Path)
+	err = fs.CopyFile(ctx, r.fremote, r.flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file1)
 	fstest.CheckItems(t, r.fremote, file2)
 
-	err = fs.CopyFile(r.fremote, r.fremote, file2.Path, file2.Path)
+	err = fs.CopyFile(ctx, r.fremote, r.fremote, file2.Path, file2.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file1)
 	fstest.CheckItems(t, r.fremote, file2)
@@ -933,6 +954,7 @@ func TestOverlapping(t *testing.T) {
 }
 
 func TestListDirSorted(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -967,27 +989,27 @@ func TestListDirSorted(t *testing.T) {
 		return name
 	}
 
-	items, err = fs.ListDirSorted(r.fremote, true, "")
+	items, err = fs.ListDirSorted(ctx, r.fremote, true, "")
 	require.NoError(t, err)
 	require.Len(t, items, 3)
 	assert.Equal(t, "a.txt", str(0))
 	assert.Equal(t, "sub dir/", str(1))
 	assert.Equal(t, "zend.txt", str(2))
 
-	items, err = fs.ListDirSorted(r.fremote, false, "")
+	items, err = fs.ListDirSorted(ctx, r.fremote, false, "")
 	require.NoError(t, err)
 	require.Len(t, items, 2)
 	assert.Equal(t, "sub dir/", str(0))
 	assert.Equal(t, "zend.txt", str(1))
 
-	items, err = fs.ListDirSorted(r.fremote, true, "sub dir")
+	items, err = fs.ListDirSorted(ctx, r.fremote, true, "sub dir")
 	require.NoError(t, err)
 	require.Len(t, items, 3)
 	assert.Equal(t, "sub dir/hello world", str(0))
 	assert.Equal(t, "sub dir/hello world2", str(1))
 	assert.Equal(t, "sub dir/sub sub dir/", str(2))
 
-	items, err = fs.ListDirSorted(r.fremote, false, "sub dir")
+	items, err = fs.ListDirSorted(ctx, r.fremote, false, "sub dir")
 	require.NoError(t, err)
 	require.Len(t, items, 1)
 	assert.Equal(t, "sub dir/sub sub dir/", str(0))
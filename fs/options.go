@@ -31,12 +31,12 @@ func (o *RangeOption) Header() (key string, value string) {
 	key = "Range"
 	value = "bytes="
 	if o.Start >= 0 {
-		value += strconv.FormatInt(o.Start, 64)
+		value += strconv.FormatInt(o.Start, 10)
 
 	}
 	value += "-"
 	if o.End >= 0 {
-		value += strconv.FormatInt(o.End, 64)
+		value += strconv.FormatInt(o.End, 10)
 	}
 	return key, value
 }
@@ -51,6 +51,38 @@ func (o *RangeOption) Mandatory() bool {
 	return false
 }
 
+// Decode interprets the RangeOption into an offset and a limit for
+// an object of the given size.
+//
+// The offset is where to start reading in the object and the limit
+// is the number of bytes to read.  If limit is returned as -1 then
+// the object should be read to the end.
+//
+// A negative Start with a non negative End means read the last End
+// bytes of the object, as in an HTTP suffix range.  If End is bigger
+// than the size then the whole object is read.
+func (o *RangeOption) Decode(size int64) (offset, limit int64) {
+	if o.Start >= 0 {
+		offset = o.Start
+		if o.End >= 0 {
+			limit = o.End - o.Start + 1
+		} else {
+			limit = -1
+		}
+	} else {
+		if o.End >= 0 {
+			offset = size - o.End
+			if offset < 0 {
+				offset = 0
+			}
+		} else {
+			offset = 0
+		}
+		limit = -1
+	}
+	return offset, limit
+}
+
 // SeekOption defines an HTTP Range option with start only.
 type SeekOption struct {
 	Offset int64
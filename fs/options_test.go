@@ -0,0 +1,43 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeOptionHeader(t *testing.T) {
+	for _, test := range []struct {
+		in   RangeOption
+		want string
+	}{
+		{RangeOption{Start: 1, End: 10}, "bytes=1-10"},
+		{RangeOption{Start: 100, End: -1}, "bytes=100-"},
+		{RangeOption{Start: -1, End: 20}, "bytes=-20"},
+	} {
+		key, value := test.in.Header()
+		assert.Equal(t, "Range", key)
+		assert.Equal(t, test.want, value, test.in.String())
+	}
+}
+
+func TestRangeOptionDecode(t *testing.T) {
+	for _, test := range []struct {
+		in         RangeOption
+		size       int64
+		wantOffset int64
+		wantLimit  int64
+	}{
+		{in: RangeOption{Start: 1, End: 10}, size: 100, wantOffset: 1, wantLimit: 10},
+		{in: RangeOption{Start: 10, End: 10}, size: 100, wantOffset: 10, wantLimit: 1},
+		{in: RangeOption{Start: 10, End: -1}, size: 100, wantOffset: 10, wantLimit: -1},
+		{in: RangeOption{Start: -1, End: 20}, size: 100, wantOffset: 80, wantLimit: -1},
+		{in: RangeOption{Start: -1, End: 200}, size: 100, wantOffset: 0, wantLimit: -1},
+		{in: RangeOption{Start: -1, End: -1}, size: 100, wantOffset: 0, wantLimit: -1},
+	} {
+		gotOffset, gotLimit := test.in.Decode(test.size)
+		what := test.in.String()
+		assert.Equal(t, test.wantOffset, gotOffset, what)
+		assert.Equal(t, test.wantLimit, gotLimit, what)
+	}
+}
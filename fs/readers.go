@@ -4,6 +4,7 @@ import (
 	"io"
 
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // A RepeatableReader implements the io.ReadSeeker it allow to seek cached data
@@ -63,3 +64,45 @@ func (r *RepeatableReader) Read(b []byte) (n int, err error) {
 func NewRepeatableReader(r io.Reader) *RepeatableReader {
 	return &RepeatableReader{in: r}
 }
+
+// contextReadCloser is an io.ReadCloser which returns an error from
+// Read once its context has been cancelled
+type contextReadCloser struct {
+	ctx context.Context
+	io.ReadCloser
+}
+
+// NewContextReadCloser wraps in so that Read returns the context's
+// error as soon as ctx is cancelled.  This is used to abort transfers
+// from remotes which can't be cancelled directly.
+func NewContextReadCloser(ctx context.Context, in io.ReadCloser) io.ReadCloser {
+	return &contextReadCloser{ctx: ctx, ReadCloser: in}
+}
+
+// Read bytes from the underlying reader unless the context has been
+// cancelled
+func (r *contextReadCloser) Read(p []byte) (n int, err error) {
+	if err = r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadCloser.Read(p)
+}
+
+// limitedReadCloser adds io.Closer to io.LimitedReader
+type limitedReadCloser struct {
+	*io.LimitedReader
+	io.Closer
+}
+
+// NewLimitedReadCloser returns a LimitedReader wrapped in a Closer
+// which reads at most limit bytes from in.  If limit is < 0 then in
+// is returned unchanged.
+func NewLimitedReadCloser(in io.ReadCloser, limit int64) io.ReadCloser {
+	if limit < 0 {
+		return in
+	}
+	return &limitedReadCloser{
+		LimitedReader: &io.LimitedReader{R: in, N: limit},
+		Closer:        in,
+	}
+}
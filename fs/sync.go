@@ -8,12 +8,14 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 var oldSyncMethod = BoolP("old-sync-method", "", false, "Deprecated - use --fast-list instead")
 
 type syncCopyMove struct {
 	// parameters
+	ctx        context.Context // context passed in by the caller
 	fdst       Fs
 	fsrc       Fs
 	deleteMode DeleteMode // how we are doing deletions
@@ -30,7 +32,8 @@ type syncCopyMove struct {
 	srcFilesChan   chan Object         // passes src objects
 	srcFilesResult chan error          // error result of src listing
 	dstFilesResult chan error          // error result of dst listing
-	abort          chan struct{}       // signal to abort the copiers
+	abortCtx       context.Context     // cancelled to abort the copiers
+	abort          func()              // call to abort the copiers
 	checkerWg      sync.WaitGroup      // wait for checkers
 	toBeChecked    ObjectPairChan      // checkers channel
 	transfersWg    sync.WaitGroup      // wait for transfers
@@ -53,8 +56,9 @@ type syncCopyMove struct {
 	dstListDir     listDirFn           // function to call to list a directory in the dst
 }
 
-func newSyncCopyMove(fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) (*syncCopyMove, error) {
+func newSyncCopyMove(ctx context.Context, fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) (*syncCopyMove, error) {
 	s := &syncCopyMove{
+		ctx:            ctx,
 		fdst:           fdst,
 		fsrc:           fsrc,
 		deleteMode:     deleteMode,
@@ -64,7 +68,6 @@ func newSyncCopyMove(fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) (*syncCo
 		srcFilesResult: make(chan error, 1),
 		dstFilesResult: make(chan error, 1),
 		noTraverse:     Config.NoTraverse,
-		abort:          make(chan struct{}),
 		toBeChecked:    make(ObjectPairChan, Config.Transfers),
 		toBeUploaded:   make(ObjectPairChan, Config.Transfers),
 		deleteFilesCh:  make(chan Object, Config.Checkers),
@@ -73,6 +76,7 @@ func newSyncCopyMove(fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) (*syncCo
 		toBeRenamed:    make(ObjectPairChan, Config.Transfers),
 		trackRenamesCh: make(chan Object, Config.Checkers),
 	}
+	s.abortCtx, s.abort = context.WithCancel(ctx)
 	if s.noTraverse && s.deleteMode != DeleteModeOff {
 		Errorf(nil, "Ignoring --no-traverse with sync")
 		s.noTraverse = false
@@ -131,7 +135,7 @@ type listDirFn func(dir string) (entries DirEntries, err error)
 func (s *syncCopyMove) makeListDir(f Fs, includeAll bool) listDirFn {
 	if !Config.UseListR || f.Features().ListR == nil {
 		return func(dir string) (entries DirEntries, err error) {
-			return ListDirSorted(f, includeAll, dir)
+			return ListDirSorted(s.ctx, f, includeAll, dir)
 		}
 	}
 	var (
@@ -144,7 +148,7 @@ func (s *syncCopyMove) makeListDir(f Fs, includeAll bool) listDirFn {
 		mu.Lock()
 		defer mu.Unlock()
 		if !started {
-			dirs, dirsErr = NewDirTree(f, s.dir, includeAll, Config.MaxDepth)
+			dirs, dirsErr = NewDirTree(s.ctx, f, s.dir, includeAll, Config.MaxDepth)
 			started = true
 		}
 		if dirsErr != nil {
@@ -160,14 +164,10 @@ func (s *syncCopyMove) makeListDir(f Fs, includeAll bool) listDirFn {
 	}
 }
 
-// Check to see if have set the abort flag
+// Check to see if have set the abort flag or the context has been
+// cancelled
 func (s *syncCopyMove) aborting() bool {
-	select {
-	case <-s.abort:
-		return true
-	default:
-	}
-	return false
+	return s.abortCtx.Err() != nil
 }
 
 // This reads the map and pumps it into the channel passed in, closing
@@ -180,7 +180,7 @@ outer:
 		}
 		select {
 		case out <- o:
-		case <-s.abort:
+		case <-s.abortCtx.Done():
 			break outer
 		}
 	}
@@ -193,7 +193,7 @@ outer:
 //
 // Returns a flag which indicates whether the file needs to be
 // transferred or not.
-func NeedTransfer(dst, src Object) bool {
+func NeedTransfer(ctx context.Context, dst, src Object) bool {
 	if dst == nil {
 		Debugf(src, "Couldn't find file - need to transfer")
 		return true
@@ -236,7 +236,7 @@ func NeedTransfer(dst, src Object) bool {
 		}
 	} else {
 		// Check to see if changed or not
-		if Equal(src, dst) {
+		if Equal(ctx, src, dst) {
 			Debugf(src, "Unchanged skipping")
 			return false
 		}
@@ -253,9 +253,7 @@ func (s *syncCopyMove) processError(err error) {
 	defer s.errorMu.Unlock()
 	switch {
 	case IsFatalError(err):
-		if !s.aborting() {
-			close(s.abort)
-		}
+		s.abort()
 		s.fatalErr = err
 	case IsNoRetryError(err):
 		s.noRetryErr = err
@@ -298,12 +296,12 @@ func (s *syncCopyMove) pairChecker(in ObjectPairChan, out ObjectPairChan, wg *sy
 			Stats.Checking(src.Remote())
 			// Check to see if can store this
 			if src.Storable() {
-				if NeedTransfer(pair.dst, pair.src) {
+				if NeedTransfer(s.ctx, pair.dst, pair.src) {
 					// If destination already exists, then we must move it into --backup-dir if required
 					if pair.dst != nil && s.backupDir != nil {
 						remoteWithSuffix := pair.dst.Remote() + s.suffix
-						overwritten, _ := s.backupDir.NewObject(remoteWithSuffix)
-						err := Move(s.backupDir, overwritten, remoteWithSuffix, pair.dst)
+						overwritten, _ := s.backupDir.NewObject(s.ctx, remoteWithSuffix)
+						err := Move(s.ctx, s.backupDir, overwritten, remoteWithSuffix, pair.dst)
 						if err != nil {
 							s.processError(err)
 						} else {
@@ -318,12 +316,12 @@ func (s *syncCopyMove) pairChecker(in ObjectPairChan, out ObjectPairChan, wg *sy
 					// If moving need to delete the files we don't need to copy
 					if s.DoMove {
 						// Delete src if no error on copy
-						s.processError(DeleteFile(src))
+						s.processError(DeleteFile(s.ctx, src))
 					}
 				}
 			}
 			Stats.DoneChecking(src.Remote())
-		case <-s.abort:
+		case <-s.abortCtx.Done():
 			return
 		}
 	}
@@ -347,7 +345,7 @@ func (s *syncCopyMove) pairRenamer(in ObjectPairChan, out ObjectPairChan, wg *sy
 				// pass on if not renamed
 				out <- pair
 			}
-		case <-s.abort:
+		case <-s.abortCtx.Done():
 			return
 		}
 	}
@@ -369,13 +367,13 @@ func (s *syncCopyMove) pairCopyOrMove(in ObjectPairChan, fdst Fs, wg *sync.WaitG
 			src := pair.src
 			Stats.Transferring(src.Remote())
 			if s.DoMove {
-				err = Move(fdst, pair.dst, src.Remote(), src)
+				err = Move(s.ctx, fdst, pair.dst, src.Remote(), src)
 			} else {
-				err = Copy(fdst, pair.dst, src.Remote(), src)
+				err = Copy(s.ctx, fdst, pair.dst, src.Remote(), src)
 			}
 			s.processError(err)
 			Stats.DoneTransferring(src.Remote(), err == nil)
-		case <-s.abort:
+		case <-s.abortCtx.Done():
 			return
 		}
 	}
@@ -463,7 +461,7 @@ func (s *syncCopyMove) startDeleters() {
 	s.deletersWg.Add(1)
 	go func() {
 		defer s.deletersWg.Done()
-		err := deleteFilesWithBackupDir(s.deleteFilesCh, s.backupDir)
+		err := deleteFilesWithBackupDir(s.ctx, s.deleteFilesCh, s.backupDir)
 		s.processError(err)
 	}()
 }
@@ -505,7 +503,7 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 		}
 		close(toDelete)
 	}()
-	return deleteFilesWithBackupDir(toDelete, s.backupDir)
+	return deleteFilesWithBackupDir(s.ctx, toDelete, s.backupDir)
 }
 
 // renameHash makes a string with the size and the hash for rename detection
@@ -606,10 +604,10 @@ func (s *syncCopyMove) tryRename(src Object) bool {
 	}
 
 	// Find dst object we are about to overwrite if it exists
-	dstOverwritten, _ := s.fdst.NewObject(src.Remote())
+	dstOverwritten, _ := s.fdst.NewObject(s.ctx, src.Remote())
 
 	// Rename dst to have name src.Remote()
-	err := Move(s.fdst, dstOverwritten, src.Remote(), dst)
+	err := Move(s.ctx, s.fdst, dstOverwritten, src.Remote(), dst)
 	if err != nil {
 		Debugf(src, "Failed to rename to %q: %v", dst.Remote(), err)
 		return false
@@ -656,7 +654,7 @@ func (s *syncCopyMove) run() error {
 	}
 
 	// Make the destination directory
-	err := Mkdir(s.fdst, s.dir)
+	err := Mkdir(s.ctx, s.fdst, s.dir)
 	if err != nil {
 		return err
 	}
@@ -698,7 +696,7 @@ func (s *syncCopyMove) run() error {
 						}()
 					}
 					traversing.Done()
-				case <-s.abort:
+				case <-s.abortCtx.Done():
 					return
 				}
 			}
@@ -734,13 +732,17 @@ func (s *syncCopyMove) run() error {
 
 	// Delete files after
 	if s.deleteMode == DeleteModeAfter {
-		if s.currentError() != nil {
+		if s.currentError() != nil || s.ctx.Err() != nil {
 			Errorf(s.fdst, "%v", ErrorNotDeleting)
 		} else {
 			s.processError(s.deleteFiles(false))
 		}
 	}
-	return s.currentError()
+	s.abort()
+	if err := s.currentError(); err != nil {
+		return err
+	}
+	return s.ctx.Err()
 }
 
 // Have an object which is in the destination only
@@ -923,7 +925,7 @@ func (s *syncCopyMove) _run(job listDirJob) (jobs []listDirJob) {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) error {
+func runSyncCopyMove(ctx context.Context, fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) error {
 	if *oldSyncMethod {
 		return FatalError(errors.New("--old-sync-method is deprecated use --fast-list instead"))
 	}
@@ -936,7 +938,7 @@ func runSyncCopyMove(fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) error {
 			return FatalError(errors.New("can't use --delete-before with --track-renames"))
 		}
 		// only delete stuff during in this pass
-		do, err := newSyncCopyMove(fdst, fsrc, DeleteModeOnly, false)
+		do, err := newSyncCopyMove(ctx, fdst, fsrc, DeleteModeOnly, false)
 		if err != nil {
 			return err
 		}
@@ -947,7 +949,7 @@ func runSyncCopyMove(fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) error {
 		// Next pass does a copy only
 		deleteMode = DeleteModeOff
 	}
-	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove)
+	do, err := newSyncCopyMove(ctx, fdst, fsrc, deleteMode, DoMove)
 	if err != nil {
 		return err
 	}
@@ -955,22 +957,22 @@ func runSyncCopyMove(fdst, fsrc Fs, deleteMode DeleteMode, DoMove bool) error {
 }
 
 // Sync fsrc into fdst
-func Sync(fdst, fsrc Fs) error {
-	return runSyncCopyMove(fdst, fsrc, Config.DeleteMode, false)
+func Sync(ctx context.Context, fdst, fsrc Fs) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, Config.DeleteMode, false)
 }
 
 // CopyDir copies fsrc into fdst
-func CopyDir(fdst, fsrc Fs) error {
-	return runSyncCopyMove(fdst, fsrc, DeleteModeOff, false)
+func CopyDir(ctx context.Context, fdst, fsrc Fs) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, DeleteModeOff, false)
 }
 
 // moveDir moves fsrc into fdst
-func moveDir(fdst, fsrc Fs) error {
-	return runSyncCopyMove(fdst, fsrc, DeleteModeOff, true)
+func moveDir(ctx context.Context, fdst, fsrc Fs) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, DeleteModeOff, true)
 }
 
 // MoveDir moves fsrc into fdst
-func MoveDir(fdst, fsrc Fs) error {
+func MoveDir(ctx context.Context, fdst, fsrc Fs) error {
 	if Same(fdst, fsrc) {
 		Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
@@ -983,7 +985,7 @@ func MoveDir(fdst, fsrc Fs) error {
 			return nil
 		}
 		Debugf(fdst, "Using server side directory move")
-		err := fdstDirMove(fsrc, "", "")
+		err := fdstDirMove(ctx, fsrc, "", "")
 		switch err {
 		case ErrorCantDirMove, ErrorDirExists:
 			Infof(fdst, "Server side directory move failed - fallback to file moves: %v", err)
@@ -1005,5 +1007,5 @@ func MoveDir(fdst, fsrc Fs) error {
 	}
 
 	// Otherwise move the files one by one
-	return moveDir(fdst, fsrc)
+	return moveDir(ctx, fdst, fsrc)
 }
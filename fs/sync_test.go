@@ -10,17 +10,19 @@ import (
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 // Check dry run is working
 func TestCopyWithDryRun(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
 	r.Mkdir(r.fremote)
 
 	fs.Config.DryRun = true
-	err := fs.CopyDir(r.fremote, r.flocal)
+	err := fs.CopyDir(ctx, r.fremote, r.flocal)
 	fs.Config.DryRun = false
 	require.NoError(t, err)
 
@@ -30,12 +32,13 @@ func TestCopyWithDryRun(t *testing.T) {
 
 // Now without dry run
 func TestCopy(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
 	r.Mkdir(r.fremote)
 
-	err := fs.CopyDir(r.fremote, r.flocal)
+	err := fs.CopyDir(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -44,6 +47,7 @@ func TestCopy(t *testing.T) {
 
 // Now with --no-traverse
 func TestCopyNoTraverse(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -52,7 +56,7 @@ func TestCopyNoTraverse(t *testing.T) {
 
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
 
-	err := fs.CopyDir(r.fremote, r.flocal)
+	err := fs.CopyDir(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -61,6 +65,7 @@ func TestCopyNoTraverse(t *testing.T) {
 
 // Now with --no-traverse
 func TestSyncNoTraverse(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -70,7 +75,7 @@ func TestSyncNoTraverse(t *testing.T) {
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -79,6 +84,7 @@ func TestSyncNoTraverse(t *testing.T) {
 
 // Test copy with depth
 func TestCopyWithDepth(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
@@ -88,7 +94,7 @@ func TestCopyWithDepth(t *testing.T) {
 	fs.Config.MaxDepth = 1
 	defer func() { fs.Config.MaxDepth = -1 }()
 
-	err := fs.CopyDir(r.fremote, r.flocal)
+	err := fs.CopyDir(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1, file2)
@@ -97,6 +103,7 @@ func TestCopyWithDepth(t *testing.T) {
 
 // Test a server side copy if possible, or the backup path if not
 func TestServerSideCopy(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("sub dir/hello world", "hello world", t1)
@@ -107,7 +114,7 @@ func TestServerSideCopy(t *testing.T) {
 	defer finaliseCopy()
 	t.Logf("Server side copy (if possible) %v -> %v", r.fremote, fremoteCopy)
 
-	err = fs.CopyDir(fremoteCopy, r.fremote)
+	err = fs.CopyDir(ctx, fremoteCopy, r.fremote)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, fremoteCopy, file1)
@@ -116,16 +123,17 @@ func TestServerSideCopy(t *testing.T) {
 // Check that if the local file doesn't exist when we copy it up,
 // nothing happens to the remote file
 func TestCopyAfterDelete(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("sub dir/hello world", "hello world", t1)
 	fstest.CheckItems(t, r.flocal)
 	fstest.CheckItems(t, r.fremote, file1)
 
-	err := fs.Mkdir(r.flocal, "")
+	err := fs.Mkdir(ctx, r.flocal, "")
 	require.NoError(t, err)
 
-	err = fs.CopyDir(r.fremote, r.flocal)
+	err = fs.CopyDir(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal)
@@ -134,12 +142,13 @@ func TestCopyAfterDelete(t *testing.T) {
 
 // Check the copy downloading a file
 func TestCopyRedownload(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("sub dir/hello world", "hello world", t1)
 	fstest.CheckItems(t, r.fremote, file1)
 
-	err := fs.CopyDir(r.flocal, r.fremote)
+	err := fs.CopyDir(ctx, r.flocal, r.fremote)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -149,6 +158,7 @@ func TestCopyRedownload(t *testing.T) {
 // If we're only doing sync by size and checksum, we expect nothing to
 // to be transferred on the second sync.
 func TestSyncBasedOnCheckSum(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	fs.Config.CheckSum = true
@@ -158,7 +168,7 @@ func TestSyncBasedOnCheckSum(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file1)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file.
@@ -170,7 +180,7 @@ func TestSyncBasedOnCheckSum(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file2)
 
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.fremote, r.flocal)
+	err = fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred no files
@@ -183,6 +193,7 @@ func TestSyncBasedOnCheckSum(t *testing.T) {
 // file contents but not the size.  If we're only doing sync by size
 // only, we expect nothing to to be transferred on the second sync.
 func TestSyncSizeOnly(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	fs.Config.SizeOnly = true
@@ -192,7 +203,7 @@ func TestSyncSizeOnly(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file1)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file.
@@ -204,7 +215,7 @@ func TestSyncSizeOnly(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file2)
 
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.fremote, r.flocal)
+	err = fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred no files
@@ -217,6 +228,7 @@ func TestSyncSizeOnly(t *testing.T) {
 // the size.  With --ignore-size we expect nothing to to be
 // transferred on the second sync.
 func TestSyncIgnoreSize(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	fs.Config.IgnoreSize = true
@@ -226,7 +238,7 @@ func TestSyncIgnoreSize(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file1)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file.
@@ -238,7 +250,7 @@ func TestSyncIgnoreSize(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file2)
 
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.fremote, r.flocal)
+	err = fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred no files
@@ -248,13 +260,14 @@ func TestSyncIgnoreSize(t *testing.T) {
 }
 
 func TestSyncIgnoreTimes(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("existing", "potato", t1)
 	fstest.CheckItems(t, r.fremote, file1)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred exactly 0 files because the
@@ -265,7 +278,7 @@ func TestSyncIgnoreTimes(t *testing.T) {
 	defer func() { fs.Config.IgnoreTimes = false }()
 
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.fremote, r.flocal)
+	err = fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file even though the
@@ -277,6 +290,7 @@ func TestSyncIgnoreTimes(t *testing.T) {
 }
 
 func TestSyncIgnoreExisting(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("existing", "potato", t1)
@@ -285,7 +299,7 @@ func TestSyncIgnoreExisting(t *testing.T) {
 	defer func() { fs.Config.IgnoreExisting = false }()
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file1)
 	fstest.CheckItems(t, r.fremote, file1)
@@ -293,13 +307,14 @@ func TestSyncIgnoreExisting(t *testing.T) {
 	// Change everything
 	r.WriteFile("existing", "newpotatoes", t2)
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.fremote, r.flocal)
+	err = fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	// Items should not change
 	fstest.CheckItems(t, r.fremote, file1)
 }
 
 func TestSyncAfterChangingModtimeOnly(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("empty space", "", t2)
@@ -312,7 +327,7 @@ func TestSyncAfterChangingModtimeOnly(t *testing.T) {
 	defer func() { fs.Config.DryRun = false }()
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -321,7 +336,7 @@ func TestSyncAfterChangingModtimeOnly(t *testing.T) {
 	fs.Config.DryRun = false
 
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.fremote, r.flocal)
+	err = fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -329,6 +344,7 @@ func TestSyncAfterChangingModtimeOnly(t *testing.T) {
 }
 
 func TestSyncAfterChangingModtimeOnlyWithNoUpdateModTime(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -349,7 +365,7 @@ func TestSyncAfterChangingModtimeOnlyWithNoUpdateModTime(t *testing.T) {
 	fstest.CheckItems(t, r.fremote, file2)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -357,6 +373,7 @@ func TestSyncAfterChangingModtimeOnlyWithNoUpdateModTime(t *testing.T) {
 }
 
 func TestSyncDoesntUpdateModtime(t *testing.T) {
+	ctx := context.Background()
 	if fs.Config.ModifyWindow == fs.ModTimeNotSupported {
 		t.Skip("Can't run this test on fs which doesn't support mod time")
 	}
@@ -370,7 +387,7 @@ func TestSyncDoesntUpdateModtime(t *testing.T) {
 	fstest.CheckItems(t, r.fremote, file2)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.flocal, file1)
@@ -381,6 +398,7 @@ func TestSyncDoesntUpdateModtime(t *testing.T) {
 }
 
 func TestSyncAfterAddingAFile(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("empty space", "", t2)
@@ -390,13 +408,14 @@ func TestSyncAfterAddingAFile(t *testing.T) {
 	fstest.CheckItems(t, r.fremote, file1)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file1, file2)
 	fstest.CheckItems(t, r.fremote, file1, file2)
 }
 
 func TestSyncAfterChangingFilesSizeOnly(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("potato", "------------------------------------------------------------", t3)
@@ -405,7 +424,7 @@ func TestSyncAfterChangingFilesSizeOnly(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file2)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file2)
 	fstest.CheckItems(t, r.fremote, file2)
@@ -414,6 +433,7 @@ func TestSyncAfterChangingFilesSizeOnly(t *testing.T) {
 // Sync after changing a file's contents, changing modtime but length
 // remaining the same
 func TestSyncAfterChangingContentsOnly(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	var file1 fstest.Item
@@ -428,7 +448,7 @@ func TestSyncAfterChangingContentsOnly(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file2)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file2)
 	fstest.CheckItems(t, r.fremote, file2)
@@ -436,6 +456,7 @@ func TestSyncAfterChangingContentsOnly(t *testing.T) {
 
 // Sync after removing a file and adding a file --dry-run
 func TestSyncAfterRemovingAFileAndAddingAFileDryRun(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("potato2", "------------------------------------------------------------", t1)
@@ -444,7 +465,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileDryRun(t *testing.T) {
 
 	fs.Config.DryRun = true
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	fs.Config.DryRun = false
 	require.NoError(t, err)
 
@@ -454,6 +475,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileDryRun(t *testing.T) {
 
 // Sync after removing a file and adding a file
 func TestSyncAfterRemovingAFileAndAddingAFile(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("potato2", "------------------------------------------------------------", t1)
@@ -463,7 +485,7 @@ func TestSyncAfterRemovingAFileAndAddingAFile(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file1, file3)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file1, file3)
 	fstest.CheckItems(t, r.fremote, file1, file3)
@@ -471,6 +493,7 @@ func TestSyncAfterRemovingAFileAndAddingAFile(t *testing.T) {
 
 // Sync after removing a file and adding a file
 func TestSyncAfterRemovingAFileAndAddingAFileSubDir(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("a/potato2", "------------------------------------------------------------", t1)
@@ -480,7 +503,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileSubDir(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file1, file3)
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file1, file3)
 	fstest.CheckItems(t, r.fremote, file1, file3)
@@ -488,6 +511,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileSubDir(t *testing.T) {
 
 // Sync after removing a file and adding a file with IO Errors
 func TestSyncAfterRemovingAFileAndAddingAFileWithErrors(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteFile("potato2", "------------------------------------------------------------", t1)
@@ -498,7 +522,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileWithErrors(t *testing.T) {
 
 	fs.Stats.ResetCounters()
 	fs.Stats.Error()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	assert.Equal(t, fs.ErrorNotDeleting, err)
 	fstest.CheckItems(t, r.flocal, file1, file3)
 	fstest.CheckItems(t, r.fremote, file1, file2, file3)
@@ -533,6 +557,7 @@ func TestSyncDeleteBefore(t *testing.T) {
 
 // Copy test delete before - shouldn't delete anything
 func TestCopyDeleteBefore(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -547,7 +572,7 @@ func TestCopyDeleteBefore(t *testing.T) {
 	fstest.CheckItems(t, r.flocal, file2)
 
 	fs.Stats.ResetCounters()
-	err := fs.CopyDir(r.fremote, r.flocal)
+	err := fs.CopyDir(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.fremote, file1, file2)
@@ -556,6 +581,7 @@ func TestCopyDeleteBefore(t *testing.T) {
 
 // Test with exclude
 func TestSyncWithExclude(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -570,20 +596,21 @@ func TestSyncWithExclude(t *testing.T) {
 	}()
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.fremote, file2, file1)
 
 	// Now sync the other way round and check enormous doesn't get
 	// deleted as it is excluded from the sync
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.flocal, r.fremote)
+	err = fs.Sync(ctx, r.flocal, r.fremote)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file2, file1, file3)
 }
 
 // Test with exclude and delete excluded
 func TestSyncWithExcludeAndDeleteExcluded(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1) // 60 bytes
@@ -600,20 +627,21 @@ func TestSyncWithExcludeAndDeleteExcluded(t *testing.T) {
 	}()
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.fremote, file2)
 
 	// Check sync the other way round to make sure enormous gets
 	// deleted even though it is excluded
 	fs.Stats.ResetCounters()
-	err = fs.Sync(r.flocal, r.fremote)
+	err = fs.Sync(ctx, r.flocal, r.fremote)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.flocal, file2)
 }
 
 // Test with UpdateOlder set
 func TestSyncWithUpdateOlder(t *testing.T) {
+	ctx := context.Background()
 	if fs.Config.ModifyWindow == fs.ModTimeNotSupported {
 		t.Skip("Can't run this test on fs which doesn't support mod time")
 	}
@@ -642,13 +670,14 @@ func TestSyncWithUpdateOlder(t *testing.T) {
 	}()
 
 	fs.Stats.ResetCounters()
-	err := fs.Sync(r.fremote, r.flocal)
+	err := fs.Sync(ctx, r.fremote, r.flocal)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.fremote, oneO, twoF, threeO, fourF, fiveF)
 }
 
 // Test with TrackRenames set
 func TestSyncWithTrackRenames(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -666,7 +695,7 @@ func TestSyncWithTrackRenames(t *testing.T) {
 	f2 := r.WriteFile("yam", "Yam Content", t2)
 
 	fs.Stats.ResetCounters()
-	require.NoError(t, fs.Sync(r.fremote, r.flocal))
+	require.NoError(t, fs.Sync(ctx, r.fremote, r.flocal))
 
 	fstest.CheckItems(t, r.fremote, f1, f2)
 	fstest.CheckItems(t, r.flocal, f1, f2)
@@ -675,7 +704,7 @@ func TestSyncWithTrackRenames(t *testing.T) {
 	f2 = r.RenameFile(f2, "yaml")
 
 	fs.Stats.ResetCounters()
-	require.NoError(t, fs.Sync(r.fremote, r.flocal))
+	require.NoError(t, fs.Sync(ctx, r.fremote, r.flocal))
 
 	fstest.CheckItems(t, r.fremote, f1, f2)
 
@@ -688,6 +717,7 @@ func TestSyncWithTrackRenames(t *testing.T) {
 
 // Test a server side move if possible, or the backup path if not
 func testServerSideMove(t *testing.T, r *Run, fremoteMove fs.Fs, withFilter bool) {
+	ctx := context.Background()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
 	file2 := r.WriteBoth("empty space", "", t2)
 	file3u := r.WriteBoth("potato3", "------------------------------------------------------------ UPDATED", t2)
@@ -703,7 +733,7 @@ func testServerSideMove(t *testing.T, r *Run, fremoteMove fs.Fs, withFilter bool
 
 	// Do server side move
 	fs.Stats.ResetCounters()
-	err := fs.MoveDir(fremoteMove, r.fremote)
+	err := fs.MoveDir(ctx, fremoteMove, r.fremote)
 	require.NoError(t, err)
 
 	if withFilter {
@@ -714,11 +744,11 @@ func testServerSideMove(t *testing.T, r *Run, fremoteMove fs.Fs, withFilter bool
 	fstest.CheckItems(t, fremoteMove, file2, file1, file3u)
 
 	// Purge the original before moving
-	require.NoError(t, fs.Purge(r.fremote))
+	require.NoError(t, fs.Purge(ctx, r.fremote))
 
 	// Move it back again, dst does not exist this time
 	fs.Stats.ResetCounters()
-	err = fs.MoveDir(r.fremote, fremoteMove)
+	err = fs.MoveDir(ctx, r.fremote, fremoteMove)
 	require.NoError(t, err)
 
 	if withFilter {
@@ -758,6 +788,7 @@ func TestServerSideMoveWithFilter(t *testing.T) {
 
 // Test a server side move with overlap
 func TestServerSideMoveOverlap(t *testing.T) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -773,7 +804,7 @@ func TestServerSideMoveOverlap(t *testing.T) {
 	fstest.CheckItems(t, r.fremote, file1)
 
 	// Subdir move with no filters should return ErrorCantMoveOverlapping
-	err = fs.MoveDir(fremoteMove, r.fremote)
+	err = fs.MoveDir(ctx, fremoteMove, r.fremote)
 	assert.EqualError(t, err, fs.ErrorCantMoveOverlapping.Error())
 
 	// Now try with a filter which should also fail with ErrorCantMoveOverlapping
@@ -781,12 +812,13 @@ func TestServerSideMoveOverlap(t *testing.T) {
 	defer func() {
 		fs.Config.Filter.MinSize = -1
 	}()
-	err = fs.MoveDir(fremoteMove, r.fremote)
+	err = fs.MoveDir(ctx, fremoteMove, r.fremote)
 	assert.EqualError(t, err, fs.ErrorCantMoveOverlapping.Error())
 }
 
 // Test with BackupDir set
 func testSyncBackupDir(t *testing.T, suffix string) {
+	ctx := context.Background()
 	r := NewRun(t)
 	defer r.Finalise()
 
@@ -817,7 +849,7 @@ func testSyncBackupDir(t *testing.T, suffix string) {
 	require.NoError(t, err)
 
 	fs.Stats.ResetCounters()
-	err = fs.Sync(fdst, r.flocal)
+	err = fs.Sync(ctx, fdst, r.flocal)
 	require.NoError(t, err)
 
 	// one should be moved to the backup dir and the new one installed
@@ -838,7 +870,7 @@ func testSyncBackupDir(t *testing.T, suffix string) {
 	// This should delete three and overwrite one again, checking
 	// the files got overwritten correctly in backup-dir
 	fs.Stats.ResetCounters()
-	err = fs.Sync(fdst, r.flocal)
+	err = fs.Sync(ctx, fdst, r.flocal)
 	require.NoError(t, err)
 
 	// one should be moved to the backup dir and the new one installed
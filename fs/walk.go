@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // ErrorSkipDir is used as a return value from Walk to indicate that the
@@ -43,6 +44,9 @@ type WalkFunc func(path string, entries DirEntries, err error) error
 //
 // It calls fn for each tranche of DirEntries read.
 //
+// If ctx is cancelled then no further directories will be listed and
+// the context's error will be returned.
+//
 // Note that fn will not be called concurrently whereas the directory
 // listing will proceed concurrently.
 //
@@ -52,35 +56,35 @@ type WalkFunc func(path string, entries DirEntries, err error) error
 // and f supports it and level > 1, or WalkN otherwise.
 //
 // NB (f, path) to be replaced by fs.Dir at some point
-func Walk(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc) error {
+func Walk(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc) error {
 	if (maxLevel < 0 || maxLevel > 1) && Config.UseListR && f.Features().ListR != nil {
-		return WalkR(f, path, includeAll, maxLevel, fn)
+		return WalkR(ctx, f, path, includeAll, maxLevel, fn)
 	}
-	return WalkN(f, path, includeAll, maxLevel, fn)
+	return WalkN(ctx, f, path, includeAll, maxLevel, fn)
 }
 
 // WalkN lists the directory.
 //
 // It implements Walk using non recursive directory listing.
-func WalkN(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc) error {
-	return walk(f, path, includeAll, maxLevel, fn, ListDirSorted)
+func WalkN(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc) error {
+	return walk(ctx, f, path, includeAll, maxLevel, fn, ListDirSorted)
 }
 
 // WalkR lists the directory.
 //
 // It implements Walk using recursive directory listing if
 // available, or returns ErrorCantListR if not.
-func WalkR(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc) error {
+func WalkR(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc) error {
 	listR := f.Features().ListR
 	if listR == nil {
 		return ErrorCantListR
 	}
-	return walkR(f, path, includeAll, maxLevel, fn, listR)
+	return walkR(ctx, f, path, includeAll, maxLevel, fn, listR)
 }
 
-type listDirFunc func(fs Fs, includeAll bool, dir string) (entries DirEntries, err error)
+type listDirFunc func(ctx context.Context, fs Fs, includeAll bool, dir string) (entries DirEntries, err error)
 
-func walk(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listDir listDirFunc) error {
+func walk(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listDir listDirFunc) error {
 	var (
 		wg         sync.WaitGroup // sync closing of go routines
 		traversing sync.WaitGroup // running directory traversals
@@ -116,7 +120,11 @@ func walk(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listDir
 					if !ok {
 						return
 					}
-					entries, err := listDir(f, includeAll, job.remote)
+					var entries DirEntries
+					err := ctx.Err()
+					if err == nil {
+						entries, err = listDir(ctx, f, includeAll, job.remote)
+					}
 					var jobs []listJob
 					if err == nil && job.depth != 0 {
 						entries.ForDir(func(dir *Dir) {
@@ -170,8 +178,13 @@ func walk(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listDir
 	close(in)
 	wg.Wait()
 	close(errs)
-	// return the first error returned or nil
-	return <-errs
+	// return the first error returned or the context error if
+	// the walk was cancelled
+	err := <-errs
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
 }
 
 // DirTree is a map of directories to entries
@@ -259,10 +272,10 @@ func (dt DirTree) String() string {
 	return out.String()
 }
 
-func walkRDirTree(f Fs, path string, includeAll bool, maxLevel int, listR ListRFn) (DirTree, error) {
+func walkRDirTree(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int, listR ListRFn) (DirTree, error) {
 	dirs := make(DirTree)
 	var mu sync.Mutex
-	err := listR(path, func(entries DirEntries) error {
+	err := listR(ctx, path, func(entries DirEntries) error {
 		mu.Lock()
 		defer mu.Unlock()
 		for _, entry := range entries {
@@ -315,16 +328,16 @@ func walkRDirTree(f Fs, path string, includeAll bool, maxLevel int, listR ListRF
 // NewDirTree returns a DirTree filled with the directory listing
 // using the parameters supplied.  This will return ErrorCantListR for
 // remotes which don't support ListR.
-func NewDirTree(f Fs, path string, includeAll bool, maxLevel int) (DirTree, error) {
+func NewDirTree(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int) (DirTree, error) {
 	listR := f.Features().ListR
 	if listR == nil {
 		return nil, ErrorCantListR
 	}
-	return walkRDirTree(f, path, includeAll, maxLevel, listR)
+	return walkRDirTree(ctx, f, path, includeAll, maxLevel, listR)
 }
 
-func walkR(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listR ListRFn) error {
-	dirs, err := walkRDirTree(f, path, includeAll, maxLevel, listR)
+func walkR(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listR ListRFn) error {
+	dirs, err := walkRDirTree(ctx, f, path, includeAll, maxLevel, listR)
 	if err != nil {
 		return err
 	}
@@ -332,6 +345,9 @@ func walkR(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listR
 	skipPrefix := ""
 	emptyDir := DirEntries{}
 	for _, dirPath := range dirs.Dirs() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if skipping {
 			// Skip over directories as required
 			if strings.HasPrefix(dirPath, skipPrefix) {
@@ -359,8 +375,8 @@ func walkR(f Fs, path string, includeAll bool, maxLevel int, fn WalkFunc, listR
 }
 
 // WalkGetAll runs Walk getting all the results
-func WalkGetAll(f Fs, path string, includeAll bool, maxLevel int) (objs []Object, dirs []*Dir, err error) {
-	err = Walk(f, path, includeAll, maxLevel, func(dirPath string, entries DirEntries, err error) error {
+func WalkGetAll(ctx context.Context, f Fs, path string, includeAll bool, maxLevel int) (objs []Object, dirs []*Dir, err error) {
+	err = Walk(ctx, f, path, includeAll, maxLevel, func(dirPath string, entries DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 type (
@@ -40,19 +41,21 @@ var errNotImpl = errors.New("not implemented")
 
 type mockObject string
 
-func (o mockObject) String() string                                    { return string(o) }
-func (o mockObject) Fs() Info                                          { return nil }
-func (o mockObject) Remote() string                                    { return string(o) }
-func (o mockObject) Hash(HashType) (string, error)                     { return "", errNotImpl }
-func (o mockObject) ModTime() (t time.Time)                            { return t }
-func (o mockObject) Size() int64                                       { return 0 }
-func (o mockObject) Storable() bool                                    { return true }
-func (o mockObject) SetModTime(time.Time) error                        { return errNotImpl }
-func (o mockObject) Open(options ...OpenOption) (io.ReadCloser, error) { return nil, errNotImpl }
-func (o mockObject) Update(in io.Reader, src ObjectInfo, options ...OpenOption) error {
+func (o mockObject) String() string                              { return string(o) }
+func (o mockObject) Fs() Info                                    { return nil }
+func (o mockObject) Remote() string                              { return string(o) }
+func (o mockObject) Hash(HashType) (string, error)               { return "", errNotImpl }
+func (o mockObject) ModTime() (t time.Time)                      { return t }
+func (o mockObject) Size() int64                                 { return 0 }
+func (o mockObject) Storable() bool                              { return true }
+func (o mockObject) SetModTime(context.Context, time.Time) error { return errNotImpl }
+func (o mockObject) Open(ctx context.Context, options ...OpenOption) (io.ReadCloser, error) {
+	return nil, errNotImpl
+}
+func (o mockObject) Update(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) error {
 	return errNotImpl
 }
-func (o mockObject) Remove() error { return errNotImpl }
+func (o mockObject) Remove(context.Context) error { return errNotImpl }
 
 func newListDirs(t *testing.T, f Fs, includeAll bool, results listResults, walkErrors errorMap, finalError error) *listDirs {
 	return &listDirs{
@@ -81,7 +84,7 @@ func (ls *listDirs) SetLevel(maxLevel int) *listDirs {
 }
 
 // ListDir returns the expected listing for the directory
-func (ls *listDirs) ListDir(f Fs, includeAll bool, dir string) (entries DirEntries, err error) {
+func (ls *listDirs) ListDir(ctx context.Context, f Fs, includeAll bool, dir string) (entries DirEntries, err error) {
 	ls.mu.Lock()
 	defer ls.mu.Unlock()
 	assert.Equal(ls.t, ls.fs, f)
@@ -102,7 +105,7 @@ func (ls *listDirs) ListDir(f Fs, includeAll bool, dir string) (entries DirEntri
 }
 
 // ListR returns the expected listing for the directory using ListR
-func (ls *listDirs) ListR(dir string, callback ListRCallback) (err error) {
+func (ls *listDirs) ListR(ctx context.Context, dir string, callback ListRCallback) (err error) {
 	ls.mu.Lock()
 	defer ls.mu.Unlock()
 
@@ -163,14 +166,14 @@ func (ls *listDirs) WalkFn(dir string, entries DirEntries, err error) error {
 
 // Walk does the walk and tests the expectations
 func (ls *listDirs) Walk() {
-	err := walk(nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListDir)
+	err := walk(context.Background(), nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListDir)
 	assert.Equal(ls.t, ls.finalError, err)
 	ls.IsFinished()
 }
 
 // WalkR does the walkR and tests the expectations
 func (ls *listDirs) WalkR() {
-	err := walkR(nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListR)
+	err := walkR(context.Background(), nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListR)
 	assert.Equal(ls.t, ls.finalError, err)
 	if ls.finalError == nil {
 		ls.IsFinished()
@@ -409,9 +412,38 @@ func testWalkMultiErrors(t *testing.T) *listDirs {
 func TestWalkMultiErrors(t *testing.T)  { testWalkMultiErrors(t).Walk() }
 func TestWalkRMultiErrors(t *testing.T) { testWalkMultiErrors(t).Walk() }
 
+func TestWalkCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := 0
+	err := walk(ctx, nil, "", true, -1, func(dir string, entries DirEntries, err error) error {
+		called++
+		assert.Equal(t, context.Canceled, err)
+		return nil
+	}, func(ctx context.Context, f Fs, includeAll bool, dir string) (DirEntries, error) {
+		t.Errorf("Unexpected list of %q", dir)
+		return nil, nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, called)
+}
+
+func TestWalkRCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	listR := func(ctx context.Context, dir string, callback ListRCallback) error {
+		cancel()
+		return callback(DirEntries{newDir("a"), mockObject("a/one")})
+	}
+	err := walkR(ctx, nil, "", true, -1, func(dir string, entries DirEntries, err error) error {
+		t.Errorf("Unexpected walk of %q", dir)
+		return nil
+	}, listR)
+	assert.Equal(t, context.Canceled, err)
+}
+
 // a very simple listRcallback function
 func makeListRCallback(entries DirEntries, err error) ListRFn {
-	return func(dir string, callback ListRCallback) error {
+	return func(ctx context.Context, dir string, callback ListRCallback) error {
 		if err == nil {
 			err = callback(entries)
 		}
@@ -556,7 +588,7 @@ a/
   b/
 `, nil, "", 2},
 	} {
-		r, err := walkRDirTree(nil, test.root, true, test.level, makeListRCallback(test.entries, test.err))
+		r, err := walkRDirTree(context.Background(), nil, test.root, true, test.level, makeListRCallback(test.entries, test.err))
 		assert.Equal(t, test.err, err, fmt.Sprintf("%+v", test))
 		assert.Equal(t, test.want, r.String(), fmt.Sprintf("%+v", test))
 	}
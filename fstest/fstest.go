@@ -16,12 +16,14 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -192,7 +194,7 @@ func CheckListingWithPrecision(t *testing.T, f fs.Fs, items []Item, expectedDirs
 	gotListing := "<unset>"
 	listingOK := false
 	for i := 1; i <= retries; i++ {
-		objs, dirs, err = fs.WalkGetAll(f, "", true, -1)
+		objs, dirs, err = fs.WalkGetAll(context.Background(), f, "", true, -1)
 		if err != nil && err != fs.ErrorDirNotFound {
 			t.Fatalf("Error listing: %v", err)
 		}
@@ -344,9 +346,9 @@ func RandomRemote(remoteName string, subdir bool) (fs.Fs, string, func(), error)
 	}
 
 	finalise := func() {
-		_ = fs.Purge(remote) // ignore error
+		_ = fs.Purge(context.Background(), remote) // ignore error
 		if parentRemote != nil {
-			err = fs.Purge(parentRemote) // ignore error
+			err = fs.Purge(context.Background(), parentRemote) // ignore error
 			if err != nil {
 				log.Printf("Failed to purge %v: %v", parentRemote, err)
 			}
@@ -358,20 +360,74 @@ func RandomRemote(remoteName string, subdir bool) (fs.Fs, string, func(), error)
 
 // TestMkdir tests Mkdir works
 func TestMkdir(t *testing.T, remote fs.Fs) {
-	err := fs.Mkdir(remote, "")
+	err := fs.Mkdir(context.Background(), remote, "")
 	require.NoError(t, err)
 	CheckListing(t, remote, []Item{})
 }
 
 // TestPurge tests Purge works
 func TestPurge(t *testing.T, remote fs.Fs) {
-	err := fs.Purge(remote)
+	err := fs.Purge(context.Background(), remote)
 	require.NoError(t, err)
 	CheckListing(t, remote, []Item{})
 }
 
 // TestRmdir tests Rmdir works
 func TestRmdir(t *testing.T, remote fs.Fs) {
-	err := fs.Rmdir(remote, "")
+	err := fs.Rmdir(context.Background(), remote, "")
 	require.NoError(t, err)
 }
+
+var loadConfigOnce sync.Once
+
+// WrapperRemote is a remote of a backend which wraps other remotes,
+// such as cache or chunker, made for the internal tests of that
+// backend.
+type WrapperRemote struct {
+	Fs          fs.Fs    // the remote
+	Dirs        []string // the local directories it wraps
+	cacheDir    string   // temporary directory used for fs.CacheDir
+	oldCacheDir string   // the previous value of fs.CacheDir
+}
+
+// NewWrapperRemote makes a remote called name of type backend which
+// wraps n new local temporary directories.
+//
+// The config for name is set to config with "remote" set to the
+// directory, or "remotes" set to the space separated directories if
+// n > 1.  fs.CacheDir points to a temporary directory until Finalise
+// is called so any storage the backend keeps there starts empty.
+func NewWrapperRemote(t *testing.T, backend, name string, n int, config map[string]string) *WrapperRemote {
+	loadConfigOnce.Do(fs.LoadConfig)
+	r := &WrapperRemote{oldCacheDir: fs.CacheDir}
+	for i := 0; i < n; i++ {
+		dir, err := ioutil.TempDir("", "rclone-"+backend)
+		require.NoError(t, err)
+		r.Dirs = append(r.Dirs, dir)
+	}
+	cacheDir, err := ioutil.TempDir("", "rclone-"+backend+"-cache")
+	require.NoError(t, err)
+	r.cacheDir = cacheDir
+	fs.CacheDir = cacheDir
+
+	fs.ConfigFileSet(name, "type", backend)
+	if n == 1 {
+		fs.ConfigFileSet(name, "remote", r.Dirs[0])
+	} else {
+		fs.ConfigFileSet(name, "remotes", strings.Join(r.Dirs, " "))
+	}
+	for key, value := range config {
+		fs.ConfigFileSet(name, key, value)
+	}
+	r.Fs, err = fs.NewFs(name + ":")
+	require.NoError(t, err)
+	return r
+}
+
+// Finalise removes the directories and restores fs.CacheDir
+func (r *WrapperRemote) Finalise() {
+	fs.CacheDir = r.oldCacheDir
+	for _, dir := range append(r.Dirs, r.cacheDir) {
+		_ = os.RemoveAll(dir)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -136,8 +137,9 @@ func TestFsRmdirEmpty(t *testing.T) {
 
 // TestFsRmdirNotFound tests deleting a non existent directory
 func TestFsRmdirNotFound(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	err := remote.Rmdir("")
+	err := remote.Rmdir(ctx, "")
 	assert.Error(t, err, "Expecting error on Rmdir non existent")
 }
 
@@ -150,17 +152,18 @@ func TestFsMkdir(t *testing.T) {
 
 // TestFsMkdirRmdirSubdir tests making and removing a sub directory
 func TestFsMkdirRmdirSubdir(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	dir := "dir/subdir"
-	err := fs.Mkdir(remote, dir)
+	err := fs.Mkdir(ctx, remote, dir)
 	require.NoError(t, err)
 	fstest.CheckListingWithPrecision(t, remote, []fstest.Item{}, []string{"dir", "dir/subdir"}, fs.Config.ModifyWindow)
 
-	err = fs.Rmdir(remote, dir)
+	err = fs.Rmdir(ctx, remote, dir)
 	require.NoError(t, err)
 	fstest.CheckListingWithPrecision(t, remote, []fstest.Item{}, []string{"dir"}, fs.Config.ModifyWindow)
 
-	err = fs.Rmdir(remote, "dir")
+	err = fs.Rmdir(ctx, remote, "dir")
 	require.NoError(t, err)
 	fstest.CheckListingWithPrecision(t, remote, []fstest.Item{}, []string{}, fs.Config.ModifyWindow)
 }
@@ -204,8 +207,9 @@ func objsToNames(objs []fs.Object) []string {
 
 // TestFsListDirEmpty tests listing the directories from an empty directory
 func TestFsListDirEmpty(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	objs, dirs, err := fs.WalkGetAll(remote, "", true, 1)
+	objs, dirs, err := fs.WalkGetAll(ctx, remote, "", true, 1)
 	require.NoError(t, err)
 	assert.Equal(t, []string{}, objsToNames(objs))
 	assert.Equal(t, []string{}, dirsToNames(dirs))
@@ -219,22 +223,24 @@ func TestFsListRDirEmpty(t *testing.T) {
 
 // TestFsNewObjectNotFound tests not finding a object
 func TestFsNewObjectNotFound(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	// Object in an existing directory
-	o, err := remote.NewObject("potato")
+	o, err := remote.NewObject(ctx, "potato")
 	assert.Nil(t, o)
 	assert.Equal(t, fs.ErrorObjectNotFound, err)
 	// Now try an object in a non existing directory
-	o, err = remote.NewObject("directory/not/found/potato")
+	o, err = remote.NewObject(ctx, "directory/not/found/potato")
 	assert.Nil(t, o)
 	assert.Equal(t, fs.ErrorObjectNotFound, err)
 }
 
 func findObject(t *testing.T, Name string) fs.Object {
+	ctx := context.Background()
 	var obj fs.Object
 	var err error
 	for i := 1; i <= *fstest.ListRetries; i++ {
-		obj, err = remote.NewObject(Name)
+		obj, err = remote.NewObject(ctx, Name)
 		if err == nil {
 			break
 		}
@@ -246,6 +252,7 @@ func findObject(t *testing.T, Name string) fs.Object {
 }
 
 func testPut(t *testing.T, file *fstest.Item) string {
+	ctx := context.Background()
 	tries := 1
 	const maxTries = 10
 again:
@@ -256,7 +263,7 @@ again:
 
 	file.Size = int64(buf.Len())
 	obji := fs.NewStaticObjectInfo(file.Path, file.ModTime, file.Size, true, nil, nil)
-	obj, err := remote.Put(in, obji)
+	obj, err := remote.Put(ctx, in, obji)
 	if err != nil {
 		// Retry if err returned a retry error
 		if fs.IsRetryError(err) && tries < maxTries {
@@ -295,6 +302,7 @@ func (er errorReader) Read(p []byte) (n int, err error) {
 // It makes sure that aborting a file half way through does not create
 // a file on the remote.
 func TestFsPutError(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Read 50 bytes then produce an error
@@ -304,11 +312,11 @@ func TestFsPutError(t *testing.T) {
 	in := io.MultiReader(buf, er)
 
 	obji := fs.NewStaticObjectInfo(file2.Path, file2.ModTime, 100, true, nil, nil)
-	_, err := remote.Put(in, obji)
+	_, err := remote.Put(ctx, in, obji)
 	// assert.Nil(t, obj) - FIXME some remotes return the object even on nil
 	assert.NotNil(t, err)
 
-	obj, err := remote.NewObject(file2.Path)
+	obj, err := remote.NewObject(ctx, file2.Path)
 	assert.Nil(t, obj)
 	assert.Equal(t, fs.ErrorObjectNotFound, err)
 }
@@ -329,13 +337,14 @@ func TestFsUpdateFile1(t *testing.T) {
 // TestFsListDirFile2 tests the files are correctly uploaded by doing
 // Depth 1 directory listings
 func TestFsListDirFile2(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	list := func(dir string, expectedDirNames, expectedObjNames []string) {
 		var objNames, dirNames []string
 		for i := 1; i <= *fstest.ListRetries; i++ {
-			objs, dirs, err := fs.WalkGetAll(remote, dir, true, 1)
+			objs, dirs, err := fs.WalkGetAll(ctx, remote, dir, true, 1)
 			if errors.Cause(err) == fs.ErrorDirNotFound {
-				objs, dirs, err = fs.WalkGetAll(remote, winPath(dir), true, 1)
+				objs, dirs, err = fs.WalkGetAll(ctx, remote, winPath(dir), true, 1)
 			}
 			require.NoError(t, err)
 			objNames = objsToNames(objs)
@@ -379,10 +388,11 @@ func TestFsListRDirFile2(t *testing.T) {
 
 // TestFsListDirRoot tests that DirList works in the root
 func TestFsListDirRoot(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	rootRemote, err := fs.NewFs(RemoteName)
 	require.NoError(t, err)
-	_, dirs, err := fs.WalkGetAll(rootRemote, "", true, 1)
+	_, dirs, err := fs.WalkGetAll(ctx, rootRemote, "", true, 1)
 	require.NoError(t, err)
 	assert.Contains(t, dirsToNames(dirs), subRemoteLeaf, "Remote leaf not found")
 }
@@ -395,6 +405,7 @@ func TestFsListRDirRoot(t *testing.T) {
 
 // TestFsListSubdir tests List works for a subdirectory
 func TestFsListSubdir(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	fileName := file2.Path
 	var err error
@@ -403,7 +414,7 @@ func TestFsListSubdir(t *testing.T) {
 	for i := 0; i < 2; i++ {
 		dir, _ := path.Split(fileName)
 		dir = dir[:len(dir)-1]
-		objs, dirs, err = fs.WalkGetAll(remote, dir, true, -1)
+		objs, dirs, err = fs.WalkGetAll(ctx, remote, dir, true, -1)
 		if err != fs.ErrorDirNotFound {
 			break
 		}
@@ -423,8 +434,9 @@ func TestFsListRSubdir(t *testing.T) {
 
 // TestFsListLevel2 tests List works for 2 levels
 func TestFsListLevel2(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	objs, dirs, err := fs.WalkGetAll(remote, "", true, 2)
+	objs, dirs, err := fs.WalkGetAll(ctx, remote, "", true, 2)
 	if err == fs.ErrorLevelNotSupported {
 		return
 	}
@@ -460,15 +472,17 @@ func TestFsListFile1and2(t *testing.T) {
 
 // TestFsNewObjectDir tests NewObject on a directory which should produce an error
 func TestFsNewObjectDir(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	dir := path.Dir(file2.Path)
-	obj, err := remote.NewObject(dir)
+	obj, err := remote.NewObject(ctx, dir)
 	assert.Nil(t, obj)
 	assert.NotNil(t, err)
 }
 
 // TestFsCopy tests Copy
 func TestFsCopy(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have Copy
@@ -482,7 +496,7 @@ func TestFsCopy(t *testing.T) {
 
 	// do the copy
 	src := findObject(t, file1.Path)
-	dst, err := doCopy(src, file1Copy.Path)
+	dst, err := doCopy(ctx, src, file1Copy.Path)
 	if err == fs.ErrorCantCopy {
 		t.Skip("FS can't copy")
 	}
@@ -495,13 +509,14 @@ func TestFsCopy(t *testing.T) {
 	assert.Equal(t, file1Copy.Path, dst.Remote())
 
 	// Delete copy
-	err = dst.Remove()
+	err = dst.Remove(ctx)
 	require.NoError(t, err)
 
 }
 
 // TestFsMove tests Move
 func TestFsMove(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have Move
@@ -522,7 +537,7 @@ func TestFsMove(t *testing.T) {
 	file2Move.Path = "other.txt"
 	file2Move.WinPath = ""
 	src := findObject(t, file2.Path)
-	dst, err := doMove(src, file2Move.Path)
+	dst, err := doMove(ctx, src, file2Move.Path)
 	if err == fs.ErrorCantMove {
 		t.Skip("FS can't move")
 	}
@@ -537,7 +552,7 @@ func TestFsMove(t *testing.T) {
 	// Check conflict on "rename, then move"
 	file1Move.Path = "moveTest/other.txt"
 	src = findObject(t, file1.Path)
-	_, err = doMove(src, file1Move.Path)
+	_, err = doMove(ctx, src, file1Move.Path)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file1Move, file2Move})
 	// 1: moveTest/other.txt
@@ -545,14 +560,14 @@ func TestFsMove(t *testing.T) {
 
 	// Check conflict on "move, then rename"
 	src = findObject(t, file1Move.Path)
-	_, err = doMove(src, file1.Path)
+	_, err = doMove(ctx, src, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file1, file2Move})
 	// 1: file name.txt
 	// 2: other.txt
 
 	src = findObject(t, file2Move.Path)
-	_, err = doMove(src, file2.Path)
+	_, err = doMove(ctx, src, file2.Path)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file1, file2})
 	// 1: file name.txt
@@ -571,6 +586,7 @@ func TestFsMove(t *testing.T) {
 //
 // go test -v -run '^Test(Setup|Init|FsMkdir|FsPutFile1|FsPutFile2|FsUpdateFile1|FsDirMove)$
 func TestFsDirMove(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have DirMove
@@ -580,7 +596,7 @@ func TestFsDirMove(t *testing.T) {
 	}
 
 	// Check it can't move onto itself
-	err := doDirMove(remote, "", "")
+	err := doDirMove(ctx, remote, "", "")
 	require.Equal(t, fs.ErrorDirExists, err)
 
 	// new remote
@@ -590,7 +606,7 @@ func TestFsDirMove(t *testing.T) {
 
 	const newName = "new_name/sub_new_name"
 	// try the move
-	err = newRemote.Features().DirMove(remote, "", newName)
+	err = newRemote.Features().DirMove(ctx, remote, "", newName)
 	require.NoError(t, err)
 
 	// check remotes
@@ -604,7 +620,7 @@ func TestFsDirMove(t *testing.T) {
 	fstest.CheckListing(t, newRemote, []fstest.Item{file2Copy, file1Copy})
 
 	// move it back
-	err = doDirMove(newRemote, newName, "")
+	err = doDirMove(ctx, newRemote, newName, "")
 	require.NoError(t, err)
 
 	// check remotes
@@ -614,8 +630,9 @@ func TestFsDirMove(t *testing.T) {
 
 // TestFsRmdirFull tests removing a non empty directory
 func TestFsRmdirFull(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	err := remote.Rmdir("")
+	err := remote.Rmdir(ctx, "")
 	require.Error(t, err, "Expecting error on RMdir on non empty remote")
 }
 
@@ -637,6 +654,7 @@ func TestFsPrecision(t *testing.T) {
 //
 // go test -v -remote TestDrive: -run '^Test(Setup|Init|FsDirChangeNotify)$' -verbose
 func TestFsDirChangeNotify(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have DirChangeNotify
@@ -645,7 +663,7 @@ func TestFsDirChangeNotify(t *testing.T) {
 		t.Skip("FS has no DirChangeNotify interface")
 	}
 
-	err := fs.Mkdir(remote, "dir")
+	err := fs.Mkdir(ctx, remote, "dir")
 	require.NoError(t, err)
 
 	changes := []string{}
@@ -654,7 +672,7 @@ func TestFsDirChangeNotify(t *testing.T) {
 	}, time.Second)
 	defer func() { close(quitChannel) }()
 
-	err = fs.Mkdir(remote, "dir/subdir")
+	err = fs.Mkdir(ctx, remote, "dir/subdir")
 	require.NoError(t, err)
 
 	time.Sleep(2 * time.Second)
@@ -723,10 +741,11 @@ func TestObjectMimeType(t *testing.T) {
 
 // TestObjectSetModTime tests that SetModTime works
 func TestObjectSetModTime(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	newModTime := fstest.Time("2011-12-13T14:15:16.999999999Z")
 	obj := findObject(t, file1.Path)
-	err := obj.SetModTime(newModTime)
+	err := obj.SetModTime(ctx, newModTime)
 	if err == fs.ErrorCantSetModTime || err == fs.ErrorCantSetModTimeWithoutDelete {
 		t.Log(err)
 		return
@@ -747,7 +766,8 @@ func TestObjectSize(t *testing.T) {
 
 // read the contents of an object as a string
 func readObject(t *testing.T, obj fs.Object, limit int64, options ...fs.OpenOption) string {
-	in, err := obj.Open(options...)
+	ctx := context.Background()
+	in, err := obj.Open(ctx, options...)
 	require.NoError(t, err)
 	var r io.Reader = in
 	if limit >= 0 {
@@ -783,6 +803,7 @@ func TestObjectPartialRead(t *testing.T) {
 
 // TestObjectUpdate tests that Update works
 func TestObjectUpdate(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	contents := fstest.RandomString(200)
 	buf := bytes.NewBufferString(contents)
@@ -792,7 +813,7 @@ func TestObjectUpdate(t *testing.T) {
 	file1.Size = int64(buf.Len())
 	obj := findObject(t, file1.Path)
 	obji := fs.NewStaticObjectInfo(file1.Path, file1.ModTime, int64(len(contents)), true, nil, obj.Fs())
-	err := obj.Update(in, obji)
+	err := obj.Update(ctx, in, obji)
 	require.NoError(t, err)
 	file1.Hashes = hash.Sums()
 
@@ -837,20 +858,41 @@ func TestFsIsFileNotFound(t *testing.T) {
 	fstest.CheckListing(t, fileRemote, []fstest.Item{})
 }
 
+// TestFsPublicLink tests making a public link to a file
+func TestFsPublicLink(t *testing.T) {
+	ctx := context.Background()
+	skipIfNotOk(t)
+
+	// Check have PublicLink
+	doPublicLink := remote.Features().PublicLink
+	if doPublicLink == nil {
+		t.Skip("FS has no PublicLinker interface")
+	}
+
+	link, err := doPublicLink(ctx, file1.Path, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, "", link)
+
+	_, err = doPublicLink(ctx, "not found.txt", 0)
+	assert.Error(t, err)
+}
+
 // TestObjectRemove tests Remove
 func TestObjectRemove(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	obj := findObject(t, file1.Path)
-	err := obj.Remove()
+	err := obj.Remove(ctx)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file2})
 }
 
 // TestObjectPurge tests Purge
 func TestObjectPurge(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	fstest.TestPurge(t, remote)
-	err := fs.Purge(remote)
+	err := fs.Purge(ctx, remote)
 	assert.Error(t, err, "Expecting error after on second purge")
 }
 
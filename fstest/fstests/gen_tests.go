@@ -62,7 +62,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest/fstests"
 	"github.com/ncw/rclone/{{ .FsName }}"
-{{ if eq .FsName "crypt" }}	_ "github.com/ncw/rclone/local"
+{{ if or (eq .FsName "crypt") (eq .FsName "cache") (eq .FsName "union") (eq .FsName "chunker") (eq .FsName "compress") (eq .FsName "hasher") }}	_ "github.com/ncw/rclone/local"
 {{end}})
 
 func TestSetup{{ .Suffix }}(t *testing.T)() {
@@ -141,7 +141,14 @@ func main() {
 	generateTestProgram(t, fns, "Crypt", "")
 	generateTestProgram(t, fns, "Crypt", "2")
 	generateTestProgram(t, fns, "Crypt", "3")
+	generateTestProgram(t, fns, "Cache", "")
+	generateTestProgram(t, fns, "Union", "")
+	generateTestProgram(t, fns, "Chunker", "")
+	generateTestProgram(t, fns, "Compress", "")
+	generateTestProgram(t, fns, "Hasher", "")
 	generateTestProgram(t, fns, "Sftp", "")
 	generateTestProgram(t, fns, "FTP", "")
+	generateTestProgram(t, fns, "Webdav", "")
+	generateTestProgram(t, fns, "Memory", "")
 	log.Printf("Done")
 }
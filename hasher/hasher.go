@@ -0,0 +1,568 @@
+// Package hasher provides wrappers for Fs and Object which calculate
+// and store hashes for remotes which don't support them
+package hasher
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// hashNames are the names of the hashes which can be stored
+var hashNames = map[string]fs.HashType{
+	"md5":  fs.HashMD5,
+	"sha1": fs.HashSHA1,
+}
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "hasher",
+		Description: "Store hashes for a remote",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remote",
+			Help: "Remote to store hashes for.\nNormally should contain a ':' and a path, eg \"myremote:path/to/dir\",\n\"myremote:bucket\" or maybe \"myremote:\" (not recommended).",
+		}, {
+			Name: "hashes",
+			Help: "Comma separated list of hashes to store.\nDefault: md5,sha1",
+			Examples: []fs.OptionExample{
+				{
+					Value: "md5,sha1",
+					Help:  "MD5 and SHA1",
+				}, {
+					Value: "md5",
+					Help:  "MD5 only",
+				}, {
+					Value: "sha1",
+					Help:  "SHA1 only",
+				},
+			},
+			Optional: true,
+		}, {
+			Name:     "auto_size",
+			Help:     "Read files up to this size to calculate missing hashes when they are asked for.\nDefault: off",
+			Optional: true,
+		}},
+	})
+}
+
+// parseHashes parses a comma separated list of hash names
+func parseHashes(value string) (hashes fs.HashSet, err error) {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		hashType, ok := hashNames[name]
+		if !ok {
+			return hashes, errors.Errorf("unknown hash %q", name)
+		}
+		hashes.Add(hashType)
+	}
+	return hashes, nil
+}
+
+// NewFs contstructs an Fs from the path, container:path
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remote := fs.ConfigFileGet(name, "remote")
+	if strings.HasPrefix(remote, name+":") {
+		return nil, errors.New("can't point hasher remote at itself - check the value of the remote setting")
+	}
+	hashes, err := parseHashes(fs.ConfigFileGet(name, "hashes", "md5,sha1"))
+	if err != nil {
+		return nil, err
+	}
+	autoSize := fs.SizeSuffix(-1)
+	if value := fs.ConfigFileGet(name, "auto_size"); value != "" {
+		err = autoSize.Set(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse auto_size %q", value)
+		}
+	}
+	st, err := getStore(name)
+	if err != nil {
+		return nil, err
+	}
+	remotePath := path.Join(remote, rpath)
+	wrappedFs, err := fs.NewFs(remotePath)
+	if err != fs.ErrorIsFile && err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
+	}
+	prefix := rpath
+	if err == fs.ErrorIsFile {
+		prefix = path.Dir(rpath)
+		if prefix == "." {
+			prefix = ""
+		}
+	}
+	f := &Fs{
+		Fs:       wrappedFs,
+		name:     name,
+		root:     rpath,
+		prefix:   prefix,
+		store:    st,
+		hashes:   hashes &^ wrappedFs.Hashes(),
+		autoSize: int64(autoSize),
+	}
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from wrappedFs
+	f.features = (&fs.Features{
+		CaseInsensitive: true,
+		DuplicateFiles:  true,
+		ReadMimeType:    true,
+		WriteMimeType:   true,
+	}).Fill(f).Mask(wrappedFs)
+	return f, err
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	prefix   string       // path of the wrapped Fs in the wrapped remote
+	features *fs.Features // optional features
+	store    *store       // where the hashes are stored
+	hashes   fs.HashSet   // hashes calculated by this Fs
+	autoSize int64        // read objects up to this size to find hashes
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Hasher drive '%s:%s'", f.name, f.root)
+}
+
+// Hashes returns the supported hash sets.
+//
+// These are the hashes of the wrapped remote and the hashes stored
+// by this Fs.
+func (f *Fs) Hashes() fs.HashSet {
+	return f.Fs.Hashes() | f.hashes
+}
+
+// key returns the key the hashes of remote are stored under
+func (f *Fs) key(remote string) string {
+	return path.Join(f.prefix, remote)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = f.newObject(o)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// newHasher returns a MultiHasher for the hashes calculated by f or
+// nil if there aren't any
+func (f *Fs) newHasher() *fs.MultiHasher {
+	if f.hashes == 0 {
+		return nil
+	}
+	hasher, err := fs.NewMultiHasherTypes(f.hashes)
+	if err != nil {
+		fs.Errorf(f, "hasher: failed to make hasher: %v", err)
+		return nil
+	}
+	return hasher
+}
+
+// storeHashes stores the sums in hasher for o if all of o was
+// hashed
+func (f *Fs) storeHashes(o fs.Object, hasher *fs.MultiHasher) {
+	if hasher == nil || hasher.Size() != o.Size() {
+		return
+	}
+	err := f.store.put(f.key(o.Remote()), o.Size(), o.ModTime(), hasher.Sums())
+	if err != nil {
+		fs.Errorf(o, "hasher: failed to store hashes: %v", err)
+	}
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	hasher := f.newHasher()
+	if hasher != nil {
+		in = io.TeeReader(in, hasher)
+	}
+	o, err := f.Fs.Put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	f.storeHashes(o, hasher)
+	return f.newObject(o), nil
+}
+
+// Purge all files in the root and the root directory
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge(ctx context.Context) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do(ctx)
+}
+
+// copyHashes copies the stored hashes of src, which may be from an
+// Fs with a different root, to dst
+func (f *Fs) copyHashes(src *Object, dst fs.Object) {
+	hashes := src.f.store.get(src.key(), src.Size(), src.ModTime())
+	if hashes == nil {
+		return
+	}
+	err := f.store.put(f.key(dst.Remote()), dst.Size(), dst.ModTime(), hashes)
+	if err != nil {
+		fs.Errorf(dst, "hasher: failed to store hashes: %v", err)
+	}
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	dst, err := do(ctx, o.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	f.copyHashes(o, dst)
+	return f.newObject(dst), nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	dst, err := do(ctx, o.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	f.copyHashes(o, dst)
+	o.f.store.remove(o.key())
+	return f.newObject(dst), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// The stored hashes of the objects in the directory aren't moved
+// so will need to be calculated again.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// CleanUp the trash in the Fs
+//
+// Implement this if you have a way of emptying the trash or
+// otherwise cleaning up old versions of files.
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do(ctx)
+}
+
+// DirChangeNotify calls the passed function with a path of a
+// directory that has had changes in the wrapped remote
+func (f *Fs) DirChangeNotify(notifyFunc func(string), pollInterval time.Duration) chan bool {
+	do := f.Fs.Features().DirChangeNotify
+	if do == nil {
+		return nil
+	}
+	return do(notifyFunc, pollInterval)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// Object is an object whose hashes are stored by the Fs
+type Object struct {
+	fs.Object
+	f *Fs
+}
+
+// newObject wraps o from the wrapped remote
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// key returns the key the hashes of o are stored under
+func (o *Object) key() string {
+	return o.f.key(o.Remote())
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// MimeType returns the content type of the Object if
+// known, or "" if not
+func (o *Object) MimeType() string {
+	return fs.MimeType(o.Object)
+}
+
+// Hash returns the selected checksum of the file
+// If no checksum is available it returns ""
+//
+// Hashes the wrapped remote doesn't support are read from the store,
+// reading the object to calculate them if it is small enough.
+func (o *Object) Hash(hashType fs.HashType) (string, error) {
+	if !o.f.hashes.Contains(hashType) {
+		return o.Object.Hash(hashType)
+	}
+	hashes := o.f.store.get(o.key(), o.Size(), o.ModTime())
+	if hash, ok := hashes[hashType]; ok {
+		return hash, nil
+	}
+	if o.Size() < 0 || o.Size() > o.f.autoSize {
+		return "", nil
+	}
+	err := o.Fill(context.Background())
+	if err != nil {
+		return "", err
+	}
+	hashes = o.f.store.get(o.key(), o.Size(), o.ModTime())
+	return hashes[hashType], nil
+}
+
+// hasHashes returns whether all the hashes of o are stored
+func (o *Object) hasHashes() bool {
+	hashes := o.f.store.get(o.key(), o.Size(), o.ModTime())
+	for _, hashType := range o.f.hashes.Array() {
+		if _, ok := hashes[hashType]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Fill reads the object to calculate and store its hashes if they
+// aren't already stored.
+func (o *Object) Fill(ctx context.Context) (err error) {
+	if o.f.hashes == 0 || o.hasHashes() {
+		return nil
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	_, err = io.Copy(ioutil.Discard, in)
+	return err
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+//
+// If the whole object is read then its hashes are stored.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		switch option.(type) {
+		case *fs.SeekOption, *fs.RangeOption:
+			return in, nil
+		}
+	}
+	hasher := o.f.newHasher()
+	if hasher == nil {
+		return in, nil
+	}
+	return &hashingReader{in: in, hasher: hasher, o: o}, nil
+}
+
+// hashingReader hashes the data read through it and stores the
+// hashes when it reaches the end
+type hashingReader struct {
+	in     io.ReadCloser
+	hasher *fs.MultiHasher
+	o      *Object
+	done   bool
+}
+
+// Read bytes from the reader hashing them
+func (r *hashingReader) Read(p []byte) (n int, err error) {
+	n, err = r.in.Read(p)
+	_, _ = r.hasher.Write(p[:n])
+	if err == io.EOF && !r.done {
+		r.done = true
+		r.o.f.storeHashes(r.o.Object, r.hasher)
+	}
+	return n, err
+}
+
+// Close the reader
+func (r *hashingReader) Close() error {
+	return r.in.Close()
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	hasher := o.f.newHasher()
+	if hasher != nil {
+		in = io.TeeReader(in, hasher)
+	}
+	o.f.store.remove(o.key())
+	err := o.Object.Update(ctx, in, src, options...)
+	if err != nil {
+		return err
+	}
+	o.f.storeHashes(o.Object, hasher)
+	return nil
+}
+
+// SetModTime sets the modification time of the file keeping the
+// stored hashes
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	hashes := o.f.store.get(o.key(), o.Size(), o.ModTime())
+	err := o.Object.SetModTime(ctx, t)
+	if err != nil || hashes == nil {
+		return err
+	}
+	err = o.f.store.put(o.key(), o.Size(), o.ModTime(), hashes)
+	if err != nil {
+		fs.Errorf(o, "hasher: failed to store hashes: %v", err)
+	}
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.Object.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	o.f.store.remove(o.key())
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs                = (*Fs)(nil)
+	_ fs.Purger            = (*Fs)(nil)
+	_ fs.Copier            = (*Fs)(nil)
+	_ fs.Mover             = (*Fs)(nil)
+	_ fs.DirMover          = (*Fs)(nil)
+	_ fs.CleanUpper        = (*Fs)(nil)
+	_ fs.DirChangeNotifier = (*Fs)(nil)
+	_ fs.UnWrapper         = (*Fs)(nil)
+	_ fs.Object            = (*Object)(nil)
+	_ fs.MimeTyper         = (*Object)(nil)
+)
@@ -0,0 +1,18 @@
+package hasher_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestHasher: remote
+func init() {
+	tempdir := filepath.Join(os.TempDir(), "rclone-hasher-test")
+	name := "TestHasher"
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "hasher"},
+		{Name: name, Key: "remote", Value: tempdir},
+	}
+}
@@ -0,0 +1,136 @@
+package hasher
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newTestFs makes a hasher remote wrapping a local temporary
+// directory returning the Fs, the directory and a cleanup function
+//
+// The local remote supports hashes itself so the Fs is set up to
+// store them anyway.
+func newTestFs(t *testing.T, name string, options map[string]string) (*Fs, string, func()) {
+	r := fstest.NewWrapperRemote(t, "hasher", name, 1, options)
+	f := r.Fs.(*Fs)
+	f.hashes = fs.NewHashSet(fs.HashMD5, fs.HashSHA1)
+	return f, r.Dirs[0], r.Finalise
+}
+
+func md5sum(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseHashes(t *testing.T) {
+	hashes, err := parseHashes("md5, SHA1")
+	require.NoError(t, err)
+	assert.Equal(t, fs.NewHashSet(fs.HashMD5, fs.HashSHA1), hashes)
+	hashes, err = parseHashes("")
+	require.NoError(t, err)
+	assert.Equal(t, fs.HashSet(fs.HashNone), hashes)
+	_, err = parseHashes("md5,potato")
+	assert.Error(t, err)
+}
+
+func TestHasherStoresHashes(t *testing.T) {
+	ctx := context.Background()
+	f, remoteDir, cleanup := newTestFs(t, "TestHasherStoresHashes", nil)
+	defer cleanup()
+
+	// Hashes are stored when files are uploaded
+	src := fs.NewStaticObjectInfo("file", time.Now(), 5, true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader("hello"), src)
+	require.NoError(t, err)
+	assert.NotNil(t, f.store.get("file", o.Size(), o.ModTime()))
+	hash, err := o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, md5sum("hello"), hash)
+
+	// and moved with them
+	o, err = f.Move(ctx, o, "moved")
+	require.NoError(t, err)
+	assert.Nil(t, f.store.get("file", o.Size(), o.ModTime()))
+	hash, err = o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, md5sum("hello"), hash)
+
+	// and kept when the modification time is changed
+	require.NoError(t, o.SetModTime(ctx, time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)))
+	hash, err = o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, md5sum("hello"), hash)
+
+	// but not used if the file is changed behind our back
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "moved"), []byte("potato"), 0600))
+	o, err = f.NewObject(ctx, "moved")
+	require.NoError(t, err)
+	hash, err = o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, "", hash)
+
+	// until the file is read
+	require.NoError(t, o.(*Object).Fill(ctx))
+	hash, err = o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, md5sum("potato"), hash)
+
+	// Removing the file removes the hashes
+	require.NoError(t, o.Remove(ctx))
+	assert.Nil(t, f.store.get("moved", o.Size(), o.ModTime()))
+}
+
+func TestHasherMoveFromSubdir(t *testing.T) {
+	ctx := context.Background()
+	f, _, cleanup := newTestFs(t, "TestHasherMoveFromSubdir", nil)
+	defer cleanup()
+	fsub, err := NewFs("TestHasherMoveFromSubdir", "dir")
+	require.NoError(t, err)
+	sub := fsub.(*Fs)
+	sub.hashes = f.hashes
+
+	src := fs.NewStaticObjectInfo("file", time.Now(), 5, true, nil, nil)
+	o, err := sub.Put(ctx, strings.NewReader("hello"), src)
+	require.NoError(t, err)
+	assert.NotNil(t, f.store.get("dir/file", o.Size(), o.ModTime()))
+
+	// The hashes are found under the key of the source
+	o, err = f.Move(ctx, o, "moved")
+	require.NoError(t, err)
+	assert.Nil(t, f.store.get("dir/file", o.Size(), o.ModTime()))
+	hash, err := o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, md5sum("hello"), hash)
+}
+
+func TestHasherAutoSize(t *testing.T) {
+	ctx := context.Background()
+	f, remoteDir, cleanup := newTestFs(t, "TestHasherAutoSize", map[string]string{"auto_size": "5b"})
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "small"), []byte("hello"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "big"), []byte("potato"), 0600))
+
+	o, err := f.NewObject(ctx, "small")
+	require.NoError(t, err)
+	hash, err := o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, md5sum("hello"), hash)
+
+	o, err = f.NewObject(ctx, "big")
+	require.NoError(t, err)
+	hash, err = o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, "", hash)
+}
@@ -0,0 +1,93 @@
+// Persistent storage of hashes for the hasher backend
+
+package hasher
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/kv"
+)
+
+// hashesBucket is the name of the bucket the hashes are stored in
+const hashesBucket = "hashes"
+
+// storedHashes is the hashes of an object as stored in the database
+type storedHashes struct {
+	Size    int64             // size of the object when it was hashed
+	ModTime time.Time         // modification time of the object when it was hashed
+	Hashes  map[string]string // hashes by name
+}
+
+// store keeps the hashes of objects in a database on disk so they
+// survive between runs of rclone.
+//
+// The hashes for each object are stored as JSON keyed by the path of
+// the object in the wrapped remote.  The size and modification time
+// of the object are stored with them and the hashes are only used if
+// these still match.
+type store struct {
+	db *kv.DB
+}
+
+// getStore returns the store for the remote called name.
+//
+// All the Fs made from the same remote share the same database.
+func getStore(name string) (*store, error) {
+	db, err := kv.Open("hasher", name)
+	if err != nil {
+		return nil, err
+	}
+	return &store{db: db}, nil
+}
+
+// get returns the stored hashes for remote if they were read when it
+// had the size and modTime given, or nil otherwise
+func (s *store) get(remote string, size int64, modTime time.Time) map[fs.HashType]string {
+	data, err := s.db.Get(hashesBucket, remote)
+	if err != nil {
+		fs.Debugf(remote, "hasher: failed to read stored hashes: %v", err)
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
+	stored := new(storedHashes)
+	err = json.Unmarshal(data, stored)
+	if err != nil || stored.Size != size || !stored.ModTime.Equal(modTime) {
+		return nil
+	}
+	hashes := make(map[fs.HashType]string, len(stored.Hashes))
+	for _, hashType := range fs.SupportedHashes.Array() {
+		if hash, ok := stored.Hashes[hashType.String()]; ok {
+			hashes[hashType] = hash
+		}
+	}
+	return hashes
+}
+
+// put stores hashes for remote which has the size and modTime given
+func (s *store) put(remote string, size int64, modTime time.Time, hashes map[fs.HashType]string) error {
+	stored := storedHashes{
+		Size:    size,
+		ModTime: modTime,
+		Hashes:  make(map[string]string, len(hashes)),
+	}
+	for hashType, hash := range hashes {
+		stored.Hashes[hashType.String()] = hash
+	}
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(hashesBucket, remote, data)
+}
+
+// remove removes the stored hashes for remote
+func (s *store) remove(remote string) {
+	err := s.db.Delete(hashesBucket, remote)
+	if err != nil {
+		fs.Errorf(remote, "hasher: failed to remove stored hashes: %v", err)
+	}
+}
@@ -0,0 +1,492 @@
+// Package http provides a filesystem interface to a read only web
+// server which serves directory listings as HTML index pages, eg
+// Apache or nginx autoindex pages.
+package http
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/net/html"
+)
+
+var errorReadOnly = errors.New("http remotes are read only")
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "http",
+		Description: "http Connection",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "url",
+			Help: "URL of http host to connect to",
+			Examples: []fs.OptionExample{{
+				Value: "https://example.com",
+				Help:  "Connect to example.com",
+			}},
+		}},
+	})
+}
+
+// Fs stores the interface to the remote HTTP files
+type Fs struct {
+	name     string
+	root     string
+	features *fs.Features // optional features
+	endpoint *url.URL     // URL of the root directory, ending in /
+	client   *http.Client
+}
+
+// Object is a remote object that has been stat'd (so it exists, but is not necessarily open for reading)
+type Object struct {
+	fs          *Fs
+	remote      string
+	size        int64
+	modTime     time.Time
+	contentType string
+}
+
+// statusError is returned for unexpected HTTP responses
+func statusError(res *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	return errors.Errorf("HTTP Error %d: %s", res.StatusCode, res.Status)
+}
+
+// NewFs creates a new Fs object from the name and root. It connects to
+// the host specified in the config file.
+func NewFs(name, root string) (fs.Fs, error) {
+	endpoint := fs.ConfigFileGet(name, "url")
+	if endpoint == "" {
+		return nil, errors.New("url not set in config file")
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse url %q", endpoint)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("url %q must start with http:// or https://", endpoint)
+	}
+	root = strings.Trim(root, "/")
+	client := fs.Config.Client()
+
+	// If the root is a file then point the Fs at its directory
+	if root != "" {
+		f := newFs(name, path.Dir(root), u, client)
+		o := &Object{fs: f, remote: path.Base(root)}
+		if o.stat(context.Background()) == nil {
+			return f, fs.ErrorIsFile
+		}
+	}
+	return newFs(name, root, u, client), nil
+}
+
+// newFs makes an Fs for root under the url u
+func newFs(name, root string, u *url.URL, client *http.Client) *Fs {
+	if root == "." {
+		root = ""
+	}
+	endpoint := u
+	if root != "" {
+		endpoint = u.ResolveReference(&url.URL{Path: root + "/"})
+	}
+	f := &Fs{
+		name:     name,
+		root:     root,
+		endpoint: endpoint,
+		client:   client,
+	}
+	f.features = (&fs.Features{}).Fill(f)
+	return f
+}
+
+// Name returns the configured name of the file system
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root returns the root for the filesystem
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns the URL for the filesystem
+func (f *Fs) String() string {
+	return f.endpoint.String()
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision is the remote http file system's modtime precision, which we have no way of knowing. We estimate at 1s
+func (f *Fs) Precision() time.Duration {
+	return time.Second
+}
+
+// Hashes returns fs.HashNone to indicate remote hashing is unavailable
+func (f *Fs) Hashes() fs.HashSet {
+	return fs.HashSet(fs.HashNone)
+}
+
+// url returns the URL of remote which is a directory if it ends in /
+func (f *Fs) url(remote string) *url.URL {
+	return f.endpoint.ResolveReference(&url.URL{Path: remote})
+}
+
+// NewObject creates a new remote http file object
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	err := o.stat(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// parseIndex parses an HTML index page at base, returning the names
+// of the entries it links to.  Directories end in /.
+//
+// Only links to the direct children of base are returned, so links
+// to parent directories, sort orders and other pages are ignored.
+func parseIndex(base *url.URL, in io.Reader) (names []string, err error) {
+	doc, err := html.Parse(in)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				name, ok := childName(base, attr.Val)
+				if ok && !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+				break
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return names, nil
+}
+
+// childName returns the name of the entry href links to if it is a
+// direct child of base
+func childName(base *url.URL, href string) (name string, ok bool) {
+	u, err := base.Parse(href)
+	if err != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", false
+	}
+	if u.Scheme != base.Scheme || u.Host != base.Host || !strings.HasPrefix(u.Path, base.Path) {
+		return "", false
+	}
+	name = u.Path[len(base.Path):]
+	leaf := strings.TrimSuffix(name, "/")
+	if leaf == "" || leaf == "." || leaf == ".." || strings.Contains(leaf, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+	u := f.url(prefix)
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "List failed")
+	}
+	res, err := ctxhttp.Do(ctx, f.client, req)
+	if err == nil && res.StatusCode == http.StatusNotFound {
+		_ = res.Body.Close()
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil || res.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(statusError(res, err), "failed to list %q", u)
+	}
+	names, err := parseIndex(u, res.Body)
+	closeErr := res.Body.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read index of %q", u)
+	}
+
+	// Stat the files in parallel
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		objs = make(chan *Object, fs.Config.Checkers)
+	)
+	for i := 0; i < fs.Config.Checkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range objs {
+				err := o.stat(ctx)
+				mu.Lock()
+				switch err {
+				case nil:
+					entries = append(entries, o)
+				case fs.ErrorNotAFile:
+					// a link to a directory without a / on the end
+					entries = append(entries, &fs.Dir{
+						Name:  o.remote,
+						Bytes: -1,
+						Count: -1,
+					})
+				case fs.ErrorObjectNotFound:
+					fs.Debugf(o, "Ignoring broken link")
+				default:
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		remote := prefix + strings.TrimSuffix(name, "/")
+		if strings.HasSuffix(name, "/") {
+			mu.Lock()
+			entries = append(entries, &fs.Dir{
+				Name:  remote,
+				Bytes: -1,
+				Count: -1,
+			})
+			mu.Unlock()
+			continue
+		}
+		objs <- &Object{fs: f, remote: remote}
+	}
+	close(objs)
+	wg.Wait()
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return entries, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir makes the root directory of the Fs object
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir removes the root directory of the Fs object
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Fs is the filesystem this remote http file object is located within
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// String returns the URL to the remote HTTP file
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote the name of the remote HTTP file, relative to the fs root
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns fs.ErrHashUnsupported as web servers don't supply hashes
+func (o *Object) Hash(r fs.HashType) (string, error) {
+	return "", fs.ErrHashUnsupported
+}
+
+// Size returns the size in bytes of the remote http file
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the remote http file
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// url returns the URL of the object
+func (o *Object) url() *url.URL {
+	return o.fs.url(o.remote)
+}
+
+// stat updates the info in the Object with a HEAD request
+func (o *Object) stat(ctx context.Context) error {
+	u := o.url()
+	req, err := http.NewRequest("HEAD", u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "stat failed")
+	}
+	res, err := ctxhttp.Do(ctx, o.fs.client, req)
+	if err == nil && res.StatusCode == http.StatusNotFound {
+		_ = res.Body.Close()
+		return fs.ErrorObjectNotFound
+	}
+	if err != nil || res.StatusCode != http.StatusOK {
+		return errors.Wrapf(statusError(res, err), "failed to stat %q", u)
+	}
+	_ = res.Body.Close()
+	// Servers redirect directories to the URL with a / on the end
+	if strings.HasSuffix(res.Request.URL.Path, "/") {
+		return fs.ErrorNotAFile
+	}
+	o.size = res.ContentLength
+	o.modTime = time.Time{}
+	if t, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		o.modTime = t
+	}
+	o.contentType = res.Header.Get("Content-Type")
+	return nil
+}
+
+// SetModTime sets the modification and access time to the specified time
+//
+// it also updates the info field
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns whether the remote http file is a regular file (not a directory, symbolic link, block device, character device, named pipe, etc)
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open a remote http file object for reading. Seek is supported
+//
+// If the server ignores the Range requested and sends the whole file
+// then the data before the range is skipped.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	u := o.url()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Open failed")
+	}
+	var offset, limit int64 = 0, -1
+	ranged := false
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset, ranged = x.Offset, true
+		case *fs.RangeOption:
+			if x.Start < 0 && o.size < 0 {
+				// Can't skip to a suffix range of unknown size
+				offset = -1
+			} else {
+				offset, limit = x.Decode(o.size)
+			}
+			ranged = true
+		}
+		key, value := option.Header()
+		if key != "" && value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+	res, err := ctxhttp.Do(ctx, o.fs.client, req)
+	if err != nil || (res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent) {
+		return nil, errors.Wrapf(statusError(res, err), "failed to open %q", u)
+	}
+	if !ranged || res.StatusCode == http.StatusPartialContent {
+		return res.Body, nil
+	}
+	// The server sent the whole file so skip to the range
+	if offset < 0 {
+		_ = res.Body.Close()
+		return nil, errors.Errorf("failed to open %q: server ignored the range requested", u)
+	}
+	_, err = io.CopyN(ioutil.Discard, res.Body, offset)
+	if err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, errors.Wrapf(err, "failed to skip to offset in %q", u)
+	}
+	if limit >= 0 {
+		return fs.NewLimitedReadCloser(res.Body, limit), nil
+	}
+	return res.Body, nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return errorReadOnly
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType() string {
+	if o.contentType == "" {
+		return ""
+	}
+	mimeType, _, err := mime.ParseMediaType(o.contentType)
+	if err != nil {
+		return o.contentType
+	}
+	return mimeType
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs        = &Fs{}
+	_ fs.Object    = &Object{}
+	_ fs.MimeTyper = &Object{}
+)
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestMain(m *testing.M) {
+	fs.LoadConfig()
+	os.Exit(m.Run())
+}
+
+var lastModified = time.Date(2017, 8, 1, 12, 30, 45, 0, time.UTC)
+
+// prepareServer serves a temporary directory over HTTP, configures
+// a remote called name for it and returns a cleanup function
+func prepareServer(t *testing.T, name string) func() {
+	dir, err := ioutil.TempDir("", "rclone-http")
+	require.NoError(t, err)
+	write := func(name, contents string) {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0700))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0600))
+		require.NoError(t, os.Chtimes(filePath, lastModified, lastModified))
+	}
+	write("one.txt", "hello world")
+	write("two space.html", "<p>potato</p>")
+	write("sub dir/three.txt", "three")
+	write("sub dir/four.txt", "four")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0700))
+
+	ts := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	fs.ConfigFileSet(name, "type", "http")
+	fs.ConfigFileSet(name, "url", ts.URL)
+	return func() {
+		ts.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func listNames(t *testing.T, f fs.Fs, dir string) (names []string) {
+	entries, err := f.List(context.Background(), dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		name := entry.Remote()
+		if _, isDir := entry.(*fs.Dir); isDir {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestParseIndex(t *testing.T) {
+	base, err := url.Parse("http://example.com/pub/dir/")
+	require.NoError(t, err)
+	page := `<html><body><h1>Index of /pub/dir</h1>
+<a href="?C=N;O=D">Name</a> <a href="?C=M;O=A">Last modified</a>
+<a href="/pub/">Parent Directory</a>
+<a href="../">..</a>
+<a href="file%20one.txt">file one.txt</a>
+<a href="./file2">file2</a>
+<a href="/pub/dir/subdir/">subdir/</a>
+<a href="subdir/deeper">deeper</a>
+<a href="http://other.com/pub/dir/elsewhere">elsewhere</a>
+<a href="http://example.com/pub/dir/absolute">absolute</a>
+<a href="file2">duplicate</a>
+<a href="#top">top</a>
+</body></html>`
+	names, err := parseIndex(base, strings.NewReader(page))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file one.txt", "file2", "subdir/", "absolute"}, names)
+}
+
+func TestListAndRead(t *testing.T) {
+	ctx := context.Background()
+	defer prepareServer(t, "TestHTTPList")()
+	f, err := NewFs("TestHTTPList", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"empty/", "one.txt", "sub dir/", "two space.html"}, listNames(t, f, ""))
+	assert.Equal(t, []string{"sub dir/four.txt", "sub dir/three.txt"}, listNames(t, f, "sub dir"))
+	assert.Equal(t, []string(nil), listNames(t, f, "empty"))
+	_, err = f.List(ctx, "missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	o, err := f.NewObject(ctx, "one.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), o.Size())
+	assert.True(t, lastModified.Equal(o.ModTime()), o.ModTime().String())
+	assert.Equal(t, "text/plain", o.(*Object).MimeType())
+
+	read := func(options ...fs.OpenOption) string {
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(data)
+	}
+	assert.Equal(t, "hello world", read())
+	assert.Equal(t, "world", read(&fs.SeekOption{Offset: 6}))
+	assert.Equal(t, "lo w", read(&fs.RangeOption{Start: 3, End: 6}))
+
+	_, err = f.NewObject(ctx, "missing")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.NewObject(ctx, "sub dir")
+	assert.Equal(t, fs.ErrorNotAFile, err)
+
+	// It is read only
+	src := fs.NewStaticObjectInfo("new", time.Now(), 1, true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader("x"), src)
+	assert.Equal(t, errorReadOnly, err)
+	assert.Equal(t, errorReadOnly, o.Remove(ctx))
+}
+
+func TestOpenIgnoredRange(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-http")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "one.txt"), []byte("hello world"), 0600))
+
+	// Serve the directory ignoring any Range headers
+	fileServer := http.FileServer(http.Dir(dir))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Range")
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	fs.ConfigFileSet("TestHTTPIgnoredRange", "type", "http")
+	fs.ConfigFileSet("TestHTTPIgnoredRange", "url", ts.URL)
+	f, err := NewFs("TestHTTPIgnoredRange", "")
+	require.NoError(t, err)
+
+	o, err := f.NewObject(ctx, "one.txt")
+	require.NoError(t, err)
+	read := func(options ...fs.OpenOption) string {
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(data)
+	}
+	assert.Equal(t, "hello world", read())
+	assert.Equal(t, "world", read(&fs.SeekOption{Offset: 6}))
+	assert.Equal(t, "lo w", read(&fs.RangeOption{Start: 3, End: 6}))
+	assert.Equal(t, "rld", read(&fs.RangeOption{Start: -1, End: 3}))
+
+	// Requests are made with the context given
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = o.Open(cancelled)
+	assert.Error(t, err)
+	_, err = f.NewObject(cancelled, "one.txt")
+	assert.Error(t, err)
+	_, err = f.List(cancelled, "")
+	assert.Error(t, err)
+}
+
+func TestNewFsRoot(t *testing.T) {
+	defer prepareServer(t, "TestHTTPRoot")()
+
+	f, err := NewFs("TestHTTPRoot", "sub dir")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"four.txt", "three.txt"}, listNames(t, f, ""))
+
+	f, err = NewFs("TestHTTPRoot", "sub dir/three.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "sub dir", f.Root())
+	assert.Equal(t, []string{"four.txt", "three.txt"}, listNames(t, f, ""))
+
+	fs.ConfigFileSet("TestHTTPBad", "url", "ftp://example.com")
+	_, err = NewFs("TestHTTPBad", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+// Package kv provides a key value database stored on disk for the
+// backends which need to keep information between runs of rclone.
+package kv
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// openTimeout is how long to wait for another process to release the
+// database
+const openTimeout = time.Second
+
+// DB is a key value database stored in a single file
+//
+// Keys and values are stored in named buckets which are created as
+// needed.  It is safe for concurrent use.
+type DB struct {
+	path string
+	db   *bolt.DB
+}
+
+var (
+	dbsMu sync.Mutex
+	dbs   = map[string]*DB{}
+)
+
+// fileName returns the name of the file the database for the remote
+// called name is stored in.
+func fileName(name string) string {
+	return fs.CacheName(name) + ".db"
+}
+
+// Open returns the database for the remote called name of the
+// backend given, creating it if necessary.
+//
+// The database is stored in the rclone cache directory.  All the
+// callers asking for the same database share it as it can only be
+// opened once.
+func Open(backend, name string) (*DB, error) {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+	dbPath := filepath.Join(fs.CacheDir, backend, fileName(name))
+	if db := dbs[dbPath]; db != nil {
+		return db, nil
+	}
+	err := os.MkdirAll(filepath.Dir(dbPath), 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create database directory")
+	}
+	boltDB, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: openTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, errors.Errorf("failed to open database %q: is it in use by another rclone?", dbPath)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database %q", dbPath)
+	}
+	db := &DB{path: dbPath, db: boltDB}
+	dbs[dbPath] = db
+	return db, nil
+}
+
+// Path returns the path of the file the database is stored in
+func (db *DB) Path() string {
+	return db.path
+}
+
+// Get returns a copy of the value of key in bucket or nil if it
+// isn't found
+func (db *DB) Get(bucket, key string) (value []byte, err error) {
+	err = db.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(key)); v != nil {
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// Put sets key in bucket to value
+func (db *DB) Put(bucket, key string, value []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+}
+
+// Delete removes keys from bucket
+//
+// It isn't an error if they aren't found.
+func (db *DB) Delete(bucket string, keys ...string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		for _, key := range keys {
+			err := b.Delete([]byte(key))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeletePrefix removes all the keys starting with prefix from bucket
+// returning the number of bytes of values removed
+func (db *DB) DeletePrefix(bucket, prefix string) (size int64, err error) {
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		size = 0
+		p := []byte(prefix)
+		c := b.Cursor()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Seek(p) {
+			size += int64(len(v))
+			err := c.Delete()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// ForEach calls fn for each key in bucket in key order
+//
+// The value is only valid until fn returns.  The database can't be
+// modified from within fn.
+func (db *DB) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	return db.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
+
+// Purge removes everything from the database
+func (db *DB) Purge() error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		var names [][]byte
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			err = tx.DeleteBucket(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
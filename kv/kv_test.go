@@ -0,0 +1,83 @@
+package kv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "remote.db", fileName("remote"))
+	assert.Equal(t, "my remote-2.db", fileName("my remote-2"))
+	for _, name := range []string{":s3,region=eu-west-1", `a\b`, "a/b", "", ".."} {
+		got := fileName(name)
+		assert.Len(t, got, 32+3, name)
+		assert.NotContains(t, got[:32], ".", name)
+	}
+	assert.NotEqual(t, fileName(":s3,region=a"), fileName(":s3,region=b"))
+}
+
+func TestDB(t *testing.T) {
+	oldCacheDir := fs.CacheDir
+	dir, err := ioutil.TempDir("", "rclone-kv-test")
+	require.NoError(t, err)
+	fs.CacheDir = dir
+	defer func() {
+		fs.CacheDir = oldCacheDir
+		_ = os.RemoveAll(dir)
+	}()
+
+	db, err := Open("test", "remote")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "test", "remote.db"), db.Path())
+
+	// The same database is returned each time
+	db2, err := Open("test", "remote")
+	require.NoError(t, err)
+	assert.True(t, db == db2)
+
+	value, err := db.Get("bucket", "a")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	require.NoError(t, db.Put("bucket", "a", []byte("potato")))
+	require.NoError(t, db.Put("bucket", "dir/b", []byte("12")))
+	require.NoError(t, db.Put("bucket", "dir/c", []byte("345")))
+	require.NoError(t, db.Put("bucket", "dis", []byte("6")))
+	require.NoError(t, db.Put("other", "a", []byte("other")))
+
+	value, err = db.Get("bucket", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "potato", string(value))
+
+	var keys []string
+	require.NoError(t, db.ForEach("bucket", func(key string, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"a", "dir/b", "dir/c", "dis"}, keys)
+
+	size, err := db.DeletePrefix("bucket", "dir/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), size)
+
+	require.NoError(t, db.Delete("bucket", "a", "notfound"))
+	require.NoError(t, db.Delete("notfound", "a"))
+
+	keys = nil
+	require.NoError(t, db.ForEach("bucket", func(key string, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"dis"}, keys)
+
+	require.NoError(t, db.Purge())
+	value, err = db.Get("other", "a")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
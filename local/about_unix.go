@@ -0,0 +1,32 @@
+// Free space reading functions
+
+// +build darwin freebsd linux
+
+package local
+
+import (
+	"syscall"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// About gets quota information
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	var s syscall.Statfs_t
+	err := syscall.Statfs(f.root, &s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read disk usage")
+	}
+	bs := int64(s.Bsize)
+	usage := &fs.Usage{
+		Total: bs * int64(s.Blocks),
+		Used:  bs * int64(s.Blocks-s.Bfree),
+		Free:  bs * int64(s.Bavail),
+	}
+	return usage, nil
+}
+
+// check interface
+var _ fs.Abouter = &Fs{}
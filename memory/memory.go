@@ -0,0 +1,572 @@
+// Package memory provides an interface to an in memory object storage
+// system.
+//
+// All memory remotes share the same storage which lasts for as long as
+// the rclone process does.  This makes it useful for testing and as a
+// scratch space.
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "memory",
+		Description: "In memory object storage system.",
+		NewFs:       NewFs,
+		Options:     []fs.Option{},
+	})
+}
+
+// objectData is the data and metadata of a stored object
+//
+// data is never modified once stored so it may be shared between
+// objects.
+type objectData struct {
+	modTime  time.Time
+	hashes   map[fs.HashType]string
+	mimeType string
+	data     []byte
+}
+
+// memoryStore is the storage for the memory remotes
+//
+// Paths are unrooted, "" being the root directory which always
+// exists.
+type memoryStore struct {
+	mu    sync.RWMutex
+	files map[string]*objectData // files by path
+	dirs  map[string]time.Time   // directories by path with their creation time
+}
+
+// The storage shared by all the memory remotes
+var store = newMemoryStore()
+
+// newMemoryStore makes an empty memoryStore
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		files: make(map[string]*objectData),
+		dirs:  map[string]time.Time{"": time.Now()},
+	}
+}
+
+// mkdirAll makes the directory p and any parents
+//
+// Call with the lock held
+func (s *memoryStore) mkdirAll(p string) error {
+	if _, isFile := s.files[p]; isFile {
+		return errors.Errorf("%q is a file", p)
+	}
+	if _, found := s.dirs[p]; found {
+		return nil
+	}
+	err := s.mkdirAll(parentDir(p))
+	if err != nil {
+		return err
+	}
+	s.dirs[p] = time.Now()
+	return nil
+}
+
+// isEmpty returns whether the directory p has nothing in
+//
+// Call with the lock held
+func (s *memoryStore) isEmpty(p string) bool {
+	prefix := childPrefix(p)
+	for filePath := range s.files {
+		if strings.HasPrefix(filePath, prefix) {
+			return false
+		}
+	}
+	for dirPath := range s.dirs {
+		if dirPath != p && strings.HasPrefix(dirPath, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// parentDir returns the parent directory of p with "" for the root
+func parentDir(p string) string {
+	parent := path.Dir(p)
+	if parent == "." || parent == "/" {
+		return ""
+	}
+	return parent
+}
+
+// childPrefix returns the prefix the paths of the contents of the
+// directory p have
+func childPrefix(p string) string {
+	if p == "" {
+		return ""
+	}
+	return p + "/"
+}
+
+// Fs represents a remote memory server
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	features *fs.Features // optional features
+}
+
+// Object describes a memory object
+type Object struct {
+	fs     *Fs         // what this object is part of
+	remote string      // The remote path
+	od     *objectData // the data and metadata of the object
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("Memory root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(name, root string) (fs.Fs, error) {
+	root = strings.Trim(root, "/")
+	f := &Fs{
+		name: name,
+		root: root,
+	}
+	f.features = (&fs.Features{
+		ReadMimeType:  true,
+		WriteMimeType: true,
+	}).Fill(f)
+	if root != "" {
+		store.mu.RLock()
+		_, isFile := store.files[root]
+		store.mu.RUnlock()
+		if isFile {
+			// Point the Fs at the parent directory
+			f.root = parentDir(root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// fullPath returns the unrooted path of remote in the store
+func (f *Fs) fullPath(remote string) string {
+	return path.Join(f.root, remote)
+}
+
+// remote returns the remote for the unrooted path p in the store
+func (f *Fs) remote(p string) string {
+	return p[len(childPrefix(f.root)):]
+}
+
+// list the objects and directories in dir returning them as entries,
+// recursively if recurse is set
+func (f *Fs) list(dir string, recurse bool) (entries fs.DirEntries, err error) {
+	dirPath := f.fullPath(dir)
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if _, found := store.dirs[dirPath]; !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	prefix := childPrefix(dirPath)
+	inDir := func(p string) bool {
+		if p == dirPath || !strings.HasPrefix(p, prefix) {
+			return false
+		}
+		return recurse || !strings.Contains(p[len(prefix):], "/")
+	}
+	for p, created := range store.dirs {
+		if inDir(p) {
+			entries = append(entries, &fs.Dir{
+				Name:  f.remote(p),
+				When:  created,
+				Bytes: -1,
+				Count: -1,
+			})
+		}
+	}
+	for p, od := range store.files {
+		if inDir(p) {
+			entries = append(entries, &Object{
+				fs:     f,
+				remote: f.remote(p),
+				od:     od,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	return f.list(dir, false)
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	entries, err := f.list(dir, true)
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// NewObject finds the Object at remote.  If it can't be found it
+// returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	filePath := f.fullPath(remote)
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	od, found := store.files[filePath]
+	if !found {
+		if _, isDir := store.dirs[filePath]; isDir {
+			return nil, fs.ErrorNotAFile
+		}
+		return nil, fs.ErrorObjectNotFound
+	}
+	return &Object{
+		fs:     f,
+		remote: remote,
+		od:     od,
+	}, nil
+}
+
+// Put the object into the store
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.mkdirAll(f.fullPath(dir))
+}
+
+// Rmdir deletes the directory
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	dirPath := f.fullPath(dir)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, found := store.dirs[dirPath]; !found {
+		return fs.ErrorDirNotFound
+	}
+	if !store.isEmpty(dirPath) {
+		return errors.New("directory not empty")
+	}
+	if dirPath != "" {
+		delete(store.dirs, dirPath)
+	}
+	return nil
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() fs.HashSet {
+	return fs.HashSet(fs.HashMD5 | fs.HashSHA1)
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	dstPath := f.fullPath(remote)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	err := store.mkdirAll(parentDir(dstPath))
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[fs.HashType]string, len(srcObj.od.hashes))
+	for hashType, hash := range srcObj.od.hashes {
+		hashes[hashType] = hash
+	}
+	od := &objectData{
+		modTime:  srcObj.od.modTime,
+		hashes:   hashes,
+		mimeType: srcObj.od.mimeType,
+		data:     srcObj.od.data,
+	}
+	store.files[dstPath] = od
+	return &Object{
+		fs:     f,
+		remote: remote,
+		od:     od,
+	}, nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	srcPath := srcObj.fs.fullPath(srcObj.remote)
+	dstPath := f.fullPath(remote)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	od, found := store.files[srcPath]
+	if !found {
+		return nil, fs.ErrorObjectNotFound
+	}
+	err := store.mkdirAll(parentDir(dstPath))
+	if err != nil {
+		return nil, err
+	}
+	delete(store.files, srcPath)
+	store.files[dstPath] = od
+	return &Object{
+		fs:     f,
+		remote: remote,
+		od:     od,
+	}, nil
+}
+
+// Purge deletes all the files and directories including the root
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, found := store.dirs[f.root]; !found {
+		return fs.ErrorDirNotFound
+	}
+	prefix := childPrefix(f.root)
+	for p := range store.files {
+		if strings.HasPrefix(p, prefix) {
+			delete(store.files, p)
+		}
+	}
+	for p := range store.dirs {
+		if p != "" && (p == f.root || strings.HasPrefix(p, prefix)) {
+			delete(store.dirs, p)
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(t fs.HashType) (string, error) {
+	if !o.fs.Hashes().Contains(t) {
+		return "", fs.ErrHashUnsupported
+	}
+	return o.od.hashes[t], nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return int64(len(o.od.data))
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return o.od.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	o.od.modTime = modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	data := o.od.data
+	if offset < 0 {
+		offset = 0
+	} else if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if limit >= 0 && limit < int64(len(data)) {
+		data = data[:limit]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	hash, err := fs.NewMultiHasherTypes(o.fs.Hashes())
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(io.TeeReader(in, hash))
+	if err != nil {
+		return errors.Wrap(err, "failed to read data")
+	}
+	od := &objectData{
+		modTime:  src.ModTime(),
+		hashes:   hash.Sums(),
+		mimeType: fs.MimeType(src),
+		data:     data,
+	}
+	filePath := o.fs.fullPath(o.remote)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, isDir := store.dirs[filePath]; isDir {
+		return errors.Errorf("can't upload %q: it is a directory", o.remote)
+	}
+	err = store.mkdirAll(parentDir(filePath))
+	if err != nil {
+		return err
+	}
+	store.files[filePath] = od
+	o.od = od
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	filePath := o.fs.fullPath(o.remote)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, found := store.files[filePath]; !found {
+		return fs.ErrorObjectNotFound
+	}
+	delete(store.files, filePath)
+	return nil
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType() string {
+	return o.od.mimeType
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs        = (*Fs)(nil)
+	_ fs.Purger    = (*Fs)(nil)
+	_ fs.Copier    = (*Fs)(nil)
+	_ fs.Mover     = (*Fs)(nil)
+	_ fs.ListRer   = (*Fs)(nil)
+	_ fs.Object    = (*Object)(nil)
+	_ fs.MimeTyper = (*Object)(nil)
+)
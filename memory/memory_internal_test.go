@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// put uploads contents to remote in f
+func put(t *testing.T, f fs.Fs, remote, contents string) fs.Object {
+	src := fs.NewStaticObjectInfo(remote, time.Date(2017, 8, 1, 12, 30, 45, 123456789, time.UTC), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(context.Background(), bytes.NewBufferString(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+func TestSharedStorage(t *testing.T) {
+	ctx := context.Background()
+	f1, err := NewFs("mem1", "shared")
+	require.NoError(t, err)
+	f2, err := NewFs("mem2", "shared/dir")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, f1.Features().Purge(ctx))
+	}()
+
+	put(t, f1, "dir/file.txt", "hello world")
+	o, err := f2.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), o.Size())
+	assert.Equal(t, time.Date(2017, 8, 1, 12, 30, 45, 123456789, time.UTC), o.ModTime())
+	md5, err := o.Hash(fs.HashMD5)
+	require.NoError(t, err)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", md5)
+	sha1, err := o.Hash(fs.HashSHA1)
+	require.NoError(t, err)
+	assert.Equal(t, "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed", sha1)
+	assert.Equal(t, "text/plain; charset=utf-8", o.(*Object).MimeType())
+
+	// A root pointing at the file should give its directory
+	f3, err := NewFs("mem3", "shared/dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "shared/dir", f3.Root())
+}
+
+func TestOpenRange(t *testing.T) {
+	ctx := context.Background()
+	f, err := NewFs("mem", "open")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, f.Features().Purge(ctx))
+	}()
+	o := put(t, f, "file.txt", "0123456789")
+
+	for _, test := range []struct {
+		options []fs.OpenOption
+		want    string
+	}{
+		{nil, "0123456789"},
+		{[]fs.OpenOption{&fs.SeekOption{Offset: 3}}, "3456789"},
+		{[]fs.OpenOption{&fs.SeekOption{Offset: 20}}, ""},
+		{[]fs.OpenOption{&fs.RangeOption{Start: 2, End: 4}}, "234"},
+		{[]fs.OpenOption{&fs.RangeOption{Start: -1, End: 3}}, "789"},
+		{[]fs.OpenOption{&fs.RangeOption{Start: -1, End: 20}}, "0123456789"},
+	} {
+		in, err := o.Open(ctx, test.options...)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		assert.Equal(t, test.want, string(got), test.options)
+	}
+}
+
+func TestListRAndPurge(t *testing.T) {
+	ctx := context.Background()
+	f, err := NewFs("mem", "listr")
+	require.NoError(t, err)
+	put(t, f, "a.txt", "a")
+	put(t, f, "dir/b.txt", "b")
+	put(t, f, "dir/sub/c.txt", "c")
+	require.NoError(t, f.Mkdir(ctx, "empty"))
+
+	var names []string
+	err = f.Features().ListR(ctx, "", func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			names = append(names, entry.Remote())
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	sort.Strings(names)
+	assert.Equal(t, []string{"a.txt", "dir", "dir/b.txt", "dir/sub", "dir/sub/c.txt", "empty"}, names)
+
+	entries, err := f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	assert.Error(t, f.Rmdir(ctx, "dir"))
+	require.NoError(t, f.Features().Purge(ctx))
+	_, err = f.List(ctx, "")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
@@ -190,6 +190,24 @@ type UploadFragmentResponse struct {
 	NextExpectedRanges []string  `json:"nextExpectedRanges"` // ["0-"]
 }
 
+// CreateShareLinkRequest is the request to create a sharing link
+type CreateShareLinkRequest struct {
+	Type               string     `json:"type"`                         // Link type in View, Edit or Embed
+	Scope              string     `json:"scope,omitempty"`              // Optional. Scope in anonymous, organization
+	ExpirationDateTime *Timestamp `json:"expirationDateTime,omitempty"` // Optional. When the link stops working - OneDrive Personal only
+}
+
+// CreateShareLinkResponse is the response from creating a sharing link
+type CreateShareLinkResponse struct {
+	ID    string   `json:"id"`    // The unique identifier of the permission.
+	Roles []string `json:"roles"` // The type of permission, eg read.
+	Link  struct {
+		Type   string `json:"type"`   // The type of the link, eg view.
+		Scope  string `json:"scope"`  // The scope of the link, eg anonymous.
+		WebURL string `json:"webUrl"` // A URL that opens the item in the browser on the OneDrive website.
+	} `json:"link"` // The sharing link.
+}
+
 // CopyItemRequest is the request to copy an item object
 //
 // Note: The parentReference should include either an id or path but
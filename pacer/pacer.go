@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"golang.org/x/net/context"
 )
 
 // Pacer state
@@ -195,14 +196,24 @@ func (p *Pacer) SetPacer(t Type) *Pacer {
 // This must be called as a pair with endCall
 //
 // This waits for the pacer token
-func (p *Pacer) beginCall() {
+func (p *Pacer) beginCall(ctx context.Context) error {
 	// pacer starts with a token in and whenever we take one out
 	// XXX ms later we put another in.  We could do this with a
 	// Ticker more accurately, but then we'd have to work out how
 	// not to run it when it wasn't needed
-	<-p.pacer
+	select {
+	case <-p.pacer:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 	if p.maxConnections > 0 {
-		<-p.connTokens
+		select {
+		case <-p.connTokens:
+		case <-ctx.Done():
+			// give the pace token back as we didn't use it
+			p.pacer <- struct{}{}
+			return ctx.Err()
+		}
 	}
 
 	p.mu.Lock()
@@ -213,6 +224,7 @@ func (p *Pacer) beginCall() {
 		p.pacer <- struct{}{}
 	}(p.sleepTime)
 	p.mu.Unlock()
+	return nil
 }
 
 // exponentialImplementation implements a exponentialImplementation up
@@ -327,15 +339,24 @@ func (p *Pacer) endCall(retry bool) {
 }
 
 // call implements Call but with settable retries
-func (p *Pacer) call(fn Paced, retries int) (err error) {
+//
+// If ctx is cancelled while waiting for the pacer or between retries
+// then it gives up and returns the error from the context.
+func (p *Pacer) call(ctx context.Context, fn Paced, retries int) (err error) {
 	var retry bool
 	for i := 1; i <= retries; i++ {
-		p.beginCall()
+		err = p.beginCall(ctx)
+		if err != nil {
+			return err
+		}
 		retry, err = fn()
 		p.endCall(retry)
 		if !retry {
 			break
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		fs.Debugf("pacer", "low level retry %d/%d (error %v)", i, retries, err)
 	}
 	if retry {
@@ -350,11 +371,14 @@ func (p *Pacer) call(fn Paced, retries int) (err error) {
 // This calls fn, expecting it to return a retry flag and an
 // error. This error may be returned wrapped in a RetryError if the
 // number of retries is exceeded.
-func (p *Pacer) Call(fn Paced) (err error) {
+//
+// If ctx is cancelled then no further attempts will be made and the
+// context's error will be returned.
+func (p *Pacer) Call(ctx context.Context, fn Paced) (err error) {
 	p.mu.Lock()
 	retries := p.retries
 	p.mu.Unlock()
-	return p.call(fn, retries)
+	return p.call(ctx, fn, retries)
 }
 
 // CallNoRetry paces the remote operations to not exceed the limits
@@ -362,6 +386,6 @@ func (p *Pacer) Call(fn Paced) (err error) {
 //
 // This calls fn and wraps the output in a RetryError if it would like
 // it to be retried
-func (p *Pacer) CallNoRetry(fn Paced) error {
-	return p.call(fn, 1)
+func (p *Pacer) CallNoRetry(ctx context.Context, fn Paced) error {
+	return p.call(ctx, fn, 1)
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 func TestNew(t *testing.T) {
@@ -201,7 +202,7 @@ func waitForPace(p *Pacer, duration time.Duration) (when time.Time) {
 func TestBeginCall(t *testing.T) {
 	p := New().SetMaxConnections(10).SetMinSleep(1 * time.Millisecond)
 	emptyTokens(p)
-	go p.beginCall()
+	go func() { _ = p.beginCall(context.Background()) }()
 	if !waitForPace(p, 10*time.Millisecond).IsZero() {
 		t.Errorf("beginSleep fired too early #1")
 	}
@@ -224,7 +225,7 @@ func TestBeginCall(t *testing.T) {
 func TestBeginCallZeroConnections(t *testing.T) {
 	p := New().SetMaxConnections(0).SetMinSleep(1 * time.Millisecond)
 	emptyTokens(p)
-	go p.beginCall()
+	go func() { _ = p.beginCall(context.Background()) }()
 	if !waitForPace(p, 10*time.Millisecond).IsZero() {
 		t.Errorf("beginSleep fired too early #1")
 	}
@@ -381,7 +382,7 @@ func Test_callNoRetry(t *testing.T) {
 	p := New().SetMinSleep(time.Millisecond).SetMaxSleep(2 * time.Millisecond)
 
 	dp := &dummyPaced{retry: false}
-	err := p.call(dp.fn, 10)
+	err := p.call(context.Background(), dp.fn, 10)
 	if dp.called != 1 {
 		t.Errorf("called want %d got %d", 1, dp.called)
 	}
@@ -394,7 +395,7 @@ func Test_callRetry(t *testing.T) {
 	p := New().SetMinSleep(time.Millisecond).SetMaxSleep(2 * time.Millisecond)
 
 	dp := &dummyPaced{retry: true}
-	err := p.call(dp.fn, 10)
+	err := p.call(context.Background(), dp.fn, 10)
 	if dp.called != 10 {
 		t.Errorf("called want %d got %d", 10, dp.called)
 	}
@@ -411,7 +412,7 @@ func TestCall(t *testing.T) {
 	p := New().SetMinSleep(time.Millisecond).SetMaxSleep(2 * time.Millisecond).SetRetries(20)
 
 	dp := &dummyPaced{retry: true}
-	err := p.Call(dp.fn)
+	err := p.Call(context.Background(), dp.fn)
 	if dp.called != 20 {
 		t.Errorf("called want %d got %d", 20, dp.called)
 	}
@@ -425,7 +426,7 @@ func TestCallNoRetry(t *testing.T) {
 	p := New().SetMinSleep(time.Millisecond).SetMaxSleep(2 * time.Millisecond).SetRetries(20)
 
 	dp := &dummyPaced{retry: true}
-	err := p.CallNoRetry(dp.fn)
+	err := p.CallNoRetry(context.Background(), dp.fn)
 	if dp.called != 1 {
 		t.Errorf("called want %d got %d", 1, dp.called)
 	}
@@ -434,3 +435,55 @@ func TestCallNoRetry(t *testing.T) {
 		t.Errorf("didn't return a retry error")
 	}
 }
+
+func TestBeginCallCancelled(t *testing.T) {
+	p := New().SetMaxConnections(10).SetMinSleep(1 * time.Millisecond)
+	emptyTokens(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := p.beginCall(ctx)
+	if err != context.Canceled {
+		t.Errorf("err want %v got %v", context.Canceled, err)
+	}
+	if len(p.pacer) != 0 {
+		t.Errorf("Expecting 0 pacer tokens")
+	}
+}
+
+func TestBeginCallCancelledWaitingForConnection(t *testing.T) {
+	p := New().SetMaxConnections(10).SetMinSleep(1 * time.Millisecond)
+	emptyTokens(p)
+	p.pacer <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := p.beginCall(ctx)
+	if err != context.Canceled {
+		t.Errorf("err want %v got %v", context.Canceled, err)
+	}
+	if len(p.pacer) != 1 {
+		t.Errorf("Expecting pacer token to be returned")
+	}
+}
+
+func TestCallCancelled(t *testing.T) {
+	p := New().SetMinSleep(time.Millisecond).SetMaxSleep(2 * time.Millisecond).SetRetries(20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	called := 0
+	err := p.Call(ctx, func() (bool, error) {
+		called++
+		if called == 3 {
+			cancel()
+		}
+		return true, errFoo
+	})
+	if called != 3 {
+		t.Errorf("called want %d got %d", 3, called)
+	}
+	if err != context.Canceled {
+		t.Errorf("err want %v got %v", context.Canceled, err)
+	}
+}
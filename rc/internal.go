@@ -0,0 +1,110 @@
+// Define the internal rc functions
+
+package rc
+
+import (
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	Add(Call{
+		Path:  "rc/noop",
+		Fn:    rcNoop,
+		Title: "Echo the input to the output parameters",
+		Help: `
+This echoes the input parameters to the output parameters for testing
+purposes.  It can be used to check that rclone is still alive and to
+check that parameter passing is working properly.`,
+	})
+	Add(Call{
+		Path:  "rc/error",
+		Fn:    rcError,
+		Title: "This returns an error",
+		Help: `
+This returns an error with the input as part of its error string.
+Useful for testing error handling.`,
+	})
+	Add(Call{
+		Path:  "rc/list",
+		Fn:    rcList,
+		Title: "List all the registered remote control commands",
+		Help: `
+This lists all the registered remote control commands as a JSON map in
+the commands response.`,
+	})
+	Add(Call{
+		Path:  "core/stats",
+		Fn:    rcStats,
+		Title: "Returns stats about current transfers.",
+		Help: `
+This returns all available stats
+
+Returns the following values:
+
+- bytes - total transferred bytes since the start of the process
+- checks - number of checked files
+- checking - an array of names of currently active file checks
+- elapsedTime - time in seconds since the start of the process
+- errors - number of errors
+- speed - average speed in bytes/sec since start of the process
+- transfers - number of transferred files
+- transferring - an array of currently active file transfers, each
+  with name, size, bytes, percentage, speed and eta if known`,
+	})
+	Add(Call{
+		Path:  "core/bwlimit",
+		Fn:    rcBwLimit,
+		Title: "Set the bandwidth limit.",
+		Help: `
+This sets the bandwidth limit to that passed in, eg
+
+    rclone rc core/bwlimit rate=1M
+    rclone rc core/bwlimit rate=off
+
+If the rate parameter is not supplied then the current limit is
+returned.  The rate is returned in bytes/s, 0 meaning unlimited.`,
+	})
+}
+
+// Echo the input to the output parameters
+func rcNoop(ctx context.Context, in Params) (out Params, err error) {
+	return in, nil
+}
+
+// Return an error regardless
+func rcError(ctx context.Context, in Params) (out Params, err error) {
+	return nil, errors.Errorf("arbitrary error on input %+v", in)
+}
+
+// List the registered commands
+func rcList(ctx context.Context, in Params) (out Params, err error) {
+	out = make(Params)
+	out["commands"] = List()
+	return out, nil
+}
+
+// Return the stats
+func rcStats(ctx context.Context, in Params) (out Params, err error) {
+	return fs.Stats.RemoteStats(), nil
+}
+
+// Set or read the bandwidth limit
+func rcBwLimit(ctx context.Context, in Params) (out Params, err error) {
+	rate, err := in.GetString("rate")
+	if err == nil {
+		var bwlimit fs.SizeSuffix
+		err = bwlimit.Set(rate)
+		if err != nil {
+			return nil, ErrParamInvalid{errors.Wrap(err, "bad bwlimit")}
+		}
+		fs.SetBwLimit(bwlimit)
+	} else if !IsErrParamNotFound(err) {
+		return nil, err
+	}
+	out = make(Params)
+	out["rate"] = fs.BwLimit().String()
+	out["bytesPerSecond"] = int64(fs.BwLimit())
+	return out, nil
+}
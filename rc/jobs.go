@@ -0,0 +1,247 @@
+// Manage background jobs that the rc is running
+
+package rc
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Job describes an asynchronous task started via the rc
+type Job struct {
+	mu        sync.Mutex
+	ID        int64
+	StartTime time.Time
+	EndTime   time.Time
+	Error     string
+	Finished  bool
+	Success   bool
+	Duration  float64
+	Output    Params
+	cancel    func()
+}
+
+// jobs is the global registry of jobs
+type jobs struct {
+	mu     sync.Mutex
+	jobs   map[int64]*Job
+	expire time.Duration
+}
+
+var (
+	jobsRegistry = newJobs()
+	jobID        = int64(0)
+)
+
+// newJobs makes a new job registry
+func newJobs() *jobs {
+	return &jobs{
+		jobs:   map[int64]*Job{},
+		expire: time.Minute,
+	}
+}
+
+// kickExpire removes finished jobs which finished longer ago than
+// the expiry time
+//
+// Call with lock held
+func (jobs *jobs) kickExpire() {
+	now := time.Now()
+	for ID, job := range jobs.jobs {
+		job.mu.Lock()
+		if job.Finished && now.Sub(job.EndTime) > jobs.expire {
+			delete(jobs.jobs, ID)
+		}
+		job.mu.Unlock()
+	}
+}
+
+// IDs returns the IDs of all the jobs, running and finished, in
+// ascending order
+//
+// Finished jobs are kept until they expire.
+func (jobs *jobs) IDs() (IDs []int64) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	jobs.kickExpire()
+	IDs = []int64{}
+	for ID := range jobs.jobs {
+		IDs = append(IDs, ID)
+	}
+	sort.Sort(int64s(IDs))
+	return IDs
+}
+
+// int64s is a sortable slice of int64
+type int64s []int64
+
+func (x int64s) Len() int           { return len(x) }
+func (x int64s) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x int64s) Less(i, j int) bool { return x[i] < x[j] }
+
+// Get a job with a given ID or nil if it doesn't exist
+func (jobs *jobs) Get(ID int64) *Job {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	jobs.kickExpire()
+	return jobs.jobs[ID]
+}
+
+// finish marks the job as finished
+func (job *Job) finish(out Params, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.EndTime = time.Now()
+	if out == nil {
+		out = make(Params)
+	}
+	job.Output = out
+	job.Duration = job.EndTime.Sub(job.StartTime).Seconds()
+	if err != nil {
+		job.Error = err.Error()
+		job.Success = false
+	} else {
+		job.Error = ""
+		job.Success = true
+	}
+	job.Finished = true
+}
+
+// run the job until completion writing the return status
+func (job *Job) run(ctx context.Context, fn Func, in Params) {
+	defer job.cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			job.finish(nil, errors.Errorf("panic received: %v", r))
+		}
+	}()
+	job.finish(fn(ctx, in))
+}
+
+// NewJob starts a new Job running fn with in in the background
+func (jobs *jobs) NewJob(fn Func, in Params) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		ID:        atomic.AddInt64(&jobID, 1),
+		StartTime: time.Now(),
+		cancel:    cancel,
+	}
+	jobs.mu.Lock()
+	jobs.jobs[job.ID] = job
+	jobs.mu.Unlock()
+	go job.run(ctx, fn, in)
+	return job
+}
+
+// StartJob starts a new job and returns a Param suitable for output
+func StartJob(fn Func, in Params) (Params, error) {
+	job := jobsRegistry.NewJob(fn, in)
+	fs.Debugf(nil, "rc: started job %d", job.ID)
+	out := make(Params)
+	out["jobid"] = job.ID
+	return out, nil
+}
+
+// status returns the status of the job as Params
+func (job *Job) status() Params {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	duration := job.Duration
+	if !job.Finished {
+		duration = time.Since(job.StartTime).Seconds()
+	}
+	return Params{
+		"id":        job.ID,
+		"startTime": job.StartTime,
+		"endTime":   job.EndTime,
+		"error":     job.Error,
+		"finished":  job.Finished,
+		"success":   job.Success,
+		"duration":  duration,
+		"output":    job.Output,
+	}
+}
+
+func init() {
+	Add(Call{
+		Path:  "job/status",
+		Fn:    rcJobStatus,
+		Title: "Reads the status of the job ID",
+		Help: `Parameters
+- jobid - id of the job (integer)
+
+Results
+- finished - boolean
+- duration - time in seconds that the job ran for
+- endTime - time the job finished (eg "2017-08-23T12:13:14.123456789Z")
+- error - error from the job or empty string for no error
+- id - as passed in above
+- startTime - time the job started (eg "2017-08-23T12:13:14.123456789Z")
+- success - boolean - true for success false otherwise
+- output - output of the job as would have been returned if called synchronously
+`,
+	})
+	Add(Call{
+		Path:  "job/list",
+		Fn:    rcJobList,
+		Title: "Lists the IDs of the running and finished jobs",
+		Help: `Parameters - None
+
+Results
+- jobids - array of integer job ids
+`,
+	})
+	Add(Call{
+		Path:  "job/stop",
+		Fn:    rcJobStop,
+		Title: "Stop the running job",
+		Help: `Parameters
+- jobid - id of the job (integer)
+`,
+	})
+}
+
+// getJob returns the job given by the jobid parameter
+func getJob(in Params) (*Job, error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	job := jobsRegistry.Get(jobID)
+	if job == nil {
+		return nil, ErrParamInvalid{errors.Errorf("job %d not found", jobID)}
+	}
+	return job, nil
+}
+
+// Return the status of a job
+func rcJobStatus(ctx context.Context, in Params) (out Params, err error) {
+	job, err := getJob(in)
+	if err != nil {
+		return nil, err
+	}
+	return job.status(), nil
+}
+
+// Return the IDs of all the jobs
+func rcJobList(ctx context.Context, in Params) (out Params, err error) {
+	out = make(Params)
+	out["jobids"] = jobsRegistry.IDs()
+	return out, nil
+}
+
+// Stop a running job
+func rcJobStop(ctx context.Context, in Params) (out Params, err error) {
+	job, err := getJob(in)
+	if err != nil {
+		return nil, err
+	}
+	job.cancel()
+	return nil, nil
+}
@@ -0,0 +1,82 @@
+package rc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// waitFinished waits for job to finish
+func waitFinished(t *testing.T, job *Job) Params {
+	for i := 0; i < 100; i++ {
+		status := job.status()
+		if status["finished"].(bool) {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("job didn't finish")
+	return nil
+}
+
+func TestJobRunAndExpire(t *testing.T) {
+	jobs := newJobs()
+	job := jobs.NewJob(func(ctx context.Context, in Params) (Params, error) {
+		return Params{"echo": in["a"]}, nil
+	}, Params{"a": "b"})
+	assert.Equal(t, []int64{job.ID}, jobs.IDs())
+	assert.Equal(t, job, jobs.Get(job.ID))
+
+	status := waitFinished(t, job)
+	assert.Equal(t, true, status["success"])
+	assert.Equal(t, "", status["error"])
+	assert.Equal(t, Params{"echo": "b"}, status["output"])
+
+	// Expire the job
+	jobs.expire = 0
+	time.Sleep(time.Millisecond)
+	assert.Nil(t, jobs.Get(job.ID))
+	assert.Equal(t, []int64{}, jobs.IDs())
+}
+
+func TestJobError(t *testing.T) {
+	jobs := newJobs()
+	job := jobs.NewJob(func(ctx context.Context, in Params) (Params, error) {
+		return nil, errors.New("boom")
+	}, Params{})
+	status := waitFinished(t, job)
+	assert.Equal(t, false, status["success"])
+	assert.Equal(t, "boom", status["error"])
+}
+
+func TestJobPanic(t *testing.T) {
+	jobs := newJobs()
+	job := jobs.NewJob(func(ctx context.Context, in Params) (Params, error) {
+		panic("boom")
+	}, Params{})
+	status := waitFinished(t, job)
+	assert.Equal(t, false, status["success"])
+	assert.Contains(t, status["error"], "panic")
+}
+
+func TestJobStop(t *testing.T) {
+	job, err := StartJob(func(ctx context.Context, in Params) (Params, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, Params{})
+	require.NoError(t, err)
+
+	_, err = rcJobStop(context.Background(), Params{"jobid": job["jobid"]})
+	require.NoError(t, err)
+
+	status := waitFinished(t, jobsRegistry.Get(job["jobid"].(int64)))
+	assert.Equal(t, false, status["success"])
+	assert.Equal(t, context.Canceled.Error(), status["error"])
+
+	_, err = rcJobStatus(context.Background(), Params{"jobid": int64(-1)})
+	assert.True(t, IsErrParamInvalid(err))
+}
@@ -0,0 +1,261 @@
+// Define the rc functions for sync and operations
+
+package rc
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	for _, name := range []string{"sync", "copy", "move"} {
+		name := name
+		Add(Call{
+			Path: "sync/" + name,
+			Fn: func(ctx context.Context, in Params) (Params, error) {
+				return rcSyncCopyMove(ctx, in, name)
+			},
+			Title: name + " a directory from source remote to destination remote",
+			Help: `This takes the following parameters
+
+- srcFs - a remote name string eg "drive:src" for the source
+- dstFs - a remote name string eg "drive:dst" for the destination
+
+This is equivalent to the ` + "`rclone " + name + "`" + ` command.  Add _async=true
+to run it in the background and poll the job with job/status.
+`,
+		})
+	}
+	for _, op := range []struct {
+		name  string
+		title string
+		fn    func(ctx context.Context, f fs.Fs, remote string) error
+		root  bool // if set, root the Fs at remote
+	}{
+		{name: "mkdir", title: "Make a destination directory or container", fn: fs.Mkdir},
+		{name: "rmdir", title: "Remove an empty directory or container", fn: fs.Rmdir},
+		{name: "rmdirs", title: "Remove all the empty directories in the path", fn: fs.Rmdirs},
+		{name: "purge", title: "Remove a directory or container and all of its contents", fn: func(ctx context.Context, f fs.Fs, remote string) error {
+			return fs.Purge(ctx, f)
+		}, root: true},
+		{name: "delete", title: "Remove files in the path", fn: func(ctx context.Context, f fs.Fs, remote string) error {
+			return fs.Delete(ctx, f)
+		}, root: true},
+		{name: "deletefile", title: "Remove the single file pointed to", fn: func(ctx context.Context, f fs.Fs, remote string) error {
+			o, err := f.NewObject(ctx, remote)
+			if err != nil {
+				return err
+			}
+			return fs.DeleteFile(ctx, o)
+		}},
+		{name: "cleanup", title: "Remove trashed files in the remote or path", fn: func(ctx context.Context, f fs.Fs, remote string) error {
+			return fs.CleanUp(ctx, f)
+		}},
+	} {
+		op := op
+		Add(Call{
+			Path: "operations/" + op.name,
+			Fn: func(ctx context.Context, in Params) (Params, error) {
+				f, remote, err := getFsAndRemote(in, op.root)
+				if err != nil {
+					return nil, err
+				}
+				return nil, op.fn(ctx, f, remote)
+			},
+			Title: op.title,
+			Help: `This takes the following parameters
+
+- fs - a remote name string eg "drive:"
+- remote - a path within that remote eg "dir"
+`,
+		})
+	}
+	for _, name := range []string{"copyfile", "movefile"} {
+		name := name
+		Add(Call{
+			Path: "operations/" + name,
+			Fn: func(ctx context.Context, in Params) (Params, error) {
+				return rcMoveOrCopyFile(ctx, in, name == "copyfile")
+			},
+			Title: name[:4] + " a file from source remote to destination remote",
+			Help: `This takes the following parameters
+
+- srcFs - a remote name string eg "drive:" for the source
+- srcRemote - a path within that remote eg "file.txt" for the source
+- dstFs - a remote name string eg "drive2:" for the destination
+- dstRemote - a path within that remote eg "file2.txt" for the destination
+`,
+		})
+	}
+	Add(Call{
+		Path:  "operations/list",
+		Fn:    rcOperationsList,
+		Title: "List the given remote and path in JSON format",
+		Help: `This takes the following parameters
+
+- fs - a remote name string eg "drive:"
+- remote - a path within that remote eg "dir"
+- recurse - set to list recursively
+
+The result is
+
+- list - an array of items with Path, Name, Size, ModTime and IsDir
+`,
+	})
+	Add(Call{
+		Path:  "operations/size",
+		Fn:    rcSize,
+		Title: "Count the number of bytes and files in remote",
+		Help: `This takes the following parameters
+
+- fs - a remote name string eg "drive:path/to/dir"
+
+Returns
+
+- count - number of files
+- bytes - number of bytes in those files
+`,
+	})
+}
+
+// getFs gets a fs.Fs named by the key passed in
+func getFs(in Params, key string) (fs.Fs, error) {
+	name, err := in.GetString(key)
+	if err != nil {
+		return nil, err
+	}
+	return fs.NewFs(name)
+}
+
+// getFsAndRemote gets the fs and remote parameters
+//
+// remote is optional and defaults to the root.  If root is set then
+// the Fs returned is rooted at remote and remote is returned as "".
+func getFsAndRemote(in Params, root bool) (f fs.Fs, remote string, err error) {
+	name, err := in.GetString("fs")
+	if err != nil {
+		return nil, "", err
+	}
+	remote, err = in.GetString("remote")
+	if err != nil && !IsErrParamNotFound(err) {
+		return nil, "", err
+	}
+	if root && remote != "" {
+		if !strings.HasSuffix(name, ":") && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		name, remote = name+remote, ""
+	}
+	f, err = fs.NewFs(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, remote, nil
+}
+
+// Sync, copy or move a directory
+func rcSyncCopyMove(ctx context.Context, in Params, name string) (out Params, err error) {
+	srcFs, err := getFs(in, "srcFs")
+	if err != nil {
+		return nil, err
+	}
+	dstFs, err := getFs(in, "dstFs")
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "sync":
+		return nil, fs.Sync(ctx, dstFs, srcFs)
+	case "copy":
+		return nil, fs.CopyDir(ctx, dstFs, srcFs)
+	}
+	return nil, fs.MoveDir(ctx, dstFs, srcFs)
+}
+
+// Copy or move a single file
+func rcMoveOrCopyFile(ctx context.Context, in Params, cp bool) (out Params, err error) {
+	srcFs, err := getFs(in, "srcFs")
+	if err != nil {
+		return nil, err
+	}
+	srcRemote, err := in.GetString("srcRemote")
+	if err != nil {
+		return nil, err
+	}
+	dstFs, err := getFs(in, "dstFs")
+	if err != nil {
+		return nil, err
+	}
+	dstRemote, err := in.GetString("dstRemote")
+	if err != nil {
+		return nil, err
+	}
+	if cp {
+		return nil, fs.CopyFile(ctx, dstFs, srcFs, dstRemote, srcRemote)
+	}
+	return nil, fs.MoveFile(ctx, dstFs, srcFs, dstRemote, srcRemote)
+}
+
+// listItem is the JSON form of an item returned by operations/list
+type listItem struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// List the directory
+func rcOperationsList(ctx context.Context, in Params) (out Params, err error) {
+	f, remote, err := getFsAndRemote(in, false)
+	if err != nil {
+		return nil, err
+	}
+	recurse, err := in.GetBool("recurse")
+	if err != nil && !IsErrParamNotFound(err) {
+		return nil, err
+	}
+	list := []listItem{}
+	err = fs.Walk(ctx, f, remote, false, fs.ConfigMaxDepth(recurse), func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			item := listItem{
+				Path:    entry.Remote(),
+				Name:    path.Base(entry.Remote()),
+				Size:    entry.Size(),
+				ModTime: entry.ModTime(),
+			}
+			_, item.IsDir = entry.(*fs.Dir)
+			list = append(list, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out = make(Params)
+	out["list"] = list
+	return out, nil
+}
+
+// Count the files and bytes in the remote
+func rcSize(ctx context.Context, in Params) (out Params, err error) {
+	f, err := getFs(in, "fs")
+	if err != nil {
+		return nil, err
+	}
+	count, bytes, err := fs.Count(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	out = make(Params)
+	out["count"] = count
+	out["bytes"] = bytes
+	return out, nil
+}
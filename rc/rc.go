@@ -0,0 +1,226 @@
+// Package rc implements a remote control server and registry for rclone
+//
+// To register your internal calls, call rc.Add with a Call.  Your
+// function should take and return a Params.  It can also return an
+// error.  Errors of type ErrParamNotFound or ErrParamInvalid are
+// returned to the caller as 400 Bad Request, any other error as 500
+// Internal Server Error.
+package rc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Params is the input and output type for the Func
+type Params map[string]interface{}
+
+// Func defines a type for a remote control function
+type Func func(ctx context.Context, in Params) (out Params, err error)
+
+// Call defines info about a remote control function and is used in
+// the Add function to create new entry points.
+type Call struct {
+	Path  string // path to activate this RC
+	Fn    Func   `json:"-"` // function to call
+	Title string // help for the function
+	Help  string // multi-line markdown formatted help
+}
+
+// registry holds the list of all the registered remote control functions
+type registry struct {
+	mu   sync.RWMutex
+	call map[string]*Call
+}
+
+// newRegistry makes a new registry for remote control functions
+func newRegistry() *registry {
+	return &registry{
+		call: make(map[string]*Call),
+	}
+}
+
+// add a call to the registry
+func (r *registry) add(call Call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	call.Path = strings.Trim(call.Path, "/")
+	call.Help = strings.TrimSpace(call.Help)
+	r.call[call.Path] = &call
+}
+
+// get a Call from a path or nil
+func (r *registry) get(path string) *Call {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.call[path]
+}
+
+// list returns a sorted list of all the Calls
+func (r *registry) list() (out []*Call) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var keys []string
+	for key := range r.call {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		out = append(out, r.call[key])
+	}
+	return out
+}
+
+// calls is the global registry of Call
+var calls = newRegistry()
+
+// Add a function to the global registry
+//
+// Adding a call with the same Path as an existing one replaces it.
+func Add(call Call) {
+	calls.add(call)
+}
+
+// Find a Call by path returning nil if not found
+func Find(path string) *Call {
+	return calls.get(strings.Trim(path, "/"))
+}
+
+// List returns a sorted list of all the registered Calls
+func List() []*Call {
+	return calls.list()
+}
+
+// ErrParamNotFound - this is returned from the Get* functions if the
+// parameter isn't found along with a zero value of the requested
+// item.
+//
+// Returning an error of this type from an rc.Func will cause the http
+// method to return http.StatusBadRequest
+type ErrParamNotFound string
+
+// Error turns this error into a string
+func (e ErrParamNotFound) Error() string {
+	return fmt.Sprintf("Didn't find key %q in input", string(e))
+}
+
+// IsErrParamNotFound returns whether err is ErrParamNotFound
+func IsErrParamNotFound(err error) bool {
+	_, isNotFound := errors.Cause(err).(ErrParamNotFound)
+	return isNotFound
+}
+
+// ErrParamInvalid - this is returned from the Get* functions if the
+// parameter is invalid.
+//
+// Returning an error of this type from an rc.Func will cause the http
+// method to return http.StatusBadRequest
+type ErrParamInvalid struct {
+	error
+}
+
+// IsErrParamInvalid returns whether err is ErrParamInvalid
+func IsErrParamInvalid(err error) bool {
+	_, isInvalid := errors.Cause(err).(ErrParamInvalid)
+	return isInvalid
+}
+
+// Get gets a parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be nil.
+func (p Params) Get(key string) (interface{}, error) {
+	value, ok := p[key]
+	if !ok {
+		return nil, ErrParamNotFound(key)
+	}
+	return value, nil
+}
+
+// GetString gets a string parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be "".
+func (p Params) GetString(key string) (string, error) {
+	value, err := p.Get(key)
+	if err != nil {
+		return "", err
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", ErrParamInvalid{errors.Errorf("expecting string value for key %q (was %T)", key, value)}
+	}
+	return str, nil
+}
+
+// GetInt64 gets an int64 parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be 0.
+func (p Params) GetInt64(key string) (int64, error) {
+	value, err := p.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	switch x := value.(type) {
+	case int:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case float64:
+		if x > float64(1<<63-1) || x < -float64(1<<63) || x != float64(int64(x)) {
+			return 0, ErrParamInvalid{errors.Errorf("key %q (%v) isn't an integer", key, value)}
+		}
+		return int64(x), nil
+	case string:
+		i, err := strconv.ParseInt(x, 10, 0)
+		if err != nil {
+			return 0, ErrParamInvalid{errors.Wrapf(err, "couldn't parse key %q (%v) as int64", key, value)}
+		}
+		return i, nil
+	}
+	return 0, ErrParamInvalid{errors.Errorf("expecting int64 value for key %q (was %T)", key, value)}
+}
+
+// GetBool gets a boolean parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be false.
+func (p Params) GetBool(key string) (bool, error) {
+	value, err := p.Get(key)
+	if err != nil {
+		return false, err
+	}
+	switch x := value.(type) {
+	case int:
+		return x != 0, nil
+	case int64:
+		return x != 0, nil
+	case float64:
+		return x != 0, nil
+	case bool:
+		return x, nil
+	case string:
+		b, err := strconv.ParseBool(x)
+		if err != nil {
+			return false, ErrParamInvalid{errors.Wrapf(err, "couldn't parse key %q (%v) as bool", key, value)}
+		}
+		return b, nil
+	}
+	return false, ErrParamInvalid{errors.Errorf("expecting bool value for key %q (was %T)", key, value)}
+}
+
+// Copy shallow copies the Params
+func (p Params) Copy() (out Params) {
+	out = make(Params, len(p))
+	for k, v := range p {
+		out[k] = v
+	}
+	return out
+}
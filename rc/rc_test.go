@@ -0,0 +1,81 @@
+package rc
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRegistry(t *testing.T) {
+	r := newRegistry()
+	fn := func(ctx context.Context, in Params) (Params, error) { return in, nil }
+	r.add(Call{Path: "/b/call/", Fn: fn, Help: "\nhelp\n"})
+	r.add(Call{Path: "a/call", Fn: fn})
+
+	call := r.get("b/call")
+	require.NotNil(t, call)
+	assert.Equal(t, "b/call", call.Path)
+	assert.Equal(t, "help", call.Help)
+	assert.Nil(t, r.get("potato"))
+
+	var paths []string
+	for _, call := range r.list() {
+		paths = append(paths, call.Path)
+	}
+	assert.Equal(t, []string{"a/call", "b/call"}, paths)
+}
+
+func TestParamsGet(t *testing.T) {
+	in := Params{
+		"string":    "one",
+		"int":       int(2),
+		"int64":     int64(3),
+		"float":     float64(4),
+		"badfloat":  float64(4.5),
+		"intstring": "5",
+		"bool":      true,
+		"boolstr":   "false",
+	}
+
+	_, err := in.Get("potato")
+	assert.True(t, IsErrParamNotFound(err))
+
+	s, err := in.GetString("string")
+	require.NoError(t, err)
+	assert.Equal(t, "one", s)
+	_, err = in.GetString("int")
+	assert.True(t, IsErrParamInvalid(err))
+
+	for key, want := range map[string]int64{"int": 2, "int64": 3, "float": 4, "intstring": 5} {
+		got, err := in.GetInt64(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, got, key)
+	}
+	_, err = in.GetInt64("badfloat")
+	assert.True(t, IsErrParamInvalid(err))
+	_, err = in.GetInt64("string")
+	assert.True(t, IsErrParamInvalid(err))
+	_, err = in.GetInt64("potato")
+	assert.True(t, IsErrParamNotFound(err))
+
+	b, err := in.GetBool("bool")
+	require.NoError(t, err)
+	assert.True(t, b)
+	b, err = in.GetBool("boolstr")
+	require.NoError(t, err)
+	assert.False(t, b)
+	b, err = in.GetBool("int")
+	require.NoError(t, err)
+	assert.True(t, b)
+	_, err = in.GetBool("string")
+	assert.True(t, IsErrParamInvalid(err))
+}
+
+func TestErrorCause(t *testing.T) {
+	err := errors.Wrap(ErrParamNotFound("x"), "wrapped")
+	assert.True(t, IsErrParamNotFound(err))
+	assert.False(t, IsErrParamInvalid(err))
+}
@@ -0,0 +1,248 @@
+// Serve the remote control over HTTP
+
+package rc
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Options contains options for the remote control server
+type Options struct {
+	Enabled           bool          // set to enable the server
+	ListenAddr        string        // IP address:Port to listen on
+	User              string        // user name for basic auth
+	Pass              string        // password for basic auth
+	NoAuth            bool          // set to allow serving without auth on a non local address
+	JobExpireDuration time.Duration // how long to keep finished jobs for
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:        "localhost:5572",
+	JobExpireDuration: 60 * time.Second,
+}
+
+// Flags
+var (
+	enabled           = fs.BoolP("rc", "", false, "Enable the remote control server.")
+	listenAddr        = fs.StringP("rc-addr", "", DefaultOpt.ListenAddr, "IPaddress:Port to bind the remote control server to.")
+	user              = fs.StringP("rc-user", "", "", "User name for authentication to the remote control server.")
+	pass              = fs.StringP("rc-pass", "", "", "Password for authentication to the remote control server.")
+	noAuth            = fs.BoolP("rc-no-auth", "", false, "Don't require auth for the remote control server even on a non local address.")
+	jobExpireDuration = fs.DurationP("rc-job-expire-duration", "", DefaultOpt.JobExpireDuration, "How long to keep finished rc jobs for.")
+)
+
+// Start the remote control server if enabled by the command line flags
+func Start() error {
+	return StartWithOptions(Options{
+		Enabled:           *enabled,
+		ListenAddr:        *listenAddr,
+		User:              *user,
+		Pass:              *pass,
+		NoAuth:            *noAuth,
+		JobExpireDuration: *jobExpireDuration,
+	})
+}
+
+// StartWithOptions starts the remote control server if opt.Enabled is
+// set
+func StartWithOptions(opt Options) error {
+	if !opt.Enabled {
+		return nil
+	}
+	s, err := newServer(opt)
+	if err != nil {
+		return err
+	}
+	go s.serve()
+	return nil
+}
+
+// server contains everything to run the rc server
+type server struct {
+	opt      Options
+	listener net.Listener
+}
+
+// newServer makes a new rc server listening on opt.ListenAddr
+func newServer(opt Options) (*server, error) {
+	if opt.User == "" && !opt.NoAuth && !isLocalAddress(opt.ListenAddr) {
+		return nil, errors.Errorf("refusing to serve the remote control on non local address %q without --rc-user and --rc-pass or --rc-no-auth", opt.ListenAddr)
+	}
+	listener, err := net.Listen("tcp", opt.ListenAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start remote control server")
+	}
+	jobsRegistry.mu.Lock()
+	jobsRegistry.expire = opt.JobExpireDuration
+	jobsRegistry.mu.Unlock()
+	fs.Logf(nil, "Serving remote control on http://%s/", listener.Addr())
+	return &server{
+		opt:      opt,
+		listener: listener,
+	}, nil
+}
+
+// isLocalAddress returns true if addr will only listen on the
+// loopback interface
+func isLocalAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serve runs the server until it has an error
+func (s *server) serve() {
+	err := http.Serve(s.listener, s)
+	if err != nil {
+		fs.Errorf(nil, "Remote control server failed: %v", err)
+	}
+}
+
+// URL returns the URL the server is listening on
+func (s *server) URL() string {
+	return "http://" + s.listener.Addr().String() + "/"
+}
+
+// close stops the server listening
+func (s *server) close() error {
+	return s.listener.Close()
+}
+
+// checkAuth returns true if the request is authorised
+func (s *server) checkAuth(r *http.Request) bool {
+	if s.opt.User == "" {
+		return true
+	}
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.opt.User)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.opt.Pass)) == 1
+	return userOK && passOK
+}
+
+// writeJSON writes the JSON output to the response
+func writeJSON(w http.ResponseWriter, status int, out interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	err := enc.Encode(out)
+	if err != nil {
+		fs.Errorf(nil, "rc: failed to write JSON output: %v", err)
+	}
+}
+
+// writeError writes a formatted error to the output
+func writeError(path string, in Params, w http.ResponseWriter, err error, status int) {
+	fs.Errorf(nil, "rc: %q: error: %v", path, err)
+	// Adjust the error return for some well known errors
+	if IsErrParamNotFound(err) || IsErrParamInvalid(err) {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, Params{
+		"status": status,
+		"error":  err.Error(),
+		"input":  in,
+		"path":   path,
+	})
+}
+
+// readParams reads the parameters for the call from the URL query,
+// the form and a JSON body if supplied
+func readParams(r *http.Request) (Params, error) {
+	in := make(Params)
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType == "application/json" {
+		err := json.NewDecoder(r.Body).Decode(&in)
+		if err != nil {
+			return in, errors.Wrap(err, "failed to read input JSON")
+		}
+		// A JSON body of null leaves in as nil
+		if in == nil {
+			in = make(Params)
+		}
+	}
+	// ParseForm won't read a JSON body so this just adds the query
+	// parameters in that case
+	err := r.ParseForm()
+	if err != nil {
+		return in, errors.Wrap(err, "failed to parse form/URL parameters")
+	}
+	for k, vs := range r.Form {
+		if len(vs) > 0 {
+			in[k] = vs[len(vs)-1]
+		}
+	}
+	return in, nil
+}
+
+// ServeHTTP handles a single remote control request
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	in := make(Params)
+
+	if !s.checkAuth(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="rclone rc"`)
+		writeError(path, in, w, errors.New("authentication required"), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		writeError(path, in, w, errors.Errorf("method %q not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	in, err := readParams(r)
+	if err != nil {
+		writeError(path, in, w, err, http.StatusBadRequest)
+		return
+	}
+
+	call := Find(path)
+	if call == nil {
+		writeError(path, in, w, errors.Errorf("couldn't find method %q", path), http.StatusNotFound)
+		return
+	}
+
+	// Check to see if it is async or not
+	isAsync, err := in.GetBool("_async")
+	if err != nil && !IsErrParamNotFound(err) {
+		writeError(path, in, w, err, http.StatusBadRequest)
+		return
+	}
+	delete(in, "_async")
+
+	fs.Debugf(nil, "rc: %q: with parameters %+v", path, in)
+	var out Params
+	if isAsync {
+		out, err = StartJob(call.Fn, in)
+	} else {
+		out, err = call.Fn(r.Context(), in)
+	}
+	if err != nil {
+		writeError(path, in, w, err, http.StatusInternalServerError)
+		return
+	}
+	if out == nil {
+		out = make(Params)
+	}
+	fs.Debugf(nil, "rc: %q: reply %+v", path, out)
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,123 @@
+package rc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// do makes a request to the server returning the status and decoded
+// JSON response
+func do(t *testing.T, s *server, method, path, contentType, body string, auth bool) (int, Params) {
+	req, err := http.NewRequest(method, "http://localhost/"+path, strings.NewReader(body))
+	require.NoError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if auth {
+		req.SetBasicAuth("user", "pass")
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var out Params
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	return w.Code, out
+}
+
+func TestServerCalls(t *testing.T) {
+	s := &server{}
+
+	code, out := do(t, s, "POST", "rc/noop?a=b", "", "", false)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Params{"a": "b"}, out)
+
+	code, out = do(t, s, "POST", "rc/noop", "application/x-www-form-urlencoded", "c=d", false)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Params{"c": "d"}, out)
+
+	code, out = do(t, s, "POST", "rc/noop?e=f", "application/json", `{"g":1}`, false)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Params{"e": "f", "g": float64(1)}, out)
+
+	code, out = do(t, s, "POST", "rc/noop?h=i", "application/json", `null`, false)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Params{"h": "i"}, out)
+
+	code, _ = do(t, s, "POST", "rc/noop", "application/json", `{bad`, false)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, out = do(t, s, "POST", "rc/error", "", "", false)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Contains(t, out["error"], "arbitrary error")
+	assert.Equal(t, "rc/error", out["path"])
+
+	code, _ = do(t, s, "POST", "potato", "", "", false)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = do(t, s, "GET", "rc/noop", "", "", false)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	code, _ = do(t, s, "POST", "job/status?jobid=potato", "", "", false)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, out = do(t, s, "POST", "core/stats", "", "", false)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, out, "bytes")
+	assert.Contains(t, out, "transfers")
+}
+
+func TestServerAsync(t *testing.T) {
+	s := &server{}
+	code, out := do(t, s, "POST", "rc/noop?a=b&_async=true", "", "", false)
+	assert.Equal(t, http.StatusOK, code)
+	jobID, ok := out["jobid"].(float64)
+	require.True(t, ok)
+
+	job := jobsRegistry.Get(int64(jobID))
+	require.NotNil(t, job)
+	status := waitFinished(t, job)
+	assert.Equal(t, Params{"a": "b"}, status["output"])
+}
+
+func TestServerAuth(t *testing.T) {
+	s := &server{opt: Options{User: "user", Pass: "pass"}}
+
+	code, _ := do(t, s, "POST", "rc/noop", "", "", false)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = do(t, s, "POST", "rc/noop", "", "", true)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestIsLocalAddress(t *testing.T) {
+	for _, test := range []struct {
+		addr string
+		want bool
+	}{
+		{"localhost:5572", true},
+		{"127.0.0.1:5572", true},
+		{"[::1]:5572", true},
+		{":5572", false},
+		{"0.0.0.0:5572", false},
+		{"example.com:5572", false},
+		{"potato", false},
+	} {
+		assert.Equal(t, test.want, isLocalAddress(test.addr), test.addr)
+	}
+}
+
+func TestNewServerRefusesNoAuth(t *testing.T) {
+	_, err := newServer(Options{ListenAddr: "0.0.0.0:0"})
+	require.Error(t, err)
+
+	s, err := newServer(Options{ListenAddr: "localhost:0"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(s.URL(), "http://127.0.0.1:"))
+	require.NoError(t, s.close())
+}
@@ -6,6 +6,7 @@ package rest
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -13,6 +14,8 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
 )
 
 // Client contains the info to sustain the API
@@ -99,6 +102,13 @@ func DecodeJSON(resp *http.Response, result interface{}) (err error) {
 	return decoder.Decode(result)
 }
 
+// DecodeXML decodes resp.Body into result
+func DecodeXML(resp *http.Response, result interface{}) (err error) {
+	defer fs.CheckClose(resp.Body, &err)
+	decoder := xml.NewDecoder(resp.Body)
+	return decoder.Decode(result)
+}
+
 // ClientWithHeaderReset makes a new http client which resets the
 // headers passed in on redirect
 //
@@ -128,7 +138,9 @@ func ClientWithHeaderReset(c *http.Client, headers map[string]string) *http.Clie
 // if err != nil then resp.Body will need to be closed
 //
 // it will return resp if at all possible, even if err is set
-func (api *Client) Call(opts *Opts) (resp *http.Response, err error) {
+//
+// The request is aborted if ctx is cancelled.
+func (api *Client) Call(ctx context.Context, opts *Opts) (resp *http.Response, err error) {
 	api.mu.RLock()
 	defer api.mu.RUnlock()
 	if opts == nil {
@@ -180,7 +192,7 @@ func (api *Client) Call(opts *Opts) (resp *http.Response, err error) {
 	}
 	c := ClientWithHeaderReset(api.c, headers)
 	api.mu.RUnlock()
-	resp, err = c.Do(req)
+	resp, err = ctxhttp.Do(ctx, c, req)
 	api.mu.RLock()
 	if err != nil {
 		return nil, err
@@ -201,7 +213,7 @@ func (api *Client) Call(opts *Opts) (resp *http.Response, err error) {
 // If request is not nil then it will be JSON encoded as the body of the request
 //
 // It will return resp if at all possible, even if err is set
-func (api *Client) CallJSON(opts *Opts, request interface{}, response interface{}) (resp *http.Response, err error) {
+func (api *Client) CallJSON(ctx context.Context, opts *Opts, request interface{}, response interface{}) (resp *http.Response, err error) {
 	// Set the body up as a JSON object if required
 	if opts.Body == nil && request != nil {
 		body, err := json.Marshal(request)
@@ -213,7 +225,7 @@ func (api *Client) CallJSON(opts *Opts, request interface{}, response interface{
 		newOpts.ContentType = "application/json"
 		opts = &newOpts
 	}
-	resp, err = api.Call(opts)
+	resp, err = api.Call(ctx, opts)
 	if err != nil {
 		return resp, err
 	}
@@ -223,3 +235,31 @@ func (api *Client) CallJSON(opts *Opts, request interface{}, response interface{
 	err = DecodeJSON(resp, response)
 	return resp, err
 }
+
+// CallXML runs Call and decodes the body as an XML object into response (if not nil)
+//
+// If request is not nil then it will be XML encoded as the body of the request
+//
+// It will return resp if at all possible, even if err is set
+func (api *Client) CallXML(ctx context.Context, opts *Opts, request interface{}, response interface{}) (resp *http.Response, err error) {
+	// Set the body up as an XML object if required
+	if opts.Body == nil && request != nil {
+		body, err := xml.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		var newOpts = *opts
+		newOpts.Body = bytes.NewBuffer(body)
+		newOpts.ContentType = "application/xml"
+		opts = &newOpts
+	}
+	resp, err = api.Call(ctx, opts)
+	if err != nil {
+		return resp, err
+	}
+	if response == nil || opts.NoResponse {
+		return resp, nil
+	}
+	err = DecodeXML(resp, response)
+	return resp, err
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/swift"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Register with Fs
@@ -215,6 +216,7 @@ func init() {
 			}},
 		}},
 	})
+	fs.VarP(&copyCutoff, "s3-copy-cutoff", "", "Cutoff for switching to multipart copy - also the size of the parts")
 }
 
 // Constants
@@ -223,13 +225,19 @@ const (
 	listChunkSize  = 1024                   // number of items to read at once
 	maxRetries     = 10                     // number of retries to make of operations
 	maxSizeForCopy = 5 * 1024 * 1024 * 1024 // The maximum size of object we can COPY
+	minCopyCutoff  = 5 * 1024 * 1024        // The minimum size of a part of a multipart copy
+	maxLinkExpiry  = 7 * 24 * time.Hour     // The longest a pre-signed URL can last
 )
 
 // Globals
 var (
 	// Flags
-	s3ACL          = fs.StringP("s3-acl", "", "", "Canned ACL used when creating buckets and/or storing objects in S3")
-	s3StorageClass = fs.StringP("s3-storage-class", "", "", "Storage class to use when uploading S3 objects (STANDARD|REDUCED_REDUNDANCY|STANDARD_IA)")
+	s3ACL             = fs.StringP("s3-acl", "", "", "Canned ACL used when creating buckets and/or storing objects in S3")
+	s3StorageClass    = fs.StringP("s3-storage-class", "", "", "Storage class to use when uploading S3 objects (STANDARD|REDUCED_REDUNDANCY|STANDARD_IA)")
+	s3CopyConcurrency = fs.IntP("s3-copy-concurrency", "", 4, "Number of parts of a multipart copy to copy at once")
+	s3Versions        = fs.BoolP("s3-versions", "", false, "Include old versions in directory listings.")
+	s3VersionAt       = fs.StringP("s3-version-at", "", "", "Show the files as they were at this time, eg \"2017-09-01 12:00:00\" or an age like 1d.")
+	copyCutoff        = fs.SizeSuffix(maxSizeForCopy)
 )
 
 // Fs represents a remote s3 server
@@ -246,6 +254,8 @@ type Fs struct {
 	locationConstraint string           // location constraint of new buckets
 	sse                string           // the type of server-side encryption
 	storageClass       string           // storage class
+	versions           bool             // set to show old versions
+	versionAt          time.Time        // if set show the objects as they were at this time
 }
 
 // Object describes a s3 object
@@ -261,6 +271,8 @@ type Object struct {
 	lastModified time.Time          // Last modified
 	meta         map[string]*string // The object metadata if known - may be nil
 	mimeType     string             // MimeType of object - may be ""
+	storageClass string             // storage class of the object - may be ""
+	version      *objectVersion     // the version if --s3-versions or --s3-version-at - may be nil
 }
 
 // ------------------------------------------------------------
@@ -370,6 +382,10 @@ func s3Connection(name string) (*s3.S3, *session.Session, error) {
 			if req.Config.Credentials == credentials.AnonymousCredentials {
 				return
 			}
+			if req.ExpireTime != 0 {
+				presign(v.AccessKeyID, v.SecretAccessKey, req.HTTPRequest, time.Now().Add(req.ExpireTime))
+				return
+			}
 			sign(v.AccessKeyID, v.SecretAccessKey, req.HTTPRequest)
 		}
 		c.Handlers.Sign.Clear()
@@ -381,6 +397,24 @@ func s3Connection(name string) (*s3.S3, *session.Session, error) {
 
 // NewFs constructs an Fs from the path, bucket:path
 func NewFs(name, root string) (fs.Fs, error) {
+	ctx := context.Background()
+	if copyCutoff < minCopyCutoff || copyCutoff > maxSizeForCopy {
+		return nil, errors.Errorf("s3: copy cutoff must be between %v and %v - was %v", fs.SizeSuffix(minCopyCutoff), fs.SizeSuffix(maxSizeForCopy), copyCutoff)
+	}
+	if *s3CopyConcurrency < 1 {
+		return nil, errors.Errorf("s3: copy concurrency must be at least 1 - was %d", *s3CopyConcurrency)
+	}
+	var versionAt time.Time
+	if *s3VersionAt != "" {
+		if *s3Versions {
+			return nil, errors.New("s3: can't use --s3-versions and --s3-version-at together")
+		}
+		var err error
+		versionAt, err = parseVersionAt(*s3VersionAt, time.Now())
+		if err != nil {
+			return nil, errors.Wrap(err, "s3: bad --s3-version-at")
+		}
+	}
 	bucket, directory, err := s3ParsePath(root)
 	if err != nil {
 		return nil, err
@@ -399,6 +433,8 @@ func NewFs(name, root string) (fs.Fs, error) {
 		locationConstraint: fs.ConfigFileGet(name, "location_constraint"),
 		sse:                fs.ConfigFileGet(name, "server_side_encryption"),
 		storageClass:       fs.ConfigFileGet(name, "storage_class"),
+		versions:           *s3Versions,
+		versionAt:          versionAt,
 	}
 	f.features = (&fs.Features{ReadMimeType: true, WriteMimeType: true}).Fill(f)
 	if *s3ACL != "" {
@@ -409,19 +445,31 @@ func NewFs(name, root string) (fs.Fs, error) {
 	}
 	if f.root != "" {
 		f.root += "/"
+		parent := path.Dir(directory)
+		if parent == "." {
+			parent = ""
+		} else {
+			parent += "/"
+		}
 		// Check to see if the object exists
-		req := s3.HeadObjectInput{
-			Bucket: &f.bucket,
-			Key:    &directory,
+		if f.versions || !f.versionAt.IsZero() {
+			root := f.root
+			f.root = parent
+			_, err = f.newObjectVersion(ctx, path.Base(directory))
+			if err != nil {
+				f.root = root
+			}
+		} else {
+			req := s3.HeadObjectInput{
+				Bucket: &f.bucket,
+				Key:    &directory,
+			}
+			_, err = f.c.HeadObjectWithContext(ctx, &req)
+			if err == nil {
+				f.root = parent
+			}
 		}
-		_, err = f.c.HeadObject(&req)
 		if err == nil {
-			f.root = path.Dir(directory)
-			if f.root == "." {
-				f.root = ""
-			} else {
-				f.root += "/"
-			}
 			// return an error with an fs which points to the parent
 			return f, fs.ErrorIsFile
 		}
@@ -433,10 +481,11 @@ func NewFs(name, root string) (fs.Fs, error) {
 // Return an Object from a path
 //
 //If it can't be found it returns the error ErrorObjectNotFound.
-func (f *Fs) newObjectWithInfo(remote string, info *s3.Object) (fs.Object, error) {
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *s3.Object, version *objectVersion) (fs.Object, error) {
 	o := &Object{
-		fs:     f,
-		remote: remote,
+		fs:      f,
+		remote:  remote,
+		version: version,
 	}
 	if info != nil {
 		// Set info but not meta
@@ -448,8 +497,9 @@ func (f *Fs) newObjectWithInfo(remote string, info *s3.Object) (fs.Object, error
 		}
 		o.etag = aws.StringValue(info.ETag)
 		o.bytes = aws.Int64Value(info.Size)
+		o.storageClass = aws.StringValue(info.StorageClass)
 	} else {
-		err := o.readMetaData() // reads info and meta, returning an error
+		err := o.readMetaData(ctx) // reads info and meta, returning an error
 		if err != nil {
 			return nil, err
 		}
@@ -459,23 +509,67 @@ func (f *Fs) newObjectWithInfo(remote string, info *s3.Object) (fs.Object, error
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
-	return f.newObjectWithInfo(remote, nil)
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.versions || !f.versionAt.IsZero() {
+		return f.newObjectVersion(ctx, remote)
+	}
+	return f.newObjectWithInfo(ctx, remote, nil, nil)
 }
 
 // listFn is called from list to handle an object.
-type listFn func(remote string, object *s3.Object, isDirectory bool) error
+//
+// version is only set when listing versions.
+type listFn func(remote string, object *s3.Object, version *objectVersion, isDirectory bool) error
+
+// translateListError turns a not found error from a listing into
+// fs.ErrorDirNotFound
+func translateListError(err error) error {
+	if awsErr, ok := err.(awserr.RequestFailure); ok {
+		if awsErr.StatusCode() == http.StatusNotFound {
+			return fs.ErrorDirNotFound
+		}
+	}
+	return err
+}
+
+// sendCommonPrefixes sends the directories from a listing to fn
+func (f *Fs) sendCommonPrefixes(commonPrefixes []*s3.CommonPrefix, fn listFn) error {
+	rootLength := len(f.root)
+	for _, commonPrefix := range commonPrefixes {
+		if commonPrefix.Prefix == nil {
+			fs.Logf(f, "Nil common prefix received")
+			continue
+		}
+		remote := *commonPrefix.Prefix
+		if !strings.HasPrefix(remote, f.root) {
+			fs.Logf(f, "Odd name received %q", remote)
+			continue
+		}
+		remote = remote[rootLength:]
+		if strings.HasSuffix(remote, "/") {
+			remote = remote[:len(remote)-1]
+		}
+		err := fn(remote, &s3.Object{Key: &remote}, nil, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 // list the objects into the function supplied
 //
 // dir is the starting directory, "" for root
 //
 // Set recurse to read sub directories
-func (f *Fs) list(dir string, recurse bool, fn listFn) error {
+func (f *Fs) list(ctx context.Context, dir string, recurse bool, fn listFn) error {
 	root := f.root
 	if dir != "" {
 		root += dir + "/"
 	}
+	if f.versions || !f.versionAt.IsZero() {
+		return f.listVersions(ctx, root, recurse, fn)
+	}
 	maxKeys := int64(listChunkSize)
 	delimiter := ""
 	if !recurse {
@@ -491,35 +585,15 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 			MaxKeys:   &maxKeys,
 			Marker:    marker,
 		}
-		resp, err := f.c.ListObjects(&req)
+		resp, err := f.c.ListObjectsWithContext(ctx, &req)
 		if err != nil {
-			if awsErr, ok := err.(awserr.RequestFailure); ok {
-				if awsErr.StatusCode() == http.StatusNotFound {
-					err = fs.ErrorDirNotFound
-				}
-			}
-			return err
+			return translateListError(err)
 		}
 		rootLength := len(f.root)
 		if !recurse {
-			for _, commonPrefix := range resp.CommonPrefixes {
-				if commonPrefix.Prefix == nil {
-					fs.Logf(f, "Nil common prefix received")
-					continue
-				}
-				remote := *commonPrefix.Prefix
-				if !strings.HasPrefix(remote, f.root) {
-					fs.Logf(f, "Odd name received %q", remote)
-					continue
-				}
-				remote = remote[rootLength:]
-				if strings.HasSuffix(remote, "/") {
-					remote = remote[:len(remote)-1]
-				}
-				err = fn(remote, &s3.Object{Key: &remote}, true)
-				if err != nil {
-					return err
-				}
+			err = f.sendCommonPrefixes(resp.CommonPrefixes, fn)
+			if err != nil {
+				return err
 			}
 		}
 		for _, object := range resp.Contents {
@@ -529,7 +603,7 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 				continue
 			}
 			remote := key[rootLength:]
-			err = fn(remote, object, false)
+			err = fn(remote, object, nil, false)
 			if err != nil {
 				return err
 			}
@@ -548,7 +622,7 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 }
 
 // Convert a list item into a BasicInfo
-func (f *Fs) itemToDirEntry(remote string, object *s3.Object, isDirectory bool) (fs.BasicInfo, error) {
+func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *s3.Object, version *objectVersion, isDirectory bool) (fs.BasicInfo, error) {
 	if isDirectory {
 		size := int64(0)
 		if object.Size != nil {
@@ -561,7 +635,7 @@ func (f *Fs) itemToDirEntry(remote string, object *s3.Object, isDirectory bool)
 		}
 		return d, nil
 	}
-	o, err := f.newObjectWithInfo(remote, object)
+	o, err := f.newObjectWithInfo(ctx, remote, object, version)
 	if err != nil {
 		return nil, err
 	}
@@ -569,10 +643,10 @@ func (f *Fs) itemToDirEntry(remote string, object *s3.Object, isDirectory bool)
 }
 
 // listDir lists files and directories to out
-func (f *Fs) listDir(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) listDir(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	// List the objects and directories
-	err = f.list(dir, false, func(remote string, object *s3.Object, isDirectory bool) error {
-		entry, err := f.itemToDirEntry(remote, object, isDirectory)
+	err = f.list(ctx, dir, false, func(remote string, object *s3.Object, version *objectVersion, isDirectory bool) error {
+		entry, err := f.itemToDirEntry(ctx, remote, object, version, isDirectory)
 		if err != nil {
 			return err
 		}
@@ -588,12 +662,12 @@ func (f *Fs) listDir(dir string) (entries fs.DirEntries, err error) {
 }
 
 // listBuckets lists the buckets to out
-func (f *Fs) listBuckets(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) listBuckets(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if dir != "" {
 		return nil, fs.ErrorListBucketRequired
 	}
 	req := s3.ListBucketsInput{}
-	resp, err := f.c.ListBuckets(&req)
+	resp, err := f.c.ListBucketsWithContext(ctx, &req)
 	if err != nil {
 		return nil, err
 	}
@@ -618,11 +692,11 @@ func (f *Fs) listBuckets(dir string) (entries fs.DirEntries, err error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if f.bucket == "" {
-		return f.listBuckets(dir)
+		return f.listBuckets(ctx, dir)
 	}
-	return f.listDir(dir)
+	return f.listDir(ctx, dir)
 }
 
 // ListR lists the objects and directories of the Fs starting
@@ -641,13 +715,13 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	if f.bucket == "" {
 		return fs.ErrorListBucketRequired
 	}
 	list := fs.NewListRHelper(callback)
-	err = f.list(dir, true, func(remote string, object *s3.Object, isDirectory bool) error {
-		entry, err := f.itemToDirEntry(remote, object, isDirectory)
+	err = f.list(ctx, dir, true, func(remote string, object *s3.Object, version *objectVersion, isDirectory bool) error {
+		entry, err := f.itemToDirEntry(ctx, remote, object, version, isDirectory)
 		if err != nil {
 			return err
 		}
@@ -660,21 +734,21 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 }
 
 // Put the Object into the bucket
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	fs := &Object{
 		fs:     f,
 		remote: src.Remote(),
 	}
-	return fs, fs.Update(in, src, options...)
+	return fs, fs.Update(ctx, in, src, options...)
 }
 
 // Check if the bucket exists
-func (f *Fs) dirExists() (bool, error) {
+func (f *Fs) dirExists(ctx context.Context) (bool, error) {
 	req := s3.HeadBucketInput{
 		Bucket: &f.bucket,
 	}
-	_, err := f.c.HeadBucket(&req)
+	_, err := f.c.HeadBucketWithContext(ctx, &req)
 	if err == nil {
 		return true, nil
 	}
@@ -687,13 +761,16 @@ func (f *Fs) dirExists() (bool, error) {
 }
 
 // Mkdir creates the bucket if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if !f.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.bucketOK {
 		return nil
 	}
-	exists, err := f.dirExists()
+	exists, err := f.dirExists(ctx)
 	if err == nil {
 		f.bucketOK = exists
 	}
@@ -709,7 +786,7 @@ func (f *Fs) Mkdir(dir string) error {
 			LocationConstraint: &f.locationConstraint,
 		}
 	}
-	_, err = f.c.CreateBucket(&req)
+	_, err = f.c.CreateBucketWithContext(ctx, &req)
 	if err, ok := err.(awserr.Error); ok {
 		if err.Code() == "BucketAlreadyOwnedByYou" {
 			err = nil
@@ -724,7 +801,10 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes the bucket if the fs is at the root
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	if !f.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.root != "" || dir != "" {
@@ -733,7 +813,7 @@ func (f *Fs) Rmdir(dir string) error {
 	req := s3.DeleteBucketInput{
 		Bucket: &f.bucket,
 	}
-	_, err := f.c.DeleteBucket(&req)
+	_, err := f.c.DeleteBucketWithContext(ctx, &req)
 	if err == nil {
 		f.bucketOK = false
 	}
@@ -754,26 +834,231 @@ func (f *Fs) Precision() time.Duration {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't copy - not same remote type")
 		return nil, fs.ErrorCantCopy
 	}
-	srcFs := srcObj.fs
-	key := f.root + remote
-	source := url.QueryEscape(srcFs.bucket + "/" + srcFs.root + srcObj.remote)
+	if !f.versionAt.IsZero() {
+		return nil, errNotWithVersionAt
+	}
 	req := s3.CopyObjectInput{
-		Bucket:            &f.bucket,
-		Key:               &key,
-		CopySource:        &source,
 		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
 	}
-	_, err := f.c.CopyObject(&req)
+	err := f.copy(ctx, &req, f.root+remote, srcObj)
 	if err != nil {
 		return nil, err
 	}
-	return f.NewObject(remote)
+	return f.NewObject(ctx, remote)
+}
+
+// copy does a server side copy of src to key in f filling in the
+// rest of req
+//
+// Objects of --s3-copy-cutoff or bigger are copied with a multipart
+// copy as a single CopyObject can't copy objects bigger than 5GB.
+func (f *Fs) copy(ctx context.Context, req *s3.CopyObjectInput, key string, src *Object) error {
+	req.Bucket = &f.bucket
+	req.ACL = &f.acl
+	req.Key = &key
+	req.CopySource = aws.String(url.QueryEscape(src.fs.bucket + "/" + src.key()))
+	if src.version != nil {
+		*req.CopySource += "?versionId=" + url.QueryEscape(src.version.versionID)
+	}
+	if f.sse != "" {
+		req.ServerSideEncryption = &f.sse
+	}
+	// Keep the storage class of the source unless overridden -
+	// objects in GLACIER can't be copied into GLACIER
+	if f.storageClass != "" {
+		req.StorageClass = &f.storageClass
+	} else if src.storageClass != "" && src.storageClass != "GLACIER" {
+		req.StorageClass = &src.storageClass
+	}
+	if src.bytes >= int64(copyCutoff) {
+		return f.copyMultipart(ctx, req, src)
+	}
+	_, err := f.c.CopyObjectWithContext(ctx, req)
+	return err
+}
+
+// copyPartSize returns the size of the parts and the number of parts
+// for a multipart copy of size bytes.  The parts are cutoff bytes
+// unless that would make too many parts.
+func copyPartSize(size, cutoff int64) (partSize, numParts int64) {
+	partSize = cutoff
+	// Adjust partSize until the number of parts is small enough.
+	if size/partSize >= s3manager.MaxUploadParts {
+		// Calculate partition size rounded up to the nearest MB
+		partSize = (((size / s3manager.MaxUploadParts) >> 20) + 1) << 20
+	}
+	numParts = (size + partSize - 1) / partSize
+	return partSize, numParts
+}
+
+// copyPartRange returns the first and last byte of part i (from 0)
+// of a multipart copy of size bytes
+func copyPartRange(i, partSize, size int64) (start, end int64) {
+	start = i * partSize
+	end = start + partSize - 1
+	if end >= size {
+		end = size - 1
+	}
+	return start, end
+}
+
+// copyMultipart does a server side copy of src as described by req
+// using a multipart upload with UploadPartCopy
+//
+// The parts are copied --s3-copy-concurrency at a time.
+func (f *Fs) copyMultipart(ctx context.Context, req *s3.CopyObjectInput, src *Object) (err error) {
+	if aws.StringValue(req.MetadataDirective) == s3.MetadataDirectiveCopy {
+		// Multipart uploads don't copy the metadata or the
+		// headers so read them from the source
+		key := src.key()
+		head, err := src.fs.c.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket:    &src.fs.bucket,
+			Key:       &key,
+			VersionId: src.versionID(),
+		})
+		if err != nil {
+			return errors.Wrap(err, "multipart copy failed to read source metadata")
+		}
+		req.Metadata = head.Metadata
+		req.ContentType = head.ContentType
+		req.ContentEncoding = head.ContentEncoding
+		req.ContentDisposition = head.ContentDisposition
+		req.CacheControl = head.CacheControl
+		req.ContentLanguage = head.ContentLanguage
+	}
+	resp, err := f.c.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               req.Bucket,
+		Key:                  req.Key,
+		ACL:                  req.ACL,
+		CacheControl:         req.CacheControl,
+		ContentDisposition:   req.ContentDisposition,
+		ContentEncoding:      req.ContentEncoding,
+		ContentLanguage:      req.ContentLanguage,
+		ContentType:          req.ContentType,
+		Metadata:             req.Metadata,
+		ServerSideEncryption: req.ServerSideEncryption,
+		StorageClass:         req.StorageClass,
+	})
+	if err != nil {
+		return errors.Wrap(err, "multipart copy create failed")
+	}
+	uploadID := resp.UploadId
+	defer func() {
+		if err != nil {
+			// Abort the upload so the parts don't hang around
+			_, abortErr := f.c.AbortMultipartUploadWithContext(context.Background(), &s3.AbortMultipartUploadInput{
+				Bucket:   req.Bucket,
+				Key:      req.Key,
+				UploadId: uploadID,
+			})
+			if abortErr != nil {
+				fs.Errorf(src, "Failed to abort multipart copy: %v", abortErr)
+			}
+		}
+	}()
+
+	size := src.bytes
+	partSize, numParts := copyPartSize(size, int64(copyCutoff))
+	fs.Debugf(src, "Starting multipart copy with %d parts", numParts)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg     sync.WaitGroup
+		errMu  sync.Mutex
+		parts  = make([]*s3.CompletedPart, numParts)
+		tokens = make(chan struct{}, *s3CopyConcurrency)
+	)
+	for i := int64(0); i < numParts; i++ {
+		start, end := copyPartRange(i, partSize, size)
+		partNumber := aws.Int64(i + 1)
+		tokens <- struct{}{}
+		errMu.Lock()
+		failed := err != nil
+		errMu.Unlock()
+		if failed {
+			<-tokens
+			break
+		}
+		wg.Add(1)
+		go func(i int64) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+			partResp, partErr := f.c.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+				Bucket:          req.Bucket,
+				Key:             req.Key,
+				CopySource:      req.CopySource,
+				CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+				PartNumber:      partNumber,
+				UploadId:        uploadID,
+			})
+			errMu.Lock()
+			defer errMu.Unlock()
+			if partErr != nil {
+				if err == nil {
+					err = errors.Wrapf(partErr, "multipart copy of part %d failed", *partNumber)
+					cancel()
+				}
+				return
+			}
+			parts[i] = &s3.CompletedPart{
+				ETag:       partResp.CopyPartResult.ETag,
+				PartNumber: partNumber,
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	_, err = f.c.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket: req.Bucket,
+		Key:    req.Key,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: parts,
+		},
+		UploadId: uploadID,
+	})
+	if err != nil {
+		return errors.Wrap(err, "multipart copy complete failed")
+	}
+	return nil
+}
+
+// PublicLink generates a pre-signed URL for the object at remote
+// which lasts for expire or maxLinkExpiry if expire isn't set
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire time.Duration) (string, error) {
+	if expire == 0 {
+		expire = maxLinkExpiry
+	}
+	if expire < 0 || expire > maxLinkExpiry {
+		return "", errors.Errorf("s3: link expiry must be between 0 and %v", maxLinkExpiry)
+	}
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	obj := o.(*Object)
+	key := obj.key()
+	req, _ := f.c.GetObjectRequest(&s3.GetObjectInput{
+		Bucket:    &f.bucket,
+		Key:       &key,
+		VersionId: obj.versionID(),
+	})
+	link, err := req.Presign(expire)
+	if err != nil {
+		return "", errors.Wrap(err, "s3: failed to make pre-signed URL")
+	}
+	return link, nil
 }
 
 // Hashes returns the supported hash sets.
@@ -801,6 +1086,28 @@ func (o *Object) Remote() string {
 	return o.remote
 }
 
+// key returns the key of the object in the bucket
+func (o *Object) key() string {
+	if o.version != nil {
+		return o.version.key
+	}
+	return o.fs.root + o.remote
+}
+
+// isOldVersion returns true if this is an old version of the object
+func (o *Object) isOldVersion() bool {
+	return o.version != nil && !o.version.isLatest
+}
+
+// versionID returns the version ID to read or nil for the current
+// version
+func (o *Object) versionID() *string {
+	if o.version == nil {
+		return nil
+	}
+	return &o.version.versionID
+}
+
 var matchMd5 = regexp.MustCompile(`^[0-9a-f]{32}$`)
 
 // Hash returns the Md5sum of an object returning a lowercase hex string
@@ -825,16 +1132,17 @@ func (o *Object) Size() int64 {
 // readMetaData gets the metadata if it hasn't already been fetched
 //
 // it also sets the info
-func (o *Object) readMetaData() (err error) {
+func (o *Object) readMetaData(ctx context.Context) (err error) {
 	if o.meta != nil {
 		return nil
 	}
-	key := o.fs.root + o.remote
+	key := o.key()
 	req := s3.HeadObjectInput{
-		Bucket: &o.fs.bucket,
-		Key:    &key,
+		Bucket:    &o.fs.bucket,
+		Key:       &key,
+		VersionId: o.versionID(),
 	}
-	resp, err := o.fs.c.HeadObject(&req)
+	resp, err := o.fs.c.HeadObjectWithContext(ctx, &req)
 	if err != nil {
 		if awsErr, ok := err.(awserr.RequestFailure); ok {
 			if awsErr.StatusCode() == http.StatusNotFound {
@@ -859,6 +1167,7 @@ func (o *Object) readMetaData() (err error) {
 		o.lastModified = *resp.LastModified
 	}
 	o.mimeType = aws.StringValue(resp.ContentType)
+	o.storageClass = aws.StringValue(resp.StorageClass)
 	return nil
 }
 
@@ -867,7 +1176,7 @@ func (o *Object) readMetaData() (err error) {
 // It attempts to read the objects mtime and if that isn't present the
 // LastModified returned in the http headers
 func (o *Object) ModTime() time.Time {
-	err := o.readMetaData()
+	err := o.readMetaData(context.TODO())
 	if err != nil {
 		fs.Logf(o, "Failed to read metadata: %v", err)
 		return time.Now()
@@ -887,36 +1196,36 @@ func (o *Object) ModTime() time.Time {
 }
 
 // SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(modTime time.Time) error {
-	err := o.readMetaData()
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if !o.fs.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
+	if o.isOldVersion() {
+		return errNotWithVersions
+	}
+	err := o.readMetaData(ctx)
 	if err != nil {
 		return err
 	}
 	o.meta[metaMtime] = aws.String(swift.TimeToFloatString(modTime))
 
-	if o.bytes >= maxSizeForCopy {
-		fs.Debugf(o, "SetModTime is unsupported for objects bigger than %v bytes", fs.SizeSuffix(maxSizeForCopy))
-		return nil
-	}
-
 	// Guess the content type
 	mimeType := fs.MimeType(o)
 
 	// Copy the object to itself to update the metadata
-	key := o.fs.root + o.remote
-	sourceKey := o.fs.bucket + "/" + key
 	directive := s3.MetadataDirectiveReplace // replace metadata with that passed in
 	req := s3.CopyObjectInput{
-		Bucket:            &o.fs.bucket,
-		ACL:               &o.fs.acl,
-		Key:               &key,
 		ContentType:       &mimeType,
-		CopySource:        aws.String(url.QueryEscape(sourceKey)),
 		Metadata:          o.meta,
 		MetadataDirective: &directive,
 	}
-	_, err = o.fs.c.CopyObject(&req)
-	return err
+	err = o.fs.copy(ctx, &req, o.key(), o)
+	if err != nil {
+		return err
+	}
+	// The copy is now the current version
+	o.version = nil
+	return nil
 }
 
 // Storable raturns a boolean indicating if this object is storable
@@ -925,11 +1234,12 @@ func (o *Object) Storable() bool {
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
-	key := o.fs.root + o.remote
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	key := o.key()
 	req := s3.GetObjectInput{
-		Bucket: &o.fs.bucket,
-		Key:    &key,
+		Bucket:    &o.fs.bucket,
+		Key:       &key,
+		VersionId: o.versionID(),
 	}
 	for _, option := range options {
 		switch option.(type) {
@@ -942,7 +1252,7 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 			}
 		}
 	}
-	resp, err := o.fs.c.GetObject(&req)
+	resp, err := o.fs.c.GetObjectWithContext(ctx, &req)
 	if err != nil {
 		return nil, err
 	}
@@ -950,8 +1260,14 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 }
 
 // Update the Object from in with modTime and size
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
-	err := o.fs.Mkdir("")
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if !o.fs.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
+	if o.isOldVersion() {
+		return errNotWithVersions
+	}
+	err := o.fs.Mkdir(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -979,7 +1295,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	// Guess the content type
 	mimeType := fs.MimeType(src)
 
-	key := o.fs.root + o.remote
+	key := o.key()
 	req := s3manager.UploadInput{
 		Bucket:      &o.fs.bucket,
 		ACL:         &o.fs.acl,
@@ -995,31 +1311,40 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	if o.fs.storageClass != "" {
 		req.StorageClass = &o.fs.storageClass
 	}
-	_, err = uploader.Upload(&req)
+	_, err = uploader.UploadWithContext(ctx, &req)
 	if err != nil {
 		return err
 	}
 
 	// Read the metadata from the newly created object
 	o.meta = nil // wipe old metadata
-	err = o.readMetaData()
+	o.version = nil
+	err = o.readMetaData(ctx)
 	return err
 }
 
 // Remove an object
-func (o *Object) Remove() error {
-	key := o.fs.root + o.remote
+//
+// Old versions are deleted permanently.
+func (o *Object) Remove(ctx context.Context) error {
+	if !o.fs.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
+	key := o.key()
 	req := s3.DeleteObjectInput{
 		Bucket: &o.fs.bucket,
 		Key:    &key,
 	}
-	_, err := o.fs.c.DeleteObject(&req)
+	if o.isOldVersion() {
+		req.VersionId = o.versionID()
+	}
+	_, err := o.fs.c.DeleteObjectWithContext(ctx, &req)
 	return err
 }
 
 // MimeType of an Object if known, "" otherwise
 func (o *Object) MimeType() string {
-	err := o.readMetaData()
+	err := o.readMetaData(context.TODO())
 	if err != nil {
 		fs.Logf(o, "Failed to read metadata: %v", err)
 		return ""
@@ -1029,9 +1354,11 @@ func (o *Object) MimeType() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs        = &Fs{}
-	_ fs.Copier    = &Fs{}
-	_ fs.ListRer   = &Fs{}
-	_ fs.Object    = &Object{}
-	_ fs.MimeTyper = &Object{}
+	_ fs.Fs           = &Fs{}
+	_ fs.Copier       = &Fs{}
+	_ fs.CleanUpper   = &Fs{}
+	_ fs.ListRer      = &Fs{}
+	_ fs.PublicLinker = &Fs{}
+	_ fs.Object       = &Object{}
+	_ fs.MimeTyper    = &Object{}
 )
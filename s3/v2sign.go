@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -43,13 +44,46 @@ func sign(AccessKey, SecretKey string, req *http.Request) {
 	date := time.Now().UTC().Format(time.RFC1123)
 	req.Header.Set("Date", date)
 
+	// Make signature
+	payload := stringToSign(req, date, true)
+	signature := signPayload(SecretKey, payload)
+
+	// Set signature in request
+	req.Header.Set("Authorization", "AWS "+AccessKey+":"+signature)
+}
+
+// presign makes req into a v2 pre-signed URL which expires at
+// expires
+func presign(AccessKey, SecretKey string, req *http.Request, expires time.Time) {
+	expiresString := strconv.FormatInt(expires.Unix(), 10)
+
+	// Make signature
+	payload := stringToSign(req, expiresString, false)
+	signature := signPayload(SecretKey, payload)
+
+	// Put the signature in the query
+	params := req.URL.Query()
+	params.Set("AWSAccessKeyId", AccessKey)
+	params.Set("Expires", expiresString)
+	params.Set("Signature", signature)
+	req.URL.RawQuery = params.Encode()
+}
+
+// stringToSign makes the string to sign for req
+//
+// date should be the Date header, or the expiry time for pre-signed
+// URLs.  If resetOpaque is set then an absolute URI in req.URL.Opaque
+// is replaced by the plain URI.
+func stringToSign(req *http.Request, date string, resetOpaque bool) string {
 	// Sort out URI
 	uri := req.URL.Opaque
 	if uri != "" {
 		if strings.HasPrefix(uri, "//") {
 			// Strip off //host/uri
 			uri = "/" + strings.Join(strings.Split(uri, "/")[3:], "/")
-			req.URL.Opaque = uri // reset to plain URI otherwise Ceph gets confused
+			if resetOpaque {
+				req.URL.Opaque = uri // reset to plain URI otherwise Ceph gets confused
+			}
 		}
 	} else {
 		uri = req.URL.Path
@@ -103,13 +137,13 @@ func sign(AccessKey, SecretKey string, req *http.Request) {
 		uri += "?" + strings.Join(queriesToSign, "&")
 	}
 
-	// Make signature
-	payload := req.Method + "\n" + md5 + "\n" + contentType + "\n" + date + "\n" + joinedHeadersToSign + uri
+	return req.Method + "\n" + md5 + "\n" + contentType + "\n" + date + "\n" + joinedHeadersToSign + uri
+}
+
+// signPayload signs payload with SecretKey returning the signature
+// base64 encoded
+func signPayload(SecretKey, payload string) string {
 	hash := hmac.New(sha1.New, []byte(SecretKey))
 	_, _ = hash.Write([]byte(payload))
-	signature := make([]byte, base64.StdEncoding.EncodedLen(hash.Size()))
-	base64.StdEncoding.Encode(signature, hash.Sum(nil))
-
-	// Set signature in request
-	req.Header.Set("Authorization", "AWS "+AccessKey+":"+string(signature))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
 }
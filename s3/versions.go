@@ -0,0 +1,402 @@
+// Listing, reading and cleaning up old versions of objects in
+// versioned buckets
+
+package s3
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Constants
+const (
+	versionFormat = "-v2006-01-02-150405.000" // the same as b2 uses
+	maxUploadAge  = 24 * time.Hour            // multipart uploads older than this are removed by cleanup
+	maxDeleteKeys = 1000                      // maximum number of keys in a DeleteObjects call
+)
+
+// Errors
+var (
+	errEndList          = errors.New("end list")
+	errNotWithVersions  = errors.New("can't modify old versions of files in --s3-versions mode")
+	errNotWithVersionAt = errors.New("can't modify or delete files in --s3-version-at mode")
+)
+
+// addVersion adds the time t as a version string into remote
+// before the extension.
+func addVersion(remote string, t time.Time) string {
+	ext := path.Ext(remote)
+	base := remote[:len(remote)-len(ext)]
+	s := t.UTC().Format(versionFormat)
+	// Replace the '.' with a '-'
+	s = strings.Replace(s, ".", "-", -1)
+	return base + s + ext
+}
+
+// removeVersion removes the version string from remote.
+//
+// It returns the time of the version and the new remote, or a zero
+// time and the old remote if there wasn't a version string.
+func removeVersion(remote string) (t time.Time, newRemote string) {
+	newRemote = remote
+	ext := path.Ext(remote)
+	base := remote[:len(remote)-len(ext)]
+	if len(base) < len(versionFormat) {
+		return
+	}
+	versionStart := len(base) - len(versionFormat)
+	// Check it ends in -xxx
+	if base[len(base)-4] != '-' {
+		return
+	}
+	// Replace with .xxx for parsing
+	base = base[:len(base)-4] + "." + base[len(base)-3:]
+	newT, err := time.Parse(versionFormat, base[versionStart:])
+	if err != nil {
+		return
+	}
+	return newT, base[:versionStart] + ext
+}
+
+// parseVersionAt parses the --s3-version-at flag which is either an
+// age, eg "1d", or a time, eg "2017-09-01 12:00:00", in UTC
+func parseVersionAt(s string, now time.Time) (time.Time, error) {
+	if age, err := fs.ParseDuration(s); err == nil {
+		return now.Add(-age), nil
+	}
+	for _, layout := range []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("couldn't parse %q as an age or a time", s)
+}
+
+// objectVersion is a version of an object or a delete marker from a
+// version listing
+type objectVersion struct {
+	key          string     // the key of the object
+	versionID    string     // the version ID
+	isLatest     bool       // set if this is the current version
+	deleteMarker bool       // set if this is a delete marker
+	lastModified time.Time  // when this version was made
+	object       *s3.Object // info about the object - nil for delete markers
+}
+
+// byKeyNewestFirst sorts object versions by key then newest first
+// which is the order S3 lists them in
+type byKeyNewestFirst []*objectVersion
+
+func (vs byKeyNewestFirst) Len() int      { return len(vs) }
+func (vs byKeyNewestFirst) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs byKeyNewestFirst) Less(i, j int) bool {
+	if vs[i].key != vs[j].key {
+		return vs[i].key < vs[j].key
+	}
+	if !vs[i].lastModified.Equal(vs[j].lastModified) {
+		return vs[i].lastModified.After(vs[j].lastModified)
+	}
+	return vs[i].isLatest && !vs[j].isLatest
+}
+
+// mergeVersions merges the versions and the delete markers in a page
+// of a version listing which are returned separately
+func mergeVersions(resp *s3.ListObjectVersionsOutput) []*objectVersion {
+	vs := make([]*objectVersion, 0, len(resp.Versions)+len(resp.DeleteMarkers))
+	for _, v := range resp.Versions {
+		vs = append(vs, &objectVersion{
+			key:          aws.StringValue(v.Key),
+			versionID:    aws.StringValue(v.VersionId),
+			isLatest:     aws.BoolValue(v.IsLatest),
+			lastModified: aws.TimeValue(v.LastModified),
+			object: &s3.Object{
+				Key:          v.Key,
+				ETag:         v.ETag,
+				LastModified: v.LastModified,
+				Size:         v.Size,
+				StorageClass: v.StorageClass,
+			},
+		})
+	}
+	for _, m := range resp.DeleteMarkers {
+		vs = append(vs, &objectVersion{
+			key:          aws.StringValue(m.Key),
+			versionID:    aws.StringValue(m.VersionId),
+			isLatest:     aws.BoolValue(m.IsLatest),
+			deleteMarker: true,
+			lastModified: aws.TimeValue(m.LastModified),
+		})
+	}
+	sort.Stable(byKeyNewestFirst(vs))
+	return vs
+}
+
+// versionPicker chooses which object versions are shown and what
+// they are called.  It must be shown all the versions in the order
+// S3 lists them.
+type versionPicker struct {
+	at      time.Time // if set show the objects as they were at this time
+	lastKey string    // the key of the last version seen
+	found   bool      // set if the version of lastKey at "at" has been found
+}
+
+// pick returns the key that v should be shown as or "" if it
+// shouldn't be shown
+//
+// With --s3-versions the current versions are shown as they are and
+// old versions have the time they were made added to the key.
+//
+// With --s3-version-at only the newest version of each object made
+// at or before that time is shown.
+func (p *versionPicker) pick(v *objectVersion) string {
+	if p.at.IsZero() {
+		if v.deleteMarker {
+			return ""
+		}
+		if v.isLatest {
+			return v.key
+		}
+		return addVersion(v.key, v.lastModified)
+	}
+	if v.key != p.lastKey {
+		p.lastKey = v.key
+		p.found = false
+	}
+	if p.found || v.lastModified.After(p.at) {
+		return ""
+	}
+	p.found = true
+	if v.deleteMarker {
+		return ""
+	}
+	return v.key
+}
+
+// versionFn is called from listObjectVersions to handle a version
+type versionFn func(v *objectVersion) error
+
+// listObjectVersions lists all the versions and delete markers of
+// the objects with keys starting with prefix in the order S3 lists
+// them, passing them to fn.
+//
+// If recurse isn't set then the directories are passed to dirFn.
+func (f *Fs) listObjectVersions(ctx context.Context, prefix string, recurse bool, dirFn listFn, fn versionFn) error {
+	maxKeys := int64(listChunkSize)
+	delimiter := ""
+	if !recurse {
+		delimiter = "/"
+	}
+	var keyMarker, versionIDMarker *string
+	for {
+		req := s3.ListObjectVersionsInput{
+			Bucket:          &f.bucket,
+			Delimiter:       &delimiter,
+			Prefix:          &prefix,
+			MaxKeys:         &maxKeys,
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+		}
+		resp, err := f.c.ListObjectVersionsWithContext(ctx, &req)
+		if err != nil {
+			return translateListError(err)
+		}
+		if !recurse {
+			err = f.sendCommonPrefixes(resp.CommonPrefixes, dirFn)
+			if err != nil {
+				return err
+			}
+		}
+		for _, v := range mergeVersions(resp) {
+			err = fn(v)
+			if err != nil {
+				return err
+			}
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		keyMarker, versionIDMarker = resp.NextKeyMarker, resp.NextVersionIdMarker
+	}
+	return nil
+}
+
+// listVersions lists the objects under prefix into the function
+// supplied choosing the versions with --s3-versions or
+// --s3-version-at.
+func (f *Fs) listVersions(ctx context.Context, prefix string, recurse bool, fn listFn) error {
+	picker := versionPicker{at: f.versionAt}
+	err := f.listObjectVersions(ctx, prefix, recurse, fn, func(v *objectVersion) error {
+		key := picker.pick(v)
+		if key == "" {
+			return nil
+		}
+		if !strings.HasPrefix(key, f.root) {
+			fs.Logf(f, "Odd name received %q", key)
+			return nil
+		}
+		return fn(key[len(f.root):], v.object, v, false)
+	})
+	if err == errEndList {
+		err = nil
+	}
+	return err
+}
+
+// newObjectVersion finds the version of the object shown as remote
+// with --s3-versions or --s3-version-at
+func (f *Fs) newObjectVersion(ctx context.Context, remote string) (o fs.Object, err error) {
+	key := remote
+	if f.versions {
+		_, key = removeVersion(remote)
+	}
+	key = f.root + key
+	err = f.listVersions(ctx, key, true, func(name string, object *s3.Object, version *objectVersion, isDirectory bool) error {
+		// The listing is sorted by key so stop once past key
+		if version.key != key {
+			if version.key > key {
+				return errEndList
+			}
+			return nil
+		}
+		if name != remote {
+			return nil
+		}
+		o, err = f.newObjectWithInfo(ctx, remote, object, version)
+		if err != nil {
+			return err
+		}
+		return errEndList
+	})
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return o, nil
+}
+
+// deleteVersions permanently deletes the versions passed in
+func (f *Fs) deleteVersions(ctx context.Context, vs []*objectVersion) error {
+	if len(vs) == 0 {
+		return nil
+	}
+	objects := make([]*s3.ObjectIdentifier, len(vs))
+	for i, v := range vs {
+		objects[i] = &s3.ObjectIdentifier{
+			Key:       aws.String(v.key),
+			VersionId: aws.String(v.versionID),
+		}
+	}
+	resp, err := f.c.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+		Bucket: &f.bucket,
+		Delete: &s3.Delete{
+			Objects: objects,
+			Quiet:   aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range resp.Errors {
+		fs.Stats.Error()
+		fs.Errorf(f, "Failed to delete version %q of %q: %s", aws.StringValue(e.VersionId), aws.StringValue(e.Key), aws.StringValue(e.Message))
+	}
+	if len(resp.Errors) > 0 {
+		return errors.Errorf("failed to delete %d versions", len(resp.Errors))
+	}
+	return nil
+}
+
+// cleanUpVersions permanently deletes all the old versions and
+// delete markers of the objects in the root leaving the current
+// versions
+func (f *Fs) cleanUpVersions(ctx context.Context) error {
+	var toDelete []*objectVersion
+	err := f.listObjectVersions(ctx, f.root, true, nil, func(v *objectVersion) error {
+		if v.isLatest && !v.deleteMarker {
+			return nil
+		}
+		fs.Debugf(f, "Deleting version %q of %q", v.versionID, v.key)
+		toDelete = append(toDelete, v)
+		if len(toDelete) >= maxDeleteKeys {
+			err := f.deleteVersions(ctx, toDelete)
+			toDelete = toDelete[:0]
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.deleteVersions(ctx, toDelete)
+}
+
+// cleanUpUploads aborts the multipart uploads in the root which were
+// started more than maxUploadAge ago
+func (f *Fs) cleanUpUploads(ctx context.Context) error {
+	oldest := time.Now().Add(-maxUploadAge)
+	var keyMarker, uploadIDMarker *string
+	for {
+		resp, err := f.c.ListMultipartUploadsWithContext(ctx, &s3.ListMultipartUploadsInput{
+			Bucket:         &f.bucket,
+			Prefix:         &f.root,
+			KeyMarker:      keyMarker,
+			UploadIdMarker: uploadIDMarker,
+		})
+		if err != nil {
+			return err
+		}
+		for _, upload := range resp.Uploads {
+			if upload.Initiated != nil && upload.Initiated.After(oldest) {
+				continue
+			}
+			fs.Debugf(f, "Aborting multipart upload %q of %q started at %v", aws.StringValue(upload.UploadId), aws.StringValue(upload.Key), aws.TimeValue(upload.Initiated))
+			_, err = f.c.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   &f.bucket,
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to abort multipart upload of %q", aws.StringValue(upload.Key))
+			}
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		keyMarker, uploadIDMarker = resp.NextKeyMarker, resp.NextUploadIdMarker
+	}
+	return nil
+}
+
+// CleanUp permanently deletes the old versions of the objects in the
+// root and aborts any multipart uploads in the root which were
+// started more than a day ago.
+func (f *Fs) CleanUp(ctx context.Context) error {
+	if !f.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
+	err := f.cleanUpVersions(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to clean up old versions")
+	}
+	err = f.cleanUpUploads(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to clean up multipart uploads")
+	}
+	return nil
+}
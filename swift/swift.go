@@ -3,8 +3,12 @@ package swift
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net/url"
 	"path"
 	"regexp"
 	"strconv"
@@ -15,12 +19,14 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/swift"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Constants
 const (
 	directoryMarkerContentType = "application/directory" // content type of directory marker objects
 	listChunks                 = 1000                    // chunk size to read directory listings
+	defaultLinkExpiry          = 7 * 24 * time.Hour      // how long temporary URLs last if not set
 )
 
 // Globals
@@ -263,7 +269,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *swift.Object) (fs.Object, er
 
 // NewObject finds the Object at remote.  If it can't be found it
 // returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -389,7 +395,7 @@ func (f *Fs) listContainers(dir string) (entries fs.DirEntries, err error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if f.container == "" {
 		return f.listContainers(dir)
 	}
@@ -412,7 +418,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	if f.container == "" {
 		return errors.New("container needed for recursive list")
 	}
@@ -431,17 +437,17 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	fs := &Object{
 		fs:     f,
 		remote: src.Remote(),
 	}
-	return fs, fs.Update(in, src, options...)
+	return fs, fs.Update(ctx, in, src, options...)
 }
 
 // Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	f.containerOKMu.Lock()
 	defer f.containerOKMu.Unlock()
 	if f.containerOK {
@@ -465,7 +471,7 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes the container if the fs is at the root
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	f.containerOKMu.Lock()
 	defer f.containerOKMu.Unlock()
 	if f.root != "" || dir != "" {
@@ -486,12 +492,12 @@ func (f *Fs) Precision() time.Duration {
 // Purge deletes all the files and directories
 //
 // Implemented here so we can make sure we delete directory markers
-func (f *Fs) Purge() error {
+func (f *Fs) Purge(ctx context.Context) error {
 	// Delete all the files including the directory markers
 	toBeDeleted := make(chan fs.Object, fs.Config.Transfers)
 	delErr := make(chan error, 1)
 	go func() {
-		delErr <- fs.DeleteFiles(toBeDeleted)
+		delErr <- fs.DeleteFiles(ctx, toBeDeleted)
 	}()
 	err := f.list("", true, func(entry fs.BasicInfo) error {
 		if o, ok := entry.(*Object); ok {
@@ -507,7 +513,66 @@ func (f *Fs) Purge() error {
 	if err != nil {
 		return err
 	}
-	return f.Rmdir("")
+	return f.Rmdir(ctx, "")
+}
+
+// tempURLKey reads the key for signing temporary URLs from the
+// container metadata or if not set there the account metadata
+func (f *Fs) tempURLKey() (string, error) {
+	_, headers, err := f.c.Container(f.container)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read container metadata")
+	}
+	if key := headers.ContainerMetadata()["temp-url-key"]; key != "" {
+		return key, nil
+	}
+	_, headers, err = f.c.Account()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read account metadata")
+	}
+	if key := headers.AccountMetadata()["temp-url-key"]; key != "" {
+		return key, nil
+	}
+	return "", errors.New("no Temp-URL-Key set on the container or the account")
+}
+
+// tempURL makes a temporary URL to GET objectName in container which
+// expires at expires
+//
+// The signature is made from the path before it is URL encoded as
+// that is what the tempurl middleware checks.
+func tempURL(storageURL, container, objectName, key string, expires time.Time) (string, error) {
+	u, err := url.Parse(storageURL)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse storage URL")
+	}
+	mac := hmac.New(sha1.New, []byte(key))
+	_, _ = fmt.Fprintf(mac, "GET\n%d\n%s/%s/%s", expires.Unix(), u.Path, container, objectName)
+	sig := hex.EncodeToString(mac.Sum(nil))
+	return fmt.Sprintf("%s/%s/%s?temp_url_sig=%s&temp_url_expires=%d", storageURL, urlEncode(container), urlEncode(objectName), sig, expires.Unix()), nil
+}
+
+// PublicLink makes a temporary URL for the object at remote which
+// lasts for expire or defaultLinkExpiry if expire isn't set
+//
+// This needs a Temp-URL-Key to be set on the container or the
+// account.
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire time.Duration) (string, error) {
+	if expire == 0 {
+		expire = defaultLinkExpiry
+	}
+	if expire < 0 {
+		return "", errors.New("link expiry can't be negative")
+	}
+	_, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	key, err := f.tempURLKey()
+	if err != nil {
+		return "", err
+	}
+	return tempURL(f.c.StorageUrl, f.container, f.root+remote, key, time.Now().Add(expire))
 }
 
 // Copy src to this remote using server side copy operations.
@@ -519,7 +584,7 @@ func (f *Fs) Purge() error {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't copy - not same remote type")
@@ -530,7 +595,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	if err != nil {
 		return nil, err
 	}
-	return f.NewObject(remote)
+	return f.NewObject(ctx, remote)
 }
 
 // Hashes returns the supported hash sets.
@@ -648,7 +713,7 @@ func (o *Object) ModTime() time.Time {
 }
 
 // SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	err := o.readMetaData()
 	if err != nil {
 		return err
@@ -677,7 +742,7 @@ func (o *Object) Storable() bool {
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	headers := fs.OpenOptionHeaders(options)
 	_, isRanging := headers["Range"]
 	in, _, err = o.fs.c.ObjectOpen(o.fs.container, o.fs.root+o.remote, !isRanging, headers)
@@ -775,11 +840,11 @@ func (o *Object) updateChunks(in io.Reader, headers swift.Headers, size int64, c
 // Update the object with the contents of the io.Reader, modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
 	if o.fs.container == "" {
 		return fs.FatalError(errors.New("container name needed in remote"))
 	}
-	err := o.fs.Mkdir("")
+	err := o.fs.Mkdir(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -825,7 +890,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() error {
+func (o *Object) Remove(ctx context.Context) error {
 	isDynamicLargeObject, err := o.isDynamicLargeObject()
 	if err != nil {
 		return err
@@ -852,10 +917,11 @@ func (o *Object) MimeType() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs        = &Fs{}
-	_ fs.Purger    = &Fs{}
-	_ fs.Copier    = &Fs{}
-	_ fs.ListRer   = &Fs{}
-	_ fs.Object    = &Object{}
-	_ fs.MimeTyper = &Object{}
+	_ fs.Fs           = &Fs{}
+	_ fs.Purger       = &Fs{}
+	_ fs.Copier       = &Fs{}
+	_ fs.ListRer      = &Fs{}
+	_ fs.PublicLinker = &Fs{}
+	_ fs.Object       = &Object{}
+	_ fs.MimeTyper    = &Object{}
 )
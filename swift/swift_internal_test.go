@@ -1,6 +1,9 @@
 package swift
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestInternalUrlEncode(t *testing.T) {
 	for _, test := range []struct {
@@ -23,3 +26,14 @@ func TestInternalUrlEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestInternalTempURL(t *testing.T) {
+	got, err := tempURL("https://swift.example.com/v1/AUTH_test", "container", "dir/Vidéo 1.txt", "secret", time.Unix(1500000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://swift.example.com/v1/AUTH_test/container/dir/Vid%C3%A9o%201.txt?temp_url_sig=9a119fee29adf8bfed358facdc48f074904e6385&temp_url_expires=1500000000"
+	if got != want {
+		t.Errorf("want %q got %q", want, got)
+	}
+}
@@ -0,0 +1,569 @@
+// Package union implements a file system which merges several
+// upstream remotes into one
+package union
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "union",
+		Description: "Merge several remotes into one",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "remotes",
+			Help: "List of space separated remotes.\nCan be 'remotea:test/dir remoteb:', '\"remotea:test/space dir\" remoteb:', etc.\nFiles are read from the first remote they are found in.",
+		}, {
+			Name: "create_policy",
+			Help: "Policy to choose which remote new files and directories are created in.",
+			Examples: []fs.OptionExample{
+				{
+					Value: policyExistingPath,
+					Help:  "Existing path, first found. Create in the first remote the parent directory exists in (default).",
+				}, {
+					Value: policyFirstFound,
+					Help:  "First found. Create in the first remote.",
+				}, {
+					Value: policyMostFreeSpace,
+					Help:  "Most free space. Create in the remote with the most free space.",
+				},
+			},
+			Optional: true,
+		}},
+	})
+}
+
+// Create policies
+const (
+	policyExistingPath  = "epff"
+	policyFirstFound    = "ff"
+	policyMostFreeSpace = "mfs"
+)
+
+// Fs represents a union of remotes
+type Fs struct {
+	name      string
+	root      string
+	features  *fs.Features // optional features
+	upstreams []fs.Fs      // the remotes, in priority order
+	policy    string       // create policy
+}
+
+// splitRemotes splits the remotes config value on spaces, allowing
+// remotes with spaces in to be quoted
+func splitRemotes(value string) (remotes []string, err error) {
+	var current []rune
+	inQuote, haveRemote := false, false
+	for _, c := range value {
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+			haveRemote = true
+		case c == ' ' && !inQuote:
+			if haveRemote {
+				remotes = append(remotes, string(current))
+			}
+			current, haveRemote = nil, false
+		default:
+			current = append(current, c)
+			haveRemote = true
+		}
+	}
+	if inQuote {
+		return nil, errors.Errorf("unterminated quote in %q", value)
+	}
+	if haveRemote {
+		remotes = append(remotes, string(current))
+	}
+	return remotes, nil
+}
+
+// joinRemote joins rpath onto the remote
+func joinRemote(remote, rpath string) string {
+	if strings.HasSuffix(remote, ":") {
+		return remote + rpath
+	}
+	return path.Join(remote, rpath)
+}
+
+// newUpstreams makes an Fs for each remote at rpath, returning
+// fs.ErrorIsFile if rpath is a file in any of them
+func newUpstreams(remotes []string, rpath string) (upstreams []fs.Fs, err error) {
+	isFile := false
+	for _, remote := range remotes {
+		remotePath := joinRemote(remote, rpath)
+		upstream, err := fs.NewFs(remotePath)
+		if err == fs.ErrorIsFile {
+			isFile = true
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to make remote %q to union", remotePath)
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	if isFile {
+		return upstreams, fs.ErrorIsFile
+	}
+	return upstreams, nil
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(name, rpath string) (fs.Fs, error) {
+	remotes, err := splitRemotes(fs.ConfigFileGet(name, "remotes"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse remotes")
+	}
+	if len(remotes) == 0 {
+		return nil, errors.New("union can't be empty - check the value of the remotes setting")
+	}
+	for _, remote := range remotes {
+		if strings.HasPrefix(remote, name+":") {
+			return nil, errors.New("can't point union remote at itself - check the value of the remotes setting")
+		}
+	}
+	policy := fs.ConfigFileGet(name, "create_policy", policyExistingPath)
+	switch policy {
+	case policyExistingPath, policyFirstFound, policyMostFreeSpace:
+	default:
+		return nil, errors.Errorf("unknown create_policy %q", policy)
+	}
+
+	rpath = strings.Trim(rpath, "/")
+	root := rpath
+	upstreams, err := newUpstreams(remotes, rpath)
+	if err == fs.ErrorIsFile {
+		// The file may only be in some of the upstreams so
+		// point them all at the parent directory
+		root = path.Dir(rpath)
+		if root == "." {
+			root = ""
+		}
+		upstreams, err = newUpstreams(remotes, root)
+		if err == nil {
+			err = fs.ErrorIsFile
+		}
+	}
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, err
+	}
+	f := &Fs{
+		name:      name,
+		root:      root,
+		upstreams: upstreams,
+		policy:    policy,
+	}
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from all the upstreams
+	features := (&fs.Features{
+		CaseInsensitive: true,
+		DuplicateFiles:  false,
+		ReadMimeType:    true,
+		WriteMimeType:   true,
+	}).Fill(f)
+	for _, upstream := range upstreams {
+		features = features.Mask(upstream)
+	}
+	f.features = features
+	return f, err
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("union root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision is the greatest precision of all the upstreams
+func (f *Fs) Precision() time.Duration {
+	var greatestPrecision time.Duration
+	for _, upstream := range f.upstreams {
+		if upstream.Precision() > greatestPrecision {
+			greatestPrecision = upstream.Precision()
+		}
+	}
+	return greatestPrecision
+}
+
+// Hashes returns the hashes supported by all the upstreams
+func (f *Fs) Hashes() fs.HashSet {
+	hashes := fs.SupportedHashes
+	for _, upstream := range f.upstreams {
+		hashes = hashes.Overlap(upstream.Hashes())
+	}
+	return hashes
+}
+
+// dirExists returns whether dir exists in upstream
+func dirExists(ctx context.Context, upstream fs.Fs, dir string) bool {
+	_, err := upstream.List(ctx, dir)
+	return err == nil
+}
+
+// create returns the index of the upstream to create remote in
+// according to the create policy
+func (f *Fs) create(ctx context.Context, remote string) int {
+	switch f.policy {
+	case policyExistingPath:
+		parent := path.Dir(remote)
+		if parent == "." {
+			parent = ""
+		}
+		for i, upstream := range f.upstreams {
+			if dirExists(ctx, upstream, parent) {
+				return i
+			}
+		}
+	case policyMostFreeSpace:
+		best := -1
+		var bestFree int64 = -1
+		for i, upstream := range f.upstreams {
+			do := upstream.Features().About
+			if do == nil {
+				continue
+			}
+			usage, err := do(ctx)
+			if err != nil {
+				fs.Debugf(upstream, "union: failed to read free space: %v", err)
+				continue
+			}
+			if usage.Free > bestFree {
+				best, bestFree = i, usage.Free
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	// Otherwise use the first upstream
+	return 0
+}
+
+// Mkdir makes the directory in the upstream chosen by the create
+// policy
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	for _, upstream := range f.upstreams {
+		if dirExists(ctx, upstream, dir) {
+			return nil
+		}
+	}
+	return f.upstreams[f.create(ctx, dir)].Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory from all the upstreams it is in
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	found := false
+	for _, upstream := range f.upstreams {
+		if !dirExists(ctx, upstream, dir) {
+			continue
+		}
+		found = true
+		err := upstream.Rmdir(ctx, dir)
+		if err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
+
+// Purge all files in the root and the root directory of all the
+// upstreams
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge(ctx context.Context) error {
+	found := false
+	for _, upstream := range f.upstreams {
+		if !dirExists(ctx, upstream, "") {
+			continue
+		}
+		found = true
+		err := upstream.Features().Purge(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
+
+// Copy src to this remote using server side copy operations within
+// the upstream src is in.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || len(srcObj.f.upstreams) != len(f.upstreams) {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := f.upstreams[srcObj.i].Features().Copy(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, srcObj.i), nil
+}
+
+// Move src to this remote using server side move operations within
+// the upstream src is in.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || len(srcObj.f.upstreams) != len(f.upstreams) {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	o, err := f.upstreams[srcObj.i].Features().Move(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, srcObj.i), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations in each upstream the source
+// directory is in.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || len(srcFs.upstreams) != len(f.upstreams) {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	for _, upstream := range f.upstreams {
+		if dirExists(ctx, upstream, dstRemote) {
+			return fs.ErrorDirExists
+		}
+	}
+	found := false
+	for i, upstream := range f.upstreams {
+		srcUpstream := srcFs.upstreams[i]
+		if !dirExists(ctx, srcUpstream, srcRemote) {
+			continue
+		}
+		found = true
+		err := upstream.Features().DirMove(ctx, srcUpstream, srcRemote, dstRemote)
+		if err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
+
+// CleanUp the trash in all the upstreams
+func (f *Fs) CleanUp(ctx context.Context) error {
+	for _, upstream := range f.upstreams {
+		err := upstream.Features().CleanUp(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DirCacheFlush resets the directory caches of all the upstreams
+func (f *Fs) DirCacheFlush() {
+	for _, upstream := range f.upstreams {
+		upstream.Features().DirCacheFlush()
+	}
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// The upstream to put it in is chosen by the create policy.
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	i := f.create(ctx, src.Remote())
+	o, err := f.upstreams[i].Put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, i), nil
+}
+
+// listing is the result of listing one upstream
+type listing struct {
+	entries fs.DirEntries
+	err     error
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// The listings of all the upstreams are merged.  If an object is in
+// more than one upstream the one from the first upstream is used.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	listings := make([]listing, len(f.upstreams))
+	var wg sync.WaitGroup
+	for i, upstream := range f.upstreams {
+		wg.Add(1)
+		go func(i int, upstream fs.Fs) {
+			defer wg.Done()
+			listings[i].entries, listings[i].err = upstream.List(ctx, dir)
+		}(i, upstream)
+	}
+	wg.Wait()
+
+	found := false
+	seen := make(map[string]struct{})
+	for i, l := range listings {
+		if l.err == fs.ErrorDirNotFound {
+			continue
+		}
+		if l.err != nil {
+			return nil, l.err
+		}
+		found = true
+		for _, entry := range l.entries {
+			remote := entry.Remote()
+			if _, ok := seen[remote]; ok {
+				continue
+			}
+			seen[remote] = struct{}{}
+			if o, ok := entry.(fs.Object); ok {
+				entry = f.newObject(o, i)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	if !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote in the first upstream which
+// has it.  If it can't be found it returns the error
+// fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	for i, upstream := range f.upstreams {
+		o, err := upstream.NewObject(ctx, remote)
+		if err == fs.ErrorObjectNotFound || err == fs.ErrorNotAFile {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return f.newObject(o, i), nil
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// Object describes a union Object
+//
+// This is an Object from one of the upstreams
+type Object struct {
+	fs.Object
+	f *Fs
+	i int // index of the upstream the object is in
+}
+
+// newObject wraps the object o from upstream i
+func (f *Fs) newObject(o fs.Object, i int) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+		i:      i,
+	}
+}
+
+// Fs returns the union Fs as the parent
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// UnWrap returns the upstream Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// MimeType returns the content type of the upstream Object, or one
+// guessed from its name if the upstream doesn't know it
+func (o *Object) MimeType() string {
+	return fs.MimeType(o.Object)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+)
@@ -0,0 +1,19 @@
+package union_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestUnion: remote
+func init() {
+	tempdir1 := filepath.Join(os.TempDir(), "rclone-union-test-1")
+	tempdir2 := filepath.Join(os.TempDir(), "rclone-union-test-2")
+	name := "TestUnion"
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "union"},
+		{Name: name, Key: "remotes", Value: tempdir1 + " " + tempdir2},
+	}
+}
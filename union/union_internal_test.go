@@ -0,0 +1,130 @@
+package union
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	_ "github.com/ncw/rclone/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestSplitRemotes(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{"", nil, false},
+		{"a: b:dir", []string{"a:", "b:dir"}, false},
+		{"  a:   /tmp/x  ", []string{"a:", "/tmp/x"}, false},
+		{`"a:space dir" b: ""`, []string{"a:space dir", "b:", ""}, false},
+		{`"a:unterminated`, nil, true},
+	} {
+		got, err := splitRemotes(test.in)
+		if test.err {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+// newTestFs makes a union of two local directories with the create
+// policy given
+func newTestFs(t *testing.T, name, policy string) (*Fs, []string, func()) {
+	r := fstest.NewWrapperRemote(t, "union", name, 2, map[string]string{"create_policy": policy})
+	return r.Fs.(*Fs), r.Dirs, r.Finalise
+}
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	filePath := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0700))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0600))
+}
+
+func put(t *testing.T, f *Fs, remote string) {
+	src := fs.NewStaticObjectInfo(remote, time.Now(), 5, true, nil, nil)
+	_, err := f.Put(context.Background(), strings.NewReader("hello"), src)
+	require.NoError(t, err)
+}
+
+func TestUnionList(t *testing.T) {
+	ctx := context.Background()
+	f, dirs, cleanup := newTestFs(t, "TestUnionList", policyExistingPath)
+	defer cleanup()
+	writeFile(t, dirs[0], "both", "first")
+	writeFile(t, dirs[1], "both", "second!")
+	writeFile(t, dirs[1], "second", "2")
+	writeFile(t, dirs[0], "dir/a", "a")
+	writeFile(t, dirs[1], "dir/b", "b")
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"both", "dir", "second"}, names)
+
+	// Objects are read from the first upstream they are in
+	o, err := f.NewObject(ctx, "both")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+	assert.Equal(t, 0, o.(*Object).i)
+	o, err = f.NewObject(ctx, "second")
+	require.NoError(t, err)
+	assert.Equal(t, 1, o.(*Object).i)
+	_, err = f.NewObject(ctx, "missing")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Directories are merged
+	entries, err = f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	_, err = f.List(ctx, "missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
+
+func TestUnionCreatePolicy(t *testing.T) {
+	f, dirs, cleanup := newTestFs(t, "TestUnionCreatePolicy", policyExistingPath)
+	defer cleanup()
+	writeFile(t, dirs[1], "dir/existing", "x")
+
+	// epff puts files where the parent directory exists
+	put(t, f, "dir/new")
+	_, err := os.Stat(filepath.Join(dirs[1], "dir", "new"))
+	assert.NoError(t, err)
+
+	// falling back to the first upstream
+	put(t, f, "newdir/new")
+	_, err = os.Stat(filepath.Join(dirs[0], "newdir", "new"))
+	assert.NoError(t, err)
+
+	// ff always uses the first upstream
+	f.policy = policyFirstFound
+	put(t, f, "dir/ff")
+	_, err = os.Stat(filepath.Join(dirs[0], "dir", "ff"))
+	assert.NoError(t, err)
+
+	// mfs uses an upstream with free space
+	f.policy = policyMostFreeSpace
+	put(t, f, "mfs")
+	entries, err := f.List(context.Background(), "")
+	require.NoError(t, err)
+	found := false
+	for _, entry := range entries {
+		found = found || entry.Remote() == "mfs"
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,192 @@
+// Types passed and returned to and from the API
+
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Multistatus contains responses returned from an HTTP 207 return code
+type Multistatus struct {
+	Responses []Response `xml:"response"`
+}
+
+// Response contains an Href and the properties of the resource it is about
+type Response struct {
+	Href      string     `xml:"href"`
+	Propstats []Propstat `xml:"propstat"`
+}
+
+// Propstat is a group of properties and the status of fetching them
+//
+// The server returns one propstat with a 200 status for the
+// properties it found and usually another with a 404 status for the
+// ones it didn't.
+type Propstat struct {
+	Status string `xml:"status"`
+	Prop   Prop   `xml:"prop"`
+}
+
+// Prop is the properties of a response
+type Prop struct {
+	Name        string    `xml:"displayname,omitempty"`
+	Type        *xml.Name `xml:"resourcetype>collection,omitempty"`
+	Size        int64     `xml:"getcontentlength,omitempty"`
+	Modified    Time      `xml:"getlastmodified,omitempty"`
+	ContentType string    `xml:"getcontenttype,omitempty"`
+	Checksums   []string  `xml:"checksums>checksum,omitempty"`
+}
+
+// Parse a status of the form "HTTP/1.1 200 OK"
+var parseStatus = regexp.MustCompile(`^HTTP/[0-9.]+\s+(\d+)`)
+
+// StatusOK examines the Status and returns an OK flag
+func (p *Propstat) StatusOK() bool {
+	match := parseStatus.FindStringSubmatch(p.Status)
+	if len(match) < 2 {
+		return false
+	}
+	code, err := strconv.Atoi(match[1])
+	if err != nil {
+		return false
+	}
+	return code >= 200 && code < 300
+}
+
+// Prop returns the properties which were found OK for the response
+// and whether there were any
+func (r *Response) Prop() (prop *Prop, ok bool) {
+	for i := range r.Propstats {
+		propstat := &r.Propstats[i]
+		if propstat.StatusOK() {
+			return &propstat.Prop, true
+		}
+	}
+	return nil, false
+}
+
+// IsDir returns whether the properties are for a collection
+func (p *Prop) IsDir() bool {
+	return p.Type != nil
+}
+
+// Hashes returns the checksums of the object keyed by their upper
+// case names, eg "SHA1", "MD5"
+//
+// The checksums are returned by ownCloud and Nextcloud in the form
+// "SHA1:abc MD5:def ADLER32:123"
+func (p *Prop) Hashes() map[string]string {
+	hashes := make(map[string]string)
+	for _, checksums := range p.Checksums {
+		for _, checksum := range strings.Fields(checksums) {
+			i := strings.IndexRune(checksum, ':')
+			if i < 0 {
+				continue
+			}
+			hashes[strings.ToUpper(checksum[:i])] = strings.ToLower(checksum[i+1:])
+		}
+	}
+	return hashes
+}
+
+// PropFindRequest is the body of the PROPFIND request used to read
+// the properties of files and directories
+//
+// oc:checksums is only known to ownCloud and Nextcloud - other
+// servers return it in the 404 propstat.
+const PropFindRequest = `<?xml version="1.0" encoding="utf-8" ?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+ <d:prop>
+  <d:displayname />
+  <d:getlastmodified />
+  <d:getcontentlength />
+  <d:resourcetype />
+  <d:getcontenttype />
+  <oc:checksums />
+ </d:prop>
+</d:propfind>
+`
+
+// PropPatchModTimeRequest is the body of the PROPPATCH request used
+// to set the modification time on ownCloud and Nextcloud.  It should
+// be formatted with the time in seconds since the epoch.
+const PropPatchModTimeRequest = `<?xml version="1.0" encoding="utf-8" ?>
+<d:propertyupdate xmlns:d="DAV:">
+ <d:set>
+  <d:prop>
+   <d:lastmodified>%d</d:lastmodified>
+  </d:prop>
+ </d:set>
+</d:propertyupdate>
+`
+
+// Error is used to describe webdav errors
+//
+// <d:error xmlns:d="DAV:" xmlns:s="http://sabredav.org/ns">
+//
+//	<s:exception>Sabre\DAV\Exception\NotFound</s:exception>
+//	<s:message>File with name Photo could not be located</s:message>
+//
+// </d:error>
+type Error struct {
+	Exception  string `xml:"exception,omitempty"`
+	Message    string `xml:"message,omitempty"`
+	Status     string `xml:"-"`
+	StatusCode int    `xml:"-"`
+}
+
+// Error returns a string for the error and satisfies the error interface
+func (e *Error) Error() string {
+	var out []string
+	if e.Message != "" {
+		out = append(out, e.Message)
+	}
+	if e.Exception != "" {
+		out = append(out, e.Exception)
+	}
+	if e.Status != "" {
+		out = append(out, e.Status)
+	}
+	if len(out) == 0 {
+		return "Webdav Error"
+	}
+	return strings.Join(out, ": ")
+}
+
+// Check Error satisfies the error interface
+var _ error = (*Error)(nil)
+
+// Time represents date and time information for the
+// webdav API marshalling to and from http.TimeFormat
+type Time time.Time
+
+// MarshalXML turns a Time into XML
+func (t *Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	timeString := (*time.Time)(t).UTC().Format(http.TimeFormat)
+	return e.EncodeElement(timeString, start)
+}
+
+// UnmarshalXML turns XML into a Time
+func (t *Time) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v string
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+	if v == "" {
+		*t = Time(time.Time{})
+		return nil
+	}
+	newT, err := http.ParseTime(v)
+	if err != nil {
+		return fmt.Errorf("couldn't parse time %q: %v", v, err)
+	}
+	*t = Time(newT)
+	return nil
+}
@@ -0,0 +1,946 @@
+// Package webdav provides an interface to the WebDAV protocol as
+// served by Nextcloud, ownCloud and generic WebDAV servers.
+package webdav
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/pacer"
+	"github.com/ncw/rclone/rest"
+	"github.com/ncw/rclone/webdav/api"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "webdav",
+		Description: "Webdav",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "url",
+			Help: "URL of http host to connect to",
+			Examples: []fs.OptionExample{{
+				Value: "https://example.com",
+				Help:  "Connect to example.com",
+			}},
+		}, {
+			Name: "vendor",
+			Help: "Name of the Webdav site/service/software you are using",
+			Examples: []fs.OptionExample{{
+				Value: "nextcloud",
+				Help:  "Nextcloud",
+			}, {
+				Value: "owncloud",
+				Help:  "Owncloud",
+			}, {
+				Value: "other",
+				Help:  "Other site/service or software",
+			}},
+		}, {
+			Name:     "user",
+			Help:     "User name",
+			Optional: true,
+		}, {
+			Name:       "pass",
+			Help:       "Password.",
+			Optional:   true,
+			IsPassword: true,
+		}},
+	})
+}
+
+// Fs represents a remote webdav
+type Fs struct {
+	name          string       // name of this remote
+	root          string       // the path we are working on
+	features      *fs.Features // optional features
+	endpoint      *url.URL     // URL of the host
+	endpointURL   string       // endpoint as a string without a trailing /
+	srv           *rest.Client // the connection to the server
+	pacer         *pacer.Pacer // pacer for API calls
+	useOCMtime    bool         // set if can use X-OC-Mtime and PROPPATCH to set the modtime
+	hasChecksums  bool         // set if can use OC-Checksum and oc:checksums for hashes
+	canSetModTime bool         // set if the server can set the modtime of existing objects
+}
+
+// Object describes a webdav object
+//
+// Will definitely have info but maybe not meta
+type Object struct {
+	fs          *Fs       // what this object is part of
+	remote      string    // The remote path
+	hasMetaData bool      // whether info below has been set
+	size        int64     // size of the object
+	modTime     time.Time // modification time of the object
+	sha1        string    // SHA-1 of the object content if known
+	md5         string    // MD5 of the object content if known
+	mimeType    string    // Content-Type of the object from the server
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("webdav root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(resp *http.Response, err error) (bool, error) {
+	return fs.ShouldRetry(err) || fs.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// urlPathEscape escapes an unrooted path for use in a URL
+func urlPathEscape(p string) string {
+	u := url.URL{
+		Path: "/" + p,
+	}
+	return u.EscapedPath()
+}
+
+// dirURLPath returns the escaped URL path for the directory p which
+// ends in a /
+func dirURLPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return urlPathEscape(p) + "/"
+}
+
+// statusCode returns the HTTP status code of err if it is an
+// *api.Error or 0 otherwise
+func statusCode(err error) int {
+	if apiErr, ok := err.(*api.Error); ok {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	// Decode error response
+	errResponse := new(api.Error)
+	err := rest.DecodeXML(resp, &errResponse)
+	if err != nil {
+		// Errors often don't have a body so don't log them
+		fs.Debugf(nil, "Couldn't decode error response: %v", err)
+	}
+	errResponse.Status = resp.Status
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// propfind reads the properties of the escaped urlPath to the depth
+// given returning the responses
+func (f *Fs) propfind(ctx context.Context, urlPath string, depth string) (result *api.Multistatus, err error) {
+	opts := rest.Opts{
+		Method:      "PROPFIND",
+		Path:        urlPath,
+		ContentType: "application/xml; charset=utf-8",
+		ExtraHeaders: map[string]string{
+			"Depth": depth,
+		},
+	}
+	var resp *http.Response
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		// Make the body each time as it is consumed by the call
+		opts.Body = strings.NewReader(api.PropFindRequest)
+		result = new(api.Multistatus)
+		resp, err = f.srv.CallXML(ctx, &opts, nil, result)
+		return shouldRetry(resp, err)
+	})
+	return result, err
+}
+
+// readMetaDataForPath reads the metadata from the unrooted path p
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) readMetaDataForPath(ctx context.Context, p string) (info *api.Prop, err error) {
+	result, err := f.propfind(ctx, urlPathEscape(p), "0")
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, errors.Wrap(err, "read metadata failed")
+	}
+	if len(result.Responses) < 1 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	info, ok := result.Responses[0].Prop()
+	if !ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return info, nil
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string) (fs.Fs, error) {
+	ctx := context.Background()
+	endpoint := fs.ConfigFileGet(name, "url")
+	if endpoint == "" {
+		return nil, errors.New("url not set in config file")
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse url %q", endpoint)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("url %q must start with http:// or https://", endpoint)
+	}
+	user := fs.ConfigFileGet(name, "user")
+	pass := fs.ConfigFileGet(name, "pass")
+	if pass != "" {
+		pass, err = fs.Reveal(pass)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt password")
+		}
+	}
+	vendor := fs.ConfigFileGet(name, "vendor")
+	root = strings.Trim(root, "/")
+
+	f := &Fs{
+		name:        name,
+		root:        root,
+		endpoint:    u,
+		endpointURL: strings.TrimSuffix(u.String(), "/"),
+		pacer:       pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+	}
+	f.srv = rest.NewClient(fs.Config.Client()).SetRoot(f.endpointURL)
+	f.srv.SetErrorHandler(errorHandler)
+	if user != "" || pass != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+		f.srv.SetHeader("Authorization", "Basic "+auth)
+	}
+	f.setQuirks(vendor)
+	f.features = (&fs.Features{ReadMimeType: true}).Fill(f)
+
+	// See if the root is a file
+	if root != "" {
+		info, err := f.readMetaDataForPath(ctx, root)
+		if err == nil && !info.IsDir() {
+			// Point the Fs at the parent directory
+			f.root = path.Dir(root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// setQuirks adjusts the Fs for the vendor passed in
+func (f *Fs) setQuirks(vendor string) {
+	switch vendor {
+	case "owncloud", "nextcloud":
+		// Both can set the modtime on upload with the X-OC-Mtime
+		// header, set it afterwards with a PROPPATCH and return
+		// checksums uploaded with OC-Checksum in oc:checksums.
+		f.useOCMtime = true
+		f.canSetModTime = true
+		f.hasChecksums = true
+	case "other", "":
+	default:
+		fs.Debugf(f, "Unknown vendor %q", vendor)
+	}
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *api.Prop) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	var err error
+	if info != nil {
+		// Set info
+		err = o.setMetaData(info)
+	} else {
+		err = o.readMetaData(ctx) // reads info and meta, returning an error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(ctx, remote, nil)
+}
+
+// listAllFn is the user function called on each item found by
+// listAll
+//
+// Should return true to finish processing
+type listAllFn func(remote string, isDir bool, info *api.Prop) bool
+
+// Lists the directory required calling the user function on each item found
+//
+// If the user fn ever returns true then it early exits with found = true
+func (f *Fs) listAll(ctx context.Context, dir string, directoriesOnly bool, filesOnly bool, fn listAllFn) (found bool, err error) {
+	dirPath := path.Join(f.root, dir)
+	result, err := f.propfind(ctx, dirURLPath(dirPath), "1")
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			return found, fs.ErrorDirNotFound
+		}
+		return found, errors.Wrap(err, "couldn't list files")
+	}
+	// The hrefs returned are the paths of the items on the server
+	// which should be children of basePath
+	basePath := path.Join("/", f.endpoint.Path, dirPath)
+	for i := range result.Responses {
+		item := &result.Responses[i]
+		u, err := url.Parse(item.Href)
+		if err != nil {
+			fs.Debugf(f, "Ignoring item with bad href %q: %v", item.Href, err)
+			continue
+		}
+		itemPath := path.Clean(u.Path)
+		var leaf string
+		if basePath == "/" {
+			leaf = strings.TrimPrefix(itemPath, "/")
+		} else if strings.HasPrefix(itemPath, basePath+"/") {
+			leaf = itemPath[len(basePath)+1:]
+		} else {
+			// Ignore the directory itself and anything not in it
+			continue
+		}
+		if leaf == "" || strings.Contains(leaf, "/") {
+			continue
+		}
+		info, ok := item.Prop()
+		if !ok {
+			fs.Debugf(f, "Ignoring item %q with no properties", leaf)
+			continue
+		}
+		isDir := info.IsDir()
+		if isDir {
+			if filesOnly {
+				continue
+			}
+		} else {
+			if directoriesOnly {
+				continue
+			}
+		}
+		if fn(path.Join(dir, leaf), isDir, info) {
+			found = true
+			break
+		}
+	}
+	return found, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	var iErr error
+	_, err = f.listAll(ctx, dir, false, false, func(remote string, isDir bool, info *api.Prop) bool {
+		if isDir {
+			d := &fs.Dir{
+				Name:  remote,
+				When:  time.Time(info.Modified),
+				Bytes: -1,
+				Count: -1,
+			}
+			entries = append(entries, d)
+		} else {
+			o, err := f.newObjectWithInfo(ctx, remote, info)
+			if err != nil {
+				iErr = err
+				return true
+			}
+			entries = append(entries, o)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if iErr != nil {
+		return nil, iErr
+	}
+	return entries, nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// mkParentDir makes the parent of the unrooted path p if necessary
+func (f *Fs) mkParentDir(ctx context.Context, p string) error {
+	parent := path.Dir(p)
+	if parent == "." || parent == "/" {
+		return nil
+	}
+	return f.mkdir(ctx, parent)
+}
+
+// mkcol makes the unrooted directory p with a single MKCOL
+func (f *Fs) mkcol(ctx context.Context, p string) error {
+	opts := rest.Opts{
+		Method:     "MKCOL",
+		Path:       dirURLPath(p),
+		NoResponse: true,
+	}
+	return f.pacer.Call(ctx, func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(resp, err)
+	})
+}
+
+// mkdir makes the unrooted directory p and its parents if necessary
+func (f *Fs) mkdir(ctx context.Context, p string) error {
+	if p == "" || p == "." {
+		return nil
+	}
+	err := f.mkcol(ctx, p)
+	switch statusCode(err) {
+	case http.StatusMethodNotAllowed:
+		// The directory already exists
+		return nil
+	case http.StatusConflict:
+		// The parent doesn't exist so make it and try again
+		err = f.mkParentDir(ctx, p)
+		if err != nil {
+			return err
+		}
+		err = f.mkcol(ctx, p)
+		if statusCode(err) == http.StatusMethodNotAllowed {
+			return nil
+		}
+	}
+	return err
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdir(ctx, path.Join(f.root, dir))
+}
+
+// purgeCheck removes the directory dir, if check is set then it
+// refuses to do so if it has anything in
+func (f *Fs) purgeCheck(ctx context.Context, dir string, check bool) error {
+	if check {
+		notEmpty, err := f.listAll(ctx, dir, false, false, func(remote string, isDir bool, info *api.Prop) bool {
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if notEmpty {
+			return errors.New("directory not empty")
+		}
+	}
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       dirURLPath(path.Join(f.root, dir)),
+		NoResponse: true,
+	}
+	err := f.pacer.Call(ctx, func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(resp, err)
+	})
+	if statusCode(err) == http.StatusNotFound {
+		return fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "rmdir failed")
+	}
+	return nil
+}
+
+// Rmdir deletes the directory
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.purgeCheck(ctx, dir, true)
+}
+
+// Precision return the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	if f.useOCMtime {
+		return time.Second
+	}
+	return fs.ModTimeNotSupported
+}
+
+// copyOrMove copies or moves src to remote with the method COPY or
+// MOVE
+func (f *Fs) copyOrMove(ctx context.Context, src *Object, remote string, method string) (fs.Object, error) {
+	dstPath := path.Join(f.root, remote)
+	err := f.mkParentDir(ctx, dstPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't make parent directory")
+	}
+	opts := rest.Opts{
+		Method:     method,
+		Path:       urlPathEscape(src.fullPath()),
+		NoResponse: true,
+		ExtraHeaders: map[string]string{
+			"Destination": f.endpointURL + urlPathEscape(dstPath),
+			"Overwrite":   "T",
+		},
+	}
+	if f.useOCMtime {
+		opts.ExtraHeaders["X-OC-Mtime"] = fmt.Sprintf("%d", src.ModTime().Unix())
+	}
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s failed", strings.ToLower(method))
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	return f.copyOrMove(ctx, srcObj, remote, "COPY")
+}
+
+// Purge deletes all the files and the container
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context) error {
+	return f.purgeCheck(ctx, "", false)
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	return f.copyOrMove(ctx, srcObj, remote, "MOVE")
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := path.Join(srcFs.root, srcRemote)
+	dstPath := path.Join(f.root, dstRemote)
+
+	// Check if destination exists
+	_, err := f.readMetaDataForPath(ctx, dstPath)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if err != fs.ErrorObjectNotFound {
+		return err
+	}
+
+	// Make sure the parent directory exists
+	err = f.mkParentDir(ctx, dstPath)
+	if err != nil {
+		return errors.Wrap(err, "couldn't make parent directory")
+	}
+
+	opts := rest.Opts{
+		Method:     "MOVE",
+		Path:       dirURLPath(srcPath),
+		NoResponse: true,
+		ExtraHeaders: map[string]string{
+			"Destination": f.endpointURL + dirURLPath(dstPath),
+			"Overwrite":   "F",
+		},
+	}
+	err = f.pacer.Call(ctx, func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "dirmove failed")
+	}
+	return nil
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() fs.HashSet {
+	if f.hasChecksums {
+		return fs.HashSet(fs.HashMD5 | fs.HashSHA1)
+	}
+	return fs.HashSet(fs.HashNone)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// fullPath returns the unrooted path of the object on the server
+func (o *Object) fullPath() string {
+	return path.Join(o.fs.root, o.remote)
+}
+
+// Hash returns the SHA-1 or MD5 of an object returning a lowercase
+// hex string
+func (o *Object) Hash(t fs.HashType) (string, error) {
+	if !o.fs.Hashes().Contains(t) {
+		return "", fs.ErrHashUnsupported
+	}
+	switch t {
+	case fs.HashSHA1:
+		return o.sha1, nil
+	case fs.HashMD5:
+		return o.md5, nil
+	}
+	return "", fs.ErrHashUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	err := o.readMetaData(context.TODO())
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return 0
+	}
+	return o.size
+}
+
+// setMetaData sets the metadata from info
+func (o *Object) setMetaData(info *api.Prop) (err error) {
+	if info.IsDir() {
+		return errors.Wrapf(fs.ErrorNotAFile, "%q", o.remote)
+	}
+	o.hasMetaData = true
+	o.size = info.Size
+	o.modTime = time.Time(info.Modified)
+	o.mimeType = info.ContentType
+	hashes := info.Hashes()
+	o.sha1 = hashes["SHA1"]
+	o.md5 = hashes["MD5"]
+	return nil
+}
+
+// readMetaData gets the metadata if it hasn't already been fetched
+//
+// it also sets the info
+func (o *Object) readMetaData(ctx context.Context) (err error) {
+	if o.hasMetaData {
+		return nil
+	}
+	info, err := o.fs.readMetaDataForPath(ctx, o.fullPath())
+	if err != nil {
+		return err
+	}
+	return o.setMetaData(info)
+}
+
+// ModTime returns the modification time of the object
+//
+// It attempts to read the objects mtime and if that isn't present the
+// LastModified returned in the http headers
+func (o *Object) ModTime() time.Time {
+	err := o.readMetaData(context.TODO())
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Now()
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if !o.fs.canSetModTime {
+		return fs.ErrorCantSetModTime
+	}
+	opts := rest.Opts{
+		Method:      "PROPPATCH",
+		Path:        urlPathEscape(o.fullPath()),
+		ContentType: "application/xml; charset=utf-8",
+	}
+	var result api.Multistatus
+	err := o.fs.pacer.Call(ctx, func() (bool, error) {
+		// Make the body each time as it is consumed by the call
+		opts.Body = strings.NewReader(fmt.Sprintf(api.PropPatchModTimeRequest, modTime.Unix()))
+		resp, err := o.fs.srv.CallXML(ctx, &opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't set modification time")
+	}
+	if len(result.Responses) < 1 {
+		return errors.New("couldn't set modification time: no response")
+	}
+	if _, ok := result.Responses[0].Prop(); !ok {
+		return fs.ErrorCantSetModTime
+	}
+	o.modTime = modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+//
+// If the server ignores the range asked for and sends the whole
+// object then the data before the range is skipped.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	size := int64(-1)
+	if o.hasMetaData {
+		size = o.size
+	}
+	var offset, limit int64 = 0, -1
+	ranged := false
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset, ranged = x.Offset, true
+		case *fs.RangeOption:
+			if x.Start < 0 && size < 0 {
+				// Can't skip to a suffix range of unknown size
+				offset = -1
+			} else {
+				offset, limit = x.Decode(size)
+			}
+			ranged = true
+		}
+	}
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    urlPathEscape(o.fullPath()),
+		Options: options,
+	}
+	err = o.fs.pacer.Call(ctx, func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ranged || resp.StatusCode == http.StatusPartialContent {
+		return resp.Body, nil
+	}
+	// The server sent the whole file so skip to the range
+	if offset < 0 {
+		_ = resp.Body.Close()
+		return nil, errors.Errorf("failed to open %q: server ignored the range requested", o.remote)
+	}
+	_, err = io.CopyN(ioutil.Discard, resp.Body, offset)
+	if err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, errors.Wrapf(err, "failed to skip to offset in %q", o.remote)
+	}
+	if limit >= 0 {
+		return fs.NewLimitedReadCloser(resp.Body, limit), nil
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	// Objects made by Put don't have any metadata so check
+	// whether they are replacing a file on the server
+	existed := o.hasMetaData
+	if !existed {
+		_, statErr := o.fs.readMetaDataForPath(ctx, o.fullPath())
+		existed = statErr != fs.ErrorObjectNotFound
+	}
+	err = o.fs.mkParentDir(ctx, o.fullPath())
+	if err != nil {
+		return errors.Wrap(err, "couldn't make parent directory")
+	}
+
+	size := src.Size()
+	opts := rest.Opts{
+		Method:       "PUT",
+		Path:         urlPathEscape(o.fullPath()),
+		Body:         in,
+		NoResponse:   true,
+		ContentType:  fs.MimeType(src),
+		ExtraHeaders: map[string]string{},
+	}
+	if size >= 0 {
+		opts.ContentLength = &size
+	}
+	// for go1.8 (see release notes) we must nil the Body if we want a
+	// "Content-Length: 0" header
+	if size == 0 {
+		opts.Body = nil
+	}
+	if o.fs.useOCMtime {
+		opts.ExtraHeaders["X-OC-Mtime"] = fmt.Sprintf("%d", src.ModTime().Unix())
+	}
+	if o.fs.hasChecksums {
+		// Set an upload checksum - prefer SHA1
+		if sha1, _ := src.Hash(fs.HashSHA1); sha1 != "" {
+			opts.ExtraHeaders["OC-Checksum"] = "SHA1:" + sha1
+		} else if md5, _ := src.Hash(fs.HashMD5); md5 != "" {
+			opts.ExtraHeaders["OC-Checksum"] = "MD5:" + md5
+		}
+	}
+	err = o.fs.pacer.CallNoRetry(ctx, func() (bool, error) {
+		resp, err := o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		// Servers usually keep what they received of a failed
+		// upload so remove it rather than leave a partial file,
+		// unless it may still be the file which was there before
+		if !existed {
+			removeErr := o.Remove(ctx)
+			if removeErr != nil {
+				fs.Debugf(o, "Failed to remove failed upload: %v", removeErr)
+			}
+		}
+		return err
+	}
+	// Read the metadata back from the server
+	o.hasMetaData = false
+	return o.readMetaData(ctx)
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       urlPathEscape(o.fullPath()),
+		NoResponse: true,
+	}
+	return o.fs.pacer.Call(ctx, func() (bool, error) {
+		resp, err := o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(resp, err)
+	})
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType() string {
+	return o.mimeType
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs        = (*Fs)(nil)
+	_ fs.Purger    = (*Fs)(nil)
+	_ fs.Copier    = (*Fs)(nil)
+	_ fs.Mover     = (*Fs)(nil)
+	_ fs.DirMover  = (*Fs)(nil)
+	_ fs.Object    = (*Object)(nil)
+	_ fs.MimeTyper = &Object{}
+)
@@ -0,0 +1,24 @@
+package webdav_test
+
+import (
+	"net/http/httptest"
+
+	"github.com/ncw/rclone/fstest/fstests"
+	"golang.org/x/net/webdav"
+)
+
+// Create the TestWebdav: remote pointing at an in memory webdav
+// server so the tests don't need a real one
+func init() {
+	handler := &webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(handler)
+	name := "TestWebdav"
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "webdav"},
+		{Name: name, Key: "url", Value: server.URL + "/"},
+		{Name: name, Key: "vendor", Value: "other"},
+	}
+}
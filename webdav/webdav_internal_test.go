@@ -0,0 +1,311 @@
+package webdav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/webdav/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"golang.org/x/net/webdav"
+)
+
+func TestMain(m *testing.M) {
+	fs.LoadConfig()
+	os.Exit(m.Run())
+}
+
+// An ownCloud style response to a PROPFIND with Depth: 1
+const ownCloudListing = `<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:" xmlns:s="http://sabredav.org/ns" xmlns:oc="http://owncloud.org/ns">
+ <d:response>
+  <d:href>/remote.php/webdav/dir/</d:href>
+  <d:propstat>
+   <d:prop>
+    <d:getlastmodified>Tue, 01 Aug 2017 12:30:45 GMT</d:getlastmodified>
+    <d:resourcetype><d:collection/></d:resourcetype>
+   </d:prop>
+   <d:status>HTTP/1.1 200 OK</d:status>
+  </d:propstat>
+ </d:response>
+ <d:response>
+  <d:href>/remote.php/webdav/dir/file%20one.txt</d:href>
+  <d:propstat>
+   <d:prop>
+    <d:getlastmodified>Wed, 02 Aug 2017 01:02:03 GMT</d:getlastmodified>
+    <d:getcontentlength>11</d:getcontentlength>
+    <d:resourcetype/>
+    <d:getcontenttype>text/plain</d:getcontenttype>
+    <oc:checksums><oc:checksum>SHA1:2AAE6C35C94FCFB415DBE95F408B9CE91EE846ED MD5:5eb63bbbe01eeed093cb22bb8f5acdc3 ADLER32:1a0b045d</oc:checksum></oc:checksums>
+   </d:prop>
+   <d:status>HTTP/1.1 200 OK</d:status>
+  </d:propstat>
+  <d:propstat>
+   <d:prop>
+    <d:displayname/>
+   </d:prop>
+   <d:status>HTTP/1.1 404 Not Found</d:status>
+  </d:propstat>
+ </d:response>
+ <d:response>
+  <d:href>https://example.com/remote.php/webdav/dir/sub%3Fdir/</d:href>
+  <d:propstat>
+   <d:prop>
+    <d:getlastmodified>Tue, 01 Aug 2017 12:30:45 GMT</d:getlastmodified>
+    <d:resourcetype><d:collection/></d:resourcetype>
+   </d:prop>
+   <d:status>HTTP/1.1 200 OK</d:status>
+  </d:propstat>
+ </d:response>
+ <d:response>
+  <d:href>/remote.php/webdav/elsewhere/file</d:href>
+  <d:propstat>
+   <d:prop>
+    <d:resourcetype/>
+   </d:prop>
+   <d:status>HTTP/1.1 200 OK</d:status>
+  </d:propstat>
+ </d:response>
+</d:multistatus>
+`
+
+func TestParseMultistatus(t *testing.T) {
+	var result api.Multistatus
+	require.NoError(t, xml.Unmarshal([]byte(ownCloudListing), &result))
+	require.Equal(t, 4, len(result.Responses))
+
+	dir, ok := result.Responses[0].Prop()
+	require.True(t, ok)
+	assert.True(t, dir.IsDir())
+
+	file, ok := result.Responses[1].Prop()
+	require.True(t, ok)
+	assert.False(t, file.IsDir())
+	assert.Equal(t, int64(11), file.Size)
+	assert.Equal(t, "text/plain", file.ContentType)
+	assert.Equal(t, time.Date(2017, 8, 2, 1, 2, 3, 0, time.UTC), time.Time(file.Modified).UTC())
+	assert.Equal(t, map[string]string{
+		"SHA1":    "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed",
+		"MD5":     "5eb63bbbe01eeed093cb22bb8f5acdc3",
+		"ADLER32": "1a0b045d",
+	}, file.Hashes())
+
+	notFound := api.Propstat{Status: "HTTP/1.1 404 Not Found"}
+	assert.False(t, notFound.StatusOK())
+}
+
+func TestListOwnCloud(t *testing.T) {
+	var depth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PROPFIND", r.Method)
+		assert.Equal(t, "/remote.php/webdav/dir/", r.URL.Path)
+		depth = r.Header.Get("Depth")
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(ownCloudListing))
+	}))
+	defer ts.Close()
+	fs.ConfigFileSet("TestWebdavOwnCloud", "type", "webdav")
+	fs.ConfigFileSet("TestWebdavOwnCloud", "url", ts.URL+"/remote.php/webdav/")
+	fs.ConfigFileSet("TestWebdavOwnCloud", "vendor", "owncloud")
+
+	fsrc, err := fs.NewFs("TestWebdavOwnCloud:")
+	require.NoError(t, err)
+	entries, err := fsrc.List(context.Background(), "dir")
+	require.NoError(t, err)
+	assert.Equal(t, "1", depth)
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"dir/file one.txt", "dir/sub?dir"}, names)
+
+	o, ok := entries[0].(*Object)
+	if !ok {
+		o = entries[1].(*Object)
+	}
+	sha1, err := o.Hash(fs.HashSHA1)
+	require.NoError(t, err)
+	assert.Equal(t, "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed", sha1)
+	assert.Equal(t, time.Second, fsrc.Precision())
+}
+
+func TestUpdateOwnCloudHeaders(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		headers http.Header
+	)
+	handler := &webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			mu.Lock()
+			headers = r.Header
+			mu.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	fs.ConfigFileSet("TestWebdavHeaders", "type", "webdav")
+	fs.ConfigFileSet("TestWebdavHeaders", "url", ts.URL)
+	fs.ConfigFileSet("TestWebdavHeaders", "vendor", "nextcloud")
+	fs.ConfigFileSet("TestWebdavHeaders", "user", "user")
+	fs.ConfigFileSet("TestWebdavHeaders", "pass", fs.MustObscure("pass"))
+
+	fdst, err := fs.NewFs("TestWebdavHeaders:")
+	require.NoError(t, err)
+	modTime := time.Date(2017, 8, 1, 12, 30, 45, 0, time.UTC)
+	contents := "hello world"
+	src := fs.NewStaticObjectInfo("a/b/file.txt", modTime, int64(len(contents)), true, map[fs.HashType]string{
+		fs.HashSHA1: "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed",
+	}, nil)
+	o, err := fdst.Put(context.Background(), bytes.NewBufferString(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), o.Size())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotNil(t, headers)
+	assert.Equal(t, "1501590645", headers.Get("X-OC-Mtime"))
+	assert.Equal(t, "SHA1:2aae6c35c94fcfb415dbe95f408b9ce91ee846ed", headers.Get("OC-Checksum"))
+	assert.Equal(t, "Basic dXNlcjpwYXNz", headers.Get("Authorization"))
+}
+
+func TestUpdateFailed(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu      sync.Mutex
+		failPut bool
+		deletes int
+	)
+	handler := &webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := failPut && r.Method == "PUT"
+		if r.Method == "DELETE" {
+			deletes++
+		}
+		mu.Unlock()
+		if fail {
+			http.Error(w, "upload failed", http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	fs.ConfigFileSet("TestWebdavUpdateFailed", "type", "webdav")
+	fs.ConfigFileSet("TestWebdavUpdateFailed", "url", ts.URL)
+	f, err := fs.NewFs("TestWebdavUpdateFailed:")
+	require.NoError(t, err)
+
+	src := fs.NewStaticObjectInfo("file.txt", time.Now(), 5, true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewBufferString("hello"), src)
+	require.NoError(t, err)
+	mu.Lock()
+	failPut = true
+	mu.Unlock()
+
+	// A failed update leaves the existing file alone
+	src = fs.NewStaticObjectInfo("file.txt", time.Now(), 6, true, nil, nil)
+	require.Error(t, o.Update(ctx, bytes.NewBufferString("potato"), src))
+	_, err = f.NewObject(ctx, "file.txt")
+	assert.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, 0, deletes)
+	mu.Unlock()
+
+	// as does a failed Put over the existing file
+	_, err = f.Put(ctx, bytes.NewBufferString("potato"), src)
+	require.Error(t, err)
+	_, err = f.NewObject(ctx, "file.txt")
+	assert.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, 0, deletes)
+	mu.Unlock()
+
+	// but a failed upload of a new file is removed
+	src = fs.NewStaticObjectInfo("new.txt", time.Now(), 5, true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewBufferString("hello"), src)
+	require.Error(t, err)
+	mu.Lock()
+	assert.Equal(t, 1, deletes)
+	mu.Unlock()
+}
+
+func TestOpenIgnoredRange(t *testing.T) {
+	ctx := context.Background()
+	handler := &webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	// Serve the files ignoring any Range headers
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Range")
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	fs.ConfigFileSet("TestWebdavIgnoredRange", "type", "webdav")
+	fs.ConfigFileSet("TestWebdavIgnoredRange", "url", ts.URL)
+	f, err := fs.NewFs("TestWebdavIgnoredRange:")
+	require.NoError(t, err)
+	src := fs.NewStaticObjectInfo("file.txt", time.Now(), 11, true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewBufferString("hello world"), src)
+	require.NoError(t, err)
+
+	read := func(options ...fs.OpenOption) string {
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(data)
+	}
+	assert.Equal(t, "hello world", read())
+	assert.Equal(t, "world", read(&fs.SeekOption{Offset: 6}))
+	assert.Equal(t, "lo w", read(&fs.RangeOption{Start: 3, End: 6}))
+	assert.Equal(t, "rld", read(&fs.RangeOption{Start: -1, End: 3}))
+}
+
+func TestNewFsIsFile(t *testing.T) {
+	handler := &webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	fs.ConfigFileSet("TestWebdavIsFile", "type", "webdav")
+	fs.ConfigFileSet("TestWebdavIsFile", "url", ts.URL)
+
+	f, err := fs.NewFs("TestWebdavIsFile:")
+	require.NoError(t, err)
+	src := fs.NewStaticObjectInfo("dir/file.txt", time.Now(), 5, true, nil, nil)
+	_, err = f.Put(context.Background(), bytes.NewBufferString("hello"), src)
+	require.NoError(t, err)
+
+	f, err = fs.NewFs("TestWebdavIsFile:dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "dir", f.Root())
+
+	f, err = fs.NewFs("TestWebdavIsFile:dir")
+	require.NoError(t, err)
+	assert.Equal(t, "dir", f.Root())
+	assert.Equal(t, fs.ModTimeNotSupported, f.Precision())
+	assert.Equal(t, fs.HashSet(fs.HashNone), f.Hashes())
+}